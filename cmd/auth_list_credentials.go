@@ -0,0 +1,97 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/pkg/credfiles"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// List the credential files written by the CLI.
+type authListCredentialsOpts struct {
+	flagFormat string
+}
+
+func init() {
+	o := authListCredentialsOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "list-credentials [flags]",
+		Short: "List the credential files written by the CLI",
+		Run:   runCommand(&o),
+		Long: renderLong(&o, `
+			List the credential-bearing files (eg, kubeconfigs with embedded credentials) that the
+			CLI has written into its managed credentials directory, along with the environment
+			they are for and when they expire.
+
+			Expired files are deleted automatically when the CLI is run, or explicitly with
+			'metaplay auth prune-credentials'.
+
+			Related commands:
+			- 'metaplay auth prune-credentials' to delete the expired credential files.
+			- 'metaplay get kubeconfig ... --save' to write a kubeconfig into the managed directory.
+		`),
+		Example: trimIndent(`
+			# List the credential files.
+			metaplay auth list-credentials
+
+			# List the credential files in JSON format.
+			metaplay auth list-credentials --format=json
+		`),
+	}
+	authCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format. Valid values are 'text' or 'json'")
+}
+
+func (o *authListCredentialsOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q, must be either 'text' or 'json'", o.flagFormat)
+	}
+	return nil
+}
+
+func (o *authListCredentialsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	files, err := credfiles.List()
+	if err != nil {
+		return err
+	}
+
+	if o.flagFormat == "json" {
+		filesJSON, err := json.MarshalIndent(files, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal credential files as JSON: %v", err)
+		}
+		log.Info().Msg(string(filesJSON))
+		return nil
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Credential Files"))
+	log.Info().Msg("")
+	if len(files) == 0 {
+		log.Info().Msg("No credential files written by the CLI")
+		return nil
+	}
+	now := time.Now()
+	for _, file := range files {
+		status := styles.RenderSuccess(fmt.Sprintf("expires %s", humanize.Time(file.ExpiresAt)))
+		if file.IsExpired(now) {
+			status = styles.RenderWarning(fmt.Sprintf("expired %s", humanize.Time(file.ExpiresAt)))
+		}
+		log.Info().Msgf("%s %s", styles.RenderTechnical(file.Path), styles.RenderMuted("("+file.Kind+")"))
+		log.Info().Msgf("  Environment: %s", styles.RenderTechnical(file.Environment))
+		log.Info().Msgf("  Created:     %s", humanize.Time(file.CreatedAt))
+		log.Info().Msgf("  Status:      %s", status)
+	}
+	return nil
+}
@@ -45,7 +45,7 @@ func (o *LoginOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *LoginOpts) Run(cmd *cobra.Command) error {
+func (o *LoginOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
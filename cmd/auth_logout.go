@@ -44,7 +44,7 @@ func (o *LogoutOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *LogoutOpts) Run(cmd *cobra.Command) error {
+func (o *LogoutOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
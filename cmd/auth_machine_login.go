@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/auth"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -49,7 +50,7 @@ func (o *MachineLoginOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *MachineLoginOpts) Run(cmd *cobra.Command) error {
+func (o *MachineLoginOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -70,21 +71,18 @@ func (o *MachineLoginOpts) Run(cmd *cobra.Command) error {
 	} else {
 		log.Debug().Msg("Using environment variable METAPLAY_CREDENTIALS for machine login")
 		if envCredentials, ok := os.LookupEnv("METAPLAY_CREDENTIALS"); !ok {
-			log.Error().Msg("Unable to find the credentials, the environment variable METAPLAY_CREDENTIALS is not defined!")
-			os.Exit(2)
+			return exitcode.Errorf(exitcode.ExitUsage, "unable to find the credentials, the environment variable METAPLAY_CREDENTIALS is not defined")
 		} else {
 			credentials = envCredentials
 		}
 	}
 
 	if clientId, clientSecret, ok := strings.Cut(credentials, "+"); !ok {
-		log.Error().Msg("Invalid format for credentials, you should copy-paste the value from the developer portal verbatim")
-		os.Exit(2)
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid format for credentials, you should copy-paste the value from the developer portal verbatim")
 	} else {
 		err := auth.MachineLogin(authProvider, clientId, clientSecret)
 		if err != nil {
-			log.Error().Msgf("Machine login failed: %s", err)
-			os.Exit(1)
+			return exitcode.Errorf(exitcode.ExitAuthRequired, "machine login failed: %w", err)
 		}
 	}
 
@@ -0,0 +1,104 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/metaplay/cli/pkg/credfiles"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// How much time the automatic pruning of expired credential files may take at command start.
+const autoPruneCredentialsBudget = 5 * time.Millisecond
+
+// Delete the expired credential files written by the CLI.
+type authPruneCredentialsOpts struct {
+	flagAll bool
+}
+
+func init() {
+	o := authPruneCredentialsOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "prune-credentials [flags]",
+		Short: "Delete the expired credential files written by the CLI",
+		Run:   runCommand(&o),
+		Long: renderLong(&o, `
+			Delete the expired credential files from the CLI's managed credentials directory.
+			Use --all to also delete the files whose credentials are still valid.
+
+			Only files written by the CLI are deleted: other files in the directory are never
+			touched.
+
+			Related commands:
+			- 'metaplay auth list-credentials' to list the credential files.
+		`),
+		Example: trimIndent(`
+			# Delete the expired credential files.
+			metaplay auth prune-credentials
+
+			# Delete all the credential files, including the ones that are still valid.
+			metaplay auth prune-credentials --all
+		`),
+	}
+	authCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVar(&o.flagAll, "all", false, "Also delete the credential files that have not expired yet")
+}
+
+func (o *authPruneCredentialsOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *authPruneCredentialsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	var pruned []*credfiles.File
+	var err error
+	if o.flagAll {
+		pruned, err = removeAllCredentialFiles()
+	} else {
+		pruned, err = credfiles.Prune(time.Now(), 0)
+	}
+	for _, file := range pruned {
+		log.Info().Msgf("Deleted %s %s", styles.RenderTechnical(file.Path), styles.RenderMuted("("+file.Kind+" for "+file.Environment+")"))
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("✅ %s", styles.RenderSuccess(fmt.Sprintf("Deleted %d credential file(s)", len(pruned))))
+	return nil
+}
+
+// Delete all the credential files, including the ones that are still valid.
+func removeAllCredentialFiles() ([]*credfiles.File, error) {
+	files, err := credfiles.List()
+	if err != nil {
+		return nil, err
+	}
+	removed := []*credfiles.File{}
+	for _, file := range files {
+		if err := file.Remove(); err != nil {
+			return removed, err
+		}
+		removed = append(removed, file)
+	}
+	return removed, nil
+}
+
+// Opportunistically delete the expired credential files, spending at most a few milliseconds.
+// Failures are not fatal as the files can always be pruned explicitly.
+func autoPruneCredentials() {
+	pruned, err := credfiles.Prune(time.Now(), autoPruneCredentialsBudget)
+	if err != nil {
+		log.Debug().Msgf("Failed to prune expired credential files: %v", err)
+	}
+	for _, file := range pruned {
+		log.Debug().Msgf("Pruned expired credential file %s", file.Path)
+	}
+}
@@ -6,9 +6,10 @@ package cmd
 import (
 	"encoding/base64"
 	"encoding/json"
-	"os"
+	"fmt"
 	"strings"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/auth"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -73,7 +74,7 @@ func decodeJWT(tokenString string) (map[string]interface{}, error) {
 	return claims, nil
 }
 
-func (o *authShowTokensOpts) Run(cmd *cobra.Command) error {
+func (o *authShowTokensOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -94,8 +95,7 @@ func (o *authShowTokensOpts) Run(cmd *cobra.Command) error {
 
 	// Handle missing tokens (not logged in).
 	if tokenSet == nil {
-		log.Warn().Msg("Not logged in! Sign in first with 'metaplay auth login' or 'metaplay auth machine-login'")
-		os.Exit(1)
+		return exitcode.Errorf(exitcode.ExitAuthRequired, "not logged in! Sign in first with 'metaplay auth login' or 'metaplay auth machine-login'")
 	}
 
 	// Decode and log the access token at debug level
@@ -128,7 +128,7 @@ func (o *authShowTokensOpts) Run(cmd *cobra.Command) error {
 		log.Panic().Msgf("failed to serialize tokens into JSON: %v", err)
 	}
 
-	// Print as string.
-	log.Info().Msg(string(bytes))
+	// Print with fmt to keep the tokens out of the debug log file.
+	fmt.Println(string(bytes))
 	return nil
 }
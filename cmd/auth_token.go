@@ -0,0 +1,139 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Print the access token for debugging integrations.
+type authTokenOpts struct {
+	flagEnvironment string
+	flagDecode      bool
+}
+
+func init() {
+	o := authTokenOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "token [flags]",
+		Short: "Print the access token to stdout",
+		Long: trimIndent(`
+			Print the currently active access token (JWT) to stdout, eg, for debugging
+			integrations with the StackAPI.
+
+			By default, the token of the built-in 'metaplay' auth provider is printed. With
+			--environment, the token used for accessing the given environment is printed
+			instead, ie, the token of the auth provider configured for the environment in
+			'metaplay-project.yaml'. Tokens are not scoped to individual environments, so
+			environments sharing an auth provider use the same token. Using --environment
+			outside of a project (see --project) is an error.
+
+			With --decode, the payload of the token is decoded and printed as JSON instead.
+			The signature of the token is not verified.
+
+			The token is refreshed first if it has expired and a refresh token is available.
+			A warning is shown (on stderr) if the printed token has expired.
+		`),
+		Example: trimIndent(`
+			# Print the access token.
+			metaplay auth token
+
+			# Use the token with curl.
+			curl -H "Authorization: Bearer $(metaplay auth token)" https://infra.<stack-domain>/stackapi/...
+
+			# Print the token used to access environment tough-falcons.
+			metaplay auth token --environment=tough-falcons
+
+			# Show the claims in the token.
+			metaplay auth token --decode
+		`),
+		Run: runCommand(&o),
+	}
+
+	authCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVarP(&o.flagEnvironment, "environment", "e", "", "Print the token used for accessing the given environment")
+	flags.BoolVar(&o.flagDecode, "decode", false, "Print the decoded token payload as JSON (signature is not verified)")
+}
+
+func (o *authTokenOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	return nil
+}
+
+func (o *authTokenOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Try to resolve the project & auth provider.
+	project, err := tryResolveProject()
+	if err != nil {
+		return err
+	}
+	if o.flagEnvironment != "" && project == nil {
+		return exitcode.Errorf(exitcode.ExitUsage, "--environment requires a Metaplay project to resolve the environment's auth provider, but no project was found; use --project to specify its location")
+	}
+
+	// Resolve the auth provider: use the environment's provider if environment is specified.
+	authProviderName := ""
+	if o.flagEnvironment != "" && project != nil {
+		envConfig, err := project.Config.FindEnvironmentConfig(o.flagEnvironment)
+		if err != nil {
+			return err
+		}
+		authProviderName = envConfig.AuthProvider
+	}
+	authProvider, err := getAuthProvider(project, authProviderName)
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("Using auth provider %s", authProvider.Name)
+
+	// Load tokenSet from keyring & refresh if needed.
+	tokenSet, err := auth.LoadAndRefreshTokenSet(authProvider)
+	if err != nil {
+		return err
+	}
+
+	// Handle missing tokens (not logged in).
+	if tokenSet == nil || tokenSet.AccessToken == "" {
+		return exitcode.Errorf(exitcode.ExitAuthRequired, "not logged in! Sign in first with 'metaplay auth login' or 'metaplay auth machine-login'")
+	}
+
+	// Warn on stderr if the token has expired (so stdout can be captured).
+	expiresAt, err := auth.GetAccessTokenExpiresAt(tokenSet)
+	if err != nil {
+		stderrLogger.Warn().Msgf("Unable to determine access token expiration: %v", err)
+	} else if time.Now().After(expiresAt) {
+		stderrLogger.Warn().Msgf(styles.RenderWarning("Warning: The access token expired at %s. Sign in again with 'metaplay auth login'."), expiresAt.Format(time.RFC3339))
+	}
+
+	// Print the raw token, or its decoded payload.
+	if o.flagDecode {
+		claims, err := decodeJWT(tokenSet.AccessToken)
+		if err != nil {
+			return fmt.Errorf("failed to decode access token: %w", err)
+		} else if claims == nil {
+			return fmt.Errorf("failed to decode access token: not a valid JWT")
+		}
+		claimsJSON, err := json.MarshalIndent(claims, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize token claims into JSON: %w", err)
+		}
+		fmt.Println(string(claimsJSON))
+	} else {
+		fmt.Println(tokenSet.AccessToken)
+	}
+
+	return nil
+}
@@ -70,7 +70,7 @@ func (o *WhoamiOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *WhoamiOpts) Run(cmd *cobra.Command) error {
+func (o *WhoamiOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
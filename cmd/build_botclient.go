@@ -4,8 +4,7 @@
 package cmd
 
 import (
-	"os"
-
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -54,22 +53,20 @@ func (o *buildBotClientOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *buildBotClientOpts) Run(cmd *cobra.Command) error {
+func (o *buildBotClientOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Load project config.
 	project, err := resolveProject()
 	if err != nil {
-		log.Error().Msgf("Failed to find project: %v", err)
-		os.Exit(1)
+		return err
 	}
 
 	log.Info().Msg("")
 	log.Info().Msg(styles.RenderTitle("Build Bot Client Locally"))
 	log.Info().Msg("")
 
-	// Check for .NET SDK installation and required version (based on SDK version).
-	if err := checkDotnetSdkVersion(project.VersionMetadata.MinDotnetSdkVersion); err != nil {
-		log.Error().Msgf("Failed to resolve .NET version: %s", err)
-		os.Exit(1)
+	// Check for a .NET SDK installation compatible with the SDK and runtime versions.
+	if err := checkDotnetSdkVersion(cmd.Context(), project); err != nil {
+		return err
 	}
 
 	// Resolve backend root path.
@@ -77,8 +74,7 @@ func (o *buildBotClientOpts) Run(cmd *cobra.Command) error {
 
 	// Build the project
 	if err := execChildTask(botClientPath, "dotnet", []string{"build"}); err != nil {
-		log.Error().Msgf("Failed to build the game server .NET project: %s", err)
-		os.Exit(1)
+		return exitcode.Errorf(exitcode.ExitBuildFailed, "failed to build the BotClient .NET project: %w", err)
 	}
 
 	// Server built successfully
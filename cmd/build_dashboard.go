@@ -5,8 +5,8 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -41,7 +41,7 @@ func (o *buildDashboardOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *buildDashboardOpts) Run(cmd *cobra.Command) error {
+func (o *buildDashboardOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Load project config.
 	project, err := resolveProject()
 	if err != nil {
@@ -69,8 +69,7 @@ func (o *buildDashboardOpts) Run(cmd *cobra.Command) error {
 	if !o.skipPnpm {
 		log.Info().Msg("Installing dashboard dependencies...")
 		if err := execChildInteractive(dashboardPath, "pnpm", []string{"install"}); err != nil {
-			log.Error().Msgf("Failed to install LiveOps Dashboard dependencies: %s", err)
-			os.Exit(1)
+			return exitcode.Errorf(exitcode.ExitBuildFailed, "failed to install LiveOps Dashboard dependencies: %w", err)
 		}
 	} else {
 		log.Info().Msg("Skipping pnpm install because of the --skip-pnpm flag")
@@ -79,8 +78,7 @@ func (o *buildDashboardOpts) Run(cmd *cobra.Command) error {
 	// Build the dashboard.
 	buildArgs := append([]string{"build"}, o.extraArgs...)
 	if err := execChildInteractive(dashboardPath, "pnpm", buildArgs); err != nil {
-		log.Error().Msgf("Failed to build the LiveOps Dashboard: %s", err)
-		os.Exit(1)
+		return exitcode.Errorf(exitcode.ExitBuildFailed, "failed to build the LiveOps Dashboard: %w", err)
 	}
 
 	// Built done
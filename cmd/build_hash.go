@@ -0,0 +1,191 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/buildhash"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Name of the Metaplay-specific ignore file (in the build root), in addition to .dockerignore.
+const metaplayIgnoreFileName = ".metaplayignore"
+
+// Compute the content hash of the project's build inputs.
+type buildHashOpts struct {
+	RequiresProject
+
+	flagFormat               string
+	flagCheckRegistry        bool
+	flagEnvironment          string
+	flagNormalizeLineEndings bool
+}
+
+func init() {
+	o := buildHashOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "hash [flags]",
+		Short: "Compute the content hash of the project's docker image build inputs",
+		Run:   runCommand(&o),
+		Long: renderLong(&o, `
+			Compute a stable hash over the inputs of the project's docker image build: the
+			backend and shared code directories, the Dockerfile, the Metaplay SDK version, and
+			the .NET version. Files excluded by the .dockerignore or .metaplayignore in the
+			build root directory are not included.
+
+			The same sources produce the same hash on all operating systems. By default, line
+			endings are normalized before hashing; use --normalize-line-endings=false to hash
+			the files as-is.
+
+			The hash is also available as the '<contenthash>' placeholder in the image tag of
+			'metaplay build image', eg, to skip rebuilding an image that already exists.
+
+			With --check-registry, also check whether an image tagged with the hash already
+			exists in the target environment's registry. The command exits with code 5 if it
+			does not exist.
+
+			Related commands:
+			- 'metaplay build image <contenthash>' to build an image tagged with the hash.
+		`),
+		Example: trimIndent(`
+			# Compute the content hash of the build inputs.
+			metaplay build hash
+
+			# Check whether an image with the content hash exists in environment 'tough-falcons'.
+			metaplay build hash --check-registry -e tough-falcons
+
+			# Output the hash and the registry check result as JSON.
+			metaplay build hash --check-registry -e tough-falcons --format=json
+		`),
+	}
+	buildCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format. Valid values are 'text' or 'json'")
+	flags.BoolVar(&o.flagCheckRegistry, "check-registry", false, "Check whether an image tagged with the hash exists in the environment's registry")
+	flags.StringVarP(&o.flagEnvironment, "environment", "e", "", "Environment whose registry to check with --check-registry")
+	flags.BoolVar(&o.flagNormalizeLineEndings, "normalize-line-endings", true, "Normalize CRLF line endings to LF before hashing")
+}
+
+func (o *buildHashOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q, must be either 'text' or 'json'", o.flagFormat)
+	}
+	if o.flagCheckRegistry && o.flagEnvironment == "" {
+		return fmt.Errorf("--check-registry requires the environment to be specified with --environment")
+	}
+	if o.flagEnvironment != "" && !o.flagCheckRegistry {
+		return fmt.Errorf("--environment can only be used with --check-registry")
+	}
+	return nil
+}
+
+func (o *buildHashOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	project := cmdCtx.Project
+
+	hash, err := computeProjectBuildHash(project, o.flagNormalizeLineEndings)
+	if err != nil {
+		return err
+	}
+
+	// Check whether the image exists in the environment's registry.
+	var imageName string
+	var imageExists bool
+	if o.flagCheckRegistry {
+		envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.flagEnvironment)
+		if err != nil {
+			return err
+		}
+		targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+		envDetails, err := targetEnv.GetDetails()
+		if err != nil {
+			return err
+		}
+		if err := envDetails.Validate(); err != nil {
+			return err
+		}
+		dockerCredentials, err := targetEnv.GetDockerCredentials(envDetails)
+		if err != nil {
+			return fmt.Errorf("failed to get docker credentials: %w", err)
+		}
+
+		imageName = fmt.Sprintf("%s:%s", envDetails.Deployment.EcrRepo, hash)
+		imageExists, err = envapi.RemoteDockerImageExists(dockerCredentials, imageName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.flagFormat == "json" {
+		type buildHashResult struct {
+			Hash        string `json:"hash"`
+			Image       string `json:"image,omitempty"`
+			ImageExists *bool  `json:"imageExists,omitempty"`
+		}
+		result := buildHashResult{Hash: hash}
+		if o.flagCheckRegistry {
+			result.Image = imageName
+			result.ImageExists = &imageExists
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal to JSON: %w", err)
+		}
+		log.Info().Msg(string(resultJSON))
+	} else {
+		log.Info().Msg(hash)
+		if o.flagCheckRegistry {
+			if imageExists {
+				stderrLogger.Info().Msgf("Image %s exists in the registry", styles.RenderTechnical(imageName))
+			} else {
+				stderrLogger.Info().Msgf("Image %s not found in the registry", styles.RenderTechnical(imageName))
+			}
+		}
+	}
+
+	// Only report the missing image with the exit code, to keep the output parseable.
+	if o.flagCheckRegistry && !imageExists {
+		return exitcode.Silent(exitcode.ExitNotFound)
+	}
+	return nil
+}
+
+// Compute the content hash of the project's docker image build inputs, honoring the
+// .dockerignore and .metaplayignore in the build root directory.
+func computeProjectBuildHash(project *metaproj.MetaplayProject, normalizeLineEndings bool) (string, error) {
+	buildRootDir := project.GetBuildRootDir()
+	ignoreMatcher, err := buildhash.ReadIgnoreFiles(
+		filepath.Join(buildRootDir, ".dockerignore"),
+		filepath.Join(buildRootDir, metaplayIgnoreFileName),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	dotnetVersionSegments := project.Config.DotnetRuntimeVersion.Segments()
+	hash, err := buildhash.Compute(buildhash.Inputs{
+		RootDir:        buildRootDir,
+		Dirs:           []string{project.GetBackendDir(), project.GetSharedCodeDir()},
+		Files:          []string{filepath.Join(project.GetSdkRootDir(), "Dockerfile.server")},
+		SdkVersion:     project.VersionMetadata.SdkVersion.String(),
+		DotnetVersion:  fmt.Sprintf("%d.%d", dotnetVersionSegments[0], dotnetVersionSegments[1]),
+		IgnoreMatcher:  ignoreMatcher,
+		NormalizeCRLFs: normalizeLineEndings,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute the build content hash: %w", err)
+	}
+	log.Debug().Msgf("Build content hash: %s", hash)
+	return hash, nil
+}
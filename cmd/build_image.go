@@ -4,28 +4,68 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/buildcache"
+	"github.com/metaplay/cli/pkg/containerutil"
+	"github.com/metaplay/cli/pkg/dotnetutil"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/gitutil"
+	"github.com/metaplay/cli/pkg/metaplay"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
+// Characters allowed in docker image tags.
+var dockerTagCharsRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Docker image label that marks the image as built from a git working tree with uncommitted changes.
+const dockerImageDirtyLabel = metaplay.DockerImageDirtyLabel
+
 // Build docker image for the project.
 type buildDockerImageOpts struct {
 	UsePositionalArgs
 
-	argImageName     string
-	extraArgs        []string
-	flagBuildEngine  string
-	flagArchitecture string
-	flagCommitID     string
-	flagBuildNumber  string
+	argImageName      string
+	extraArgs         []string
+	flagBuildEngine   string
+	flagArchitecture  string
+	flagCommitID      string
+	flagBuildNumber   string
+	flagQuiet         bool
+	flagAllowLatest   bool
+	flagNoLatestCheck bool
+	flagLocalOnly     bool
+	flagOutputImageID string
+	flagSquash        bool
+	flagCompress      string
+	flagAnalyzeCache  bool
+	flagPush          string
+	flagBuildArgs     []string
+	flagProvenance    bool
+	flagProvenanceKey string
+
+	flagStats             bool
+	flagOutputSummary     string
+	flagUseBake           bool
+	flagBakeTarget        string
+	flagEnforceSizeBudget bool
+
+	flagNormalizeLineEndings bool
+	flagTagTimestampFormat   string
 }
 
 func init() {
@@ -37,7 +77,7 @@ func init() {
 
 	cmd := &cobra.Command{
 		Use:     "image [IMAGE] [flags] [-- EXTRA_ARGS]",
-		Aliases: []string{"i"},
+		Aliases: []string{"i", "img", "docker-image"},
 		Short:   "Build a Docker image of the server components that can be deployed in the cloud",
 		Run:     runCommand(&o),
 		Long: renderLong(&o, `
@@ -45,6 +85,77 @@ func init() {
 			The built image contains both the game server (C# project), the LiveOps
 			Dashboard, and the BotClient.
 
+			If the project is in a git repository with uncommitted changes, the commit ID is
+			suffixed with '-dirty' and the image is labeled as dirty. Deploying a dirty image
+			into a production environment requires an extra confirmation.
+
+			Building an image tagged 'latest' is only allowed with --allow-latest or --local-only,
+			eg, for docker-compose setups, and a warning is shown. Automated pipelines building
+			'latest' for a local registry or tests can skip the check and the warning altogether
+			with --no-latest-check, also together with --local-only. Such images can never be
+			pushed or deployed into the cloud, as the tag is mutable and deployments could not be
+			traced back to the exact image. With --local-only, the commit ID and build number are
+			not auto-detected.
+
+			To reduce the image size, --squash squashes the layers into one (podman engine only,
+			docker ignores it with buildkit) and --compress selects the layer compression (buildx
+			engine only). Options not supported by the used build engine are skipped with a
+			warning.
+
+			The image is built with docker or, if docker is not available, with podman. Select
+			the container runtime with the global --container-runtime flag, the
+			METAPLAYCLI_CONTAINER_RUNTIME environment variable, or 'containerRuntime' in the
+			metaplay-project.yaml. With podman, the image is built with 'podman build' in the
+			docker image format, and buildx-only features (--compress, --analyze-cache, and
+			buildx arguments like '--cache-to=type=gha') are not available.
+
+			The ID (sha256 digest) of the built image is shown after the build. Use
+			--output-image-id to also write it into a file, eg, for provenance tracking.
+
+			Use --push ENVIRONMENT to push the built image into the environment's image
+			repository right after a successful build. This is equivalent to running
+			'metaplay image push ENVIRONMENT IMAGE' after the build. The environment is resolved
+			before the build starts, so access problems are reported without waiting for the
+			build. Once pushed, the image can be deployed with 'metaplay deploy server
+			ENVIRONMENT TAG'.
+
+			With --provenance, a signed SLSA provenance attestation is recorded for the image: the
+			builder identity (eg, the CI run URL), the source commit, the build args, and the
+			digests of the build inputs. The attestation is signed with the ed25519 private key
+			given with --provenance-key, or keylessly with the workflow's OIDC identity token when
+			running in GitHub Actions (requires the 'id-token: write' permission). The attestation
+			is stored locally and pushed into the environment's registry next to the image, by
+			--push, 'metaplay image push', and 'metaplay deploy server'. Use 'metaplay deploy
+			server --require-provenance' to verify it before deploying.
+
+			Use --stats to show the build duration, the ratio of build steps served from the layer
+			cache, and the size of the built image, eg, to find out why CI builds are slow. Use
+			--output-summary to write the same measurements into a JSON file, along with the image
+			name and ID. With the buildx engine, the cache usage is tracked from docker's progress
+			output, which replaces docker's own output with one line per completed build step.
+
+			If the build root directory has a docker-bake.hcl file, use --use-bake to build the
+			image with 'docker buildx bake' instead of 'docker buildx build' (buildx engine only).
+			The bake file defines the Dockerfile and the build context of the target (--bake-target,
+			'server' by default), and the CLI fills in the image name, the target platform, the
+			build args, and the labels as with a regular build.
+
+			After the build, the size of the image is reported, before pushing it anywhere: the
+			total size compared to the previous local build of the same image name, and the size
+			of each layer with the Dockerfile step that created it. If 'imageSizeBudgetMB' is set
+			in metaplay-project.yaml, a warning is shown when the image exceeds the budget. Use
+			--enforce-size-budget to fail the build instead, eg, in CI.
+
+			The image tag can contain the following placeholders:
+			- '<timestamp>' is the current time, formatted with --tag-timestamp-format: 'unix'
+			  (default) for unix seconds, 'rfc3339compact' for eg, '20240115T103000Z', or any Go
+			  time layout, eg, '2006-01-02'.
+			- '<date>' is the current date in format 'YYYYMMDD', eg, '20240115'.
+			- '<contenthash>' is the content hash of the build inputs, see 'metaplay build hash'.
+			- '<commit>' is the resolved commit ID, see --commit-id, and '<shortcommit>' its
+			  first 8 characters. Both get the suffix '-dirty' if there are uncommitted changes.
+			All times are in UTC.
+
 			{Arguments}
 
 			Related commands:
@@ -58,6 +169,18 @@ func init() {
 			# Specify only the tag, produces image named '<projectID>:364cff09'.
 			metaplay build image 364cff09
 
+			# Produce a human-sortable tag, eg, 'mygame:20240115-364cff09'.
+			metaplay build image 'mygame:<date>-364cff09'
+
+			# Use a compact RFC3339 timestamp in the tag, eg, '<projectID>:20240115T103000Z'.
+			metaplay build image --tag-timestamp-format=rfc3339compact
+
+			# Tag the image with the content hash of the build inputs, see 'metaplay build hash'.
+			metaplay build image '<contenthash>'
+
+			# Tag the image with the (short) commit ID, eg, '<projectID>:364cff09'.
+			metaplay build image '<projectID>:<shortcommit>'
+
 			# Build a project from another directory.
 			metaplay -p ../MyProject build image
 
@@ -67,24 +190,141 @@ func init() {
 			# Build using docker's BuildKit engine (in case buildx isn't available).
 			metaplay build image mygame:364cff09 --engine=buildkit
 
+			# Build with podman instead of docker.
+			metaplay build image mygame:364cff09 --container-runtime=podman
+
 			# Build an image to be run on an arm64 machine.
 			metaplay build image mygame:364cff09 --platform=arm64
 
+			# Pass custom build args to Dockerfile.server.
+			metaplay build image mygame:364cff09 --build-arg FOO=BAR --build-arg ENABLE_FEATURE=1
+
 			# Pass extra arguments to the docker build.
-			metaplay build image mygame:364cff09 -- --build-arg FOO=BAR
+			metaplay build image mygame:364cff09 -- --no-cache
+
+			# Only show docker's output if the build fails.
+			metaplay build image mygame:364cff09 --quiet
+
+			# Write the ID (sha256 digest) of the built image into a file.
+			metaplay build image mygame:364cff09 --output-image-id=image-id.txt
+
+			# Build with zstd-compressed layers (buildx only) to reduce the image size.
+			metaplay build image mygame:364cff09 --compress=zstd
+
+			# Analyze which build steps were served from the layer cache and get suggestions
+			# for improving the Dockerfile (buildx only).
+			metaplay build image mygame:364cff09 --analyze-cache
+
+			# Build the image and push it into the environment 'tough-falcons' in one step.
+			metaplay build image mygame:364cff09 --push tough-falcons
+
+			# Build and push the image with a provenance attestation signed with a key file.
+			metaplay build image mygame:364cff09 --provenance --provenance-key=provenance.key --push tough-falcons
+
+			# In GitHub Actions, sign the provenance keylessly with the workflow's identity.
+			metaplay build image mygame:364cff09 --provenance --push tough-falcons
+
+			# Show the build duration, cache hit ratio, and image size, and write them into a
+			# JSON file for CI.
+			metaplay build image mygame:364cff09 --stats --output-summary=build-summary.json
+
+			# Build the 'server' target of the build root's docker-bake.hcl with 'docker buildx bake'.
+			metaplay build image mygame:364cff09 --use-bake
+
+			# Build another target of the bake file.
+			metaplay build image mygame:364cff09 --use-bake --bake-target=server-debug
+
+			# Fail the build if the image exceeds the project's imageSizeBudgetMB.
+			metaplay build image mygame:364cff09 --enforce-size-budget
+
+			# Build a throwaway image for local use only, tagged 'latest'.
+			metaplay build image mygame:latest --local-only
+
+			# Build an image tagged 'latest' for a local registry in an automated pipeline.
+			metaplay build image mygame:latest --no-latest-check
 		`),
 	}
 
 	buildCmd.AddCommand(cmd)
 
 	flags := cmd.Flags()
-	flags.StringVar(&o.flagBuildEngine, "engine", "", "Docker build engine to use ('buildx' or 'buildkit'), auto-detected if not specified")
+	flags.StringVar(&o.flagBuildEngine, "engine", "", "Build engine to use ('buildx' or 'buildkit' with docker, 'podman' with podman), auto-detected if not specified")
 	flags.StringVar(&o.flagArchitecture, "architecture", "amd64", "Architecture of build target, 'amd64' or 'arm64'")
 	flags.StringVar(&o.flagCommitID, "commit-id", "", "Git commit SHA hash or similar, eg, '7d1ebc858b'")
 	flags.StringVar(&o.flagBuildNumber, "build-number", "", "Number identifying this build, eg, '715'")
+	flags.BoolVar(&o.flagQuiet, "quiet", false, "Hide the output from docker unless the build fails")
+	flags.BoolVar(&o.flagAllowLatest, "allow-latest", false, "Allow building an image tagged 'latest' (cannot be pushed or deployed into the cloud)")
+	flags.BoolVar(&o.flagNoLatestCheck, "no-latest-check", false, "Skip the check and the warning for building an image tagged 'latest', eg, in automated pipelines (cannot be pushed or deployed into the cloud)")
+	flags.StringVar(&o.flagOutputImageID, "output-image-id", "", "Write the ID (sha256 digest) of the built image into the given file")
+	flags.BoolVar(&o.flagSquash, "squash", false, "Squash the image layers into one (podman engine only)")
+	flags.StringVar(&o.flagCompress, "compress", "", "Compression of the image layers: 'gzip', 'zstd', 'estargz', or 'uncompressed' (buildx engine only)")
+	flags.StringVar(&o.flagTagTimestampFormat, "tag-timestamp-format", "unix", "Format of <timestamp> in the image tag: 'unix', 'rfc3339compact', or a Go time layout")
+	flags.BoolVar(&o.flagNormalizeLineEndings, "normalize-line-endings", true, "Normalize CRLF line endings to LF when computing <contenthash>")
+	flags.BoolVar(&o.flagAnalyzeCache, "analyze-cache", false, "Analyze the layer cache usage of the build and suggest Dockerfile optimizations (buildx engine only)")
+	flags.StringVar(&o.flagPush, "push", "", "Push the built image into the given environment's image repository")
+	flags.StringArrayVar(&o.flagBuildArgs, "build-arg", nil, "Custom build arg 'KEY=VALUE' to pass to Dockerfile.server, can be repeated (overrides the CLI's build arg with the same key)")
+	flags.BoolVar(&o.flagProvenance, "provenance", false, "Record a signed provenance attestation for the image, pushed along with the image")
+	flags.StringVar(&o.flagProvenanceKey, "provenance-key", "", "Path to the ed25519 private key (PEM) to sign the provenance with (default: keyless signing in GitHub Actions)")
+	flags.BoolVar(&o.flagStats, "stats", false, "Show the build duration, the layer cache hit ratio (buildx engine only), and the image size after the build")
+	flags.StringVar(&o.flagOutputSummary, "output-summary", "", "Write a JSON summary of the build (image, duration, cache hits, and size) into the given file")
+	flags.BoolVar(&o.flagUseBake, "use-bake", false, "Build with 'docker buildx bake' using docker-bake.hcl in the build root directory (buildx engine only)")
+	flags.StringVar(&o.flagBakeTarget, "bake-target", "server", "Target in docker-bake.hcl to build with --use-bake")
+	flags.BoolVar(&o.flagEnforceSizeBudget, "enforce-size-budget", false, "Fail if the built image exceeds the project's 'imageSizeBudgetMB' (the image is not pushed)")
+	flags.BoolVar(&o.flagLocalOnly, "local-only", false, "Build a throwaway image for local use only: allows the 'latest' tag and skips commit ID and build number detection")
+	cmd.RegisterFlagCompletionFunc("push", func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return completeEnvironmentIDs(), cobra.ShellCompDirectiveNoFileComp
+	})
 }
 
 func (o *buildDockerImageOpts) Prepare(cmd *cobra.Command, args []string) error {
+	// Validate the timestamp format: Go layouts must produce something that can be used in a tag.
+	if o.flagTagTimestampFormat == "" {
+		return fmt.Errorf("--tag-timestamp-format must not be empty")
+	}
+	if formatted := formatTagTimestamp(time.Now(), o.flagTagTimestampFormat); !dockerTagCharsRegex.MatchString(formatted) {
+		return fmt.Errorf("invalid --tag-timestamp-format %q: produces '%s', which is not valid in a docker image tag", o.flagTagTimestampFormat, formatted)
+	}
+
+	// Validate compression.
+	validCompressions := []string{"gzip", "zstd", "estargz", "uncompressed"}
+	if o.flagCompress != "" && !contains(validCompressions, o.flagCompress) {
+		return fmt.Errorf("invalid --compress %q, must be one of %v", o.flagCompress, validCompressions)
+	}
+
+	// Validate custom build args, and warn about overriding the ones set by the CLI.
+	for _, buildArg := range o.flagBuildArgs {
+		key, _, err := metaplay.ParseBuildArg(buildArg)
+		if err != nil {
+			return err
+		}
+		if contains(metaplay.BuiltinBuildArgs, key) {
+			log.Warn().Msgf("Build arg %s overrides the value set by the CLI, make sure this is intended", key)
+		}
+	}
+
+	if cmd.Flags().Changed("bake-target") && !o.flagUseBake {
+		return fmt.Errorf("--bake-target can only be used with --use-bake")
+	}
+	if o.flagUseBake && o.flagBakeTarget == "" {
+		return fmt.Errorf("--bake-target must not be empty")
+	}
+
+	// Local-only images are never pushed.
+	if o.flagPush != "" && o.flagLocalOnly {
+		return fmt.Errorf("--push cannot be used with --local-only")
+	}
+	if o.flagAllowLatest && o.flagNoLatestCheck {
+		return fmt.Errorf("--allow-latest cannot be used with --no-latest-check, which skips the 'latest' check altogether")
+	}
+
+	// Provenance is only recorded for images that can be deployed.
+	if o.flagProvenanceKey != "" && !o.flagProvenance {
+		return fmt.Errorf("--provenance-key can only be used with --provenance")
+	}
+	if o.flagProvenance && o.flagLocalOnly {
+		return fmt.Errorf("--provenance cannot be used with --local-only")
+	}
+
 	// Handle image name.
 	if o.argImageName == "" {
 		o.argImageName = "<projectID>:<timestamp>"
@@ -98,26 +338,70 @@ func (o *buildDockerImageOpts) Prepare(cmd *cobra.Command, args []string) error
 	return nil
 }
 
-func (o *buildDockerImageOpts) Run(cmd *cobra.Command) error {
-	log.Info().Msg("")
-	log.Info().Msg(styles.RenderTitle("Build Docker Image"))
-	log.Info().Msg("")
-
+func (o *buildDockerImageOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Find & load the project config file.
 	project, err := resolveProject()
 	if err != nil {
 		return err
 	}
 
-	// Resolve image name to use: fill in <timestamp> with current unix time
-	// and <projectID> with the project's human ID.
+	// Enforcing the size budget requires one to be configured.
+	if o.flagEnforceSizeBudget && project.Config.ImageSizeBudgetMB == 0 {
+		return exitcode.Errorf(exitcode.ExitUsage, "--enforce-size-budget requires 'imageSizeBudgetMB' to be set in metaplay-project.yaml")
+	}
+
+	// Resolve image name to use: fill in <timestamp> and <date> with the current time, <projectID>
+	// with the project's human ID, and <contenthash> with the hash of the build inputs. The
+	// <commit> and <shortcommit> are filled in once the commit ID is resolved.
 	log.Debug().Msgf("Image name template: %s", o.argImageName)
-	imageName := strings.Replace(o.argImageName, "<timestamp>", fmt.Sprintf("%d", time.Now().Unix()), -1)
+	now := time.Now().UTC()
+	imageName := strings.Replace(o.argImageName, "<timestamp>", formatTagTimestamp(now, o.flagTagTimestampFormat), -1)
+	imageName = strings.Replace(imageName, "<date>", now.Format("20060102"), -1)
 	imageName = strings.Replace(imageName, "<projectID>", project.Config.ProjectHumanID, -1)
+	if strings.Contains(imageName, "<contenthash>") {
+		contentHash, err := computeProjectBuildHash(project, o.flagNormalizeLineEndings)
+		if err != nil {
+			return err
+		}
+		imageName = strings.Replace(imageName, "<contenthash>", contentHash, -1)
+	}
 
+	// Images tagged 'latest' are only allowed for local use. With --no-latest-check, the check
+	// and the warning are skipped, but the image still cannot be pushed.
 	if strings.HasSuffix(imageName, ":latest") {
-		log.Error().Msg("Building docker image with 'latest' tag is not allowed. Use a commit hash or timestamp instead.")
-		os.Exit(1)
+		if o.flagPush != "" {
+			return checkImageTagNotLatest("latest")
+		}
+		if !o.flagNoLatestCheck {
+			if !o.flagAllowLatest && !o.flagLocalOnly {
+				return exitcode.Errorf(exitcode.ExitUsage, "building docker image with 'latest' tag is not allowed: %s; use a commit hash or timestamp tag instead, or use --allow-latest or --local-only (or --no-latest-check in automated pipelines) for images only used locally, eg, with a local registry", latestTagExplanation)
+			}
+			log.Warn().Msgf("Building an image tagged 'latest' for local use only: %s. The image cannot be pushed or deployed into the cloud.", latestTagExplanation)
+		}
+	}
+
+	// Resolve the environment to push to before building, so that configuration and
+	// authentication problems are reported without waiting for the build to complete.
+	var pushEnv *metaplay.Environment
+	if o.flagPush != "" {
+		envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.flagPush)
+		if err != nil {
+			return err
+		}
+		pushEnv = metaplay.NewEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+	}
+
+	// Resolve the provenance signer before building, so that a missing key is reported early.
+	var provenanceSigner metaplay.ProvenanceSigner
+	if o.flagProvenance {
+		if o.flagProvenanceKey != "" {
+			provenanceSigner, err = metaplay.LoadProvenanceSigningKey(o.flagProvenanceKey)
+		} else {
+			provenanceSigner, err = metaplay.NewKeylessProvenanceSigner()
+		}
+		if err != nil {
+			return exitcode.New(exitcode.ExitUsage, err)
+		}
 	}
 
 	// Log extra arguments.
@@ -125,182 +409,302 @@ func (o *buildDockerImageOpts) Run(cmd *cobra.Command) error {
 		log.Debug().Msgf("Extra args to docker: %s", strings.Join(o.extraArgs, " "))
 	}
 
-	// Auto-detect git commit ID
+	// Auto-detect git commit ID (not for local-only builds)
 	commitId := o.flagCommitID
 	commitIdBadge := ""
-	if commitId == "" {
-		commitId = detectEnvVar([]string{
+	if commitId == "" && o.flagLocalOnly {
+		commitId = "none"
+		commitIdBadge = "(local-only)"
+	} else if commitId == "" {
+		var commitIdKey string
+		commitIdKey, commitId = detectEnvVarWithKey([]string{
 			"GIT_COMMIT", "GITHUB_SHA", "CI_COMMIT_SHA", "CIRCLE_SHA1", "TRAVIS_COMMIT",
 			"BUILD_SOURCEVERSION", "BITBUCKET_COMMIT", "BUILD_VCS_NUMBER", "BUILDKITE_COMMIT", "DRONE_COMMIT_SHA",
 			"SEMAPHORE_GIT_SHA",
 		})
 		if commitId != "" {
-			commitIdBadge = styles.RenderMuted("(auto-detected)")
+			commitIdBadge = fmt.Sprintf("(from %s)", commitIdKey)
+		} else if gitCommitId, err := gitutil.GetCurrentCommitSHA(project.RelativeDir); err == nil {
+			// Not in CI, use the commit checked out in the local git repository.
+			commitId = gitCommitId
+			commitIdBadge = "(from git)"
 		} else {
+			log.Debug().Msgf("Unable to resolve commit ID from git: %v", err)
 			commitId = "none" // default if not specified
-			commitIdBadge = styles.RenderWarning("[unable to auto-detect; specify with --commit-id=<id>]")
+			commitIdBadge = "[unable to auto-detect; specify with --commit-id=<id>]"
+		}
+	}
+
+	// Detect if the image is built from uncommitted changes, in which case the commit ID
+	// doesn't fully describe the source code. Mark the commit ID and image as dirty.
+	// Not being able to check (eg, not in a git repository) is not an error.
+	isDirty := false
+	if !o.flagLocalOnly {
+		isDirty, err = gitutil.GetIsDirty(project.RelativeDir)
+		if err != nil {
+			log.Debug().Msgf("Unable to check git working tree status (not a git repository?): %v", err)
+		}
+	}
+	if isDirty {
+		commitId = commitId + "-dirty"
+		commitIdBadge = "[uncommitted changes]"
+	}
+
+	// Fill in <commit> and <shortcommit> in the image name with the resolved commit ID.
+	if strings.Contains(imageName, "<commit>") || strings.Contains(imageName, "<shortcommit>") {
+		if strings.HasPrefix(commitId, "none") {
+			return exitcode.Errorf(exitcode.ExitUsage, "the image name %q uses the commit ID but it could not be resolved, specify it with --commit-id=<id>", o.argImageName)
+		}
+		if !dockerTagCharsRegex.MatchString(commitId) {
+			return exitcode.Errorf(exitcode.ExitUsage, "the commit ID %q cannot be used in an image tag, it must only contain letters, digits, '_', '.', and '-'", commitId)
 		}
+		imageName = strings.Replace(imageName, "<commit>", commitId, -1)
+		imageName = strings.Replace(imageName, "<shortcommit>", shortCommitID(commitId), -1)
 	}
 
-	// Auto-detect build number
+	// Auto-detect build number (not for local-only builds)
 	buildNumber := o.flagBuildNumber
 	buildNumberBadge := ""
-	if buildNumber == "" {
-		buildNumber = detectEnvVar([]string{
+	if buildNumber == "" && o.flagLocalOnly {
+		buildNumber = "none"
+		buildNumberBadge = "(local-only)"
+	} else if buildNumber == "" {
+		var buildNumberKey string
+		buildNumberKey, buildNumber = detectEnvVarWithKey([]string{
 			"BUILD_NUMBER", "GITHUB_RUN_NUMBER", "CI_PIPELINE_IID", "CIRCLE_BUILD_NUM", "TRAVIS_BUILD_NUMBER",
 			"BUILD_BUILDNUMBER", "BITBUCKET_BUILD_NUMBER", "BUILDKITE_BUILD_NUMBER", "DRONE_BUILD_NUMBER",
 			"SEMAPHORE_BUILD_NUMBER",
 		})
 		if buildNumber != "" {
-			buildNumberBadge = styles.RenderMuted("(auto-detected)")
+			buildNumberBadge = fmt.Sprintf("(from %s)", buildNumberKey)
 		} else {
 			buildNumber = "none" // default if not specified
-			buildNumberBadge = styles.RenderWarning("[unable to auto-detect; specify with --commit-number=<number>]")
+			buildNumberBadge = "[unable to auto-detect; specify with --commit-number=<number>]"
 		}
 	}
 
-	// Resolve docker build root directory. All other paths need to be made relative to it.
-	buildRootDir := project.GetBuildRootDir()
-
-	// Check that sdkRoot is a valid directory
-	sdkRootPath := project.GetSdkRootDir()
-	if _, err := os.Stat(sdkRootPath); os.IsNotExist(err) {
-		log.Error().Msgf("The Metaplay SDK directory '%s' does not exist.", sdkRootPath)
-		os.Exit(2)
-	}
-
-	dockerFilePath := filepath.Join(sdkRootPath, "Dockerfile.server")
-	if _, err := os.Stat(dockerFilePath); os.IsNotExist(err) {
-		log.Error().Msgf("Cannot locate Dockerfile.server at %s.", dockerFilePath)
-		os.Exit(2)
-	}
-
-	// Check project root directory.
-	projectBackendDir := project.GetBackendDir()
-	if _, err := os.Stat(projectBackendDir); os.IsNotExist(err) {
-		log.Error().Msgf("Unable to find project backend in '%s'.", projectBackendDir)
-		os.Exit(2)
-	}
-
-	// Check SharedCode directory.
-	sharedCodeDir := project.GetSharedCodeDir()
-	if _, err := os.Stat(sharedCodeDir); os.IsNotExist(err) {
-		log.Error().Msgf("The shared code directory (%s) does not exist.", sharedCodeDir)
-		os.Exit(2)
+	// Check that the SDK, backend, and shared code exist.
+	if err := metaplay.CheckBuildInputs(project); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return exitcode.New(exitcode.ExitNotFound, err)
+		}
+		return err
 	}
 
 	// Resolve target platform.
-	validArchitectures := []string{"amd64", "arm64"}
-	if !contains(validArchitectures, o.flagArchitecture) {
-		log.Error().Msgf("Invalid architecture '%s'. Must be one of %v.", o.flagArchitecture, validArchitectures)
-		os.Exit(2)
+	if !contains(metaplay.BuildArchitectures, o.flagArchitecture) {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid architecture '%s', must be one of %v", o.flagArchitecture, metaplay.BuildArchitectures)
 	}
 	platform := fmt.Sprintf("linux/%s", o.flagArchitecture)
 
-	// Check that docker is installed and running with a 5 second timeout
-	log.Debug().Msgf("Check if docker is available")
-	err = checkDockerAvailable()
+	// Check that the container runtime (docker or podman) is installed and running.
+	containerRuntime, err := resolveContainerRuntime(cmd.Context(), project)
 	if err != nil {
 		return err
 	}
 
-	// Resolve docker build engine
-	log.Debug().Msg("Resolve docker build engine")
-	buildEngine, err := resolveBuildEngine(o.flagBuildEngine)
-	if err != nil {
-		log.Error().Msgf("Failed to resolve docker build engine: %v", err)
-		os.Exit(1)
+	// Pushing the image and enforcing its size budget use the runtime's API, so check it
+	// before building.
+	if pushEnv != nil || o.flagEnforceSizeBudget {
+		if err := containerRuntime.CheckAPIAvailable(); err != nil {
+			return err
+		}
 	}
 
-	// Print build info.
-	log.Info().Msgf("Project ID:          %s", styles.RenderTechnical(project.Config.ProjectHumanID))
-	log.Info().Msgf("Docker image:        %s", styles.RenderTechnical(imageName))
-	log.Info().Msgf("Commit ID            %s %s", styles.RenderTechnical(commitId), commitIdBadge)
-	log.Info().Msgf("Build number:        %s %s", styles.RenderTechnical(buildNumber), buildNumberBadge)
-	log.Info().Msgf("Target platform:     %s", styles.RenderTechnical(platform))
-	log.Info().Msgf("Docker build engine: %s", styles.RenderTechnical(buildEngine))
-
-	// Rebase paths to be relative to docker build root.
-	rebasedSdkRoot, err := rebasePath(sdkRootPath, buildRootDir)
+	// Resolve build engine
+	log.Debug().Msg("Resolve build engine")
+	buildEngine, err := resolveBuildEngine(o.flagBuildEngine, containerRuntime)
 	if err != nil {
-		log.Error().Msgf("Failed to resolve relative path to MetaplaySDK/ from build root: %v", err)
-		os.Exit(2)
+		return exitcode.New(exitcode.ExitUsage, err)
 	}
-	rebasedDockerFilePath, err := rebasePath(dockerFilePath, buildRootDir)
-	if err != nil {
-		log.Error().Msgf("Failed to resolve relative path to Dockerfile.server from build root: %v", err)
-		os.Exit(2)
-	}
-	rebasedProjectRoot, err := rebasePath(project.RelativeDir, buildRootDir)
-	if err != nil {
-		log.Error().Msgf("Failed to resolve relative path to project root from build root: %v", err)
-		os.Exit(2)
+	if err := checkBuildxOnlyArgs(o.extraArgs, buildEngine); err != nil {
+		return exitcode.New(exitcode.ExitUsage, err)
 	}
 
-	// Rebase paths relative to project root dir (where metaplay-project.yaml is located).
-	rebasedBackendDir, err := rebasePath(projectBackendDir, project.RelativeDir)
-	if err != nil {
-		log.Error().Msgf("Failed to resolve relative path to project backend directory from project root: %v", err)
-		os.Exit(2)
+	// Resolve whether to build with 'docker buildx bake'.
+	bakeTarget := ""
+	buildEngineDesc := buildEngine
+	if o.flagUseBake {
+		if buildEngine != "buildx" {
+			return exitcode.Errorf(exitcode.ExitUsage, "--use-bake requires the buildx build engine, got %s", buildEngine)
+		}
+		if !metaplay.HasBakeFile(project) {
+			return exitcode.Errorf(exitcode.ExitNotFound, "--use-bake requires %s in the build root directory %s", metaplay.BakeFileName, project.GetBuildRootDir())
+		}
+		bakeTarget = o.flagBakeTarget
+		buildEngineDesc = fmt.Sprintf("%s (bake target '%s')", buildEngine, bakeTarget)
+	} else if buildEngine == "buildx" && metaplay.HasBakeFile(project) {
+		log.Info().Msgf("Found %s in the build root directory, use --use-bake to build with 'docker buildx bake'", metaplay.BakeFileName)
 	}
-	rebasedSharedCodeDir, err := rebasePath(sharedCodeDir, project.RelativeDir)
-	if err != nil {
-		log.Error().Msgf("Failed to resolve relative path to project shared code directory from project root: %v", err)
-		os.Exit(2)
+
+	// Print build info.
+	log.Info().Msg("")
+	tui.PrintSummaryTable("Build Docker Image", [][2]string{
+		{"Project ID", project.Config.ProjectHumanID},
+		{"Docker image", imageName},
+		{"Commit ID", strings.TrimSpace(commitId + " " + commitIdBadge)},
+		{"Build number", strings.TrimSpace(buildNumber + " " + buildNumberBadge)},
+		{"Target platform", platform},
+		{"Container runtime", containerRuntime.String()},
+		{"Build engine", buildEngineDesc},
+	})
+	if isDirty {
+		log.Info().Msg("")
+		log.Warn().Msg(styles.RenderWarning("WARNING: The git working tree has uncommitted changes! The built image does not match the commit ID."))
 	}
 
-	// Silence docker's recomendation messages at end-of-build.
-	var dockerEnv []string = os.Environ()
-	dockerEnv = append(dockerEnv, "DOCKER_CLI_HINTS=false")
+	// Cache analysis relies on the rawjson progress output of buildx.
+	if o.flagAnalyzeCache && buildEngine != "buildx" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--analyze-cache requires the buildx build engine, got %s", buildEngine)
+	}
 
-	// Handle build engine differences.
-	var buildEngineArgs []string
-	if buildEngine == "buildkit" {
-		dockerEnv = append(dockerEnv, "DOCKER_BUILDKIT=1")
-		buildEngineArgs = []string{"build"}
-	} else if buildEngine == "buildx" {
-		buildEngineArgs = []string{"buildx", "build", "--load"}
-	} else {
-		log.Panic().Msgf("Unsupported docker build engine: %s", buildEngine)
+	// Options unsupported by the build engine are skipped.
+	squash := o.flagSquash
+	if squash && buildEngine != "podman" {
+		log.Warn().Msgf("--squash is not supported by the %s build engine, skipping it. Use a multi-stage Dockerfile or --compress (buildx engine) to reduce the image size instead.", buildEngine)
+		squash = false
+	}
+	compress := o.flagCompress
+	if compress != "" && buildEngine != "buildx" {
+		log.Warn().Msgf("--compress is not supported by the %s build engine, skipping it.", buildEngine)
+		compress = ""
 	}
 
-	// Resolve .NET runtime version to build project for, expects '<major>.<minor>'.
+	// Cross-check against the locally installed .NET runtime. The image is built with the .NET
+	// SDK inside docker, so a mismatch only affects local builds and is not an error.
 	projectDotnetVersionSegments := project.Config.DotnetRuntimeVersion.Segments()
 	projectDotnetVersion := fmt.Sprintf("%d.%d", projectDotnetVersionSegments[0], projectDotnetVersionSegments[1])
+	if dotnetPath, err := exec.LookPath("dotnet"); err == nil {
+		localRuntimeVersion, err := dotnetutil.GetRuntimeVersion(dotnetPath)
+		if err != nil {
+			log.Debug().Msgf("Unable to resolve the local .NET runtime version: %v", err)
+		} else if localRuntimeVersion.Segments()[0] < projectDotnetVersionSegments[0] {
+			log.Warn().Msgf("Local .NET runtime %s is older than the project's .NET %s. The image build is not affected, but running the project locally (eg, 'metaplay dev server') requires .NET %s.", localRuntimeVersion, projectDotnetVersion, projectDotnetVersion)
+		}
+	}
 
-	// Resolve final docker build invocation
-	dockerArgs := append(
-		buildEngineArgs,
-		[]string{
-			"--pull",
-			"-t", imageName,
-			"-f", filepath.ToSlash(rebasedDockerFilePath),
-			"--platform", platform,
-			"--build-arg", "SDK_ROOT=" + filepath.ToSlash(rebasedSdkRoot),
-			"--build-arg", "PROJECT_ROOT=" + filepath.ToSlash(rebasedProjectRoot),
-			"--build-arg", "BACKEND_DIR=" + filepath.ToSlash(rebasedBackendDir),
-			"--build-arg", "SHARED_CODE_DIR=" + filepath.ToSlash(rebasedSharedCodeDir),
-			"--build-arg", "METAPLAY_DOTNET_SDK_VERSION=" + projectDotnetVersion,
-			"--build-arg", fmt.Sprintf("PROJECT_ID=%s", project.Config.ProjectHumanID),
-			"--build-arg", fmt.Sprintf("BUILD_NUMBER=%s", buildNumber),
-			"--build-arg", fmt.Sprintf("COMMIT_ID=%s", commitId),
-		}...,
-	)
-	dockerArgs = append(dockerArgs, o.extraArgs...)
-	dockerArgs = append(dockerArgs, ".")
-	log.Info().Msg("")
-	log.Info().Msgf(styles.RenderMuted("docker %s"), strings.Join(dockerArgs, " "))
-	log.Info().Msg("")
+	// Run the docker build. In quiet mode, only show docker's output if the build fails.
+	buildOpts := metaplay.BuildImageOptions{
+		Project:      project,
+		ImageName:    imageName,
+		CommitID:     commitId,
+		BuildNumber:  buildNumber,
+		IsDirty:      isDirty,
+		Architecture: o.flagArchitecture,
+		Runtime:      containerRuntime,
+		Engine:       buildEngine,
+		Squash:       squash,
+		Compress:     compress,
+		BakeTarget:   bakeTarget,
+		BuildArgs:    o.flagBuildArgs,
+		ExtraArgs:    o.extraArgs,
+		Progress: metaplay.ProgressCallbacks{
+			OnLog: func(line string) {
+				if o.flagQuiet {
+					log.Debug().Msg(line)
+				} else {
+					log.Info().Msg("")
+					log.Info().Msg(styles.RenderMuted(line))
+					log.Info().Msg("")
+				}
+			},
+		},
+	}
+	var quietOutput bytes.Buffer
+	if o.flagQuiet {
+		buildOpts.Stdout = &quietOutput
+		buildOpts.Stderr = &quietOutput
+	} else {
+		buildOpts.Stdout = os.Stdout
+		buildOpts.Stderr = os.Stderr
+	}
+	// With --analyze-cache, parse docker's progress output to track the cache usage of each step.
+	// The build stats also include the cache usage when it's available (buildx only).
+	measureBuild := o.flagStats || o.flagOutputSummary != ""
+	var cacheAnalysis *cacheAnalysisRun
+	if o.flagAnalyzeCache || (measureBuild && buildEngine == "buildx") {
+		cacheAnalysis = startCacheAnalysis(imageName, o.flagQuiet)
+		buildOpts.ExtraArgs = append(slices.Clone(o.extraArgs), "--progress=rawjson")
+		buildOpts.Stderr = cacheAnalysis
+	}
+	buildStartTime := time.Now()
+	buildResult, err := metaplay.BuildImage(cmd.Context(), buildOpts)
+	buildDuration := time.Since(buildStartTime)
+	var analysis *buildcache.Analysis
+	if cacheAnalysis != nil {
+		analysis = cacheAnalysis.finish(project, err == nil, o.flagAnalyzeCache)
+	}
+	if errors.Is(err, metaplay.ErrBuildFailed) {
+		if o.flagQuiet {
+			os.Stderr.Write(quietOutput.Bytes())
+		}
+		return exitcode.New(exitcode.ExitBuildFailed, err)
+	} else if err != nil {
+		return err
+	}
 
-	// Execute the docker build
-	if err := executeCommand(buildRootDir, dockerEnv, "docker", dockerArgs...); err != nil {
-		log.Error().Msgf("Docker build failed: %v", err)
-		os.Exit(1)
+	// Resolve the ID of the built image and write it into the output file, if requested.
+	imageID := buildResult.ImageID
+	if o.flagOutputImageID != "" {
+		if err := os.WriteFile(o.flagOutputImageID, []byte(imageID+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write image ID to %s: %w", o.flagOutputImageID, err)
+		}
 	}
 
 	log.Info().Msg("")
 	log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Successfully built docker image"), styles.RenderTechnical(imageName))
+	log.Info().Msgf("Image ID: %s", styles.RenderTechnical(imageID))
+
+	// Measure the image size once, for the size report and the build measurements. Without
+	// the runtime's API (podman without the API socket), the size is not measured. Failing to
+	// measure the size only fails the build if the size budget is enforced.
+	var imageSize *envapi.DockerImageSize
+	if err := containerRuntime.CheckAPIAvailable(); err != nil {
+		log.Debug().Msgf("Skip measuring the image size: %v", err)
+	} else {
+		imageSize, err = envapi.ReadLocalDockerImageSize(cmd.Context(), imageName)
+		if err != nil {
+			if o.flagEnforceSizeBudget {
+				return err
+			}
+			log.Warn().Msgf("Unable to measure the image size: %v", err)
+		}
+	}
+
+	// Show the build measurements for --stats and --output-summary.
+	if measureBuild {
+		summary := measureImageBuild(buildOpts, buildResult, platform, buildDuration, analysis, imageSize)
+		if o.flagStats {
+			log.Info().Msg("")
+			printBuildStats(summary)
+		}
+		if o.flagOutputSummary != "" {
+			if err := writeBuildSummary(o.flagOutputSummary, summary); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Report the image size before pushing it anywhere.
+	if imageSize != nil {
+		if overBudget := printImageSizeReport(imageSize, project.Config.ImageSizeBudgetMB); overBudget && o.flagEnforceSizeBudget {
+			return exitcode.Errorf(exitcode.ExitBuildFailed, "image %s exceeds the size budget of %d MB", imageName, project.Config.ImageSizeBudgetMB)
+		}
+	}
 	log.Info().Msg("")
+
+	// Record the signed provenance of the image, to be pushed along with it.
+	if provenanceSigner != nil {
+		if err := recordImageProvenance(cmd, buildOpts, buildResult, provenanceSigner, buildStartTime); err != nil {
+			return err
+		}
+	}
+
+	// Push the image into the target environment, if requested.
+	if pushEnv != nil {
+		return pushBuiltImage(cmd, pushEnv, imageName)
+	}
+
 	log.Info().Msg("You can deploy the image to a cloud environment using:")
 	log.Info().Msgf(styles.RenderTechnical("  metaplay deploy server ENVIRONMENT %s"), imageName)
 
@@ -313,6 +717,33 @@ func (o *buildDockerImageOpts) Run(cmd *cobra.Command) error {
 	return nil
 }
 
+// Shorten the commit ID for the <shortcommit> placeholder of the image tag. The '-dirty'
+// suffix of images built from uncommitted changes is retained.
+func shortCommitID(commitId string) string {
+	const shortLength = 8
+	baseId, isDirty := strings.CutSuffix(commitId, "-dirty")
+	if len(baseId) > shortLength {
+		baseId = baseId[:shortLength]
+	}
+	if isDirty {
+		return baseId + "-dirty"
+	}
+	return baseId
+}
+
+// Format the time for the <timestamp> placeholder of the image tag. The format is 'unix',
+// 'rfc3339compact', or a Go time layout.
+func formatTagTimestamp(t time.Time, format string) string {
+	switch format {
+	case "unix":
+		return fmt.Sprintf("%d", t.Unix())
+	case "rfc3339compact":
+		return t.Format("20060102T150405Z")
+	default:
+		return t.Format(format)
+	}
+}
+
 func contains(slice []string, value string) bool {
 	for _, v := range slice {
 		if v == value {
@@ -322,35 +753,110 @@ func contains(slice []string, value string) bool {
 	return false
 }
 
-func detectEnvVar(keys []string) string {
+// Return the name and value of the first of the given environment variables that is set,
+// or empty strings if none are set.
+func detectEnvVarWithKey(keys []string) (key, value string) {
 	for _, key := range keys {
 		if val, ok := os.LookupEnv(key); ok {
-			return val
+			return key, val
 		}
 	}
-	return ""
+	return "", ""
 }
 
-func resolveBuildEngine(engine string) (string, error) {
-	validBuildEngines := []string{"buildx", "buildkit"}
+// Default docker build engine for each CI system (as returned by ciEnvironment()).
+// CI systems not listed here (and local builds) use buildx.
+var ciDefaultBuildEngines = map[string]string{
+	"bitbucket":              "buildkit", // Bitbucket doesn't support buildx
+	"github-actions":         "buildx",   // Linux runners have buildx pre-installed
+	"github-actions-windows": "buildkit", // Windows and macOS runners don't have buildx pre-installed
+	"github-actions-macos":   "buildkit",
+	"gitlab":                 "buildx",
+	"circleci":               "buildx",
+	"jenkins":                "buildx",
+}
 
-	// If not specified, auto-detect
+// Detect the CI system the CLI is running in, based on the environment variables set by
+// the CI systems. Returns an empty string if not running in a known CI system.
+func ciEnvironment() string {
+	switch {
+	case os.Getenv("BITBUCKET_PIPELINE_UUID") != "":
+		return "bitbucket"
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		// Distinguish the runner OS, as the available tooling differs.
+		if runnerOS := strings.ToLower(os.Getenv("RUNNER_OS")); runnerOS != "" && runnerOS != "linux" {
+			return "github-actions-" + runnerOS
+		}
+		return "github-actions"
+	case os.Getenv("GITLAB_CI") == "true":
+		return "gitlab"
+	case os.Getenv("CIRCLECI") == "true":
+		return "circleci"
+	case os.Getenv("JENKINS_URL") != "":
+		return "jenkins"
+	default:
+		return ""
+	}
+}
+
+// Get the default docker build engine to use in the given CI system (or locally, if empty).
+func defaultEngineForCI(ci string) string {
+	if engine, found := ciDefaultBuildEngines[ci]; found {
+		return engine
+	}
+	return "buildx"
+}
+
+// Resolve the build engine to use with the container runtime. With podman, the engine is
+// always 'podman'. With docker, the engine is auto-detected based on the CI system, falling
+// back to 'buildkit' if buildx isn't installed.
+func resolveBuildEngine(engine string, rt *containerutil.Runtime) (string, error) {
+	// If not specified, auto-detect based on the runtime and the CI system.
 	if engine == "" {
-		// Bitbucket doesn't support buildx, fall back to buildkit
-		if _, exists := os.LookupEnv("BITBUCKET_PIPELINE_UUID"); exists {
-			return "buildkit", nil
+		if rt.Name == containerutil.RuntimePodman {
+			return "podman", nil
+		}
+
+		ci := ciEnvironment()
+		engine = defaultEngineForCI(ci)
+		if engine == "buildx" && !rt.Supports(containerutil.CapabilityBuildx) {
+			log.Debug().Msg("Docker buildx is not installed, falling back to the buildkit build engine")
+			engine = "buildkit"
 		}
-		return "buildx", nil
+		log.Debug().Msgf("Detected CI system: %s, using build engine %s", coalesceString(ci, "none"), engine)
+		return engine, nil
 	}
 
 	// Check validity if specified
-	for _, validEngine := range validBuildEngines {
-		if engine == validEngine {
-			return engine, nil
-		}
+	if err := metaplay.CheckBuildEngine(rt, engine); err != nil {
+		return "", err
 	}
+	return engine, nil
+}
 
-	return "", fmt.Errorf("invalid Docker build engine '%s', must be one of: %v", engine, validBuildEngines)
+// Docker build arguments for features only supported by the buildx engine: cache exporters
+// (eg, '--cache-to=type=gha') and multi-platform builds.
+var buildxOnlyArgPrefixes = []string{"--cache-to=type=", "--cache-from=type=", "--platform="}
+
+// Check that the extra arguments to the build don't use buildx-only features with another
+// build engine, which would otherwise fail with cryptic errors.
+func checkBuildxOnlyArgs(extraArgs []string, buildEngine string) error {
+	if buildEngine == "buildx" {
+		return nil
+	}
+	for _, arg := range extraArgs {
+		for _, prefix := range buildxOnlyArgPrefixes {
+			if !strings.HasPrefix(arg, prefix) {
+				continue
+			}
+			// Only multi-platform builds are buildx-only.
+			if prefix == "--platform=" && !strings.Contains(arg, ",") {
+				continue
+			}
+			return fmt.Errorf("the build argument '%s' requires the buildx build engine, got %s", arg, buildEngine)
+		}
+	}
+	return nil
 }
 
 func checkCommand(command string, args ...string) error {
@@ -371,47 +877,90 @@ func executeCommand(workingDir string, env []string, command string, args ...str
 	return cmd.Run()
 }
 
-// rebasePath calculates a new path for `targetPath` such that it is relative
-// to `newBaseDir` instead of current working directory.
-func rebasePath(targetPath, newBaseDir string) (string, error) {
-	// Resolve absolute directories of new base path & target path.
-	absNewBaseDir, err := filepath.Abs(newBaseDir)
+// executeCommandCaptureOutput runs a command like executeCommand, but instead of streaming
+// the command's stdout and stderr, collects them into a buffer that is returned.
+func executeCommandCaptureOutput(workingDir string, env []string, command string, args ...string) ([]byte, error) {
+	var output bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Env = env
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Dir = workingDir // Set the working directory
+	err := cmd.Run()
+	return output.Bytes(), err
+}
+
+// Create, sign, and locally store the provenance of the built image. The provenance is pushed
+// along with the image by metaplay.PushImage().
+func recordImageProvenance(cmd *cobra.Command, buildOpts metaplay.BuildImageOptions, buildResult *metaplay.BuildResult, signer metaplay.ProvenanceSigner, buildStartTime time.Time) error {
+	statement, err := metaplay.NewImageProvenance(buildOpts, buildResult, metaplay.ProvenanceBuilder{
+		ID:           resolveProvenanceBuilderID(),
+		InvocationID: buildOpts.BuildNumber,
+		CliVersion:   version.AppVersion,
+		StartedOn:    buildStartTime,
+		FinishedOn:   time.Now(),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve absolute base path: %w", err)
+		return err
 	}
-	absTargetPath, err := filepath.Abs(targetPath)
+	envelope, err := metaplay.SignProvenance(cmd.Context(), statement, signer)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve absolute target path: %w", err)
+		return err
 	}
-
-	// Compute the relative path to the new base.
-	relativePath, err := filepath.Rel(absNewBaseDir, absTargetPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve relative path: %w", err)
+	if err := metaplay.SaveLocalImageProvenance(buildResult.ImageID, envelope); err != nil {
+		return err
 	}
 
-	// log.Debug().Msgf("Rebase %s onto %s -> %s", targetPath, newBaseDir, relativePath)
-	// log.Debug().Msgf("  absNewBaseDir=%s, absTargetPath=%s", absNewBaseDir, absTargetPath)
+	log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Recorded provenance signed by"), styles.RenderTechnical(signer.KeyID()))
+	log.Info().Msgf("Builder: %s", styles.RenderTechnical(statement.Predicate.RunDetails.Builder.ID))
+	log.Info().Msg("")
+	return nil
+}
 
-	return relativePath, nil
+// Resolve the identity of the builder for the image provenance: the URL of the CI run, or the
+// local host when not running in a known CI system.
+func resolveProvenanceBuilderID() string {
+	if serverURL, repository, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); serverURL != "" && repository != "" && runID != "" {
+		builderID := fmt.Sprintf("%s/%s/actions/runs/%s", serverURL, repository, runID)
+		if attempt := os.Getenv("GITHUB_RUN_ATTEMPT"); attempt != "" {
+			builderID += "/attempts/" + attempt
+		}
+		return builderID
+	}
+	if _, buildURL := detectEnvVarWithKey([]string{"CI_JOB_URL", "CIRCLE_BUILD_URL", "BUILD_URL", "BUILDKITE_BUILD_URL"}); buildURL != "" {
+		return buildURL
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return "local://" + hostname
 }
 
-// Check if docker is available and running. Uses a short timeout as 'docker' invocation
-// can sometimes hang indefinitely.
-func checkDockerAvailable() error {
-	done := make(chan error)
-	go func() {
-		done <- checkCommand("docker", "info")
-	}()
+// Push the built image into the environment's image repository and show how to deploy it.
+func pushBuiltImage(cmd *cobra.Command, env *metaplay.Environment, imageName string) error {
+	imageTag, err := extractDockerImageTag(imageName)
+	if err != nil {
+		return err
+	}
 
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("docker is not available: %w. Ensure docker is installed and running.", err)
-		}
-	case <-time.After(10 * time.Second):
-		return fmt.Errorf("timeout while checking for docker. Ensure docker is running and responsive.")
+	var pushResult *metaplay.PushImageResult
+	taskRunner := tui.NewTaskRunner()
+	taskRunner.AddTask(fmt.Sprintf("Push docker image to environment %s", env.HumanID()), func(output *tui.TaskOutput) error {
+		pushResult, err = metaplay.PushImage(cmd.Context(), env, metaplay.PushImageOptions{
+			ImageName: imageName,
+			Progress:  taskOutputProgress(output),
+		})
+		return err
+	})
+	if err := taskRunner.Run(); err != nil {
+		return err
 	}
 
+	log.Info().Msg("")
+	log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Successfully pushed image"), styles.RenderTechnical(pushResult.RemoteImageName))
+	log.Info().Msg("")
+	log.Info().Msg("You can deploy the image to the environment using:")
+	log.Info().Msgf(styles.RenderTechnical("  metaplay deploy server %s %s"), env.HumanID(), imageTag)
 	return nil
 }
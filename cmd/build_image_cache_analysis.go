@@ -0,0 +1,171 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/pkg/buildcache"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+)
+
+// Number of log lines to show for each failed build step.
+const cacheAnalysisFailedStepLogLines = 30
+
+// Cache analysis of an ongoing docker build (with --analyze-cache). Docker's rawjson progress
+// output is written into the run, which parses it in the background.
+type cacheAnalysisRun struct {
+	writer   *io.PipeWriter
+	done     chan struct{}
+	analysis *buildcache.Analysis
+	err      error
+}
+
+// Start analyzing the progress output of a docker build. Unless quiet, each completed step is
+// printed as the build progresses, as docker's own progress output is replaced by rawjson.
+func startCacheAnalysis(imageName string, quiet bool) *cacheAnalysisRun {
+	reader, writer := io.Pipe()
+	run := &cacheAnalysisRun{writer: writer, done: make(chan struct{})}
+	go func() {
+		defer close(run.done)
+		run.analysis, run.err = buildcache.ParseRawJSONProgress(reader, imageName, func(step *buildcache.Step) {
+			if quiet {
+				return
+			}
+			if step.Cached {
+				log.Info().Msgf("%s %s", step, styles.RenderMuted("CACHED"))
+			} else {
+				log.Info().Msgf("%s %s", step, styles.RenderMuted(step.Duration.Round(100*time.Millisecond).String()))
+			}
+		})
+		// Keep consuming the output in case parsing stopped early, so that docker doesn't block.
+		io.Copy(io.Discard, reader)
+	}()
+	return run
+}
+
+// Write docker's progress output into the analysis.
+func (run *cacheAnalysisRun) Write(p []byte) (int, error) {
+	return run.writer.Write(p)
+}
+
+// Finish the analysis after the build has completed. On success with report set, print the
+// per-stage cache usage (compared to the previous analyzed build) and the suggestions, and
+// store the analysis for the next run. On failure, print the output of the failed steps.
+// Returns the analysis, or nil if docker's output could not be parsed.
+func (run *cacheAnalysisRun) finish(project *metaproj.MetaplayProject, buildSucceeded bool, report bool) *buildcache.Analysis {
+	run.writer.Close()
+	<-run.done
+	if run.err != nil {
+		log.Warn().Msgf("Build cache analysis incomplete: %v", run.err)
+	}
+	analysis := run.analysis
+	if analysis == nil {
+		return nil
+	}
+
+	// Docker's own output is not shown, so show the logs of the failed steps.
+	if !buildSucceeded {
+		for _, step := range analysis.FailedSteps() {
+			log.Info().Msg("")
+			log.Error().Msgf("Build step failed: %s", step)
+			logLines := strings.Split(strings.TrimRight(step.Logs(), "\n"), "\n")
+			if len(logLines) > cacheAnalysisFailedStepLogLines {
+				logLines = logLines[len(logLines)-cacheAnalysisFailedStepLogLines:]
+			}
+			for _, line := range logLines {
+				log.Info().Msgf("  %s", line)
+			}
+			log.Error().Msgf("%s", step.Error)
+		}
+		return analysis
+	}
+	if !report {
+		return analysis
+	}
+
+	// Load the previous analysis to compare against.
+	lastAnalysisPath, err := buildcache.LastAnalysisFilePath(project.Config.ProjectHumanID)
+	var previous *buildcache.Analysis
+	if err == nil {
+		previous, err = buildcache.LoadAnalysis(lastAnalysisPath)
+	}
+	if err != nil {
+		log.Debug().Msgf("Unable to load the previous build cache analysis: %v", err)
+	}
+
+	printCacheAnalysis(analysis, previous)
+
+	// Store the analysis for the next run.
+	if lastAnalysisPath != "" {
+		if err := analysis.Save(lastAnalysisPath); err != nil {
+			log.Warn().Msgf("Unable to store the build cache analysis: %v", err)
+		}
+	}
+	return analysis
+}
+
+// Print the per-stage cache usage table, the delta to the previous run, and the suggestions.
+func printCacheAnalysis(analysis *buildcache.Analysis, previous *buildcache.Analysis) {
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Build Cache Analysis"))
+	log.Info().Msg("")
+
+	// Per-stage table.
+	summaries := analysis.StageSummaries()
+	stageWidth := len("Stage")
+	for _, summary := range summaries {
+		stageWidth = max(stageWidth, len(summary.Stage))
+	}
+	previousCached := map[string]int{}
+	if previous != nil {
+		for _, summary := range previous.StageSummaries() {
+			previousCached[summary.Stage] = summary.NumCached
+		}
+	}
+	log.Info().Msgf("  %-*s  %5s  %6s  %8s  %10s  %s", stageWidth, "Stage", "Steps", "Cached", "Hit rate", "Time", "vs. previous")
+	for _, summary := range summaries {
+		delta := ""
+		if prevCached, found := previousCached[summary.Stage]; found {
+			delta = fmt.Sprintf("%+d cached", summary.NumCached-prevCached)
+		}
+		log.Info().Msgf("  %-*s  %5d  %6d  %8s  %10s  %s", stageWidth, summary.Stage, summary.NumSteps, summary.NumCached,
+			formatHitRate(summary.NumCached, summary.NumSteps), summary.Duration.Round(100*time.Millisecond), delta)
+	}
+	log.Info().Msg("")
+
+	// Totals, compared to the previous run.
+	numCached, numSteps := analysis.CacheHits()
+	totals := fmt.Sprintf("Cache hits: %d/%d steps (%s)", numCached, numSteps, formatHitRate(numCached, numSteps))
+	if previous != nil {
+		prevCached, prevSteps := previous.CacheHits()
+		totals += styles.RenderMuted(fmt.Sprintf(", previous run %d/%d steps (%s) at %s", prevCached, prevSteps, formatHitRate(prevCached, prevSteps), previous.Timestamp.Local().Format(time.DateTime)))
+	}
+	log.Info().Msg(totals)
+
+	// Suggestions.
+	suggestions := analysis.Suggestions()
+	log.Info().Msg("")
+	if len(suggestions) == 0 {
+		log.Info().Msg(styles.RenderSuccess("No cache invalidation issues detected."))
+		return
+	}
+	log.Info().Msg("Suggestions:")
+	for _, suggestion := range suggestions {
+		log.Info().Msgf("- %s", suggestion)
+	}
+}
+
+// Format the cache hit rate as a percentage.
+func formatHitRate(numCached, numSteps int) string {
+	if numSteps == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", numCached*100/numSteps)
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+)
+
+// Maximum length of the Dockerfile step shown for each layer in the size report.
+const imageSizeLayerStepMaxLength = 80
+
+// Print the size report of the built image: the total size compared to the previous local
+// build and the budget (if any), and the size of each non-empty layer with the Dockerfile step
+// that created it. Returns whether the image exceeds the budget.
+func printImageSizeReport(size *envapi.DockerImageSize, budgetMB int) bool {
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Image Size"))
+	log.Info().Msg("")
+
+	// Layers in build order, skipping the metadata-only steps.
+	for _, layer := range size.Layers {
+		if layer.Size == 0 {
+			continue
+		}
+		step := layer.CreatedBy
+		if len(step) > imageSizeLayerStepMaxLength {
+			step = step[:imageSizeLayerStepMaxLength-3] + "..."
+		}
+		log.Info().Msgf("  %8s  %s", humanize.Bytes(uint64(layer.Size)), styles.RenderMuted(step))
+	}
+	log.Info().Msg("")
+
+	// Total, compared to the previous build of the same repository.
+	total := fmt.Sprintf("Total size: %s", styles.RenderTechnical(humanize.Bytes(uint64(size.Size))))
+	if size.PreviousRepoTag != "" {
+		total += styles.RenderMuted(fmt.Sprintf(" (%s vs. previous build %s)", formatSizeDelta(size.Size-size.PreviousSize), size.PreviousRepoTag))
+	}
+	log.Info().Msg(total)
+
+	if budgetMB <= 0 {
+		return false
+	}
+	budgetBytes := int64(budgetMB) * 1000 * 1000
+	if size.Size > budgetBytes {
+		log.Warn().Msg(styles.RenderWarning(fmt.Sprintf("WARNING: The image exceeds the size budget of %d MB (imageSizeBudgetMB in metaplay-project.yaml) by %s!", budgetMB, humanize.Bytes(uint64(size.Size-budgetBytes)))))
+		return true
+	}
+	log.Info().Msgf("Size budget: %s", styles.RenderMuted(fmt.Sprintf("%d MB, %s remaining", budgetMB, humanize.Bytes(uint64(budgetBytes-size.Size)))))
+	return false
+}
+
+// Format the change in size with an explicit sign, eg, '+12 MB' or '-3.4 MB'.
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + humanize.Bytes(uint64(-delta))
+	}
+	return "+" + humanize.Bytes(uint64(delta))
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/buildcache"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaplay"
+)
+
+// Summary of an image build, written as JSON with --output-summary. The measurements are
+// also shown with --stats.
+type buildImageSummary struct {
+	ImageName       string           `json:"imageName"`       // Name of the built image, 'name:tag'.
+	ImageID         string           `json:"imageId"`         // ID of the built image (sha256 digest of the image config).
+	CommitID        string           `json:"commitId"`        // Commit ID embedded into the image.
+	BuildNumber     string           `json:"buildNumber"`     // Build number embedded into the image.
+	Platform        string           `json:"platform"`        // Target platform, eg, 'linux/amd64'.
+	BuildEngine     string           `json:"buildEngine"`     // Build engine used, eg, 'buildx'.
+	DurationSeconds float64          `json:"durationSeconds"` // Duration of the docker build.
+	ImageSizeBytes  int64            `json:"imageSizeBytes"`  // Size of the image, as reported by 'docker image inspect' (0 if it couldn't be measured).
+	Cache           *buildCacheStats `json:"cache,omitempty"` // Layer cache usage, only available with the buildx build engine.
+}
+
+// Layer cache usage of an image build.
+type buildCacheStats struct {
+	CachedSteps int     `json:"cachedSteps"` // Number of build steps served from the cache.
+	TotalSteps  int     `json:"totalSteps"`  // Total number of build steps.
+	HitRatio    float64 `json:"hitRatio"`    // Ratio of cached steps, from 0 to 1.
+}
+
+// Measure the completed build: the image size as measured from the local docker (nil if it
+// couldn't be measured), and the cache usage from the cache analysis (nil if not available).
+func measureImageBuild(buildOpts metaplay.BuildImageOptions, buildResult *metaplay.BuildResult, platform string, duration time.Duration, analysis *buildcache.Analysis, imageSize *envapi.DockerImageSize) *buildImageSummary {
+	summary := &buildImageSummary{
+		ImageName:       buildResult.ImageName,
+		ImageID:         buildResult.ImageID,
+		CommitID:        buildOpts.CommitID,
+		BuildNumber:     buildOpts.BuildNumber,
+		Platform:        platform,
+		BuildEngine:     buildOpts.Engine,
+		DurationSeconds: duration.Round(time.Millisecond).Seconds(),
+	}
+	if imageSize != nil {
+		summary.ImageSizeBytes = imageSize.Size
+	}
+	if analysis != nil {
+		numCached, numSteps := analysis.CacheHits()
+		summary.Cache = &buildCacheStats{CachedSteps: numCached, TotalSteps: numSteps}
+		if numSteps > 0 {
+			summary.Cache.HitRatio = float64(numCached) / float64(numSteps)
+		}
+	}
+	return summary
+}
+
+// Print the build measurements (with --stats).
+func printBuildStats(summary *buildImageSummary) {
+	cacheHits := "n/a (requires the buildx build engine)"
+	if summary.Cache != nil {
+		cacheHits = fmt.Sprintf("%d/%d steps (%s)", summary.Cache.CachedSteps, summary.Cache.TotalSteps, formatHitRate(summary.Cache.CachedSteps, summary.Cache.TotalSteps))
+	}
+	imageSize := "n/a (unable to inspect the image)"
+	if summary.ImageSizeBytes > 0 {
+		imageSize = humanize.Bytes(uint64(summary.ImageSizeBytes))
+	}
+	duration := time.Duration(summary.DurationSeconds * float64(time.Second))
+	tui.PrintSummaryTable("Build Stats", [][2]string{
+		{"Build duration", duration.Round(100 * time.Millisecond).String()},
+		{"Cache hits", cacheHits},
+		{"Image size", imageSize},
+	})
+}
+
+// Write the build summary as JSON into the file (with --output-summary).
+func writeBuildSummary(filePath string, summary *buildImageSummary) error {
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write build summary to %s: %w", filePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/metaplay/cli/pkg/buildcache"
+	"github.com/metaplay/cli/pkg/containerutil"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaplay"
+)
+
+// Environment variables used to detect the CI system, see ciEnvironment().
+var ciDetectionEnvVars = []string{"BITBUCKET_PIPELINE_UUID", "GITHUB_ACTIONS", "RUNNER_OS", "GITLAB_CI", "CIRCLECI", "JENKINS_URL"}
+
+// Clear the CI detection environment variables for the duration of the test, so that the
+// tests behave the same locally and in CI.
+func clearCIEnvironment(t *testing.T) {
+	for _, key := range ciDetectionEnvVars {
+		t.Setenv(key, "")
+	}
+}
+
+func TestDetectEnvVarWithKey(t *testing.T) {
+	t.Setenv("METAPLAY_TEST_FIRST", "")
+	os.Unsetenv("METAPLAY_TEST_FIRST")
+	t.Setenv("METAPLAY_TEST_SECOND", "abc123")
+	t.Setenv("METAPLAY_TEST_THIRD", "def456")
+
+	// The first variable that is set wins.
+	key, value := detectEnvVarWithKey([]string{"METAPLAY_TEST_FIRST", "METAPLAY_TEST_SECOND", "METAPLAY_TEST_THIRD"})
+	if key != "METAPLAY_TEST_SECOND" || value != "abc123" {
+		t.Errorf("detectEnvVarWithKey() = (%q, %q), expected (%q, %q)", key, value, "METAPLAY_TEST_SECOND", "abc123")
+	}
+
+	// A variable set to an empty value is still detected.
+	t.Setenv("METAPLAY_TEST_FIRST", "")
+	key, value = detectEnvVarWithKey([]string{"METAPLAY_TEST_FIRST", "METAPLAY_TEST_SECOND"})
+	if key != "METAPLAY_TEST_FIRST" || value != "" {
+		t.Errorf("detectEnvVarWithKey() = (%q, %q), expected (%q, %q)", key, value, "METAPLAY_TEST_FIRST", "")
+	}
+
+	// None set.
+	os.Unsetenv("METAPLAY_TEST_FIRST")
+	key, value = detectEnvVarWithKey([]string{"METAPLAY_TEST_FIRST"})
+	if key != "" || value != "" {
+		t.Errorf("detectEnvVarWithKey() = (%q, %q), expected empty", key, value)
+	}
+}
+
+func TestResolveBuildEngine(t *testing.T) {
+	docker := containerutil.DefaultRuntime()
+	dockerNoBuildx := &containerutil.Runtime{Name: containerutil.RuntimeDocker, Binary: "docker"}
+	podman := &containerutil.Runtime{Name: containerutil.RuntimePodman, Binary: "podman"}
+
+	tests := []struct {
+		name     string
+		engine   string
+		runtime  *containerutil.Runtime
+		env      map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{"explicit buildx", "buildx", docker, nil, "buildx", false},
+		{"explicit buildkit", "buildkit", docker, nil, "buildkit", false},
+		{"invalid", "kaniko", docker, nil, "", true},
+		{"auto-detect local", "", docker, nil, "buildx", false},
+		{"auto-detect bitbucket", "", docker, map[string]string{"BITBUCKET_PIPELINE_UUID": "{1234}"}, "buildkit", false},
+		{"auto-detect github linux", "", docker, map[string]string{"GITHUB_ACTIONS": "true", "RUNNER_OS": "Linux"}, "buildx", false},
+		{"auto-detect github windows", "", docker, map[string]string{"GITHUB_ACTIONS": "true", "RUNNER_OS": "Windows"}, "buildkit", false},
+		{"auto-detect gitlab", "", docker, map[string]string{"GITLAB_CI": "true"}, "buildx", false},
+		{"explicit overrides bitbucket", "buildx", docker, map[string]string{"BITBUCKET_PIPELINE_UUID": "{1234}"}, "buildx", false},
+		{"auto-detect without buildx", "", dockerNoBuildx, nil, "buildkit", false},
+		{"explicit buildx without buildx", "buildx", dockerNoBuildx, nil, "", true},
+		{"podman with docker", "podman", docker, nil, "", true},
+		{"auto-detect podman", "", podman, map[string]string{"GITLAB_CI": "true"}, "podman", false},
+		{"explicit podman", "podman", podman, nil, "podman", false},
+		{"buildx with podman", "buildx", podman, nil, "", true},
+		{"buildkit with podman", "buildkit", podman, nil, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearCIEnvironment(t)
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			engine, err := resolveBuildEngine(test.engine, test.runtime)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got engine %q", engine)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if engine != test.expected {
+				t.Errorf("engine = %q, expected %q", engine, test.expected)
+			}
+		})
+	}
+}
+
+func TestCheckBuildxOnlyArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		engine  string
+		wantErr bool
+	}{
+		{[]string{"--cache-to=type=gha,mode=max"}, "buildx", false},
+		{[]string{"--cache-to=type=gha,mode=max"}, "podman", true},
+		{[]string{"--cache-from=type=registry,ref=foo"}, "buildkit", true},
+		{[]string{"--platform=linux/amd64,linux/arm64"}, "podman", true},
+		{[]string{"--platform=linux/amd64"}, "podman", false},
+		{[]string{"--no-cache", "--pull"}, "podman", false},
+		{nil, "buildkit", false},
+	}
+
+	for _, test := range tests {
+		err := checkBuildxOnlyArgs(test.args, test.engine)
+		if (err != nil) != test.wantErr {
+			t.Errorf("checkBuildxOnlyArgs(%v, %s) error = %v, wantErr %v", test.args, test.engine, err, test.wantErr)
+		}
+	}
+}
+
+func TestFormatSizeDelta(t *testing.T) {
+	testCases := map[int64]string{
+		12_000_000: "+12 MB",
+		-3_400_000: "-3.4 MB",
+		0:          "+0 B",
+	}
+	for delta, expected := range testCases {
+		if got := formatSizeDelta(delta); got != expected {
+			t.Errorf("formatSizeDelta(%d) = %q, expected %q", delta, got, expected)
+		}
+	}
+}
+
+func TestWriteBuildSummary(t *testing.T) {
+	summaryPath := filepath.Join(t.TempDir(), "build-summary.json")
+	summary := &buildImageSummary{
+		ImageName:       "mygame:364cff09",
+		ImageID:         "sha256:1234",
+		BuildEngine:     "buildkit",
+		DurationSeconds: 95.5,
+		ImageSizeBytes:  245_000_000,
+	}
+	if err := writeBuildSummary(summaryPath, summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal(content, &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if parsed["durationSeconds"] != 95.5 || parsed["imageSizeBytes"] != float64(245_000_000) {
+		t.Errorf("unexpected measurements in summary: %s", content)
+	}
+	// The cache usage is omitted when it's not available.
+	if _, found := parsed["cache"]; found {
+		t.Errorf("expected no cache usage in summary: %s", content)
+	}
+}
+
+func TestMeasureImageBuild(t *testing.T) {
+	buildOpts := metaplay.BuildImageOptions{CommitID: "364cff09", BuildNumber: "42", Engine: "buildx"}
+	buildResult := &metaplay.BuildResult{ImageName: "mygame:364cff09", ImageID: "sha256:1234"}
+	analysis := &buildcache.Analysis{Steps: []*buildcache.Step{
+		{Stage: "build", Index: 1, Cached: true},
+		{Stage: "build", Index: 2, Cached: true},
+		{Stage: "build", Index: 3, Cached: true},
+		{Stage: "runtime", Index: 1, Cached: false},
+	}}
+
+	summary := measureImageBuild(buildOpts, buildResult, "linux/amd64", 1500*time.Millisecond, analysis, &envapi.DockerImageSize{Size: 245_000_000})
+	if summary.ImageName != "mygame:364cff09" || summary.CommitID != "364cff09" || summary.DurationSeconds != 1.5 || summary.ImageSizeBytes != 245_000_000 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if summary.Cache == nil || summary.Cache.CachedSteps != 3 || summary.Cache.TotalSteps != 4 || summary.Cache.HitRatio != 0.75 {
+		t.Errorf("expected 3/4 cached steps with hit ratio 0.75, got: %+v", summary.Cache)
+	}
+
+	// Without steps, the hit ratio is zero instead of NaN.
+	summary = measureImageBuild(buildOpts, buildResult, "linux/amd64", time.Second, &buildcache.Analysis{}, nil)
+	if summary.Cache == nil || summary.Cache.TotalSteps != 0 || summary.Cache.HitRatio != 0 {
+		t.Errorf("expected an empty cache usage, got: %+v", summary.Cache)
+	}
+
+	// Without the image size or the cache analysis, they're left out.
+	summary = measureImageBuild(buildOpts, buildResult, "linux/amd64", time.Second, nil, nil)
+	if summary.Cache != nil || summary.ImageSizeBytes != 0 {
+		t.Errorf("expected no cache usage or image size, got: %+v", summary)
+	}
+}
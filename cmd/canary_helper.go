@@ -0,0 +1,99 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Suffix appended to the stable release's name to get the name of its canary release.
+const canaryReleaseSuffix = "-canary"
+
+// Resolve the stable release that a canary is deployed alongside: the named release if
+// specified, or the only non-canary game server release in the environment.
+func resolveCanaryStableRelease(releases []*release.Release, releaseName string) (*release.Release, error) {
+	stableReleases := []*release.Release{}
+	for _, rel := range releases {
+		if helmutil.GetCanaryConfig(rel) == nil {
+			stableReleases = append(stableReleases, rel)
+		}
+	}
+
+	if releaseName != "" {
+		found := helmutil.FindReleaseByName(stableReleases, releaseName)
+		if found == nil {
+			return nil, fmt.Errorf("stable game server release '%s' not found; existing stable releases: %s", releaseName, strings.Join(helmutil.GetReleaseNames(stableReleases), ", "))
+		}
+		return found, nil
+	}
+
+	switch len(stableReleases) {
+	case 0:
+		return nil, exitcode.Errorf(exitcode.ExitNotFound, "no game server release to deploy the canary alongside; deploy the game server without --canary-percent first")
+	case 1:
+		return stableReleases[0], nil
+	default:
+		return nil, fmt.Errorf("multiple game server releases found in the environment (%s), specify the stable release with --release-name", strings.Join(helmutil.GetReleaseNames(stableReleases), ", "))
+	}
+}
+
+// Find the canary release and its stable release in the environment. Returns an ExitNotFound
+// error if the environment has no canary release.
+func findEnvironmentCanaryReleases(targetEnv *envapi.TargetEnvironment, envConfig *metaproj.ProjectEnvironmentConfig) (*action.Configuration, *release.Release, *release.Release, error) {
+	// Get kubeconfig to access the environment.
+	kubeconfigPayload, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Configure Helm.
+	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, envConfig.GetKubernetesNamespace())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+
+	releases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), metaplayGameServerChartName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stable, canary, err := helmutil.FindCanaryReleases(releases)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if canary == nil {
+		return nil, nil, nil, exitcode.Errorf(exitcode.ExitNotFound, "no canary release found in environment %s", envConfig.HumanID)
+	}
+	return actionConfig, stable, canary, nil
+}
+
+// Print the stable and canary releases with their image tags and the traffic split.
+func printCanaryStatus(stable *release.Release, canary *release.Release) {
+	weight := 0
+	if config := helmutil.GetCanaryConfig(canary); config != nil {
+		weight = config.Weight
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Canary Deployment"))
+	log.Info().Msg("")
+	log.Info().Msgf("Stable release:")
+	log.Info().Msgf("  Name:      %s", styles.RenderTechnical(stable.Name))
+	log.Info().Msgf("  Image tag: %s", styles.RenderTechnical(helmutil.GetReleaseImageTag(stable)))
+	log.Info().Msgf("  Traffic:   %s", styles.RenderTechnical(fmt.Sprintf("%d%%", 100-weight)))
+	log.Info().Msgf("Canary release:")
+	log.Info().Msgf("  Name:      %s", styles.RenderTechnical(canary.Name))
+	log.Info().Msgf("  Image tag: %s", styles.RenderTechnical(helmutil.GetReleaseImageTag(canary)))
+	log.Info().Msgf("  Traffic:   %s", styles.RenderTechnical(fmt.Sprintf("%d%%", weight)))
+	log.Info().Msg("")
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/rs/zerolog/log"
+)
+
+// Marker for commands that require a Metaplay project (metaplay-project.yaml). Embed into
+// the command's options struct and the project is resolved before Run() is called. The
+// command fails if the project cannot be found.
+type RequiresProject struct{}
+
+// Marker for commands that require the user to be logged in with the Metaplay auth provider.
+// Embed into the command's options struct and the user is logged in (if not already) before
+// Run() is called.
+type RequiresLogin struct{}
+
+// Marker for commands that operate on a target environment. Embed into the command's options
+// struct and register the ENVIRONMENT argument with AddEnvironmentArgument(). The environment
+// is resolved (asking the user to choose one in interactive mode, if not specified), the user
+// is logged in to the environment's auth provider, and the project is resolved (if it can be
+// found) before Run() is called.
+type RequiresEnvironment struct {
+	argEnvironment string
+}
+
+// Register the standard optional ENVIRONMENT positional argument.
+func (r *RequiresEnvironment) AddEnvironmentArgument(args *PositionalArgs) {
+	args.AddStringArgumentOpt(&r.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
+}
+
+// Values resolved by the framework based on the requirements declared by a command (using the
+// Requires* markers). Passed to the command's Run(). Members not required by the command are nil.
+type CommandContext struct {
+	Project   *metaproj.MetaplayProject          // Project, with RequiresProject (or RequiresEnvironment, if found).
+	TokenSet  *auth.TokenSet                     // Auth tokens, with RequiresLogin or RequiresEnvironment.
+	EnvConfig *metaproj.ProjectEnvironmentConfig // Target environment config, with RequiresEnvironment.
+	TargetEnv *envapi.TargetEnvironment          // Target environment, with RequiresEnvironment.
+}
+
+// Check whether the options struct embeds the given marker type.
+func hasRequirement[T any](opts CommandOptions) bool {
+	_, found := getRequirement[T](opts)
+	return found
+}
+
+// Get a pointer to the marker of the given type embedded in the options struct.
+func getRequirement[T any](opts CommandOptions) (*T, bool) {
+	v := reflect.ValueOf(opts)
+	if v.Kind() != reflect.Ptr {
+		return nil, false
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	field := v.FieldByName(reflect.TypeFor[T]().Name())
+	if !field.IsValid() || field.Type() != reflect.TypeFor[T]() {
+		return nil, false
+	}
+	return field.Addr().Interface().(*T), true
+}
+
+// Resolve the values required by the command (as declared with the Requires* markers)
+// into a CommandContext.
+func resolveCommandContext(ctx context.Context, opts CommandOptions) (*CommandContext, error) {
+	cmdCtx := &CommandContext{}
+	var err error
+
+	// Resolve the project: required or optional (if the command targets an environment).
+	if hasRequirement[RequiresProject](opts) {
+		cmdCtx.Project, err = resolveProject()
+		if err != nil {
+			return nil, err
+		}
+	} else if hasRequirement[RequiresEnvironment](opts) {
+		cmdCtx.Project, err = tryResolveProject()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve the target environment, which also logs in to the environment's auth provider.
+	if requiresEnv, found := getRequirement[RequiresEnvironment](opts); found {
+		cmdCtx.EnvConfig, cmdCtx.TokenSet, err = resolveEnvironment(ctx, cmdCtx.Project, requiresEnv.argEnvironment)
+		if err != nil {
+			return nil, err
+		}
+		cmdCtx.TargetEnv = envapi.NewTargetEnvironment(cmdCtx.TokenSet, cmdCtx.EnvConfig.StackDomain, cmdCtx.EnvConfig.HumanID)
+		log.Debug().Msgf("Resolved target environment %s", cmdCtx.EnvConfig.HumanID)
+	} else if hasRequirement[RequiresLogin](opts) {
+		// Commands not targeting an environment always use the built-in Metaplay auth provider.
+		cmdCtx.TokenSet, err = tui.RequireLoggedIn(ctx, auth.NewMetaplayAuthProvider())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cmdCtx, nil
+}
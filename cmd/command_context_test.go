@@ -0,0 +1,307 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/spf13/cobra"
+	"github.com/zalando/go-keyring"
+)
+
+// Options without any requirements, recording the context passed to Run().
+type noRequirementsOpts struct {
+	UsePositionalArgs
+	cmdCtx *CommandContext
+}
+
+func (o *noRequirementsOpts) Prepare(cmd *cobra.Command, args []string) error { return nil }
+func (o *noRequirementsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	o.cmdCtx = cmdCtx
+	return nil
+}
+
+// Options requiring a project.
+type requiresProjectOpts struct {
+	noRequirementsOpts
+	RequiresProject
+}
+
+// Options requiring a login.
+type requiresLoginOpts struct {
+	noRequirementsOpts
+	RequiresLogin
+}
+
+// Options targeting an environment, with the ENVIRONMENT argument followed by a release name.
+type requiresEnvironmentOpts struct {
+	noRequirementsOpts
+	RequiresEnvironment
+	argReleaseName string
+}
+
+func newRequiresEnvironmentOpts() *requiresEnvironmentOpts {
+	o := &requiresEnvironmentOpts{}
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+	args.AddStringArgumentOpt(&o.argReleaseName, "RELEASE", "Name of the release.")
+	return o
+}
+
+// Create a minimal project with the 'tough-falcons' environment in a temporary directory and
+// use it as the current project. Without a project, runs outside of any project.
+func useTestProject(t *testing.T, withProject bool) {
+	projectDir := t.TempDir()
+	t.Chdir(projectDir)
+	oldProjectPath := flagProjectConfigPath
+	t.Cleanup(func() { flagProjectConfigPath = oldProjectPath })
+	flagProjectConfigPath = "."
+	if !withProject {
+		return
+	}
+
+	for _, dir := range []string{"MetaplaySDK", "Backend", "Assets/SharedCode", "Unity"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		metaproj.ConfigFileName: fmt.Sprintf(`configVersion: %d
+projectID: tough-falcons
+buildRootDir: .
+sdkRootDir: MetaplaySDK
+backendDir: Backend
+sharedCodeDir: Assets/SharedCode
+unityProjectDir: Unity
+dotnetRuntimeVersion: "9.0"
+serverChartVersion: 0.8.0
+botClientChartVersion: 0.8.0
+environments:
+  - name: Tough Falcons
+    humanId: tough-falcons
+    type: development
+    stackDomain: p1.metaplay.io
+`, metaproj.CurrentProjectConfigVersion),
+		"MetaplaySDK/version.yaml": `sdkVersion: 33.0.0
+defaultDotnetRuntimeVersion: "9.0"
+defaultServerChartVersion: 0.8.0
+defaultBotClientChartVersion: 0.8.0
+minInfraVersion: 0.5.0
+minServerChartVersion: 0.7.0
+minBotClientChartVersion: 0.7.0
+minDotnetSdkVersion: 9.0.100
+nodeVersion: 22.14.0
+pnpmVersion: 10.6.0
+`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Redirect the persisted sessions into a temporary directory, optionally logging in to the
+// Metaplay auth provider.
+func useTestLogin(t *testing.T, loggedIn bool) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on HOME to redirect the state directory")
+	}
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+	if !loggedIn {
+		return
+	}
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix(), "sub": "test-user"}).SignedString([]byte("test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.SaveSessionState(auth.NewMetaplayAuthProvider().GetSessionID(), auth.UserTypeMachine, &auth.TokenSet{AccessToken: accessToken}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunCommandWithoutRequirements(t *testing.T) {
+	useTestProject(t, true)
+	useTestLogin(t, true)
+
+	// Nothing is resolved for commands without requirements, even if available.
+	o := &noRequirementsOpts{}
+	if err := runCommandOptions(&cobra.Command{Use: "test"}, o, []string{}); err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if o.cmdCtx.Project != nil || o.cmdCtx.TokenSet != nil || o.cmdCtx.EnvConfig != nil || o.cmdCtx.TargetEnv != nil {
+		t.Errorf("expected an empty context, got %+v", o.cmdCtx)
+	}
+}
+
+func TestRunCommandRequiresProject(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+
+	// The project is resolved and passed to Run().
+	useTestProject(t, true)
+	o := &requiresProjectOpts{}
+	if err := runCommandOptions(cmd, o, []string{}); err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if o.cmdCtx.Project == nil || o.cmdCtx.Project.Config.ProjectHumanID != "tough-falcons" {
+		t.Errorf("expected the project to be resolved, got %+v", o.cmdCtx.Project)
+	}
+	if o.cmdCtx.TokenSet != nil || o.cmdCtx.TargetEnv != nil {
+		t.Errorf("expected no login or environment, got %+v", o.cmdCtx)
+	}
+
+	// A required project that cannot be found results in a not found error, without running.
+	useTestProject(t, false)
+	o = &requiresProjectOpts{}
+	err := runCommandOptions(cmd, o, []string{})
+	if code := exitcode.FromError(err); code != exitcode.ExitNotFound {
+		t.Errorf("exit code = %d (err: %v), expected %d", code, err, exitcode.ExitNotFound)
+	}
+	if o.cmdCtx != nil {
+		t.Errorf("expected the command not to run without a project")
+	}
+}
+
+func TestRunCommandRequiresLogin(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	useTestProject(t, false)
+
+	// The tokens of the existing session are passed to Run().
+	useTestLogin(t, true)
+	o := &requiresLoginOpts{}
+	if err := runCommandOptions(cmd, o, []string{}); err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if o.cmdCtx.TokenSet == nil || o.cmdCtx.TokenSet.AccessToken == "" {
+		t.Errorf("expected the tokens to be resolved, got %+v", o.cmdCtx.TokenSet)
+	}
+
+	// Without a session, a non-interactive run requires logging in first.
+	useTestLogin(t, false)
+	o = &requiresLoginOpts{}
+	err := runCommandOptions(cmd, o, []string{})
+	if code := exitcode.FromError(err); code != exitcode.ExitAuthRequired {
+		t.Errorf("exit code = %d (err: %v), expected %d", code, err, exitcode.ExitAuthRequired)
+	}
+	if o.cmdCtx != nil {
+		t.Errorf("expected the command not to run without a login")
+	}
+}
+
+func TestRunCommandRequiresEnvironment(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	useTestProject(t, true)
+	useTestLogin(t, true)
+
+	// The ENVIRONMENT argument is parsed before the command's own arguments, and the project,
+	// tokens, and environment are resolved.
+	o := newRequiresEnvironmentOpts()
+	if err := runCommandOptions(cmd, o, []string{"tough-falcons", "tough-falcons-green"}); err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if o.argReleaseName != "tough-falcons-green" {
+		t.Errorf("release = %q, expected %q", o.argReleaseName, "tough-falcons-green")
+	}
+	if o.cmdCtx.Project == nil || o.cmdCtx.TokenSet == nil || o.cmdCtx.TargetEnv == nil {
+		t.Fatalf("expected the project, tokens, and environment to be resolved, got %+v", o.cmdCtx)
+	}
+	if o.cmdCtx.EnvConfig.HumanID != "tough-falcons" || o.cmdCtx.TargetEnv.HumanId != "tough-falcons" {
+		t.Errorf("expected environment tough-falcons, got %+v", o.cmdCtx.EnvConfig)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		loggedIn bool
+		code     int
+	}{
+		{"environment not specified", []string{}, true, exitcode.ExitUsage},
+		{"unknown environment", []string{"lovely-wombats"}, true, exitcode.ExitNotFound},
+		{"not logged in", []string{"tough-falcons"}, false, exitcode.ExitAuthRequired},
+	}
+	for _, test := range tests {
+		useTestLogin(t, test.loggedIn)
+		o := newRequiresEnvironmentOpts()
+		err := runCommandOptions(cmd, o, test.args)
+		if code := exitcode.FromError(err); code != test.code {
+			t.Errorf("%s: exit code = %d (err: %v), expected %d", test.name, code, err, test.code)
+		}
+		if o.cmdCtx != nil {
+			t.Errorf("%s: expected the command not to run", test.name)
+		}
+	}
+}
+
+func TestMigratedCommandsHelpText(t *testing.T) {
+	// The help texts of the migrated commands must still document the ENVIRONMENT argument.
+	for _, path := range [][]string{
+		{"get", "pods"},
+		{"get", "docker-login"},
+		{"restart", "server"},
+		{"scale", "server"},
+		{"remove", "server"},
+		{"remove", "botclient"},
+	} {
+		cmd, _, err := rootCmd.Find(path)
+		if err != nil {
+			t.Errorf("command '%s' not found: %v", strings.Join(path, " "), err)
+			continue
+		}
+		if !strings.Contains(cmd.Long, "ENVIRONMENT (optional) -- Target environment name or id, eg, 'tough-falcons'.") {
+			t.Errorf("command '%s' doesn't document the ENVIRONMENT argument", strings.Join(path, " "))
+		}
+	}
+}
+
+// Options whose Prepare or Run fails.
+type failingOpts struct {
+	UsePositionalArgs
+	prepareErr error
+	runErr     error
+}
+
+func (o *failingOpts) Prepare(cmd *cobra.Command, args []string) error      { return o.prepareErr }
+func (o *failingOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error { return o.runErr }
+
+func TestRunCommandOptionsExitCodes(t *testing.T) {
+	// Run outside of any project.
+	useTestProject(t, false)
+
+	cmd := &cobra.Command{Use: "test"}
+	tests := []struct {
+		name   string
+		opts   CommandOptions
+		code   int
+		silent bool
+	}{
+		{"success", &failingOpts{}, exitcode.ExitSuccess, false},
+		{"prepare fails", &failingOpts{prepareErr: errors.New("bad flag")}, exitcode.ExitUsage, true},
+		{"run fails", &failingOpts{runErr: errors.New("failed")}, exitcode.ExitError, false},
+		{"run fails with code", &failingOpts{runErr: exitcode.Errorf(exitcode.ExitNotFound, "not found")}, exitcode.ExitNotFound, false},
+		{"run fails silently", &failingOpts{runErr: exitcode.Silent(exitcode.ExitBuildFailed)}, exitcode.ExitBuildFailed, true},
+		{"auth token --environment without project", &authTokenOpts{flagEnvironment: "tough-falcons"}, exitcode.ExitUsage, false},
+	}
+
+	for _, test := range tests {
+		err := runCommandOptions(cmd, test.opts, []string{})
+		if code := exitcode.FromError(err); code != test.code {
+			t.Errorf("%s: exit code = %d, expected %d", test.name, code, test.code)
+		}
+		if silent := exitcode.IsSilent(err); silent != test.silent {
+			t.Errorf("%s: silent = %v, expected %v", test.name, silent, test.silent)
+		}
+	}
+}
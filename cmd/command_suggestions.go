@@ -0,0 +1,284 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Maximum number of commands to suggest for a mistyped command.
+const maxCommandSuggestions = 3
+
+// Command suggested for a mistyped command line.
+type commandSuggestion struct {
+	Path     []string // Words of the suggested command, excluding the root, eg, ['deploy', 'server'].
+	Distance int      // Edit distance between the typed and the suggested command.
+}
+
+// Positional word of a command line and its index in the arguments.
+type commandLineWord struct {
+	Word  string
+	Index int
+}
+
+// Check whether the command line refers to an unknown command, eg, 'metaplay deploy gameserver'.
+// If so, report the error with suggestions of the nearest commands, and in interactive mode,
+// offer to run the suggestion if there is a single close match. Returns the arguments to run
+// instead, or nil if the command line is not handled here. Exits if the command is unknown
+// and no suggestion is run.
+func handleUnknownCommand(ctx context.Context, root *cobra.Command, args []string) []string {
+	// Find the deepest known command: unknown commands are positional args to a command
+	// that only has subcommands (cobra would just show its help).
+	cmd, _, _ := root.Find(args)
+	if cmd == nil || !cmd.HasSubCommands() || cmd.Runnable() {
+		return nil
+	}
+	words := getCommandLineWords(cmd, args)
+	knownDepth := len(getCommandPath(cmd))
+	if len(words) <= knownDepth {
+		return nil
+	}
+	unknownWord := words[knownDepth].Word
+
+	// Initialize the logging and the interactive mode as if running the command. Flags of the
+	// intended subcommand are unknown to the parent so ignore them.
+	cmd.FParseErrWhitelist.UnknownFlags = true
+	_ = cmd.ParseFlags(args)
+	root.PersistentPreRun(cmd, nil)
+
+	// Report the unknown command with the suggestions.
+	suggestions := suggestCommands(root, words, knownDepth)
+	log.Error().Msgf("ERROR: Unknown command '%s' for '%s'", unknownWord, cmd.CommandPath())
+	if len(suggestions) > 0 {
+		log.Info().Msg("")
+		log.Info().Msg("Did you mean this?")
+		for _, suggestion := range suggestions {
+			log.Info().Msgf("  %s %s", root.Name(), strings.Join(suggestion.Path, " "))
+		}
+	}
+	log.Info().Msg("")
+	log.Info().Msgf("Run '%s --help' for usage.", cmd.CommandPath())
+
+	// Offer to run a high-confidence suggestion, only in interactive mode.
+	if tui.IsInteractive() && isHighConfidenceSuggestion(suggestions) {
+		suggestedArgs := replaceCommandLineWords(args, words, suggestions[0].Path)
+		log.Info().Msg("")
+		confirmed, err := tui.DoConfirmQuestionDefaultNo(ctx, fmt.Sprintf("Run '%s %s' instead?", root.Name(), strings.Join(suggestedArgs, " ")))
+		if err == nil && confirmed {
+			return suggestedArgs
+		}
+	}
+
+	os.Exit(exitcode.ExitUsage)
+	return nil
+}
+
+// Get the positional words of the command line, ie, skip the flags and their values. Flags
+// are resolved from the command (and its parents), unknown flags are assumed to not take a
+// value. Stops at '--'.
+func getCommandLineWords(cmd *cobra.Command, args []string) []commandLineWord {
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(cmd.LocalFlags())
+	flags.AddFlagSet(cmd.InheritedFlags())
+
+	words := []commandLineWord{}
+	for ndx := 0; ndx < len(args); ndx++ {
+		arg := args[ndx]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			words = append(words, commandLineWord{arg, ndx})
+			continue
+		}
+		if strings.Contains(arg, "=") {
+			continue
+		}
+
+		// Skip the value of a flag that takes one, eg, '--project <path>' or '-p <path>'.
+		var flag *pflag.Flag
+		if name, isLong := strings.CutPrefix(arg, "--"); isLong {
+			flag = flags.Lookup(name)
+		} else if len(arg) == 2 {
+			flag = flags.ShorthandLookup(arg[1:])
+		}
+		if flag != nil && flag.NoOptDefVal == "" {
+			ndx++
+		}
+	}
+	return words
+}
+
+// Replace the command words of the command line with the given command path, keeping the
+// flags and other args as-is.
+func replaceCommandLineWords(args []string, words []commandLineWord, path []string) []string {
+	result := append([]string{}, args...)
+	for ndx, word := range path {
+		result[words[ndx].Index] = word
+	}
+	return result
+}
+
+// Get the words of the command's path, excluding the root.
+func getCommandPath(cmd *cobra.Command) []string {
+	path := []string{}
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		path = append([]string{cmd.Name()}, path...)
+	}
+	return path
+}
+
+// Get all the paths of the available commands in the tree, including the aliases, mapped to the
+// canonical path, eg, 'deploy srv' -> ['deploy', 'server'].
+func getAllCommandPaths(root *cobra.Command) map[string][]string {
+	paths := map[string][]string{}
+	var visit func(cmd *cobra.Command, prefixes [][]string)
+	visit = func(cmd *cobra.Command, prefixes [][]string) {
+		for _, child := range cmd.Commands() {
+			if !child.IsAvailableCommand() {
+				continue
+			}
+			canonical := append(getCommandPath(cmd), child.Name())
+			childPrefixes := [][]string{}
+			for _, prefix := range prefixes {
+				for _, name := range append([]string{child.Name()}, child.Aliases...) {
+					childPath := append(append([]string{}, prefix...), name)
+					paths[strings.Join(childPath, " ")] = canonical
+					childPrefixes = append(childPrefixes, childPath)
+				}
+			}
+			visit(child, childPrefixes)
+		}
+	}
+	visit(root, [][]string{{}})
+	return paths
+}
+
+// Suggest the commands nearest to the typed command words, considering the full command tree
+// (including aliases). The commands must be deeper than knownDepth, ie, replace the unknown
+// word(s). Returns the suggestions, nearest first.
+func suggestCommands(root *cobra.Command, words []commandLineWord, knownDepth int) []commandSuggestion {
+	bestByPath := map[string]commandSuggestion{}
+	for pathStr, canonical := range getAllCommandPaths(root) {
+		depth := len(strings.Fields(pathStr))
+		if depth <= knownDepth || depth > len(words) {
+			continue
+		}
+		typedWords := []string{}
+		for _, word := range words[:depth] {
+			typedWords = append(typedWords, word.Word)
+		}
+		typed := strings.Join(typedWords, " ")
+
+		distance := levenshteinDistance(strings.ToLower(typed), pathStr)
+		lastTyped := typedWords[depth-1]
+		if len(lastTyped) >= 3 && strings.HasPrefix(pathStr, typed) {
+			distance = min(distance, 1) // Prefix of the command, eg, 'deploy serv'.
+		}
+		if distance > max(2, len(typed)/4) {
+			continue
+		}
+
+		key := strings.Join(canonical, " ")
+		if existing, found := bestByPath[key]; !found || distance < existing.Distance {
+			bestByPath[key] = commandSuggestion{Path: canonical, Distance: distance}
+		}
+	}
+
+	// Skip the parents of other suggestions, eg, 'build' when 'build image' also matches.
+	suggestions := []commandSuggestion{}
+	for key, suggestion := range bestByPath {
+		isParent := false
+		for otherKey := range bestByPath {
+			if strings.HasPrefix(otherKey, key+" ") {
+				isParent = true
+				break
+			}
+		}
+		if !isParent {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Distance != suggestions[j].Distance {
+			return suggestions[i].Distance < suggestions[j].Distance
+		}
+		return strings.Join(suggestions[i].Path, " ") < strings.Join(suggestions[j].Path, " ")
+	})
+	if len(suggestions) > maxCommandSuggestions {
+		suggestions = suggestions[:maxCommandSuggestions]
+	}
+	return suggestions
+}
+
+// Check whether the nearest suggestion is close and unambiguous enough to offer running it.
+func isHighConfidenceSuggestion(suggestions []commandSuggestion) bool {
+	if len(suggestions) == 0 || suggestions[0].Distance > 2 {
+		return false
+	}
+	return len(suggestions) == 1 || suggestions[1].Distance > suggestions[0].Distance
+}
+
+// Add the nearest valid flag as a suggestion to unknown flag errors. Used as the cobra
+// FlagErrorFunc, other errors are returned as-is.
+func suggestFlagOnError(cmd *cobra.Command, err error) error {
+	name, isUnknown := strings.CutPrefix(err.Error(), "unknown flag: --")
+	if !isUnknown {
+		return err
+	}
+
+	bestName := ""
+	bestDistance := max(2, len(name)/3) + 1
+	visitFlag := func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		distance := levenshteinDistance(name, flag.Name)
+		if len(name) >= 3 && strings.HasPrefix(flag.Name, name) {
+			distance = min(distance, 1)
+		}
+		if distance < bestDistance || (distance == bestDistance && flag.Name < bestName) {
+			bestName = flag.Name
+			bestDistance = distance
+		}
+	}
+	cmd.LocalFlags().VisitAll(visitFlag)
+	cmd.InheritedFlags().VisitAll(visitFlag)
+	if bestName == "" {
+		return err
+	}
+	return fmt.Errorf("%w\n\nDid you mean this?\n  --%s", err, bestName)
+}
+
+// Compute the Levenshtein edit distance between the strings.
+func levenshteinDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
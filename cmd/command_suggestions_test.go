@@ -0,0 +1,154 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/metaplay/cli/pkg/metahttp"
+	"github.com/spf13/cobra"
+)
+
+// Create a small command tree resembling the CLI's.
+func newSuggestionTestTree() *cobra.Command {
+	run := func(cmd *cobra.Command, args []string) {}
+	root := &cobra.Command{Use: "metaplay"}
+	root.PersistentFlags().StringP("project", "p", "", "")
+	root.PersistentFlags().BoolP("verbose", "v", false, "")
+
+	build := &cobra.Command{Use: "build", Aliases: []string{"b"}}
+	build.AddCommand(
+		&cobra.Command{Use: "image", Aliases: []string{"img", "docker-image"}, Run: run},
+		&cobra.Command{Use: "dashboard", Aliases: []string{"dash"}, Run: run},
+	)
+	deploy := &cobra.Command{Use: "deploy"}
+	deployServer := &cobra.Command{Use: "server", Aliases: []string{"gameserver"}, Run: run}
+	deployServer.Flags().StringP("values", "f", "", "")
+	deployServer.Flags().Bool("dry-run", false, "")
+	deploy.AddCommand(deployServer, &cobra.Command{Use: "botclient", Run: run})
+	remove := &cobra.Command{Use: "remove"}
+	remove.AddCommand(&cobra.Command{Use: "server", Run: run}, &cobra.Command{Use: "botclient", Run: run})
+
+	root.AddCommand(build, deploy, remove)
+	return root
+}
+
+func TestLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"server", "server", 0},
+		{"", "abc", 3},
+		{"builds", "build", 1},
+		{"sevrer", "server", 2},
+		{"gameserver", "server", 4},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if distance := levenshteinDistance(test.a, test.b); distance != test.expected {
+			t.Errorf("levenshteinDistance(%q, %q) = %d, expected %d", test.a, test.b, distance, test.expected)
+		}
+	}
+}
+
+func TestSuggestCommands(t *testing.T) {
+	root := newSuggestionTestTree()
+
+	tests := []struct {
+		args           []string
+		expected       []string
+		highConfidence bool
+	}{
+		{[]string{"deploy", "sever", "tough-falcons"}, []string{"deploy server"}, true},
+		{[]string{"deploy", "gamesrver"}, []string{"deploy server"}, true},
+		{[]string{"builds", "docker-imag"}, []string{"build image"}, true},
+		{[]string{"-p", "game", "buidl", "img"}, []string{"build image"}, true},
+		{[]string{"remove", "serv"}, []string{"remove server"}, true},
+		{[]string{"dploy", "botclient"}, []string{"deploy botclient"}, true},
+		{[]string{"build", "dashbord"}, []string{"build dashboard"}, true},
+		{[]string{"deploy", "xyzzy"}, []string{}, false},
+	}
+
+	for _, test := range tests {
+		cmd, _, _ := root.Find(test.args)
+		words := getCommandLineWords(cmd, test.args)
+		suggestions := suggestCommands(root, words, len(getCommandPath(cmd)))
+		paths := []string{}
+		for _, suggestion := range suggestions {
+			paths = append(paths, strings.Join(suggestion.Path, " "))
+		}
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("suggestions for %v = %v, expected %v", test.args, paths, test.expected)
+		}
+		if isHighConfidenceSuggestion(suggestions) != test.highConfidence {
+			t.Errorf("suggestions for %v: high confidence = %v, expected %v", test.args, !test.highConfidence, test.highConfidence)
+		}
+	}
+}
+
+func TestReplaceCommandLineWords(t *testing.T) {
+	root := newSuggestionTestTree()
+	args := []string{"-v", "--project", "game", "deploy", "sevrer", "tough-falcons", "--values=x.yaml", "--", "extra"}
+	cmd, _, _ := root.Find(args)
+
+	words := getCommandLineWords(cmd, args)
+	wordStrs := []string{}
+	for _, word := range words {
+		wordStrs = append(wordStrs, word.Word)
+	}
+	if !reflect.DeepEqual(wordStrs, []string{"deploy", "sevrer", "tough-falcons"}) {
+		t.Fatalf("words = %v", wordStrs)
+	}
+
+	replaced := replaceCommandLineWords(args, words, []string{"deploy", "server"})
+	expected := []string{"-v", "--project", "game", "deploy", "server", "tough-falcons", "--values=x.yaml", "--", "extra"}
+	if !reflect.DeepEqual(replaced, expected) {
+		t.Errorf("replaced = %v, expected %v", replaced, expected)
+	}
+}
+
+func TestSuggestFlagOnError(t *testing.T) {
+	root := newSuggestionTestTree()
+	deployServer, _, _ := root.Find([]string{"deploy", "server"})
+
+	tests := []struct {
+		err      string
+		expected string
+	}{
+		{"unknown flag: --valeus", "--values"},
+		{"unknown flag: --dry", "--dry-run"},
+		{"unknown flag: --projet", "--project"},
+		{"unknown flag: --completely-different", ""},
+		{"unknown shorthand flag: 'x' in -x", ""},
+	}
+	for _, test := range tests {
+		err := suggestFlagOnError(deployServer, errors.New(test.err))
+		if test.expected == "" {
+			if err.Error() != test.err {
+				t.Errorf("%q: unexpected suggestion: %v", test.err, err)
+			}
+			continue
+		}
+		if !strings.HasSuffix(err.Error(), "Did you mean this?\n  "+test.expected) {
+			t.Errorf("%q: error = %q, expected suggestion %s", test.err, err, test.expected)
+		}
+	}
+}
+
+func TestPersistentPreRunOnce(t *testing.T) {
+	// Reporting an unknown command initializes the CLI before the command line is executed:
+	// running the hook again must not re-initialize it (eg, start a new request ID).
+	completionCmd := &cobra.Command{Use: cobra.ShellCompRequestCmd}
+	rootCmd.PersistentPreRun(completionCmd, nil)
+	requestID := metahttp.GetCommandRequestID()
+	rootCmd.PersistentPreRun(completionCmd, nil)
+	if got := metahttp.GetCommandRequestID(); got != requestID {
+		t.Errorf("request ID changed from %s to %s, expected the initialization to run once", requestID, got)
+	}
+}
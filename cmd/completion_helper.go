@@ -0,0 +1,100 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/portalapi"
+	"github.com/spf13/cobra"
+)
+
+// Names of the positional arguments that take an environment ID and are completed by
+// completePositionalArgs().
+var environmentArgNames = map[string]bool{
+	"ENVIRONMENT": true,
+	"HUMANID":     true,
+	"SOURCE_ENV":  true,
+	"TARGET_ENV":  true,
+}
+
+// Timeout for discovering the environments from the portal during shell completion.
+const completionPortalTimeout = 3 * time.Second
+
+// Create the shell completion function (cobra's ValidArgsFunction) for the positional
+// arguments of a command. Environment arguments are completed with the environment IDs, the
+// other arguments use the shell's default completion.
+func completePositionalArgs(opts CommandOptions) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		posArgs, hasPosArgs := getUsePositionalArgs(opts)
+		if !hasPosArgs {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		// Resolve the argument being completed.
+		specs := posArgs.args.Specs
+		if len(args) >= len(specs) {
+			if posArgs.args.ExtraArgsPtr != nil {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if !environmentArgNames[specs[len(args)].Name] {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		return completeEnvironmentIDs(), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// Get the environment IDs for shell completion, with the environment names as descriptions.
+// Uses the environments in the project's metaplay-project.yaml or, if not in a project, the
+// environments accessible in the portal. Errors are ignored so that completion never breaks.
+func completeEnvironmentIDs() []cobra.Completion {
+	// Use the project's environments, if in a project.
+	if project, err := resolveProject(); err == nil {
+		completions := []cobra.Completion{}
+		for _, envConfig := range project.Config.Environments {
+			completions = append(completions, cobra.CompletionWithDesc(envConfig.HumanID, envConfig.Name))
+		}
+		return completions
+	}
+
+	// Otherwise, discover the environments from the portal (only if already logged in).
+	tokenSet, err := auth.LoadAndRefreshTokenSet(auth.NewMetaplayAuthProvider())
+	if err != nil || tokenSet == nil {
+		return nil
+	}
+	result := make(chan []cobra.Completion, 1)
+	go func() {
+		result <- fetchPortalEnvironmentCompletions(portalapi.NewClient(tokenSet))
+	}()
+	select {
+	case completions := <-result:
+		return completions
+	case <-time.After(completionPortalTimeout):
+		return nil
+	}
+}
+
+// Fetch the environments of all the projects the user has access to in the portal.
+func fetchPortalEnvironmentCompletions(portalClient *portalapi.Client) []cobra.Completion {
+	projects, err := portalClient.FetchAllUserProjects()
+	if err != nil {
+		return nil
+	}
+	completions := []cobra.Completion{}
+	for _, project := range projects {
+		environments, err := portalClient.FetchProjectEnvironments(project.UUID)
+		if err != nil {
+			continue
+		}
+		for _, env := range environments {
+			completions = append(completions, cobra.CompletionWithDesc(env.HumanID, fmt.Sprintf("%s (%s)", env.Name, project.Name)))
+		}
+	}
+	return completions
+}
@@ -0,0 +1,26 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configCmd includes commands for editing the project config (metaplay-project.yaml).
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Edit the project config (metaplay-project.yaml)",
+}
+
+// configEnvironmentCmd includes commands for editing the environments in the project config.
+var configEnvironmentCmd = &cobra.Command{
+	Use:     "environment",
+	Aliases: []string{"env"},
+	Short:   "Edit the environments in the project config",
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configEnvironmentCmd)
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/portalapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Add an environment to the metaplay-project.yaml.
+type configEnvironmentAddOpts struct {
+	UsePositionalArgs
+	RequiresProject
+
+	argHumanID              string
+	flagName                string
+	flagType                string
+	flagStackDomain         string
+	flagServerValuesFile    string
+	flagBotClientValuesFile string
+}
+
+func init() {
+	o := configEnvironmentAddOpts{}
+
+	args := o.Arguments()
+	args.AddStringArgument(&o.argHumanID, "HUMANID", "Human ID of the environment, eg, 'tough-falcons'. Also the environment's Kubernetes namespace.")
+
+	cmd := &cobra.Command{
+		Use:   "add HUMANID [flags]",
+		Short: "Add an environment to the metaplay-project.yaml",
+		Run:   runCommand(&o),
+		Long: renderLong(&o, `
+			Add an environment to the 'environments' in the metaplay-project.yaml.
+
+			The environment is appended after the existing environments, and the rest of the
+			file, including its comments and field order, is retained as is. The human ID must
+			be unique within the project, and the stack domain must be a plain domain name,
+			eg, 'p1.metaplay.io', not a URL.
+
+			Environments managed in the Metaplay portal are best added with
+			'metaplay update project-environments' instead, which fetches their details from
+			the portal.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay config environment remove HUMANID' to remove an environment.
+			- 'metaplay update project-environments' to update the environments from the portal.
+			- 'metaplay env list' to list the project's environments.
+		`),
+		Example: trimIndent(`
+			# Add the development environment 'tough-falcons' in the stack 'p1.metaplay.io'.
+			metaplay config environment add tough-falcons --stack-domain=p1.metaplay.io
+
+			# Add a production environment with a name and a Helm values file.
+			metaplay config environment add idler-prod --name="Production" --type=production --stack-domain=p1.metaplay.io --server-values-file=Backend/Deployments/production-server.yaml
+		`),
+	}
+
+	configEnvironmentCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagName, "name", "", "Name of the environment, defaults to the human ID")
+	flags.StringVar(&o.flagType, "type", string(portalapi.EnvironmentTypeDevelopment), "Type of the environment: 'development', 'staging', or 'production'")
+	flags.StringVar(&o.flagStackDomain, "stack-domain", "", "Domain of the infrastructure stack hosting the environment, eg, 'p1.metaplay.io' (required)")
+	flags.StringVar(&o.flagServerValuesFile, "server-values-file", "", "Path (relative to metaplay-project.yaml) to the game server Helm values file")
+	flags.StringVar(&o.flagBotClientValuesFile, "botclient-values-file", "", "Path (relative to metaplay-project.yaml) to the bot client Helm values file")
+}
+
+func (o *configEnvironmentAddOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if err := metaproj.ValidateEnvironmentID(o.argHumanID); err != nil {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid HUMANID: %v", err)
+	}
+	if o.flagStackDomain == "" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--stack-domain is required, eg, --stack-domain=p1.metaplay.io")
+	}
+	if err := metaproj.ValidateStackDomain(o.flagStackDomain); err != nil {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid --stack-domain: %v", err)
+	}
+	if o.flagName == "" {
+		o.flagName = o.argHumanID
+	}
+	return nil
+}
+
+func (o *configEnvironmentAddOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	project := cmdCtx.Project
+
+	if _, err := project.Config.GetEnvironmentByHumanID(o.argHumanID); err == nil {
+		return exitcode.Errorf(exitcode.ExitUsage, "environment '%s' already exists in the project config", o.argHumanID)
+	}
+
+	envConfig := metaproj.ProjectEnvironmentConfig{
+		Name:                o.flagName,
+		HumanID:             o.argHumanID,
+		Type:                portalapi.EnvironmentType(o.flagType),
+		StackDomain:         o.flagStackDomain,
+		ServerValuesFile:    o.flagServerValuesFile,
+		BotClientValuesFile: o.flagBotClientValuesFile,
+	}
+
+	err := editProjectConfigFile(project, func(content string) (string, error) {
+		return metaproj.AddEnvironmentToConfigFile(content, envConfig)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Environment %s added to %s", o.argHumanID, project.GetConfigFilePath())))
+	return nil
+}
+
+// Edit the project config file with the editFunc, and check that the result is still a valid
+// config before writing it.
+func editProjectConfigFile(project *metaproj.MetaplayProject, editFunc func(content string) (string, error)) error {
+	configFilePath := project.GetConfigFilePath()
+	content, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read project config file: %w", err)
+	}
+
+	edited, err := editFunc(string(content))
+	if err != nil {
+		return err
+	}
+
+	if _, err := metaproj.ParseProjectConfig([]byte(edited), configFilePath); err != nil {
+		return fmt.Errorf("edited project config is invalid: %w", err)
+	}
+
+	if err := os.WriteFile(configFilePath, []byte(edited), 0644); err != nil {
+		return fmt.Errorf("failed to write project config file: %w", err)
+	}
+	return nil
+}
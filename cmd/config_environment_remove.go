@@ -0,0 +1,84 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Remove an environment from the metaplay-project.yaml.
+type configEnvironmentRemoveOpts struct {
+	UsePositionalArgs
+	RequiresProject
+
+	argHumanID string
+}
+
+func init() {
+	o := configEnvironmentRemoveOpts{}
+
+	args := o.Arguments()
+	args.AddStringArgument(&o.argHumanID, "HUMANID", "Human ID of the environment to remove, eg, 'tough-falcons'.")
+
+	cmd := &cobra.Command{
+		Use:               "remove HUMANID [flags]",
+		Aliases:           []string{"rm"},
+		Short:             "Remove an environment from the metaplay-project.yaml",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Remove an environment from the 'environments' in the metaplay-project.yaml.
+
+			The comments directly above the environment's entry are removed with it, and the
+			rest of the file is retained as is. Only the project config is edited: the
+			environment itself, and anything deployed into it, is not affected.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay config environment add HUMANID ...' to add an environment.
+			- 'metaplay env list' to list the project's environments.
+		`),
+		Example: trimIndent(`
+			# Remove the environment 'tough-falcons' from the project config.
+			metaplay config environment remove tough-falcons
+		`),
+	}
+
+	configEnvironmentCmd.AddCommand(cmd)
+}
+
+func (o *configEnvironmentRemoveOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *configEnvironmentRemoveOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	project := cmdCtx.Project
+
+	// Only accept exact human IDs to avoid removing the wrong environment.
+	if _, err := project.Config.GetEnvironmentByHumanID(o.argHumanID); err != nil {
+		humanIDs := []string{}
+		for _, envConfig := range project.Config.Environments {
+			humanIDs = append(humanIDs, envConfig.HumanID)
+		}
+		return exitcode.Errorf(exitcode.ExitNotFound, "environment '%s' not found in the project config, the valid environments are: %s", o.argHumanID, strings.Join(humanIDs, ", "))
+	}
+
+	err := editProjectConfigFile(project, func(content string) (string, error) {
+		return metaproj.RemoveEnvironmentFromConfigFile(content, o.argHumanID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Environment %s removed from %s", o.argHumanID, project.GetConfigFilePath())))
+	return nil
+}
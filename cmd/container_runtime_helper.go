@@ -0,0 +1,62 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/metaplay/cli/pkg/containerutil"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+)
+
+// Container runtime resolved for the current command, see resolveContainerRuntime().
+var resolvedContainerRuntime *containerutil.Runtime
+
+// Resolve the container runtime (docker or podman) to use for building, running, and pushing
+// images. The runtime is selected with --container-runtime, METAPLAYCLI_CONTAINER_RUNTIME, or
+// the project's 'containerRuntime' (if project is non-nil), and auto-detected otherwise. The
+// docker API clients are pointed at the runtime's API.
+func resolveContainerRuntime(ctx context.Context, project *metaproj.MetaplayProject) (*containerutil.Runtime, error) {
+	if resolvedContainerRuntime != nil {
+		return resolvedContainerRuntime, nil
+	}
+
+	preferred := coalesceString(flagContainerRuntime, os.Getenv("METAPLAYCLI_CONTAINER_RUNTIME"))
+	if preferred == "" && project != nil {
+		preferred = project.Config.ContainerRuntime
+	}
+
+	log.Debug().Msgf("Detect container runtime (preferred: %s)", coalesceString(preferred, "auto"))
+	rt, err := containerutil.Detect(ctx, preferred)
+	if err != nil {
+		return nil, err
+	}
+	rt.ConfigureAPIClients()
+
+	// Docker is the common case, only mention other runtimes.
+	if rt.Name != containerutil.RuntimeDocker || rt.Rootless {
+		stderrLogger.Info().Msgf(styles.RenderMuted("Container runtime: %s"), rt)
+	} else {
+		log.Debug().Msgf("Container runtime: %s", rt)
+	}
+
+	resolvedContainerRuntime = rt
+	return rt, nil
+}
+
+// Resolve the container runtime for operations using its docker-compatible API, ie, listing,
+// inspecting, and pushing local images, and check that the API is available.
+func resolveContainerRuntimeAPI(ctx context.Context, project *metaproj.MetaplayProject) (*containerutil.Runtime, error) {
+	rt, err := resolveContainerRuntime(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	if err := rt.CheckAPIAvailable(); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
@@ -37,10 +37,11 @@ func init() {
 	args.AddStringArgument(&o.argPath, "PATH", "Path for the admin API request, eg '/api/v1/status'.")
 
 	cmd := &cobra.Command{
-		Use:     "admin-request ENVIRONMENT METHOD PATH [flags]",
-		Aliases: []string{"admin"},
-		Short:   "[preview] Make HTTP requests to the game server admin API",
-		Run:     runCommand(&o),
+		Use:               "admin-request ENVIRONMENT METHOD PATH [flags]",
+		Aliases:           []string{"admin"},
+		Short:             "[preview] Make HTTP requests to the game server admin API",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			PREVIEW: This is a preview feature and interface may change in the future.
 
@@ -112,7 +113,7 @@ func (o *debugAdminRequestOpts) Prepare(cmd *cobra.Command, args []string) error
 	return nil
 }
 
-func (o *debugAdminRequestOpts) Run(cmd *cobra.Command) error {
+func (o *debugAdminRequestOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -133,6 +134,9 @@ func (o *debugAdminRequestOpts) Run(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
+	if !envDetails.HasGameServerDeployment() {
+		return fmt.Errorf("environment %s has no admin hostname for the game server", envConfig.HumanID)
+	}
 
 	// Create a client for the admin API
 	adminAPIBaseURL := fmt.Sprintf("https://%s", envDetails.Deployment.AdminHostname)
@@ -70,7 +70,8 @@ func init() {
 			# Pass extra arguments to dotnet-trace (after --)
 			metaplay debug collect-cpu-profile tough-falcons -- --providers Microsoft-Windows-DotNETRuntime:4:4
 		`),
-		Run: runCommand(&o),
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 	}
 	debugCmd.AddCommand(cmd)
 
@@ -138,7 +139,7 @@ func (o *CollectCpuProfileOpts) Prepare(cmd *cobra.Command, args []string) error
 	return nil
 }
 
-func (o *CollectCpuProfileOpts) Run(cmd *cobra.Command) error {
+func (o *CollectCpuProfileOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -72,7 +72,8 @@ func init() {
 			# Don't ask for confirmation on the operation.
 			metaplay debug collect-heap-dump tough-falcons --yes
 		`),
-		Run: runCommand(&o),
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 	}
 	debugCmd.AddCommand(cmd)
 
@@ -111,7 +112,7 @@ func (o *CollectHeapDumpOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *CollectHeapDumpOpts) Run(cmd *cobra.Command) error {
+func (o *CollectHeapDumpOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
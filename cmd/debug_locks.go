@@ -0,0 +1,138 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Show (and optionally break) the operation lock of an environment.
+type debugLocksOpts struct {
+	UsePositionalArgs
+
+	argEnvironment  string
+	flagForceUnlock bool
+}
+
+func init() {
+	o := debugLocksOpts{}
+
+	args := o.Arguments()
+	args.AddStringArgumentOpt(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
+
+	cmd := &cobra.Command{
+		Use:               "locks ENVIRONMENT [flags]",
+		Short:             "Show the holder of the environment's operation lock",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Show who is currently holding the operation lock of an environment.
+
+			Mutating operations like 'metaplay deploy server' and 'metaplay remove server' hold
+			an advisory lock on the environment to prevent concurrent invocations from conflicting
+			with each other. The lock is kept alive with a periodic heartbeat and expires if the
+			holder stops sending them, eg, when a CI job gets killed.
+
+			Use --force-unlock to remove a stale lock. Only locks whose heartbeat has expired
+			can be removed.
+
+			{Arguments}
+		`),
+		Example: trimIndent(`
+			# Show the current holder of the lock in environment tough-falcons.
+			metaplay debug locks tough-falcons
+
+			# Remove a stale lock from environment tough-falcons.
+			metaplay debug locks tough-falcons --force-unlock
+		`),
+	}
+	debugCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVar(&o.flagForceUnlock, "force-unlock", false, "Remove the lock if its holder's heartbeat has expired")
+}
+
+func (o *debugLocksOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *debugLocksOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Try to resolve the project & auth provider.
+	project, err := tryResolveProject()
+	if err != nil {
+		return err
+	}
+
+	// Resolve environment.
+	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.argEnvironment)
+	if err != nil {
+		return err
+	}
+
+	// Create TargetEnvironment.
+	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+
+	// Fetch the current lock state.
+	lockInfo, err := targetEnv.GetOperationLock(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Environment Operation Lock"))
+	log.Info().Msg("")
+
+	if lockInfo == nil {
+		log.Info().Msgf("No operation lock is held in environment %s", styles.RenderTechnical(envConfig.HumanID))
+		return nil
+	}
+
+	stateBadge := styles.RenderSuccess("[active]")
+	if lockInfo.IsExpired() {
+		stateBadge = styles.RenderWarning("[expired]")
+	}
+	log.Info().Msgf("Holder:         %s %s", styles.RenderTechnical(lockInfo.Holder.User), stateBadge)
+	log.Info().Msgf("Host:           %s", styles.RenderTechnical(lockInfo.Holder.Host))
+	log.Info().Msgf("CLI version:    %s", styles.RenderTechnical(lockInfo.Holder.CliVersion))
+	log.Info().Msgf("Operation:      %s", styles.RenderTechnical(lockInfo.Holder.Operation))
+	log.Info().Msgf("Acquired:       %s", styles.RenderTechnical(humanize.Time(lockInfo.AcquireTime)))
+	log.Info().Msgf("Last heartbeat: %s", styles.RenderTechnical(humanize.Time(lockInfo.RenewTime)))
+	log.Info().Msg("")
+
+	if !o.flagForceUnlock {
+		return nil
+	}
+
+	// Only allow breaking locks whose holder has stopped sending heartbeats.
+	if !lockInfo.IsExpired() {
+		return fmt.Errorf("refusing to remove the lock: holder's last heartbeat was %s ago, lock expires after %s without heartbeats", time.Since(lockInfo.RenewTime).Round(time.Second), lockInfo.LeaseDuration)
+	}
+
+	// Confirm from the user (in interactive mode).
+	if tui.IsInteractive() {
+		confirmed, err := tui.DoConfirmQuestion(cmd.Context(), fmt.Sprintf("Remove the stale lock held by %s?", lockInfo.Holder.User))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			log.Info().Msg("Cancelled")
+			return nil
+		}
+	}
+
+	if err := targetEnv.ForceReleaseOperationLock(cmd.Context()); err != nil {
+		return err
+	}
+
+	log.Info().Msg(styles.RenderSuccess("✅ Removed the stale operation lock"))
+	return nil
+}
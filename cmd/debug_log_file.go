@@ -0,0 +1,172 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/metaplay/cli/pkg/common"
+)
+
+// Name of the persistent debug log file (in the CLI state directory).
+const debugLogFileName = "cli-debug.log"
+
+// Rotate the debug log when it grows beyond this size, keeping this many old files.
+const debugLogMaxSize = 5 * 1024 * 1024
+const debugLogMaxBackups = 3
+
+// Matches JWTs (eg, access and ID tokens) so they can be scrubbed before writing to disk.
+var jwtTokenRegex = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+
+// Matches the values of secret fields in JSON ('"refresh_token": "..."'), YAML ('token: ...'),
+// and text ('AWS Session Token: ...') output, eg, refresh tokens, AWS credentials, kubeconfig
+// credentials and prefixed fields like 'loki_password', which aren't JWTs.
+var secretFieldRegex = regexp.MustCompile(`(?i)(\b(?:[a-z0-9]+_)*(?:refresh_token|access_token|id_token|client_secret|secretaccesskey|sessiontoken|secret access key|session token|token|client-key-data|password)"?\s*:\s*"?)[^"\s,<]+`)
+
+// Matches AWS access key IDs (long-term 'AKIA...' and temporary 'ASIA...' keys).
+var awsAccessKeyIDRegex = regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`)
+
+// Matches ANSI escape sequences (colors), which are stripped from the log file.
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Replace anything looking like an auth token or other credentials in the input with a placeholder.
+func redactTokens(input []byte) []byte {
+	output := jwtTokenRegex.ReplaceAll(input, []byte("<redacted-token>"))
+	output = secretFieldRegex.ReplaceAll(output, []byte("${1}<redacted-secret>"))
+	return awsAccessKeyIDRegex.ReplaceAll(output, []byte("<redacted-secret>"))
+}
+
+// Get the path to the persistent debug log file.
+func getDebugLogFilePath() (string, error) {
+	stateDir, err := common.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "logs", debugLogFileName), nil
+}
+
+// Writer that appends to a log file and rotates it when it grows too large.
+// Auth tokens and other credentials are redacted from everything written.
+type rotatingFileWriter struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+// Open the debug log file for appending (creating it if needed).
+func openDebugLogFile() (*rotatingFileWriter, error) {
+	path, err := getDebugLogFilePath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	w := &rotatingFileWriter{path: path}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotatingFileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", w.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// Shift the old log files by one (dropping the oldest) and start a new file.
+func (w *rotatingFileWriter) rotate() error {
+	w.file.Close()
+	for ndx := debugLogMaxBackups - 1; ndx >= 1; ndx-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", w.path, ndx), fmt.Sprintf("%s.%d", w.path, ndx+1))
+	}
+	_ = os.Rename(w.path, w.path+".1")
+	return w.open()
+}
+
+func (w *rotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.size+int64(len(p)) > debugLogMaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(redactTokens(ansiEscapeRegex.ReplaceAll(p, nil)))
+	w.size += int64(n)
+	// Report the original length to not confuse callers about the redacted contents.
+	if err == nil {
+		n = len(p)
+	}
+	return n, err
+}
+
+// Read the last numLines lines from the debug log (including the rotated
+// files if the current one is shorter than that).
+func readDebugLogTail(numLines int) ([]string, error) {
+	path, err := getDebugLogFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Files from newest to oldest.
+	files := []string{path}
+	for ndx := 1; ndx <= debugLogMaxBackups; ndx++ {
+		files = append(files, fmt.Sprintf("%s.%d", path, ndx))
+	}
+
+	lines := []string{}
+	for _, filePath := range files {
+		if len(lines) >= numLines {
+			break
+		}
+		fileLines, err := readFileLines(filePath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		lines = append(fileLines, lines...)
+	}
+
+	if len(lines) > numLines {
+		lines = lines[len(lines)-numLines:]
+	}
+	return lines, nil
+}
+
+func readFileLines(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
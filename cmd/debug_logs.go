@@ -8,10 +8,10 @@ import (
 	"container/heap"
 	"context"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/envapi"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -44,9 +44,10 @@ func init() {
 	args.AddStringArgumentOpt(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
 
 	cmd := &cobra.Command{
-		Use:   "logs [ENVIRONMENT] [flags]",
-		Short: "Show logs from one or more game server pods",
-		Run:   runCommand(&o),
+		Use:               "logs [ENVIRONMENT] [flags]",
+		Short:             "Show logs from one or more game server pods",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Show logs from one or more game server pods in the target environment.
 
@@ -101,7 +102,7 @@ func (o *debugLogsOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *debugLogsOpts) Run(cmd *cobra.Command) error {
+func (o *debugLogsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	if o.flagSince != 0 {
 		log.Debug().Msgf("Since: %v", o.flagSince)
 	}
@@ -132,12 +133,10 @@ func (o *debugLogsOpts) Run(cmd *cobra.Command) error {
 	// \todo Keep updating the list of pods to dynamically adapt to new/delete pods.
 	pods, err := envapi.FetchGameServerPods(cmd.Context(), kubeCli)
 	if err != nil {
-		log.Error().Msgf("Failed to determine game server pods in the environment: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to determine game server pods in the environment: %w", err)
 	}
 	if len(pods) == 0 {
-		log.Error().Msgf("No game server pods found in the environment. Make sure you have a game server deployed.")
-		os.Exit(1)
+		return exitcode.Errorf(exitcode.ExitNotFound, "no game server pods found in the environment, make sure you have a game server deployed")
 	}
 	log.Debug().Msgf("Found %d game server pods: %s", len(pods), strings.Join(getPodNames(pods), ", "))
 
@@ -182,7 +181,7 @@ func (o *debugLogsOpts) readOrderedLogs(ctx context.Context, kubeCli *envapi.Kub
 
 	// Next, aggregate real-time sources with a small time-window for merging sources in timestamp order.
 	if o.flagFollow {
-		aggregateRealtimeSourcesInTimeOrder(realtimeSources)
+		aggregateRealtimeSourcesInTimeOrder(realtimeSources, printLogEntry)
 	}
 
 	return nil
@@ -270,7 +269,9 @@ func readPodLogs(ctx context.Context, source *podLogSource, cutoffTime *time.Tim
 	// Open a stream to read log entries from Kubernetes.
 	stream, err := source.request.Stream(ctx)
 	if err != nil {
-		log.Error().Msgf("Failed to open stream for pod %s: %v", source.prefix, err)
+		if ctx.Err() == nil {
+			log.Error().Msgf("Failed to open stream for pod %s: %v", source.prefix, err)
+		}
 		return
 	}
 	defer stream.Close()
@@ -318,7 +319,7 @@ func readPodLogs(ctx context.Context, source *podLogSource, cutoffTime *time.Tim
 	}
 
 	// Handle scanner errors.
-	if err := scanner.Err(); err != nil {
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
 		log.Error().Msgf("Scanner error for pod %s: %v", source.prefix, err)
 	}
 }
@@ -388,14 +389,15 @@ func aggregateHistoricalLogsInTimeOrder(sources []*podLogSource) {
 	// (all channels are either exhausted or never produced a line).
 }
 
-// Aggregate real-time logs from sources in best-effort timestamp order.
+// Aggregate real-time logs from sources in best-effort timestamp order, passing each entry
+// to printEntry.
 // A small time window is used for keeping the items in timestamp order but
 // in the event of network latencies larger than the window, it's possible
 // that entries are printed out-of-order.
 // \todo Optimize the memory usage by not fetching only one entry per source at a time.
 // This also fixes a potential misordering of entries if they have the same timestamp
 // (heap provides no guarantees of stable ordering of items with identical priority).
-func aggregateRealtimeSourcesInTimeOrder(sources []*podLogSource) {
+func aggregateRealtimeSourcesInTimeOrder(sources []*podLogSource, printEntry func(prefix string, entry LogEntry)) {
 	// Initialize a min-heap for log entries
 	var pq logEntryHeap
 	heap.Init(&pq)
@@ -453,7 +455,7 @@ func aggregateRealtimeSourcesInTimeOrder(sources []*podLogSource) {
 			oldest := pq[0] // peek at the earliest event
 			if oldest.entry.timestamp.Before(cutoff) {
 				popped := heap.Pop(&pq).(entryWithSource)
-				log.Info().Msgf("%s%s", sources[popped.sourceNdx].prefix, popped.entry.message)
+				printEntry(sources[popped.sourceNdx].prefix, popped.entry)
 			} else {
 				// The earliest event is still within the 1-second window,
 				// so we wait for the next iteration in case something older arrives.
@@ -470,6 +472,18 @@ func aggregateRealtimeSourcesInTimeOrder(sources []*podLogSource) {
 	// so there's nothing left to print.
 }
 
+// Print a log entry prefixed with its source.
+func printLogEntry(prefix string, entry LogEntry) {
+	log.Info().Msgf("%s%s", prefix, entry.message)
+}
+
+// Follow the logs of the pods, starting from sinceTime, until the context is cancelled.
+// The entries are passed to printEntry in best-effort timestamp order.
+func followPodLogs(ctx context.Context, kubeCli *envapi.KubeClient, pods []corev1.Pod, sinceTime time.Time, printEntry func(prefix string, entry LogEntry)) {
+	sources := readRealtimeLogsFromPods(ctx, kubeCli, pods, sinceTime)
+	aggregateRealtimeSourcesInTimeOrder(sources, printEntry)
+}
+
 // getPodNames extracts the names of each pod in the input array.
 func getPodNames(pods []corev1.Pod) []string {
 	names := make([]string, len(pods))
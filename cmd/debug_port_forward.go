@@ -0,0 +1,302 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/portutil"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/pkg/browser"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Delays between attempts to re-establish a dropped port-forward.
+const (
+	portForwardInitialRetryDelay = 1 * time.Second
+	portForwardMaxRetryDelay     = 30 * time.Second
+)
+
+// Forward local ports to the observability and admin services in an environment.
+type debugPortForwardOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	argService   string
+	argLocalPort string
+	flagServices []string
+	flagList     bool
+	flagOpen     bool
+
+	localPort int
+}
+
+func init() {
+	o := debugPortForwardOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+	args.AddStringArgumentOpt(&o.argService, "SERVICE", "Service to forward to: 'admin', 'grafana' or 'prometheus'.")
+	args.AddStringArgumentOpt(&o.argLocalPort, "LOCAL_PORT", "Local port to listen on, eg, 8080. Defaults to the service's usual port, or a free port if it is taken.")
+
+	cmd := &cobra.Command{
+		Use:               "port-forward ENVIRONMENT [SERVICE] [LOCAL_PORT] [flags]",
+		Short:             "Forward local ports to the observability and admin services of an environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Forward a local port to a well-known service running in the environment's namespace,
+			equivalent to 'kubectl port-forward', and print the local URL to access it with.
+
+			The available services are:
+			- admin: the game server's admin API and LiveOps Dashboard (port 5550).
+			- grafana: Grafana dashboards, if deployed in the environment.
+			- prometheus: Prometheus metrics, if deployed in the environment.
+
+			Use --list to show the services available in the environment. If no service is given,
+			you are asked to choose one in interactive mode.
+
+			Multiple services can be forwarded at the same time by passing --service multiple
+			times. Each service gets its own local port.
+
+			The command keeps running until interrupted with Ctrl-C. If the connection drops, eg,
+			when the pod is restarted, the port-forward is automatically re-established.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay env open ...' to open the LiveOps Dashboard via its public address.
+		`),
+		Example: trimIndent(`
+			# List the services available for port-forwarding in environment tough-falcons.
+			metaplay debug port-forward tough-falcons --list
+
+			# Forward to Grafana and open it in the browser.
+			metaplay debug port-forward tough-falcons grafana --open
+
+			# Forward the game server admin API to local port 8080.
+			metaplay debug port-forward tough-falcons admin 8080
+
+			# Forward both Grafana and Prometheus at the same time.
+			metaplay debug port-forward tough-falcons --service grafana --service prometheus
+		`),
+	}
+	debugCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringArrayVar(&o.flagServices, "service", []string{}, "Service to forward to, can be given multiple times")
+	flags.BoolVar(&o.flagList, "list", false, "Only list the services available for port-forwarding")
+	flags.BoolVar(&o.flagOpen, "open", false, "Open the forwarded services in the browser")
+}
+
+func (o *debugPortForwardOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.argService != "" {
+		o.flagServices = append([]string{o.argService}, o.flagServices...)
+	}
+
+	if o.argLocalPort != "" {
+		port, err := strconv.Atoi(o.argLocalPort)
+		if err != nil || port < 1 || port > 65535 {
+			return exitcode.Errorf(exitcode.ExitUsage, "invalid LOCAL_PORT '%s', must be a number between 1 and 65535", o.argLocalPort)
+		}
+		if len(o.flagServices) > 1 {
+			return exitcode.Errorf(exitcode.ExitUsage, "LOCAL_PORT can only be given when forwarding a single service")
+		}
+		o.localPort = port
+	}
+
+	if o.flagList && (len(o.flagServices) > 0 || o.flagOpen) {
+		return exitcode.Errorf(exitcode.ExitUsage, "--list cannot be used with a service or --open")
+	}
+
+	return nil
+}
+
+func (o *debugPortForwardOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+
+	// Find the services that can be forwarded to.
+	targets, err := envapi.DiscoverPortForwardTargets(cmd.Context(), kubeCli)
+	if err != nil {
+		return err
+	}
+
+	if o.flagList {
+		log.Info().Msg("")
+		log.Info().Msg(styles.RenderTitle(fmt.Sprintf("Services in Environment %s", envConfig.HumanID)))
+		log.Info().Msg("")
+		if len(targets) == 0 {
+			log.Info().Msg("No services available for port-forwarding")
+			return nil
+		}
+		for _, target := range targets {
+			log.Info().Msgf("  %-12s %s", styles.RenderTechnical(target.Name), styles.RenderMuted(target.Description))
+		}
+		return nil
+	}
+
+	// Resolve the targets to forward to.
+	selected, err := o.selectTargets(targets, envConfig.HumanID)
+	if err != nil {
+		return err
+	}
+
+	// Assign distinct local ports for the forwards.
+	usedPorts := map[int]bool{}
+	localPorts := make([]int, len(selected))
+	for ndx, target := range selected {
+		if o.localPort != 0 {
+			if portutil.IsPortInUse(o.localPort) {
+				return fmt.Errorf("local port %d is already in use", o.localPort)
+			}
+			localPorts[ndx] = o.localPort
+		} else {
+			preferred := target.LocalPort
+			if usedPorts[preferred] {
+				preferred = 0
+			}
+			port, err := portutil.FindFreePort(preferred)
+			if err != nil {
+				return err
+			}
+			localPorts[ndx] = port
+		}
+		usedPorts[localPorts[ndx]] = true
+	}
+
+	// Keep forwarding until interrupted.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Info().Msg("")
+	log.Info().Msgf("Forwarding to services in environment %s, press Ctrl-C to stop", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msg("")
+
+	var wg sync.WaitGroup
+	for ndx := range selected {
+		wg.Add(1)
+		go func(target envapi.PortForwardTarget, localPort int) {
+			defer wg.Done()
+			o.forwardWithReconnect(ctx, kubeCli, target, localPort)
+		}(selected[ndx], localPorts[ndx])
+	}
+	wg.Wait()
+
+	log.Info().Msg("")
+	log.Info().Msg("Stopped port-forwarding")
+	return nil
+}
+
+// Resolve the targets to forward to from the requested service names, or ask the user to choose
+// one if none were given.
+func (o *debugPortForwardOpts) selectTargets(targets []envapi.PortForwardTarget, envHumanID string) ([]envapi.PortForwardTarget, error) {
+	availableNames := make([]string, len(targets))
+	for ndx, target := range targets {
+		availableNames[ndx] = target.Name
+	}
+
+	if len(o.flagServices) == 0 {
+		if len(targets) == 0 {
+			return nil, exitcode.Errorf(exitcode.ExitNotFound, "no services available for port-forwarding in environment %s", envHumanID)
+		}
+		if !tui.IsInteractive() {
+			return nil, exitcode.Errorf(exitcode.ExitUsage, "no service specified, available services are: %s", strings.Join(availableNames, ", "))
+		}
+		target, err := tui.ChooseFromListDialog("Select Service", targets, func(target *envapi.PortForwardTarget) (string, string) {
+			return target.Name, target.Description
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Msgf(" %s %s", styles.RenderSuccess("✓"), target.Name)
+		return []envapi.PortForwardTarget{*target}, nil
+	}
+
+	selected := []envapi.PortForwardTarget{}
+	seen := map[string]bool{}
+	for _, name := range o.flagServices {
+		if seen[name] {
+			return nil, exitcode.Errorf(exitcode.ExitUsage, "service '%s' specified multiple times", name)
+		}
+		seen[name] = true
+
+		found := false
+		for _, target := range targets {
+			if target.Name == name {
+				selected = append(selected, target)
+				found = true
+				break
+			}
+		}
+		if !found {
+			if len(targets) == 0 {
+				return nil, exitcode.Errorf(exitcode.ExitNotFound, "service '%s' not found in environment %s, no services are available for port-forwarding", name, envHumanID)
+			}
+			return nil, exitcode.Errorf(exitcode.ExitNotFound, "service '%s' not found in environment %s, available services are: %s", name, envHumanID, strings.Join(availableNames, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// Forward the local port to the target until the context is cancelled. Re-establishes the
+// port-forward (to a possibly different pod) with an increasing delay if the connection drops.
+func (o *debugPortForwardOpts) forwardWithReconnect(ctx context.Context, kubeCli *envapi.KubeClient, target envapi.PortForwardTarget, localPort int) {
+	localURL := fmt.Sprintf("http://localhost:%d", localPort)
+	retryDelay := portForwardInitialRetryDelay
+	var openBrowserOnce sync.Once
+
+	for {
+		var connected atomic.Bool
+		podName, podPort, err := target.ResolvePod(ctx, kubeCli)
+		if err == nil {
+			log.Debug().Msgf("Forwarding localhost:%d to pod %s port %d", localPort, podName, podPort)
+			err = kubeCli.PortForward(ctx, podName, localPort, podPort, func() {
+				connected.Store(true)
+				log.Info().Msgf("%-12s %s %s", styles.RenderTechnical(target.Name), styles.RenderLink(localURL, localURL), styles.RenderMuted(fmt.Sprintf("(pod %s)", podName)))
+				if o.flagOpen {
+					openBrowserOnce.Do(func() {
+						if err := browser.OpenURL(localURL); err != nil {
+							log.Warn().Msgf("Failed to open the browser, open %s manually: %v", localURL, err)
+						}
+					})
+				}
+			})
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Start over with a short delay if the connection was up before dropping.
+		if connected.Load() {
+			retryDelay = portForwardInitialRetryDelay
+		}
+
+		log.Warn().Msgf("Port-forward to %s interrupted, reconnecting in %s: %v", target.Name, retryDelay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+		retryDelay = min(retryDelay*2, portForwardMaxRetryDelay)
+	}
+}
@@ -6,6 +6,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/dustin/go-humanize"
 	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/envapi"
 	"github.com/metaplay/cli/pkg/helmutil"
@@ -28,10 +29,11 @@ func init() {
 	args.AddStringArgumentOpt(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
 
 	cmd := &cobra.Command{
-		Use:     "server-status ENVIRONMENT [flags]",
-		Aliases: []string{"srv"},
-		Short:   "Check the status of a game server deployment",
-		Run:     runCommand(&o),
+		Use:               "server-status ENVIRONMENT [flags]",
+		Aliases:           []string{"srv"},
+		Short:             "Check the status of a game server deployment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Check the status of a game server deployment.
 
@@ -62,7 +64,7 @@ func (o *debugCheckServerStatus) Prepare(cmd *cobra.Command, args []string) erro
 	return nil
 }
 
-func (o *debugCheckServerStatus) Run(cmd *cobra.Command) error {
+func (o *debugCheckServerStatus) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -103,11 +105,14 @@ func (o *debugCheckServerStatus) Run(cmd *cobra.Command) error {
 		return fmt.Errorf("failed to initialize Helm config: %v", err)
 	}
 
-	// Determine if there's an existing release deployed.
-	existingRelease, err := helmutil.GetExistingRelease(actionConfig, metaplayGameServerChartName)
+	// Find all the game server releases deployed in the environment.
+	releases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), metaplayGameServerChartName)
 	if err != nil {
 		return err
 	}
+	if len(releases) == 0 {
+		return fmt.Errorf("no game server deployment found in environment %s", envConfig.HumanID)
+	}
 
 	log.Info().Msg("")
 	log.Info().Msg(styles.RenderTitle("Check Game Server Deployment Status"))
@@ -115,16 +120,32 @@ func (o *debugCheckServerStatus) Run(cmd *cobra.Command) error {
 	log.Info().Msgf("Target environment:")
 	log.Info().Msgf("  Name:              %s", styles.RenderTechnical(envConfig.Name))
 	log.Info().Msgf("  ID:                %s", styles.RenderTechnical(envConfig.HumanID))
-	log.Info().Msgf("  Type:              %s", styles.RenderTechnical(string(envConfig.Type)))
+	log.Info().Msgf("  Type:              %s", renderEnvironmentType(envConfig.Type))
 	log.Info().Msgf("  Stack domain:      %s", styles.RenderTechnical(envConfig.StackDomain))
-	log.Info().Msg("Deployment info:")
-	log.Info().Msgf("  Helm release name: %s", styles.RenderTechnical(existingRelease.Name))
-	log.Info().Msgf("  Chart version:     %s", styles.RenderTechnical(existingRelease.Chart.Metadata.Version))
-	// Print image name/tag from chart values
-	if imageTag, ok := existingRelease.Config["image"].(map[string]interface{})["tag"].(string); ok {
-		log.Info().Msgf("  Image tag:         %s", styles.RenderTechnical(imageTag))
+	for _, release := range releases {
+		log.Info().Msg("Deployment info:")
+		log.Info().Msgf("  Helm release name: %s", styles.RenderTechnical(release.Name))
+		log.Info().Msgf("  Chart version:     %s", styles.RenderTechnical(release.Chart.Metadata.Version))
+		// Print image name/tag from chart values
+		if imageValues, ok := release.Config["image"].(map[string]interface{}); ok {
+			if imageTag, ok := imageValues["tag"].(string); ok {
+				log.Info().Msgf("  Image tag:         %s", styles.RenderTechnical(imageTag))
+			}
+		}
+		log.Info().Msgf("  Public hostname:   %s", styles.RenderTechnical(resolveGameServerHostname(release.Config, envDetails.Deployment.ServerHostname)))
+		log.Info().Msgf("  Status:            %s", styles.RenderTechnical(release.Info.Status.String()))
+		log.Info().Msgf("  Revision:          %s", styles.RenderTechnical(fmt.Sprintf("%d", release.Version)))
+		log.Info().Msgf("  Last deployed:     %s", styles.RenderTechnical(humanize.Time(release.Info.LastDeployed.Time)))
+		if deployer := describeReleaseDeployer(release); deployer != "" {
+			log.Info().Msgf("  Deployed by:       %s", styles.RenderTechnical(deployer))
+		} else {
+			log.Info().Msgf("  Deployed by:       %s", styles.RenderMuted("not managed by the Metaplay CLI"))
+		}
+		if release.Info.Description != "" {
+			log.Info().Msgf("  Description:       %s", styles.RenderTechnical(release.Info.Description))
+		}
+		log.Info().Msg("")
 	}
-	log.Info().Msg("")
 
 	taskRunner := tui.NewTaskRunner()
 
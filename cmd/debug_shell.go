@@ -59,10 +59,11 @@ func init() {
 	args.AddStringArgumentOpt(&o.PodName, "POD", "Target pod name, eg, 'all-0'.")
 
 	cmd := &cobra.Command{
-		Use:     "shell [ENVIRONMENT] [POD] [flags]",
-		Aliases: []string{"sh"},
-		Short:   "[preview] Start a debug container targeting the specified pod",
-		Run:     runCommand(&o),
+		Use:               "shell [ENVIRONMENT] [POD] [flags]",
+		Aliases:           []string{"sh"},
+		Short:             "[preview] Start a debug container targeting the specified pod",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			PREVIEW: This command is in preview and subject to change
 
@@ -107,7 +108,7 @@ func (o *debugShellOpts) Prepare(cmd *cobra.Command, args []string) error {
 }
 
 // Run executes the command
-func (o *debugShellOpts) Run(cmd *cobra.Command) error {
+func (o *debugShellOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -246,7 +247,7 @@ func resolveTargetPod(gameServer *envapi.TargetGameServer, podName string) (*env
 }
 
 func chooseTargetShardAndPodDialog(shardSetsWithPods []envapi.ShardSetWithPods) (*envapi.KubeClient, *corev1.Pod, error) {
-	if !tui.IsInteractiveMode() {
+	if !tui.IsInteractive() {
 		return nil, nil, fmt.Errorf("interactive mode required for selecting target pod")
 	}
 
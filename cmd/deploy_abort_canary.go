@@ -0,0 +1,95 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Remove the canary game server release, routing all traffic back to the stable release.
+type deployAbortCanaryOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagTimeout     time.Duration
+	flagLockTimeout time.Duration
+}
+
+func init() {
+	o := deployAbortCanaryOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "abort-canary ENVIRONMENT [flags]",
+		Short:             "Remove the canary game server release",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Remove the canary game server release (deployed with 'metaplay deploy server
+			--canary-percent'), routing all traffic back to the stable release. The stable release
+			is not modified.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay deploy server ... --canary-percent N' to deploy a canary release.
+			- 'metaplay deploy promote-canary ...' to promote the canary release to stable.
+		`),
+		Example: trimIndent(`
+			# Remove the canary release in environment tough-falcons.
+			metaplay deploy abort-canary tough-falcons
+		`),
+	}
+	deployCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.DurationVar(&o.flagTimeout, "timeout", defaultHelmTimeout, "Timeout for the Helm operation")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+}
+
+func (o *deployAbortCanaryOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *deployAbortCanaryOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	actionConfig, stable, canary, err := findEnvironmentCanaryReleases(targetEnv, envConfig)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Abort Canary Release"))
+	printCanaryStatus(stable, canary)
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "abort canary", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	taskRunner := tui.NewTaskRunner()
+	taskRunner.AddTask(fmt.Sprintf("Remove canary release %s", canary.Name), func(output *tui.TaskOutput) error {
+		err := helmutil.UninstallRelease(actionConfig, canary, o.flagTimeout)
+		return reportHelmUninstallTimeout(actionConfig, canary.Name, err)
+	})
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("✅ %s", styles.RenderSuccess(fmt.Sprintf("Removed canary release %s, all traffic is routed to stable release %s (image %s)", canary.Name, stable.Name, helmutil.GetReleaseImageTag(stable))))
+	return nil
+}
@@ -36,6 +36,9 @@ type deployBotClientOpts struct {
 	flagHelmChartRepository string
 	flagHelmChartVersion    string
 	flagHelmValuesPath      string
+	flagTimeout             time.Duration
+	flagLockTimeout         time.Duration
+	flagOverridePolicy      bool
 }
 
 func init() {
@@ -47,10 +50,11 @@ func init() {
 	args.SetExtraArgs(&o.extraArgs, "Passed as-is to Helm.")
 
 	cmd := &cobra.Command{
-		Use:     "botclient [ENVIRONMENT] [IMAGE_TAG] [flags] [-- EXTRA_ARGS]",
-		Aliases: []string{"bots", "botclients"},
-		Short:   "[preview] Deploy load testing bots into the target environment",
-		Run:     runCommand(&o),
+		Use:               "botclient [ENVIRONMENT] [IMAGE_TAG] [flags] [-- EXTRA_ARGS]",
+		Aliases:           []string{"bots", "botclients"},
+		Short:             "[preview] Deploy load testing bots into the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			PREVIEW: This command is in preview and subject to change! It also still lacks some
 			key functionality.
@@ -79,6 +83,9 @@ func init() {
 	flags.StringVar(&o.flagHelmChartRepository, "helm-chart-repo", "", "Override for Helm chart repository to use for the metaplay-loadtest chart")
 	flags.StringVar(&o.flagHelmChartVersion, "helm-chart-version", "", "Override for Helm chart version to use, eg, '0.4.2'")
 	flags.StringVarP(&o.flagHelmValuesPath, "values", "f", "", "Override for path to the Helm values file, e.g., 'Backend/Deployments/develop-server.yaml'")
+	flags.DurationVar(&o.flagTimeout, "timeout", defaultHelmTimeout, "Timeout for the Helm operation")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+	flags.BoolVar(&o.flagOverridePolicy, "override-policy", false, "Override the project's environment policies (requires typed confirmation)")
 }
 
 func (o *deployBotClientOpts) Prepare(cmd *cobra.Command, args []string) error {
@@ -89,11 +96,14 @@ func (o *deployBotClientOpts) Prepare(cmd *cobra.Command, args []string) error {
 	if strings.Contains(o.argImageTag, ":") {
 		return fmt.Errorf("IMAGE_TAG must contain only the tag (not the repository prefix), eg, '364cff092af8646bd'")
 	}
+	if err := checkImageTagNotLatest(o.argImageTag); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func (o *deployBotClientOpts) Run(cmd *cobra.Command) error {
+func (o *deployBotClientOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -144,6 +154,9 @@ func (o *deployBotClientOpts) Run(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
+	if err := envDetails.Validate(); err != nil {
+		return err
+	}
 
 	// Resolve path to Helm chart (local or remote).
 	var helmChartPath string
@@ -180,7 +193,7 @@ func (o *deployBotClientOpts) Run(cmd *cobra.Command) error {
 	}
 
 	// Determine if there's an existing release deployed.
-	existingRelease, err := helmutil.GetExistingRelease(actionConfig, metaplayLoadTestChartName)
+	existingRelease, err := helmutil.GetExistingRelease(actionConfig, envConfig.GetKubernetesNamespace(), metaplayLoadTestChartName)
 	if err != nil {
 		return err
 	}
@@ -238,7 +251,7 @@ func (o *deployBotClientOpts) Run(cmd *cobra.Command) error {
 	// Show info.
 	log.Info().Msgf("Environment ID:     %s", styles.RenderTechnical(envConfig.HumanID))
 	log.Info().Msgf("Environment name:   %s", styles.RenderTechnical(envConfig.Name))
-	log.Info().Msgf("Environment type:   %s", styles.RenderTechnical(string(envConfig.Type)))
+	log.Info().Msgf("Environment type:   %s", renderEnvironmentType(envConfig.Type))
 	log.Info().Msgf("Docker image tag:   %s", styles.RenderTechnical(o.argImageTag))
 	log.Info().Msgf("Helm chart version: %s", styles.RenderTechnical(useHelmChartVersion))
 	log.Info().Msgf("Helm chart path:    %s", styles.RenderTechnical(helmChartPath))
@@ -246,11 +259,24 @@ func (o *deployBotClientOpts) Run(cmd *cobra.Command) error {
 	log.Info().Msgf("Helm values files:  %s", styles.RenderTechnical(strings.Join(valuesFiles, ", ")))
 	log.Info().Msg("")
 
+	// Check the project's policies for the target environment.
+	policyOverride, err := enforceEnvironmentPolicies(cmd.Context(), project, envConfig, tokenSet, policyOperationDeployBotClient, o.flagOverridePolicy)
+	if err != nil {
+		return err
+	}
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, tokenSet, "deploy botclient", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
 	taskRunner := tui.NewTaskRunner()
 
 	// Install or upgrade the Helm chart.
 	taskRunner.AddTask("Deploy loadtest Helm chart", func(output *tui.TaskOutput) error {
-		_, err = helmutil.HelmUpgradeOrInstall(
+		_, err := helmutil.HelmUpgradeOrInstall(
 			output,
 			actionConfig,
 			existingRelease,
@@ -260,8 +286,12 @@ func (o *deployBotClientOpts) Run(cmd *cobra.Command) error {
 			useHelmChartVersion,
 			valuesFiles,
 			helmValues,
-			5*time.Minute)
-		return err
+			nil,
+			o.flagTimeout,
+			false,
+			newCliReleaseDescription(tokenSet, policyOverride),
+			newCliReleaseLabels(tokenSet))
+		return reportHelmTimeout(actionConfig, helmReleaseName, err)
 	})
 
 	// Validate the bots status.
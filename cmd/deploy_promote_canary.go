@@ -0,0 +1,118 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Promote the canary game server release to the stable release.
+type deployPromoteCanaryOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagTimeout        time.Duration
+	flagLockTimeout    time.Duration
+	flagOverridePolicy bool
+}
+
+func init() {
+	o := deployPromoteCanaryOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "promote-canary ENVIRONMENT [flags]",
+		Short:             "Promote the canary game server release to stable",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Promote the canary game server release (deployed with 'metaplay deploy server
+			--canary-percent') to stable.
+
+			The stable release is upgraded to the canary's image, Helm values, and chart version,
+			after which the canary release is removed and all traffic is routed to the stable
+			release again.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay deploy server ... --canary-percent N' to deploy a canary release.
+			- 'metaplay deploy abort-canary ...' to remove the canary release without promoting it.
+		`),
+		Example: trimIndent(`
+			# Promote the canary release in environment tough-falcons.
+			metaplay deploy promote-canary tough-falcons
+		`),
+	}
+	deployCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.DurationVar(&o.flagTimeout, "timeout", defaultHelmTimeout, "Timeout for each Helm operation")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+	flags.BoolVar(&o.flagOverridePolicy, "override-policy", false, "Override the project's environment policies (requires typed confirmation)")
+}
+
+func (o *deployPromoteCanaryOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *deployPromoteCanaryOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	actionConfig, stable, canary, err := findEnvironmentCanaryReleases(targetEnv, envConfig)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Promote Canary Release"))
+	printCanaryStatus(stable, canary)
+
+	// Promoting rolls out a new version into the stable release, like 'deploy server'.
+	policyOverride, err := enforceEnvironmentPolicies(cmd.Context(), cmdCtx.Project, envConfig, cmdCtx.TokenSet, policyOperationDeployServer, o.flagOverridePolicy)
+	if err != nil {
+		return err
+	}
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "promote canary", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	// The canary's values, without the canary configuration, become the stable release's values.
+	stableValues := maps.Clone(canary.Config)
+	delete(stableValues, helmutil.CanaryValuesKey)
+
+	taskRunner := tui.NewTaskRunner()
+
+	taskRunner.AddTask(fmt.Sprintf("Upgrade stable release %s to image %s", stable.Name, helmutil.GetReleaseImageTag(canary)), func(output *tui.TaskOutput) error {
+		_, err := helmutil.UpgradeReleaseWithChart(output, actionConfig, envConfig.GetKubernetesNamespace(), stable.Name, canary.Chart, stableValues, true, o.flagTimeout, newCliReleaseDescription(cmdCtx.TokenSet, policyOverride), newCliReleaseLabels(cmdCtx.TokenSet))
+		return reportHelmTimeout(actionConfig, stable.Name, err)
+	})
+
+	taskRunner.AddTask(fmt.Sprintf("Remove canary release %s", canary.Name), func(output *tui.TaskOutput) error {
+		err := helmutil.UninstallRelease(actionConfig, canary, o.flagTimeout)
+		return reportHelmUninstallTimeout(actionConfig, canary.Name, err)
+	})
+
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("✅ %s", styles.RenderSuccess(fmt.Sprintf("Promoted image %s to stable release %s", helmutil.GetReleaseImageTag(canary), stable.Name)))
+	return nil
+}
@@ -4,25 +4,38 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/hashicorp/go-version"
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/envapi"
 	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaplay"
+	"github.com/metaplay/cli/pkg/metaproj"
 	"github.com/metaplay/cli/pkg/portalapi"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
 )
 
-const metaplayGameServerChartName = "metaplay-gameserver"
+const metaplayGameServerChartName = metaplay.GameServerChartName
 const metaplayGameServerPodLabelSelector = "app=metaplay-server"
 
+// Helm value with which a game server release can override its public hostname.
+const gameServerHostnameValueKey = "hostname"
+
 // Deploy a game server to the target environment with specified docker image version.
 type deployGameServerOpts struct {
 	UsePositionalArgs
@@ -35,6 +48,30 @@ type deployGameServerOpts struct {
 	flagHelmChartRepository string
 	flagHelmChartVersion    string
 	flagHelmValuesPath      string
+	flagValuesFromEnv       bool
+	flagSetValues           []string
+	flagAllowSharedIngress  bool
+	flagCanaryPercent       int
+	flagSkipImageCheck      bool
+	flagSdkVersion          string
+	flagAllowDirty          bool
+	flagImage               string
+	flagImageFile           string
+	flagImageFromCurrent    bool
+	flagLocalCluster        bool
+	flagKubeConfigPath      string
+	flagKubeContext         string
+	flagNamespace           string
+	flagTimeout             time.Duration
+	flagAtomic              bool
+	flagLockTimeout         time.Duration
+	flagFollowLogs          bool
+	flagFollowTimeout       time.Duration
+	flagOverridePolicy      bool
+	flagRequireProvenance   bool
+	flagExpectedCommit      string
+	flagProvenancePublicKey string
+	flagProvenanceIdentity  string
 }
 
 func init() {
@@ -46,10 +83,11 @@ func init() {
 	args.SetExtraArgs(&o.extraArgs, "Passed as-is to Helm.")
 
 	cmd := &cobra.Command{
-		Use:     "server ENVIRONMENT [IMAGE:]TAG [flags] [-- EXTRA_ARGS]",
-		Aliases: []string{"srv"},
-		Short:   "Deploy a server image into the target environment",
-		Run:     runCommand(&o),
+		Use:               "server ENVIRONMENT [IMAGE:]TAG [flags] [-- EXTRA_ARGS]",
+		Aliases:           []string{"srv", "game-server", "gameserver", "game_server"},
+		Short:             "Deploy a server image into the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Deploy a game server into a cloud environment using the specified docker image version.
 
@@ -63,7 +101,80 @@ func init() {
 
 			When a full docker image tag is specified (eg, 'mygame:364cff09'), the image is first
 			pushed to the environment's registry. If only a tag is specified (eg, '364cff09'), the
-			image is assumed to be present in the remote registry already.
+			image is assumed to be present in the remote registry already. The existence of the image
+			in the registry is checked before deploying, use --skip-image-check to skip the check. As the
+			image metadata is then unavailable, the image's Metaplay SDK version must be given with
+			--sdk-version, unless the current image is re-deployed with --image-from-current.
+
+			Multiple game servers can be deployed side by side into the same environment by giving
+			each of them its own Helm release name with --release-name. If the environment has
+			multiple game server releases, --release-name must be specified. Each release must be
+			given its own public hostname (with the 'hostname' Helm value) unless
+			--allow-shared-ingress is specified.
+
+			With --canary-percent N, the image is deployed as a canary release ('<release>-canary')
+			alongside the existing stable release, and N percent of the client traffic is routed to
+			the canary. Running the command again updates the canary's image or traffic weight. Use
+			'metaplay deploy promote-canary' to roll the canary's image out to the stable release, or
+			'metaplay deploy abort-canary' to remove the canary. Canary releases require a Helm chart
+			version that supports them.
+
+			Alternatively, the image can be specified with --image as 'REPOSITORY:TAG', where the
+			repository is relative to the environment's registry. The image repository and tag Helm
+			values are then set directly from it, and the image is not pushed. Use '--image -' to read
+			the image reference from stdin.
+
+			In CI pipelines, the image can also be read from a file with --image-file (eg, written by
+			the build step), instead of passing it as the [IMAGE:]TAG argument. The file contents are
+			trimmed of whitespace and must be a single valid image reference. Use '--image-file -' to
+			read it from stdin.
+
+			The Helm values are read from the following files, each layered on top of the previous:
+			1. The environment's 'serverValuesFile' in metaplay-project.yaml, if specified.
+			2. The environment's auto-discovered values file, found using the project's
+			   'serverValuesFilePattern' (default 'deployments/<environment>.yaml', relative to
+			   metaplay-project.yaml). A missing file is skipped. Disable with --values-from-env=false.
+			3. The values file given with --values.
+			4. The individual values given with --set (eg, '--set shards=4'), which take precedence
+			   over all the files.
+
+			With --image-from-current, the image of the game server release already deployed in the
+			environment is re-deployed, eg, to only change its Helm values. The image repository and
+			tag are read from the existing release's values, and the command fails if the
+			environment has no game server release. The summary shown before deploying lists the
+			Helm values that differ from the deployed ones.
+
+			Images built from a git working tree with uncommitted changes (see 'metaplay build image')
+			require an extra confirmation, or --allow-dirty, to be deployed into a production
+			environment.
+
+			With --require-provenance, the image's provenance attestation (see 'metaplay build image
+			--provenance') is verified before deploying: the attestation must exist, be about the
+			exact image being deployed, and have a valid signature. Signatures made with a key file
+			are verified with the public key given with --provenance-public-key. Otherwise, a
+			keyless signature from the GitHub Actions workflow identity given with
+			--provenance-identity is expected (eg, 'repo:myorg/mygame:ref:refs/heads/main', a
+			trailing '*' matches any suffix). One of the two is required, as any GitHub Actions
+			workflow can make a keyless signature. With --expected-commit, the commit recorded in
+			the attestation must also match.
+
+			With --atomic, a failed deploy is rolled back automatically, like with 'helm --atomic':
+			if the Helm operation fails or times out, an upgraded release is rolled back to its
+			previous revision and a newly installed release is removed. Without it, the failed
+			release is left in place for inspecting the errors.
+
+			With --follow-logs, the logs of the new game server pods are streamed after the
+			deployment has completed, until interrupted with Ctrl-C or --follow-timeout elapses.
+			Pods from the previous version that are still shutting down are not included. A
+			highlighted marker is shown when a server completes its start sequence.
+
+			With --local-cluster, the game server is deployed into a local kind or minikube cluster
+			instead of a cloud environment, eg, for testing in CI. The ENVIRONMENT argument is then
+			omitted and the image must be a locally built one. The image is loaded directly into the
+			cluster (with 'kind load docker-image' or 'minikube image load', auto-detected from the
+			kubeconfig context) and the chart is installed without ingress or TLS. No StackAPI or
+			authentication is used. Only the values file given with --values and the values given
+			with --set are used.
 
 			{Arguments}
 
@@ -72,6 +183,8 @@ func init() {
 			- 'metaplay image push ...' to push the built image to the environment.
 			- 'metaplay debug logs ...' to view logs from the deployed server.
 			- 'metaplay debug shell ...' to start a shell on a running server pod.
+			- 'metaplay deploy promote-canary ...' to promote a canary release to stable.
+			- 'metaplay deploy abort-canary ...' to remove a canary release.
 		`),
 		Example: trimIndent(`
 			# Push the local image and deploy to the environment tough-falcons.
@@ -92,25 +205,185 @@ func init() {
 			# Override the Helm chart repository and version.
 			metaplay deploy server tough-falcons mygame:364cff09 --helm-chart-repo=https://custom-repo.domain.com --helm-chart-version=0.7.0
 
+			# Layer an extra Helm values file on top of the environment's values files.
+			metaplay deploy server tough-falcons mygame:364cff09 --values=my-overrides.yaml
+
+			# Don't apply the auto-discovered 'deployments/tough-falcons.yaml' values file.
+			metaplay deploy server tough-falcons mygame:364cff09 --values-from-env=false
+
+			# Re-deploy the currently running image with a different shard count.
+			metaplay deploy server tough-falcons --image-from-current --set shards=4
+
+			# Re-deploy the currently running image with updated values files.
+			metaplay deploy server tough-falcons --image-from-current --values=my-overrides.yaml
+
 			# Override the Helm release name.
-			metaplay deploy server tough-falcons mygame:364cff09 --helm-release-name=my-release-name
+			metaplay deploy server tough-falcons mygame:364cff09 --release-name=my-release-name
+
+			# Deploy a second game server next to an existing one, sharing its public hostname.
+			metaplay deploy server tough-falcons mygame:364cff09 --release-name=tough-falcons-green --allow-shared-ingress
+
+			# Deploy a canary release next to the stable one, routing 10% of the traffic to it.
+			metaplay deploy server tough-falcons mygame:364cff09 --canary-percent=10
+
+			# Deploy an image from the given repository in the environment's registry.
+			metaplay deploy server tough-falcons --image=mygame:364cff09
+
+			# Deploy the image whose name was written into a file by the build step.
+			metaplay deploy server tough-falcons --image-file=image-tag.txt
+
+			# Read the image repository and tag from stdin.
+			echo mygame:364cff09 | metaplay deploy server tough-falcons --image -
+
+			# Only deploy the image if its provenance is signed with the key and records the commit.
+			metaplay deploy server tough-falcons 364cff09 --require-provenance --provenance-public-key=provenance.pub --expected-commit=364cff09
+
+			# Only deploy images built keylessly by the main branch of the GitHub repository.
+			metaplay deploy server tough-falcons 364cff09 --require-provenance --provenance-identity='repo:myorg/mygame:ref:refs/heads/main'
+
+			# Deploy, rolling back automatically if the deploy fails.
+			metaplay deploy server tough-falcons mygame:364cff09 --atomic
+
+			# Deploy and then follow the new server's logs through its startup.
+			metaplay deploy server tough-falcons mygame:364cff09 --follow-logs
+
+			# Deploy the local image into a local kind cluster using the current kubeconfig context.
+			metaplay deploy server --local-cluster mygame:364cff09
+
+			# Deploy into a specific minikube cluster.
+			metaplay deploy server --local-cluster mygame:364cff09 --kubeconfig=/path/to/kubeconfig --kube-context=minikube
 		`),
 	}
 	deployCmd.AddCommand(cmd)
 
 	flags := cmd.Flags()
-	flags.StringVar(&o.flagHelmReleaseName, "helm-release-name", "", "Helm release name to use for the game server deployment (default to '<environmentID>-gameserver')")
+	flags.StringVar(&o.flagHelmReleaseName, "release-name", "", "Helm release name to use for the game server deployment (defaults to the existing release or '<environmentID>-gameserver')")
+	flags.StringVar(&o.flagHelmReleaseName, "helm-release-name", "", "Deprecated alias for --release-name")
+	flags.MarkDeprecated("helm-release-name", "use --release-name instead")
 	flags.StringVar(&o.flagHelmChartLocalPath, "local-chart-path", "", "Path to a local version of the metaplay-gameserver chart (repository and version are ignored if this is set)")
 	flags.StringVar(&o.flagHelmChartRepository, "helm-chart-repo", "", "Override for Helm chart repository to use for the metaplay-gameserver chart")
 	flags.StringVar(&o.flagHelmChartVersion, "helm-chart-version", "", "Override for Helm chart version to use, eg, '0.7.0'")
-	flags.StringVarP(&o.flagHelmValuesPath, "values", "f", "", "Override for path to the Helm values file, e.g., 'Backend/Deployments/develop-server.yaml'")
+	flags.StringVarP(&o.flagHelmValuesPath, "values", "f", "", "Path to an extra Helm values file, applied on top of the environment's values files, e.g., 'Backend/Deployments/develop-server.yaml'")
+	flags.BoolVar(&o.flagValuesFromEnv, "values-from-env", true, "Apply the environment's values file found with the project's 'serverValuesFilePattern' (if it exists)")
+	flags.StringArrayVar(&o.flagSetValues, "set", nil, "Set a Helm value, eg, 'shards=4' (can be given multiple times, overrides the values files)")
+	flags.BoolVar(&o.flagAllowSharedIngress, "allow-shared-ingress", false, "Allow the release to use the same public hostname as another game server release in the environment")
+	flags.IntVar(&o.flagCanaryPercent, "canary-percent", 0, "Deploy as a canary release alongside the stable release, routing the given percentage (1-99) of the traffic to it")
+	flags.BoolVar(&o.flagSkipImageCheck, "skip-image-check", false, "Skip checking that the image exists in the environment's registry (image metadata is then unavailable)")
+	flags.StringVar(&o.flagSdkVersion, "sdk-version", "", "With --skip-image-check, the Metaplay SDK version the image was built with, eg, '32.0'")
+	flags.BoolVar(&o.flagAllowDirty, "allow-dirty", false, "Allow deploying an image built from uncommitted changes into a production environment without confirmation")
+	flags.StringVar(&o.flagImage, "image", "", "Image repository (relative to the environment's registry) and tag to deploy, eg, 'mygame:364cff09', or '-' to read it from stdin")
+	flags.StringVar(&o.flagImageFile, "image-file", "", "Read the [IMAGE:]TAG to deploy from the given file, or '-' for stdin")
+	flags.BoolVar(&o.flagImageFromCurrent, "image-from-current", false, "Re-deploy the image of the game server release already deployed in the environment")
+	flags.BoolVar(&o.flagLocalCluster, "local-cluster", false, "Deploy into a local kind or minikube cluster instead of a cloud environment")
+	flags.StringVar(&o.flagKubeConfigPath, "kubeconfig", "", "Path to the kubeconfig file to use with --local-cluster (defaults to $KUBECONFIG or ~/.kube/config)")
+	flags.StringVar(&o.flagKubeContext, "kube-context", "", "Kubeconfig context to use with --local-cluster (defaults to the current context)")
+	flags.StringVar(&o.flagNamespace, "namespace", "default", "Kubernetes namespace to deploy into with --local-cluster")
+	flags.DurationVar(&o.flagTimeout, "timeout", defaultHelmTimeout, "Timeout for the Helm operation")
+	flags.BoolVar(&o.flagAtomic, "atomic", false, "Roll back the release automatically if the Helm operation fails (like 'helm --atomic')")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+	flags.BoolVar(&o.flagFollowLogs, "follow-logs", false, "After deploying, follow the logs of the new game server pods")
+	flags.DurationVar(&o.flagFollowTimeout, "follow-timeout", 10*time.Minute, "How long to follow the logs with --follow-logs")
+	flags.BoolVar(&o.flagOverridePolicy, "override-policy", false, "Override the project's environment policies (requires typed confirmation)")
+	flags.BoolVar(&o.flagRequireProvenance, "require-provenance", false, "Verify the image's signed provenance attestation before deploying, fail if it is missing or invalid")
+	flags.StringVar(&o.flagExpectedCommit, "expected-commit", "", "With --require-provenance, require the provenance to record the given commit ID (or a prefix of it)")
+	flags.StringVar(&o.flagProvenancePublicKey, "provenance-public-key", "", "With --require-provenance, path to the ed25519 public key (PEM) to verify the provenance signature with")
+	flags.StringVar(&o.flagProvenanceIdentity, "provenance-identity", "", "With --require-provenance and no --provenance-public-key, the GitHub Actions workflow identity required of keyless signatures")
 }
 
 func (o *deployGameServerOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagLocalCluster {
+		// No environment with local clusters: the only positional argument is the image.
+		if o.argImageNameTag != "" {
+			return fmt.Errorf("the ENVIRONMENT argument is not used with --local-cluster, only specify the image")
+		}
+		o.argImageNameTag = o.argEnvironment
+		o.argEnvironment = ""
+		if o.flagImageFile != "" && o.argImageNameTag != "" {
+			return fmt.Errorf("specify the image either with the argument or --image-file, not both")
+		}
+
+		if o.argImageNameTag != "" && o.argImageNameTag != "latest-local" && !strings.Contains(o.argImageNameTag, ":") {
+			return fmt.Errorf("a full local image name (eg, 'mygame:364cff09') is required with --local-cluster")
+		}
+		if o.flagSkipImageCheck {
+			return fmt.Errorf("--skip-image-check cannot be used with --local-cluster")
+		}
+		if o.flagImage != "" {
+			return fmt.Errorf("--image cannot be used with --local-cluster, specify the local image as an argument")
+		}
+		if o.flagFollowLogs {
+			return fmt.Errorf("--follow-logs cannot be used with --local-cluster")
+		}
+		if cmd.Flags().Changed("canary-percent") {
+			return fmt.Errorf("--canary-percent cannot be used with --local-cluster")
+		}
+		if o.flagRequireProvenance {
+			return fmt.Errorf("--require-provenance cannot be used with --local-cluster")
+		}
+		if o.flagImageFromCurrent {
+			return fmt.Errorf("--image-from-current cannot be used with --local-cluster")
+		}
+	} else {
+		if o.flagImage != "" && o.argImageNameTag != "" {
+			return fmt.Errorf("specify the image either with the [IMAGE:]TAG argument or --image, not both")
+		}
+		if o.flagImageFile != "" && (o.flagImage != "" || o.argImageNameTag != "") {
+			return fmt.Errorf("specify the image either with the [IMAGE:]TAG argument, --image, or --image-file, not several")
+		}
+		if o.flagImageFromCurrent {
+			if o.flagImage != "" || o.flagImageFile != "" || o.argImageNameTag != "" {
+				return fmt.Errorf("--image-from-current re-deploys the current image, don't specify the image with the [IMAGE:]TAG argument, --image, or --image-file")
+			}
+			if cmd.Flags().Changed("canary-percent") {
+				return fmt.Errorf("--image-from-current cannot be used with --canary-percent")
+			}
+		}
+		for _, flagName := range []string{"kubeconfig", "kube-context", "namespace"} {
+			if cmd.Flags().Changed(flagName) {
+				return fmt.Errorf("--%s can only be used with --local-cluster", flagName)
+			}
+		}
+	}
+	if cmd.Flags().Changed("canary-percent") && (o.flagCanaryPercent < 1 || o.flagCanaryPercent > 99) {
+		return fmt.Errorf("invalid --canary-percent %d, must be between 1 and 99", o.flagCanaryPercent)
+	}
+	if cmd.Flags().Changed("follow-timeout") && !o.flagFollowLogs {
+		return fmt.Errorf("--follow-timeout can only be used with --follow-logs")
+	}
+	for _, flagName := range []string{"expected-commit", "provenance-public-key", "provenance-identity"} {
+		if cmd.Flags().Changed(flagName) && !o.flagRequireProvenance {
+			return fmt.Errorf("--%s can only be used with --require-provenance", flagName)
+		}
+	}
+	if o.flagProvenancePublicKey != "" && o.flagProvenanceIdentity != "" {
+		return fmt.Errorf("--provenance-identity only applies to keyless signatures and cannot be used with --provenance-public-key")
+	}
+	if o.flagRequireProvenance && o.flagProvenancePublicKey == "" && o.flagProvenanceIdentity == "" {
+		return fmt.Errorf("--require-provenance requires either --provenance-public-key or --provenance-identity")
+	}
+	if o.flagRequireProvenance && o.flagSkipImageCheck {
+		return fmt.Errorf("--require-provenance cannot be used with --skip-image-check")
+	}
+	if o.flagSdkVersion != "" && !o.flagSkipImageCheck {
+		return fmt.Errorf("--sdk-version can only be used with --skip-image-check, otherwise the SDK version is read from the image")
+	}
+	if o.flagSkipImageCheck && o.flagSdkVersion == "" && !o.flagImageFromCurrent {
+		return fmt.Errorf("--skip-image-check requires --sdk-version, as the SDK version can't be read from the image")
+	}
 	return nil
 }
 
-func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
+func (o *deployGameServerOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Read the image from a file or stdin, if requested.
+	if err := o.readImageFromFile(); err != nil {
+		return err
+	}
+
+	// Deploying into a local cluster doesn't use any environment.
+	if o.flagLocalCluster {
+		return o.runLocalCluster(cmd)
+	}
+
 	// Try to resolve the project & auth provider.
 	project, err := resolveProject()
 	if err != nil {
@@ -126,30 +399,10 @@ func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
 	// Create TargetEnvironment.
 	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
 
-	// Validate Helm chart reference.
-	var chartVersionConstraints version.Constraints = nil
-	if o.flagHelmChartLocalPath != "" {
-		err = helmutil.ValidateLocalHelmChart(o.flagHelmChartLocalPath)
-		if err != nil {
-			return fmt.Errorf("invalid --helm-chart-path: %v", err)
-		}
-	} else {
-		// Resolve Helm chart version to use, either from config file or command line override
-		helmChartVersion := project.Config.ServerChartVersion
-		if o.flagHelmChartVersion != "" {
-			helmChartVersion = o.flagHelmChartVersion
-		}
-
-		if helmChartVersion == "latest-prerelease" {
-			// Accept any version
-		} else {
-			// Parse Helm chart semver range.
-			chartVersionConstraints, err = version.NewConstraint(helmChartVersion)
-			if err != nil {
-				return fmt.Errorf("invalid Helm chart version: %v", err)
-			}
-			log.Debug().Msgf("Accepted Helm chart semver constraints: %v", chartVersionConstraints)
-		}
+	// Resolve Helm chart to use (local or remote).
+	helmChartPath, useHelmChartVersion, err := resolveGameServerHelmChart(project, o.flagHelmChartLocalPath, o.flagHelmChartRepository, o.flagHelmChartVersion)
+	if err != nil {
+		return err
 	}
 
 	// Get environment details.
@@ -157,6 +410,12 @@ func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
+	if err := envDetails.Validate(); err != nil {
+		return err
+	}
+	if !envDetails.HasGameServerDeployment() {
+		return fmt.Errorf("environment %s is not set up for game server deployments (no server hostnames configured)", envConfig.HumanID)
+	}
 
 	// Get docker credentials.
 	dockerCredentials, err := targetEnv.GetDockerCredentials(envDetails)
@@ -165,6 +424,60 @@ func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
 	}
 	log.Debug().Msgf("Got docker credentials: username=%s", dockerCredentials.Username)
 
+	// Create a Kubernetes client.
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+
+	// Configure Helm.
+	actionConfig, err := helmutil.NewActionConfig(kubeCli.KubeConfig, envConfig.GetKubernetesNamespace())
+	if err != nil {
+		return fmt.Errorf("failed to initialize Helm config: %v", err)
+	}
+
+	// Find the existing game server releases in the environment.
+	existingReleases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), resolveGameServerChartName(envDetails))
+	if err != nil {
+		return err
+	}
+
+	// With --image-from-current, re-deploy the image of the existing release. The image is
+	// expected to still be in the registry, from where its metadata is fetched as usual.
+	var currentRelease *release.Release
+	if o.flagImageFromCurrent {
+		currentRelease, err = resolveCurrentGameServerRelease(existingReleases, o.flagHelmReleaseName, envConfig.HumanID)
+		if err != nil {
+			return err
+		}
+		o.flagHelmReleaseName = currentRelease.Name
+		o.argImageNameTag = helmutil.GetReleaseImageTag(currentRelease)
+	}
+
+	// Resolve the image repository. With --image, the repository and tag are given explicitly
+	// and the image is expected to be in the environment's registry. With --image-from-current,
+	// the repository of the existing release is used, if it was set explicitly.
+	imageRepository := envDetails.Deployment.EcrRepo
+	if currentRelease != nil {
+		if repository := getReleaseImageRepository(currentRelease); repository != "" {
+			imageRepository = repository
+		}
+	} else if o.flagImage != "" {
+		repository, tag, err := splitDockerImageReference(o.flagImage)
+		if err != nil {
+			return exitcode.New(exitcode.ExitUsage, fmt.Errorf("invalid --image: %w", err))
+		}
+		imageRepository = fmt.Sprintf("%s/%s", dockerCredentials.RegistryHost(), repository)
+		o.argImageNameTag = tag
+	}
+
+	// Local images are read and pushed via the container runtime's API, check that it's available.
+	if currentRelease == nil && (o.argImageNameTag == "" || o.argImageNameTag == "latest-local" || strings.Contains(o.argImageNameTag, ":")) {
+		if _, err := resolveContainerRuntimeAPI(cmd.Context(), project); err != nil {
+			return err
+		}
+	}
+
 	// If no docker image specified, scan the images matching project from the local docker repo
 	// and then let the user choose from the images.
 	if o.argImageNameTag == "" {
@@ -190,7 +503,7 @@ func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
 	}
 
 	// Push the image to the remote repository (if full name is specified).
-	useLocalImage := strings.Contains(o.argImageNameTag, ":")
+	useLocalImage := currentRelease == nil && strings.Contains(o.argImageNameTag, ":")
 	var imageTag string
 	var imageConfig *v1.ConfigFile
 	if useLocalImage {
@@ -207,75 +520,396 @@ func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
 		}
 	} else {
 		imageTag = o.argImageNameTag
+	}
+	if err := checkImageTagNotLatest(imageTag); err != nil {
+		return err
+	}
+	if !useLocalImage {
+		remoteImageName := fmt.Sprintf("%s:%s", imageRepository, imageTag)
+		if o.flagSkipImageCheck {
+			log.Warn().Msgf("Skipping the check for image %s in the environment's registry (--skip-image-check)", remoteImageName)
+		} else {
+			// Check that the image exists in the registry to fail early (instead of the pods
+			// failing with ImagePullBackOff after the deployment).
+			exists, err := envapi.RemoteDockerImageExists(dockerCredentials, remoteImageName)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return exitcode.Errorf(exitcode.ExitNotFound, "image %s not found in the environment's registry, did you push it? Push it with 'metaplay image push' or deploy using the full local image name, eg, '%s:%s'", remoteImageName, project.Config.ProjectHumanID, imageTag)
+			}
+
+			// Fetch the labels from the remote docker image.
+			imageConfig, err = envapi.FetchRemoteDockerImageMetadata(dockerCredentials, remoteImageName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Determine the Metaplay SDK version, commit id, and build number from the docker image metadata.
+	// If the image check was skipped, the metadata is not available: use the SDK version given with
+	// --sdk-version, or keep the SDK version of the existing release when re-deploying its image. The
+	// commit id and build number are then left unknown.
+	var imageSdkVersion, imageCommitId, imageBuildNumber string
+	if imageConfig != nil {
+		imageLabels := imageConfig.Config.Labels
+		var found bool
+		imageSdkVersion, found = imageLabels["io.metaplay.sdk_version"]
+		if !found {
+			return fmt.Errorf("invalid docker image: required label 'io.metaplay.sdk_version' not found in the image metadata")
+		}
+		log.Debug().Msgf("Metaplay SDK version found in the image: %s", imageSdkVersion)
 
-		// Fetch the labels from the remote docker image.
-		remoteImageName := fmt.Sprintf("%s:%s", envDetails.Deployment.EcrRepo, imageTag)
-		imageConfig, err = envapi.FetchRemoteDockerImageMetadata(dockerCredentials, remoteImageName)
+		imageCommitId, found = imageLabels["io.metaplay.commit_id"]
+		if !found {
+			return fmt.Errorf("invalid docker image: required label 'io.metaplay.commit_id' not found in the image metadata")
+		}
+		log.Debug().Msgf("Commit ID found in the image: %s", imageCommitId)
+
+		imageBuildNumber, found = imageLabels["io.metaplay.build_number"]
+		if !found {
+			return fmt.Errorf("invalid docker image: required label 'io.metaplay.build_number' not found in the image metadata")
+		}
+		log.Debug().Msgf("Build number found in the image: %s", imageBuildNumber)
+	} else if o.flagSdkVersion != "" {
+		imageSdkVersion = o.flagSdkVersion
+	} else {
+		imageSdkVersion = getReleaseSdkVersion(currentRelease)
+		if imageSdkVersion == "" {
+			return exitcode.Errorf(exitcode.ExitUsage, "the SDK version of the current release %s is not known, specify it with --sdk-version", currentRelease.Name)
+		}
+	}
+
+	// Verify the image's provenance before anything is changed in the environment.
+	var provenance *metaplay.ProvenanceStatement
+	if o.flagRequireProvenance {
+		provenance, err = o.verifyImageProvenance(cmd.Context(), dockerCredentials, useLocalImage, imageRepository, imageTag)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Determine the Metaplay SDK version, commit id, and build number from the docker image metadata.
-	imageLabels := imageConfig.Config.Labels
-	imageSdkVersion, found := imageLabels["io.metaplay.sdk_version"]
-	if !found {
-		return fmt.Errorf("invalid docker image: required label 'io.metaplay.sdk_version' not found in the image metadata")
+	// Check whether the image was built from uncommitted changes.
+	isDirtyImage := false
+	if imageConfig != nil {
+		isDirtyImage = imageConfig.Config.Labels[dockerImageDirtyLabel] == "true"
 	}
-	log.Debug().Msgf("Metaplay SDK version found in the image: %s", imageSdkVersion)
 
-	imageCommitId, hasCommitId := imageLabels["io.metaplay.commit_id"]
-	if !hasCommitId {
-		return fmt.Errorf("invalid docker image: required label 'io.metaplay.commit_id' not found in the image metadata")
+	// Resolve Helm values file paths relative to current directory: the environment's values
+	// file from the project config, the auto-discovered one, and the one given with --values.
+	valuesFiles, err := resolveServerValuesFiles(project, envConfig, o.flagValuesFromEnv, o.flagHelmValuesPath)
+	if err != nil {
+		return err
 	}
-	log.Debug().Msgf("Commit ID found in the image: %s", imageCommitId)
 
-	imageBuildNumber, hasBuildNumber := imageLabels["io.metaplay.build_number"]
-	if !hasBuildNumber {
-		return fmt.Errorf("invalid docker image: required label 'io.metaplay.build_number' not found in the image metadata")
+	// Default Helm values. The user Helm values files are applied on top so
+	// all these values can be overridden by the user.
+	helmValues := newGameServerHelmValues(envConfig, imageTag, imageSdkVersion)
+
+	// With --image, also set the repository explicitly (otherwise the chart's default is used).
+	// With --image-from-current, keep the existing release's image values as they are.
+	if currentRelease != nil {
+		if currentImage, ok := currentRelease.Config["image"].(map[string]interface{}); ok {
+			helmValues["image"] = maps.Clone(currentImage)
+		}
+	} else if o.flagImage != "" {
+		helmValues["image"].(map[string]interface{})["repository"] = imageRepository
 	}
-	log.Debug().Msgf("Build number found in the image: %s", imageBuildNumber)
 
-	// Resolve Helm chart to use (local or remote).
-	var helmChartPath string
-	var useHelmChartVersion string
+	// Resolve Helm release name. If not specified, default to:
+	// - Earlier name if a single deployment already exists.
+	// - The release name assigned by the StackAPI, or '<environmentID>-gameserver', if no
+	//   deployments exist.
+	// With multiple existing deployments, the release must be specified explicitly.
+	// With --canary-percent, the release is the canary of the stable release instead.
+	helmReleaseName := o.flagHelmReleaseName
+	helmReleaseNameBadge := ""
+	var stableRelease *release.Release
+	if o.flagCanaryPercent != 0 {
+		stableRelease, err = resolveCanaryStableRelease(existingReleases, o.flagHelmReleaseName)
+		if err != nil {
+			return err
+		}
+		helmReleaseName = stableRelease.Name + canaryReleaseSuffix
+		helmValues[helmutil.CanaryValuesKey] = helmutil.NewCanaryValues(stableRelease.Name, o.flagCanaryPercent)
+
+		// Check that the chart version supports canary releases.
+		loadedChart, err := helmutil.LoadChart(helmChartPath, useHelmChartVersion)
+		if err != nil {
+			return err
+		}
+		if !helmutil.ChartSupportsCanary(loadedChart) {
+			return exitcode.Errorf(exitcode.ExitUsage, "Helm chart version %s does not support canary releases; use a newer chart version with --helm-chart-version or the project's 'serverChartVersion'", loadedChart.Metadata.Version)
+		}
+	} else if helmReleaseName == "" {
+		if len(existingReleases) > 1 {
+			return fmt.Errorf("multiple game server releases found in the environment (%s), specify the release to deploy with --release-name", strings.Join(helmutil.GetReleaseNames(existingReleases), ", "))
+		} else if len(existingReleases) == 1 {
+			helmReleaseName = existingReleases[0].Name
+		} else {
+			helmReleaseName = resolveDefaultGameServerReleaseName(envConfig, envDetails)
+			helmReleaseNameBadge = styles.RenderMuted("[default]")
+		}
+	}
+	existingRelease := helmutil.FindReleaseByName(existingReleases, helmReleaseName)
+	if existingRelease != nil {
+		helmReleaseNameBadge = styles.RenderMuted("[update existing]")
+	} else if helmReleaseNameBadge == "" {
+		helmReleaseNameBadge = styles.RenderMuted("[new release]")
+	}
+
+	// Check that the release doesn't claim the same public hostname as another release.
+	finalHelmValues, err := helmutil.ResolveValues(valuesFiles, helmValues, o.flagSetValues)
+	if err != nil {
+		return err
+	}
+	hostname := resolveGameServerHostname(finalHelmValues, envDetails.Deployment.ServerHostname)
+	// The canary shares the hostname of its stable release by design.
+	for _, otherRelease := range existingReleases {
+		if otherRelease.Name == helmReleaseName || otherRelease == stableRelease {
+			continue
+		}
+		otherHostname := resolveGameServerHostname(otherRelease.Config, envDetails.Deployment.ServerHostname)
+		if otherHostname == hostname {
+			if !o.flagAllowSharedIngress {
+				return fmt.Errorf("release '%s' already uses the public hostname '%s'; set the '%s' Helm value to give release '%s' its own hostname, or use --allow-shared-ingress", otherRelease.Name, hostname, gameServerHostnameValueKey, helmReleaseName)
+			}
+			log.Warn().Msgf("Release '%s' shares the public hostname '%s' with release '%s'", helmReleaseName, hostname, otherRelease.Name)
+		}
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Deploy Game Server to Cloud"))
+	log.Info().Msg("")
+
+	// Show info.
+	log.Info().Msgf("Target environment:")
+	log.Info().Msgf("  Name:               %s", styles.RenderTechnical(envConfig.Name))
+	log.Info().Msgf("  ID:                 %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("  Type:               %s", renderEnvironmentType(envConfig.Type))
+	log.Info().Msgf("  Stack domain:       %s", styles.RenderTechnical(envConfig.StackDomain))
+	log.Info().Msgf("Build information:")
+	if useLocalImage {
+		log.Info().Msgf("  Image name:         %s", styles.RenderTechnical(o.argImageNameTag))
+	} else if currentRelease != nil {
+		log.Info().Msgf("  Image name:         %s %s", styles.RenderTechnical(fmt.Sprintf("%s:%s", imageRepository, imageTag)), styles.RenderMuted("[unchanged, currently deployed]"))
+	} else {
+		log.Info().Msgf("  Image name:         %s", styles.RenderTechnical(fmt.Sprintf("%s:%s", imageRepository, imageTag)))
+	}
+	if imageConfig == nil {
+		log.Info().Msgf("  Build number:       %s", styles.RenderMuted("n/a [image metadata not checked]"))
+		log.Info().Msgf("  Commit ID:          %s", styles.RenderMuted("n/a [image metadata not checked]"))
+	} else {
+		log.Info().Msgf("  Build number:       %s", styles.RenderTechnical(imageBuildNumber))
+		if isDirtyImage {
+			log.Info().Msgf("  Commit ID:          %s %s", styles.RenderTechnical(imageCommitId), styles.RenderWarning("[uncommitted changes]"))
+		} else {
+			log.Info().Msgf("  Commit ID:          %s", styles.RenderTechnical(imageCommitId))
+		}
+	}
+	if imageConfig != nil {
+		log.Info().Msgf("  Created:            %s", styles.RenderTechnical(humanize.Time(imageConfig.Created.Time)))
+	}
+	log.Info().Msgf("  Metaplay SDK:       %s", styles.RenderTechnical(imageSdkVersion))
+	if provenance != nil {
+		log.Info().Msgf("  Provenance:         %s %s", styles.RenderSuccess("verified"), styles.RenderMuted(fmt.Sprintf("[built by %s]", provenance.Predicate.RunDetails.Builder.ID)))
+	}
+	log.Info().Msgf("Deployment info:")
 	if o.flagHelmChartLocalPath != "" {
-		// Use local Helm chart directly.
-		helmChartPath = o.flagHelmChartLocalPath
-		useHelmChartVersion = "local"
+		log.Info().Msgf("  Helm chart path:    %s", styles.RenderTechnical(helmChartPath))
 	} else {
-		// Determine the Helm chart repo and version to use.
-		helmChartRepo := coalesceString(project.Config.HelmChartRepository, o.flagHelmChartRepository, "https://charts.metaplay.dev")
-		minChartVersion, _ := version.NewVersion("0.7.0")
-		useHelmChartVersion, err = helmutil.ResolveBestMatchingHelmVersion(helmChartRepo, metaplayGameServerChartName, minChartVersion, chartVersionConstraints)
-		helmChartPath = helmutil.GetHelmChartPath(helmChartRepo, metaplayGameServerChartName, useHelmChartVersion)
+		log.Info().Msgf("  Helm chart version: %s", styles.RenderTechnical(useHelmChartVersion))
+	}
+	log.Info().Msgf("  Helm release name:  %s %s", styles.RenderTechnical(helmReleaseName), helmReleaseNameBadge)
+	if stableRelease != nil {
+		log.Info().Msgf("  Canary of release:  %s %s", styles.RenderTechnical(stableRelease.Name), styles.RenderMuted(fmt.Sprintf("[%d%% of traffic]", o.flagCanaryPercent)))
+	}
+	log.Info().Msgf("  Public hostname:    %s", styles.RenderTechnical(hostname))
+	if len(valuesFiles) > 0 {
+		log.Info().Msgf("  Helm values files:  %s", styles.RenderTechnical(strings.Join(valuesFiles, ", ")))
+	}
+	if len(o.flagSetValues) > 0 {
+		log.Info().Msgf("  Helm set values:    %s", styles.RenderTechnical(strings.Join(o.flagSetValues, ", ")))
+	}
+	if o.flagAtomic {
+		log.Info().Msgf("  Atomic mode:        %s", styles.RenderTechnical("enabled, the release is rolled back automatically on failure"))
+	}
+	// \todo list of runtime options files
+	log.Info().Msg("")
+
+	// When re-deploying the current image, show which values change.
+	if currentRelease != nil {
+		diff, err := diffHelmValues(currentRelease.Name, currentRelease.Config, finalHelmValues)
 		if err != nil {
 			return err
 		}
+		if diff == "" {
+			log.Info().Msg(styles.RenderWarning("No changes to the deployed Helm values, the game server is re-deployed as-is"))
+		} else {
+			log.Info().Msgf("Changes to the deployed Helm values:")
+			for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+				log.Info().Msg(renderDiffLine(line))
+			}
+		}
+		log.Info().Msg("")
 	}
-	log.Debug().Msgf("Helm chart path: %s", helmChartPath)
 
-	// Resolve Helm values file path relative to current directory.
-	valuesFiles := project.GetServerValuesFiles(envConfig)
+	// Deploying an image built from uncommitted changes into production requires confirmation.
+	if isDirtyImage {
+		log.Warn().Msg(styles.RenderWarning("WARNING: The image was built from a git working tree with uncommitted changes!"))
+		if envConfig.Type == portalapi.EnvironmentTypeProduction && !o.flagAllowDirty {
+			if !tui.IsInteractive() {
+				return exitcode.Errorf(exitcode.ExitUsage, "refusing to deploy an image built from uncommitted changes into production environment %s; use --allow-dirty to deploy it anyway", envConfig.HumanID)
+			}
+			confirmed, err := tui.DoConfirmQuestion(cmd.Context(), fmt.Sprintf("Deploy the dirty image into production environment %s anyway?", envConfig.HumanID))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				log.Info().Msg("Cancelled")
+				return nil
+			}
+		}
+		log.Info().Msg("")
+	}
 
-	// Create a Kubernetes client.
-	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	// Check the project's policies for the target environment.
+	policyOverride, err := enforceEnvironmentPolicies(cmd.Context(), project, envConfig, tokenSet, policyOperationDeployServer, o.flagOverridePolicy)
 	if err != nil {
 		return err
 	}
 
-	// Configure Helm.
-	actionConfig, err := helmutil.NewActionConfig(kubeCli.KubeConfig, envConfig.GetKubernetesNamespace())
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, tokenSet, "deploy server", o.flagLockTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to initialize Helm config: %v", err)
+		return err
 	}
+	defer releaseLock()
 
-	// Determine if there's an existing release deployed.
-	existingRelease, err := helmutil.GetExistingRelease(actionConfig, metaplayGameServerChartName)
+	taskRunner := tui.NewTaskRunner()
+
+	// If using local image, add task to push it.
+	if useLocalImage {
+		taskRunner.AddTask("Push docker image to environment repository", func(output *tui.TaskOutput) error {
+			return pushDockerImage(cmd.Context(), output, o.argImageNameTag, envDetails.Deployment.EcrRepo, dockerCredentials)
+		})
+	}
+
+	// Install or upgrade the Helm chart. The readiness is checked by the tasks below.
+	var deployedRelease *release.Release
+	taskRunner.AddTask("Deploy game server using Helm", func(output *tui.TaskOutput) error {
+		result, err := metaplay.DeployGameServer(cmd.Context(), metaplay.NewEnvironmentFromTarget(targetEnv), metaplay.DeployGameServerOptions{
+			ImageTag:       imageTag,
+			ChartPath:      helmChartPath,
+			ChartVersion:   useHelmChartVersion,
+			ReleaseName:    helmReleaseName,
+			ValuesFiles:    valuesFiles,
+			Values:         helmValues,
+			SetValues:      o.flagSetValues,
+			Description:    policyOverride,
+			Timeout:        o.flagTimeout,
+			Atomic:         o.flagAtomic,
+			SkipReadyCheck: true,
+			Progress:       taskOutputProgress(output),
+		})
+		if result != nil {
+			deployedRelease = result.Release
+		}
+		return reportHelmTimeout(actionConfig, helmReleaseName, err)
+	})
+
+	// Validate the game server status.
+	err = targetEnv.WaitForServerToBeReady(cmd.Context(), taskRunner)
 	if err != nil {
 		return err
 	}
 
+	// Run the tasks.
+	deployStartTime := time.Now()
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msg(styles.RenderSuccess("✅ Game server successfully deployed!"))
+
+	// Show the releases and traffic split of the canary deployment.
+	if stableRelease != nil {
+		printCanaryStatus(stableRelease, deployedRelease)
+	}
+
+	// Follow the logs of the new pods, if requested. The operation lock is not needed anymore.
+	if o.flagFollowLogs {
+		releaseLock()
+		return followDeployedServerLogs(cmd.Context(), targetEnv, imageTag, deployStartTime, o.flagFollowTimeout)
+	}
+	return nil
+}
+
+// Verify the provenance of the image to deploy: the attestation must exist, be signed with the
+// public key (or keylessly), be about the exact image, and record the expected commit. Local
+// images have their provenance stored locally, images in the registry next to them.
+func (o *deployGameServerOpts) verifyImageProvenance(ctx context.Context, dockerCredentials *envapi.DockerCredentials, useLocalImage bool, imageRepository, imageTag string) (*metaplay.ProvenanceStatement, error) {
+	var verifier metaplay.ProvenanceVerifier
+	if o.flagProvenancePublicKey != "" {
+		var err error
+		verifier, err = metaplay.LoadProvenanceVerificationKey(o.flagProvenancePublicKey)
+		if err != nil {
+			return nil, exitcode.New(exitcode.ExitUsage, err)
+		}
+	} else {
+		var err error
+		verifier, err = metaplay.NewKeylessProvenanceVerifier(metaplay.GitHubActionsOIDCIssuer, o.flagProvenanceIdentity)
+		if err != nil {
+			return nil, exitcode.New(exitcode.ExitUsage, err)
+		}
+	}
+
+	// Resolve the image ID and find the image's provenance.
+	var imageName, imageID string
+	var envelope *metaplay.ProvenanceEnvelope
+	if useLocalImage {
+		imageName = o.argImageNameTag
+		var err error
+		if imageID, err = envapi.ReadLocalDockerImageID(imageName); err != nil {
+			return nil, err
+		}
+		if envelope, err = metaplay.LoadLocalImageProvenance(imageID); err != nil {
+			return nil, err
+		}
+	} else {
+		imageName = fmt.Sprintf("%s:%s", imageRepository, imageTag)
+		remoteImage, err := envapi.ResolveRemoteDockerImage(ctx, dockerCredentials, imageName)
+		if err != nil {
+			return nil, err
+		}
+		configName, err := remoteImage.Image.ConfigName()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the ID of image %s: %w", imageName, err)
+		}
+		imageID = configName.String()
+		if envelope, err = metaplay.FetchImageProvenance(ctx, dockerCredentials, imageRepository, imageID); err != nil {
+			return nil, err
+		}
+	}
+	if envelope == nil {
+		return nil, exitcode.Errorf(exitcode.ExitNotFound, "refusing to deploy image %s: no provenance attestation found, build the image with 'metaplay build image --provenance'", imageName)
+	}
+	log.Debug().Msgf("Verify provenance of image %s (%s)", imageName, imageID)
+
+	statement, err := metaplay.VerifyProvenance(ctx, envelope, verifier, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("refusing to deploy image %s: %w", imageName, err)
+	}
+	if o.flagExpectedCommit != "" && !statement.MatchesCommit(o.flagExpectedCommit) {
+		return nil, fmt.Errorf("refusing to deploy image %s: its provenance records commit '%s', expected '%s'", imageName, statement.CommitID(), o.flagExpectedCommit)
+	}
+	return statement, nil
+}
+
+// Create the default Helm values for deploying the image into a cloud environment. The user
+// Helm values files are applied on top, so all these values can be overridden by the user.
+func newGameServerHelmValues(envConfig *metaproj.ProjectEnvironmentConfig, imageTag string, sdkVersion string) map[string]interface{} {
 	// Default shard config based on environment type.
 	// \todo Auto-detect these from the infrastructure.
 	var shardConfig []map[string]interface{}
@@ -303,10 +937,9 @@ func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
 		}
 	}
 
-	// Default Helm values. The user Helm values files are applied on top so
-	// all these values can be overridden by the user.
+	// Default Helm values.
 	// \todo check for the existence of the runtime options files
-	helmValues := map[string]interface{}{
+	return map[string]interface{}{
 		"environment":       envConfig.Name,
 		"environmentFamily": envConfig.GetEnvironmentFamily(),
 		"config": map[string]interface{}{
@@ -325,100 +958,153 @@ func (o *deployGameServerOpts) Run(cmd *cobra.Command) error {
 			"discoveryEnabled": true,
 		},
 		"sdk": map[string]interface{}{
-			"version": imageSdkVersion,
+			"version": sdkVersion,
 		},
 		"image": map[string]interface{}{
 			"tag": imageTag,
 		},
 		"shards": shardConfig,
 	}
+}
 
-	// Resolve Helm release name. If not specified, default to:
-	// - Earlier name if a deployment already exists.
-	// - '<environmentID>-gameserver' otherwise.
-	helmReleaseName := o.flagHelmReleaseName
-	helmReleaseNameBadge := ""
-	if helmReleaseName == "" {
-		if existingRelease != nil {
-			helmReleaseName = existingRelease.Name
-			helmReleaseNameBadge = styles.RenderMuted("[update existing]")
-		} else {
-			helmReleaseName = fmt.Sprintf("%s-gameserver", envConfig.HumanID)
-			helmReleaseNameBadge = styles.RenderMuted("[default]")
+// Resolve the Helm values files to use for the environment, each layered on top of the
+// previous: the environment's values file from the project config, the auto-discovered one
+// (if valuesFromEnv), and the extra values file (if non-empty).
+func resolveServerValuesFiles(project *metaproj.MetaplayProject, envConfig *metaproj.ProjectEnvironmentConfig, valuesFromEnv bool, extraValuesFile string) ([]string, error) {
+	valuesFiles := project.GetServerValuesFiles(envConfig)
+	if valuesFromEnv {
+		envValuesFile, err := project.FindEnvironmentServerValuesFile(envConfig)
+		if err != nil {
+			return nil, err
 		}
+		if envValuesFile != "" && !slices.Contains(valuesFiles, envValuesFile) {
+			log.Debug().Msgf("Using auto-discovered Helm values file: %s", envValuesFile)
+			valuesFiles = append(valuesFiles, envValuesFile)
+		}
+	}
+	if extraValuesFile != "" {
+		valuesFiles = append(valuesFiles, extraValuesFile)
 	}
+	return valuesFiles, nil
+}
 
-	log.Info().Msg("")
-	log.Info().Msg(styles.RenderTitle("Deploy Game Server to Cloud"))
-	log.Info().Msg("")
+// Read the image reference from stdin with '--image -', or from the file (or stdin) given
+// with --image-file.
+func (o *deployGameServerOpts) readImageFromFile() error {
+	if o.flagImage == "-" {
+		imageRef, err := readImageReference("-")
+		if err != nil {
+			return err
+		}
+		o.flagImage = imageRef
+	}
+	if o.flagImageFile != "" {
+		imageRef, err := readImageReference(o.flagImageFile)
+		if err != nil {
+			return err
+		}
+		if o.flagLocalCluster && imageRef != "latest-local" && !strings.Contains(imageRef, ":") {
+			return exitcode.Errorf(exitcode.ExitUsage, "a full local image name (eg, 'mygame:364cff09') is required with --local-cluster, got '%s' from %s", imageRef, o.flagImageFile)
+		}
+		o.argImageNameTag = imageRef
+	}
+	return nil
+}
 
-	// Show info.
-	log.Info().Msgf("Target environment:")
-	log.Info().Msgf("  Name:               %s", styles.RenderTechnical(envConfig.Name))
-	log.Info().Msgf("  ID:                 %s", styles.RenderTechnical(envConfig.HumanID))
-	log.Info().Msgf("  Type:               %s", styles.RenderTechnical(string(envConfig.Type)))
-	log.Info().Msgf("  Stack domain:       %s", styles.RenderTechnical(envConfig.StackDomain))
-	log.Info().Msgf("Build information:")
-	if useLocalImage {
-		log.Info().Msgf("  Image name:         %s", styles.RenderTechnical(o.argImageNameTag))
-	} else {
-		log.Info().Msgf("  Image name:         %s", styles.RenderTechnical(fmt.Sprintf("%s:%s", envDetails.Deployment.EcrRepo, imageTag)))
+// Resolve the existing game server release whose image --image-from-current re-deploys: the
+// release given with --release-name, or the only release in the environment.
+func resolveCurrentGameServerRelease(releases []*release.Release, releaseName string, environment string) (*release.Release, error) {
+	if len(releases) == 0 {
+		return nil, exitcode.Errorf(exitcode.ExitNotFound, "no game server deployed in environment %s, nothing to re-deploy with --image-from-current; specify the image to deploy instead", environment)
 	}
-	log.Info().Msgf("  Build number:       %s", styles.RenderTechnical(imageBuildNumber))
-	log.Info().Msgf("  Commit ID:          %s", styles.RenderTechnical(imageCommitId))
-	log.Info().Msgf("  Created:            %s", styles.RenderTechnical(humanize.Time(imageConfig.Created.Time)))
-	log.Info().Msgf("  Metaplay SDK:       %s", styles.RenderTechnical(imageSdkVersion))
-	log.Info().Msgf("Deployment info:")
-	if o.flagHelmChartLocalPath != "" {
-		log.Info().Msgf("  Helm chart path:    %s", styles.RenderTechnical(helmChartPath))
-	} else {
-		log.Info().Msgf("  Helm chart version: %s", styles.RenderTechnical(useHelmChartVersion))
+	if releaseName == "" && len(releases) > 1 {
+		return nil, fmt.Errorf("multiple game server releases found in the environment (%s), specify the release to re-deploy with --release-name", strings.Join(helmutil.GetReleaseNames(releases), ", "))
 	}
-	log.Info().Msgf("  Helm release name:  %s %s", styles.RenderTechnical(helmReleaseName), helmReleaseNameBadge)
-	if len(valuesFiles) > 0 {
-		log.Info().Msgf("  Helm values files:  %s", styles.RenderTechnical(strings.Join(valuesFiles, ", ")))
+	currentRelease, err := selectGameServerRelease(releases, releaseName)
+	if err != nil {
+		return nil, exitcode.New(exitcode.ExitNotFound, err)
 	}
-	// \todo list of runtime options files
-	log.Info().Msg("")
+	if helmutil.GetReleaseImageTag(currentRelease) == "unknown" {
+		return nil, fmt.Errorf("cannot resolve the image of game server release '%s': no 'image.tag' in its Helm values", currentRelease.Name)
+	}
+	return currentRelease, nil
+}
 
-	taskRunner := tui.NewTaskRunner()
+// Get the image repository explicitly set in the release's values, or an empty string if the
+// chart's default is used.
+func getReleaseImageRepository(rel *release.Release) string {
+	if imageValues, ok := rel.Config["image"].(map[string]interface{}); ok {
+		repository, _ := imageValues["repository"].(string)
+		return repository
+	}
+	return ""
+}
 
-	// If using local image, add task to push it.
-	if useLocalImage {
-		taskRunner.AddTask("Push docker image to environment repository", func(output *tui.TaskOutput) error {
-			return pushDockerImage(cmd.Context(), output, o.argImageNameTag, envDetails.Deployment.EcrRepo, dockerCredentials)
-		})
+// Get the Metaplay SDK version from the release's values, or an empty string if the release is
+// nil or doesn't have it.
+func getReleaseSdkVersion(rel *release.Release) string {
+	if rel == nil {
+		return ""
 	}
+	if sdkValues, ok := rel.Config["sdk"].(map[string]interface{}); ok {
+		sdkVersion, _ := sdkValues["version"].(string)
+		return sdkVersion
+	}
+	return ""
+}
 
-	// Install or upgrade the Helm chart.
-	taskRunner.AddTask("Deploy game server using Helm", func(output *tui.TaskOutput) error {
-		_, err := helmutil.HelmUpgradeOrInstall(
-			output,
-			actionConfig,
-			existingRelease,
-			envConfig.GetKubernetesNamespace(),
-			helmReleaseName,
-			helmChartPath,
-			useHelmChartVersion,
-			valuesFiles,
-			helmValues,
-			5*time.Minute)
-		return err
-	})
+// Resolve the name of the game server Helm chart whose releases are deployed in the environment:
+// the chart name assigned by the StackAPI, or the default 'metaplay-gameserver'.
+func resolveGameServerChartName(envDetails *envapi.DeploymentSecret) string {
+	return metaplay.ResolveGameServerChartName(envDetails)
+}
 
-	// Validate the game server status.
-	err = targetEnv.WaitForServerToBeReady(cmd.Context(), taskRunner)
-	if err != nil {
-		return err
+// Resolve the name of a new game server Helm release: the release name assigned by the
+// StackAPI, or the default '<environmentID>-gameserver'.
+func resolveDefaultGameServerReleaseName(envConfig *metaproj.ProjectEnvironmentConfig, envDetails *envapi.DeploymentSecret) string {
+	return metaplay.ResolveDefaultGameServerReleaseName(envConfig.HumanID, envDetails)
+}
+
+// Resolve the game server Helm chart to use: either the local chart (--local-chart-path) or
+// the best matching version from the chart repository, using the chart repository and version
+// overrides (--helm-chart-repo, --helm-chart-version) if given. Returns the chart path and
+// version.
+func resolveGameServerHelmChart(project *metaproj.MetaplayProject, localChartPath string, chartRepository string, chartVersion string) (string, string, error) {
+	// Use local Helm chart directly.
+	if localChartPath != "" {
+		if err := helmutil.ValidateLocalHelmChart(localChartPath); err != nil {
+			return "", "", fmt.Errorf("invalid --local-chart-path: %v", err)
+		}
+		log.Debug().Msgf("Helm chart path: %s", localChartPath)
+		return localChartPath, "local", nil
 	}
 
-	// Run the tasks.
-	if err = taskRunner.Run(); err != nil {
-		return err
+	// Resolve Helm chart version to use, either from config file or command line override
+	helmChartVersion := coalesceString(chartVersion, project.Config.ServerChartVersion)
+
+	var chartVersionConstraints version.Constraints = nil
+	if helmChartVersion == "latest-prerelease" {
+		// Accept any version
+	} else {
+		// Parse Helm chart semver range.
+		var err error
+		chartVersionConstraints, err = version.NewConstraint(helmChartVersion)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid Helm chart version: %v", err)
+		}
+		log.Debug().Msgf("Accepted Helm chart semver constraints: %v", chartVersionConstraints)
 	}
 
-	log.Info().Msg(styles.RenderSuccess("✅ Game server successfully deployed!"))
-	return nil
+	// Determine the Helm chart repo and version to use.
+	helmChartRepo := coalesceString(project.Config.HelmChartRepository, chartRepository, "https://charts.metaplay.dev")
+	minChartVersion, _ := version.NewVersion("0.7.0")
+	useHelmChartVersion, err := helmutil.ResolveBestMatchingHelmVersion(helmChartRepo, metaplayGameServerChartName, minChartVersion, chartVersionConstraints)
+	if err != nil {
+		return "", "", err
+	}
+	helmChartPath := helmutil.GetHelmChartPath(helmChartRepo, metaplayGameServerChartName, useHelmChartVersion)
+	log.Debug().Msgf("Helm chart path: %s", helmChartPath)
+	return helmChartPath, useHelmChartVersion, nil
 }
 
 func selectDockerImageInteractively(title string, projectHumanID string) (*envapi.MetaplayImageInfo, error) {
@@ -449,6 +1135,54 @@ func selectDockerImageInteractively(title string, projectHumanID string) (*envap
 	return selectedImage, nil
 }
 
+// Read a docker image reference (or a plain tag) from the file, or from stdin if the path is
+// '-'. Surrounding whitespace is trimmed and the reference is validated.
+func readImageReference(path string) (string, error) {
+	var content []byte
+	var err error
+	source := path
+	if path == "-" {
+		source = "stdin"
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the image from %s: %w", source, err)
+	}
+
+	imageRef := strings.TrimSpace(string(content))
+	if imageRef == "" {
+		return "", exitcode.Errorf(exitcode.ExitUsage, "no image found in %s", source)
+	}
+	if strings.ContainsAny(imageRef, " \t\r\n") {
+		return "", exitcode.Errorf(exitcode.ExitUsage, "expecting a single image reference in %s, got '%s'", source, imageRef)
+	}
+	if _, err := name.ParseReference(imageRef); err != nil {
+		return "", exitcode.Errorf(exitcode.ExitUsage, "invalid image reference '%s' in %s: %v", imageRef, source, err)
+	}
+	return imageRef, nil
+}
+
+// Split a docker image reference 'REPOSITORY:TAG' into the repository and tag. The
+// repository may contain a registry host with a port, eg, 'localhost:5000/mygame:abc'.
+func splitDockerImageReference(imageRef string) (string, string, error) {
+	tagSep := strings.LastIndex(imageRef, ":")
+	if tagSep <= 0 || tagSep < strings.LastIndex(imageRef, "/") || tagSep == len(imageRef)-1 {
+		return "", "", fmt.Errorf("expecting an image reference in the format 'REPOSITORY:TAG', got '%s'", imageRef)
+	}
+	return imageRef[:tagSep], imageRef[tagSep+1:], nil
+}
+
+// Resolve the public hostname of a game server release from its Helm values. Releases
+// that don't override the hostname use the environment's default server hostname.
+func resolveGameServerHostname(helmValues map[string]interface{}, defaultHostname string) string {
+	if hostname, ok := helmValues[gameServerHostnameValueKey].(string); ok && hostname != "" {
+		return hostname
+	}
+	return defaultHostname
+}
+
 // Return the first non-empty string in the provided arguments.
 func coalesceString(values ...string) string {
 	for _, value := range values {
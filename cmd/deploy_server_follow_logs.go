@@ -0,0 +1,84 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Log message emitted by the game server when it has completed its startup.
+const serverStartSequenceCompletedMessage = "start sequence completed"
+
+// Follow the logs of the game server pods running the just-deployed image, starting from
+// sinceTime, until the user interrupts (Ctrl-C) or the timeout elapses. Pods from the old
+// revision that are still being terminated are excluded.
+func followDeployedServerLogs(ctx context.Context, targetEnv *envapi.TargetEnvironment, imageTag string, sinceTime time.Time, timeout time.Duration) error {
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+
+	// Resolve the pods running the new image.
+	allPods, err := envapi.FetchGameServerPods(ctx, kubeCli)
+	if err != nil {
+		return fmt.Errorf("failed to determine game server pods in the environment: %w", err)
+	}
+	pods := []corev1.Pod{}
+	for _, pod := range allPods {
+		if pod.DeletionTimestamp == nil && isPodRunningImageTag(&pod, imageTag) {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		log.Warn().Msgf("No game server pods running image tag %s found, not following logs", imageTag)
+		return nil
+	}
+	log.Debug().Msgf("Following logs of pods: %s", strings.Join(getPodNames(pods), ", "))
+
+	// Stop on Ctrl-C or when the timeout elapses.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderMuted(fmt.Sprintf("Following logs from %d game server pod(s) for up to %s, press Ctrl-C to stop...", len(pods), timeout)))
+	log.Info().Msg("")
+
+	followPodLogs(ctx, kubeCli, pods, sinceTime, func(prefix string, entry LogEntry) {
+		printLogEntry(prefix, entry)
+		if strings.Contains(strings.ToLower(entry.message), serverStartSequenceCompletedMessage) {
+			podName := strings.TrimSuffix(strings.TrimSpace(prefix), ":")
+			log.Info().Msg(styles.RenderSuccess(fmt.Sprintf(">>> %s: server start sequence completed <<<", podName)))
+		}
+	})
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Info().Msg(styles.RenderMuted("Stopped following logs: --follow-timeout elapsed"))
+	} else {
+		log.Info().Msg(styles.RenderMuted("Stopped following logs"))
+	}
+	return nil
+}
+
+// Check whether the pod's game server container runs an image with the given tag.
+func isPodRunningImageTag(pod *corev1.Pod, imageTag string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == metaplayServerContainerName {
+			return strings.HasSuffix(container.Image, ":"+imageTag)
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Type of local Kubernetes cluster, determines how images are loaded into the cluster.
+type localClusterType string
+
+const (
+	localClusterTypeKind     localClusterType = "kind"
+	localClusterTypeMinikube localClusterType = "minikube"
+)
+
+// Deploy the game server into a local Kubernetes cluster (kind or minikube). The locally
+// built image is loaded directly into the cluster, so no registry is needed, and the
+// StackAPI and authentication are not used at all.
+func (o *deployGameServerOpts) runLocalCluster(cmd *cobra.Command) error {
+	// Resolve the project.
+	project, err := resolveProject()
+	if err != nil {
+		return err
+	}
+
+	// Resolve the local image to deploy.
+	if o.argImageNameTag == "" {
+		selectedImage, err := selectDockerImageInteractively("Select Image to Deploy", project.Config.ProjectHumanID)
+		if err != nil {
+			return err
+		}
+		o.argImageNameTag = selectedImage.RepoTag
+	} else if o.argImageNameTag == "latest-local" {
+		localImages, err := envapi.ReadLocalDockerImagesByProjectID(project.Config.ProjectHumanID)
+		if err != nil {
+			return err
+		}
+		if len(localImages) == 0 {
+			return fmt.Errorf("no docker images matching project '%s' found locally; build an image first with 'metaplay build image'", project.Config.ProjectHumanID)
+		}
+		o.argImageNameTag = localImages[0].RepoTag
+	}
+	imageTag, err := extractDockerImageTag(o.argImageNameTag)
+	if err != nil {
+		return err
+	}
+	imageRepository := strings.TrimSuffix(o.argImageNameTag, ":"+imageTag)
+
+	// Resolve the Metaplay SDK version from the local image.
+	imageConfig, err := envapi.ReadLocalDockerImageMetadata(o.argImageNameTag)
+	if err != nil {
+		return err
+	}
+	imageSdkVersion, found := imageConfig.Config.Labels["io.metaplay.sdk_version"]
+	if !found {
+		return fmt.Errorf("invalid docker image: required label 'io.metaplay.sdk_version' not found in the image metadata")
+	}
+
+	// Resolve the kubeconfig and context to use.
+	kubeconfigPayload, kubeContextName, clusterType, clusterName, err := o.resolveLocalClusterKubeConfig()
+	if err != nil {
+		return err
+	}
+
+	// Resolve Helm chart to use (local or remote).
+	helmChartPath, useHelmChartVersion, err := resolveGameServerHelmChart(project, o.flagHelmChartLocalPath, o.flagHelmChartRepository, o.flagHelmChartVersion)
+	if err != nil {
+		return err
+	}
+
+	// Use the values file given on the command line, if any.
+	valuesFiles := []string{}
+	if o.flagHelmValuesPath != "" {
+		valuesFiles = append(valuesFiles, o.flagHelmValuesPath)
+	}
+
+	// Configure Helm.
+	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, o.flagNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Helm config: %v", err)
+	}
+
+	// Resolve Helm release name: default to the existing release, if any.
+	existingReleases, err := helmutil.HelmListReleases(actionConfig, o.flagNamespace, metaplayGameServerChartName)
+	if err != nil {
+		return err
+	}
+	helmReleaseName := o.flagHelmReleaseName
+	if helmReleaseName == "" {
+		if len(existingReleases) > 1 {
+			return fmt.Errorf("multiple game server releases found in the cluster (%s), specify the release to deploy with --release-name", strings.Join(helmutil.GetReleaseNames(existingReleases), ", "))
+		} else if len(existingReleases) == 1 {
+			helmReleaseName = existingReleases[0].Name
+		} else {
+			helmReleaseName = fmt.Sprintf("%s-gameserver", project.Config.ProjectHumanID)
+		}
+	}
+	existingRelease := helmutil.FindReleaseByName(existingReleases, helmReleaseName)
+
+	// Helm values for running in a local cluster: use the image loaded into the cluster
+	// and don't expose the server publicly (no ingress or TLS). The user values file is
+	// applied on top so all these values can be overridden by the user.
+	helmValues := map[string]interface{}{
+		"environment":       "local",
+		"environmentFamily": metaproj.EnvironmentFamilyDevelopment,
+		"config": map[string]interface{}{
+			"files": []string{
+				"./Config/Options.base.yaml",
+				"./Config/Options.dev.yaml",
+			},
+		},
+		"tenant": map[string]interface{}{
+			"discoveryEnabled": false,
+		},
+		"sdk": map[string]interface{}{
+			"version": imageSdkVersion,
+		},
+		"image": map[string]interface{}{
+			"repository": imageRepository,
+			"tag":        imageTag,
+			"pullPolicy": "IfNotPresent",
+		},
+		"ingress": map[string]interface{}{
+			"enabled": false,
+		},
+		"tls": map[string]interface{}{
+			"enabled": false,
+		},
+		"shards": []map[string]interface{}{
+			{
+				"name":      "all",
+				"singleton": true,
+				"requests": map[string]interface{}{
+					"cpu":    "250m",
+					"memory": "500Mi",
+				},
+			},
+		},
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Deploy Game Server to Local Cluster"))
+	log.Info().Msg("")
+	log.Info().Msgf("Target cluster:")
+	log.Info().Msgf("  Kube context:       %s", styles.RenderTechnical(kubeContextName))
+	log.Info().Msgf("  Cluster type:       %s", styles.RenderTechnical(string(clusterType)))
+	log.Info().Msgf("  Namespace:          %s", styles.RenderTechnical(o.flagNamespace))
+	log.Info().Msgf("Build information:")
+	log.Info().Msgf("  Image name:         %s", styles.RenderTechnical(o.argImageNameTag))
+	log.Info().Msgf("  Metaplay SDK:       %s", styles.RenderTechnical(imageSdkVersion))
+	log.Info().Msgf("Deployment info:")
+	if o.flagHelmChartLocalPath != "" {
+		log.Info().Msgf("  Helm chart path:    %s", styles.RenderTechnical(helmChartPath))
+	} else {
+		log.Info().Msgf("  Helm chart version: %s", styles.RenderTechnical(useHelmChartVersion))
+	}
+	log.Info().Msgf("  Helm release name:  %s", styles.RenderTechnical(helmReleaseName))
+	if len(valuesFiles) > 0 {
+		log.Info().Msgf("  Helm values files:  %s", styles.RenderTechnical(strings.Join(valuesFiles, ", ")))
+	}
+	if o.flagAtomic {
+		log.Info().Msgf("  Atomic mode:        %s", styles.RenderTechnical("enabled, the release is rolled back automatically on failure"))
+	}
+	log.Info().Msg("")
+
+	taskRunner := tui.NewTaskRunner()
+
+	// Load the image into the cluster's nodes.
+	taskRunner.AddTask("Load docker image into the local cluster", func(output *tui.TaskOutput) error {
+		return loadImageIntoLocalCluster(output, clusterType, clusterName, o.argImageNameTag)
+	})
+
+	// Install or upgrade the Helm chart. Helm waits for the resources to become ready.
+	taskRunner.AddTask("Deploy game server using Helm", func(output *tui.TaskOutput) error {
+		_, err := helmutil.HelmUpgradeOrInstall(
+			output,
+			actionConfig,
+			existingRelease,
+			o.flagNamespace,
+			helmReleaseName,
+			helmChartPath,
+			useHelmChartVersion,
+			valuesFiles,
+			helmValues,
+			o.flagSetValues,
+			o.flagTimeout,
+			o.flagAtomic,
+			newCliReleaseDescription(nil, ""),
+			newCliReleaseLabels(nil))
+		return reportHelmTimeout(actionConfig, helmReleaseName, err)
+	})
+
+	// Run the tasks.
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msg(styles.RenderSuccess("✅ Game server successfully deployed to the local cluster!"))
+	return nil
+}
+
+// Load the kubeconfig (from --kubeconfig or the default locations) and resolve the context to
+// use (from --kube-context or the current context). Returns the kubeconfig payload with the
+// context selected, the name of the context, and the type and name of the local cluster.
+func (o *deployGameServerOpts) resolveLocalClusterKubeConfig() (string, string, localClusterType, string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if o.flagKubeConfigPath != "" {
+		loadingRules.ExplicitPath = o.flagKubeConfigPath
+	}
+	kubeConfig, err := loadingRules.Load()
+	if err != nil {
+		return "", "", "", "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	// Resolve the context.
+	contextName := coalesceString(o.flagKubeContext, kubeConfig.CurrentContext)
+	if contextName == "" {
+		return "", "", "", "", fmt.Errorf("no current context in the kubeconfig, specify the context to use with --kube-context")
+	}
+	kubeContext, found := kubeConfig.Contexts[contextName]
+	if !found {
+		return "", "", "", "", fmt.Errorf("context '%s' not found in the kubeconfig", contextName)
+	}
+	kubeConfig.CurrentContext = contextName
+
+	// Detect the type of the cluster.
+	clusterType, clusterName, err := detectLocalClusterType(contextName, kubeContext)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	log.Debug().Msgf("Detected local %s cluster '%s' from context '%s'", clusterType, clusterName, contextName)
+
+	// Serialize the kubeconfig with the selected context.
+	kubeconfigPayload, err := clientcmd.Write(*kubeConfig)
+	if err != nil {
+		return "", "", "", "", fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+
+	return string(kubeconfigPayload), contextName, clusterType, clusterName, nil
+}
+
+// Detect whether the kubeconfig context points to a kind or minikube cluster. Returns the
+// cluster type and the name of the cluster (kind cluster name or minikube profile).
+func detectLocalClusterType(contextName string, kubeContext *clientcmdapi.Context) (localClusterType, string, error) {
+	// kind names its contexts 'kind-<clusterName>'.
+	if clusterName, ok := strings.CutPrefix(contextName, "kind-"); ok {
+		return localClusterTypeKind, clusterName, nil
+	}
+
+	// minikube names the context after the profile and annotates it with a 'context_info' extension.
+	if _, ok := kubeContext.Extensions["context_info"]; ok || contextName == "minikube" {
+		return localClusterTypeMinikube, contextName, nil
+	}
+
+	return "", "", fmt.Errorf("unable to detect the type of the cluster of context '%s': only kind and minikube clusters are supported with --local-cluster", contextName)
+}
+
+// Load a local docker image into the nodes of a kind or minikube cluster using the
+// respective tool's CLI.
+func loadImageIntoLocalCluster(output *tui.TaskOutput, clusterType localClusterType, clusterName, imageName string) error {
+	var command string
+	var args []string
+	switch clusterType {
+	case localClusterTypeKind:
+		command = "kind"
+		args = []string{"load", "docker-image", imageName, "--name", clusterName}
+	case localClusterTypeMinikube:
+		command = "minikube"
+		args = []string{"image", "load", imageName, "--profile", clusterName}
+	default:
+		return fmt.Errorf("unsupported local cluster type '%s'", clusterType)
+	}
+
+	if _, err := exec.LookPath(command); err != nil {
+		return fmt.Errorf("'%s' not found in PATH; it is required to load images into a %s cluster", command, clusterType)
+	}
+
+	output.AppendLinef("Running: %s %s", command, strings.Join(args, " "))
+	commandOutput, err := executeCommandCaptureOutput(".", os.Environ(), command, args...)
+	for _, line := range strings.Split(strings.TrimRight(string(commandOutput), "\n"), "\n") {
+		output.AppendLine(line)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load image %s into the %s cluster: %w", imageName, clusterType, err)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/portalapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Number of steps in the deploy wizard.
+const deployWizardNumSteps = 4
+
+// Guide the user through deploying the game server, step by step.
+type deployWizardOpts struct {
+	UsePositionalArgs
+	RequiresProject
+
+	// Arguments to 'metaplay deploy server', built up by the steps.
+	deployArgs []string
+}
+
+// Source of the image to deploy, chosen in the wizard.
+type deployWizardImageSource struct {
+	name        string
+	description string
+	fromLocal   bool
+}
+
+func init() {
+	o := deployWizardOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "wizard",
+		Short: "Deploy the game server step by step with an interactive guide",
+		Run:   runCommand(&o),
+		Long: renderLong(&o, `
+			Deploy the game server into a cloud environment with an interactive guide, without
+			having to know the flags of 'metaplay deploy server'.
+
+			The wizard walks through the following steps:
+			1. Choose the target environment. Deploying into staging and production
+			   environments is warned about.
+			2. Choose the image to deploy: a locally built image (pushed into the environment
+			   when deploying), or an image already in the environment's registry.
+			3. Review the changes to the game server's Helm values compared to the deployed
+			   game server.
+			4. Confirm, after which the game server is deployed with 'metaplay deploy server',
+			   and its logs are followed until it's ready.
+
+			Each step shows the equivalent 'metaplay deploy server' command built up so far, and
+			the full command is shown before deploying, eg, for copy-pasting into CI scripts.
+
+			The wizard requires an interactive terminal. In scripts and CI, use 'metaplay deploy
+			server' directly.
+
+			Related commands:
+			- 'metaplay deploy server ENVIRONMENT IMAGE' to deploy without the wizard.
+			- 'metaplay env diff ENVIRONMENT [TAG]' to review the changes of a deploy.
+			- 'metaplay build image' to build an image to deploy.
+		`),
+		Example: trimIndent(`
+			# Start the deploy wizard.
+			metaplay deploy wizard
+		`),
+	}
+	deployCmd.AddCommand(cmd)
+}
+
+func (o *deployWizardOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *deployWizardOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	if !tui.IsInteractive() {
+		return exitcode.Errorf(exitcode.ExitUsage, "the deploy wizard requires an interactive terminal; in scripts and CI, use 'metaplay deploy server ENVIRONMENT IMAGE' instead")
+	}
+	project := cmdCtx.Project
+	o.deployArgs = nil
+
+	// Step 1: Choose the target environment and log in to it.
+	o.printStep(1, "Choose the target environment")
+	envID, err := tui.SelectEnvironment(project)
+	if err != nil {
+		return err
+	}
+	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, envID)
+	if err != nil {
+		return err
+	}
+	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+	printDeployWizardEnvironmentWarning(envConfig)
+	o.deployArgs = append(o.deployArgs, envConfig.HumanID)
+
+	// Step 2: Choose the image to deploy.
+	o.printStep(2, "Choose the image to deploy")
+	imageArg, imageTag, sdkVersion, err := o.chooseImage(cmd.Context(), project, targetEnv)
+	if err != nil {
+		return err
+	}
+	o.deployArgs = append(o.deployArgs, imageArg)
+
+	// Step 3: Review the changes to the deployed game server.
+	o.printStep(3, "Review the changes")
+	if err := o.reviewChanges(project, envConfig, targetEnv, imageTag, sdkVersion); err != nil {
+		return err
+	}
+
+	// Step 4: Confirm and deploy. Follow the logs until the game server is ready, so that
+	// the outcome is clear without knowing the 'debug' commands.
+	o.deployArgs = append(o.deployArgs, "--follow-logs")
+	o.printStep(4, "Confirm and deploy")
+	confirmed, err := tui.DoConfirmQuestion(cmd.Context(), fmt.Sprintf("Deploy %s into %s?", imageArg, envConfig.HumanID))
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		log.Info().Msg("Deploy cancelled. To deploy later, run:")
+		log.Info().Msg(styles.RenderTechnical("  " + o.renderCommand()))
+		return nil
+	}
+
+	log.Info().Msg("Deploying with the command (eg, for CI scripts):")
+	log.Info().Msg(styles.RenderTechnical("  " + o.renderCommand()))
+	return runDeployServerCommand(cmd, o.deployArgs)
+}
+
+// Print the header of a wizard step, along with the command built up so far.
+func (o *deployWizardOpts) printStep(step int, title string) {
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle(fmt.Sprintf("Step %d/%d: %s", step, deployWizardNumSteps, title)))
+	log.Info().Msgf("Command so far: %s", styles.RenderTechnical(o.renderCommand()))
+	log.Info().Msg("")
+}
+
+// Render the equivalent 'metaplay deploy server' command of the wizard's choices so far.
+func (o *deployWizardOpts) renderCommand() string {
+	return strings.Join(append([]string{"metaplay", "deploy", "server"}, o.deployArgs...), " ")
+}
+
+// Warn about deploying into a staging or production environment, where the deploy affects
+// real players or the final testing before them.
+func printDeployWizardEnvironmentWarning(envConfig *metaproj.ProjectEnvironmentConfig) {
+	log.Info().Msgf("Environment type: %s", renderEnvironmentType(envConfig.Type))
+	switch envConfig.Type {
+	case portalapi.EnvironmentTypeProduction:
+		log.Warn().Msg(styles.RenderWarning("WARNING: This is a production environment! Deploying restarts the game server for live players."))
+	case portalapi.EnvironmentTypeStaging:
+		log.Warn().Msg(styles.RenderWarning("Note: This is a staging environment, make sure the deploy is expected by others using it."))
+	}
+}
+
+// Let the user choose the image to deploy: a local image or an image in the environment's
+// registry. Returns the image argument for 'metaplay deploy server', and the image tag and
+// Metaplay SDK version for reviewing the changes.
+func (o *deployWizardOpts) chooseImage(ctx context.Context, project *metaproj.MetaplayProject, targetEnv *envapi.TargetEnvironment) (string, string, string, error) {
+	sources := []deployWizardImageSource{
+		{name: "Local image", description: "built with 'metaplay build image', pushed when deploying", fromLocal: true},
+		{name: "Image in the environment's registry", description: "pushed earlier, eg, by CI"},
+	}
+	source, err := tui.ChooseFromListDialog("Select Image Source", sources, func(source *deployWizardImageSource) (string, string) {
+		return source.name, source.description
+	})
+	if err != nil {
+		return "", "", "", err
+	}
+	log.Info().Msgf(" %s %s", styles.RenderSuccess("✓"), source.name)
+
+	// Local images are deployed with their full name, so that they get pushed.
+	if source.fromLocal {
+		if _, err := resolveContainerRuntimeAPI(ctx, project); err != nil {
+			return "", "", "", err
+		}
+		image, err := selectDockerImageInteractively("Select Image to Deploy", project.Config.ProjectHumanID)
+		if err != nil {
+			return "", "", "", err
+		}
+		return image.RepoTag, image.Tag, image.SdkVersion, nil
+	}
+
+	// Images in the registry are deployed with their tag.
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return "", "", "", err
+	}
+	repository, err := envapi.ParseECRRepository(envDetails.Deployment.EcrRepo)
+	if err != nil {
+		return "", "", "", err
+	}
+	ecrClient, err := targetEnv.NewECRClient(ctx, envDetails)
+	if err != nil {
+		return "", "", "", err
+	}
+	images, err := envapi.ListECRImages(ctx, ecrClient, repository)
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(images) == 0 {
+		return "", "", "", exitcode.Errorf(exitcode.ExitNotFound, "no images in the environment's registry; push one with 'metaplay image push' or choose a local image")
+	}
+	image, err := tui.ChooseFromListDialog("Select Image to Deploy", images, func(image *envapi.ECRImage) (string, string) {
+		description := fmt.Sprintf("pushed %s", humanize.Time(image.PushedAt))
+		if len(image.Tags) > 1 {
+			description = fmt.Sprintf("also %s, %s", strings.Join(image.Tags[1:], ", "), description)
+		}
+		return image.Tags[0], description
+	})
+	if err != nil {
+		return "", "", "", err
+	}
+	log.Info().Msgf(" %s %s", styles.RenderSuccess("✓"), image.Tags[0])
+
+	// Resolve the SDK version from the image's labels.
+	imageTag := image.Tags[0]
+	sdkVersion, err := resolveRegistryImageSdkVersion(targetEnv, imageTag)
+	if err != nil {
+		return "", "", "", err
+	}
+	return imageTag, imageTag, sdkVersion, nil
+}
+
+// Show the changes that deploying the image makes to the Helm values of the deployed game
+// server, like 'metaplay env diff'. If there are multiple game server releases, the user
+// chooses the one to deploy into.
+func (o *deployWizardOpts) reviewChanges(project *metaproj.MetaplayProject, envConfig *metaproj.ProjectEnvironmentConfig, targetEnv *envapi.TargetEnvironment, imageTag string, sdkVersion string) error {
+	kubeconfigPayload, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
+	if err != nil {
+		return err
+	}
+	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, envConfig.GetKubernetesNamespace())
+	if err != nil {
+		return fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+	releases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), metaplayGameServerChartName)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		log.Info().Msgf("No game server deployed in %s yet, the image will be deployed as a new release.", styles.RenderTechnical(envConfig.HumanID))
+		return nil
+	}
+	deployedRelease, err := selectGameServerRelease(releases, "")
+	if err != nil {
+		return err
+	}
+	if len(releases) > 1 {
+		o.deployArgs = append(o.deployArgs, "--release-name="+deployedRelease.Name)
+	}
+
+	diffOpts := envDiffOpts{argImageTag: imageTag, flagValuesFromEnv: true}
+	_, err = diffOpts.printValuesDiff(project, envConfig, actionConfig, deployedRelease, imageTag, sdkVersion)
+	return err
+}
+
+// Run 'metaplay deploy server' with the arguments, as if run from the command line. The
+// command exits the process on failure.
+func runDeployServerCommand(cmd *cobra.Command, args []string) error {
+	serverCmd, _, err := deployCmd.Find([]string{"server"})
+	if err != nil {
+		return err
+	}
+	if err := serverCmd.ParseFlags(args); err != nil {
+		return err
+	}
+	serverCmd.SetContext(cmd.Context())
+	serverCmd.Run(serverCmd, serverCmd.Flags().Args())
+	return nil
+}
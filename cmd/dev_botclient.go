@@ -4,10 +4,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"time"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/portutil"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -18,6 +21,8 @@ type devBotClientOpts struct {
 
 	extraArgs       []string
 	flagEnvironment string
+	flagWait        bool
+	flagWaitTimeout time.Duration
 }
 
 func init() {
@@ -47,6 +52,9 @@ func init() {
 			# Run bots against the 'tough-falcons' cloud environment.
 			metaplay dev botclient -e tough-falcons
 
+			# Wait for the locally running server to start before running the bots.
+			metaplay dev botclient --wait
+
 			# Pass additional arguments to 'dotnet run' of the BotClient project.
 			metaplay dev botclient -- -MaxBots=5 -MaxBotId=20
 		`),
@@ -56,13 +64,21 @@ func init() {
 
 	flags := cmd.Flags()
 	flags.StringVarP(&o.flagEnvironment, "environment", "e", "", "Environment (from metaplay-project.yaml) to run the bots against.")
+	flags.BoolVar(&o.flagWait, "wait", false, "Wait for the locally running server to accept connections before starting the bots")
+	flags.DurationVar(&o.flagWaitTimeout, "wait-timeout", 5*time.Minute, "How long to wait for the local server with --wait")
 }
 
 func (o *devBotClientOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagWait && o.flagEnvironment != "" {
+		return fmt.Errorf("--wait can only be used when running against the local server")
+	}
+	if cmd.Flags().Changed("wait-timeout") && !o.flagWait {
+		return fmt.Errorf("--wait-timeout can only be used with --wait")
+	}
 	return nil
 }
 
-func (o *devBotClientOpts) Run(cmd *cobra.Command) error {
+func (o *devBotClientOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Load project config.
 	project, err := resolveProject()
 	if err != nil {
@@ -100,8 +116,8 @@ func (o *devBotClientOpts) Run(cmd *cobra.Command) error {
 		log.Debug().Msgf("Flags to run against environment %s: %v", o.flagEnvironment, targetEnvFlags)
 	}
 
-	// Check for .NET SDK installation and required version (based on SDK version).
-	if err := checkDotnetSdkVersion(project.VersionMetadata.MinDotnetSdkVersion); err != nil {
+	// Check for a .NET SDK installation compatible with the SDK and runtime versions.
+	if err := checkDotnetSdkVersion(cmd.Context(), project); err != nil {
 		return err
 	}
 
@@ -110,16 +126,24 @@ func (o *devBotClientOpts) Run(cmd *cobra.Command) error {
 
 	// Build the BotClient project
 	if err := execChildInteractive(botClientPath, "dotnet", []string{"build"}); err != nil {
-		log.Error().Msgf("Failed to build the BotClient .NET project: %s", err)
-		os.Exit(1)
+		return exitcode.Errorf(exitcode.ExitBuildFailed, "failed to build the BotClient .NET project: %w", err)
+	}
+
+	// Wait for the local server to be ready, if requested.
+	if o.flagWait {
+		log.Info().Msgf("Waiting for the local game server to accept connections on port %d...", localServerClientPort)
+		ctx, cancel := context.WithTimeout(cmd.Context(), o.flagWaitTimeout)
+		defer cancel()
+		if err := portutil.WaitForPort(ctx, "localhost", localServerClientPort, time.Second); err != nil {
+			return err
+		}
 	}
 
 	// Run the project without rebuilding
 	botRunFlags := append([]string{"run", "--no-build"}, targetEnvFlags...)
 	botRunFlags = append(botRunFlags, o.extraArgs...)
 	if err := execChildInteractive(botClientPath, "dotnet", botRunFlags); err != nil {
-		log.Error().Msgf("BotClient exited with error: %s", err)
-		os.Exit(1)
+		return fmt.Errorf("BotClient exited with error: %w", err)
 	}
 
 	// BotClients terminated normally
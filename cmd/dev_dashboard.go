@@ -6,6 +6,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/metaplay/cli/pkg/portutil"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -37,7 +38,7 @@ func (o *devDashboardOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *devDashboardOpts) Run(cmd *cobra.Command) error {
+func (o *devDashboardOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Load project config.
 	project, err := resolveProject()
 	if err != nil {
@@ -66,6 +67,15 @@ func (o *devDashboardOpts) Run(cmd *cobra.Command) error {
 		return fmt.Errorf("failed to build the LiveOps Dashboard: %s", err)
 	}
 
+	// Check that the dashboard's port is free, and that a game server is running for the
+	// dashboard to connect to.
+	if portutil.IsPortInUse(localDashboardDevPort) {
+		return fmt.Errorf("port %d is already in use; is another dashboard already running?", localDashboardDevPort)
+	}
+	if !portutil.IsPortInUse(localServerAdminAPIPort) {
+		log.Warn().Msgf("No game server is running locally (port %d is free). Start it with 'metaplay dev server' for the dashboard to connect to.", localServerAdminAPIPort)
+	}
+
 	// Run the dashboard project in dev mode
 	devArgs := append([]string{"dev"}, o.extraArgs...)
 	if err := execChildInteractive(dashboardPath, "pnpm", devArgs); err != nil {
@@ -5,7 +5,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/metaplay/cli/pkg/envapi"
@@ -61,16 +60,17 @@ func (o *devImageOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *devImageOpts) Run(cmd *cobra.Command) error {
+func (o *devImageOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := resolveProject()
 	if err != nil {
 		return err
 	}
 
-	// Check that docker is installed and running
-	if err := checkCommand("docker", "info"); err != nil {
-		return fmt.Errorf("failed to invoke docker. Ensure docker is installed and running.")
+	// Check that the container runtime (docker or podman) is installed and running.
+	containerRuntime, err := resolveContainerRuntimeAPI(cmd.Context(), project)
+	if err != nil {
+		return err
 	}
 
 	// If no docker image specified, scan the images matching project from the local docker repo
@@ -119,13 +119,12 @@ func (o *devImageOpts) Run(cmd *cobra.Command) error {
 	dockerRunArgs = append(dockerRunArgs, o.extraArgs...)
 
 	log.Info().Msg("")
-	log.Info().Msgf(styles.RenderMuted("docker %s"), strings.Join(dockerRunArgs, " "))
+	log.Info().Msgf(styles.RenderMuted("%s %s"), containerRuntime.Binary, strings.Join(dockerRunArgs, " "))
 	log.Info().Msg("")
 
 	// Run the docker image.
-	if err := executeCommand(".", nil, "docker", dockerRunArgs...); err != nil {
-		log.Error().Msgf("Docker run failed: %v", err)
-		os.Exit(1)
+	if err := executeCommand(".", nil, containerRuntime.Binary, dockerRunArgs...); err != nil {
+		return fmt.Errorf("%s run failed: %w", containerRuntime.Name, err)
 	}
 
 	// The docker container exited normally.
@@ -5,17 +5,31 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/portutil"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
+// Ports used by the locally running game server and dashboard.
+const (
+	localServerClientPort   = 9339 // Game server port for client connections.
+	localServerAdminAPIPort = 5550 // Game server admin API (and built-in dashboard).
+	localDashboardDevPort   = 5551 // Dashboard development server ('metaplay dev dashboard').
+)
+
 // Run the game server locally.
 type devServerOpts struct {
 	UsePositionalArgs
 
-	extraArgs []string
+	extraArgs              []string
+	flagEmulateEnvironment string
 }
 
 func init() {
@@ -36,6 +50,17 @@ func init() {
 			This command is roughly equivalent to running:
 			Backend/Server$ dotnet run EXTRA_ARGS
 
+			With --emulate-environment, the runtime options of the given cloud environment are
+			fetched and the subset that is meaningful locally, eg, feature flags and game-specific
+			options, is written into a temporary options file. The server is then run with the
+			files Config/Options.base.yaml, Config/Options.local.yaml (if it exists), and the
+			temporary file, in that order, using the METAPLAY_OPTIONS environment variable. The
+			temporary file is written outside the project and removed when the server exits.
+
+			Infrastructure options (database, cluster topology, endpoints, admin API
+			authentication, external services) and secrets are not emulated. These differences
+			are listed before the server is started.
+
 			{Arguments}
 		`),
 		Example: trimIndent(`
@@ -47,10 +72,16 @@ func init() {
 
 			# Pass additional arguments to the game server (dotnet run).
 			metaplay dev server -- -ExitAfter=00:00:30
+
+			# Run with the runtime options of environment tough-falcons.
+			metaplay dev server --emulate-environment tough-falcons
 		`),
 	}
 
 	devCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagEmulateEnvironment, "emulate-environment", "", "Run with the runtime options of the given environment (that can be emulated locally)")
 }
 
 func (o *devServerOpts) Prepare(cmd *cobra.Command, args []string) error {
@@ -59,7 +90,7 @@ func (o *devServerOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *devServerOpts) Run(cmd *cobra.Command) error {
+func (o *devServerOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Load project config.
 	project, err := resolveProject()
 	if err != nil {
@@ -70,14 +101,38 @@ func (o *devServerOpts) Run(cmd *cobra.Command) error {
 	log.Info().Msg(styles.RenderTitle("Run Game Server Locally"))
 	log.Info().Msg("")
 
-	// Check for .NET SDK installation and required version (based on SDK version).
-	if err := checkDotnetSdkVersion(project.VersionMetadata.MinDotnetSdkVersion); err != nil {
+	// Check for a .NET SDK installation compatible with the SDK and runtime versions.
+	if err := checkDotnetSdkVersion(cmd.Context(), project); err != nil {
 		return err
 	}
 
+	// Check that the server's ports are free, eg, that another server isn't already running.
+	for _, port := range []int{localServerClientPort, localServerAdminAPIPort} {
+		if portutil.IsPortInUse(port) {
+			return fmt.Errorf("port %d is already in use; is another game server already running?", port)
+		}
+	}
+
 	// Resolve server path.
 	serverPath := project.GetServerDir()
 
+	// Emulate the runtime options of a cloud environment, if requested.
+	var serverEnv []string
+	if o.flagEmulateEnvironment != "" {
+		overlayPath, err := o.writeEmulatedRuntimeOptions(cmd, project)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(overlayPath)
+
+		optionsFiles := []string{"./Config/Options.base.yaml"}
+		if _, err := os.Stat(filepath.Join(serverPath, "Config", "Options.local.yaml")); err == nil {
+			optionsFiles = append(optionsFiles, "./Config/Options.local.yaml")
+		}
+		optionsFiles = append(optionsFiles, overlayPath)
+		serverEnv = append(serverEnv, "METAPLAY_OPTIONS="+strings.Join(optionsFiles, ";"))
+	}
+
 	// Build the game server .NET project.
 	if err := execChildInteractive(serverPath, "dotnet", []string{"build"}); err != nil {
 		return fmt.Errorf("failed to build the game server .NET project: %s", err)
@@ -85,7 +140,7 @@ func (o *devServerOpts) Run(cmd *cobra.Command) error {
 
 	// Run the game server (skip build).
 	runArgs := append([]string{"run", "--no-build"}, o.extraArgs...)
-	if err := execChildInteractive(serverPath, "dotnet", runArgs); err != nil {
+	if err := execChildInteractiveWithEnv(serverPath, "dotnet", runArgs, serverEnv); err != nil {
 		return fmt.Errorf("game server exited with error: %s", err)
 	}
 
@@ -93,3 +148,54 @@ func (o *devServerOpts) Run(cmd *cobra.Command) error {
 	log.Info().Msgf("Game server terminated normally")
 	return nil
 }
+
+// Fetch the runtime options of the environment to emulate, list the differences that cannot be
+// emulated, and write the emulated options into a temporary options file outside the project.
+// Returns the path to the file, which the caller must remove.
+func (o *devServerOpts) writeEmulatedRuntimeOptions(cmd *cobra.Command, project *metaproj.MetaplayProject) (string, error) {
+	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.flagEmulateEnvironment)
+	if err != nil {
+		return "", err
+	}
+	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+
+	options, err := targetEnv.GetRuntimeOptions()
+	if err != nil {
+		return "", err
+	}
+	emulated, gaps := envapi.SplitLocallyEmulatedRuntimeOptions(options)
+
+	log.Info().Msgf("Emulated environment:")
+	log.Info().Msgf("  Name:               %s", styles.RenderTechnical(envConfig.Name))
+	log.Info().Msgf("  ID:                 %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("  Runtime options:    %s", styles.RenderTechnical(fmt.Sprintf("%d of %d emulated", len(emulated), len(options))))
+	log.Info().Msg("")
+	log.Info().Msgf("%s Not emulated:", styles.RenderWarning("⚠️"))
+	for _, gap := range gaps {
+		log.Info().Msgf("  - %s: %s %s", gap.Name, gap.Description, styles.RenderMuted(fmt.Sprintf("(%d options left out)", len(gap.Keys))))
+		for _, key := range gap.Keys {
+			log.Debug().Msgf("    %s", key)
+		}
+	}
+	log.Info().Msg("")
+
+	overlay, err := envapi.RenderRuntimeOptionsOverlay(emulated)
+	if err != nil {
+		return "", err
+	}
+	header := fmt.Sprintf("# Runtime options emulating environment %s, generated by 'metaplay dev server'.\n", envConfig.HumanID)
+
+	// Write into the system temp directory so that the file never ends up in the project.
+	file, err := os.CreateTemp("", fmt.Sprintf("metaplay-options-%s-*.yaml", envConfig.HumanID))
+	if err != nil {
+		return "", fmt.Errorf("failed to create the runtime options file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append([]byte(header), overlay...)); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write the runtime options file: %w", err)
+	}
+
+	log.Debug().Msgf("Wrote emulated runtime options to %s", file.Name())
+	return file.Name(), nil
+}
@@ -31,7 +31,7 @@ func (o *showCommandsOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *showCommandsOpts) Run(cmd *cobra.Command) error {
+func (o *showCommandsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	printCommandTree(rootCmd, "")
 
 	return nil
@@ -4,84 +4,155 @@
 package cmd
 
 import (
-	"bytes"
-	"errors"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
 
-	"github.com/hashicorp/go-version"
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/dotnetutil"
+	"github.com/metaplay/cli/pkg/metaproj"
 	"github.com/metaplay/cli/pkg/styles"
+	"github.com/pkg/browser"
 	"github.com/rs/zerolog/log"
 )
 
-// Provide installation instructions based on the operating system
-func getDotnetInstallInstructions() string {
+// Get the .NET download page URL for the given channel (eg, '9.0').
+func getDotnetDownloadURL(channel string) string {
+	return fmt.Sprintf("https://dotnet.microsoft.com/download/dotnet/%s", channel)
+}
+
+// Provide installation instructions for the given .NET channel (eg, '9.0') based on
+// the operating system.
+func getDotnetInstallInstructions(channel string) string {
+	major := strings.Split(channel, ".")[0]
+	downloadURL := getDotnetDownloadURL(channel)
 	switch runtime.GOOS {
 	case "windows":
-		return `
-.NET SDK is missing or outdated. Please install or upgrade .NET SDK:
-1. Go to: https://dotnet.microsoft.com/download
-2. Download and run the installer for desired .NET SDK version.
-3. Follow the installation steps and ensure 'dotnet' is added to your PATH.
-`
+		return fmt.Sprintf(`Install .NET SDK %s with:
+  winget install Microsoft.DotNet.SDK.%s
+Or download the installer from: %s`, channel, major, downloadURL)
 	case "darwin":
-		return `
-.NET SDK is missing or outdated. Please install or upgrade .NET SDK:
-1. Open a terminal.
-2. Install Homebrew (if not installed): https://brew.sh/
-3. Run: brew install --cask dotnet-sdk
-4. Add .NET SDK to your PATH by running: export PATH="$PATH:/usr/local/share/dotnet"
-5. Verify installation with: dotnet --version
-`
+		return fmt.Sprintf(`Download and run the .NET SDK %s installer from: %s
+Or, with Homebrew (installs the latest .NET SDK):
+  brew install --cask dotnet-sdk`, channel, downloadURL)
 	case "linux":
-		return `.NET SDK is missing or outdated.
-Please install or upgrade .NET SDK: https://learn.microsoft.com/en-us/dotnet/core/install/linux`
+		return fmt.Sprintf(`Install .NET SDK %s with your package manager, eg, on Ubuntu/Debian:
+  sudo apt-get update && sudo apt-get install -y dotnet-sdk-%s
+Or see the instructions for your distribution at: %s`, channel, channel, downloadURL)
 	default:
-		return `
-.NET SDK is missing or outdated. Please install or upgrade .NET SDK:
-Visit: https://dotnet.microsoft.com/download for instructions specific to your operating system.
-`
+		return fmt.Sprintf("Download .NET SDK %s from: %s", channel, downloadURL)
 	}
 }
 
-// Checks if .NET SDK is installed and check that it is recent enough for the SDK
-// version used.
-func checkDotnetSdkVersion(requiredDotnetVersion *version.Version) error {
-	// Note: This gets the SDK version, not runtime version (eg, 8.0.400)
-	cmd := exec.Command("dotnet", "--version")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	if err := cmd.Run(); err != nil {
-		return errors.New(".NET SDK is not installed or not in PATH.\n" + getDotnetInstallInstructions())
+// Check whether the 'dotnet' binary is one known to cause problems: the .NET runtime
+// bundled with Unity (which has no SDK) or a snap-confined installation (which breaks
+// 'dotnet run'). Returns a warning message, or an empty string if all is fine.
+func detectProblematicDotnetBinary(dotnetPath string) string {
+	resolvedPath, err := filepath.EvalSymlinks(dotnetPath)
+	if err != nil {
+		resolvedPath = dotnetPath
+	}
+	lowerPath := strings.ToLower(filepath.ToSlash(resolvedPath))
+
+	if strings.Contains(lowerPath, "unity") {
+		return fmt.Sprintf("'dotnet' resolves to %s, which looks like the .NET runtime bundled with Unity. It cannot be used to build or run the project; make sure a standalone .NET SDK installation comes first in your PATH.", resolvedPath)
+	}
+	if strings.HasPrefix(lowerPath, "/snap/") || strings.Contains(lowerPath, "/snap/bin/") {
+		return fmt.Sprintf("'dotnet' resolves to %s, which is a snap-confined installation. Snap's confinement is known to break 'dotnet run'; consider installing .NET SDK with your package manager or Microsoft's install script instead.", resolvedPath)
+	}
+	return ""
+}
+
+// Check that a .NET SDK able to build the project is installed: the SDK must be at least
+// the minimum version required by the Metaplay SDK, and its major version must be at least
+// that of the project's .NET runtime version (eg, building for .NET 9 requires SDK 9.x).
+// If no suitable SDK is found, instructions for installing one are shown and, in interactive
+// mode, the user is offered to open the download page.
+func checkDotnetSdkVersion(ctx context.Context, project *metaproj.MetaplayProject) error {
+	minSdkVersion := project.VersionMetadata.MinDotnetSdkVersion
+	runtimeVersion := project.Config.DotnetRuntimeVersion
+	runtimeMajor := runtimeVersion.Segments()[0]
+
+	// Resolve the .NET channel (major.minor) to recommend installing.
+	channelSegments := runtimeVersion.Segments()
+	if minSdkVersion.Segments()[0] > runtimeMajor {
+		channelSegments = minSdkVersion.Segments()
 	}
+	channel := fmt.Sprintf("%d.%d", channelSegments[0], channelSegments[1])
 
-	// Parse installed .NET version
-	installedVersionStr := strings.TrimSpace(out.String())
-	installedVersion, err := version.NewVersion(installedVersionStr)
+	// Handle missing .NET altogether.
+	dotnetPath, err := exec.LookPath("dotnet")
 	if err != nil {
-		return fmt.Errorf("failed to parse required .NET version string '%s': %v", installedVersionStr, err)
+		return handleMissingDotnetSdk(ctx, channel, fmt.Sprintf(".NET SDK is not installed or 'dotnet' is not in PATH. .NET SDK %s is required.", channel))
 	}
+	log.Debug().Msgf("Using dotnet binary: %s", dotnetPath)
 
-	// Print the info.
-	badge := styles.RenderMuted(fmt.Sprintf("[minimum: %s]", requiredDotnetVersion))
-	log.Info().Msgf(".NET SDK detected: %s %s %s", styles.RenderTechnical(installedVersion.String()), styles.RenderSuccess("✓"), badge)
+	// Warn about problematic installations.
+	if warning := detectProblematicDotnetBinary(dotnetPath); warning != "" {
+		log.Warn().Msgf("Warning: %s", warning)
+	}
+
+	// Find the installed SDKs.
+	installedSdks, err := dotnetutil.ListInstalledSDKs(dotnetPath)
+	if err != nil {
+		return err
+	}
+	installedVersions := []string{}
+	for _, sdk := range installedSdks {
+		installedVersions = append(installedVersions, sdk.Version.String())
+	}
+	log.Debug().Msgf("Installed .NET SDKs: %s", strings.Join(installedVersions, ", "))
 
-	// Check that .NET version is recent enough
-	if installedVersion.LessThan(requiredDotnetVersion) {
-		return fmt.Errorf(".NET SDK version %s or higher is required, but found %s.\n%s",
-			requiredDotnetVersion, installedVersion, getDotnetInstallInstructions())
+	// Find the latest compatible SDK.
+	bestSdk := dotnetutil.FindBestSDK(installedSdks, minSdkVersion, runtimeMajor)
+
+	// Handle no compatible SDK.
+	if bestSdk == nil {
+		installedStr := "none"
+		if len(installedVersions) > 0 {
+			installedStr = strings.Join(installedVersions, ", ")
+		}
+		return handleMissingDotnetSdk(ctx, channel, fmt.Sprintf("No compatible .NET SDK found. The project targets .NET %s and requires .NET SDK %s or later (with major version %d or higher). Installed SDKs: %s.", runtimeVersion, minSdkVersion, runtimeMajor, installedStr))
 	}
 
+	// Print the info.
+	badge := styles.RenderMuted(fmt.Sprintf("[minimum: %s, runtime: %s]", minSdkVersion, runtimeVersion))
+	log.Info().Msgf(".NET SDK detected: %s %s %s", styles.RenderTechnical(bestSdk.Version.String()), styles.RenderSuccess("✓"), badge)
 	log.Info().Msg("")
 	return nil
 }
 
+// Show the instructions for installing the required .NET SDK and, in interactive mode,
+// offer to open the download page. Always returns an error describing the problem.
+func handleMissingDotnetSdk(ctx context.Context, channel string, problem string) error {
+	log.Error().Msg(problem)
+	log.Info().Msg("")
+	log.Info().Msg(getDotnetInstallInstructions(channel))
+	log.Info().Msg("")
+
+	if tui.IsInteractive() {
+		downloadURL := getDotnetDownloadURL(channel)
+		openPage, err := tui.DoConfirmQuestion(ctx, fmt.Sprintf("Open %s in your browser?", downloadURL))
+		if err != nil {
+			return err
+		}
+		if openPage {
+			if err := browser.OpenURL(downloadURL); err != nil {
+				log.Warn().Msgf("Failed to open the browser: %v", err)
+			}
+		}
+	}
+
+	return exitcode.Errorf(exitcode.ExitNotFound, "a compatible .NET SDK %s is required", channel)
+}
+
 func execChildTask(workingDir string, binary string, args []string) error {
 	cmd := exec.Command(binary, args...)
 	cmd.Dir = workingDir
@@ -99,9 +170,18 @@ func execChildTask(workingDir string, binary string, args []string) error {
 // Runs a child process in "interactive" mode where all inputs/outputs are forwarded
 // to the sub-process.
 func execChildInteractive(workingDir string, binary string, args []string) error {
+	return execChildInteractiveWithEnv(workingDir, binary, args, nil)
+}
+
+// Like execChildInteractive(), but with additional environment variables (as KEY=VALUE)
+// for the sub-process.
+func execChildInteractiveWithEnv(workingDir string, binary string, args []string, extraEnv []string) error {
 	// Create the command to run the .NET binary
 	cmd := exec.Command(binary, args...)
 	cmd.Dir = workingDir
+	if len(extraEnv) > 0 {
+		cmd.Env = append(os.Environ(), extraEnv...)
+	}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -0,0 +1,19 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// envCmd includes commands for managing the configuration of cloud environments.
+var envCmd = &cobra.Command{
+	Use:     "env",
+	Aliases: []string{"environment"},
+	Short:   "Manage the configuration of cloud environments",
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Set up kubectl to access the environment.
+type envConnectOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagKubeConfigPath string
+	flagPrintExport    bool
+}
+
+func init() {
+	o := envConnectOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "connect ENVIRONMENT [flags]",
+		Short:             "Set up kubectl to access the environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Set up kubectl (and other Kubernetes tools) to access the environment's namespace.
+
+			The environment's cluster, user, and context are merged into your kubeconfig
+			(~/.kube/config, or the first file in $KUBECONFIG) under the name
+			'metaplay-<environment>', and the current context is switched to it. Other entries in
+			the kubeconfig are retained. Running the command again updates the entries.
+
+			The kubeconfig contains no credentials: kubectl invokes
+			'metaplay get kubernetes-execcredential' to get fresh credentials when needed, so the
+			access lasts as long as you are logged in to the CLI.
+
+			With --print-export, your kubeconfig is not modified. Instead, the environment's
+			kubeconfig is written into a new temporary file and a command to point KUBECONFIG at
+			it is printed to stdout, to be evaluated in your shell.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay get kubeconfig ...' to get the kubeconfig for the environment.
+			- 'metaplay debug shell ...' to start a shell in a game server pod.
+		`),
+		Example: trimIndent(`
+			# Merge environment tough-falcons into ~/.kube/config and switch kubectl to it.
+			metaplay env connect tough-falcons
+			kubectl get pods
+
+			# Only point the current shell to the environment.
+			eval "$(metaplay env connect tough-falcons --print-export)"
+
+			# Merge into a specific kubeconfig file.
+			metaplay env connect tough-falcons --kubeconfig=$HOME/.kube/metaplay.yaml
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagKubeConfigPath, "kubeconfig", "", "Path to the kubeconfig file to merge into (defaults to the first file in $KUBECONFIG or ~/.kube/config)")
+	flags.BoolVar(&o.flagPrintExport, "print-export", false, "Write the kubeconfig into a temporary file and print a command to set KUBECONFIG to it, instead of merging")
+}
+
+func (o *envConnectOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagPrintExport && o.flagKubeConfigPath != "" {
+		return fmt.Errorf("--kubeconfig cannot be used with --print-export")
+	}
+	return nil
+}
+
+func (o *envConnectOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// The exec credential kubeconfig identifies the user by email.
+	authProvider, err := getAuthProvider(cmdCtx.Project, "metaplay")
+	if err != nil {
+		return err
+	}
+	userinfo, err := auth.FetchUserInfo(authProvider, cmdCtx.TokenSet)
+	if err != nil {
+		return err
+	}
+	kubeconfigPayload, err := targetEnv.GetKubeConfigWithExecCredential(userinfo.Email)
+	if err != nil {
+		return fmt.Errorf("failed to get environment k8s config: %w", err)
+	}
+
+	// Write the kubeconfig into a temporary file, if requested.
+	if o.flagPrintExport {
+		filePath, err := writeTemporaryKubeConfig(envConfig.HumanID, kubeconfigPayload)
+		if err != nil {
+			return err
+		}
+		stderrLogger.Info().Msgf("Wrote kubeconfig for %s to %s", envConfig.HumanID, filePath)
+		fmt.Println(renderKubeConfigExport(filePath))
+		return nil
+	}
+
+	envKubeConfig, err := clientcmd.Load([]byte(kubeconfigPayload))
+	if err != nil {
+		return fmt.Errorf("failed to parse environment k8s config: %w", err)
+	}
+
+	// Merge the environment into the kubeconfig file.
+	kubeConfigPath := o.flagKubeConfigPath
+	if kubeConfigPath == "" {
+		kubeConfigPath = clientcmd.NewDefaultPathOptions().GetDefaultFilename()
+	}
+	kubeConfig, err := loadKubeConfigFile(kubeConfigPath)
+	if err != nil {
+		return err
+	}
+	contextName := "metaplay-" + envConfig.HumanID
+	if err := envapi.MergeKubeConfigContext(kubeConfig, envKubeConfig, contextName); err != nil {
+		return err
+	}
+	if err := clientcmd.WriteToFile(*kubeConfig, kubeConfigPath); err != nil {
+		return fmt.Errorf("failed to write kubeconfig %s: %w", kubeConfigPath, err)
+	}
+
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Switched kubectl to environment %s", envConfig.HumanID)))
+	log.Info().Msgf("  Kubeconfig: %s", styles.RenderTechnical(kubeConfigPath))
+	log.Info().Msgf("  Context:    %s", styles.RenderTechnical(contextName))
+	log.Info().Msgf("  Namespace:  %s", styles.RenderTechnical(envConfig.GetKubernetesNamespace()))
+	log.Info().Msg("")
+	log.Info().Msgf("Try it out with: %s", styles.RenderPrompt("kubectl get pods"))
+	return nil
+}
+
+// Load the kubeconfig file, or return an empty config if the file doesn't exist yet.
+func loadKubeConfigFile(filePath string) (*clientcmdapi.Config, error) {
+	kubeConfig, err := clientcmd.LoadFromFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Debug().Msgf("Kubeconfig %s does not exist, creating it", filePath)
+		return clientcmdapi.NewConfig(), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig %s: %w", filePath, err)
+	}
+	return kubeConfig, nil
+}
+
+// Write the kubeconfig into a new temporary file, readable only by the user.
+func writeTemporaryKubeConfig(environment string, kubeconfigPayload string) (string, error) {
+	file, err := os.CreateTemp("", fmt.Sprintf("metaplay-kubeconfig-%s-*.yaml", environment))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary kubeconfig file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(kubeconfigPayload); err != nil {
+		return "", fmt.Errorf("failed to write temporary kubeconfig file: %w", err)
+	}
+	return filepath.Abs(file.Name())
+}
+
+// Render the shell command for setting KUBECONFIG to the file.
+func renderKubeConfigExport(filePath string) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf("$env:KUBECONFIG = '%s'", filePath)
+	}
+	return fmt.Sprintf("export KUBECONFIG='%s'", filePath)
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Copy game server ConfigMaps from one environment to another.
+type envCopyConfigOpts struct {
+	UsePositionalArgs
+
+	argSourceEnvironment string
+	argTargetEnvironment string
+	flagConfigMap        string
+	flagDryRun           bool
+	flagYes              bool
+	flagForceConflicts   bool
+}
+
+// Pending copy of a single ConfigMap, with the diff against the target's current contents.
+type configMapCopy struct {
+	source   *corev1.ConfigMap
+	diff     []string // Rendered diff lines, empty if the contents are already equal.
+	isNew    bool     // Does the ConfigMap not exist in the target yet?
+	keptKeys []string // Keys only in the target, which are left as-is.
+}
+
+func init() {
+	o := envCopyConfigOpts{}
+
+	args := o.Arguments()
+	args.AddStringArgument(&o.argSourceEnvironment, "SOURCE_ENV", "Environment to copy the configuration from, eg, 'tough-falcons'.")
+	args.AddStringArgument(&o.argTargetEnvironment, "TARGET_ENV", "Environment to copy the configuration to, eg, 'lovely-wombats'.")
+
+	cmd := &cobra.Command{
+		Use:               "copy-config SOURCE_ENV TARGET_ENV [flags]",
+		Short:             "Copy game server ConfigMaps from one environment to another",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Copy the game server ConfigMaps from one environment to another, eg, to promote
+			configuration changes from a development environment to staging.
+
+			By default, all the game server ConfigMaps of the source environment are copied.
+			Use --configmap to copy only the named ConfigMap.
+
+			The changes are shown as a diff against the target environment's current contents
+			and confirmed before applying them, unless --yes is specified. In non-interactive
+			mode, --yes is required. Use --dry-run to only show the diff.
+
+			Only the contents (data and binary data) of the ConfigMaps are copied: the metadata,
+			including the auto-generated fields like creationTimestamp and resourceVersion,
+			is not. The changes are applied with Kubernetes server-side apply. Keys that only
+			exist in the target are left as-is. If the changed fields are managed by another
+			tool (eg, Helm), applying fails with a conflict unless --force-conflicts is given.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay restart server ...' to restart the game server to take the changes into use.
+		`),
+		Example: trimIndent(`
+			# Show what would be copied from environment tough-falcons to lovely-wombats.
+			metaplay env copy-config tough-falcons lovely-wombats --dry-run
+
+			# Copy a single ConfigMap without confirmation.
+			metaplay env copy-config tough-falcons lovely-wombats --configmap=gameserver-config --yes
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagConfigMap, "configmap", "", "Name of the ConfigMap to copy (defaults to all game server ConfigMaps)")
+	flags.BoolVar(&o.flagDryRun, "dry-run", false, "Only show the changes, don't apply them")
+	flags.BoolVarP(&o.flagYes, "yes", "y", false, "Apply the changes without asking for confirmation")
+	flags.BoolVar(&o.flagForceConflicts, "force-conflicts", false, "Take over the ownership of fields managed by other tools (eg, Helm) when applying")
+}
+
+func (o *envCopyConfigOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.argSourceEnvironment == o.argTargetEnvironment {
+		return exitcode.Errorf(exitcode.ExitUsage, "SOURCE_ENV and TARGET_ENV must be different environments")
+	}
+	if !o.flagYes && !o.flagDryRun && !tui.IsInteractive() {
+		return exitcode.Errorf(exitcode.ExitUsage, "in non-interactive mode, --yes (or --dry-run) must be specified")
+	}
+	return nil
+}
+
+func (o *envCopyConfigOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Try to resolve the project & auth provider.
+	project, err := tryResolveProject()
+	if err != nil {
+		return err
+	}
+
+	// Resolve the source and target environments.
+	sourceEnvConfig, sourceEnv, err := resolveTargetEnvironment(cmd, project, o.argSourceEnvironment)
+	if err != nil {
+		return err
+	}
+	targetEnvConfig, targetEnv, err := resolveTargetEnvironment(cmd, project, o.argTargetEnvironment)
+	if err != nil {
+		return err
+	}
+
+	// Fetch the ConfigMaps to copy from the source environment.
+	var sourceConfigMaps []corev1.ConfigMap
+	if o.flagConfigMap != "" {
+		configMap, err := sourceEnv.GetConfigMap(cmd.Context(), o.flagConfigMap)
+		if err != nil {
+			return err
+		}
+		if configMap == nil {
+			return exitcode.Errorf(exitcode.ExitNotFound, "ConfigMap %s not found in environment %s", o.flagConfigMap, sourceEnvConfig.HumanID)
+		}
+		sourceConfigMaps = []corev1.ConfigMap{*configMap}
+	} else {
+		sourceConfigMaps, err = sourceEnv.ListGameServerConfigMaps(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(sourceConfigMaps) == 0 {
+			return exitcode.Errorf(exitcode.ExitNotFound, "no game server ConfigMaps found in environment %s", sourceEnvConfig.HumanID)
+		}
+	}
+
+	// Compare against the target environment's current contents.
+	copies := []configMapCopy{}
+	numChanged := 0
+	for ndx := range sourceConfigMaps {
+		source := &sourceConfigMaps[ndx]
+		target, err := targetEnv.GetConfigMap(cmd.Context(), source.Name)
+		if err != nil {
+			return err
+		}
+		cmCopy := diffConfigMaps(source, target)
+		if len(cmCopy.diff) > 0 {
+			numChanged++
+		}
+		copies = append(copies, cmCopy)
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Copy Configuration Between Environments"))
+	log.Info().Msg("")
+	log.Info().Msgf("Source environment: %s", styles.RenderTechnical(sourceEnvConfig.HumanID))
+	log.Info().Msgf("Target environment: %s", styles.RenderTechnical(targetEnvConfig.HumanID))
+	log.Info().Msg("")
+	for _, cmCopy := range copies {
+		switch {
+		case cmCopy.isNew:
+			log.Info().Msgf("ConfigMap %s %s", styles.RenderBright(cmCopy.source.Name), styles.RenderSuccess("[new]"))
+		case len(cmCopy.diff) == 0:
+			log.Info().Msgf("ConfigMap %s %s", styles.RenderBright(cmCopy.source.Name), styles.RenderMuted("[unchanged]"))
+		default:
+			log.Info().Msgf("ConfigMap %s %s", styles.RenderBright(cmCopy.source.Name), styles.RenderWarning("[changed]"))
+		}
+		for _, line := range cmCopy.diff {
+			log.Info().Msgf("  %s", line)
+		}
+		for _, key := range cmCopy.keptKeys {
+			log.Info().Msgf("  %s", styles.RenderMuted(fmt.Sprintf("= %s (only in %s, kept)", key, targetEnvConfig.HumanID)))
+		}
+	}
+	log.Info().Msg("")
+
+	if numChanged == 0 {
+		log.Info().Msgf("The configuration in environment %s is already up to date", targetEnvConfig.HumanID)
+		return nil
+	}
+	if o.flagDryRun {
+		log.Info().Msgf("Dry run: %d ConfigMap(s) would be updated in environment %s", numChanged, targetEnvConfig.HumanID)
+		return nil
+	}
+
+	// Ask for confirmation.
+	if !o.flagYes {
+		confirmed, err := tui.DoConfirmQuestion(cmd.Context(), fmt.Sprintf("Apply the changes to %d ConfigMap(s) in environment %s?", numChanged, targetEnvConfig.HumanID))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			log.Info().Msg("Cancelled")
+			return nil
+		}
+	}
+
+	// Apply the changed ConfigMaps.
+	for _, cmCopy := range copies {
+		if len(cmCopy.diff) == 0 {
+			continue
+		}
+		err := targetEnv.ApplyConfigMapContents(cmd.Context(), cmCopy.source.Name, cmCopy.source.Data, cmCopy.source.BinaryData, o.flagForceConflicts)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("Applied ConfigMap %s", styles.RenderTechnical(cmCopy.source.Name))
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Configuration copied to environment %s!", targetEnvConfig.HumanID)))
+	log.Info().Msgf("The game server may need to be restarted to take the changes into use: %s", styles.RenderTechnical(fmt.Sprintf("metaplay restart server %s", targetEnvConfig.HumanID)))
+	return nil
+}
+
+// Resolve the environment config and target environment for an environment argument.
+func resolveTargetEnvironment(cmd *cobra.Command, project *metaproj.MetaplayProject, environment string) (*metaproj.ProjectEnvironmentConfig, *envapi.TargetEnvironment, error) {
+	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, environment)
+	if err != nil {
+		return nil, nil, err
+	}
+	return envConfig, envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID), nil
+}
+
+// Compare the contents of the source ConfigMap against the target (nil if it doesn't exist).
+func diffConfigMaps(source, target *corev1.ConfigMap) configMapCopy {
+	result := configMapCopy{source: source, isNew: target == nil}
+	if target == nil {
+		target = &corev1.ConfigMap{}
+	}
+
+	// Data is diffed line by line.
+	for _, key := range slices.Sorted(maps.Keys(source.Data)) {
+		newValue := source.Data[key]
+		oldValue, found := target.Data[key]
+		if !found {
+			result.diff = append(result.diff, styles.RenderSuccess(fmt.Sprintf("+ %s (new key)", key)))
+		} else if oldValue != newValue {
+			result.diff = append(result.diff, styles.RenderWarning(fmt.Sprintf("~ %s:", key)))
+			for _, line := range diffLines(oldValue, newValue) {
+				result.diff = append(result.diff, "    "+line)
+			}
+		}
+	}
+
+	// Binary data is only compared as a whole.
+	for _, key := range slices.Sorted(maps.Keys(source.BinaryData)) {
+		newValue := source.BinaryData[key]
+		oldValue, found := target.BinaryData[key]
+		if !found {
+			result.diff = append(result.diff, styles.RenderSuccess(fmt.Sprintf("+ %s (new key, %d bytes of binary data)", key, len(newValue))))
+		} else if !bytes.Equal(oldValue, newValue) {
+			result.diff = append(result.diff, styles.RenderWarning(fmt.Sprintf("~ %s: binary data changed (%d bytes -> %d bytes)", key, len(oldValue), len(newValue))))
+		}
+	}
+
+	// A new ConfigMap is created even if it is empty.
+	if result.isNew && len(result.diff) == 0 {
+		result.diff = append(result.diff, styles.RenderSuccess("+ (empty ConfigMap)"))
+	}
+
+	// Keys only in the target are not removed.
+	for _, key := range slices.Sorted(maps.Keys(target.Data)) {
+		if _, found := source.Data[key]; !found {
+			result.keptKeys = append(result.keptKeys, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(target.BinaryData)) {
+		if _, found := source.BinaryData[key]; !found {
+			result.keptKeys = append(result.keptKeys, key)
+		}
+	}
+
+	return result
+}
+
+// Render the changed lines between the old and new text, based on their longest common
+// subsequence. Unchanged lines are omitted.
+func diffLines(oldText, newText string) []string {
+	oldLines := strings.Split(oldText, "\n")
+	newLines := strings.Split(newText, "\n")
+
+	// Compute the lengths of the longest common subsequences of the suffixes.
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table to emit the removed and added lines.
+	result := []string{}
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			i++
+			j++
+		case j < len(newLines) && (i == len(oldLines) || lcs[i][j+1] >= lcs[i+1][j]):
+			result = append(result, styles.RenderSuccess("+ "+newLines[j]))
+			j++
+		default:
+			result = append(result, styles.RenderError("- "+oldLines[i]))
+			i++
+		}
+	}
+	return result
+}
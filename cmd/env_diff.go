@@ -0,0 +1,304 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Compare the Helm values of the deployed game server against the values the next deploy would use.
+type envDiffOpts struct {
+	UsePositionalArgs
+	RequiresProject
+	RequiresEnvironment
+
+	argImageTag             string
+	flagHelmReleaseName     string
+	flagHelmChartLocalPath  string
+	flagHelmChartRepository string
+	flagHelmChartVersion    string
+	flagHelmValuesPath      string
+	flagValuesFromEnv       bool
+}
+
+func init() {
+	o := envDiffOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+	args.AddStringArgumentOpt(&o.argImageTag, "TAG", "Image tag that the next deploy would use, eg, '364cff09' (defaults to the deployed image).")
+
+	cmd := &cobra.Command{
+		Use:               "diff ENVIRONMENT [TAG] [flags]",
+		Short:             "Show how the next game server deploy would change the Helm values",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Compare the Helm values of the game server deployed in the environment against the
+			values that 'metaplay deploy server' would use, and show the differences as a unified
+			diff. Similar to 'helm diff', this helps to avoid surprise changes when re-deploying.
+
+			The values are compared including the chart's default values, so changes caused by
+			upgrading the Helm chart version are also shown. The desired values are resolved like
+			in 'metaplay deploy server': the CLI's defaults, the environment's values files, and
+			the values file given with --values, on top of the chart's defaults.
+
+			If TAG is given, the desired values use that image tag (and the Metaplay SDK version
+			from the image in the environment's registry), otherwise the deployed image is kept.
+
+			The command exits with code 1 when there are differences, eg, for gating in CI.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay deploy server ...' to deploy the game server.
+		`),
+		Example: trimIndent(`
+			# Show what would change when re-deploying the current image into tough-falcons.
+			metaplay env diff tough-falcons
+
+			# Show what would change when deploying a new image.
+			metaplay env diff tough-falcons 364cff09
+
+			# Include an extra values file, as with 'deploy server --values'.
+			metaplay env diff tough-falcons --values=my-overrides.yaml
+
+			# Show the changes from upgrading to a new Helm chart version.
+			metaplay env diff tough-falcons --helm-chart-version=0.8.0
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagHelmReleaseName, "release-name", "", "Helm release to compare against (defaults to the only game server release)")
+	flags.StringVar(&o.flagHelmChartLocalPath, "local-chart-path", "", "Path to a local version of the metaplay-gameserver chart (repository and version are ignored if this is set)")
+	flags.StringVar(&o.flagHelmChartRepository, "helm-chart-repo", "", "Override for Helm chart repository to use for the metaplay-gameserver chart")
+	flags.StringVar(&o.flagHelmChartVersion, "helm-chart-version", "", "Override for Helm chart version to use, eg, '0.7.0'")
+	flags.StringVarP(&o.flagHelmValuesPath, "values", "f", "", "Path to an extra Helm values file, applied on top of the environment's values files")
+	flags.BoolVar(&o.flagValuesFromEnv, "values-from-env", true, "Apply the environment's values file found with the project's 'serverValuesFilePattern' (if it exists)")
+}
+
+func (o *envDiffOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.argImageTag != "" {
+		if strings.Contains(o.argImageTag, ":") {
+			return fmt.Errorf("only specify the image tag, eg, '364cff09', not the full image name")
+		}
+		if err := checkImageTagNotLatest(o.argImageTag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *envDiffOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	project := cmdCtx.Project
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Get kubeconfig to access the environment.
+	kubeconfigPayload, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
+	if err != nil {
+		return err
+	}
+
+	// Configure Helm.
+	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, envConfig.GetKubernetesNamespace())
+	if err != nil {
+		return fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+
+	// Resolve the deployed release to compare against.
+	releases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), metaplayGameServerChartName)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		return exitcode.Errorf(exitcode.ExitNotFound, "no game server deployed in environment %s", envConfig.HumanID)
+	}
+	deployedRelease, err := selectGameServerRelease(releases, o.flagHelmReleaseName)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the image that the next deploy would use.
+	imageTag, sdkVersion, err := o.resolveDesiredImage(targetEnv, deployedRelease)
+	if err != nil {
+		return err
+	}
+
+	hasDiff, err := o.printValuesDiff(project, envConfig, actionConfig, deployedRelease, imageTag, sdkVersion)
+	if err != nil {
+		return err
+	}
+
+	// Signal the differences with the exit code, the diff itself has been printed already.
+	if hasDiff {
+		return exitcode.Silent(exitcode.ExitError)
+	}
+	return nil
+}
+
+// Print the differences between the Helm values of the deployed release and the values that
+// deploying the image would use. Returns whether there are differences.
+func (o *envDiffOpts) printValuesDiff(project *metaproj.MetaplayProject, envConfig *metaproj.ProjectEnvironmentConfig, actionConfig *action.Configuration, deployedRelease *release.Release, imageTag string, sdkVersion string) (bool, error) {
+	// Resolve the desired values, like 'deploy server' does.
+	valuesFiles, err := resolveServerValuesFiles(project, envConfig, o.flagValuesFromEnv, o.flagHelmValuesPath)
+	if err != nil {
+		return false, err
+	}
+	helmValues := newGameServerHelmValues(envConfig, imageTag, sdkVersion)
+	if o.argImageTag == "" {
+		// Keep the deployed image as-is, including its repository (if set).
+		if deployedImage, ok := deployedRelease.Config["image"].(map[string]interface{}); ok {
+			helmValues["image"] = maps.Clone(deployedImage)
+		}
+	}
+	desiredUserValues, err := helmutil.ResolveValues(valuesFiles, helmValues, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// Load the chart that the next deploy would use.
+	helmChartPath, useHelmChartVersion, err := resolveGameServerHelmChart(project, o.flagHelmChartLocalPath, o.flagHelmChartRepository, o.flagHelmChartVersion)
+	if err != nil {
+		return false, err
+	}
+	desiredChart, err := helmutil.LoadChart(helmChartPath, useHelmChartVersion)
+	if err != nil {
+		return false, err
+	}
+
+	// Compute the effective values, including the chart defaults, on both sides.
+	deployedValues, err := helmutil.GetReleaseValues(actionConfig, deployedRelease.Name)
+	if err != nil {
+		return false, err
+	}
+	desiredValues, err := helmutil.ComputeReleaseValues(desiredChart, desiredUserValues)
+	if err != nil {
+		return false, err
+	}
+	diff, err := diffHelmValues(deployedRelease.Name, deployedValues, desiredValues)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Game Server Values Diff"))
+	log.Info().Msg("")
+	log.Info().Msgf("Environment:          %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("Helm release name:    %s", styles.RenderTechnical(deployedRelease.Name))
+	log.Info().Msgf("Helm chart version:   %s", renderValueChange(deployedRelease.Chart.Metadata.Version, desiredChart.Metadata.Version))
+	log.Info().Msgf("Image tag:            %s", renderValueChange(helmutil.GetReleaseImageTag(deployedRelease), imageTag))
+	if len(valuesFiles) > 0 {
+		log.Info().Msgf("Helm values files:    %s", styles.RenderTechnical(strings.Join(valuesFiles, ", ")))
+	}
+	log.Info().Msg("")
+
+	if diff == "" {
+		log.Info().Msgf("✅ %s", styles.RenderSuccess("No differences in the Helm values"))
+		return false, nil
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		log.Info().Msg(renderDiffLine(line))
+	}
+	log.Info().Msg("")
+	return true, nil
+}
+
+// Resolve the image tag and Metaplay SDK version that the next deploy would use: either the
+// deployed image, or the image with the given tag in the environment's registry.
+func (o *envDiffOpts) resolveDesiredImage(targetEnv *envapi.TargetEnvironment, deployedRelease *release.Release) (string, string, error) {
+	if o.argImageTag == "" {
+		return helmutil.GetReleaseImageTag(deployedRelease), getReleaseSdkVersion(deployedRelease), nil
+	}
+
+	sdkVersion, err := resolveRegistryImageSdkVersion(targetEnv, o.argImageTag)
+	if err != nil {
+		return "", "", err
+	}
+	return o.argImageTag, sdkVersion, nil
+}
+
+// Fetch the Metaplay SDK version of the image with the given tag from the image's labels in
+// the environment's registry.
+func resolveRegistryImageSdkVersion(targetEnv *envapi.TargetEnvironment, imageTag string) (string, error) {
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return "", err
+	}
+	dockerCredentials, err := targetEnv.GetDockerCredentials(envDetails)
+	if err != nil {
+		return "", fmt.Errorf("failed to get docker credentials: %v", err)
+	}
+	remoteImageName := fmt.Sprintf("%s:%s", envDetails.Deployment.EcrRepo, imageTag)
+	exists, err := envapi.RemoteDockerImageExists(dockerCredentials, remoteImageName)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", exitcode.Errorf(exitcode.ExitNotFound, "image %s not found in the environment's registry", remoteImageName)
+	}
+	imageConfig, err := envapi.FetchRemoteDockerImageMetadata(dockerCredentials, remoteImageName)
+	if err != nil {
+		return "", err
+	}
+	sdkVersion, found := imageConfig.Config.Labels["io.metaplay.sdk_version"]
+	if !found {
+		return "", fmt.Errorf("invalid docker image: required label 'io.metaplay.sdk_version' not found in the image metadata")
+	}
+	return sdkVersion, nil
+}
+
+// Render the deployed and desired Helm values as YAML and return their unified diff, or an
+// empty string if they are equal.
+func diffHelmValues(releaseName string, deployedValues map[string]interface{}, desiredValues map[string]interface{}) (string, error) {
+	deployedYAML, err := yaml.Marshal(deployedValues)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal deployed Helm values: %w", err)
+	}
+	desiredYAML, err := yaml.Marshal(desiredValues)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal desired Helm values: %w", err)
+	}
+	return udiff.Unified(fmt.Sprintf("deployed/%s", releaseName), fmt.Sprintf("desired/%s", releaseName), string(deployedYAML), string(desiredYAML)), nil
+}
+
+// Render a line of a unified diff color-coded.
+func renderDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return styles.RenderBright(line)
+	case strings.HasPrefix(line, "@@"):
+		return styles.RenderMuted(line)
+	case strings.HasPrefix(line, "+"):
+		return styles.RenderSuccess(line)
+	case strings.HasPrefix(line, "-"):
+		return styles.RenderError(line)
+	default:
+		return line
+	}
+}
+
+// Render a value that may change, eg, '0.7.0 -> 0.8.0'.
+func renderValueChange(deployed string, desired string) string {
+	if deployed == desired {
+		return styles.RenderTechnical(deployed)
+	}
+	return fmt.Sprintf("%s -> %s", styles.RenderTechnical(deployed), styles.RenderAttention(desired))
+}
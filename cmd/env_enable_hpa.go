@@ -0,0 +1,117 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Restore the HorizontalPodAutoscaler frozen by 'env set-replicas --disable-hpa'.
+type envEnableHPAOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagShardSet    string
+	flagLockTimeout time.Duration
+}
+
+func init() {
+	o := envEnableHPAOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "enable-hpa ENVIRONMENT [flags]",
+		Short:             "Restore the game server's HorizontalPodAutoscaler frozen by 'env set-replicas'",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Restore the HorizontalPodAutoscaler (HPA) of the game server in the target environment
+			after it has been frozen with 'metaplay env set-replicas --disable-hpa'. The original
+			minimum and maximum replicas saved by 'env set-replicas' are restored, and the HPA
+			resumes scaling the game server.
+
+			If the game server has multiple shard sets, the shard set must be specified with
+			--shard-set.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay env set-replicas ...' to set the replicas and freeze the HPA.
+			- 'metaplay get pods ...' to see the state of the pods.
+		`),
+		Example: trimIndent(`
+			# Restore the HPA of the game server in environment tough-falcons.
+			metaplay env enable-hpa tough-falcons
+
+			# Restore the HPA of a specific shard set.
+			metaplay env enable-hpa tough-falcons --shard-set service
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagShardSet, "shard-set", "", "Name of the shard set whose autoscaler to restore (required if the game server has multiple shard sets)")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+}
+
+func (o *envEnableHPAOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *envEnableHPAOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Resolve the game server, the shard set, and its autoscaler.
+	gameServer, err := targetEnv.GetGameServer(cmd.Context())
+	if err != nil {
+		return err
+	}
+	shardSetName, err := resolveShardSetName(gameServer, o.flagShardSet)
+	if err != nil {
+		return err
+	}
+	autoscaler, err := gameServer.GetShardSetAutoscaler(cmd.Context(), shardSetName)
+	if err != nil {
+		return err
+	}
+	if autoscaler == nil {
+		return exitcode.Errorf(exitcode.ExitNotFound, "shard set '%s' in environment %s has no HorizontalPodAutoscaler", shardSetName, envConfig.HumanID)
+	}
+	if !autoscaler.IsFrozen {
+		log.Info().Msgf("Autoscaler %s is not frozen %s, nothing to do", styles.RenderTechnical(autoscaler.Name), styles.RenderMuted(renderAutoscalerBounds(autoscaler)))
+		return nil
+	}
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "enable hpa", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	var restored *envapi.ShardSetAutoscaler
+	taskRunner := tui.NewTaskRunner()
+	taskRunner.AddTask(fmt.Sprintf("Restore autoscaler %s", autoscaler.Name), func(output *tui.TaskOutput) error {
+		restored, err = gameServer.RestoreShardSetAutoscaler(cmd.Context(), shardSetName)
+		return err
+	})
+	if err := taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Autoscaler %s restored", restored.Name)))
+	log.Info().Msgf("  Replicas:           %s", styles.RenderTechnical(fmt.Sprintf("%d-%d", restored.MinReplicas, restored.MaxReplicas)))
+	return nil
+}
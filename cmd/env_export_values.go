@@ -0,0 +1,188 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Export the Helm values of the deployed game server into a values file.
+type envExportValuesOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagOutput          string
+	flagHelmReleaseName string
+}
+
+func init() {
+	o := envExportValuesOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "export-values ENVIRONMENT --output=FILE [flags]",
+		Short:             "Export the Helm values of the deployed game server into a file",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Export the user-supplied Helm values of the game server deployed in the environment
+			into a YAML file, eg, to reproduce a manual deploy that used custom values. The
+			exported file can be passed to 'metaplay deploy server --values=FILE'.
+
+			Only the values given when deploying are exported, not the chart's default values.
+			The image tag and the Metaplay SDK version are omitted, as they are set on each deploy
+			from the deployed image: the exported image tag is noted in a comment at the top of
+			the file instead.
+
+			Values whose keys look like credentials (eg, 'password', 'apiKey', or 'token') are
+			replaced with empty strings (or, for lists and maps, emptied entirely) and marked
+			with a comment. Fill them in manually before using the file.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay deploy server ENVIRONMENT TAG --values=FILE' to deploy with the exported values.
+			- 'metaplay env import-values ENVIRONMENT --from=FILE' to apply the exported values.
+			- 'metaplay env diff ENVIRONMENT' to compare the deployed values against the next deploy.
+		`),
+		Example: trimIndent(`
+			# Export the values of the game server in tough-falcons into values.yaml.
+			metaplay env export-values tough-falcons --output=values.yaml
+
+			# Print the values to stdout.
+			metaplay env export-values tough-falcons --output=-
+
+			# Re-deploy the same image with the exported values.
+			metaplay deploy server tough-falcons 364cff09 --values=values.yaml
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVarP(&o.flagOutput, "output", "o", "", "Path of the values file to write, or '-' for stdout (required)")
+	flags.StringVar(&o.flagHelmReleaseName, "release-name", "", "Helm release to export the values of (defaults to the only game server release)")
+}
+
+func (o *envExportValuesOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagOutput == "" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--output is required, eg, --output=values.yaml")
+	}
+	return nil
+}
+
+func (o *envExportValuesOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Get kubeconfig to access the environment.
+	kubeconfigPayload, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
+	if err != nil {
+		return err
+	}
+
+	// Configure Helm.
+	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, envConfig.GetKubernetesNamespace())
+	if err != nil {
+		return fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+
+	// Resolve the deployed release to export, of the chart assigned by the StackAPI.
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+	releases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), resolveGameServerChartName(envDetails))
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		return exitcode.Errorf(exitcode.ExitNotFound, "no game server deployed in environment %s", envConfig.HumanID)
+	}
+	deployedRelease, err := selectGameServerRelease(releases, o.flagHelmReleaseName)
+	if err != nil {
+		return err
+	}
+
+	values, err := helmutil.GetReleaseUserValues(actionConfig, deployedRelease.Name)
+	if err != nil {
+		return err
+	}
+	imageTag := helmutil.GetReleaseImageTag(deployedRelease)
+	omitDeployManagedValues(values)
+
+	valuesYAML, redacted, err := helmutil.MarshalValuesRedacted(values)
+	if err != nil {
+		return err
+	}
+	output := renderExportedValuesHeader(envConfig.HumanID, deployedRelease, imageTag, redacted) + string(valuesYAML)
+
+	if o.flagOutput == "-" {
+		fmt.Print(output)
+	} else {
+		if err := os.WriteFile(o.flagOutput, []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to write values file: %w", err)
+		}
+		log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Helm values exported to"), styles.RenderTechnical(o.flagOutput))
+		log.Info().Msgf("  Helm release: %s (revision %d)", styles.RenderTechnical(deployedRelease.Name), deployedRelease.Version)
+		log.Info().Msgf("  Image tag:    %s", styles.RenderTechnical(imageTag))
+	}
+
+	// Remind about the values that need to be filled in.
+	if len(redacted) > 0 {
+		stderrLogger.Warn().Msgf("%s %d sensitive-looking values were redacted and must be filled in manually: %s", styles.RenderWarning("⚠️"), len(redacted), strings.Join(redacted, ", "))
+	}
+	return nil
+}
+
+// Paths of the Helm values that 'metaplay deploy server' sets from the deployed image on each
+// deploy.
+var deployManagedValuePaths = [][]string{{"image", "tag"}, {"sdk", "version"}}
+
+// Remove the values that 'metaplay deploy server' sets from the deployed image on each deploy,
+// so that the exported values don't override them. Empty parent maps are removed too.
+func omitDeployManagedValues(values map[string]interface{}) {
+	for _, path := range deployManagedValuePaths {
+		parent, ok := values[path[0]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(parent, path[1])
+		if len(parent) == 0 {
+			delete(values, path[0])
+		}
+	}
+}
+
+// Render the comment header of an exported values file, describing where the values came from.
+func renderExportedValuesHeader(environment string, rel *release.Release, imageTag string, redacted []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Helm values of game server release '%s' in environment '%s'.\n", rel.Name, environment)
+	fmt.Fprintf(&sb, "# Exported with 'metaplay env export-values' at %s.\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "# Chart: %s %s, revision: %d\n", rel.Chart.Metadata.Name, rel.Chart.Metadata.Version, rel.Version)
+	fmt.Fprintf(&sb, "# Image tag: %s\n", imageTag)
+	sb.WriteString("#\n")
+	sb.WriteString("# The image tag and SDK version are set when deploying, re-deploy the same image with:\n")
+	fmt.Fprintf(&sb, "#   metaplay deploy server %s %s --values=<this file>\n", environment, imageTag)
+	if len(redacted) > 0 {
+		sb.WriteString("#\n")
+		sb.WriteString("# Sensitive-looking values have been redacted, fill them in manually before deploying:\n")
+		for _, path := range redacted {
+			fmt.Fprintf(&sb, "# - %s\n", path)
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Port used by the game server for client connections when the environment doesn't report any.
+const defaultGameServerClientPort = 9339
+
+// Get the client-facing connection details of an environment.
+type envGetConnectionInfoOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagFormat          string
+	flagWriteUnityAsset string
+}
+
+// Connection details needed by the game client to connect to an environment.
+type clientConnectionInfo struct {
+	EnvironmentID     string `json:"environmentId"`     // Human ID of the environment, eg, 'tough-falcons'.
+	EnvironmentFamily string `json:"environmentFamily"` // Environment family, eg, 'Development' or 'Production'.
+	ServerHost        string `json:"serverHost"`        // Hostname of the game server.
+	ServerPorts       []int  `json:"serverPorts"`       // Ports the game server accepts client connections on.
+	EnableTLS         bool   `json:"enableTls"`         // Whether the client must use TLS when connecting.
+	CdnBaseURL        string `json:"cdnBaseUrl"`        // Base URL of the environment's CDN (game config and other assets).
+}
+
+func init() {
+	o := envGetConnectionInfoOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "get-connection-info ENVIRONMENT [flags]",
+		Aliases:           []string{"connection-info"},
+		Short:             "Get the connection details for game clients connecting to the environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Get the details that game clients need to connect to the target environment: the
+			game server hostname and ports, whether TLS is required, the CDN base URL, and the
+			environment family.
+
+			The output format is selected with --format:
+			- 'json' (default) outputs the details as a JSON object.
+			- 'unity' outputs the details as a JSON object with the field names used by the
+			  Metaplay Unity SDK, which can be loaded into a ScriptableObject with
+			  JsonUtility.FromJsonOverwrite().
+			- 'env' outputs the details as KEY=value lines, eg, to be sourced in shell scripts.
+
+			Use --write-unity-asset to write the details (in the 'unity' format) into a file in
+			the Unity project. If the file already exists, only the connection fields are
+			updated and all other fields in the file are preserved.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay get environment-info ...' to show the full details of the environment.
+		`),
+		Example: trimIndent(`
+			# Show the connection details of environment tough-falcons as JSON.
+			metaplay env get-connection-info tough-falcons
+
+			# Output the connection details in the Unity SDK's format.
+			metaplay env get-connection-info tough-falcons --format=unity
+
+			# Use the connection details in a shell script.
+			eval "$(metaplay env get-connection-info tough-falcons --format=env)"
+			echo "Connecting to $METAPLAY_SERVER_HOST"
+
+			# Write (or update) the connection config file in the Unity project.
+			metaplay env get-connection-info tough-falcons --write-unity-asset=MyGame/Assets/Resources/ConnectionConfig.json
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagFormat, "format", "json", "Output format: 'json', 'unity', or 'env'")
+	flags.StringVar(&o.flagWriteUnityAsset, "write-unity-asset", "", "Write or update the connection details in the given Unity connection config file")
+}
+
+func (o *envGetConnectionInfoOpts) Prepare(cmd *cobra.Command, args []string) error {
+	validFormats := []string{"json", "unity", "env"}
+	if !contains(validFormats, o.flagFormat) {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid format %q, must be one of %v", o.flagFormat, validFormats)
+	}
+	if o.flagWriteUnityAsset != "" && cmd.Flags().Changed("format") {
+		return exitcode.Errorf(exitcode.ExitUsage, "--format cannot be used with --write-unity-asset")
+	}
+	return nil
+}
+
+func (o *envGetConnectionInfoOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+
+	// Extract the client-facing details from the environment details.
+	envDetails, err := cmdCtx.TargetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+	if !envDetails.HasGameServerDeployment() {
+		return fmt.Errorf("environment %s is not set up for game server deployments (no server hostname configured)", envConfig.HumanID)
+	}
+	connInfo := resolveClientConnectionInfo(envConfig, envDetails)
+
+	// Write into the Unity project, if requested.
+	if o.flagWriteUnityAsset != "" {
+		if err := writeUnityConnectionAsset(o.flagWriteUnityAsset, connInfo); err != nil {
+			return err
+		}
+		log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Wrote connection details of environment "+envConfig.HumanID+" into"), styles.RenderTechnical(o.flagWriteUnityAsset))
+		return nil
+	}
+
+	// Output in the requested format (without decorations, for scripts).
+	switch o.flagFormat {
+	case "json":
+		return printJSON(connInfo)
+	case "unity":
+		return printJSON(connInfo.unityFields())
+	case "env":
+		for _, line := range connInfo.envLines() {
+			log.Info().Msg(line)
+		}
+	}
+	return nil
+}
+
+// Resolve the client connection details from the environment details.
+func resolveClientConnectionInfo(envConfig *metaproj.ProjectEnvironmentConfig, envDetails *envapi.DeploymentSecret) *clientConnectionInfo {
+	deployment := envDetails.Deployment
+
+	// The reported ports are occasionally empty, fall back to the default game server port.
+	serverPorts := deployment.ServerPorts
+	if len(serverPorts) == 0 {
+		serverPorts = []int{defaultGameServerClientPort}
+	}
+
+	cdnBaseURL := ""
+	if deployment.CdnS3Fqdn != "" {
+		cdnBaseURL = fmt.Sprintf("https://%s", deployment.CdnS3Fqdn)
+	}
+
+	return &clientConnectionInfo{
+		EnvironmentID:     envConfig.HumanID,
+		EnvironmentFamily: resolveEnvironmentFamily(envConfig),
+		ServerHost:        deployment.ServerHostname,
+		ServerPorts:       serverPorts,
+		// Game servers in cloud environments only accept TLS connections.
+		EnableTLS:  true,
+		CdnBaseURL: cdnBaseURL,
+	}
+}
+
+// Resolve the SDK's environment family (eg, 'Development') from the environment type.
+func resolveEnvironmentFamily(envConfig *metaproj.ProjectEnvironmentConfig) string {
+	envType := string(envConfig.Type)
+	if envType == "" {
+		return ""
+	}
+	return strings.ToUpper(envType[:1]) + envType[1:]
+}
+
+// Get the connection details with the field names used by the Unity SDK.
+func (info *clientConnectionInfo) unityFields() map[string]any {
+	return map[string]any{
+		"EnvironmentId":     info.EnvironmentID,
+		"EnvironmentFamily": info.EnvironmentFamily,
+		"ServerHost":        info.ServerHost,
+		"ServerPorts":       info.ServerPorts,
+		"EnableTls":         info.EnableTLS,
+		"CdnBaseUrl":        info.CdnBaseURL,
+	}
+}
+
+// Get the connection details as KEY=value lines.
+func (info *clientConnectionInfo) envLines() []string {
+	ports := make([]string, len(info.ServerPorts))
+	for ndx, port := range info.ServerPorts {
+		ports[ndx] = strconv.Itoa(port)
+	}
+	return []string{
+		fmt.Sprintf("METAPLAY_ENVIRONMENT_ID=%s", info.EnvironmentID),
+		fmt.Sprintf("METAPLAY_ENVIRONMENT_FAMILY=%s", info.EnvironmentFamily),
+		fmt.Sprintf("METAPLAY_SERVER_HOST=%s", info.ServerHost),
+		fmt.Sprintf("METAPLAY_SERVER_PORTS=%s", strings.Join(ports, ",")),
+		fmt.Sprintf("METAPLAY_ENABLE_TLS=%t", info.EnableTLS),
+		fmt.Sprintf("METAPLAY_CDN_BASE_URL=%s", info.CdnBaseURL),
+	}
+}
+
+// Write the connection details into the Unity connection config file. The fields of an
+// existing file that are not connection details are preserved.
+func writeUnityConnectionAsset(filePath string, info *clientConnectionInfo) error {
+	fields := map[string]any{}
+	existing, err := os.ReadFile(filePath)
+	if err == nil {
+		if err := json.Unmarshal(existing, &fields); err != nil {
+			return fmt.Errorf("failed to parse existing connection config %s (expecting a JSON object): %w", filePath, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read existing connection config %s: %w", filePath, err)
+	}
+
+	for key, value := range info.unityFields() {
+		fields[key] = value
+	}
+
+	content, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write connection config %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// Print the value as indented JSON.
+func printJSON(value any) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	log.Info().Msg(string(content))
+	return nil
+}
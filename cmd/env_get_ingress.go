@@ -0,0 +1,262 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/pkg/browser"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// List the public endpoints (Ingress rules) of the target environment.
+type envGetIngressOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagOutput string
+	flagOpen   bool
+}
+
+// Public endpoint of an environment, ie, an Ingress rule.
+type ingressEndpoint struct {
+	Ingress string `json:"ingress"`        // Name of the Ingress resource.
+	Host    string `json:"host"`           // Hostname of the rule, '*' for the default backend.
+	Path    string `json:"path"`           // Path of the rule.
+	Service string `json:"service"`        // Name of the backend service.
+	Port    string `json:"port"`           // Number or name of the backend service port.
+	TLS     bool   `json:"tls"`            // Is TLS configured for the host?
+	URL     string `json:"url"`            // Public URL of the endpoint.
+	Role    string `json:"role,omitempty"` // Role of the endpoint in the environment: 'dashboard', 'server', or empty if unknown.
+}
+
+// Output of 'env get-ingress --output=json'.
+type envIngressInfo struct {
+	ServerHostname          string            `json:"serverHostname"`          // Game server hostname from the environment details.
+	AdminHostname           string            `json:"adminHostname"`           // LiveOps Dashboard hostname from the environment details.
+	ServerHostnameInIngress bool              `json:"serverHostnameInIngress"` // Does an Ingress rule match the game server hostname?
+	AdminHostnameInIngress  bool              `json:"adminHostnameInIngress"`  // Does an Ingress rule match the LiveOps Dashboard hostname?
+	Endpoints               []ingressEndpoint `json:"endpoints"`
+}
+
+func init() {
+	o := envGetIngressOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "get-ingress ENVIRONMENT [flags]",
+		Short:             "List the public endpoints of the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			List the public endpoints of the target environment: the hostnames and paths of the
+			Kubernetes Ingress rules, and the services and ports they route to.
+
+			The game server and LiveOps Dashboard hostnames of the environment are checked against
+			the Ingress rules, and a warning is shown if no rule matches them.
+
+			Use --open to open the LiveOps Dashboard in the browser, like 'metaplay env open'.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay env open ...' to open the LiveOps Dashboard in the browser.
+			- 'metaplay get environment-info ...' to show the environment's details.
+		`),
+		Example: trimIndent(`
+			# List the public endpoints of environment tough-falcons.
+			metaplay env get-ingress tough-falcons
+
+			# Output the endpoints as JSON for scripting.
+			metaplay env get-ingress tough-falcons --output=json
+
+			# List the endpoints and open the LiveOps Dashboard in the browser.
+			metaplay env get-ingress tough-falcons --open
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagOutput, "output", "table", "Output format (table or json)")
+	flags.BoolVar(&o.flagOpen, "open", false, "Open the LiveOps Dashboard URL in the browser")
+}
+
+func (o *envGetIngressOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagOutput != "table" && o.flagOutput != "json" {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid output format %q; must be either \"table\" or \"json\"", o.flagOutput)
+	}
+	return nil
+}
+
+func (o *envGetIngressOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Get the expected hostnames from the environment details.
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+
+	// List the Ingresses in the environment's namespace.
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("List ingresses in namespace %s", kubeCli.Namespace)
+	ingresses, err := kubeCli.Clientset.NetworkingV1().Ingresses(kubeCli.Namespace).List(cmd.Context(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list ingresses: %w", err)
+	}
+
+	info := envIngressInfo{
+		ServerHostname: envDetails.Deployment.ServerHostname,
+		AdminHostname:  envDetails.Deployment.AdminHostname,
+		Endpoints:      []ingressEndpoint{},
+	}
+	for ndx := range ingresses.Items {
+		info.Endpoints = append(info.Endpoints, getIngressEndpoints(&ingresses.Items[ndx], info.ServerHostname, info.AdminHostname)...)
+	}
+	sort.SliceStable(info.Endpoints, func(i, j int) bool {
+		return info.Endpoints[i].Host < info.Endpoints[j].Host
+	})
+	for _, endpoint := range info.Endpoints {
+		info.ServerHostnameInIngress = info.ServerHostnameInIngress || (info.ServerHostname != "" && endpoint.Host == info.ServerHostname)
+		info.AdminHostnameInIngress = info.AdminHostnameInIngress || (info.AdminHostname != "" && endpoint.Host == info.AdminHostname)
+	}
+
+	// Output the endpoints in desired format.
+	if o.flagOutput == "json" {
+		infoJSON, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal ingress info as JSON: %v", err)
+		}
+		fmt.Println(string(infoJSON))
+	} else {
+		o.printEndpointsTable(envConfig.HumanID, &info)
+	}
+
+	// Open the first dashboard endpoint in the browser.
+	if o.flagOpen {
+		for _, endpoint := range info.Endpoints {
+			if endpoint.Role == "dashboard" {
+				log.Info().Msgf("Opening LiveOps Dashboard: %s", styles.RenderLink(endpoint.URL, endpoint.URL))
+				if err := browser.OpenURL(endpoint.URL); err != nil {
+					return fmt.Errorf("failed to open the browser, open the URL manually: %w", err)
+				}
+				return nil
+			}
+		}
+		return exitcode.Errorf(exitcode.ExitNotFound, "no Ingress rule for the LiveOps Dashboard found in environment %s", envConfig.HumanID)
+	}
+
+	return nil
+}
+
+// Print the endpoints as a table, with warnings about the hostnames not matching any rule.
+func (o *envGetIngressOpts) printEndpointsTable(envHumanID string, info *envIngressInfo) {
+	if len(info.Endpoints) == 0 {
+		log.Info().Msgf("No Ingress rules found in environment %s", envHumanID)
+	} else {
+		endpointsTable := table.New().
+			Border(lipgloss.NormalBorder()).
+			BorderStyle(styles.StyleMuted).
+			Headers("URL", "SERVICE", "PORT", "ROLE", "INGRESS").
+			StyleFunc(func(row, col int) lipgloss.Style {
+				style := lipgloss.NewStyle().Padding(0, 1)
+				if row == table.HeaderRow {
+					return style.Inherit(styles.StyleTitle)
+				}
+				return style
+			})
+		for _, endpoint := range info.Endpoints {
+			endpointsTable.Row(endpoint.URL, endpoint.Service, endpoint.Port, endpoint.Role, endpoint.Ingress)
+		}
+		log.Info().Msg(endpointsTable.String())
+	}
+
+	if info.ServerHostname != "" && !info.ServerHostnameInIngress {
+		log.Warn().Msgf("%s No Ingress rule matches the game server hostname %s", styles.RenderWarning("⚠️"), styles.RenderTechnical(info.ServerHostname))
+	}
+	if info.AdminHostname != "" && !info.AdminHostnameInIngress {
+		log.Warn().Msgf("%s No Ingress rule matches the LiveOps Dashboard hostname %s", styles.RenderWarning("⚠️"), styles.RenderTechnical(info.AdminHostname))
+	}
+}
+
+// Get the endpoints of the Ingress: one per rule path, and one for the default backend.
+func getIngressEndpoints(ingress *networkingv1.Ingress, serverHostname, adminHostname string) []ingressEndpoint {
+	tlsHosts := map[string]bool{}
+	for _, tls := range ingress.Spec.TLS {
+		for _, host := range tls.Hosts {
+			tlsHosts[host] = true
+		}
+	}
+
+	newEndpoint := func(host, path string, backend *networkingv1.IngressBackend) ingressEndpoint {
+		endpoint := ingressEndpoint{
+			Ingress: ingress.Name,
+			Host:    host,
+			Path:    path,
+			TLS:     tlsHosts[host],
+		}
+		if backend != nil && backend.Service != nil {
+			endpoint.Service = backend.Service.Name
+			if backend.Service.Port.Name != "" {
+				endpoint.Port = backend.Service.Port.Name
+			} else {
+				endpoint.Port = fmt.Sprintf("%d", backend.Service.Port.Number)
+			}
+		} else if backend != nil && backend.Resource != nil {
+			endpoint.Service = fmt.Sprintf("%s/%s", backend.Resource.Kind, backend.Resource.Name)
+		}
+
+		scheme := "http"
+		if endpoint.TLS {
+			scheme = "https"
+		}
+		endpoint.URL = fmt.Sprintf("%s://%s%s", scheme, host, path)
+
+		switch {
+		case host == adminHostname && adminHostname != "":
+			endpoint.Role = "dashboard"
+		case host == serverHostname && serverHostname != "":
+			endpoint.Role = "server"
+		}
+		return endpoint
+	}
+
+	endpoints := []ingressEndpoint{}
+	if ingress.Spec.DefaultBackend != nil {
+		endpoints = append(endpoints, newEndpoint("*", "/", ingress.Spec.DefaultBackend))
+	}
+	for _, rule := range ingress.Spec.Rules {
+		host := rule.Host
+		if host == "" {
+			host = "*"
+		}
+		if rule.HTTP == nil || len(rule.HTTP.Paths) == 0 {
+			endpoints = append(endpoints, newEndpoint(host, "/", nil))
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			pathStr := path.Path
+			if pathStr == "" {
+				pathStr = "/"
+			}
+			endpoints = append(endpoints, newEndpoint(host, pathStr, &path.Backend))
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,248 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Apply a Helm values file (eg, from 'env export-values') to the deployed game server.
+type envImportValuesOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagFrom            string
+	flagHelmReleaseName string
+	flagNoDiff          bool
+	flagWait            bool
+	flagTimeout         time.Duration
+	flagLockTimeout     time.Duration
+	flagOverridePolicy  bool
+}
+
+func init() {
+	o := envImportValuesOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "import-values ENVIRONMENT --from=FILE [flags]",
+		Short:             "Apply a Helm values file to the deployed game server",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Apply the Helm values from a YAML file to the game server deployed in the environment,
+			eg, a file written by 'metaplay env export-values'. The game server release is upgraded
+			with the same chart version, and its user-supplied values are replaced with the values
+			from the file.
+
+			The image tag and the Metaplay SDK version are kept from the deployed release, unless
+			the file sets them.
+
+			Before applying, the differences between the deployed values and the file are shown.
+			If there are no differences, nothing is done. Use --no-diff to skip the comparison and
+			always upgrade the release.
+
+			Values that were redacted when exporting (marked with the comment
+			'# REDACTED: fill in manually before deploying') must be filled in, and the comment
+			removed, before the file can be imported.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay env export-values ENVIRONMENT --output=FILE' to export the deployed values.
+			- 'metaplay env diff ENVIRONMENT' to compare the deployed values against the next deploy.
+			- 'metaplay deploy server ...' to deploy a new image.
+		`),
+		Example: trimIndent(`
+			# Apply the values from values.yaml to the game server in tough-falcons.
+			metaplay env import-values tough-falcons --from=values.yaml
+
+			# Copy the values of the game server in tough-falcons to lovely-wombats.
+			metaplay env export-values tough-falcons --output=values.yaml
+			metaplay env import-values lovely-wombats --from=values.yaml
+
+			# Read the values from stdin, don't wait for the game server to become ready.
+			cat values.yaml | metaplay env import-values tough-falcons --from=- --wait=false
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagFrom, "from", "", "Path of the Helm values file to apply, or '-' for stdin (required)")
+	flags.StringVar(&o.flagHelmReleaseName, "release-name", "", "Helm release to apply the values to (defaults to the only game server release)")
+	flags.BoolVar(&o.flagNoDiff, "no-diff", false, "Don't compare the values against the deployed ones, always upgrade the release")
+	flags.BoolVar(&o.flagWait, "wait", true, "Wait for the game server resources to become ready")
+	flags.DurationVar(&o.flagTimeout, "timeout", defaultHelmTimeout, "Timeout for the Helm operation")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+	flags.BoolVar(&o.flagOverridePolicy, "override-policy", false, "Override the project's environment policies (requires typed confirmation)")
+}
+
+func (o *envImportValuesOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFrom == "" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--from is required, eg, --from=values.yaml")
+	}
+	return nil
+}
+
+func (o *envImportValuesOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Read and validate the values file before touching the environment.
+	valuesYAML, err := o.readValuesFile()
+	if err != nil {
+		return err
+	}
+	redacted, err := helmutil.FindRedactedValues(valuesYAML)
+	if err != nil {
+		return exitcode.New(exitcode.ExitUsage, fmt.Errorf("invalid values file %s: %w", o.flagFrom, err))
+	}
+	if len(redacted) > 0 {
+		return exitcode.Errorf(exitcode.ExitUsage, "values file %s has %d redacted values that must be filled in first: %s", o.flagFrom, len(redacted), strings.Join(redacted, ", "))
+	}
+	newValues, err := chartutil.ReadValues(valuesYAML)
+	if err != nil {
+		return exitcode.New(exitcode.ExitUsage, fmt.Errorf("invalid values file %s: %w", o.flagFrom, err))
+	}
+
+	// Get kubeconfig to access the environment.
+	kubeconfigPayload, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
+	if err != nil {
+		return err
+	}
+
+	// Configure Helm.
+	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, envConfig.GetKubernetesNamespace())
+	if err != nil {
+		return fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+
+	// Resolve the deployed release to apply the values to, of the chart assigned by the StackAPI.
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+	releases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), resolveGameServerChartName(envDetails))
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		return exitcode.Errorf(exitcode.ExitNotFound, "no game server deployed in environment %s", envConfig.HumanID)
+	}
+	deployedRelease, err := selectGameServerRelease(releases, o.flagHelmReleaseName)
+	if err != nil {
+		return err
+	}
+	restoreDeployManagedValues(newValues, deployedRelease.Config)
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Import Game Server Values"))
+	log.Info().Msg("")
+	log.Info().Msgf("Environment:          %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("Helm release name:    %s %s", styles.RenderTechnical(deployedRelease.Name), styles.RenderMuted(fmt.Sprintf("[revision %d]", deployedRelease.Version)))
+	log.Info().Msgf("Helm chart version:   %s", styles.RenderTechnical(deployedRelease.Chart.Metadata.Version))
+	log.Info().Msgf("Image tag:            %s", styles.RenderTechnical(helmutil.GetReleaseImageTag(deployedRelease)))
+	log.Info().Msgf("Helm values file:     %s", styles.RenderTechnical(o.flagFrom))
+	log.Info().Msg("")
+
+	// Show the changes to the deployed values.
+	if !o.flagNoDiff {
+		deployedValues, err := helmutil.GetReleaseUserValues(actionConfig, deployedRelease.Name)
+		if err != nil {
+			return err
+		}
+		diff, err := diffHelmValues(deployedRelease.Name, deployedValues, newValues)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			log.Info().Msgf("✅ %s", styles.RenderSuccess("No differences in the Helm values, nothing to import"))
+			return nil
+		}
+		for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+			log.Info().Msg(renderDiffLine(line))
+		}
+		log.Info().Msg("")
+	}
+
+	// Changing the values rolls out the game server again, like 'deploy server'.
+	policyOverride, err := enforceEnvironmentPolicies(cmd.Context(), cmdCtx.Project, envConfig, cmdCtx.TokenSet, policyOperationDeployServer, o.flagOverridePolicy)
+	if err != nil {
+		return err
+	}
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "import values", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	taskRunner := tui.NewTaskRunner()
+	taskRunner.AddTask(fmt.Sprintf("Upgrade release %s with the imported values", deployedRelease.Name), func(output *tui.TaskOutput) error {
+		_, err := helmutil.UpgradeReleaseWithChart(output, actionConfig, envConfig.GetKubernetesNamespace(), deployedRelease.Name, deployedRelease.Chart, newValues, o.flagWait, o.flagTimeout, newCliReleaseDescription(cmdCtx.TokenSet, policyOverride), newCliReleaseLabels(cmdCtx.TokenSet))
+		return reportHelmTimeout(actionConfig, deployedRelease.Name, err)
+	})
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Imported Helm values into release"), styles.RenderTechnical(deployedRelease.Name))
+	return nil
+}
+
+// Read the contents of the values file, or stdin if the path is '-'.
+func (o *envImportValuesOpts) readValuesFile() ([]byte, error) {
+	var content []byte
+	var err error
+	if o.flagFrom == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(o.flagFrom)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+	return content, nil
+}
+
+// Fill in the values that 'metaplay deploy server' sets from the deployed image (see
+// omitDeployManagedValues) from the deployed release's values, unless they are set already.
+func restoreDeployManagedValues(values map[string]interface{}, deployedValues map[string]interface{}) {
+	for _, path := range deployManagedValuePaths {
+		deployedParent, ok := deployedValues[path[0]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		deployedValue, ok := deployedParent[path[1]]
+		if !ok {
+			continue
+		}
+		parent, ok := values[path[0]].(map[string]interface{})
+		if !ok {
+			if values[path[0]] != nil {
+				continue // Not a map, leave it for Helm to complain about.
+			}
+			parent = map[string]interface{}{}
+			values[path[0]] = parent
+		}
+		if _, ok := parent[path[1]]; !ok {
+			parent[path[1]] = deployedValue
+		}
+	}
+}
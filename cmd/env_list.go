@@ -0,0 +1,149 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/portalapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// List the environments configured in the project.
+type envListOpts struct {
+	UsePositionalArgs
+	RequiresProject
+
+	flagFormat  string
+	flagNoTrunc bool
+	flagColumns []string
+}
+
+// Environment as output by 'env list --format=json'.
+type envListEntry struct {
+	Name        string                    `json:"name"`
+	HumanID     string                    `json:"humanId"`
+	Type        portalapi.EnvironmentType `json:"type"`
+	StackDomain string                    `json:"stackDomain"`
+	Policies    []string                  `json:"policies"`
+}
+
+func init() {
+	o := envListOpts{}
+
+	cmd := &cobra.Command{
+		Use:     "list [flags]",
+		Aliases: []string{"ls"},
+		Short:   "List the environments configured in the project",
+		Run:     runCommand(&o),
+		Long: renderLong(&o, `
+			List the environments configured in the project's metaplay-project.yaml, along
+			with their types and the project policies that apply to them.
+
+			Environment types are color-coded: development is green, staging is yellow, and
+			production is red.
+
+			The table is fit into the terminal width by truncating and hiding the less
+			important columns; use --no-trunc to show everything, or --columns to choose the
+			columns. When the output is not a terminal, the full values are output as
+			tab-separated values, eg, for processing with awk or cut.
+
+			Related commands:
+			- 'metaplay update project-environments' to update the environments from the portal.
+			- 'metaplay get environment-info ...' to get information about an environment.
+		`),
+		Example: trimIndent(`
+			# List the project's environments.
+			metaplay env list
+
+			# List the project's environments in JSON format.
+			metaplay env list --format=json
+
+			# List only the IDs and types of the environments.
+			metaplay env list --columns=id,type
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format. Valid values are 'text' or 'json'")
+	flags.BoolVar(&o.flagNoTrunc, "no-trunc", false, "Don't truncate the values to fit the terminal")
+	flags.StringSliceVar(&o.flagColumns, "columns", nil, "Columns to show, eg, 'name,id,type' (valid columns: name, id, type, stack-domain, policies)")
+}
+
+func (o *envListOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q, must be either 'text' or 'json'", o.flagFormat)
+	}
+	return nil
+}
+
+func (o *envListOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	projectConfig := cmdCtx.Project.Config
+
+	// Resolve the environments along with their policies.
+	entries := make([]envListEntry, len(projectConfig.Environments))
+	for ndx := range projectConfig.Environments {
+		envConfig := &projectConfig.Environments[ndx]
+		policies := []string{}
+		for _, policy := range projectConfig.GetEnvironmentPolicies(envConfig) {
+			policies = append(policies, string(policy.Rule))
+		}
+		entries[ndx] = envListEntry{
+			Name:        envConfig.Name,
+			HumanID:     envConfig.HumanID,
+			Type:        envConfig.Type,
+			StackDomain: envConfig.StackDomain,
+			Policies:    policies,
+		}
+	}
+
+	if o.flagFormat == "json" {
+		entriesJSON, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal environments as JSON: %v", err)
+		}
+		log.Info().Msg(string(entriesJSON))
+		return nil
+	}
+
+	table := tui.NewTable(
+		tui.TableColumn{Key: "name", Title: "NAME", Priority: 4, MinWidth: 12, MaxWidth: 40, Style: styles.RenderBright},
+		tui.TableColumn{Key: "id", Title: "ID", Priority: 5, MinWidth: 16},
+		tui.TableColumn{Key: "type", Title: "TYPE", Priority: 3, MinWidth: 11, Style: func(value string) string {
+			return renderEnvironmentType(portalapi.EnvironmentType(value))
+		}},
+		tui.TableColumn{Key: "stack-domain", Title: "STACK DOMAIN", Priority: 2, MinWidth: 16, Style: styles.RenderTechnical},
+		tui.TableColumn{Key: "policies", Title: "POLICIES", Priority: 1, MinWidth: 12, Style: styles.RenderTechnical},
+	)
+	for _, entry := range entries {
+		table.AddRow(entry.Name, entry.HumanID, string(entry.Type), entry.StackDomain, strings.Join(entry.Policies, ", "))
+	}
+	tableOpts := tui.NewTableOptions(o.flagColumns, o.flagNoTrunc)
+	lines, err := table.Render(tableOpts)
+	if err != nil {
+		return exitcode.New(exitcode.ExitUsage, err)
+	}
+
+	// Only output the values when piped, so that they can be processed by other tools.
+	if !tableOpts.Plain {
+		log.Info().Msg("")
+		log.Info().Msg(styles.RenderTitle("Project Environments"))
+		log.Info().Msg("")
+		if len(entries) == 0 {
+			log.Info().Msg("No environments configured in the project")
+			return nil
+		}
+	}
+	for _, line := range lines {
+		log.Info().Msg(line)
+	}
+	return nil
+}
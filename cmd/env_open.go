@@ -0,0 +1,109 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/pkg/browser"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Open the LiveOps Dashboard of the target environment in a browser.
+type envOpenOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagBrowser string
+	flagURLOnly bool
+}
+
+func init() {
+	o := envOpenOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "open ENVIRONMENT [flags]",
+		Short:             "Open the LiveOps Dashboard of the target environment in a browser",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Open the LiveOps Dashboard of the target environment in the system's default
+			browser, or in the browser given with --browser.
+
+			The command fails if there is no game server running in the environment, as the
+			dashboard is served by the game server. Use --url-only to only print the URL.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay deploy server ...' to deploy a game server into the environment.
+		`),
+		Example: trimIndent(`
+			# Open the LiveOps Dashboard of environment tough-falcons.
+			metaplay env open tough-falcons
+
+			# Open the dashboard in a specific browser.
+			metaplay env open tough-falcons --browser=/usr/bin/firefox
+
+			# Only print the dashboard URL.
+			metaplay env open tough-falcons --url-only
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagBrowser, "browser", "", "Path to the browser executable to use (defaults to the system's default browser)")
+	flags.BoolVar(&o.flagURLOnly, "url-only", false, "Only print the dashboard URL, don't open it")
+}
+
+func (o *envOpenOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagURLOnly && o.flagBrowser != "" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--browser cannot be used with --url-only")
+	}
+	return nil
+}
+
+func (o *envOpenOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Resolve the dashboard URL from the environment details.
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+	if !envDetails.HasGameServerDeployment() {
+		return fmt.Errorf("environment %s is not set up for game server deployments (no admin hostname configured)", envConfig.HumanID)
+	}
+	dashboardURL := fmt.Sprintf("https://%s", envDetails.Deployment.AdminHostname)
+
+	// The dashboard is served by the game server, so check that one is running.
+	if _, err := targetEnv.GetGameServer(cmd.Context()); err != nil {
+		log.Debug().Msgf("Failed to get the game server: %v", err)
+		return exitcode.Errorf(exitcode.ExitNotFound, "no game server is running in environment %s, deploy one with 'metaplay deploy server %s'", envConfig.HumanID, envConfig.HumanID)
+	}
+
+	// With --url-only, only print the URL (without decorations, for scripts).
+	if o.flagURLOnly {
+		log.Info().Msg(dashboardURL)
+		return nil
+	}
+
+	log.Info().Msgf("Opening LiveOps Dashboard: %s", styles.RenderLink(dashboardURL, dashboardURL))
+	if o.flagBrowser != "" {
+		if err := exec.Command(o.flagBrowser, dashboardURL).Start(); err != nil {
+			return fmt.Errorf("failed to start browser %s: %w", o.flagBrowser, err)
+		}
+	} else if err := browser.OpenURL(dashboardURL); err != nil {
+		return fmt.Errorf("failed to open the browser, open the URL manually: %w", err)
+	}
+	return nil
+}
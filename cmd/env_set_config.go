@@ -0,0 +1,194 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Update runtime options of the game server in the target environment.
+type envSetConfigOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	extraArgs []string
+	flagYes   bool
+}
+
+// Single runtime option change, resolved against the environment's current options.
+type runtimeOptionChange struct {
+	option   *envapi.RuntimeOption // Current option (before the change).
+	newValue any                   // New value, parsed into the option's type.
+}
+
+func init() {
+	o := envSetConfigOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+	args.SetExtraArgs(&o.extraArgs, "Runtime options to set, as KEY=VALUE pairs, eg, 'Player:MaxNameLength=24'.")
+
+	cmd := &cobra.Command{
+		Use:               "set-config ENVIRONMENT KEY=VALUE [KEY=VALUE ...] [flags]",
+		Short:             "Update runtime options of the game server in the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Update one or more runtime options of the game server in the target environment.
+
+			The keys are the full runtime option keys, eg, 'Player:MaxNameLength'. The values
+			are validated against the types of the options, when the types are known. The
+			changes are shown as a before/after diff and confirmed before applying them, unless
+			--yes is specified. In non-interactive mode, --yes is required.
+
+			Some runtime options only take effect when the game server is restarted. If any
+			of those are changed, a hint on how to restart the game server is shown.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay restart server ...' to restart the game server.
+		`),
+		Example: trimIndent(`
+			# Set a single runtime option in environment tough-falcons.
+			metaplay env set-config tough-falcons Player:MaxNameLength=24
+
+			# Set multiple runtime options without confirmation.
+			metaplay env set-config tough-falcons System:EnableMaintenance=true Player:MaxNameLength=24 --yes
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&o.flagYes, "yes", "y", false, "Apply the changes without asking for confirmation")
+}
+
+func (o *envSetConfigOpts) Prepare(cmd *cobra.Command, args []string) error {
+	// The environment is optional in general, so catch the case where it was left out.
+	if strings.Contains(o.argEnvironment, "=") {
+		return exitcode.Errorf(exitcode.ExitUsage, "the ENVIRONMENT must be specified before the KEY=VALUE pairs")
+	}
+	if len(o.extraArgs) == 0 {
+		return exitcode.Errorf(exitcode.ExitUsage, "at least one KEY=VALUE pair must be specified")
+	}
+	for _, arg := range o.extraArgs {
+		if key, _, ok := strings.Cut(arg, "="); !ok || key == "" {
+			return exitcode.Errorf(exitcode.ExitUsage, "invalid argument %q, expecting KEY=VALUE", arg)
+		}
+	}
+	if !o.flagYes && !tui.IsInteractive() {
+		return fmt.Errorf("in non-interactive mode, --yes must be specified to update runtime options")
+	}
+	return nil
+}
+
+func (o *envSetConfigOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Fetch the current runtime options.
+	options, err := targetEnv.GetRuntimeOptions()
+	if err != nil {
+		return err
+	}
+
+	// Resolve and validate the changes against the current options.
+	changes := []runtimeOptionChange{}
+	newValues := map[string]any{}
+	for _, arg := range o.extraArgs {
+		key, valueStr, _ := strings.Cut(arg, "=")
+		option := envapi.FindRuntimeOption(options, key)
+		if option == nil {
+			return exitcode.Errorf(exitcode.ExitNotFound, "unknown runtime option %q", key)
+		}
+		if _, found := newValues[key]; found {
+			return exitcode.Errorf(exitcode.ExitUsage, "runtime option %s specified multiple times", key)
+		}
+
+		newValue, err := option.ParseValue(valueStr)
+		if err != nil {
+			return exitcode.New(exitcode.ExitUsage, err)
+		}
+		changes = append(changes, runtimeOptionChange{option: option, newValue: newValue})
+		newValues[key] = newValue
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Update Runtime Options"))
+	log.Info().Msg("")
+	log.Info().Msgf("Target environment:")
+	log.Info().Msgf("  Name:               %s", styles.RenderTechnical(envConfig.Name))
+	log.Info().Msgf("  ID:                 %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msg("")
+	log.Info().Msgf("Changes:")
+	for _, change := range changes {
+		restartNote := ""
+		if change.option.RequiresRestart {
+			restartNote = styles.RenderMuted(" (requires restart)")
+		}
+		log.Info().Msgf("  %s: %s -> %s%s",
+			change.option.Key,
+			styles.RenderMuted(formatRuntimeOptionValue(change.option.Value)),
+			styles.RenderTechnical(formatRuntimeOptionValue(change.newValue)),
+			restartNote)
+	}
+	log.Info().Msg("")
+
+	// Ask for confirmation.
+	if !o.flagYes {
+		confirmed, err := tui.DoConfirmQuestion(cmd.Context(), fmt.Sprintf("Update the runtime options in environment %s?", envConfig.HumanID))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			log.Info().Msg("Cancelled")
+			return nil
+		}
+	}
+
+	// Apply the changes.
+	updatedOptions, err := targetEnv.SetRuntimeOptions(newValues)
+	if err != nil {
+		return err
+	}
+
+	// Confirm that the environment reports the new values.
+	requiresRestart := false
+	for _, change := range changes {
+		updated := envapi.FindRuntimeOption(updatedOptions, change.option.Key)
+		if updated == nil {
+			return fmt.Errorf("runtime option %s missing from the update response", change.option.Key)
+		}
+		if formatRuntimeOptionValue(updated.Value) != formatRuntimeOptionValue(change.newValue) {
+			return fmt.Errorf("runtime option %s was not updated: value is %s, expected %s", change.option.Key, formatRuntimeOptionValue(updated.Value), formatRuntimeOptionValue(change.newValue))
+		}
+		if updated.RequiresRestart {
+			requiresRestart = true
+		}
+	}
+
+	log.Info().Msg(styles.RenderSuccess("✅ Runtime options updated!"))
+	if requiresRestart {
+		log.Info().Msg("")
+		log.Info().Msgf("Some of the changed options only take effect after a restart. Use %s to restart the game server.", styles.RenderTechnical(fmt.Sprintf("metaplay restart server %s", envConfig.HumanID)))
+	}
+	return nil
+}
+
+// Format a runtime option value for display. Numbers from JSON are float64s, so whole
+// numbers are formatted without decimals to make them comparable to parsed integers.
+func formatRuntimeOptionValue(value any) string {
+	if f, ok := value.(float64); ok && f == float64(int64(f)) {
+		return fmt.Sprintf("%d", int64(f))
+	}
+	return fmt.Sprint(value)
+}
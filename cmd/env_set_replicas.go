@@ -0,0 +1,245 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Set the number of game server replicas, taking a HorizontalPodAutoscaler into account.
+type envSetReplicasOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	argReplicas     string
+	flagDisableHPA  bool
+	flagShardSet    string
+	flagForce       bool
+	flagWait        bool
+	flagTimeout     time.Duration
+	flagLockTimeout time.Duration
+
+	replicas int
+}
+
+func init() {
+	o := envSetReplicasOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+	args.AddStringArgument(&o.argReplicas, "REPLICAS", "Desired number of game server pods, eg, '4'.")
+
+	cmd := &cobra.Command{
+		Use:               "set-replicas ENVIRONMENT REPLICAS [flags]",
+		Short:             "Set the number of game server pods, with HorizontalPodAutoscaler detection",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Set the number of game server pods (replicas) in the target environment, like
+			'metaplay scale server', but also taking the shard set's HorizontalPodAutoscaler (HPA)
+			into account.
+
+			If the shard set is autoscaled, a manually set replica count is immediately overridden
+			by the HPA. The command then refuses to scale unless --disable-hpa is given (or the
+			freeze is confirmed interactively): the HPA is frozen at the given replica count by
+			setting both its minimum and maximum replicas to it. The original bounds are saved in
+			an annotation on the HPA, and 'metaplay env enable-hpa' restores them. Setting the
+			replicas of an already frozen HPA updates the freeze.
+
+			The change is temporary: the next 'metaplay deploy server' resets the node count and
+			the HPA to what is configured in the Helm values.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay env enable-hpa ...' to restore the HPA frozen by this command.
+			- 'metaplay scale server ...' to scale the game server without HPA handling.
+			- 'metaplay get pods ...' to see the state of the pods.
+		`),
+		Example: trimIndent(`
+			# Set the game server in environment tough-falcons to 4 pods.
+			metaplay env set-replicas tough-falcons 4
+
+			# Freeze the HPA of the game server at 6 pods and wait for the pods to be ready.
+			metaplay env set-replicas tough-falcons 6 --disable-hpa --wait
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVar(&o.flagDisableHPA, "disable-hpa", false, "Freeze the shard set's HorizontalPodAutoscaler at the given replica count")
+	flags.StringVar(&o.flagShardSet, "shard-set", "", "Name of the shard set to scale (required if the game server has multiple shard sets)")
+	flags.BoolVar(&o.flagForce, "force", false, fmt.Sprintf("Allow scaling to more than %d replicas", maxGameServerReplicasWithoutForce))
+	flags.BoolVar(&o.flagWait, "wait", false, "Wait for the scaling to complete")
+	flags.DurationVar(&o.flagTimeout, "timeout", 10*time.Minute, "How long to wait for the scaling to complete with --wait")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+}
+
+func (o *envSetReplicasOpts) Prepare(cmd *cobra.Command, args []string) error {
+	replicas, err := strconv.Atoi(o.argReplicas)
+	if err != nil || replicas < 0 {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid REPLICAS '%s': must be zero or greater", o.argReplicas)
+	}
+	if replicas > maxGameServerReplicasWithoutForce && !o.flagForce {
+		return exitcode.Errorf(exitcode.ExitUsage, "refusing to scale to %d replicas (more than %d); use --force if this is intended", replicas, maxGameServerReplicasWithoutForce)
+	}
+	if cmd.Flags().Changed("timeout") && !o.flagWait {
+		return fmt.Errorf("--timeout can only be used with --wait")
+	}
+	o.replicas = replicas
+	return nil
+}
+
+func (o *envSetReplicasOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Resolve the game server and the shard set to scale.
+	gameServer, err := targetEnv.GetGameServer(cmd.Context())
+	if err != nil {
+		return err
+	}
+	shardSetName, err := resolveShardSetName(gameServer, o.flagShardSet)
+	if err != nil {
+		return err
+	}
+
+	// Validate the replica count against the limits configured for the shard set.
+	scaling, err := gameServer.GetShardSetScaling(shardSetName)
+	if err != nil {
+		return err
+	}
+	if scaling.MinNodeCount != nil && o.replicas < *scaling.MinNodeCount {
+		return exitcode.Errorf(exitcode.ExitUsage, "cannot scale shard set '%s' to %d replicas: the minimum node count is %d", shardSetName, o.replicas, *scaling.MinNodeCount)
+	}
+	if scaling.MaxNodeCount != nil && o.replicas > *scaling.MaxNodeCount {
+		return exitcode.Errorf(exitcode.ExitUsage, "cannot scale shard set '%s' to %d replicas: the maximum node count is %d", shardSetName, o.replicas, *scaling.MaxNodeCount)
+	}
+
+	// Check whether the shard set is autoscaled: the HPA would override the replica count.
+	autoscaler, err := gameServer.GetShardSetAutoscaler(cmd.Context(), shardSetName)
+	if err != nil {
+		return err
+	}
+	freezeAutoscaler, err := o.confirmFreezeAutoscaler(cmd, autoscaler, shardSetName)
+	if errors.Is(err, tui.ErrCancelled) {
+		log.Info().Msg("Cancelled")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Get the current replica counts.
+	before, err := gameServer.GetShardSetReplicas(cmd.Context(), shardSetName)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Set Game Server Replicas"))
+	log.Info().Msg("")
+	log.Info().Msgf("Target environment:")
+	log.Info().Msgf("  Name:               %s", styles.RenderTechnical(envConfig.Name))
+	log.Info().Msgf("  ID:                 %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("  Shard set:          %s", styles.RenderTechnical(shardSetName))
+	log.Info().Msgf("  Current replicas:   %s", styles.RenderTechnical(fmt.Sprintf("%d desired, %d ready", before.Desired, before.Ready)))
+	log.Info().Msgf("  New replicas:       %s", styles.RenderTechnical(fmt.Sprint(o.replicas)))
+	if autoscaler != nil {
+		log.Info().Msgf("  Autoscaler:         %s %s", styles.RenderTechnical(autoscaler.Name), styles.RenderMuted(renderAutoscalerBounds(autoscaler)))
+	}
+	log.Info().Msg("")
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "set replicas", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	taskRunner := tui.NewTaskRunner()
+
+	if freezeAutoscaler {
+		taskRunner.AddTask(fmt.Sprintf("Freeze autoscaler %s at %d replicas", autoscaler.Name, o.replicas), func(output *tui.TaskOutput) error {
+			return gameServer.FreezeShardSetAutoscaler(cmd.Context(), shardSetName, int32(o.replicas))
+		})
+	}
+
+	taskRunner.AddTask(fmt.Sprintf("Scale shard set %s to %d replicas", shardSetName, o.replicas), func(output *tui.TaskOutput) error {
+		return gameServer.ScaleShardSet(cmd.Context(), shardSetName, o.replicas)
+	})
+
+	if o.flagWait {
+		taskRunner.AddTask("Wait for the game server pods to scale", func(output *tui.TaskOutput) error {
+			return gameServer.WaitForShardSetReplicas(cmd.Context(), shardSetName, int32(o.replicas), o.flagTimeout)
+		})
+	}
+
+	// Run the tasks.
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Game server replicas set to %d", o.replicas)))
+	if freezeAutoscaler {
+		log.Info().Msgf("The autoscaler %s is frozen, restore it with %s", styles.RenderTechnical(autoscaler.Name), styles.RenderTechnical(fmt.Sprintf("metaplay env enable-hpa %s", envConfig.HumanID)))
+	}
+	if !o.flagWait {
+		log.Info().Msgf("Use %s to follow the progress.", styles.RenderTechnical(fmt.Sprintf("metaplay get pods %s", envConfig.HumanID)))
+	}
+	return nil
+}
+
+// Decide whether the shard set's autoscaler needs to be frozen: an already frozen autoscaler is
+// updated to the new replica count, an active one is only frozen with --disable-hpa or an
+// interactive confirmation. Returns tui.ErrCancelled if the user declines.
+func (o *envSetReplicasOpts) confirmFreezeAutoscaler(cmd *cobra.Command, autoscaler *envapi.ShardSetAutoscaler, shardSetName string) (bool, error) {
+	if autoscaler == nil {
+		if o.flagDisableHPA {
+			log.Warn().Msgf("Shard set '%s' has no HorizontalPodAutoscaler, ignoring --disable-hpa", shardSetName)
+		}
+		return false, nil
+	}
+
+	if o.replicas < 1 {
+		return false, exitcode.Errorf(exitcode.ExitUsage, "cannot scale autoscaled shard set '%s' to zero replicas, the autoscaler %s requires at least one", shardSetName, autoscaler.Name)
+	}
+	if autoscaler.IsFrozen {
+		log.Info().Msgf("Autoscaler %s is already frozen, updating it to %d replicas", autoscaler.Name, o.replicas)
+		return true, nil
+	}
+	if o.flagDisableHPA {
+		return true, nil
+	}
+
+	log.Warn().Msgf("%s Shard set '%s' is autoscaled by %s %s: the replica count would be immediately overridden.", styles.RenderWarning("⚠️"), shardSetName, autoscaler.Name, renderAutoscalerBounds(autoscaler))
+	if !tui.IsInteractive() {
+		return false, exitcode.Errorf(exitcode.ExitUsage, "shard set '%s' is autoscaled by %s; use --disable-hpa to freeze the autoscaler at %d replicas", shardSetName, autoscaler.Name, o.replicas)
+	}
+	confirmed, err := tui.DoConfirmQuestion(cmd.Context(), fmt.Sprintf("Freeze the autoscaler %s at %d replicas?", autoscaler.Name, o.replicas))
+	if err != nil {
+		return false, err
+	}
+	if !confirmed {
+		return false, tui.ErrCancelled
+	}
+	return true, nil
+}
+
+// Render the replica bounds of the autoscaler, eg, '[2-8 replicas]' or '[frozen, originally 2-8 replicas]'.
+func renderAutoscalerBounds(autoscaler *envapi.ShardSetAutoscaler) string {
+	if autoscaler.IsFrozen {
+		return fmt.Sprintf("[frozen at %d, originally %d-%d replicas]", autoscaler.MaxReplicas, autoscaler.OriginalMinReplicas, autoscaler.OriginalMaxReplicas)
+	}
+	return fmt.Sprintf("[%d-%d replicas]", autoscaler.MinReplicas, autoscaler.MaxReplicas)
+}
@@ -0,0 +1,390 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Overall timeout for collecting an environment snapshot.
+const envSnapshotTimeout = 60 * time.Second
+
+// Number of lines to include from the end of each container's logs.
+const envSnapshotLogTailLines int64 = 1000
+
+// Capture the state of an environment into a zip file for post-incident debugging.
+type envSnapshotOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagOutput string
+	flagSince  time.Duration
+}
+
+// Entries and errors of an environment snapshot, appended to from the collector goroutines.
+type envSnapshot struct {
+	mu      sync.Mutex
+	entries []supportBundleEntry
+	errors  []string
+}
+
+func init() {
+	o := envSnapshotOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "snapshot ENVIRONMENT [flags]",
+		Short:             "Capture the state of the environment into a zip file",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Capture a point-in-time snapshot of the environment into a zip file, eg, for
+			debugging after an incident. The files are written into a timestamp-prefixed
+			directory within the zip.
+
+			The snapshot contains:
+			- The environment details from the StackAPI.
+			- The pods in the environment's namespace.
+			- The logs of each container (last 1000 lines, and of the previous container
+			  instance if it has restarted). Use --since to limit how far back to go.
+			- The values of the Helm releases.
+			- The recent Kubernetes events.
+			- The ConfigMaps and resource quotas in the namespace.
+
+			The data is collected in parallel with an overall timeout of 60 seconds. If some
+			of the data cannot be collected, the snapshot is still written and the failures
+			are listed in errors.txt within the zip.
+
+			Auth tokens and the credentials in the environment details are redacted, but the
+			Helm values and ConfigMaps are included as-is, so treat the snapshot as containing
+			sensitive information.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay get pods ...' to list the pods in the environment.
+			- 'metaplay debug logs ...' to view the game server logs.
+			- 'metaplay support bundle' to collect information about the CLI and your system.
+		`),
+		Example: trimIndent(`
+			# Capture a snapshot of environment tough-falcons into the current directory.
+			metaplay env snapshot tough-falcons
+
+			# Capture a snapshot into a specific file.
+			metaplay env snapshot tough-falcons --output=/tmp/incident.zip
+
+			# Only include the logs from the last 30 minutes.
+			metaplay env snapshot tough-falcons --since=30m
+		`),
+	}
+	envCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVarP(&o.flagOutput, "output", "o", "", "Path of the zip file to write (default 'metaplay-snapshot-<environment>-<timestamp>.zip')")
+	flags.DurationVar(&o.flagSince, "since", 0, "Only include logs more recent than the duration, eg, 30m or 3h (default: no limit)")
+}
+
+func (o *envSnapshotOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagSince < 0 {
+		return fmt.Errorf("--since must be non-negative, got %s", o.flagSince)
+	}
+	return nil
+}
+
+func (o *envSnapshotOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Capture Environment Snapshot"))
+	log.Info().Msg("")
+
+	startTime := time.Now().UTC()
+	if o.flagOutput == "" {
+		o.flagOutput = fmt.Sprintf("metaplay-snapshot-%s-%s.zip", envConfig.HumanID, startTime.Format("20060102-150405"))
+	}
+
+	// Create a Kubernetes client and Helm config.
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+	actionConfig, err := helmutil.NewActionConfig(kubeCli.KubeConfig, kubeCli.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+
+	// Collect the data in parallel, with an overall timeout.
+	ctx, cancel := context.WithTimeout(cmd.Context(), envSnapshotTimeout)
+	defer cancel()
+
+	snapshot := &envSnapshot{}
+	collectors := map[string]func(context.Context, *envSnapshot) error{
+		"environment details": func(ctx context.Context, snapshot *envSnapshot) error {
+			return collectSnapshotDetails(targetEnv, snapshot)
+		},
+		"pods and logs": func(ctx context.Context, snapshot *envSnapshot) error {
+			return collectSnapshotPods(ctx, kubeCli, o.flagSince, snapshot)
+		},
+		"Helm releases": func(ctx context.Context, snapshot *envSnapshot) error {
+			return collectSnapshotHelmValues(actionConfig, kubeCli.Namespace, snapshot)
+		},
+		"events": func(ctx context.Context, snapshot *envSnapshot) error {
+			return collectSnapshotEvents(ctx, kubeCli, snapshot)
+		},
+		"ConfigMaps": func(ctx context.Context, snapshot *envSnapshot) error {
+			configMaps, err := kubeCli.Clientset.CoreV1().ConfigMaps(kubeCli.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			return snapshot.addJSON("configmaps.json", "ConfigMaps", configMaps)
+		},
+		"resource quotas": func(ctx context.Context, snapshot *envSnapshot) error {
+			quotas, err := kubeCli.Clientset.CoreV1().ResourceQuotas(kubeCli.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			return snapshot.addJSON("resource-quotas.json", "Resource quotas", quotas)
+		},
+	}
+
+	var wg sync.WaitGroup
+	for name, collect := range collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Debug().Msgf("Collect %s", name)
+			if err := collect(ctx, snapshot); err != nil {
+				snapshot.addError(fmt.Sprintf("failed to collect %s: %v", name, err))
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		snapshot.addError(fmt.Sprintf("collecting the snapshot did not complete within %s: %v", envSnapshotTimeout, ctx.Err()))
+	}
+
+	// Take the collected entries: collectors still running after a timeout are ignored.
+	entries, errors := snapshot.take()
+	if len(entries) == 0 {
+		return fmt.Errorf("failed to collect any data from environment %s:\n%s", envConfig.HumanID, strings.Join(errors, "\n"))
+	}
+	if len(errors) > 0 {
+		entries = append(entries, supportBundleEntry{"errors.txt", "Data that could not be collected", []byte(strings.Join(errors, "\n") + "\n")})
+	}
+
+	// Put the files into a timestamp-prefixed directory and redact auth tokens.
+	dirName := fmt.Sprintf("%s-%s", startTime.Format("20060102-150405"), envConfig.HumanID)
+	for ndx := range entries {
+		entries[ndx].FileName = dirName + "/" + entries[ndx].FileName
+		entries[ndx].Contents = redactTokens(entries[ndx].Contents)
+	}
+
+	if err := writeSupportBundle(o.flagOutput, entries); err != nil {
+		return err
+	}
+
+	totalBytes := 0
+	for _, entry := range entries {
+		log.Debug().Msgf("  %s [%s]", entry.FileName, humanize.Bytes(uint64(len(entry.Contents))))
+		totalBytes += len(entry.Contents)
+	}
+	for _, errMsg := range errors {
+		log.Warn().Msgf("%s %s", styles.RenderWarning("⚠️"), errMsg)
+	}
+	if len(errors) > 0 {
+		log.Info().Msg("")
+	}
+	log.Info().Msgf("✅ %s %s %s", styles.RenderSuccess("Snapshot written to"), styles.RenderTechnical(o.flagOutput), styles.RenderMuted(fmt.Sprintf("[%d files, %s]", len(entries), humanize.Bytes(uint64(totalBytes)))))
+	return nil
+}
+
+// Add a file to the snapshot.
+func (snapshot *envSnapshot) add(fileName, description string, contents []byte) {
+	snapshot.mu.Lock()
+	defer snapshot.mu.Unlock()
+	snapshot.entries = append(snapshot.entries, supportBundleEntry{fileName, description, contents})
+}
+
+// Add a file with the value marshaled as indented JSON to the snapshot.
+func (snapshot *envSnapshot) addJSON(fileName, description string, value any) error {
+	contents, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", fileName, err)
+	}
+	snapshot.add(fileName, description, contents)
+	return nil
+}
+
+// Record data that could not be collected.
+func (snapshot *envSnapshot) addError(errMsg string) {
+	snapshot.mu.Lock()
+	defer snapshot.mu.Unlock()
+	snapshot.errors = append(snapshot.errors, errMsg)
+}
+
+// Take the collected entries (sorted by file name) and errors. Entries added afterwards are ignored.
+func (snapshot *envSnapshot) take() ([]supportBundleEntry, []string) {
+	snapshot.mu.Lock()
+	defer snapshot.mu.Unlock()
+
+	entries := snapshot.entries
+	errors := snapshot.errors
+	snapshot.entries = nil
+	snapshot.errors = nil
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FileName < entries[j].FileName })
+	sort.Strings(errors)
+	return entries, errors
+}
+
+// Collect the environment details from the StackAPI, without the credentials.
+func collectSnapshotDetails(targetEnv *envapi.TargetEnvironment, snapshot *envSnapshot) error {
+	details, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+	return snapshot.addJSON("environment-details.json", "Environment details", redactEnvironmentDetails(details))
+}
+
+// Return a copy of the environment details with the credentials cleared. The details are
+// cached by the TargetEnvironment, so they must not be modified in place.
+func redactEnvironmentDetails(details *envapi.DeploymentSecret) *envapi.DeploymentSecret {
+	redacted := *details
+	redacted.OAuth2Client.ClientSecret = ""
+	redacted.Observability.LokiPassword = ""
+	redacted.Observability.PrometheusPassword = ""
+	return &redacted
+}
+
+// Collect the pods and the logs of their containers. The logs are fetched in parallel.
+func collectSnapshotPods(ctx context.Context, kubeCli *envapi.KubeClient, since time.Duration, snapshot *envSnapshot) error {
+	pods, err := kubeCli.Clientset.CoreV1().Pods(kubeCli.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if err := snapshot.addJSON("pods.json", "Pods", pods); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				collectSnapshotContainerLogs(ctx, kubeCli, pod.Name, containerStatus.Name, false, since, snapshot)
+				if containerStatus.RestartCount > 0 {
+					collectSnapshotContainerLogs(ctx, kubeCli, pod.Name, containerStatus.Name, true, since, snapshot)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+// Collect the last lines of logs of a container, or of its previous instance if previous is true.
+func collectSnapshotContainerLogs(ctx context.Context, kubeCli *envapi.KubeClient, podName, containerName string, previous bool, since time.Duration, snapshot *envSnapshot) {
+	tailLines := envSnapshotLogTailLines
+	logOpts := &corev1.PodLogOptions{
+		Container:  containerName,
+		Previous:   previous,
+		Timestamps: true,
+		TailLines:  &tailLines,
+	}
+	if since > 0 {
+		sinceSeconds := int64(since.Seconds())
+		logOpts.SinceSeconds = &sinceSeconds
+	}
+
+	fileName := fmt.Sprintf("logs/%s/%s.log", podName, containerName)
+	if previous {
+		fileName = fmt.Sprintf("logs/%s/%s.previous.log", podName, containerName)
+	}
+
+	logs, err := kubeCli.Clientset.CoreV1().Pods(kubeCli.Namespace).GetLogs(podName, logOpts).DoRaw(ctx)
+	if err != nil {
+		snapshot.addError(fmt.Sprintf("failed to collect %s: %v", fileName, err))
+		return
+	}
+	snapshot.add(fileName, fmt.Sprintf("Logs of container %s in pod %s", containerName, podName), logs)
+}
+
+// Collect the values of all the Helm releases in the namespace.
+func collectSnapshotHelmValues(actionConfig *action.Configuration, namespace string, snapshot *envSnapshot) error {
+	var releases []*release.Release
+	for _, chartName := range []string{metaplayGameServerChartName, metaplayLoadTestChartName} {
+		chartReleases, err := helmutil.HelmListReleases(actionConfig, namespace, chartName)
+		if err != nil {
+			return err
+		}
+		releases = append(releases, chartReleases...)
+	}
+
+	for _, rel := range releases {
+		values, err := helmutil.GetReleaseValues(actionConfig, rel.Name)
+		if err != nil {
+			snapshot.addError(fmt.Sprintf("failed to collect the values of Helm release %s: %v", rel.Name, err))
+			continue
+		}
+		valuesYAML, err := yaml.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the values of Helm release %s: %w", rel.Name, err)
+		}
+		header := fmt.Sprintf("# Release: %s, chart: %s-%s, revision: %d, status: %s\n", rel.Name, rel.Chart.Metadata.Name, rel.Chart.Metadata.Version, rel.Version, rel.Info.Status)
+		snapshot.add(fmt.Sprintf("helm/%s-values.yaml", rel.Name), fmt.Sprintf("Values of Helm release %s", rel.Name), append([]byte(header), valuesYAML...))
+	}
+	return nil
+}
+
+// Collect the recent Kubernetes events in the namespace, oldest first.
+func collectSnapshotEvents(ctx context.Context, kubeCli *envapi.KubeClient, snapshot *envSnapshot) error {
+	events, err := kubeCli.Clientset.CoreV1().Events(kubeCli.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return getEventTime(&events.Items[i]).Before(getEventTime(&events.Items[j]))
+	})
+	return snapshot.addJSON("events.json", "Kubernetes events", events)
+}
+
+// Get the time of the latest occurrence of the event.
+func getEventTime(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/metaplay/cli/pkg/envapi"
+)
+
+func TestCollectSnapshotDetailsRedactsCredentials(t *testing.T) {
+	details := &envapi.DeploymentSecret{
+		Deployment:    envapi.Deployment{ServerHostname: "tough-falcons.p1.metaplay.io"},
+		OAuth2Client:  envapi.OAuth2Client{ClientId: "client-id", ClientSecret: "oauth2-client-secret"},
+		Observability: envapi.Observability{LokiUsername: "loki", LokiPassword: "loki-secret-password", PrometheusUsername: "prometheus", PrometheusPassword: "prometheus-secret-password"},
+	}
+
+	snapshot := &envSnapshot{}
+	if err := snapshot.addJSON("environment-details.json", "Environment details", redactEnvironmentDetails(details)); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := snapshot.take()
+	contents := string(entries[0].Contents)
+	for _, secret := range []string{"oauth2-client-secret", "loki-secret-password", "prometheus-secret-password"} {
+		if strings.Contains(contents, secret) {
+			t.Errorf("secret %q written to environment-details.json:\n%s", secret, contents)
+		}
+	}
+	for _, value := range []string{"tough-falcons.p1.metaplay.io", "client-id", "loki"} {
+		if !strings.Contains(contents, value) {
+			t.Errorf("expected %q in environment-details.json:\n%s", value, contents)
+		}
+	}
+
+	// The cached details must be left as-is.
+	if details.OAuth2Client.ClientSecret != "oauth2-client-secret" || details.Observability.LokiPassword != "loki-secret-password" || details.Observability.PrometheusPassword != "prometheus-secret-password" {
+		t.Errorf("the original details were modified: %+v", details)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/portalapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+)
+
+// Operation checked against the project's environment policies.
+type policyOperation string
+
+const (
+	policyOperationDeployServer    policyOperation = "deploy server"
+	policyOperationDeployBotClient policyOperation = "deploy botclient"
+)
+
+// Render the environment type color-coded: the closer to production, the more alarming.
+func renderEnvironmentType(envType portalapi.EnvironmentType) string {
+	switch envType {
+	case portalapi.EnvironmentTypeProduction:
+		return styles.RenderError(string(envType))
+	case portalapi.EnvironmentTypeStaging:
+		return styles.RenderWarning(string(envType))
+	case portalapi.EnvironmentTypeDevelopment:
+		return styles.RenderSuccess(string(envType))
+	default:
+		return styles.RenderTechnical(string(envType))
+	}
+}
+
+// Check whether the operation violates the given policy rule.
+func isPolicyViolated(rule metaproj.PolicyRule, operation policyOperation) bool {
+	switch rule {
+	case metaproj.PolicyRuleRequireCI:
+		return ciEnvironment() == ""
+	case metaproj.PolicyRuleNoBotClientDeploy:
+		return operation == policyOperationDeployBotClient
+	default:
+		return false
+	}
+}
+
+// Enforce the project's policies (from metaplay-project.yaml) for the operation on the target
+// environment. A violation fails the operation unless overridePolicy is set, in which case the
+// user must confirm the override by typing in the environment ID. Returns the description of the
+// override to record in the Helm release history, or an empty string if nothing was overridden.
+func enforceEnvironmentPolicies(ctx context.Context, project *metaproj.MetaplayProject, envConfig *metaproj.ProjectEnvironmentConfig, tokenSet *auth.TokenSet, operation policyOperation, overridePolicy bool) (string, error) {
+	// Without a project, there are no policies.
+	if project == nil {
+		return "", nil
+	}
+
+	// Collect the violated rules.
+	var violatedRules []string
+	for _, policy := range project.Config.GetEnvironmentPolicies(envConfig) {
+		if isPolicyViolated(policy.Rule, operation) {
+			log.Debug().Msgf("Operation '%s' violates policy '%s' in environment %s", operation, policy.Rule, envConfig.HumanID)
+			violatedRules = append(violatedRules, string(policy.Rule))
+		}
+	}
+	if len(violatedRules) == 0 {
+		return "", nil
+	}
+
+	// Fail unless the override was requested.
+	if !overridePolicy {
+		reasons := make([]string, len(violatedRules))
+		for ndx, rule := range violatedRules {
+			reasons[ndx] = fmt.Sprintf("'%s' (%s)", rule, metaproj.PolicyRule(rule).Description())
+		}
+		return "", fmt.Errorf("policy violation: '%s' into %s environment %s is not allowed by project policy %s; use --override-policy to override", operation, envConfig.Type, envConfig.HumanID, strings.Join(reasons, ", "))
+	}
+
+	// Overriding requires typed confirmation, so it is not possible in non-interactive mode.
+	if !tui.IsInteractive() {
+		return "", fmt.Errorf("--override-policy requires typed confirmation and cannot be used in non-interactive mode")
+	}
+	for _, rule := range violatedRules {
+		log.Warn().Msgf("Overriding policy '%s': %s", rule, metaproj.PolicyRule(rule).Description())
+	}
+	err := tui.ConfirmDangerousAction(ctx, fmt.Sprintf("This will override the project policies for %s environment %s!", envConfig.Type, envConfig.HumanID), envConfig.HumanID, false)
+	if err != nil {
+		return "", err
+	}
+
+	description := fmt.Sprintf("Policy override (%s) by %s", strings.Join(violatedRules, ", "), auth.GetTokenSetUserIdentity(tokenSet))
+	log.Info().Msgf("Policy override recorded in the release history: %s", styles.RenderMuted(description))
+	return description, nil
+}
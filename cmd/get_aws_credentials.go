@@ -6,10 +6,9 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/metaplay/cli/pkg/envapi"
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -63,7 +62,8 @@ func init() {
 			#    export AWS_SESSION_TOKEN=\(.SessionToken)"
 			# ')
 		`),
-		Run: runCommand(&o),
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 	}
 	getCmd.AddCommand(cmd)
 
@@ -79,7 +79,7 @@ func (o *getAWSCredentialsOpts) Prepare(cmd *cobra.Command, args []string) error
 	return nil
 }
 
-func (o *getAWSCredentialsOpts) Run(cmd *cobra.Command) error {
+func (o *getAWSCredentialsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -98,8 +98,7 @@ func (o *getAWSCredentialsOpts) Run(cmd *cobra.Command) error {
 	// Get AWS credentials
 	credentials, err := targetEnv.GetAWSCredentials()
 	if err != nil {
-		log.Error().Msgf("Failed to get AWS credentials: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to get AWS credentials: %w", err)
 	}
 
 	// Output the credentials in the requested format
@@ -111,12 +110,20 @@ func (o *getAWSCredentialsOpts) Run(cmd *cobra.Command) error {
 		}
 		fmt.Println(string(output))
 	case "text":
-		log.Info().Msgf("AWS Access Key ID:     %s", credentials.AccessKeyID)
-		log.Info().Msgf("AWS Secret Access Key: %s", credentials.SecretAccessKey)
-		if credentials.SessionToken != "" {
-			log.Info().Msgf("AWS Session Token:     %s", credentials.SessionToken)
-		}
+		// Print with fmt to keep the secrets out of the debug log file.
+		fmt.Print(formatAWSCredentialsText(credentials))
 	}
 
 	return nil
 }
+
+// Format the AWS credentials as human-readable text, one value per line.
+func formatAWSCredentialsText(credentials *envapi.AWSCredentials) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "AWS Access Key ID:     %s\n", credentials.AccessKeyID)
+	fmt.Fprintf(&sb, "AWS Secret Access Key: %s\n", credentials.SecretAccessKey)
+	if credentials.SessionToken != "" {
+		fmt.Fprintf(&sb, "AWS Session Token:     %s\n", credentials.SessionToken)
+	}
+	return sb.String()
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/pkg/common"
+	"github.com/metaplay/cli/pkg/containerutil"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Name of the file (in the CLI state directory) where the expiration times of docker logins
+// performed by the CLI are stored. Docker itself doesn't track when the credentials expire.
+const dockerLoginCacheFileName = "docker-logins.json"
+
+// Consider an existing login expired if it's about to expire within this margin.
+const dockerLoginExpiryMargin = 5 * time.Minute
+
+// Log in to the target environment's docker registry.
+type getDockerLoginOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagPrintOnly bool
+	flagFormat    string
+	flagForce     bool
+}
+
+func init() {
+	o := getDockerLoginOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:   "docker-login ENVIRONMENT [flags]",
+		Short: "Log in to the target environment's docker registry",
+		Long: renderLong(&o, `
+			Log the local docker in to the target environment's docker image registry, so that
+			images can be pushed and pulled with the docker CLI. With podman as the container
+			runtime (see 'metaplay build image --help'), podman is logged in instead.
+
+			The login is performed with 'docker login --password-stdin' (or 'podman login'), so
+			the credentials are stored according to your docker or podman configuration,
+			including any configured credential store or helper. The credentials are temporary: the expiration time is shown after
+			logging in. If an earlier login by this command is still valid, nothing is done
+			(unless --force is specified).
+
+			With --print-only, the credentials are printed to stdout instead of logging in:
+			- text: Only the password, suitable for piping into 'docker login --password-stdin'
+			- json: The registry URL, username, password, and expiration time
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay image push ...' to push an image into the environment's registry.
+			- 'metaplay get aws-credentials ...' to get AWS credentials for the environment.
+		`),
+		Example: trimIndent(`
+			# Log in to the docker registry of environment tough-falcons.
+			metaplay get docker-login tough-falcons
+
+			# Log in again even if the earlier login is still valid.
+			metaplay get docker-login tough-falcons --force
+
+			# Print the credentials as JSON for other tools.
+			metaplay get docker-login tough-falcons --print-only --format=json
+
+			# Pipe the password into another tool.
+			metaplay get docker-login tough-falcons --print-only | podman login --username AWS --password-stdin <registry>
+		`),
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+	}
+	getCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVar(&o.flagPrintOnly, "print-only", false, "Print the credentials to stdout instead of logging in")
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format with --print-only (text or json)")
+	flags.BoolVar(&o.flagForce, "force", false, "Log in even if an earlier login is still valid")
+}
+
+func (o *getDockerLoginOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q; must be either \"text\" or \"json\"", o.flagFormat)
+	}
+	if cmd.Flags().Changed("format") && !o.flagPrintOnly {
+		return fmt.Errorf("--format can only be used with --print-only")
+	}
+
+	return nil
+}
+
+func (o *getDockerLoginOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	targetEnv := cmdCtx.TargetEnv
+
+	// Get environment details.
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+
+	// Get docker credentials.
+	dockerCredentials, err := targetEnv.GetDockerCredentials(envDetails)
+	if err != nil {
+		return fmt.Errorf("failed to get docker credentials: %v", err)
+	}
+	registryHost := dockerCredentials.RegistryHost()
+
+	// Only print the credentials, if requested.
+	if o.flagPrintOnly {
+		switch o.flagFormat {
+		case "json":
+			output, err := json.MarshalIndent(dockerCredentials, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal credentials to JSON: %v", err)
+			}
+			fmt.Println(string(output))
+		case "text":
+			stderrLogger.Info().Msgf("Registry:   %s", dockerCredentials.RegistryURL)
+			stderrLogger.Info().Msgf("Username:   %s", dockerCredentials.Username)
+			fmt.Println(dockerCredentials.Password)
+		}
+		return nil
+	}
+
+	// Check that the container runtime (docker or podman) is installed and running.
+	containerRuntime, err := resolveContainerRuntime(cmd.Context(), cmdCtx.Project)
+	if err != nil {
+		return err
+	}
+
+	// Skip if an earlier login is still valid.
+	loginCache := loadDockerLoginCache()
+	if expiresAt, found := loginCache[registryHost]; found && !o.flagForce {
+		if time.Now().Add(dockerLoginExpiryMargin).Before(expiresAt) && hasRegistryLogin(cmd.Context(), containerRuntime, registryHost) {
+			log.Info().Msgf("Already logged in to %s, valid until %s (%s)", styles.RenderTechnical(registryHost), expiresAt.Local().Format(time.RFC1123), humanize.Time(expiresAt))
+			log.Info().Msg(styles.RenderMuted("Use --force to log in again."))
+			return nil
+		}
+		log.Info().Msgf("Earlier login to %s has expired, logging in again", registryHost)
+	}
+
+	// Log in with the runtime's CLI, passing the password via stdin.
+	loginArgs := containerRuntime.LoginArgs(dockerCredentials.Username, dockerCredentials.RegistryURL)
+	log.Debug().Msgf("Run: %s %s", containerRuntime.Binary, strings.Join(loginArgs, " "))
+	var output bytes.Buffer
+	loginCmd := containerRuntime.Command(cmd.Context(), loginArgs...)
+	loginCmd.Stdin = strings.NewReader(dockerCredentials.Password)
+	loginCmd.Stdout = &output
+	loginCmd.Stderr = &output
+	if err := loginCmd.Run(); err != nil {
+		return fmt.Errorf("%s login failed: %w\n%s", containerRuntime.Name, err, strings.TrimSpace(output.String()))
+	}
+	log.Debug().Msgf("%s login output: %s", containerRuntime.Name, strings.TrimSpace(output.String()))
+
+	// Remember when the login expires.
+	if !dockerCredentials.ExpiresAt.IsZero() {
+		loginCache[registryHost] = dockerCredentials.ExpiresAt
+		if err := saveDockerLoginCache(loginCache); err != nil {
+			log.Warn().Msgf("Failed to store docker login expiration time: %v", err)
+		}
+	}
+
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Logged %s in to the environment's docker registry", containerRuntime.Name)))
+	log.Info().Msgf("  Registry:   %s", styles.RenderTechnical(dockerCredentials.RegistryURL))
+	log.Info().Msgf("  Repository: %s", styles.RenderTechnical(envDetails.Deployment.EcrRepo))
+	if !dockerCredentials.ExpiresAt.IsZero() {
+		log.Info().Msgf("  Expires:    %s (%s)", styles.RenderTechnical(dockerCredentials.ExpiresAt.Local().Format(time.RFC1123)), humanize.Time(dockerCredentials.ExpiresAt))
+	}
+
+	return nil
+}
+
+// Get the path to the docker login cache file.
+func getDockerLoginCachePath() (string, error) {
+	stateDir, err := common.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, dockerLoginCacheFileName), nil
+}
+
+// Load the expiration times of earlier docker logins, keyed by registry host. Returns an
+// empty map if the cache doesn't exist or cannot be read.
+func loadDockerLoginCache() map[string]time.Time {
+	cache := map[string]time.Time{}
+	path, err := getDockerLoginCachePath()
+	if err != nil {
+		return cache
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(content, &cache); err != nil {
+		log.Debug().Msgf("Ignoring invalid docker login cache %s: %v", path, err)
+		return map[string]time.Time{}
+	}
+	return cache
+}
+
+// Store the expiration times of docker logins. Expired entries are dropped.
+func saveDockerLoginCache(cache map[string]time.Time) error {
+	path, err := getDockerLoginCachePath()
+	if err != nil {
+		return err
+	}
+	for registryHost, expiresAt := range cache {
+		if time.Now().After(expiresAt) {
+			delete(cache, registryHost)
+		}
+	}
+	content, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// Check whether the container runtime still has credentials for the registry. Podman keeps
+// its credentials in its own auth file, so ask podman directly.
+func hasRegistryLogin(ctx context.Context, rt *containerutil.Runtime, registryHost string) bool {
+	if rt.Name == containerutil.RuntimePodman {
+		return rt.Command(ctx, "login", "--get-login", registryHost).Run() == nil
+	}
+	return hasDockerConfigEntry(registryHost)
+}
+
+// Check whether the docker config file (~/.docker/config.json or $DOCKER_CONFIG/config.json)
+// has credentials, or a credential helper, configured for the registry.
+func hasDockerConfigEntry(registryHost string) bool {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		configDir = filepath.Join(homeDir, ".docker")
+	}
+
+	content, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return false
+	}
+
+	var dockerConfig struct {
+		Auths       map[string]json.RawMessage `json:"auths"`
+		CredHelpers map[string]string          `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(content, &dockerConfig); err != nil {
+		log.Debug().Msgf("Failed to parse docker config: %v", err)
+		return false
+	}
+
+	for _, key := range []string{registryHost, "https://" + registryHost} {
+		if _, found := dockerConfig.Auths[key]; found {
+			return true
+		}
+		if _, found := dockerConfig.CredHelpers[key]; found {
+			return true
+		}
+	}
+	return false
+}
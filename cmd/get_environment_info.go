@@ -9,7 +9,7 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaplay"
 	"github.com/metaplay/cli/pkg/portalapi"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
@@ -30,10 +30,11 @@ func init() {
 	args.AddStringArgumentOpt(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
 
 	cmd := &cobra.Command{
-		Use:     "environment-info ENVIRONMENT [flags]",
-		Aliases: []string{"env-info"},
-		Short:   "Get information about the target environment",
-		Run:     runCommand(&o),
+		Use:               "environment-info ENVIRONMENT [flags]",
+		Aliases:           []string{"env-info", "env"},
+		Short:             "Get information about the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Get information about the target environment.
 
@@ -76,7 +77,7 @@ func intListToStr(ints []int) string {
 	return "[ " + strings.Join(strInts, ", ") + " ]"
 }
 
-func (o *getEnvironmentInfoOpts) Run(cmd *cobra.Command) error {
+func (o *getEnvironmentInfoOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -89,11 +90,9 @@ func (o *getEnvironmentInfoOpts) Run(cmd *cobra.Command) error {
 		return err
 	}
 
-	// Create TargetEnvironment.
-	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
-
 	// Fetch the information from the environment via StackAPI.
-	envInfo, err := targetEnv.GetDetails()
+	env := metaplay.NewEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+	envInfo, err := metaplay.GetEnvironmentDetails(cmd.Context(), env)
 	if err != nil {
 		return err
 	}
@@ -130,6 +129,7 @@ func (o *getEnvironmentInfoOpts) Run(cmd *cobra.Command) error {
 		// Print relevant information in text format
 		log.Info().Msgf("")
 		log.Info().Msgf("Environment details:")
+		log.Info().Msgf("  Type:                 %s", renderEnvironmentType(envConfig.Type))
 		log.Info().Msgf("  Admin hostname:       %s", styles.RenderTechnical(deployment.AdminHostname))
 		log.Info().Msgf("  Server hostname:      %s", styles.RenderTechnical(deployment.ServerHostname))
 		log.Info().Msgf("  Server ports:         %s", styles.RenderTechnical(intListToStr(deployment.ServerPorts)))
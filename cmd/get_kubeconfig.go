@@ -6,8 +6,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/credfiles"
 	"github.com/metaplay/cli/pkg/envapi"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -20,8 +23,13 @@ type getKubeConfigOpts struct {
 	argAuthProvider     string
 	flagCredentialsType string
 	flagOutput          string
+	flagSave            bool
 }
 
+// The credentials embedded in static kubeconfigs are short-lived. Treat the saved files as
+// expired after this long.
+const staticKubeconfigLifetime = 1 * time.Hour
+
 func init() {
 	o := getKubeConfigOpts{}
 
@@ -30,9 +38,10 @@ func init() {
 	args.AddStringArgumentOpt(&o.argAuthProvider, "AUTH_PROVIDER", "Name of the auth provider to use. Defaults to 'metaplay'.")
 
 	cmd := &cobra.Command{
-		Use:   "kubeconfig ENVIRONMENT [AUTH_PROVIDER] [flags]",
-		Short: "Get the Kubernetes KubeConfig for the target environment",
-		Run:   runCommand(&o),
+		Use:               "kubeconfig ENVIRONMENT [AUTH_PROVIDER] [flags]",
+		Short:             "Get the Kubernetes KubeConfig for the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Get the Kubernetes KubeConfig for accessing the target environment's cluster.
 
@@ -46,6 +55,10 @@ func init() {
 
 			The KubeConfig can be written to a file using the --output flag, or printed to stdout if not specified.
 
+			With --save, a static KubeConfig is written into the CLI's managed credentials directory
+			and the path to the file is printed. Saved files are deleted automatically once the
+			credentials have expired, see 'metaplay auth list-credentials'.
+
 			The default auth provider is 'metaplay'. If you have multiple auth providers configured in your
 			'metaplay-project.yaml', you can specify the name of the provider you want to use with the
 			argument AUTH_PROVIDER.
@@ -59,6 +72,9 @@ func init() {
 			# Get KubeConfig with static credentials and save to a file
 			metaplay get kubeconfig tough-falcons --type=static --output=kubeconfig.yaml
 
+			# Save a static KubeConfig into the managed credentials directory and use it.
+			export KUBECONFIG=$(metaplay get kubeconfig tough-falcons --type=static --save)
+
 			# Get KubeConfig with default credentials type (based on user type)
 			metaplay get kubeconfig tough-falcons
 
@@ -71,13 +87,20 @@ func init() {
 	flags := cmd.Flags()
 	flags.StringVarP(&o.flagCredentialsType, "type", "t", "", "Type of credentials handling in kubeconfig, static or dynamic")
 	flags.StringVarP(&o.flagOutput, "output", "o", "", "Path of the output file where to write kubeconfig (written to stdout if not specified)")
+	flags.BoolVar(&o.flagSave, "save", false, "Write a static kubeconfig into the managed credentials directory and print its path")
 }
 
 func (o *getKubeConfigOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagSave && o.flagOutput != "" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--save cannot be used with --output")
+	}
+	if o.flagSave && o.flagCredentialsType != "" && o.flagCredentialsType != "static" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--save only supports static credentials (dynamic kubeconfigs contain no credentials, use --output instead)")
+	}
 	return nil
 }
 
-func (o *getKubeConfigOpts) Run(cmd *cobra.Command) error {
+func (o *getKubeConfigOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -105,7 +128,9 @@ func (o *getKubeConfigOpts) Run(cmd *cobra.Command) error {
 
 	// Default to credentialsType==dynamic for human users, and credentialsType==static for machine users
 	credentialsType := o.flagCredentialsType
-	if credentialsType == "" {
+	if credentialsType == "" && o.flagSave {
+		credentialsType = "static"
+	} else if credentialsType == "" {
 		if isHumanUser := tokenSet.RefreshToken != ""; isHumanUser {
 			credentialsType = "dynamic"
 		} else {
@@ -127,17 +152,21 @@ func (o *getKubeConfigOpts) Run(cmd *cobra.Command) error {
 	case "static":
 		kubeconfigPayload, err = targetEnv.GetKubeConfigWithEmbeddedCredentials()
 	default:
-		log.Error().Msg("Invalid credentials type; must be either \"static\" or \"dynamic\"")
-		os.Exit(1)
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid credentials type; must be either \"static\" or \"dynamic\"")
 	}
 
 	if err != nil {
-		log.Error().Msgf("Failed to get environment k8s config: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to get environment k8s config: %w", err)
 	}
 
-	// Write the kubeconfig payload to a file or stdout.
-	if o.flagOutput != "" {
+	// Write the kubeconfig payload to a managed file, a file, or stdout.
+	if o.flagSave {
+		filePath, err := credfiles.Write("kubeconfig", envConfig.HumanID, ".yaml", []byte(kubeconfigPayload), time.Now().Add(staticKubeconfigLifetime))
+		if err != nil {
+			return err
+		}
+		log.Info().Msg(filePath)
+	} else if o.flagOutput != "" {
 		log.Debug().Msgf("Write kubeconfig to file %s", o.flagOutput)
 		err = os.WriteFile(o.flagOutput, []byte(kubeconfigPayload), 0600)
 		if err != nil {
@@ -145,7 +174,8 @@ func (o *getKubeConfigOpts) Run(cmd *cobra.Command) error {
 		}
 		log.Info().Msgf("Wrote kubeconfig to %s", o.flagOutput)
 	} else {
-		log.Info().Msg(kubeconfigPayload)
+		// Print with fmt to keep the credentials out of the debug log file.
+		fmt.Println(kubeconfigPayload)
 	}
 
 	return nil
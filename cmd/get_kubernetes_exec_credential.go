@@ -39,7 +39,7 @@ func (o *getKubernetesExecCredentialOpts) Prepare(cmd *cobra.Command, args []str
 	return nil
 }
 
-func (o *getKubernetesExecCredentialOpts) Run(cmd *cobra.Command) error {
+func (o *getKubernetesExecCredentialOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
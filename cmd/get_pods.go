@@ -0,0 +1,210 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Label selector matching the pods of the Metaplay components (game server and bot clients).
+const metaplayPodsLabelSelector = "app in (metaplay-server,botclient)"
+
+// List the pods running in the target environment.
+type getPodsOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagAll    bool
+	flagFormat string
+}
+
+// Summary of a pod, as shown by 'get pods'.
+type podSummary struct {
+	Name      string    `json:"name"`
+	Ready     string    `json:"ready"`
+	Status    string    `json:"status"`
+	Restarts  int32     `json:"restarts"`
+	Age       string    `json:"age"`
+	CreatedAt time.Time `json:"createdAt"`
+	NodeName  string    `json:"nodeName"`
+}
+
+func init() {
+	o := getPodsOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:   "pods ENVIRONMENT [flags]",
+		Short: "List the pods running in the target environment",
+		Long: renderLong(&o, `
+			List the Kubernetes pods running in the target environment with their status,
+			restart count, and age. This is a quick snapshot of the environment, similar to
+			'kubectl get pods'.
+
+			By default, only the Metaplay pods (game server and bot clients) are shown. Use
+			--all to show all the pods in the environment's namespace.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay debug server-status ...' to check the status of the game server deployment.
+			- 'metaplay debug logs ...' to view logs from the game server pods.
+			- 'metaplay get kubeconfig ...' to get a kubeconfig for using kubectl.
+		`),
+		Example: trimIndent(`
+			# List the Metaplay pods in environment tough-falcons.
+			metaplay get pods tough-falcons
+
+			# List all the pods in the environment's namespace.
+			metaplay get pods tough-falcons --all
+
+			# Output the pods as JSON for scripting.
+			metaplay get pods tough-falcons --format=json
+		`),
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+	}
+	getCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVar(&o.flagAll, "all", false, "Show all the pods in the namespace, not only the Metaplay pods")
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format (text or json)")
+}
+
+func (o *getPodsOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q; must be either \"text\" or \"json\"", o.flagFormat)
+	}
+
+	return nil
+}
+
+func (o *getPodsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Create a Kubernetes client.
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+
+	// List the pods.
+	listOpts := metav1.ListOptions{}
+	if !o.flagAll {
+		listOpts.LabelSelector = metaplayPodsLabelSelector
+	}
+	log.Debug().Msgf("List pods in namespace %s with label selector '%s'", kubeCli.Namespace, listOpts.LabelSelector)
+	pods, err := kubeCli.Clientset.CoreV1().Pods(kubeCli.Namespace).List(cmd.Context(), listOpts)
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	summaries := make([]podSummary, len(pods.Items))
+	for ndx := range pods.Items {
+		summaries[ndx] = summarizePod(&pods.Items[ndx])
+	}
+
+	// Output the pods in desired format.
+	if o.flagFormat == "json" {
+		podsJSON, err := json.MarshalIndent(summaries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal pods as JSON: %v", err)
+		}
+		fmt.Println(string(podsJSON))
+		return nil
+	}
+
+	if len(summaries) == 0 {
+		if o.flagAll {
+			log.Info().Msgf("No pods found in environment %s", envConfig.HumanID)
+		} else {
+			log.Info().Msgf("No Metaplay pods found in environment %s (use --all to show all pods)", envConfig.HumanID)
+		}
+		return nil
+	}
+
+	podsTable := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(styles.StyleMuted).
+		Headers("NAME", "READY", "STATUS", "RESTARTS", "AGE").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			style := lipgloss.NewStyle().Padding(0, 1)
+			if row == table.HeaderRow {
+				return style.Inherit(styles.StyleTitle)
+			}
+			if col == 2 {
+				return style.Inherit(podStatusStyle(summaries[row].Status))
+			}
+			return style
+		})
+	for _, pod := range summaries {
+		podsTable.Row(pod.Name, pod.Ready, pod.Status, fmt.Sprintf("%d", pod.Restarts), pod.Age)
+	}
+	log.Info().Msg(podsTable.String())
+
+	return nil
+}
+
+// Summarize the pod's state similarly to 'kubectl get pods'.
+func summarizePod(pod *corev1.Pod) podSummary {
+	numReady := 0
+	restarts := int32(0)
+	status := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		status = pod.Status.Reason
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.Ready {
+			numReady++
+		}
+		restarts += containerStatus.RestartCount
+
+		// Show the reason of a waiting or terminated container (eg, CrashLoopBackOff) instead of the phase.
+		if waiting := containerStatus.State.Waiting; waiting != nil && waiting.Reason != "" {
+			status = waiting.Reason
+		} else if terminated := containerStatus.State.Terminated; terminated != nil && terminated.Reason != "" && pod.Status.Phase != corev1.PodSucceeded {
+			status = terminated.Reason
+		}
+	}
+
+	if pod.DeletionTimestamp != nil {
+		status = "Terminating"
+	}
+
+	return podSummary{
+		Name:      pod.Name,
+		Ready:     fmt.Sprintf("%d/%d", numReady, len(pod.Spec.Containers)),
+		Status:    status,
+		Restarts:  restarts,
+		Age:       formatAge(time.Since(pod.CreationTimestamp.Time)),
+		CreatedAt: pod.CreationTimestamp.Time,
+		NodeName:  pod.Spec.NodeName,
+	}
+}
+
+// Resolve the style to render a pod status with.
+func podStatusStyle(status string) lipgloss.Style {
+	switch status {
+	case string(corev1.PodRunning), string(corev1.PodSucceeded), "Completed":
+		return styles.StyleSuccess
+	case string(corev1.PodPending), "ContainerCreating", "PodInitializing", "Terminating":
+		return styles.StyleWarning
+	default:
+		return styles.StyleError
+	}
+}
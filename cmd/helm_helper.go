@@ -0,0 +1,124 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaplay"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// Default timeout for Helm install, upgrade, and uninstall operations. Uninstalls only
+// use it for the chart's hooks, they don't wait for the resources to be deleted.
+const defaultHelmTimeout = metaplay.DefaultHelmTimeout
+
+// Create the labels to stamp on the Helm releases deployed by the CLI: the CLI version and the
+// identity of the deploying user (if logged in), see helmutil.NewReleaseLabels().
+func newCliReleaseLabels(tokenSet *auth.TokenSet) map[string]string {
+	deployedBy := ""
+	if tokenSet != nil {
+		deployedBy = auth.GetTokenSetUserIdentity(tokenSet)
+	}
+	return helmutil.NewReleaseLabels(version.AppVersion, deployedBy)
+}
+
+// Create the description to record in the Helm release history for a deploy by the CLI, with
+// the identity of the deploying user (if logged in) and the policy override (if any), see
+// helmutil.NewReleaseDescription().
+func newCliReleaseDescription(tokenSet *auth.TokenSet, policyOverride string) string {
+	deployedBy := ""
+	if tokenSet != nil {
+		deployedBy = auth.GetTokenSetUserIdentity(tokenSet)
+	}
+	return helmutil.NewReleaseDescription(version.AppVersion, deployedBy, policyOverride)
+}
+
+// Describe who deployed the release with which CLI version, based on the release labels.
+// Returns an empty string for releases not managed by the CLI.
+func describeReleaseDeployer(rel *release.Release) string {
+	if !helmutil.IsManagedByCli(rel) {
+		return ""
+	}
+	deployedBy := coalesceString(helmutil.GetReleaseDeployedBy(rel), "unknown")
+	if cliVersion := helmutil.GetReleaseCliVersion(rel); cliVersion != "" {
+		return fmt.Sprintf("%s (CLI %s)", deployedBy, cliVersion)
+	}
+	return deployedBy
+}
+
+// Log who last deployed the release, eg, before removing it. Releases without the CLI labels
+// were deployed by other tools or by older CLI versions.
+func logReleaseDeployer(rel *release.Release) {
+	if deployer := describeReleaseDeployer(rel); deployer != "" {
+		log.Info().Msgf("Release %s was last deployed by %s", styles.RenderTechnical(rel.Name), styles.RenderTechnical(deployer))
+	} else {
+		log.Info().Msgf("Release %s is not labeled as managed by the Metaplay CLI (deployed by another tool or an older CLI version)", styles.RenderTechnical(rel.Name))
+	}
+}
+
+// If the Helm operation failed due to a timeout, print the current state of the
+// release to help figure out what is stuck. Returns the error as-is.
+func reportHelmTimeout(actionConfig *action.Configuration, releaseName string, err error) error {
+	if !helmutil.IsTimeoutError(err) {
+		return err
+	}
+
+	log.Error().Msgf("Helm operation on release %s timed out", releaseName)
+	rel, statusErr := helmutil.GetReleaseStatus(actionConfig, releaseName)
+	if statusErr != nil {
+		log.Warn().Msgf("Unable to get the current state of the release: %v", statusErr)
+		return err
+	}
+	printHelmReleaseState(rel)
+	return err
+}
+
+// If the Helm uninstall failed due to a timeout, print the current state of the release, like
+// reportHelmTimeout(). The release's stored state is only read, not waited on, and a release
+// that is already deleted (only its resources remain) is reported as such. Returns the error
+// as-is.
+func reportHelmUninstallTimeout(actionConfig *action.Configuration, releaseName string, err error) error {
+	if !helmutil.IsTimeoutError(err) {
+		return err
+	}
+
+	log.Error().Msgf("Helm uninstall of release %s timed out", releaseName)
+	rel, statusErr := helmutil.GetReleaseStatus(actionConfig, releaseName)
+	if errors.Is(statusErr, driver.ErrReleaseNotFound) {
+		log.Info().Msgf("Release %s is already uninstalled, check the state of the remaining resources with 'metaplay get pods'", styles.RenderTechnical(releaseName))
+		return err
+	} else if statusErr != nil {
+		log.Warn().Msgf("Unable to get the current state of the release: %v", statusErr)
+		return err
+	}
+	printHelmReleaseState(rel)
+	return err
+}
+
+// Print the current state of the Helm release.
+func printHelmReleaseState(rel *release.Release) {
+	log.Info().Msg("")
+	log.Info().Msgf("Current state of Helm release %s:", styles.RenderTechnical(rel.Name))
+	log.Info().Msgf("  Status:        %s", styles.RenderTechnical(rel.Info.Status.String()))
+	log.Info().Msgf("  Revision:      %s", styles.RenderTechnical(fmt.Sprintf("%d", rel.Version)))
+	log.Info().Msgf("  Chart version: %s", styles.RenderTechnical(rel.Chart.Metadata.Version))
+	log.Info().Msgf("  Last deployed: %s", styles.RenderTechnical(humanize.Time(rel.Info.LastDeployed.Time)))
+	if rel.Info.Description != "" {
+		log.Info().Msgf("  Description:   %s", styles.RenderTechnical(rel.Info.Description))
+	}
+	if deployer := describeReleaseDeployer(rel); deployer != "" {
+		log.Info().Msgf("  Deployed by:   %s", styles.RenderTechnical(deployer))
+	}
+	log.Info().Msg("")
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestReportHelmUninstallTimeout(t *testing.T) {
+	actionConfig := &action.Configuration{
+		Releases:   storage.Init(driver.NewMemory()),
+		KubeClient: &kubefake.PrintingKubeClient{Out: io.Discard},
+		Log:        func(format string, v ...interface{}) {},
+	}
+	err := actionConfig.Releases.Create(&release.Release{
+		Name:      "gameserver",
+		Namespace: "tough-falcons",
+		Version:   1,
+		Info:      &release.Info{Status: release.StatusUninstalling},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "metaplay-gameserver", Version: "0.8.0"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The error is returned as-is, both for a release stuck uninstalling and for a release
+	// that is already deleted.
+	timeoutErr := fmt.Errorf("failed to uninstall Helm release gameserver: %w", context.DeadlineExceeded)
+	for _, releaseName := range []string{"gameserver", "already-deleted"} {
+		if got := reportHelmUninstallTimeout(actionConfig, releaseName, timeoutErr); got != timeoutErr {
+			t.Errorf("reportHelmUninstallTimeout(%s) = %v, expected the original error", releaseName, got)
+		}
+	}
+
+	// Other errors don't look up the release.
+	otherErr := errors.New("forbidden")
+	if got := reportHelmUninstallTimeout(nil, "gameserver", otherErr); got != otherErr {
+		t.Errorf("reportHelmUninstallTimeout() = %v, expected the original error", got)
+	}
+}
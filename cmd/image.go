@@ -8,8 +8,9 @@ import (
 )
 
 var imageCmd = &cobra.Command{
-	Use:   "image",
-	Short: "Commands for managing server Docker images",
+	Use:     "image",
+	Aliases: []string{"img", "images"},
+	Short:   "Commands for managing server Docker images",
 }
 
 func init() {
@@ -0,0 +1,161 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// List the project's server Docker images in the local Docker.
+type imageListOpts struct {
+	UsePositionalArgs
+	RequiresProject
+
+	flagFormat  string
+	flagNoTrunc bool
+	flagColumns []string
+}
+
+// Image as output by 'image list --format=json'.
+type imageListEntry struct {
+	Image       string    `json:"image"`
+	Tag         string    `json:"tag"`
+	SdkVersion  string    `json:"sdkVersion"`
+	CommitID    string    `json:"commitId"`
+	BuildNumber string    `json:"buildNumber"`
+	Created     time.Time `json:"created"`
+}
+
+func init() {
+	o := imageListOpts{}
+
+	cmd := &cobra.Command{
+		Use:     "list [flags]",
+		Aliases: []string{"ls"},
+		Short:   "List the project's server Docker images built locally",
+		Run:     runCommand(&o),
+		Long: renderLong(&o, `
+			List the project's game server Docker images in the local Docker, newest first.
+
+			The table is fit into the terminal width by truncating and hiding the less
+			important columns; use --no-trunc to show everything, or --columns to choose the
+			columns. When the output is not a terminal, the full values are output as
+			tab-separated values, eg, for processing with awk or cut.
+
+			Related commands:
+			- 'metaplay build image' to build a server Docker image.
+			- 'metaplay image push ...' to push an image to an environment.
+			- 'metaplay dev image ...' to run an image locally.
+		`),
+		Example: trimIndent(`
+			# List the project's local server images.
+			metaplay image list
+
+			# List only the image names and commit IDs, without truncating.
+			metaplay image list --columns=image,commit --no-trunc
+
+			# List the images in JSON format.
+			metaplay image list --format=json
+		`),
+	}
+	imageCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format. Valid values are 'text' or 'json'")
+	flags.BoolVar(&o.flagNoTrunc, "no-trunc", false, "Don't truncate the values to fit the terminal")
+	flags.StringSliceVar(&o.flagColumns, "columns", nil, "Columns to show, eg, 'image,created' (valid columns: image, created, sdk-version, build, commit)")
+}
+
+func (o *imageListOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q, must be either 'text' or 'json'", o.flagFormat)
+	}
+	return nil
+}
+
+func (o *imageListOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	project := cmdCtx.Project
+
+	// Check that the container runtime (docker or podman) is installed and running.
+	if _, err := resolveContainerRuntimeAPI(cmd.Context(), project); err != nil {
+		return err
+	}
+
+	// Find the project's local images, newest first.
+	images, err := envapi.ReadLocalDockerImagesByProjectID(project.Config.ProjectHumanID)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].ConfigFile.Created.After(images[j].ConfigFile.Created.Time)
+	})
+
+	entries := make([]imageListEntry, len(images))
+	for ndx, image := range images {
+		entries[ndx] = imageListEntry{
+			Image:       image.RepoTag,
+			Tag:         image.Tag,
+			SdkVersion:  image.SdkVersion,
+			CommitID:    image.CommitID,
+			BuildNumber: image.BuildNumber,
+			Created:     image.ConfigFile.Created.Time,
+		}
+	}
+
+	if o.flagFormat == "json" {
+		entriesJSON, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal images as JSON: %v", err)
+		}
+		log.Info().Msg(string(entriesJSON))
+		return nil
+	}
+
+	table := tui.NewTable(
+		tui.TableColumn{Key: "image", Title: "IMAGE", Priority: 5, MinWidth: 20, Style: styles.RenderTechnical},
+		tui.TableColumn{Key: "created", Title: "CREATED", Priority: 4, MinWidth: 12},
+		tui.TableColumn{Key: "sdk-version", Title: "SDK VERSION", Priority: 3, MinWidth: 11},
+		tui.TableColumn{Key: "build", Title: "BUILD", Priority: 2, MinWidth: 8},
+		tui.TableColumn{Key: "commit", Title: "COMMIT", Priority: 1, MinWidth: 8, Style: styles.RenderMuted},
+	)
+	tableOpts := tui.NewTableOptions(o.flagColumns, o.flagNoTrunc)
+	for _, entry := range entries {
+		// Use absolute timestamps in the tab-separated output, for processing by other tools.
+		created := humanize.Time(entry.Created)
+		if tableOpts.Plain {
+			created = entry.Created.UTC().Format(time.RFC3339)
+		}
+		table.AddRow(entry.Image, created, entry.SdkVersion, entry.BuildNumber, entry.CommitID)
+	}
+	lines, err := table.Render(tableOpts)
+	if err != nil {
+		return exitcode.New(exitcode.ExitUsage, err)
+	}
+
+	// Only output the values when piped, so that they can be processed by other tools.
+	if !tableOpts.Plain {
+		log.Info().Msg("")
+		log.Info().Msg(styles.RenderTitle("Local Server Images"))
+		log.Info().Msg("")
+		if len(entries) == 0 {
+			log.Info().Msgf("No server images for project %s found, build one with %s", project.Config.ProjectHumanID, styles.RenderPrompt("metaplay build image"))
+			return nil
+		}
+	}
+	for _, line := range lines {
+		log.Info().Msg(line)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Promote (copy) a docker image from one environment's registry to another's.
+type promoteImageOpts struct {
+	UsePositionalArgs
+
+	argSourceEnvironment string
+	argTargetEnvironment string
+	argImageTag          string
+}
+
+func init() {
+	o := promoteImageOpts{}
+
+	args := o.Arguments()
+	args.AddStringArgument(&o.argSourceEnvironment, "SOURCE_ENV", "Environment to copy the image from, eg, 'tough-falcons'.")
+	args.AddStringArgument(&o.argTargetEnvironment, "TARGET_ENV", "Environment to copy the image to, eg, 'lovely-wombats'.")
+	args.AddStringArgument(&o.argImageTag, "TAG", "Tag of the docker image to copy, eg, '364cff09'.")
+
+	cmd := &cobra.Command{
+		Use:               "promote SOURCE_ENV TARGET_ENV TAG",
+		Short:             "Copy a game server Docker image from one environment to another",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Copy a game server docker image from the source environment's image repository
+			to the target environment's image repository, eg, to promote an image tested in
+			staging into production without rebuilding it.
+
+			The image is copied directly between the registries, without going through the
+			local docker. When both environments use the same registry, the layers are mounted
+			within the registry and no layer data is transferred through this machine. Otherwise,
+			the layers are streamed through this machine, in parallel. Layers that already exist
+			in the target repository are skipped.
+
+			After copying, the digest of the image in the target repository is verified to match
+			the source image.
+
+			Images built from uncommitted changes (labeled as dirty) cannot be promoted.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay deploy server ...' to deploy the promoted image.
+		`),
+		Example: trimIndent(`
+			# Promote image with tag '364cff09' from environment 'tough-falcons' to 'lovely-wombats'.
+			metaplay image promote tough-falcons lovely-wombats 364cff09
+		`),
+	}
+	imageCmd.AddCommand(cmd)
+}
+
+func (o *promoteImageOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if strings.Contains(o.argImageTag, ":") {
+		return fmt.Errorf("TAG must contain only the tag (not the repository prefix), eg, '364cff09'")
+	}
+	if err := checkImageTagNotLatest(o.argImageTag); err != nil {
+		return err
+	}
+	if o.argSourceEnvironment == o.argTargetEnvironment {
+		return fmt.Errorf("SOURCE_ENV and TARGET_ENV must be different environments")
+	}
+	return nil
+}
+
+func (o *promoteImageOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Try to resolve the project & auth provider.
+	project, err := tryResolveProject()
+	if err != nil {
+		return err
+	}
+
+	// Resolve the source and target environments and their registries.
+	sourceEnvConfig, sourceCreds, sourceRepo, err := resolveEnvironmentRegistry(cmd, project, o.argSourceEnvironment)
+	if err != nil {
+		return err
+	}
+	targetEnvConfig, targetCreds, targetRepo, err := resolveEnvironmentRegistry(cmd, project, o.argTargetEnvironment)
+	if err != nil {
+		return err
+	}
+
+	sourceImageName := fmt.Sprintf("%s:%s", sourceRepo, o.argImageTag)
+	targetImageName := fmt.Sprintf("%s:%s", targetRepo, o.argImageTag)
+
+	// Resolve the source image.
+	sourceImage, err := envapi.ResolveRemoteDockerImage(cmd.Context(), sourceCreds, sourceImageName)
+	if err != nil {
+		return exitcode.New(exitcode.ExitNotFound, err)
+	}
+
+	// Refuse to promote dirty images.
+	labels := sourceImage.ConfigFile.Config.Labels
+	if labels[dockerImageDirtyLabel] == "true" || strings.HasSuffix(labels["io.metaplay.commit_id"], "-dirty") {
+		return fmt.Errorf("refusing to promote image %s: it was built from uncommitted changes", sourceImageName)
+	}
+
+	copyMode := "streamed through this machine"
+	if sourceImage.CanMountInto(targetImageName) {
+		copyMode = "mounted within the registry"
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Promote Docker Image"))
+	log.Info().Msg("")
+	log.Info().Msgf("Source environment: %s", styles.RenderTechnical(sourceEnvConfig.HumanID))
+	log.Info().Msgf("Target environment: %s", styles.RenderTechnical(targetEnvConfig.HumanID))
+	log.Info().Msgf("Source image:       %s", styles.RenderTechnical(sourceImageName))
+	log.Info().Msgf("Target image:       %s", styles.RenderTechnical(targetImageName))
+	log.Info().Msgf("Image digest:       %s", styles.RenderTechnical(sourceImage.Digest.String()))
+	log.Info().Msgf("Layers:             %s", styles.RenderTechnical(copyMode))
+	log.Info().Msg("")
+
+	// Copy the image, showing progress in steps of 10% to avoid spamming the log in
+	// non-interactive mode.
+	taskRunner := tui.NewTaskRunner()
+	taskRunner.AddTask("Copy docker image to target environment repository", func(output *tui.TaskOutput) error {
+		lastPercent := int64(-1)
+		_, err := sourceImage.CopyTo(cmd.Context(), targetCreds, targetImageName, func(complete, total int64) {
+			if total <= 0 {
+				return
+			}
+			percent := complete * 100 / total
+			if percent/10 != lastPercent/10 {
+				lastPercent = percent
+				output.SetHeaderLines([]string{fmt.Sprintf("Copied %s of %s (%d%%)", humanize.Bytes(uint64(complete)), humanize.Bytes(uint64(total)), percent)})
+			}
+		})
+		return err
+	})
+
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Successfully promoted image"), styles.RenderTechnical(targetImageName))
+	log.Info().Msgf("Digest: %s", styles.RenderTechnical(sourceImage.Digest.String()))
+	log.Info().Msg("")
+	log.Info().Msg("You can deploy the image to the target environment using:")
+	log.Info().Msgf(styles.RenderTechnical("  metaplay deploy server %s %s"), targetEnvConfig.HumanID, o.argImageTag)
+	return nil
+}
+
+// Resolve an environment's config, docker registry credentials, and image repository.
+func resolveEnvironmentRegistry(cmd *cobra.Command, project *metaproj.MetaplayProject, environment string) (*metaproj.ProjectEnvironmentConfig, *envapi.DockerCredentials, string, error) {
+	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, environment)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if err := envDetails.Validate(); err != nil {
+		return nil, nil, "", err
+	}
+
+	dockerCredentials, err := targetEnv.GetDockerCredentials(envDetails)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to get docker credentials for environment %s: %w", envConfig.HumanID, err)
+	}
+
+	return envConfig, dockerCredentials, envDetails.Deployment.EcrRepo, nil
+}
@@ -5,19 +5,14 @@ package cmd
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"strings"
 
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/registry"
-	"github.com/docker/docker/client"
-	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metaplay"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -39,9 +34,10 @@ func init() {
 	args.AddStringArgument(&o.argImageName, "IMAGE:TAG", "Docker image name and tag, eg, 'mygame:364cff09'.")
 
 	cmd := &cobra.Command{
-		Use:   "push ENVIRONMENT IMAGE:TAG",
-		Short: "Push a built server Docker image to the target environment's docker image repository",
-		Run:   runCommand(&o),
+		Use:               "push ENVIRONMENT IMAGE:TAG",
+		Short:             "Push a built server Docker image to the target environment's docker image repository",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Push a built game server docker image to the target environment's image repository.
 
@@ -64,11 +60,16 @@ func (o *PushImageOptions) Prepare(cmd *cobra.Command, args []string) error {
 	if !strings.Contains(o.argImageName, ":") {
 		return fmt.Errorf("IMAGE must be a full docker image name 'NAME:TAG', got '%s'", o.argImageName)
 	}
+	if imageTag, err := extractDockerImageTag(o.argImageName); err == nil {
+		if err := checkImageTagNotLatest(imageTag); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (o *PushImageOptions) Run(cmd *cobra.Command) error {
+func (o *PushImageOptions) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -81,6 +82,11 @@ func (o *PushImageOptions) Run(cmd *cobra.Command) error {
 		return err
 	}
 
+	// The local image is pushed via the container runtime's API, check that it's available.
+	if _, err := resolveContainerRuntimeAPI(cmd.Context(), project); err != nil {
+		return err
+	}
+
 	// Log attempt
 	log.Info().Msg("")
 	log.Info().Msg(styles.RenderTitle("Push Docker Image to Cloud"))
@@ -89,28 +95,19 @@ func (o *PushImageOptions) Run(cmd *cobra.Command) error {
 	log.Info().Msgf("Docker image name: %s", styles.RenderTechnical(o.argImageName))
 	log.Info().Msg("")
 
-	// Create TargetEnvironment.
-	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
-
-	// Get environment details.
-	envDetails, err := targetEnv.GetDetails()
-	if err != nil {
-		return err
-	}
-
-	// Get docker credentials.
-	dockerCredentials, err := targetEnv.GetDockerCredentials(envDetails)
-	if err != nil {
-		return err
-	}
-	log.Debug().Msgf("Got docker credentials: username=%s", dockerCredentials.Username)
+	// Create the environment.
+	env := metaplay.NewEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
 
 	// Use task runner to push the image.
 	taskRunner := tui.NewTaskRunner()
 
 	// Push the image to the remote repository.
 	taskRunner.AddTask("Push docker image to environment repository", func(output *tui.TaskOutput) error {
-		return pushDockerImage(cmd.Context(), output, o.argImageName, envDetails.Deployment.EcrRepo, dockerCredentials)
+		_, err := metaplay.PushImage(cmd.Context(), env, metaplay.PushImageOptions{
+			ImageName: o.argImageName,
+			Progress:  taskOutputProgress(output),
+		})
+		return err
 	})
 
 	// Run the tasks.
@@ -123,6 +120,17 @@ func (o *PushImageOptions) Run(cmd *cobra.Command) error {
 	return nil
 }
 
+// Why images tagged 'latest' must not be pushed or deployed into the cloud.
+const latestTagExplanation = "the 'latest' tag is mutable, so a deployment cannot be traced back to the exact image it runs and rolling back to an earlier 'latest' is not deterministic"
+
+// Check that the image tag is not 'latest', which must never be pushed or deployed into the cloud.
+func checkImageTagNotLatest(imageTag string) error {
+	if imageTag == "latest" {
+		return exitcode.Errorf(exitcode.ExitUsage, "images tagged 'latest' cannot be pushed or deployed into the cloud: %s; build the image with a commit hash or timestamp tag instead", latestTagExplanation)
+	}
+	return nil
+}
+
 // Extrat the tag from a full 'name:tag' docker image name.
 func extractDockerImageTag(imageName string) (string, error) {
 	// Check if the image name is empty
@@ -143,113 +151,19 @@ func extractDockerImageTag(imageName string) (string, error) {
 // Push a docker image from the local repo to a remote one.
 // Output progress into the task output.
 func pushDockerImage(ctx context.Context, output *tui.TaskOutput, imageName, dstRepoName string, dockerCredentials *envapi.DockerCredentials) error {
-	// Create a Docker client
-	// \todo This has been observed to fail on Tuomo's Mac with: "Cannot connect to the Docker daemon
-	// at unix:///var/run/docker.sock. Is the docker daemon running?"
-	// For details, see comments on https://github.com/metaplay/sdk/pull/3627
-	cli, err := client.NewClientWithOpts(client.WithAPIVersionNegotiation())
-	if err != nil {
-		return fmt.Errorf("failed to create Docker client: %w", err)
-	}
-
-	// Extract tag from source image.
-	imageTag, err := extractDockerImageTag(imageName)
-	if err != nil {
-		return err
-	}
-
-	// Resolve source and destination image names.
-	srcImageName := imageName
-	dstImageName := fmt.Sprintf("%s:%s", dstRepoName, imageTag)
-
-	// If names don't match, tag the source image as the destination.
-	if srcImageName != dstImageName {
-		output.AppendLinef("Tagging image %s as %s", srcImageName, dstImageName)
-		if err := cli.ImageTag(ctx, srcImageName, dstImageName); err != nil {
-			return fmt.Errorf("failed to tag image: %w", err)
-		}
-	}
-
-	// Push the image
-	output.AppendLinef("Pushing image %s", dstImageName)
-	authConfig := registry.AuthConfig{
-		Username:      dockerCredentials.Username,
-		Password:      dockerCredentials.Password,
-		ServerAddress: dockerCredentials.RegistryURL,
-	}
-	authConfigBytes, err := json.Marshal(authConfig)
-	if err != nil {
-		return fmt.Errorf("failed to marshal auth config: %w", err)
-	}
-
-	// Encode with base64
-	authStr := string(base64.StdEncoding.EncodeToString(authConfigBytes))
-
-	pushResponseReader, err := cli.ImagePush(ctx, dstImageName, image.PushOptions{
-		RegistryAuth: authStr,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to push docker image: %w", err)
-	}
-	defer pushResponseReader.Close()
-
-	// Follow push progress
-	decoder := json.NewDecoder(pushResponseReader)
-	progressIDs := []string{}                          // Track order of progress IDs
-	progresses := map[string]jsonmessage.JSONMessage{} // Track progress by ID
-
-	for {
-		var progress jsonmessage.JSONMessage
-		if err := decoder.Decode(&progress); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("failed to decode push response: %w", err)
-		}
-
-		// Track progress by ID to show the latest status for each layer
-		if progress.ID != "" {
-			// Add ID to the order tracking slice if it's not already there
-			if _, exists := progresses[progress.ID]; !exists {
-				progressIDs = append(progressIDs, progress.ID)
-			}
-			progresses[progress.ID] = progress
-		}
-
-		// If progress has an error, return it
-		if progress.Error != nil {
-			return fmt.Errorf("error pushing image: %s", progress.Error.Message)
-		}
-
-		// Update the output with current progress information (only in interactive mode).
-		if tui.IsInteractiveMode() {
-			updateProgressOutput(output, dstImageName, progressIDs, progresses)
-		}
-	}
-
-	return nil
+	_, err := metaplay.PushImageToRepository(ctx, imageName, dstRepoName, dockerCredentials, taskOutputProgress(output))
+	return err
 }
 
-// updateProgressOutput updates the task output with the current push progress information
-func updateProgressOutput(output *tui.TaskOutput, imageName string, progressIDs []string, progresses map[string]jsonmessage.JSONMessage) {
-	// Start with the header line
-	lines := []string{}
-
-	// Add progress for each layer
-	for _, id := range progressIDs {
-		// Skip empty progress entries
-		if progresses[id].Progress == nil && progresses[id].Status == "" {
-			continue
-		}
-
-		// Format the progress line
-		progressLine := fmt.Sprintf("Layer %s: %s", id[:12], progresses[id].Status)
-		if progresses[id].Progress != nil {
-			progressLine += fmt.Sprintf(" %s", progresses[id].Progress.String())
-		}
-		lines = append(lines, progressLine)
+// Report the progress of a metaplay package operation into the task output. The status
+// updates (eg, per-layer push progress) are only shown in interactive mode.
+func taskOutputProgress(output *tui.TaskOutput) metaplay.ProgressCallbacks {
+	return metaplay.ProgressCallbacks{
+		OnLog: output.AppendLine,
+		OnStatus: func(lines []string) {
+			if tui.IsInteractive() {
+				output.SetFooterLines(lines)
+			}
+		},
 	}
-
-	// Update all lines at once
-	output.SetFooterLines(lines)
 }
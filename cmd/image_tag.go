@@ -0,0 +1,131 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Add a tag to an image in the environment's registry, without pulling or pushing it.
+type imageTagOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	argSourceTag  string
+	argDestTag    string
+	flagOverwrite bool
+}
+
+func init() {
+	o := imageTagOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+	args.AddStringArgument(&o.argSourceTag, "SOURCE_TAG", "Tag of the existing image, eg, 'sha-364cff09'.")
+	args.AddStringArgument(&o.argDestTag, "DEST_TAG", "Tag to add to the image, eg, 'release-1.2.3'.")
+
+	cmd := &cobra.Command{
+		Use:               "tag ENVIRONMENT SOURCE_TAG DEST_TAG [flags]",
+		Short:             "Add a tag to a server Docker image in the environment's registry",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Add a tag to a game server docker image in the environment's image repository, eg,
+			to mark a tested image as a release.
+
+			The tagging is done within the registry: the image manifest is copied to the new tag
+			using ECR's PutImage API, without transferring any layer data or requiring a local
+			docker. The digest of the image is printed after tagging.
+
+			If DEST_TAG already refers to another image, the command fails unless --overwrite is
+			given, in which case the tag is moved to the source image. Repositories with immutable
+			tags don't allow moving tags.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay image push ...' to push an image into the environment.
+			- 'metaplay image promote ...' to copy an image to another environment.
+			- 'metaplay deploy server ...' to deploy the image with the new tag.
+		`),
+		Example: trimIndent(`
+			# Tag image 'sha-364cff09' in environment tough-falcons as 'release-1.2.3'.
+			metaplay image tag tough-falcons sha-364cff09 release-1.2.3
+
+			# Move the tag 'stable' to another image.
+			metaplay image tag tough-falcons release-1.2.3 stable --overwrite
+		`),
+	}
+	imageCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVar(&o.flagOverwrite, "overwrite", false, "Move DEST_TAG to the source image if it already refers to another image")
+}
+
+func (o *imageTagOpts) Prepare(cmd *cobra.Command, args []string) error {
+	for _, tag := range []string{o.argSourceTag, o.argDestTag} {
+		if strings.Contains(tag, ":") {
+			return exitcode.Errorf(exitcode.ExitUsage, "only specify the image tag, eg, '364cff09', not the full image name: '%s'", tag)
+		}
+	}
+	if err := checkImageTagNotLatest(o.argDestTag); err != nil {
+		return err
+	}
+	if o.argSourceTag == o.argDestTag {
+		return exitcode.Errorf(exitcode.ExitUsage, "SOURCE_TAG and DEST_TAG must be different")
+	}
+	return nil
+}
+
+func (o *imageTagOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+	if err := envDetails.Validate(); err != nil {
+		return err
+	}
+	repository, err := envapi.ParseECRRepository(envDetails.Deployment.EcrRepo)
+	if err != nil {
+		return err
+	}
+
+	ecrClient, err := targetEnv.NewECRClient(cmd.Context(), envDetails)
+	if err != nil {
+		return err
+	}
+
+	result, err := envapi.TagECRImage(cmd.Context(), ecrClient, repository, o.argSourceTag, o.argDestTag, o.flagOverwrite)
+	if err != nil {
+		if errors.Is(err, envapi.ErrECRImageNotFound) {
+			return exitcode.New(exitcode.ExitNotFound, err)
+		}
+		return err
+	}
+
+	destImageName := fmt.Sprintf("%s:%s", envDetails.Deployment.EcrRepo, o.argDestTag)
+	if result.AlreadyTagged {
+		log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Image is already tagged as"), styles.RenderTechnical(destImageName))
+	} else {
+		log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Tagged image as"), styles.RenderTechnical(destImageName))
+	}
+	log.Info().Msgf("  Environment: %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("  Source tag:  %s", styles.RenderTechnical(o.argSourceTag))
+	if result.PreviousDigest != "" {
+		log.Info().Msgf("  Moved from:  %s", styles.RenderMuted(result.PreviousDigest))
+	}
+	log.Info().Msgf("  Digest:      %s", styles.RenderTechnical(result.Digest))
+	return nil
+}
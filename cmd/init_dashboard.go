@@ -25,9 +25,10 @@ func init() {
 	o := initDashboardOpts{}
 
 	cmd := &cobra.Command{
-		Use:   "dashboard [flags]",
-		Short: "Initializes custom LiveOps Dashboard for the project",
-		Run:   runCommand(&o),
+		Use:     "dashboard [flags]",
+		Aliases: []string{"dash"},
+		Short:   "Initializes custom LiveOps Dashboard for the project",
+		Run:     runCommand(&o),
 		Long: renderLong(&o, `
 			Setup the development environment for a custom LiveOps Dashboard in your project.
 
@@ -79,7 +80,7 @@ func writePnpmWorkspaceFile(outfilePath string, entries []string) error {
 	return nil
 }
 
-func (o *initDashboardOpts) Run(cmd *cobra.Command) error {
+func (o *initDashboardOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	log.Info().Msg("")
 	log.Info().Msg(styles.RenderTitle("Initialize Custom LiveOps Dashboard in Your Project"))
 
@@ -120,8 +121,7 @@ func (o *initDashboardOpts) Run(cmd *cobra.Command) error {
 	// Install dashboard dependencies (need to resolve the path in case '-p' was used to run this command)
 	pathToDashboardDir := filepath.Join(project.RelativeDir, dashboardDirRelative)
 	if err := execChildInteractive(pathToDashboardDir, "pnpm", []string{"install"}); err != nil {
-		log.Error().Msgf("Failed to run 'pnpm install': %s", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to run 'pnpm install': %w", err)
 	}
 
 	log.Info().Msg("")
@@ -141,7 +141,7 @@ func (o *initDashboardOpts) Run(cmd *cobra.Command) error {
 // and setting the dashboard root directory.
 func updateProjectConfigCustomDashboard(project *metaproj.MetaplayProject, dashboardDir string) error {
 	// Load the existing metaplay-project.yaml
-	projectConfigFilePath := filepath.Join(project.RelativeDir, metaproj.ConfigFileName)
+	projectConfigFilePath := project.GetConfigFilePath()
 	configFileBytes, err := os.ReadFile(projectConfigFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to read project config file: %v", err)
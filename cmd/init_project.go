@@ -138,14 +138,14 @@ func (o *initProjectOpts) Prepare(cmd *cobra.Command, args []string) error {
 	}
 
 	// Must be either in interactive mode or specify --yes.
-	if !tui.IsInteractiveMode() && !o.flagAutoConfirm {
+	if !tui.IsInteractive() && !o.flagAutoConfirm {
 		return fmt.Errorf("use --yes to automatically confirm changes when running in non-interactive mode")
 	}
 
 	return nil
 }
 
-func (o *initProjectOpts) Run(cmd *cobra.Command) error {
+func (o *initProjectOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Use default auth provider.
 	// \todo ability to customize or disable provider?
 	authProvider := auth.NewMetaplayAuthProvider()
@@ -305,7 +305,7 @@ func (o *initProjectOpts) Run(cmd *cobra.Command) error {
 		if metaplaySdkSource == "" {
 			// Download and extract to target project dir.
 			relativePathToSdk = "MetaplaySDK"
-			sdkMetadata, err = downloadAndExtractSdk(tokenSet, o.absoluteProjectPath, sdkVersionInfo)
+			sdkMetadata, err = downloadAndExtractSdk(output, tokenSet, o.absoluteProjectPath, sdkVersionInfo)
 			if err != nil {
 				return err
 			}
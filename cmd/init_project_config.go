@@ -4,6 +4,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -27,6 +28,8 @@ type initProjectConfigOpts struct {
 	flagSharedCodePath    string // Path to the shared code directory
 	flagDotnetRuntimeVer  string // .NET runtime version
 	flagAutoConfirm       bool   // Automatically confirm the 'Does this look correct?'
+	flagNonInteractive    bool   // Never prompt, all values must be given with flags or auto-detected.
+	flagForce             bool   // Overwrite an existing metaplay-project.yaml.
 
 	projectPath              string // User-provided path to project root (relative or absolute).
 	absoluteProjectPath      string // Absolute path to the project root.
@@ -63,7 +66,19 @@ func init() {
 			- .NET runtime version
 
 			The detected paths can be overridden using command-line flags if needed.
-			All paths are stored relative to the project root directory.
+			All paths are stored relative to the project root directory. If the Metaplay SDK
+			or the game backend directory cannot be detected, you are asked to type in its
+			path. The directories are checked to exist before writing the file.
+
+			The project is chosen from the projects you have access to in the Metaplay Portal,
+			unless specified with --project-id. The project's environments are fetched from
+			the portal, and the project must have at least one environment.
+
+			With --non-interactive, nothing is asked: the project must be specified with
+			--project-id, and the paths that cannot be auto-detected with their flags. This is
+			intended for scripted scaffolding.
+
+			An existing metaplay-project.yaml is never overwritten, unless --force is given.
 
 			After detection, the command will:
 			1. Validate the detected paths and settings
@@ -86,6 +101,9 @@ func init() {
 
 			# Auto-approve the operation.
 			metaplay init project-config --yes
+
+			# Scaffold the config in a script, overwriting an existing one.
+			metaplay init project-config --non-interactive --force --project-id=lovely-wombats-build --sdk-path=MetaplaySDK --backend-path=Backend
 		`),
 	}
 
@@ -99,6 +117,8 @@ func init() {
 	flags.StringVar(&o.flagSharedCodePath, "shared-code-path", "", "Path to the shared code directory (default: auto-detect)")
 	flags.StringVar(&o.flagDotnetRuntimeVer, "dotnet-version", "", ".NET runtime version (default: auto-detect)")
 	flags.BoolVar(&o.flagAutoConfirm, "yes", false, "Automatically confirm to the 'Does this look correct?' confirmation")
+	flags.BoolVar(&o.flagNonInteractive, "non-interactive", false, "Never prompt for anything, implies --yes (values must be given with flags or auto-detected)")
+	flags.BoolVar(&o.flagForce, "force", false, "Overwrite an existing metaplay-project.yaml")
 
 	initCmd.AddCommand(cmd)
 }
@@ -121,15 +141,26 @@ func (o *initProjectConfigOpts) Prepare(cmd *cobra.Command, args []string) error
 		}
 	}
 
+	// With --non-interactive, never prompt the user.
+	if o.flagNonInteractive {
+		tui.SetInteractiveMode(false)
+		o.flagAutoConfirm = true
+	}
+
 	// Must be either in interactive mode or specify --yes.
-	if !tui.IsInteractiveMode() && !o.flagAutoConfirm {
+	if !tui.IsInteractive() && !o.flagAutoConfirm {
 		return fmt.Errorf("use --yes to automatically confirm changes when running in non-interactive mode")
 	}
 
+	// The project can only be chosen interactively.
+	if !tui.IsInteractive() && o.flagProjectID == "" {
+		return fmt.Errorf("--project-id must be specified in non-interactive mode")
+	}
+
 	return nil
 }
 
-func (o *initProjectConfigOpts) Run(cmd *cobra.Command) error {
+func (o *initProjectConfigOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -151,7 +182,10 @@ func (o *initProjectConfigOpts) Run(cmd *cobra.Command) error {
 	// Check if metaplay-project.yaml already exists
 	configFilePath := filepath.Join(o.projectPath, metaproj.ConfigFileName)
 	if _, err := os.Stat(configFilePath); err == nil {
-		return fmt.Errorf("project config file %s already exists", configFilePath)
+		if !o.flagForce {
+			return fmt.Errorf("project config file %s already exists, use --force to overwrite it", configFilePath)
+		}
+		log.Warn().Msgf("Project config file %s already exists and will be overwritten", configFilePath)
 	}
 
 	// If Unity project path is not specified, try to find it within the project.
@@ -192,13 +226,30 @@ func (o *initProjectConfigOpts) Run(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
+	if len(environments) == 0 {
+		return fmt.Errorf("project %s has no environments; create an environment in the Metaplay Portal first", targetProject.HumanID)
+	}
 
 	// Detect project paths
-	projectConfig, err := o.detectProjectConfig()
+	projectConfig, err := o.detectProjectConfig(cmd.Context())
 	if err != nil {
 		return err
 	}
 
+	// Check that the directories exist.
+	for _, dir := range []struct {
+		name string
+		path string
+	}{
+		{"Metaplay SDK", projectConfig.metaplaySdkPath},
+		{"game backend", projectConfig.gameBackendPath},
+		{"shared code", projectConfig.sharedCodePath},
+	} {
+		if err := validateProjectSubDir(o.absoluteProjectPath, dir.path); err != nil {
+			return fmt.Errorf("invalid %s directory: %w", dir.name, err)
+		}
+	}
+
 	log.Info().Msg("")
 	log.Info().Msg(styles.RenderTitle("Initialize Project Config"))
 	log.Info().Msg("")
@@ -211,6 +262,7 @@ func (o *initProjectConfigOpts) Run(cmd *cobra.Command) error {
 	log.Info().Msgf("Game backend dir:     %s", styles.RenderTechnical(projectConfig.gameBackendPath))
 	log.Info().Msgf("Game dashboard dir:   %s", styles.RenderTechnical(coalesceString(projectConfig.gameDashboardPath, "n/a")))
 	log.Info().Msgf(".NET runtime version: %s", styles.RenderTechnical(projectConfig.dotnetRuntimeVersion))
+	log.Info().Msgf("Environments:         %s", styles.RenderTechnical(fmt.Sprint(len(environments))))
 	log.Info().Msg("")
 
 	// Confirm from the user that the proposed operation looks correct.
@@ -251,7 +303,7 @@ func (o *initProjectConfigOpts) Run(cmd *cobra.Command) error {
 }
 
 // Detect the project configuration from its files.
-func (o *initProjectConfigOpts) detectProjectConfig() (*detectedProjectConfig, error) {
+func (o *initProjectConfigOpts) detectProjectConfig(ctx context.Context) (*detectedProjectConfig, error) {
 	var metaplaySdkPath string
 	var err error
 
@@ -280,7 +332,10 @@ func (o *initProjectConfigOpts) detectProjectConfig() (*detectedProjectConfig, e
 			return true, nil
 		})
 		if err != nil {
-			return nil, err
+			metaplaySdkPath, err = o.promptProjectDir(ctx, "Metaplay SDK", "sdk-path", err)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -325,6 +380,12 @@ func (o *initProjectConfigOpts) detectProjectConfig() (*detectedProjectConfig, e
 
 			return true, nil
 		})
+		if err != nil {
+			gameBackendPath, err = o.promptProjectDir(ctx, "game backend", "backend-path", err)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Find game-specific dashboard directory.
@@ -458,3 +519,30 @@ func (o *initProjectConfigOpts) detectProjectConfig() (*detectedProjectConfig, e
 		dotnetRuntimeVersion: dotnetRuntimeVersion,
 	}, nil
 }
+
+// Ask the user for the path to a project directory that could not be auto-detected. In
+// non-interactive mode, the path must be given with the flag instead.
+func (o *initProjectConfigOpts) promptProjectDir(ctx context.Context, name string, flagName string, detectErr error) (string, error) {
+	if !tui.IsInteractive() {
+		return "", fmt.Errorf("%w; specify the %s directory with --%s", detectErr, name, flagName)
+	}
+
+	log.Warn().Msgf("Could not detect the %s directory: %v", name, detectErr)
+	return tui.DoTextInputQuestion(ctx, fmt.Sprintf("Path to the %s directory (relative to the project root)", name), "", func(relPath string) error {
+		return validateProjectSubDir(o.absoluteProjectPath, relPath)
+	})
+}
+
+// Check that a directory, given relative to the project root, exists.
+func validateProjectSubDir(rootPath string, relPath string) error {
+	if relPath == "" {
+		return fmt.Errorf("path must not be empty")
+	}
+	if filepath.IsAbs(relPath) {
+		return fmt.Errorf("path must be relative to the project root: %s", relPath)
+	}
+	if !isDirectory(filepath.Join(rootPath, relPath)) {
+		return fmt.Errorf("directory %s does not exist in %s", relPath, rootPath)
+	}
+	return nil
+}
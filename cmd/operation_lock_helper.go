@@ -0,0 +1,79 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Default time to wait for another operation's lock on the environment to clear.
+const defaultOperationLockTimeout = 5 * time.Minute
+
+// Acquire the per-environment operation lock for a mutating operation (deploy, remove, ...).
+// The returned release function must be deferred by the caller. The lock is also
+// released if the CLI is interrupted (eg, with Ctrl-C) while holding it.
+// If the user is not allowed to manage the lock object, a warning is logged and
+// the operation proceeds without the lock.
+func acquireOperationLock(ctx context.Context, targetEnv *envapi.TargetEnvironment, tokenSet *auth.TokenSet, operation string, timeout time.Duration) (func(), error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	holder := envapi.OperationLockHolder{
+		User:       auth.GetTokenSetUserIdentity(tokenSet),
+		Host:       hostname,
+		CliVersion: version.AppVersion,
+		Operation:  operation,
+	}
+
+	lock, err := targetEnv.AcquireOperationLock(ctx, holder, timeout)
+	if apierrors.IsForbidden(err) {
+		log.Warn().Msgf("Not allowed to manage the environment operation lock, proceeding without it: %v", err)
+		return func() {}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Release the lock if the CLI gets interrupted.
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	stopSignals := make(chan struct{})
+	go func() {
+		select {
+		case <-signalChan:
+			log.Warn().Msgf("Interrupted, releasing the environment operation lock")
+			if err := lock.Release(); err != nil {
+				log.Error().Msgf("%v", err)
+			}
+			os.Exit(exitcode.ExitInterrupted)
+		case <-stopSignals:
+		}
+	}()
+
+	// Releasing is idempotent, so the lock can be released early and also deferred.
+	var releaseOnce sync.Once
+	release := func() {
+		releaseOnce.Do(func() {
+			signal.Stop(signalChan)
+			close(stopSignals)
+			if err := lock.Release(); err != nil {
+				log.Warn().Msgf("%v", err)
+			}
+		})
+	}
+	return release, nil
+}
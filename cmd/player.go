@@ -0,0 +1,18 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// playerCmd includes commands for managing players in the game server.
+var playerCmd = &cobra.Command{
+	Use:   "player",
+	Short: "Manage individual players in a game server (eg, for GDPR requests)",
+}
+
+func init() {
+	rootCmd.AddCommand(playerCmd)
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metahttp"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Delete all the data of a single player from the game server.
+type playerDeleteOpts struct {
+	UsePositionalArgs
+
+	argEnvironment string
+	argPlayerID    string
+	flagConfirm    string
+	flagSchedule   bool
+	flagFormat     string
+	flagTimeout    time.Duration
+}
+
+// Response to scheduling a player for deletion.
+type playerScheduledDeletionResponse struct {
+	ScheduledAt string `json:"scheduledAt"`
+}
+
+func init() {
+	o := playerDeleteOpts{}
+
+	args := o.Arguments()
+	args.AddStringArgument(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
+	args.AddStringArgument(&o.argPlayerID, "PLAYER_ID", "ID of the player to delete, eg, 'Player:0123456789'.")
+
+	cmd := &cobra.Command{
+		Use:   "delete ENVIRONMENT PLAYER_ID [flags]",
+		Short: "Delete all the data of a player (eg, for GDPR erasure requests)",
+		Long: renderLong(&o, `
+			Delete all the personal data of a player from the game server in the target
+			environment, eg, to fulfill a GDPR erasure request.
+
+			By default, the deletion is run immediately as a job on the game server, and the
+			CLI waits for the job to complete. With --schedule, the player is instead scheduled
+			for deletion using the game server's configured deletion delay, which allows the
+			deletion to be cancelled from the LiveOps Dashboard until it happens.
+
+			This operation cannot be undone! To protect against accidents, you must confirm
+			the deletion by typing 'delete <PLAYER_ID>' when prompted. In non-interactive mode,
+			pass the player ID with --confirm=<PLAYER_ID> instead.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay player export ...' to export a player's data before deleting it.
+		`),
+		Example: trimIndent(`
+			# Delete player Player:0123456789 from environment tough-falcons.
+			metaplay player delete tough-falcons Player:0123456789
+
+			# Schedule the player for deletion instead of deleting immediately.
+			metaplay player delete tough-falcons Player:0123456789 --schedule
+
+			# Delete in a non-interactive script and output the status as JSON.
+			metaplay player delete tough-falcons Player:0123456789 --confirm=Player:0123456789 --format=json
+		`),
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+	}
+	playerCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagConfirm, "confirm", "", "Confirm the deletion without prompting by repeating the player ID")
+	flags.BoolVar(&o.flagSchedule, "schedule", false, "Schedule the player for deletion after the server's deletion delay instead of deleting immediately")
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format (text or json)")
+	flags.DurationVar(&o.flagTimeout, "timeout", 5*time.Minute, "Maximum time to wait for the deletion to complete")
+}
+
+func (o *playerDeleteOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q; must be either \"text\" or \"json\"", o.flagFormat)
+	}
+	if err := validatePlayerID(o.argPlayerID); err != nil {
+		return err
+	}
+	if o.flagConfirm != "" && o.flagConfirm != o.argPlayerID {
+		return exitcode.Errorf(exitcode.ExitUsage, "--confirm=%s does not match the player ID %s", o.flagConfirm, o.argPlayerID)
+	}
+	if o.flagConfirm == "" && !tui.IsInteractive() {
+		return exitcode.Errorf(exitcode.ExitUsage, "in non-interactive mode, the deletion must be confirmed with --confirm=%s", o.argPlayerID)
+	}
+
+	return nil
+}
+
+func (o *playerDeleteOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Try to resolve the project & auth provider.
+	project, err := tryResolveProject()
+	if err != nil {
+		return err
+	}
+
+	// Resolve environment.
+	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.argEnvironment)
+	if err != nil {
+		return err
+	}
+
+	// Create TargetEnvironment.
+	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+
+	// Create a client for the game server's admin API.
+	client, err := newAdminAPIClient(targetEnv)
+	if err != nil {
+		return err
+	}
+
+	// Ask the user to type in the confirmation, unless given with --confirm.
+	if o.flagConfirm == "" {
+		log.Info().Msg("")
+		log.Info().Msg(styles.RenderTitle("Delete Player"))
+		log.Info().Msg("")
+		log.Info().Msgf("  Environment:        %s", styles.RenderTechnical(envConfig.HumanID))
+		log.Info().Msgf("  Environment type:   %s", renderEnvironmentType(envConfig.Type))
+		log.Info().Msgf("  Player ID:          %s", styles.RenderTechnical(o.argPlayerID))
+		if o.flagSchedule {
+			log.Info().Msgf("  Mode:               %s", styles.RenderTechnical("scheduled"))
+		} else {
+			log.Info().Msgf("  Mode:               %s", styles.RenderTechnical("immediate"))
+		}
+		log.Info().Msg("")
+		log.Warn().Msg("The player's data will be permanently deleted!")
+
+		expected := fmt.Sprintf("delete %s", o.argPlayerID)
+		fmt.Printf("Type '%s' to confirm: ", expected)
+		response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(response) != expected {
+			log.Info().Msg("Cancelled")
+			return nil
+		}
+	}
+
+	status := playerOperationStatus{
+		PlayerID:    o.argPlayerID,
+		Environment: envConfig.HumanID,
+	}
+
+	if o.flagSchedule {
+		// Schedule the player for deletion.
+		response, err := metahttp.Put[playerScheduledDeletionResponse](client, playerAdminPath(adminPlayerScheduledDeletionPath, o.argPlayerID), nil)
+		if err != nil {
+			return fmt.Errorf("failed to schedule player %s for deletion: %w", o.argPlayerID, err)
+		}
+		status.Status = "Scheduled"
+		status.ScheduledAt = response.ScheduledAt
+	} else {
+		// Submit the deletion job and wait for it to complete.
+		if o.flagFormat == "text" {
+			log.Info().Msgf("Deleting player %s from environment %s...", styles.RenderTechnical(o.argPlayerID), styles.RenderTechnical(envConfig.HumanID))
+		}
+		jobID, err := submitAdminJob(client, playerAdminPath(adminPlayerDeletePath, o.argPlayerID), nil)
+		if err != nil {
+			return err
+		}
+		if _, err := waitForAdminJob(cmd.Context(), client, jobID, o.flagTimeout); err != nil {
+			return err
+		}
+		status.Status = adminJobStatusCompleted
+		status.JobID = jobID
+	}
+
+	// Output the status in desired format.
+	if o.flagFormat == "json" {
+		statusJSON, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal status as JSON: %v", err)
+		}
+		fmt.Println(string(statusJSON))
+		return nil
+	}
+
+	if o.flagSchedule {
+		log.Info().Msg(styles.RenderSuccess("✅ Player scheduled for deletion"))
+		if status.ScheduledAt != "" {
+			log.Info().Msgf("  Deletion at: %s", styles.RenderTechnical(status.ScheduledAt))
+		}
+	} else {
+		log.Info().Msg(styles.RenderSuccess("✅ Player deleted successfully"))
+	}
+	return nil
+}
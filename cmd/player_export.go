@@ -0,0 +1,176 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Export all the data of a single player from the game server.
+type playerExportOpts struct {
+	UsePositionalArgs
+
+	argEnvironment string
+	argPlayerID    string
+	flagOutputPath string
+	flagFormat     string
+	flagTimeout    time.Duration
+}
+
+// Status of a player export or deletion, as output with --format=json.
+type playerOperationStatus struct {
+	PlayerID    string `json:"playerId"`
+	Environment string `json:"environment"`
+	Status      string `json:"status"`
+	JobID       string `json:"jobId,omitempty"`
+	OutputPath  string `json:"output,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	Bytes       int    `json:"bytes,omitempty"`
+	ScheduledAt string `json:"scheduledAt,omitempty"`
+}
+
+func init() {
+	o := playerExportOpts{}
+
+	args := o.Arguments()
+	args.AddStringArgument(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
+	args.AddStringArgument(&o.argPlayerID, "PLAYER_ID", "ID of the player to export, eg, 'Player:0123456789'.")
+
+	cmd := &cobra.Command{
+		Use:   "export ENVIRONMENT PLAYER_ID [flags]",
+		Short: "Export all the data of a player (eg, for GDPR data requests)",
+		Long: renderLong(&o, `
+			Export all the personal data of a player from the game server in the target
+			environment, eg, to fulfill a GDPR data access request.
+
+			The export is run as a job on the game server: the job is submitted using the
+			game server's admin API, the CLI waits for the job to complete, and then downloads
+			the result into the output file. A SHA-256 checksum of the result is written next
+			to the output file (into '<output>.sha256') for verifying the integrity of the
+			export later.
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay player delete ...' to delete a player's data.
+			- 'metaplay debug admin-request ...' to make arbitrary requests to the admin API.
+		`),
+		Example: trimIndent(`
+			# Export player Player:0123456789 from environment tough-falcons into player.json.
+			metaplay player export tough-falcons Player:0123456789 --out player.json
+
+			# Output the status of the export as JSON for automation.
+			metaplay player export tough-falcons Player:0123456789 --out player.json --format=json
+		`),
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+	}
+	playerCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVarP(&o.flagOutputPath, "out", "o", "", "Path of the file to write the exported player data to (required)")
+	flags.StringVar(&o.flagFormat, "format", "text", "Output format (text or json)")
+	flags.DurationVar(&o.flagTimeout, "timeout", 5*time.Minute, "Maximum time to wait for the export to complete")
+}
+
+func (o *playerExportOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFormat != "text" && o.flagFormat != "json" {
+		return fmt.Errorf("invalid format %q; must be either \"text\" or \"json\"", o.flagFormat)
+	}
+	if o.flagOutputPath == "" {
+		return fmt.Errorf("output file must be specified with --out")
+	}
+	if err := validatePlayerID(o.argPlayerID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *playerExportOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Try to resolve the project & auth provider.
+	project, err := tryResolveProject()
+	if err != nil {
+		return err
+	}
+
+	// Resolve environment.
+	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.argEnvironment)
+	if err != nil {
+		return err
+	}
+
+	// Create TargetEnvironment.
+	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+
+	// Create a client for the game server's admin API.
+	client, err := newAdminAPIClient(targetEnv)
+	if err != nil {
+		return err
+	}
+
+	// Submit the export job and wait for it to complete.
+	if o.flagFormat == "text" {
+		log.Info().Msgf("Exporting player %s from environment %s...", styles.RenderTechnical(o.argPlayerID), styles.RenderTechnical(envConfig.HumanID))
+	}
+	jobID, err := submitAdminJob(client, playerAdminPath(adminPlayerExportPath, o.argPlayerID), nil)
+	if err != nil {
+		return err
+	}
+	if _, err := waitForAdminJob(cmd.Context(), client, jobID, o.flagTimeout); err != nil {
+		return err
+	}
+
+	// Download the result.
+	result, err := downloadAdminJobResult(client, jobID)
+	if err != nil {
+		return err
+	}
+
+	// Write the result and its checksum.
+	if err := os.WriteFile(o.flagOutputPath, result, 0600); err != nil {
+		return fmt.Errorf("failed to write output file %s: %w", o.flagOutputPath, err)
+	}
+	checksum := sha256.Sum256(result)
+	checksumHex := hex.EncodeToString(checksum[:])
+	checksumPath := o.flagOutputPath + ".sha256"
+	checksumLine := fmt.Sprintf("%s  %s\n", checksumHex, filepath.Base(o.flagOutputPath))
+	if err := os.WriteFile(checksumPath, []byte(checksumLine), 0600); err != nil {
+		return fmt.Errorf("failed to write checksum file %s: %w", checksumPath, err)
+	}
+
+	// Output the status in desired format.
+	if o.flagFormat == "json" {
+		statusJSON, err := json.MarshalIndent(playerOperationStatus{
+			PlayerID:    o.argPlayerID,
+			Environment: envConfig.HumanID,
+			Status:      adminJobStatusCompleted,
+			JobID:       jobID,
+			OutputPath:  o.flagOutputPath,
+			SHA256:      checksumHex,
+			Bytes:       len(result),
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal status as JSON: %v", err)
+		}
+		fmt.Println(string(statusJSON))
+		return nil
+	}
+
+	log.Info().Msg(styles.RenderSuccess("✅ Player data exported successfully"))
+	log.Info().Msgf("  Output file: %s (%d bytes)", styles.RenderTechnical(o.flagOutputPath), len(result))
+	log.Info().Msgf("  SHA-256:     %s", styles.RenderTechnical(checksumHex))
+	return nil
+}
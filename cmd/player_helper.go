@@ -0,0 +1,131 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metahttp"
+	"github.com/rs/zerolog/log"
+)
+
+// Admin API endpoints used by the player commands. Long-running player operations are run
+// as asynchronous jobs on the server: the job is submitted, its status is polled until it
+// completes, and then its result is downloaded.
+const (
+	adminPlayerExportPath            = "/api/players/%s/export"            // POST: submit player export job
+	adminPlayerDeletePath            = "/api/players/%s/delete"            // POST: submit player deletion job
+	adminPlayerScheduledDeletionPath = "/api/players/%s/scheduledDeletion" // PUT: schedule player for deletion
+	adminJobStatusPath               = "/api/jobs/%s"                      // GET: status of a job
+	adminJobResultPath               = "/api/jobs/%s/result"               // GET: result of a completed job
+)
+
+// How often to poll the status of admin API jobs.
+const adminJobPollInterval = 2 * time.Second
+
+// Status of an asynchronous admin API job.
+const (
+	adminJobStatusPending   = "Pending"
+	adminJobStatusRunning   = "Running"
+	adminJobStatusCompleted = "Completed"
+	adminJobStatusFailed    = "Failed"
+)
+
+// Response to submitting an admin API job.
+type adminJobSubmitResponse struct {
+	JobID string `json:"jobId"`
+}
+
+// Status of an admin API job.
+type adminJobStatusResponse struct {
+	JobID  string `json:"jobId"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Validate the player ID, which is expected to be of the form 'Player:0123456789'.
+func validatePlayerID(playerID string) error {
+	if !strings.HasPrefix(playerID, "Player:") || len(playerID) == len("Player:") {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid player ID '%s', expecting the form 'Player:0123456789'", playerID)
+	}
+	return nil
+}
+
+// Format an admin API path with the (path-escaped) player ID.
+func playerAdminPath(pathFormat, playerID string) string {
+	return fmt.Sprintf(pathFormat, url.PathEscape(playerID))
+}
+
+// Create a client for the environment's game server admin API, authenticated with the CLI's token.
+func newAdminAPIClient(targetEnv *envapi.TargetEnvironment) (*metahttp.Client, error) {
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return nil, err
+	}
+	if !envDetails.HasGameServerDeployment() {
+		return nil, fmt.Errorf("environment %s has no admin hostname for the game server", targetEnv.HumanId)
+	}
+	adminAPIBaseURL := fmt.Sprintf("https://%s", envDetails.Deployment.AdminHostname)
+	log.Debug().Msgf("Admin API: %s", adminAPIBaseURL)
+	return metahttp.NewClient(targetEnv.TokenSet, adminAPIBaseURL), nil
+}
+
+// Submit an admin API job by POSTing to the given path. Returns the ID of the job.
+func submitAdminJob(client *metahttp.Client, path string, body any) (string, error) {
+	response, err := metahttp.Post[adminJobSubmitResponse](client, path, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to submit job: %w", err)
+	}
+	if response.JobID == "" {
+		return "", fmt.Errorf("failed to submit job: server did not return a job ID")
+	}
+	log.Debug().Msgf("Submitted admin API job %s", response.JobID)
+	return response.JobID, nil
+}
+
+// Poll the status of an admin API job until it completes, fails, or the timeout is reached.
+func waitForAdminJob(ctx context.Context, client *metahttp.Client, jobID string, timeout time.Duration) (*adminJobStatusResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		status, err := metahttp.Get[adminJobStatusResponse](client, fmt.Sprintf(adminJobStatusPath, url.PathEscape(jobID)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of job %s: %w", jobID, err)
+		}
+		log.Debug().Msgf("Job %s status: %s", jobID, status.Status)
+
+		switch status.Status {
+		case adminJobStatusCompleted:
+			return &status, nil
+		case adminJobStatusFailed:
+			return &status, fmt.Errorf("job %s failed: %s", jobID, coalesceString(status.Error, "unknown error"))
+		case adminJobStatusPending, adminJobStatusRunning:
+			// Keep waiting.
+		default:
+			log.Warn().Msgf("Unknown status '%s' for job %s", status.Status, jobID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return &status, fmt.Errorf("timeout while waiting for job %s to complete (status: %s)", jobID, status.Status)
+		case <-time.After(adminJobPollInterval):
+		}
+	}
+}
+
+// Download the result of a completed admin API job.
+func downloadAdminJobResult(client *metahttp.Client, jobID string) ([]byte, error) {
+	result, err := metahttp.Get[string](client, fmt.Sprintf(adminJobResultPath, url.PathEscape(jobID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download result of job %s: %w", jobID, err)
+	}
+	return []byte(result), nil
+}
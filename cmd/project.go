@@ -0,0 +1,17 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage the project setup",
+}
+
+func init() {
+	rootCmd.AddCommand(projectCmd)
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/gitutil"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/portalapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Generate a CI pipeline for building and deploying the project.
+type projectGenerateCIOpts struct {
+	RequiresProject
+
+	flagProvider    string
+	flagOutput      string
+	flagForce       bool
+	flagUpdate      bool
+	flagDeployTypes []string
+	flagBranch      string
+	flagCliVersion  string
+
+	provider               metaproj.CIProvider
+	deployEnvironmentTypes []portalapi.EnvironmentType
+	cliVersion             string
+}
+
+func init() {
+	o := projectGenerateCIOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "generate-ci [flags]",
+		Short: "Generate a CI pipeline for building and deploying the project",
+		Run:   runCommand(&o),
+		Long: renderLong(&o, `
+			Generate a ready-to-commit CI pipeline that builds the project's server image, pushes it into
+			the project's environments, and optionally deploys it.
+
+			The pipeline:
+			- Runs on pushes to the given branch and on manual triggers.
+			- Installs the Metaplay CLI, pinned to the current CLI version.
+			- Logs in as a machine user using the METAPLAY_CREDENTIALS secret.
+			- Builds the server image with the CI system's build cache enabled.
+			- Pushes the image into all the environments in metaplay-project.yaml.
+			- With --deploy-types, deploys the image into the environments of the given types.
+
+			The pipeline file is written to the CI system's default location in the git repository,
+			unless --output is given. An existing file is not overwritten unless --force is given.
+
+			Use --update to update a previously generated pipeline: only the pinned CLI version and the
+			lists of environments are updated, other changes to the file are preserved.
+
+			Related commands:
+			- 'metaplay update project-environments' to update the environments in metaplay-project.yaml.
+			- 'metaplay auth machine-login' to log in using machine user credentials.
+		`),
+		Example: trimIndent(`
+			# Generate a GitHub Actions workflow that builds and pushes the image.
+			metaplay project generate-ci --provider=github
+
+			# Also deploy into all development and staging environments.
+			metaplay project generate-ci --provider=github --deploy-types=development,staging
+
+			# Generate a GitLab CI pipeline triggered by pushes to the 'develop' branch.
+			metaplay project generate-ci --provider=gitlab --branch=develop
+
+			# Overwrite an existing Azure Pipelines pipeline.
+			metaplay project generate-ci --provider=azure --force
+
+			# Update the CLI version and the environments in a previously generated workflow.
+			metaplay project generate-ci --provider=github --update
+		`),
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagProvider, "provider", "", "CI system to generate the pipeline for: 'github', 'gitlab', or 'azure' (required)")
+	flags.StringVarP(&o.flagOutput, "output", "o", "", "Path of the pipeline file to write (defaults to the CI system's default location in the git repository)")
+	flags.BoolVar(&o.flagForce, "force", false, "Overwrite an existing pipeline file")
+	flags.BoolVar(&o.flagUpdate, "update", false, "Only update the CLI version and the environments in a previously generated pipeline file")
+	flags.StringSliceVar(&o.flagDeployTypes, "deploy-types", nil, "Types of environments to deploy into, eg, 'development,staging' (no deploy job if not specified)")
+	flags.StringVar(&o.flagBranch, "branch", "main", "Branch whose pushes trigger the pipeline")
+	flags.StringVar(&o.flagCliVersion, "cli-version", "", "Version of the Metaplay CLI to pin the pipeline to (defaults to the current version)")
+
+	projectCmd.AddCommand(cmd)
+}
+
+func (o *projectGenerateCIOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagProvider == "" {
+		return exitcode.Errorf(exitcode.ExitUsage, "--provider must be specified, one of: %v", metaproj.CIProviders)
+	}
+	o.provider = metaproj.CIProvider(o.flagProvider)
+	if !slices.Contains(metaproj.CIProviders, o.provider) {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid --provider '%s', must be one of: %v", o.flagProvider, metaproj.CIProviders)
+	}
+
+	if o.flagForce && o.flagUpdate {
+		return exitcode.Errorf(exitcode.ExitUsage, "--force and --update cannot be used together")
+	}
+	if o.flagUpdate && (cmd.Flags().Changed("deploy-types") || cmd.Flags().Changed("branch")) {
+		return exitcode.Errorf(exitcode.ExitUsage, "--deploy-types and --branch cannot be changed with --update, regenerate the pipeline with --force instead")
+	}
+
+	for _, envType := range o.flagDeployTypes {
+		o.deployEnvironmentTypes = append(o.deployEnvironmentTypes, portalapi.EnvironmentType(envType))
+	}
+
+	// Pin to the running CLI version by default.
+	o.cliVersion = o.flagCliVersion
+	if o.cliVersion == "" {
+		if version.IsDevBuild() {
+			return exitcode.Errorf(exitcode.ExitUsage, "this is a development build of the CLI, specify the version to pin the pipeline to with --cli-version")
+		}
+		o.cliVersion = version.AppVersion
+	}
+
+	return nil
+}
+
+func (o *projectGenerateCIOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	project := cmdCtx.Project
+
+	// Resolve the git repository root: the pipeline file lives there and the CLI commands in the
+	// pipeline are run relative to it.
+	projectDir, err := filepath.Abs(project.RelativeDir)
+	if err != nil {
+		return err
+	}
+	repoRoot, err := gitutil.GetRepositoryRoot(projectDir)
+	if err != nil {
+		log.Warn().Msgf("Unable to resolve the git repository root, assuming the project directory: %v", err)
+		repoRoot = projectDir
+	}
+	projectRelDir, err := filepath.Rel(filepath.Clean(repoRoot), projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the project directory relative to the git repository root: %w", err)
+	}
+
+	// Pass a custom project config file (from --config-file) to the CLI in the pipeline.
+	configFileRelPath := ""
+	if configFilePath := project.GetConfigFilePath(); filepath.Base(configFilePath) != metaproj.ConfigFileName {
+		absConfigFilePath, err := filepath.Abs(configFilePath)
+		if err != nil {
+			return err
+		}
+		if configFileRelPath, err = filepath.Rel(filepath.Clean(repoRoot), absConfigFilePath); err != nil {
+			return fmt.Errorf("failed to resolve the project config file relative to the git repository root: %w", err)
+		}
+	}
+
+	outputPath := o.flagOutput
+	if outputPath == "" {
+		outputPath = filepath.Join(repoRoot, metaproj.GetDefaultCIPipelinePath(o.provider))
+	}
+
+	if o.flagUpdate {
+		return o.updatePipeline(project, outputPath)
+	}
+
+	// Refuse to overwrite an existing pipeline unless forced.
+	if _, err := os.Stat(outputPath); err == nil && !o.flagForce {
+		return exitcode.Errorf(exitcode.ExitUsage, "pipeline file %s already exists, use --force to overwrite it or --update to update it", outputPath)
+	}
+
+	content, err := metaproj.GenerateCIPipeline(&project.Config, metaproj.CIPipelineOptions{
+		Provider:               o.provider,
+		CliVersion:             o.cliVersion,
+		ProjectDir:             filepath.ToSlash(projectRelDir),
+		ConfigFile:             filepath.ToSlash(configFileRelPath),
+		Branch:                 o.flagBranch,
+		DeployEnvironmentTypes: o.deployEnvironmentTypes,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for the pipeline file: %w", err)
+	}
+	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write pipeline file: %w", err)
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Generated CI pipeline %s", outputPath)))
+	log.Info().Msg("")
+	log.Info().Msg("Next steps:")
+	log.Info().Msgf("- Create a machine user in the Metaplay portal and store its credentials in the CI secret %s", styles.RenderTechnical("METAPLAY_CREDENTIALS"))
+	log.Info().Msgf("- Review and commit %s", styles.RenderTechnical(outputPath))
+	return nil
+}
+
+// Update the CLI version and the environments in a previously generated pipeline.
+func (o *projectGenerateCIOpts) updatePipeline(project *metaproj.MetaplayProject, outputPath string) error {
+	existing, err := os.ReadFile(outputPath)
+	if os.IsNotExist(err) {
+		return exitcode.Errorf(exitcode.ExitUsage, "pipeline file %s does not exist, generate it first without --update", outputPath)
+	} else if err != nil {
+		return fmt.Errorf("failed to read pipeline file: %w", err)
+	}
+
+	updated, err := metaproj.UpdateCIPipeline(string(existing), &project.Config, o.provider, o.cliVersion)
+	if err != nil {
+		return fmt.Errorf("failed to update pipeline file %s: %w", outputPath, err)
+	}
+
+	if updated == string(existing) {
+		log.Info().Msgf("CI pipeline %s is already up-to-date", outputPath)
+		return nil
+	}
+
+	if err := os.WriteFile(outputPath, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write pipeline file: %w", err)
+	}
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Updated CI pipeline %s", outputPath)))
+	return nil
+}
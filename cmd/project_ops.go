@@ -13,6 +13,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/auth"
 	"github.com/metaplay/cli/pkg/metaproj"
 	"github.com/metaplay/cli/pkg/portalapi"
@@ -140,8 +142,9 @@ func validateUnityProjectPath(rootPath string, unityProjectPath string) error {
 }
 
 // Download the SDK (into the OS temp directory) and extract to the targetProjectPath.
-// Downloads the version specified by versionInfo.
-func downloadAndExtractSdk(tokenSet *auth.TokenSet, targetProjectPath string, versionInfo *portalapi.SdkVersionInfo) (*metaproj.MetaplayVersionMetadata, error) {
+// Downloads the version specified by versionInfo. The download progress is shown in the
+// task output in steps of 10%.
+func downloadAndExtractSdk(output *tui.TaskOutput, tokenSet *auth.TokenSet, targetProjectPath string, versionInfo *portalapi.SdkVersionInfo) (*metaproj.MetaplayVersionMetadata, error) {
 	// Download the SDK archive to temp directory.
 	tmpDir := os.TempDir()
 	portalClient := portalapi.NewClient(tokenSet)
@@ -150,7 +153,17 @@ func downloadAndExtractSdk(tokenSet *auth.TokenSet, targetProjectPath string, ve
 	var err error
 
 	// Download the specific version
-	sdkZipPath, err = portalClient.DownloadSdkByVersionId(tmpDir, versionInfo.ID)
+	lastPercent := int64(-1)
+	sdkZipPath, err = portalClient.DownloadSdkByVersionId(tmpDir, versionInfo.ID, func(downloaded, total int64) {
+		if total <= 0 {
+			return
+		}
+		percent := downloaded * 100 / total
+		if percent/10 != lastPercent/10 {
+			lastPercent = percent
+			output.SetHeaderLines([]string{fmt.Sprintf("Downloaded %s of %s (%d%%)", humanize.Bytes(uint64(downloaded)), humanize.Bytes(uint64(total)), percent)})
+		}
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to download SDK version '%s': %w", versionInfo.Version, err)
 	}
@@ -10,8 +10,10 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/metaplay"
 	"github.com/metaplay/cli/pkg/metaproj"
 	"github.com/metaplay/cli/pkg/portalapi"
 	"github.com/metaplay/cli/pkg/styles"
@@ -83,7 +85,7 @@ func findProjectDirectory() (string, error) {
 		// Check if we've reached the root directory
 		if parentDir == absCurrentDir {
 			// We've reached the root and didn't find the config file
-			return "", errors.New("metaplay-project.yaml file not found in any parent directory, use --project=<path> to point to your project directory")
+			return "", errors.New("metaplay-project.yaml file not found in any parent directory, use --project=<path> to point to your project directory or --config-file=<path> to your project config file")
 		}
 
 		// Move up to the parent directory
@@ -121,48 +123,101 @@ func getAuthProvider(project *metaproj.MetaplayProject, providerName string) (*a
 	return nil, fmt.Errorf("no matching auth provider '%s' found; project has the following providers: %v", providerName, existingAuthProviders)
 }
 
-// Load the metaplay-project.yaml from the specified directory.
-func loadProject(projectDir string) (*metaproj.MetaplayProject, error) {
-	// Load the project config file.
-	projectConfig, err := metaproj.LoadProjectConfigFile(projectDir)
-	if err != nil {
-		return nil, err
+// Locate the project config file. If --config-file is given, use it as-is (overriding
+// --project). Otherwise, locate the metaplay-project.yaml with findProjectDirectory().
+// The path is relative to the current directory, if possible.
+func findProjectConfigFile() (string, error) {
+	if flagProjectConfigFile == "" {
+		projectDir, err := findProjectDirectory()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(projectDir, metaproj.ConfigFileName), nil
 	}
-	log.Debug().Msgf("Project config loaded: %#v", projectConfig)
 
-	// Load version metadata from MetaplaySDK/version.yaml.
-	versionMetadata, err := metaproj.LoadSdkVersionMetadata(filepath.Join(projectDir, projectConfig.SdkRootDir))
+	if flagProjectConfigPath != "" {
+		log.Debug().Msgf("Both --config-file and --project specified, using --config-file '%s'", flagProjectConfigFile)
+	}
+	info, err := os.Stat(flagProjectConfigFile)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("provided config file '%s' does not exist", flagProjectConfigFile)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("provided config file '%s' is a directory, use --project=<path> to point to a project directory", flagProjectConfigFile)
 	}
-	log.Debug().Msgf("Version metadata loaded: %+v", versionMetadata)
 
-	return metaproj.NewMetaplayProject(projectDir, projectConfig, versionMetadata)
+	// The project directory must be relative, so convert an absolute path to a relative one.
+	if filepath.IsAbs(flagProjectConfigFile) {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current working directory: %w", err)
+		}
+		relPath, err := filepath.Rel(currentDir, flagProjectConfigFile)
+		if err != nil {
+			return "", fmt.Errorf("config file '%s' must be on the same drive as the current directory: %w", flagProjectConfigFile, err)
+		}
+		return relPath, nil
+	}
+	return flagProjectConfigFile, nil
+}
+
+// Load the project from the specified project config file.
+func loadProject(configFilePath string) (*metaproj.MetaplayProject, error) {
+	return metaplay.LoadProjectFromConfigFile(configFilePath)
 }
 
-// Try to find the metaplay-project.yaml based on the --project flag, and load
-// it if found. Returns nil, nil if not found.
+// Try to find the project config file based on the --project and --config-file flags, and
+// load it if found. Returns nil, nil if not found.
 func tryResolveProject() (*metaproj.MetaplayProject, error) {
 	// Check if we can find the project file.
-	projectDir, err := findProjectDirectory()
+	configFilePath, err := findProjectConfigFile()
 	if err != nil {
 		return nil, nil
 	}
 
 	// If found the project file, load it.
-	return loadProject(projectDir)
+	return loadProject(configFilePath)
 }
 
-// Locate and load the project config file, based on the --project flag.
+// Locate and load the project config file, based on the --project and --config-file flags.
 func resolveProject() (*metaproj.MetaplayProject, error) {
-	// Find the path with the project config file.
-	projectDir, err := findProjectDirectory()
+	// Find the project config file.
+	configFilePath, err := findProjectConfigFile()
+	if err != nil {
+		return nil, exitcode.New(exitcode.ExitNotFound, err)
+	}
+	log.Debug().Msgf("Project config file located at %s", configFilePath)
+
+	project, err := loadProject(configFilePath)
 	if err != nil {
 		return nil, err
 	}
-	log.Debug().Msgf("Project located in directory %s", projectDir)
 
-	return loadProject(projectDir)
+	if err := checkProjectConfigVersion(project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// Check whether the project config uses an outdated schema version. Outdated configs are
+// reported as a warning listing the changes since, or as an error with --strict.
+func checkProjectConfigVersion(project *metaproj.MetaplayProject) error {
+	if !project.Config.IsConfigVersionOutdated() {
+		return nil
+	}
+
+	configVersion := project.Config.GetConfigVersion()
+	configFileName := filepath.Base(project.GetConfigFilePath())
+	if flagStrict {
+		return fmt.Errorf("%s uses configVersion %d, but the current version is %d; run 'metaplay update project-config' to migrate it", configFileName, configVersion, metaproj.CurrentProjectConfigVersion)
+	}
+
+	stderrLogger.Warn().Msgf("%s %s uses configVersion %d, but the current version is %d. Changes since:", styles.RenderWarning("⚠️"), configFileName, configVersion, metaproj.CurrentProjectConfigVersion)
+	for _, change := range metaproj.GetProjectConfigChangesSince(configVersion) {
+		stderrLogger.Warn().Msgf("  - %s", change)
+	}
+	stderrLogger.Warn().Msgf("Run %s to migrate it.", styles.RenderTechnical("metaplay update project-config"))
+	return nil
 }
 
 // Resolve the environment configuration. First, try the project config, if available.
@@ -176,22 +231,21 @@ func resolveEnvironment(ctx context.Context, project *metaproj.MetaplayProject,
 	if project != nil {
 		// If environment not specified, ask it from the user (if in interactive mode).
 		if environment == "" {
-			if tui.IsInteractiveMode() {
-				envConfig, err = tui.ChooseTargetEnvironmentDialog(project.Config.Environments)
-				if err != nil {
-					return nil, nil, err
-				}
-			} else {
-				return nil, nil, fmt.Errorf("in non-interactive mode, target environment must be explicitly specified")
+			if !tui.IsInteractive() {
+				return nil, nil, exitcode.Errorf(exitcode.ExitUsage, "in non-interactive mode, target environment must be explicitly specified")
 			}
-		} else {
-			// Find target environment.
-			envConfig, err = project.Config.FindEnvironmentConfig(environment)
+			environment, err = tui.SelectEnvironment(project)
 			if err != nil {
 				return nil, nil, err
 			}
 		}
 
+		// Find target environment.
+		envConfig, err = project.Config.FindEnvironmentConfig(environment)
+		if err != nil {
+			return nil, nil, exitcode.New(exitcode.ExitNotFound, err)
+		}
+
 		// Get auth provider for env.
 		authProvider, err := getAuthProvider(project, envConfig.AuthProvider)
 		if err != nil {
@@ -278,7 +332,7 @@ func resolveProjectAndEnvironment(environment string) (*metaproj.MetaplayProject
 	// Find target environment.
 	envConfig, err := project.Config.FindEnvironmentConfig(environment)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, exitcode.New(exitcode.ExitNotFound, err)
 	}
 
 	return project, envConfig, nil
@@ -4,10 +4,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"time"
 
-	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/helmutil"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -16,24 +17,35 @@ import (
 // Remove botclient deployment from target environment.
 type removeBotClientOpts struct {
 	UsePositionalArgs
+	RequiresEnvironment
 
-	argEnvironment string
+	flagTimeout     time.Duration
+	flagLockTimeout time.Duration
+	flagYes         bool
 }
 
 func init() {
 	o := removeBotClientOpts{}
 
 	args := o.Arguments()
-	args.AddStringArgumentOpt(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
+	o.AddEnvironmentArgument(args)
 
 	cmd := &cobra.Command{
-		Use:     "botclient [ENVIRONMENT]",
-		Aliases: []string{"bots", "botclients"},
-		Short:   "Remove the BotClient deployment from the target environment",
-		Run:     runCommand(&o),
+		Use:               "botclient [ENVIRONMENT]",
+		Aliases:           []string{"bots", "botclients"},
+		Short:             "Remove the BotClient deployment from the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Remove the BotClient deployment from the target environment.
 
+			You must confirm the removal by typing in the environment name, unless --yes is
+			specified. In non-interactive mode, --yes is required.
+
+			The command doesn't wait for the release's resources (eg, the BotClient pods) to be
+			deleted: they are deleted in the background after the release is uninstalled. The
+			--timeout only limits how long the chart's uninstall hooks may take.
+
 			{Arguments}
 		`),
 		Example: trimIndent(`
@@ -43,27 +55,20 @@ func init() {
 	}
 
 	removeCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.DurationVar(&o.flagTimeout, "timeout", defaultHelmTimeout, "Timeout for the Helm uninstall hooks")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+	flags.BoolVarP(&o.flagYes, "yes", "y", false, "Remove without asking for confirmation")
 }
 
 func (o *removeBotClientOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *removeBotClientOpts) Run(cmd *cobra.Command) error {
-	// Try to resolve the project & auth provider.
-	project, err := tryResolveProject()
-	if err != nil {
-		return err
-	}
-
-	// Resolve environment.
-	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.argEnvironment)
-	if err != nil {
-		return err
-	}
-
-	// Create TargetEnvironment.
-	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+func (o *removeBotClientOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
 
 	// Get kubeconfig to access the environment.
 	kubeconfigPayload, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
@@ -72,23 +77,39 @@ func (o *removeBotClientOpts) Run(cmd *cobra.Command) error {
 	// Configure Helm.
 	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, envConfig.GetKubernetesNamespace())
 	if err != nil {
-		log.Error().Msgf("Failed to initialize Helm config: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+
+	// Ask the user to confirm by typing in the environment name.
+	err = tui.ConfirmDangerousAction(cmd.Context(), fmt.Sprintf("This will remove the BotClient deployment from environment %s!", envConfig.HumanID), envConfig.HumanID, o.flagYes)
+	if errors.Is(err, tui.ErrCancelled) {
+		log.Info().Msg("Cancelled")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "remove botclient", o.flagLockTimeout)
+	if err != nil {
+		return err
 	}
+	defer releaseLock()
 
 	// Resolve all deployed game server Helm releases.
-	helmReleases, err := helmutil.HelmListReleases(actionConfig, metaplayLoadTestChartName)
+	helmReleases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), metaplayLoadTestChartName)
 	if len(helmReleases) == 0 {
 		return fmt.Errorf("no existing bots deployment found")
 	}
 
 	// Uninstall all Helm releases (multiple releases should not happen but are possible).
 	for _, release := range helmReleases {
+		logReleaseDeployer(release)
 		log.Info().Msgf("Uninstall Helm release %s...", release.Name)
 
-		err := helmutil.UninstallRelease(actionConfig, release)
+		err := helmutil.UninstallRelease(actionConfig, release, o.flagTimeout)
 		if err != nil {
-			return fmt.Errorf("failed to uninstall Helm relese %s: %w", release.Name, err)
+			return reportHelmUninstallTimeout(actionConfig, release.Name, fmt.Errorf("failed to uninstall Helm release %s: %w", release.Name, err))
 		}
 	}
 
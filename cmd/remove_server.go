@@ -4,65 +4,83 @@
 package cmd
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaplay"
+	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 // Remove the Metaplay game server deployment from target environment.
 type removeGameServerOpts struct {
 	UsePositionalArgs
+	RequiresEnvironment
 
-	argEnvironment string
+	argReleaseName  string
+	flagTimeout     time.Duration
+	flagLockTimeout time.Duration
+	flagYes         bool
 }
 
 func init() {
 	o := removeGameServerOpts{}
 
 	args := o.Arguments()
-	args.AddStringArgumentOpt(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
+	o.AddEnvironmentArgument(args)
+	args.AddStringArgumentOpt(&o.argReleaseName, "RELEASE", "Name of the game server Helm release to remove, eg, 'tough-falcons-gameserver'.")
 
 	cmd := &cobra.Command{
-		Use:     "server ENVIRONMENT",
-		Aliases: []string{"game-server"},
-		Short:   "Remove the game server deployment from the target environment",
-		Run:     runCommand(&o),
+		Use:               "server ENVIRONMENT [RELEASE]",
+		Aliases:           []string{"game-server", "gameserver", "game_server"},
+		Short:             "Remove the game server deployment from the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			Remove the game server deployment from the target environment.
 
+			If the environment has multiple game server deployments (Helm releases), the release
+			to remove must be specified. In interactive mode, you can choose it from a list.
+
+			You must confirm the removal by typing in the environment name, unless --yes is
+			specified. In non-interactive mode, --yes is required.
+
+			The command doesn't wait for the release's resources (eg, the game server pods) to
+			be deleted: they are deleted in the background after the release is uninstalled.
+			The --timeout only limits how long the chart's uninstall hooks may take.
+
 			{Arguments}
 		`),
 		Example: trimIndent(`
 			# Remove game server deployment from environment tough-falcons.
 			metaplay remove game-server tough-falcons
+
+			# Remove a specific game server release from environment tough-falcons.
+			metaplay remove game-server tough-falcons tough-falcons-green
 		`),
 	}
 
 	removeCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.DurationVar(&o.flagTimeout, "timeout", defaultHelmTimeout, "Timeout for the Helm uninstall hooks")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+	flags.BoolVarP(&o.flagYes, "yes", "y", false, "Remove without asking for confirmation")
 }
 
 func (o *removeGameServerOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *removeGameServerOpts) Run(cmd *cobra.Command) error {
-	// Try to resolve the project & auth provider.
-	project, err := tryResolveProject()
-	if err != nil {
-		return err
-	}
-
-	// Resolve environment.
-	envConfig, tokenSet, err := resolveEnvironment(cmd.Context(), project, o.argEnvironment)
-	if err != nil {
-		return err
-	}
-
-	// Create TargetEnvironment.
-	targetEnv := envapi.NewTargetEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+func (o *removeGameServerOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
 
 	// Get kubeconfig to access the environment.
 	kubeconfigPayload, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
@@ -71,28 +89,93 @@ func (o *removeGameServerOpts) Run(cmd *cobra.Command) error {
 	// Configure Helm.
 	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, envConfig.GetKubernetesNamespace())
 	if err != nil {
-		log.Error().Msgf("Failed to initialize Helm config: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+
+	// Ask the user to confirm by typing in the environment name.
+	err = tui.ConfirmDangerousAction(cmd.Context(), fmt.Sprintf("This will remove the game server deployment from environment %s!", envConfig.HumanID), envConfig.HumanID, o.flagYes)
+	if errors.Is(err, tui.ErrCancelled) {
+		log.Info().Msg("Cancelled")
+		return nil
+	} else if err != nil {
+		return err
 	}
 
-	// Resolve all deployed game server Helm releases.
-	helmReleases, err := helmutil.HelmListReleases(actionConfig, metaplayGameServerChartName)
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "remove server", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	// Resolve all deployed game server Helm releases, of the chart assigned by the StackAPI.
+	envDetails, err := targetEnv.GetDetails()
+	if err != nil {
+		return err
+	}
+	helmReleases, err := helmutil.HelmListReleases(actionConfig, envConfig.GetKubernetesNamespace(), resolveGameServerChartName(envDetails))
+	if err != nil {
+		return err
+	}
 	if len(helmReleases) == 0 {
 		log.Error().Msgf("No game server deployment found")
-		os.Exit(0)
+		return nil
 	}
 
-	// Uninstall all Helm releases (multiple releases should not happen but are possible).
-	for _, release := range helmReleases {
-		log.Info().Msgf("Remove release %s...", release.Name)
+	// Resolve the release to remove.
+	release, err := selectGameServerRelease(helmReleases, o.argReleaseName)
+	if err != nil {
+		return err
+	}
 
-		err := helmutil.UninstallRelease(actionConfig, release)
-		if err != nil {
-			log.Error().Msgf("Failed to uninstall Helm release %s: %v", release.Name, err)
-			os.Exit(1)
-		}
+	logReleaseDeployer(release)
+	log.Info().Msgf("Remove release %s...", release.Name)
+	err = metaplay.RemoveGameServer(cmd.Context(), metaplay.NewEnvironmentFromTarget(targetEnv), metaplay.RemoveGameServerOptions{
+		ReleaseName: release.Name,
+		Timeout:     o.flagTimeout,
+	})
+	if err != nil {
+		return reportHelmUninstallTimeout(actionConfig, release.Name, err)
 	}
 
 	log.Info().Msgf("Successfully removed game server deployment")
 	return nil
 }
+
+// Resolve the game server release to operate on: use the named release if specified,
+// or the only release if there is just one. With multiple releases, let the user
+// choose one in interactive mode.
+func selectGameServerRelease(releases []*release.Release, releaseName string) (*release.Release, error) {
+	if releaseName != "" {
+		found := helmutil.FindReleaseByName(releases, releaseName)
+		if found == nil {
+			return nil, fmt.Errorf("game server release '%s' not found; existing releases: %s", releaseName, strings.Join(helmutil.GetReleaseNames(releases), ", "))
+		}
+		return found, nil
+	}
+
+	if len(releases) == 1 {
+		return releases[0], nil
+	}
+
+	if !tui.IsInteractive() {
+		return nil, fmt.Errorf("multiple game server releases found (%s), specify the release to use", strings.Join(helmutil.GetReleaseNames(releases), ", "))
+	}
+
+	selected, err := tui.ChooseFromListDialog(
+		"Select Game Server Release",
+		releases,
+		func(rel **release.Release) (string, string) {
+			description := fmt.Sprintf("chart %s, %s", (*rel).Chart.Metadata.Version, (*rel).Info.Status)
+			if deployer := describeReleaseDeployer(*rel); deployer != "" {
+				description += fmt.Sprintf(", deployed by %s", deployer)
+			}
+			return (*rel).Name, description
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msgf(" %s %s", styles.RenderSuccess("✓"), (*selected).Name)
+	return *selected, nil
+}
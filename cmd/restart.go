@@ -0,0 +1,18 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// restartCmd includes commands for restarting components deployed in the cloud.
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart deployed components in the cloud",
+}
+
+func init() {
+	rootCmd.AddCommand(restartCmd)
+}
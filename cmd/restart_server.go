@@ -0,0 +1,151 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Restart the game server in the target environment.
+type restartGameServerOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagWait        bool
+	flagTimeout     time.Duration
+	flagYes         bool
+	flagLockTimeout time.Duration
+}
+
+func init() {
+	o := restartGameServerOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "server ENVIRONMENT [flags]",
+		Aliases:           []string{"srv", "game-server", "gameserver", "game_server"},
+		Short:             "Restart the game server in the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Trigger a rolling restart of the game server in the target environment, eg, to
+			pick up changes to configuration or secrets. The game server pods are replaced
+			one by one, the same way 'kubectl rollout restart' does it.
+
+			The environment is confirmed before restarting, unless --yes is specified. In
+			non-interactive mode, --yes is required.
+
+			With --wait, the command waits until all the pods have been restarted and are
+			ready (or --timeout is reached).
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay get pods ...' to see the state of the pods.
+			- 'metaplay debug server-status ...' to check that the game server is healthy.
+		`),
+		Example: trimIndent(`
+			# Restart the game server in environment tough-falcons.
+			metaplay restart server tough-falcons
+
+			# Restart without confirmation and wait for the restart to complete.
+			metaplay restart server tough-falcons --yes --wait
+		`),
+	}
+	restartCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVar(&o.flagWait, "wait", false, "Wait for the restart to complete")
+	flags.DurationVar(&o.flagTimeout, "timeout", 10*time.Minute, "How long to wait for the restart to complete with --wait")
+	flags.BoolVarP(&o.flagYes, "yes", "y", false, "Restart without asking for confirmation")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+}
+
+func (o *restartGameServerOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if !o.flagYes && !tui.IsInteractive() {
+		return fmt.Errorf("in non-interactive mode, --yes must be specified to restart the game server")
+	}
+	if cmd.Flags().Changed("timeout") && !o.flagWait {
+		return fmt.Errorf("--timeout can only be used with --wait")
+	}
+	return nil
+}
+
+func (o *restartGameServerOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Resolve the game server and its shard sets.
+	gameServer, err := targetEnv.GetGameServer(cmd.Context())
+	if err != nil {
+		return err
+	}
+	shardSetNames := []string{}
+	for _, shardSet := range gameServer.ShardSets {
+		shardSetNames = append(shardSetNames, shardSet.Name)
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Restart Game Server"))
+	log.Info().Msg("")
+	log.Info().Msgf("Target environment:")
+	log.Info().Msgf("  Name:               %s", styles.RenderTechnical(envConfig.Name))
+	log.Info().Msgf("  ID:                 %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("  Type:               %s", renderEnvironmentType(envConfig.Type))
+	log.Info().Msgf("  Shard sets:         %s", styles.RenderTechnical(strings.Join(shardSetNames, ", ")))
+	log.Info().Msg("")
+
+	// Ask for confirmation.
+	if !o.flagYes {
+		confirmed, err := tui.DoConfirmQuestion(cmd.Context(), fmt.Sprintf("Restart the game server in environment %s?", envConfig.HumanID))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			log.Info().Msg("Cancelled")
+			return nil
+		}
+	}
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "restart server", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	taskRunner := tui.NewTaskRunner()
+
+	taskRunner.AddTask("Trigger rolling restart of the game server", func(output *tui.TaskOutput) error {
+		return gameServer.RestartShardSets(cmd.Context())
+	})
+
+	if o.flagWait {
+		taskRunner.AddTask("Wait for the game server pods to restart", func(output *tui.TaskOutput) error {
+			return gameServer.WaitForShardSetRollouts(cmd.Context(), o.flagTimeout)
+		})
+	}
+
+	// Run the tasks.
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	if o.flagWait {
+		log.Info().Msg(styles.RenderSuccess("✅ Game server successfully restarted!"))
+	} else {
+		log.Info().Msg(styles.RenderSuccess("✅ Game server restart triggered!"))
+		log.Info().Msgf("Use %s to follow the progress.", styles.RenderTechnical(fmt.Sprintf("metaplay get pods %s", envConfig.HumanID)))
+	}
+	return nil
+}
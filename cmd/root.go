@@ -5,17 +5,22 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-isatty"
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/internal/version"
 	"github.com/metaplay/cli/pkg/common"
+	"github.com/metaplay/cli/pkg/metahttp"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/muesli/termenv"
 	"github.com/rs/zerolog"
@@ -27,10 +32,18 @@ import (
 var stderrLogger zerolog.Logger
 
 var flagProjectConfigPath string // Path to Metaplay project (--project or -p).
+var flagProjectConfigFile string // Path to the project config file, overrides --project (--config-file).
+var flagStrict bool              // Treat project config warnings as errors (--strict).
 var flagVerbose bool             // Verbose logging with (--verbose or -v).
 var flagColorMode string         // Color usage mode for output (yes, no, auto).
+var flagPlain bool               // Plain line-based output without TUI elements (--plain).
+var flagProgress string          // Progress rendering mode for long operations (auto, json, or plain as an alias for --plain).
+var flagContainerRuntime string  // Container runtime for building and pushing images (docker, podman).
 var skipAppVersionCheck bool     // Skip check for a new version of the CLI (--skip-version-check)
 
+// Value of --progress that is an alias for --plain.
+const progressModePlainAlias = "plain"
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "metaplay",
@@ -50,103 +63,152 @@ var rootCmd = &cobra.Command{
 		MyGame$ metaplay debug logs
 	`),
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		// Determine if colors can be used
-		hasTerminal := isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
-
-		// Determine whether to use colors.
-		colorMode := coalesceString(os.Getenv("METAPLAYCLI_COLOR"), flagColorMode)
-		var useColors bool
-		if isTruthy(colorMode) {
-			useColors = true
-		} else if isFalsy(colorMode) {
-			useColors = false
-		} else {
-			if colorMode != "auto" {
-				fmt.Printf("ERROR: Invalid color mode (--color or METAPLAYCLI_COLOR): %s. Allowed values are yes/no/auto.\n", flagColorMode)
-				os.Exit(2)
-			}
-			useColors = hasTerminal
-		}
+		// Only initialize once: an unknown command is reported (see handleUnknownCommand())
+		// before executing the command line.
+		initCommandOnce.Do(func() { initCommand(cmd) })
+	},
+}
 
-		// Configure lipgloss to use/not use colors.
-		if useColors {
-			lipgloss.SetColorProfile(termenv.TrueColor)
-		} else {
-			lipgloss.SetColorProfile(termenv.Ascii)
-		}
+// Guards initCommand() from running more than once per process.
+var initCommandOnce sync.Once
+
+// Initialize the output, logging, and interactive mode, and show the CLI banner, before
+// running the command.
+func initCommand(cmd *cobra.Command) {
+	// Determine if colors can be used
+	hasTerminal := isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+
+	// Resolve whether to use plain output: no spinners, prompts, or colors (unless explicitly
+	// enabled). Not having a terminal implies plain output. '--progress=plain' is an alias
+	// for --plain.
+	progressModeStr := coalesceString(os.Getenv("METAPLAYCLI_PROGRESS"), flagProgress)
+	isPlain := isTruthy(os.Getenv("METAPLAYCLI_PLAIN")) || flagPlain || progressModeStr == progressModePlainAlias
+	if progressModeStr == progressModePlainAlias {
+		progressModeStr = string(tui.ProgressModeAuto)
+	}
 
-		// Resolve whether using verbose mode
-		isVerbose := isTruthy(os.Getenv("METAPLAYCLI_VERBOSE")) || flagVerbose
-
-		// Initialize zerolog
-		initLogger(useColors, isVerbose)
-
-		// Check for common CI environment variables
-		isCI := os.Getenv("CI") != "" ||
-			os.Getenv("GITHUB_ACTIONS") != "" ||
-			os.Getenv("GITLAB_CI") != "" ||
-			os.Getenv("BITBUCKET_BUILD_NUMBER") != "" ||
-			os.Getenv("CIRCLECI") != "" ||
-			os.Getenv("TRAVIS") != "" ||
-			os.Getenv("APPVEYOR") != "" ||
-			os.Getenv("TEAMCITY_VERSION") != "" ||
-			os.Getenv("BUILDKITE") != "" ||
-			os.Getenv("HUDSON_URL") != "" ||
-			os.Getenv("JENKINS_URL") != "" ||
-			os.Getenv("BAMBOO_AGENT_HOME") != "" ||
-			os.Getenv("TFS_BUILD") != "" ||
-			os.Getenv("NETLIFY") != "" ||
-			os.Getenv("NOW_BUILDER") != ""
-
-		// Determine if the CLI is running in interactive mode:
-		// - Interactive mode requires a terminal
-		// - Being in CI disabled interactive mode
-		// - Verbose mode disables interactive mode
-		isInteractive := true
-		modeStr := "interactive mode"
-		if !hasTerminal {
-			modeStr = "non-interactive mode (no terminal)"
-			isInteractive = false
-		} else if isVerbose {
-			modeStr = "non-interactive mode (verbose)"
-			isInteractive = false
-		} else if isCI {
-			modeStr = "non-interactive mode (CI detected)"
-			isInteractive = false
+	// Determine whether to use colors.
+	colorMode := coalesceString(os.Getenv("METAPLAYCLI_COLOR"), flagColorMode)
+	var useColors bool
+	if isTruthy(colorMode) {
+		useColors = true
+	} else if isFalsy(colorMode) {
+		useColors = false
+	} else {
+		if colorMode != "auto" {
+			fmt.Printf("ERROR: Invalid color mode (--color or METAPLAYCLI_COLOR): %s. Allowed values are yes/no/auto.\n", flagColorMode)
+			os.Exit(exitcode.ExitUsage)
 		}
+		useColors = hasTerminal && !isPlain
+	}
 
-		tui.SetInteractiveMode(isInteractive)
+	// Configure lipgloss to use/not use colors.
+	if useColors {
+		lipgloss.SetColorProfile(termenv.TrueColor)
+	} else {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
 
-		// Silence the boilerplate for commands where it makes no sense.
-		parentCmd := cmd.Parent()
-		isCompletion := parentCmd != nil && parentCmd.Name() == "completion"
-		isExecCredential := cmd.Name() == "kubernetes-execcredential"
-		if isCompletion || isExecCredential {
-			return
-		}
+	// Resolve whether using verbose mode
+	isVerbose := isTruthy(os.Getenv("METAPLAYCLI_VERBOSE")) || flagVerbose
+
+	// Initialize zerolog
+	initLogger(useColors, isVerbose)
+
+	// Check for common CI environment variables
+	isCI := os.Getenv("CI") != "" ||
+		os.Getenv("GITHUB_ACTIONS") != "" ||
+		os.Getenv("GITLAB_CI") != "" ||
+		os.Getenv("BITBUCKET_BUILD_NUMBER") != "" ||
+		os.Getenv("CIRCLECI") != "" ||
+		os.Getenv("TRAVIS") != "" ||
+		os.Getenv("APPVEYOR") != "" ||
+		os.Getenv("TEAMCITY_VERSION") != "" ||
+		os.Getenv("BUILDKITE") != "" ||
+		os.Getenv("HUDSON_URL") != "" ||
+		os.Getenv("JENKINS_URL") != "" ||
+		os.Getenv("BAMBOO_AGENT_HOME") != "" ||
+		os.Getenv("TFS_BUILD") != "" ||
+		os.Getenv("NETLIFY") != "" ||
+		os.Getenv("NOW_BUILDER") != ""
+
+	// Determine if the CLI is running in interactive mode:
+	// - Interactive mode requires a terminal
+	// - Being in CI disabled interactive mode
+	// - Plain mode disables interactive mode
+	// - Verbose mode disables interactive mode
+	isInteractive := true
+	modeStr := "interactive mode"
+	if !hasTerminal {
+		modeStr = "non-interactive mode (no terminal)"
+		isInteractive = false
+	} else if isPlain {
+		modeStr = "non-interactive mode (plain)"
+		isInteractive = false
+	} else if isVerbose {
+		modeStr = "non-interactive mode (verbose)"
+		isInteractive = false
+	} else if isCI {
+		modeStr = "non-interactive mode (CI detected)"
+		isInteractive = false
+	}
 
-		// Show CLI version & whether in interactive mode
-		stderrLogger.Info().Msgf(styles.RenderMuted("Metaplay CLI %s, %s"), version.AppVersion, modeStr)
+	tui.SetInteractiveMode(isInteractive)
 
-		// Log about non-default portal being used.
-		if common.PortalBaseURL != common.DefaultPortalBaseURL {
-			stderrLogger.Info().Msgf(styles.RenderMuted("Portal base URL: %s"), common.PortalBaseURL)
-		}
+	// Resolve how the progress of long operations is rendered.
+	progressMode, err := tui.ParseProgressMode(progressModeStr)
+	if err != nil {
+		fmt.Printf("ERROR: Invalid progress mode (--progress or METAPLAYCLI_PROGRESS): %v\n", err)
+		os.Exit(exitcode.ExitUsage)
+	}
+	tui.SetProgressMode(progressMode)
 
-		// Check for new CLI version available.
-		isUpdateCliCmd := parentCmd != nil && parentCmd.Name() == "update" && cmd.Use == "cli"
-		if !skipAppVersionCheck && !isUpdateCliCmd {
-			version.CheckVersion(&stderrLogger)
-		}
-	},
+	// Route server-driven warnings (eg, outdated CLI) to stderr.
+	metahttp.SetWarningLogger(&stderrLogger)
+
+	// Start a new request ID to correlate all the HTTP requests made by this command.
+	requestID := metahttp.ResetCommandRequestID()
+	log.Debug().Msgf("Request ID: %s", requestID)
+
+	// Silence the boilerplate for commands where it makes no sense.
+	parentCmd := cmd.Parent()
+	isCompletion := (parentCmd != nil && parentCmd.Name() == "completion") || cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+	isExecCredential := cmd.Name() == "kubernetes-execcredential"
+	if isCompletion || isExecCredential {
+		return
+	}
+
+	// Delete expired credential files written by earlier commands.
+	autoPruneCredentials()
+
+	// Show CLI version & whether in interactive mode
+	stderrLogger.Info().Msgf(styles.RenderMuted("Metaplay CLI %s, %s"), version.AppVersion, modeStr)
+
+	// Log about non-default portal being used.
+	if common.PortalBaseURL != common.DefaultPortalBaseURL {
+		stderrLogger.Info().Msgf(styles.RenderMuted("Portal base URL: %s"), common.PortalBaseURL)
+	}
+
+	// Check for new CLI version available.
+	isUpdateCliCmd := parentCmd != nil && parentCmd.Name() == "update" && cmd.Use == "cli"
+	if !skipAppVersionCheck && !isUpdateCliCmd {
+		version.CheckVersion(&stderrLogger)
+	}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	// Report unknown commands with suggestions, and possibly run the suggested command instead.
+	if suggestedArgs := handleUnknownCommand(context.Background(), rootCmd, os.Args[1:]); suggestedArgs != nil {
+		rootCmd.SetArgs(suggestedArgs)
+	}
+
+	// Errors returned by Cobra itself are about invalid command lines (unknown
+	// commands or flags), command failures exit directly from runCommand().
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitcode.ExitUsage)
 	}
 }
 
@@ -155,8 +217,13 @@ func init() {
 	flags := rootCmd.PersistentFlags()
 	flags.BoolVarP(&flagVerbose, "verbose", "v", false, "Enable verbose logging, useful for troubleshooting [env: METAPLAYCLI_VERBOSE]")
 	flags.StringVarP(&flagProjectConfigPath, "project", "p", "", "Path to the to project directory (where metaplay-project.yaml is located)")
+	flags.StringVar(&flagProjectConfigFile, "config-file", "", "Path to the project config file to use instead of metaplay-project.yaml, overrides --project (paths in the file are relative to its directory)")
+	flags.BoolVar(&flagStrict, "strict", false, "Treat project config warnings, eg, an outdated configVersion, as errors")
 	flags.BoolVar(&skipAppVersionCheck, "skip-version-check", false, "Skip the check for a new CLI version being available")
 	flags.StringVar(&flagColorMode, "color", "auto", "Should the output be colored (yes/no/auto)? [env: METAPLAYCLI_COLOR]")
+	flags.StringVar(&flagProgress, "progress", "auto", "How to show the progress of long operations: 'auto' (live status area with a terminal), 'plain' (same as --plain), or 'json' (log lines, and progress events as JSON lines on stderr) [env: METAPLAYCLI_PROGRESS]")
+	flags.StringVar(&flagContainerRuntime, "container-runtime", "", "Container runtime for building, running, and pushing images: 'docker' or 'podman', auto-detected if not specified [env: METAPLAYCLI_CONTAINER_RUNTIME]")
+	flags.BoolVar(&flagPlain, "plain", false, "Plain line-based output without spinners, colors, or interactive prompts; implied when the output is not a terminal [env: METAPLAYCLI_PLAIN]")
 
 	// Add command groups to root.
 	coreGroup := &cobra.Group{
@@ -185,6 +252,7 @@ func init() {
 
 	// Manage project:
 	initCmd.GroupID = "project"
+	configCmd.GroupID = "project"
 	updateCmd.GroupID = "project"
 
 	// Manage resources:
@@ -195,10 +263,14 @@ func init() {
 
 	// Other:
 	authCmd.GroupID = "other"
+	supportCmd.GroupID = "other"
 	versionCmd.GroupID = "other"
 	rootCmd.SetHelpCommandGroupID("other")
 	rootCmd.SetCompletionCommandGroupID("other")
 
+	// Suggest the nearest valid flag for unknown flags.
+	rootCmd.SetFlagErrorFunc(suggestFlagOnError)
+
 	// Initialize colored help templates
 	initColoredHelpTemplates(rootCmd)
 }
@@ -253,8 +325,12 @@ func (w *coloredLineConsoleWriter) Write(p []byte) (n int, err error) {
 	}
 	buf.WriteString("\n")
 
-	// Write to the output
-	return w.Out.Write(buf.Bytes())
+	// Write to the output. Report the length of the input event (not the rendered
+	// line) so zerolog doesn't consider it a short write.
+	if _, err := w.Out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
 
 // Initialize zerolog:
@@ -262,39 +338,65 @@ func (w *coloredLineConsoleWriter) Write(p []byte) (n int, err error) {
 // always enabled.
 // In non-verbose mode, the output is plain-text only, so its compatible with
 // piping to `jq` and other tools. Colors are auto-detected based on the TTY used.
+// In both modes, all output (including debug level) is also written into the
+// persistent debug log file in the CLI state directory.
 func initLogger(useColors, isVerbose bool) {
+	// Always log everything: the console writers filter by their own level.
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	zerolog.TimeFieldFormat = "2006-01-02 15:04:05.000"
+
+	// Open the persistent debug log file (best effort, the CLI works without it).
+	var debugLogWriter io.Writer
+	debugLogFile, err := openDebugLogFile()
+	if err == nil {
+		debugLogWriter = zerolog.ConsoleWriter{
+			Out:        debugLogFile,
+			NoColor:    true,
+			TimeFormat: "2006-01-02 15:04:05.000",
+		}
+	}
+
+	// Combine a console writer with the debug log file writer.
+	withDebugLog := func(consoleWriter io.Writer, consoleLevel zerolog.Level) io.Writer {
+		filtered := &zerolog.FilteredLevelWriter{
+			Writer: zerolog.LevelWriterAdapter{Writer: consoleWriter},
+			Level:  consoleLevel,
+		}
+		if debugLogWriter == nil {
+			return filtered
+		}
+		return zerolog.MultiLevelWriter(filtered, debugLogWriter)
+	}
+
 	if isVerbose {
 		// Verbose logging: Debug level with timestamps and log level included
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-		zerolog.TimeFieldFormat = "2006-01-02 15:04:05.000"
 		stdoutWriter := zerolog.ConsoleWriter{
 			Out:        os.Stdout,
 			TimeFormat: "2006-01-02 15:04:05.000",
 		}
-		log.Logger = zerolog.New(stdoutWriter).With().Timestamp().Logger()
+		log.Logger = zerolog.New(withDebugLog(stdoutWriter, zerolog.DebugLevel)).With().Timestamp().Logger()
 
 		stderrWriter := zerolog.ConsoleWriter{
 			Out:        os.Stderr,
 			TimeFormat: "2006-01-02 15:04:05.000",
 		}
-		stderrLogger = zerolog.New(stderrWriter).With().Timestamp().Logger()
+		stderrLogger = zerolog.New(withDebugLog(stderrWriter, zerolog.DebugLevel)).With().Timestamp().Logger()
 	} else {
 		// Non-verbose logging: Info level with no decorations
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
 		// Custom console stdoutWriter with colored lines
 		stdoutWriter := &coloredLineConsoleWriter{
 			Out:       os.Stdout,
 			UseColors: useColors,
 		}
-		log.Logger = zerolog.New(stdoutWriter).With().Logger()
+		log.Logger = zerolog.New(withDebugLog(stdoutWriter, zerolog.InfoLevel)).With().Timestamp().Logger()
 
 		// Custom console stderrWriter with colored lines
 		stderrWriter := &coloredLineConsoleWriter{
 			Out:       os.Stderr,
 			UseColors: useColors,
 		}
-		stderrLogger = zerolog.New(stderrWriter).With().Logger()
+		stderrLogger = zerolog.New(withDebugLog(stderrWriter, zerolog.InfoLevel)).With().Timestamp().Logger()
 	}
 }
 
@@ -302,7 +404,7 @@ func initLogger(useColors, isVerbose bool) {
 // structs implementing commands to see how this should be used.
 type CommandOptions interface {
 	Prepare(cmd *cobra.Command, args []string) error
-	Run(cmd *cobra.Command) error
+	Run(cmd *cobra.Command, cmdCtx *CommandContext) error
 }
 
 // Get the UsePositionalArgs for the given command
@@ -327,37 +429,53 @@ func getUsePositionalArgs(opts CommandOptions) (UsePositionalArgs, bool) {
 }
 
 // Create a Cobra.Run compatible runner function for a command implementing
-// CommandOptions.
+// CommandOptions. The process is only exited here, at the top level: the command
+// itself reports failures by returning errors carrying the exit code.
 func runCommand(opts CommandOptions) func(cmd *cobra.Command, args []string) {
 	return func(cmd *cobra.Command, args []string) {
-		posArgs, hasPosArgs := getUsePositionalArgs(opts)
-		if hasPosArgs {
-			err := posArgs.Arguments().ParseCommandLine(args)
-			if err != nil {
-				log.Error().Msgf("Expected usage: %s", cmd.UseLine())
-				log.Warn().Msgf("%s", posArgs.args.GetHelpText())
-				log.Info().Msgf("Run with --help flag for full help.")
-				os.Exit(2)
+		err := runCommandOptions(cmd, opts, args)
+		if err != nil && !exitcode.IsSilent(err) {
+			log.Error().Msgf("ERROR: %v", err)
+			if metahttp.HasSentRequests() {
+				log.Info().Msgf(styles.RenderMuted("Request ID (include this when contacting support): %s"), metahttp.GetCommandRequestID())
 			}
-		} else {
-			// \todo implement me: expect no args provided
 		}
-
-		// Prepare the command.
-		err := opts.Prepare(cmd, args)
 		if err != nil {
-			log.Info().Msgf("%s", cmd.UsageString())
-			log.Error().Msgf("USAGE ERROR: %v", err)
-			os.Exit(2)
+			os.Exit(exitcode.FromError(err))
 		}
+	}
+}
 
-		// Run the command.
-		err = opts.Run(cmd)
+// Parse the arguments, prepare, and run the command. Usage errors are reported here and
+// returned as silent ExitUsage errors; other errors are returned for the caller to report.
+func runCommandOptions(cmd *cobra.Command, opts CommandOptions, args []string) error {
+	posArgs, hasPosArgs := getUsePositionalArgs(opts)
+	if hasPosArgs {
+		err := posArgs.Arguments().ParseCommandLine(args)
 		if err != nil {
-			log.Error().Msgf("ERROR: %v", err)
-			os.Exit(1)
+			log.Error().Msgf("Expected usage: %s", cmd.UseLine())
+			log.Warn().Msgf("%s", posArgs.args.GetHelpText())
+			log.Info().Msgf("Run with --help flag for full help.")
+			return exitcode.Silent(exitcode.ExitUsage)
 		}
+	} else {
+		// \todo implement me: expect no args provided
+	}
+
+	// Prepare the command.
+	err := opts.Prepare(cmd, args)
+	if err != nil {
+		log.Info().Msgf("%s", cmd.UsageString())
+		log.Error().Msgf("USAGE ERROR: %v", err)
+		return exitcode.Silent(exitcode.ExitUsage)
+	}
+
+	// Resolve the project, login, and environment required by the command, and run it.
+	cmdCtx, err := resolveCommandContext(cmd.Context(), opts)
+	if err != nil {
+		return err
 	}
+	return opts.Run(cmd, cmdCtx)
 }
 
 // Trim the indentation from the beginning of each line in the string.
@@ -0,0 +1,18 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// scaleCmd includes commands for scaling components deployed in the cloud.
+var scaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "Scale deployed components in the cloud",
+}
+
+func init() {
+	rootCmd.AddCommand(scaleCmd)
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Scaling the game server beyond this many replicas requires --force, to protect against typos.
+const maxGameServerReplicasWithoutForce = 16
+
+// Scale the game server in the target environment.
+type scaleGameServerOpts struct {
+	UsePositionalArgs
+	RequiresEnvironment
+
+	flagReplicas    int
+	flagShardSet    string
+	flagForce       bool
+	flagWait        bool
+	flagTimeout     time.Duration
+	flagLockTimeout time.Duration
+}
+
+func init() {
+	o := scaleGameServerOpts{}
+
+	args := o.Arguments()
+	o.AddEnvironmentArgument(args)
+
+	cmd := &cobra.Command{
+		Use:               "server ENVIRONMENT --replicas N [flags]",
+		Aliases:           []string{"srv", "game-server", "gameserver", "game_server"},
+		Short:             "Scale the number of game server pods in the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
+		Long: renderLong(&o, `
+			Scale the number of game server pods (replicas) in the target environment without
+			a full redeploy, eg, to scale up for a load test and back down afterwards.
+
+			The node count of the shard set is updated in the game server resource and the
+			game server operator then adds or removes pods to match. The change is temporary:
+			the next 'metaplay deploy server' resets the node count to what is configured in
+			the Helm values.
+
+			If the game server has multiple shard sets, the shard set to scale must be
+			specified with --shard-set. The replica count must be within the minimum and
+			maximum node counts configured for the shard set. Scaling above 16 replicas
+			requires --force.
+
+			With --wait, the command waits until the pods have been added or removed and are
+			ready (or --timeout is reached).
+
+			{Arguments}
+
+			Related commands:
+			- 'metaplay get pods ...' to see the state of the pods.
+			- 'metaplay deploy server ...' to change the node count permanently.
+		`),
+		Example: trimIndent(`
+			# Scale the game server in environment tough-falcons to 4 pods.
+			metaplay scale server tough-falcons --replicas 4
+
+			# Scale a specific shard set and wait for the pods to be ready.
+			metaplay scale server tough-falcons --shard-set service --replicas 2 --wait
+		`),
+	}
+	scaleCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.IntVar(&o.flagReplicas, "replicas", -1, "Desired number of game server pods (required)")
+	flags.StringVar(&o.flagShardSet, "shard-set", "", "Name of the shard set to scale (required if the game server has multiple shard sets)")
+	flags.BoolVar(&o.flagForce, "force", false, fmt.Sprintf("Allow scaling to more than %d replicas", maxGameServerReplicasWithoutForce))
+	flags.BoolVar(&o.flagWait, "wait", false, "Wait for the scaling to complete")
+	flags.DurationVar(&o.flagTimeout, "timeout", 10*time.Minute, "How long to wait for the scaling to complete with --wait")
+	flags.DurationVar(&o.flagLockTimeout, "lock-timeout", defaultOperationLockTimeout, "How long to wait for another operation on the environment to finish")
+}
+
+func (o *scaleGameServerOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if !cmd.Flags().Changed("replicas") {
+		return exitcode.Errorf(exitcode.ExitUsage, "the desired number of replicas must be specified with --replicas")
+	}
+	if o.flagReplicas < 0 {
+		return exitcode.Errorf(exitcode.ExitUsage, "invalid --replicas=%d: must be zero or greater", o.flagReplicas)
+	}
+	if o.flagReplicas > maxGameServerReplicasWithoutForce && !o.flagForce {
+		return exitcode.Errorf(exitcode.ExitUsage, "refusing to scale to %d replicas (more than %d); use --force if this is intended", o.flagReplicas, maxGameServerReplicasWithoutForce)
+	}
+	if cmd.Flags().Changed("timeout") && !o.flagWait {
+		return fmt.Errorf("--timeout can only be used with --wait")
+	}
+	return nil
+}
+
+func (o *scaleGameServerOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	envConfig := cmdCtx.EnvConfig
+	targetEnv := cmdCtx.TargetEnv
+
+	// Resolve the game server and the shard set to scale.
+	gameServer, err := targetEnv.GetGameServer(cmd.Context())
+	if err != nil {
+		return err
+	}
+	shardSetName, err := resolveShardSetName(gameServer, o.flagShardSet)
+	if err != nil {
+		return err
+	}
+
+	// Validate the replica count against the limits configured for the shard set.
+	scaling, err := gameServer.GetShardSetScaling(shardSetName)
+	if err != nil {
+		return err
+	}
+	if scaling.MinNodeCount != nil && o.flagReplicas < *scaling.MinNodeCount {
+		return exitcode.Errorf(exitcode.ExitUsage, "cannot scale shard set '%s' to %d replicas: the minimum node count is %d", shardSetName, o.flagReplicas, *scaling.MinNodeCount)
+	}
+	if scaling.MaxNodeCount != nil && o.flagReplicas > *scaling.MaxNodeCount {
+		return exitcode.Errorf(exitcode.ExitUsage, "cannot scale shard set '%s' to %d replicas: the maximum node count is %d", shardSetName, o.flagReplicas, *scaling.MaxNodeCount)
+	}
+
+	// Get the current replica counts.
+	before, err := gameServer.GetShardSetReplicas(cmd.Context(), shardSetName)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Scale Game Server"))
+	log.Info().Msg("")
+	log.Info().Msgf("Target environment:")
+	log.Info().Msgf("  Name:               %s", styles.RenderTechnical(envConfig.Name))
+	log.Info().Msgf("  ID:                 %s", styles.RenderTechnical(envConfig.HumanID))
+	log.Info().Msgf("  Shard set:          %s", styles.RenderTechnical(shardSetName))
+	log.Info().Msgf("  Current replicas:   %s", styles.RenderTechnical(fmt.Sprintf("%d desired, %d ready", before.Desired, before.Ready)))
+	log.Info().Msgf("  New replicas:       %s", styles.RenderTechnical(fmt.Sprint(o.flagReplicas)))
+	log.Info().Msg("")
+
+	// Make sure nobody else is operating on the environment at the same time.
+	releaseLock, err := acquireOperationLock(cmd.Context(), targetEnv, cmdCtx.TokenSet, "scale server", o.flagLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	taskRunner := tui.NewTaskRunner()
+
+	taskRunner.AddTask(fmt.Sprintf("Scale shard set %s to %d replicas", shardSetName, o.flagReplicas), func(output *tui.TaskOutput) error {
+		return gameServer.ScaleShardSet(cmd.Context(), shardSetName, o.flagReplicas)
+	})
+
+	if o.flagWait {
+		taskRunner.AddTask("Wait for the game server pods to scale", func(output *tui.TaskOutput) error {
+			return gameServer.WaitForShardSetReplicas(cmd.Context(), shardSetName, int32(o.flagReplicas), o.flagTimeout)
+		})
+	}
+
+	// Run the tasks.
+	if err = taskRunner.Run(); err != nil {
+		return err
+	}
+
+	// Report the new replica counts.
+	after, err := gameServer.GetShardSetReplicas(cmd.Context(), shardSetName)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ Game server scaled to %d replicas", o.flagReplicas)))
+	log.Info().Msgf("  Desired:            %s", styles.RenderTechnical(fmt.Sprint(after.Desired)))
+	log.Info().Msgf("  Ready:              %s", styles.RenderTechnical(fmt.Sprint(after.Ready)))
+	if !o.flagWait {
+		log.Info().Msgf("Use %s to follow the progress.", styles.RenderTechnical(fmt.Sprintf("metaplay get pods %s", envConfig.HumanID)))
+	}
+	return nil
+}
+
+// Resolve the shard set to operate on: the one given with --shard-set, or the only shard set.
+func resolveShardSetName(gameServer *envapi.TargetGameServer, flagShardSet string) (string, error) {
+	shardSetNames := []string{}
+	for _, shardSet := range gameServer.ShardSets {
+		shardSetNames = append(shardSetNames, shardSet.Name)
+	}
+
+	if flagShardSet != "" {
+		for _, name := range shardSetNames {
+			if name == flagShardSet {
+				return name, nil
+			}
+		}
+		return "", exitcode.Errorf(exitcode.ExitNotFound, "shard set '%s' not found; existing shard sets: %s", flagShardSet, strings.Join(shardSetNames, ", "))
+	}
+
+	switch len(shardSetNames) {
+	case 0:
+		return "", fmt.Errorf("the game server has no shard sets")
+	case 1:
+		return shardSetNames[0], nil
+	default:
+		return "", exitcode.Errorf(exitcode.ExitUsage, "the game server has multiple shard sets (%s), specify one with --shard-set", strings.Join(shardSetNames, ", "))
+	}
+}
@@ -32,9 +32,10 @@ func init() {
 	args.AddStringArgumentOpt(&o.argSecretName, "NAME", "Name of the secret, e.g., 'user-some-secret'.")
 
 	cmd := &cobra.Command{
-		Use:   "create ENVIRONMENT NAME [flags]",
-		Short: "[preview] Create a user secret in the target environment",
-		Run:   runCommand(&o),
+		Use:               "create ENVIRONMENT NAME [flags]",
+		Short:             "[preview] Create a user secret in the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			PREVIEW: This command is in preview and subject to change!
 
@@ -123,7 +124,7 @@ func (o *CreateSecretOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *CreateSecretOpts) Run(cmd *cobra.Command) error {
+func (o *CreateSecretOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
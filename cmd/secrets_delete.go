@@ -24,9 +24,10 @@ func init() {
 	args.AddStringArgument(&o.argSecretName, "NAME", "Name of the secret, e.g., 'user-some-secret'.")
 
 	cmd := &cobra.Command{
-		Use:   "delete ENVIRONMENT NAME [flags]",
-		Short: "[preview] Delete a user secret in the target environment",
-		Run:   runCommand(&o),
+		Use:               "delete ENVIRONMENT NAME [flags]",
+		Short:             "[preview] Delete a user secret in the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			PREVIEW: This command is in preview and subject to change!
 
@@ -52,7 +53,7 @@ func (o *DeleteSecretOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *DeleteSecretOpts) Run(cmd *cobra.Command) error {
+func (o *DeleteSecretOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
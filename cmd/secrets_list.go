@@ -29,9 +29,10 @@ func init() {
 	args.AddStringArgumentOpt(&o.argEnvironment, "ENVIRONMENT", "Target environment name or id, eg, 'tough-falcons'.")
 
 	cmd := &cobra.Command{
-		Use:   "list ENVIRONMENT [flags]",
-		Short: "[preview] List the user secrets in the target environment",
-		Run:   runCommand(&o),
+		Use:               "list ENVIRONMENT [flags]",
+		Short:             "[preview] List the user secrets in the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			PREVIEW: This command is in preview and subject to change!
 
@@ -76,7 +77,7 @@ func (o *ListSecretsOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *ListSecretsOpts) Run(cmd *cobra.Command) error {
+func (o *ListSecretsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -105,7 +106,8 @@ func (o *ListSecretsOpts) Run(cmd *cobra.Command) error {
 			return fmt.Errorf("failed to marshal secrets as JSON: %v", err)
 		}
 
-		log.Info().Msgf("%s", string(secretsJson))
+		// Print with fmt to keep the secret values out of the debug log file.
+		fmt.Println(string(secretsJson))
 	} else {
 		if len(secrets) == 0 {
 			log.Info().Msgf("No secrets found in the environment")
@@ -114,11 +116,11 @@ func (o *ListSecretsOpts) Run(cmd *cobra.Command) error {
 			for ndx, secret := range secrets {
 				// Print separator.
 				if ndx != 0 {
-					log.Info().Msgf("---")
+					fmt.Println("---")
 				}
 
 				// Print secret.
-				logSecret(&secret, o.flagShowValues)
+				printSecret(&secret, o.flagShowValues)
 			}
 		}
 	}
@@ -159,16 +161,17 @@ func formatAge(duration time.Duration) string {
 	}
 }
 
-func logSecret(secret *corev1.Secret, showValues bool) {
+// Print the secret to stdout. Printed with fmt to keep the secret values out of the debug log file.
+func printSecret(secret *corev1.Secret, showValues bool) {
 	age := time.Now().Sub(secret.CreationTimestamp.Time)
-	log.Info().Msgf("Name: %s", secret.Name)
-	log.Info().Msgf("Age: %s", formatAge(age))
-	log.Info().Msgf("Data:")
+	fmt.Printf("Name: %s\n", secret.Name)
+	fmt.Printf("Age: %s\n", formatAge(age))
+	fmt.Printf("Data:\n")
 	for key, value := range secret.Data {
 		// Censor values unless they're requested to be shown.
 		if !showValues {
 			value = []byte("*****")
 		}
-		log.Info().Msgf("  %s: %s", key, value)
+		fmt.Printf("  %s: %s\n", key, value)
 	}
 }
@@ -8,7 +8,6 @@ import (
 	"fmt"
 
 	"github.com/metaplay/cli/pkg/envapi"
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -28,9 +27,10 @@ func init() {
 	args.AddStringArgument(&o.argSecretName, "NAME", "Name of the secret, e.g., 'user-some-secret'.")
 
 	cmd := &cobra.Command{
-		Use:   "show ENVIRONMENT NAME [flags]",
-		Short: "[preview] Show a user secret in the target environment",
-		Run:   runCommand(&o),
+		Use:               "show ENVIRONMENT NAME [flags]",
+		Short:             "[preview] Show a user secret in the target environment",
+		Run:               runCommand(&o),
+		ValidArgsFunction: completePositionalArgs(&o),
 		Long: renderLong(&o, `
 			PREVIEW: This command is in preview and subject to change!
 
@@ -76,7 +76,7 @@ func (o *ShowSecretOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *ShowSecretOpts) Run(cmd *cobra.Command) error {
+func (o *ShowSecretOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	// Try to resolve the project & auth provider.
 	project, err := tryResolveProject()
 	if err != nil {
@@ -104,9 +104,10 @@ func (o *ShowSecretOpts) Run(cmd *cobra.Command) error {
 			return fmt.Errorf("failed to marshal secrets as JSON: %v", err)
 		}
 
-		log.Info().Msgf("%s", string(secretJson))
+		// Print with fmt to keep the secret values out of the debug log file.
+		fmt.Println(string(secretJson))
 	} else {
-		logSecret(secret, true)
+		printSecret(secret, true)
 	}
 
 	return nil
@@ -0,0 +1,18 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// supportCmd includes commands for getting help from Metaplay support.
+var supportCmd = &cobra.Command{
+	Use:   "support",
+	Short: "Collect information for Metaplay support",
+}
+
+func init() {
+	rootCmd.AddCommand(supportCmd)
+}
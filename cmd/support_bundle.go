@@ -0,0 +1,332 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/common"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Collect diagnostics information into a zip file to be attached to support requests.
+type supportBundleOpts struct {
+	flagOutput    string
+	flagAnonymize bool
+	flagLogLines  int
+	flagYes       bool
+}
+
+// Single file to be included in the support bundle.
+type supportBundleEntry struct {
+	FileName    string // Name of the file within the zip.
+	Description string // Human-readable description of the contents.
+	Contents    []byte // Contents of the file.
+}
+
+func init() {
+	o := supportBundleOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "bundle [flags]",
+		Short: "Collect diagnostics information into a zip file for Metaplay support",
+		Run:   runCommand(&o),
+		Long: trimIndent(`
+			Collect diagnostics information about the CLI, your system, and your project into
+			a zip file that you can attach to a support request.
+
+			The bundle contains:
+			- CLI version and build information, OS and architecture.
+			- Versions of docker and the .NET SDK.
+			- The project's metaplay-project.yaml (if a project is found).
+			- The last lines of the CLI's debug log.
+			- Results of reachability checks against the portal and the project's environments.
+
+			Auth tokens are never included. Use --anonymize to also replace project and
+			environment identifiers with placeholders.
+
+			The contents of the bundle are shown before writing it and you are asked to
+			confirm. In non-interactive mode, --yes must be specified.
+		`),
+		Example: trimIndent(`
+			# Create a support bundle in the current directory.
+			metaplay support bundle
+
+			# Create an anonymized support bundle into a specific file.
+			metaplay support bundle --anonymize --output=/tmp/support.zip
+
+			# Include more lines from the debug log.
+			metaplay support bundle --log-lines=2000
+		`),
+	}
+	supportCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVarP(&o.flagOutput, "output", "o", "", "Path of the zip file to write (default 'metaplay-support-<timestamp>.zip')")
+	flags.BoolVar(&o.flagAnonymize, "anonymize", false, "Replace project and environment identifiers with placeholders")
+	flags.IntVar(&o.flagLogLines, "log-lines", 500, "Number of lines to include from the end of the CLI debug log")
+	flags.BoolVarP(&o.flagYes, "yes", "y", false, "Write the bundle without asking for confirmation")
+}
+
+func (o *supportBundleOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	if o.flagLogLines < 0 {
+		return fmt.Errorf("--log-lines must be non-negative, got %d", o.flagLogLines)
+	}
+	if o.flagOutput == "" {
+		o.flagOutput = fmt.Sprintf("metaplay-support-%s.zip", time.Now().Format("20060102-150405"))
+	}
+	if !o.flagYes && !tui.IsInteractive() {
+		return fmt.Errorf("in non-interactive mode, --yes must be specified to write the bundle")
+	}
+	return nil
+}
+
+func (o *supportBundleOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Create Support Bundle"))
+	log.Info().Msg("")
+
+	// Project is optional: include its info if one is found.
+	project, err := tryResolveProject()
+	if err != nil {
+		log.Warn().Msgf("Failed to load the project, not including it in the bundle: %v", err)
+		project = nil
+	}
+
+	// Collect all the entries.
+	entries := []supportBundleEntry{}
+
+	systemInfo, err := collectSystemInfo(project)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, supportBundleEntry{"system-info.json", "CLI version, OS/arch, docker and .NET versions", systemInfo})
+
+	if project != nil {
+		projectConfig, err := os.ReadFile(project.GetConfigFilePath())
+		if err != nil {
+			return fmt.Errorf("failed to read project config: %w", err)
+		}
+		entries = append(entries, supportBundleEntry{metaproj.ConfigFileName, "Project configuration", projectConfig})
+	}
+
+	logLines, err := readDebugLogTail(o.flagLogLines)
+	if err != nil {
+		log.Warn().Msgf("Failed to read the CLI debug log: %v", err)
+	} else {
+		entries = append(entries, supportBundleEntry{debugLogFileName, fmt.Sprintf("Last %d lines of the CLI debug log", len(logLines)), []byte(strings.Join(logLines, "\n") + "\n")})
+	}
+
+	entries = append(entries, supportBundleEntry{"reachability.txt", "Connectivity checks to the portal and environments", collectReachability(project)})
+
+	// Scrub the contents: never include tokens, and anonymize if requested.
+	replacer := newSupportBundleAnonymizer(project, o.flagAnonymize)
+	for ndx := range entries {
+		entries[ndx].Contents = []byte(replacer.Replace(string(redactTokens(entries[ndx].Contents))))
+	}
+
+	// Show the user what is about to be written.
+	log.Info().Msgf("The following files will be written to %s:", styles.RenderTechnical(o.flagOutput))
+	for _, entry := range entries {
+		log.Info().Msgf("  %-20s %s %s", styles.RenderTechnical(entry.FileName), entry.Description, styles.RenderMuted(fmt.Sprintf("[%s]", humanize.Bytes(uint64(len(entry.Contents))))))
+	}
+	if o.flagAnonymize {
+		log.Info().Msgf("Project and environment identifiers are %s.", styles.RenderSuccess("anonymized"))
+	} else {
+		log.Info().Msgf("Project and environment identifiers are included (use --anonymize to replace them).")
+	}
+	log.Info().Msgf("Auth tokens are never included.")
+	log.Info().Msg("")
+
+	// Ask for confirmation.
+	if !o.flagYes {
+		confirmed, err := tui.DoConfirmQuestion(cmd.Context(), "Write the support bundle?")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			log.Info().Msg("Cancelled")
+			return nil
+		}
+	}
+
+	// Write the zip file.
+	if err := writeSupportBundle(o.flagOutput, entries); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("✅ %s %s", styles.RenderSuccess("Support bundle written to"), styles.RenderTechnical(o.flagOutput))
+	return nil
+}
+
+// Run a command to get a tool's version. Returns a description of the error
+// (instead of failing) if the command isn't available.
+func getToolVersion(binary string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, binary, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Sprintf("unavailable: %v", err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Collect information about the CLI and the local system.
+func collectSystemInfo(project *metaproj.MetaplayProject) ([]byte, error) {
+	info := map[string]string{
+		"cliVersion":    version.AppVersion,
+		"gitCommit":     version.GitCommit,
+		"os":            runtime.GOOS,
+		"arch":          runtime.GOARCH,
+		"goVersion":     runtime.Version(),
+		"portalBaseUrl": common.PortalBaseURL,
+		"interactive":   fmt.Sprintf("%v", tui.IsInteractive()),
+		"dockerVersion": getToolVersion("docker", "version", "--format", "client={{.Client.Version}} server={{.Server.Version}}"),
+		"dotnetVersion": getToolVersion("dotnet", "--version"),
+		"dotnetSdks":    getToolVersion("dotnet", "--list-sdks"),
+	}
+	if common.StackApiBaseURLOverride != "" {
+		info["stackApiUrlOverride"] = common.StackApiBaseURLOverride
+	}
+	if project != nil {
+		info["sdkVersion"] = project.VersionMetadata.SdkVersion.String()
+	}
+
+	infoJSON, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal system info: %w", err)
+	}
+	return infoJSON, nil
+}
+
+// Check that a TLS connection can be established to the given host.
+func checkHostReachable(hostname string) string {
+	startTime := time.Now()
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(hostname, "443"), &tls.Config{ServerName: hostname})
+	if err != nil {
+		return fmt.Sprintf("FAILED: %v", err)
+	}
+	conn.Close()
+	return fmt.Sprintf("ok (%dms)", time.Since(startTime).Milliseconds())
+}
+
+// Check connectivity to the portal and the StackAPI of each project environment.
+func collectReachability(project *metaproj.MetaplayProject) []byte {
+	lines := []string{}
+
+	hostnames := []string{}
+	if portalHost, err := extractHostname(common.PortalBaseURL); err == nil {
+		hostnames = append(hostnames, portalHost)
+	}
+	if project != nil {
+		for _, envConfig := range project.Config.Environments {
+			hostnames = append(hostnames, fmt.Sprintf("infra.%s", envConfig.StackDomain))
+		}
+	}
+
+	checked := map[string]bool{}
+	for _, hostname := range hostnames {
+		if checked[hostname] {
+			continue
+		}
+		checked[hostname] = true
+		log.Debug().Msgf("Check reachability of %s", hostname)
+		lines = append(lines, fmt.Sprintf("%s: %s", hostname, checkHostReachable(hostname)))
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// Get the hostname part of an URL.
+func extractHostname(rawURL string) (string, error) {
+	parts := strings.SplitN(rawURL, "://", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid URL '%s'", rawURL)
+	}
+	return strings.SplitN(parts[1], "/", 2)[0], nil
+}
+
+// Create a replacer that replaces the identifying information (project and environment
+// IDs, names, and domains, and the user's home directory) with placeholders. If not
+// anonymizing, returns a no-op replacer.
+func newSupportBundleAnonymizer(project *metaproj.MetaplayProject, anonymize bool) *strings.Replacer {
+	if !anonymize {
+		return strings.NewReplacer()
+	}
+
+	replacements := map[string]string{}
+	if homeDir, err := os.UserHomeDir(); err == nil && homeDir != "" {
+		replacements[homeDir] = "<home>"
+	}
+	if project != nil {
+		replacements[project.Config.ProjectHumanID] = "<project-id>"
+		for ndx, envConfig := range project.Config.Environments {
+			replacements[envConfig.HumanID] = fmt.Sprintf("<environment-%d-id>", ndx+1)
+			replacements[envConfig.Name] = fmt.Sprintf("<environment-%d-name>", ndx+1)
+			replacements[envConfig.StackDomain] = fmt.Sprintf("<environment-%d-stack>", ndx+1)
+		}
+	}
+
+	// Replace longest strings first, eg, environment IDs are prefixed with the project ID.
+	originals := []string{}
+	for original := range replacements {
+		if original != "" {
+			originals = append(originals, original)
+		}
+	}
+	sort.Slice(originals, func(i, j int) bool { return len(originals[i]) > len(originals[j]) })
+
+	oldNew := []string{}
+	for _, original := range originals {
+		oldNew = append(oldNew, original, replacements[original])
+	}
+	return strings.NewReplacer(oldNew...)
+}
+
+// Write the entries into a zip file.
+func writeSupportBundle(outputPath string, entries []supportBundleEntry) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", outputPath, err)
+	}
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	for _, entry := range entries {
+		writer, err := zipWriter.Create(entry.FileName)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to the bundle: %w", entry.FileName, err)
+		}
+		if _, err := writer.Write(entry.Contents); err != nil {
+			return fmt.Errorf("failed to write %s to the bundle: %w", entry.FileName, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finalize the bundle: %w", err)
+	}
+	return nil
+}
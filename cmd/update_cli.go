@@ -4,10 +4,14 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/creativeprojects/go-selfupdate"
+	"github.com/metaplay/cli/internal/cliupdate"
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/internal/pathutil"
 	"github.com/metaplay/cli/internal/version"
 	"github.com/metaplay/cli/pkg/styles"
@@ -15,7 +19,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-type updateCliOpts struct{}
+type updateCliOpts struct {
+	flagFromFile      string
+	flagChecksumsFile string
+	flagSkipChecksum  bool
+}
 
 func init() {
 	o := updateCliOpts{}
@@ -24,27 +32,77 @@ func init() {
 		Use:   "cli",
 		Short: "Update the Metaplay CLI to the latest version",
 		Run:   runCommand(&o),
+		Long: trimIndent(`
+			Update the Metaplay CLI to the latest version from GitHub.
+
+			The previous binary is kept next to the new one (eg, 'metaplay.previous'), and the
+			new binary is checked to run before finishing. If it doesn't, the previous binary is
+			restored automatically. Use 'metaplay update rollback' to go back to the previous
+			version later.
+
+			With --from-file, the CLI is installed from a release archive (.tar.gz or .zip)
+			instead, eg, on machines without access to GitHub. The archive's SHA256 checksum is
+			verified against the release's checksums file, which is looked up next to the archive
+			(eg, 'MetaplayCLI_1.2.3_checksums.txt') unless specified with --checksums-file.
+		`),
+		Example: trimIndent(`
+			# Update to the latest version.
+			metaplay update cli
+
+			# Install from a release archive, with the checksums file in the same directory.
+			metaplay update cli --from-file=MetaplayCLI_Linux_x86_64.tar.gz
+
+			# Install from a release archive with an explicit checksums file.
+			metaplay update cli --from-file=MetaplayCLI_Windows_x86_64.zip --checksums-file=checksums.txt
+		`),
 	}
 
 	updateCmd.AddCommand(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&o.flagFromFile, "from-file", "", "Install from a release archive (.tar.gz or .zip) instead of downloading the latest version")
+	flags.StringVar(&o.flagChecksumsFile, "checksums-file", "", "With --from-file, the release's checksums file (default: the '*checksums.txt' next to the archive)")
+	flags.BoolVar(&o.flagSkipChecksum, "skip-checksum", false, "With --from-file, skip verifying the archive's checksum (not recommended)")
 }
 
 func (o *updateCliOpts) Prepare(cmd *cobra.Command, args []string) error {
+	if o.flagFromFile == "" && (o.flagChecksumsFile != "" || o.flagSkipChecksum) {
+		return exitcode.Errorf(exitcode.ExitUsage, "--checksums-file and --skip-checksum can only be used with --from-file")
+	}
+	if o.flagChecksumsFile != "" && o.flagSkipChecksum {
+		return exitcode.Errorf(exitcode.ExitUsage, "--checksums-file cannot be used with --skip-checksum")
+	}
+	if o.flagFromFile != "" && !strings.HasSuffix(o.flagFromFile, ".tar.gz") && !strings.HasSuffix(o.flagFromFile, ".zip") {
+		return exitcode.Errorf(exitcode.ExitUsage, "--from-file must be a .tar.gz or .zip release archive, got '%s'", o.flagFromFile)
+	}
 	return nil
 }
 
-func (o *updateCliOpts) Run(cmd *cobra.Command) error {
+func (o *updateCliOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
 	if version.IsDevBuild() {
 		return fmt.Errorf("The update command is disabled on development builds!")
 	}
 
+	// Calling vendored implementation of `GetExecutablePath()` due to a bug in `selfupdate.GetExecutablePath()`
+	// that uses `filepath.EvalSymlinks()` known to be broken on Windows.
+	// A PR has been made for the `go-selfupdate` library: https://github.com/creativeprojects/go-selfupdate/pull/46
+	exe, err := pathutil.GetExecutablePath()
+	if err != nil {
+		return fmt.Errorf("Could not determine the Metaplay CLI executable path")
+	}
+
+	if o.flagFromFile != "" {
+		return o.updateFromFile(cmd.Context(), exe)
+	}
+
 	source, err := selfupdate.NewGitHubSource(selfupdate.GitHubConfig{})
 	if err != nil {
 		return fmt.Errorf("Failed to initialize the Metaplay CLI updater source")
 	}
 
 	updater, err := selfupdate.NewUpdater(selfupdate.Config{
-		Source: source,
+		Source:      source,
+		OldSavePath: cliupdate.BackupPath(exe),
 	})
 	if err != nil {
 		return fmt.Errorf("Failed to initialize the Metaplay CLI updater")
@@ -59,20 +117,53 @@ func (o *updateCliOpts) Run(cmd *cobra.Command) error {
 		return nil
 	}
 
-	// Calling vendored implementation of `GetExecutablePath()` due to a bug in `selfupdate.GetExecutablePath()`
-	// that uses `filepath.EvalSymlinks()` known to be broken on Windows.
-	// A PR has been made for the `go-selfupdate` library: https://github.com/creativeprojects/go-selfupdate/pull/46
-	exe, err := pathutil.GetExecutablePath()
-	if err != nil {
-		return fmt.Errorf("Could not determine the Metaplay CLI executable path")
-	}
-
 	if err := updater.UpdateTo(context.Background(), latest, exe); err != nil {
 		return fmt.Errorf("Failed to update the Metaplay CLI binary")
 	}
+	if _, err := cliupdate.VerifyInstalled(cmd.Context(), exe); err != nil {
+		return err
+	}
 
 	log.Info().Msg("")
 	log.Info().Msgf(styles.RenderSuccess("✅ Successfully updated to version %s!"), latest.Version())
+	log.Info().Msg(styles.RenderMuted(fmt.Sprintf("The previous version was kept as %s, restore it with 'metaplay update rollback'.", cliupdate.BackupPath(exe))))
 
 	return nil
 }
+
+// Install the CLI from a release archive, after verifying its checksum.
+func (o *updateCliOpts) updateFromFile(ctx context.Context, exe string) error {
+	if o.flagSkipChecksum {
+		log.Warn().Msg(styles.RenderWarning("WARNING: Skipping the checksum verification of the release archive."))
+	} else {
+		checksumsFile := o.flagChecksumsFile
+		if checksumsFile == "" {
+			found, err := cliupdate.FindChecksumsFile(o.flagFromFile)
+			if err != nil {
+				return exitcode.New(exitcode.ExitUsage, err)
+			}
+			if found == "" {
+				return exitcode.Errorf(exitcode.ExitUsage, "no checksums file found next to %s; specify it with --checksums-file", o.flagFromFile)
+			}
+			checksumsFile = found
+		}
+		if err := cliupdate.VerifyChecksum(o.flagFromFile, checksumsFile); err != nil {
+			return err
+		}
+		log.Info().Msgf("Verified the checksum of %s against %s", o.flagFromFile, checksumsFile)
+	}
+
+	binary, err := cliupdate.ExtractBinary(o.flagFromFile)
+	if err != nil {
+		return err
+	}
+	installedVersion, err := cliupdate.Install(ctx, exe, bytes.NewReader(binary))
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msgf(styles.RenderSuccess("✅ Successfully installed version %s!"), coalesceString(installedVersion, "unknown"))
+	log.Info().Msg(styles.RenderMuted(fmt.Sprintf("The previous version was kept as %s, restore it with 'metaplay update rollback'.", cliupdate.BackupPath(exe))))
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/metaplay/cli/internal/exitcode"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Migrate the metaplay-project.yaml to the current schema version.
+type updateProjectConfigOpts struct{}
+
+func init() {
+	o := updateProjectConfigOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "project-config [flags]",
+		Short: "Migrate the metaplay-project.yaml to the current schema version",
+		Run:   runCommand(&o),
+		Long: renderLong(&o, `
+			Migrate the metaplay-project.yaml to the current schema version supported by this CLI.
+
+			The schema version is declared with the 'configVersion' field, and files without it
+			are considered version 1. The changes since the file's version are listed, and the
+			file is updated in place, retaining its formatting and comments. Fields added since
+			the file's version are filled in with their defaults, eg, 'serverValuesFilePattern'.
+			The file is only written if the migrated config is valid.
+
+			Commands that load the project config warn about an outdated schema version, or fail
+			with the global --strict flag.
+
+			Related commands:
+			- 'metaplay update project-environments' to update the environments in the config.
+		`),
+		Example: trimIndent(`
+			# Migrate the metaplay-project.yaml to the current schema version.
+			metaplay update project-config
+		`),
+	}
+
+	updateCmd.AddCommand(cmd)
+}
+
+func (o *updateProjectConfigOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *updateProjectConfigOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	// Locate and load the config file directly: the project doesn't need to be otherwise valid.
+	configFilePath, err := findProjectConfigFile()
+	if err != nil {
+		return exitcode.New(exitcode.ExitNotFound, err)
+	}
+	projectConfig, err := metaproj.LoadProjectConfigFileFromPath(configFilePath)
+	if err != nil {
+		return err
+	}
+
+	configFileName := filepath.Base(configFilePath)
+	configVersion := projectConfig.GetConfigVersion()
+	if !projectConfig.IsConfigVersionOutdated() {
+		log.Info().Msgf("%s is already at the current configVersion %d, nothing to do", configFileName, configVersion)
+		return nil
+	}
+
+	log.Info().Msgf("Migrate %s from configVersion %d to %d:", styles.RenderTechnical(configFilePath), configVersion, metaproj.CurrentProjectConfigVersion)
+	for _, change := range metaproj.GetProjectConfigChangesSince(configVersion) {
+		log.Info().Msgf("  - %s", change)
+	}
+	log.Info().Msg("")
+
+	// Migrate the file and check that the result is still a valid config before writing it.
+	content, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", configFileName, err)
+	}
+	migrated := metaproj.MigrateProjectConfigFile(string(content), configVersion)
+	if _, err := metaproj.ParseProjectConfig([]byte(migrated), configFilePath); err != nil {
+		return fmt.Errorf("migrated %s is invalid: %w", configFileName, err)
+	}
+	if err := os.WriteFile(configFilePath, []byte(migrated), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", configFileName, err)
+	}
+
+	log.Info().Msg(styles.RenderSuccess(fmt.Sprintf("✅ %s migrated to configVersion %d", configFileName, metaproj.CurrentProjectConfigVersion)))
+	return nil
+}
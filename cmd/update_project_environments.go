@@ -6,12 +6,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
 	"github.com/goccy/go-yaml/parser"
-	"github.com/metaplay/cli/internal/tui"
 	"github.com/metaplay/cli/pkg/metaproj"
 	"github.com/metaplay/cli/pkg/portalapi"
 	"github.com/rs/zerolog/log"
@@ -21,6 +19,8 @@ import (
 // Command for updating the 'environments' section in the 'metaplay-project.yaml'. The environments
 // infos are fetched from the portal using the projectID (human ID) specified in the YAML file.
 type updateProjectEnvironmentsOpts struct {
+	RequiresProject
+	RequiresLogin
 }
 
 func init() {
@@ -50,29 +50,13 @@ func (o *updateProjectEnvironmentsOpts) Prepare(cmd *cobra.Command, args []strin
 	return nil
 }
 
-func (o *updateProjectEnvironmentsOpts) Run(cmd *cobra.Command) error {
-	// Try to resolve the project & auth provider.
-	project, err := resolveProject()
-	if err != nil {
-		return err
-	}
-
-	// Always use Metaplay Auth for project initialization.
-	authProvider, err := getAuthProvider(project, "metaplay")
-	if err != nil {
-		return err
-	}
-
-	// Ensure the user is logged in.
-	tokenSet, err := tui.RequireLoggedIn(cmd.Context(), authProvider)
-	if err != nil {
-		return err
-	}
+func (o *updateProjectEnvironmentsOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	project := cmdCtx.Project
 
 	log.Info().Msgf("Update environments in metaplay-project.yaml..")
 
 	// Fetch project information from the portal.
-	portalClient := portalapi.NewClient(tokenSet)
+	portalClient := portalapi.NewClient(cmdCtx.TokenSet)
 	projectInfo, err := portalClient.FetchProjectInfo(project.Config.ProjectHumanID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch project information from the portal: %w", err)
@@ -104,7 +88,7 @@ func (o *updateProjectEnvironmentsOpts) Run(cmd *cobra.Command) error {
 // and whitespace in the untouched parts of the file.
 func (o *updateProjectEnvironmentsOpts) updateProjectConfigEnvironments(project *metaproj.MetaplayProject, newPortalEnvironments []portalapi.EnvironmentInfo) error {
 	// Load the existing YAML file
-	projectConfigFilePath := filepath.Join(project.RelativeDir, metaproj.ConfigFileName)
+	projectConfigFilePath := project.GetConfigFilePath()
 	configFileBytes, err := os.ReadFile(projectConfigFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to read project config file: %v", err)
@@ -0,0 +1,69 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/metaplay/cli/internal/cliupdate"
+	"github.com/metaplay/cli/internal/pathutil"
+	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Restore the CLI binary that was replaced by the last update.
+type updateRollbackOpts struct{}
+
+func init() {
+	o := updateRollbackOpts{}
+
+	var cmd = &cobra.Command{
+		Use:   "rollback",
+		Short: "Restore the Metaplay CLI version used before the last update",
+		Run:   runCommand(&o),
+		Long: trimIndent(`
+			Restore the Metaplay CLI binary that was replaced by the last 'metaplay update cli'.
+
+			The previous binary is kept next to the current one (eg, 'metaplay.previous'). It is
+			checked to run before restoring it. The replaced binary becomes the new backup, so
+			running the command again undoes the rollback.
+
+			If the CLI itself no longer runs, restore the previous version manually by renaming
+			'metaplay.previous' (or 'metaplay.previous.exe' on Windows) over the broken binary.
+		`),
+		Example: trimIndent(`
+			# Go back to the CLI version used before the last update.
+			metaplay update rollback
+		`),
+	}
+
+	updateCmd.AddCommand(cmd)
+}
+
+func (o *updateRollbackOpts) Prepare(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func (o *updateRollbackOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	if version.IsDevBuild() {
+		return fmt.Errorf("The rollback command is disabled on development builds!")
+	}
+
+	exe, err := pathutil.GetExecutablePath()
+	if err != nil {
+		return fmt.Errorf("Could not determine the Metaplay CLI executable path")
+	}
+
+	restoredVersion, err := cliupdate.Rollback(cmd.Context(), exe)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("")
+	log.Info().Msgf(styles.RenderSuccess("✅ Rolled back from version %s to %s!"), version.AppVersion, coalesceString(restoredVersion, "unknown"))
+	log.Info().Msg(styles.RenderMuted(fmt.Sprintf("Version %s was kept as %s, run 'metaplay update rollback' again to restore it.", version.AppVersion, cliupdate.BackupPath(exe))))
+	return nil
+}
@@ -6,8 +6,15 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -15,6 +22,33 @@ import (
 // Show the version info of the application.
 type VersionOpts struct {
 	flagFormat string
+	flagCheck  bool
+}
+
+// Structured version info, output with --format=json.
+type versionInfo struct {
+	AppVersion                           string                `json:"appVersion"`
+	GitCommit                            string                `json:"gitCommit"`
+	BuildDate                            string                `json:"buildDate"`
+	GoVersion                            string                `json:"goVersion"`
+	Prerelease                           bool                  `json:"prerelease"`
+	SupportedProjectConfigSchemaVersions []int                 `json:"supportedProjectConfigSchemaVersions"`
+	SupportedSdkVersions                 supportedSdkVersions  `json:"supportedSdkVersions"`
+	Project                              *projectCompatibility `json:"project,omitempty"` // Only when run within a project.
+}
+
+// Range of Metaplay SDK versions supported by the CLI.
+type supportedSdkVersions struct {
+	Min string  `json:"min"`
+	Max *string `json:"max"` // Nil if there is no upper bound.
+}
+
+// Compatibility of the CLI with the project it is run within.
+type projectCompatibility struct {
+	Dir        string   `json:"dir"`
+	SdkVersion *string  `json:"sdkVersion"` // Nil if the SDK version could not be determined.
+	Compatible bool     `json:"compatible"`
+	Reasons    []string `json:"reasons"`
 }
 
 var versionOpts = VersionOpts{}
@@ -23,6 +57,27 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version information of this CLI",
 	Run:   runCommand(&versionOpts),
+	Long: trimIndent(`
+		Print the version information of this CLI, including the Metaplay SDK versions and
+		metaplay-project.yaml schema versions it supports.
+
+		When run within a Metaplay project, also shows the project's Metaplay SDK version and
+		whether the project is compatible with this CLI.
+
+		With --check, the command exits with code 0 if the project is compatible with this CLI
+		and with code 1 if it is not. This is useful for checking the CLI version in CI
+		pipelines before starting a build.
+	`),
+	Example: trimIndent(`
+		# Show the version information.
+		metaplay version
+
+		# Output the version information as JSON.
+		metaplay version --format=json
+
+		# Check that the project is compatible with this CLI (exit code 1 if not).
+		metaplay version --check
+	`),
 }
 
 func init() {
@@ -30,6 +85,7 @@ func init() {
 
 	flags := versionCmd.Flags()
 	flags.StringVar(&versionOpts.flagFormat, "format", "text", "Output format. Valid values are 'text' or 'json'")
+	flags.BoolVar(&versionOpts.flagCheck, "check", false, "Exit with code 1 if the project is not compatible with this CLI")
 }
 
 func (o *VersionOpts) Prepare(cmd *cobra.Command, args []string) error {
@@ -40,20 +96,28 @@ func (o *VersionOpts) Prepare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (o *VersionOpts) Run(cmd *cobra.Command) error {
-	if o.flagFormat == "json" {
-		// Create structured version info with exported fields
-		type VersionInfo struct {
-			AppVersion string `json:"appVersion"`
-			GitCommit  string `json:"gitCommit"`
-			Prerelease bool   `json:"prerelease"`
-		}
-		info := VersionInfo{
-			AppVersion: version.AppVersion,
-			GitCommit:  version.GitCommit,
-			Prerelease: version.IsDevBuild(),
-		}
+func (o *VersionOpts) Run(cmd *cobra.Command, cmdCtx *CommandContext) error {
+	info := versionInfo{
+		AppVersion:                           version.AppVersion,
+		GitCommit:                            version.GitCommit,
+		BuildDate:                            version.BuildDate,
+		GoVersion:                            runtime.Version(),
+		Prerelease:                           version.IsDevBuild(),
+		SupportedProjectConfigSchemaVersions: metaproj.SupportedProjectConfigSchemaVersions,
+		SupportedSdkVersions: supportedSdkVersions{
+			Min: metaproj.MinSupportedSdkVersion.String(),
+		},
+	}
 
+	// Check the compatibility with the project, if run within one.
+	configFilePath, err := findProjectConfigFile()
+	if err == nil {
+		info.Project = checkProjectCompatibility(configFilePath)
+	} else if o.flagCheck {
+		return exitcode.Errorf(exitcode.ExitNotFound, "--check must be run within a Metaplay project: %v", err)
+	}
+
+	if o.flagFormat == "json" {
 		// Marshal to JSON.
 		infoJson, err := json.MarshalIndent(info, "", "  ")
 		if err != nil {
@@ -62,8 +126,75 @@ func (o *VersionOpts) Run(cmd *cobra.Command) error {
 
 		log.Info().Msg(string(infoJson))
 	} else {
-		log.Info().Msgf("%s", version.AppVersion)
+		printVersionInfo(&info)
+	}
+
+	// With --check, exit with code 1 if not compatible. Only report it with the exit code, to
+	// keep the output parseable.
+	if o.flagCheck && !info.Project.Compatible {
+		return exitcode.Silent(exitcode.ExitError)
 	}
 
 	return nil
 }
+
+// Load the project from the config file and check whether its Metaplay SDK version is supported.
+func checkProjectCompatibility(configFilePath string) *projectCompatibility {
+	result := &projectCompatibility{Dir: filepath.Dir(configFilePath)}
+
+	// Loading the project fails if the SDK is too old, so treat errors as incompatibility.
+	project, err := loadProject(configFilePath)
+	if err != nil {
+		result.Reasons = []string{fmt.Sprintf("Failed to load the project: %v", err)}
+		return result
+	}
+
+	sdkVersion := project.VersionMetadata.SdkVersion.String()
+	result.SdkVersion = &sdkVersion
+	result.Compatible, result.Reasons = metaproj.CheckSdkCompatibility(project.VersionMetadata.SdkVersion)
+	return result
+}
+
+// Print the version info in human-readable form.
+func printVersionInfo(info *versionInfo) {
+	schemaVersions := make([]string, len(info.SupportedProjectConfigSchemaVersions))
+	for ndx, schemaVersion := range info.SupportedProjectConfigSchemaVersions {
+		schemaVersions[ndx] = "v" + strconv.Itoa(schemaVersion)
+	}
+	sdkRange := fmt.Sprintf("%s or later", info.SupportedSdkVersions.Min)
+	if info.SupportedSdkVersions.Max != nil {
+		sdkRange = fmt.Sprintf("%s - %s", info.SupportedSdkVersions.Min, *info.SupportedSdkVersions.Max)
+	}
+
+	log.Info().Msg("")
+	log.Info().Msg(styles.RenderTitle("Metaplay CLI"))
+	log.Info().Msg("")
+	log.Info().Msgf("  Version:            %s", styles.RenderTechnical(info.AppVersion))
+	log.Info().Msgf("  Git commit:         %s", styles.RenderTechnical(info.GitCommit))
+	log.Info().Msgf("  Build date:         %s", styles.RenderTechnical(info.BuildDate))
+	log.Info().Msgf("  Go version:         %s", styles.RenderTechnical(info.GoVersion))
+	log.Info().Msgf("  Metaplay SDK:       %s", styles.RenderTechnical(sdkRange))
+	log.Info().Msgf("  Project schema:     %s", styles.RenderTechnical(strings.Join(schemaVersions, ", ")))
+
+	if info.Project != nil {
+		sdkVersion := "unknown"
+		if info.Project.SdkVersion != nil {
+			sdkVersion = *info.Project.SdkVersion
+		}
+
+		log.Info().Msg("")
+		log.Info().Msg(styles.RenderTitle("Project"))
+		log.Info().Msg("")
+		log.Info().Msgf("  Directory:          %s", styles.RenderTechnical(info.Project.Dir))
+		log.Info().Msgf("  Metaplay SDK:       %s", styles.RenderTechnical(sdkVersion))
+		if info.Project.Compatible {
+			log.Info().Msgf("  Compatible:         %s", styles.RenderSuccess("yes"))
+		} else {
+			log.Info().Msgf("  Compatible:         %s", styles.RenderError("no"))
+		}
+		for _, reason := range info.Project.Reasons {
+			log.Info().Msgf("    %s", styles.RenderMuted(reason))
+		}
+	}
+	log.Info().Msg("")
+}
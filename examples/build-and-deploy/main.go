@@ -0,0 +1,116 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Example of using the metaplay package: build the game server image of a project, push it
+// into a cloud environment, and deploy it. Cancelling with Ctrl-C stops the operation.
+//
+// Log in with 'metaplay auth login' first, then run:
+//
+//	go run ./examples/build-and-deploy -project path/to/project -env tough-falcons -tag 1a27c257 -chart-version 0.8.0
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/metaplay/cli/pkg/metaplay"
+)
+
+func main() {
+	projectDir := flag.String("project", ".", "Directory of the project's metaplay-project.yaml")
+	environment := flag.String("env", "", "Environment to deploy into, eg, 'tough-falcons'")
+	imageTag := flag.String("tag", "", "Tag of the image to build, eg, the git commit ID")
+	chartVersion := flag.String("chart-version", "", "Version of the game server Helm chart to deploy")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, *projectDir, *environment, *imageTag, *chartVersion); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, projectDir, environment, imageTag, chartVersion string) error {
+	project, err := metaplay.LoadProject(projectDir)
+	if err != nil {
+		return err
+	}
+	envConfig, err := project.Config.FindEnvironmentConfig(environment)
+	if err != nil {
+		return err
+	}
+
+	// Use the credentials of 'metaplay auth login'.
+	tokenSet, err := auth.LoadAndRefreshTokenSet(auth.NewMetaplayAuthProvider())
+	if err != nil {
+		return err
+	}
+	if tokenSet == nil {
+		return fmt.Errorf("not logged in, run 'metaplay auth login' first")
+	}
+	env := metaplay.NewEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+
+	// Print the progress of all operations.
+	progress := metaplay.ProgressCallbacks{
+		OnLog: func(line string) {
+			fmt.Println(line)
+		},
+		OnStatus: func(lines []string) {
+			fmt.Println(strings.Join(lines, "\n"))
+		},
+	}
+
+	// Build the image.
+	build, err := metaplay.BuildImage(ctx, metaplay.BuildImageOptions{
+		Project:     project,
+		ImageName:   fmt.Sprintf("%s:%s", project.Config.ProjectHumanID, imageTag),
+		CommitID:    imageTag,
+		BuildNumber: "none",
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		Progress:    progress,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Built image %s (%s)\n", build.ImageName, build.ImageID)
+
+	// Push the image into the environment.
+	push, err := metaplay.PushImage(ctx, env, metaplay.PushImageOptions{
+		ImageName: build.ImageName,
+		Progress:  progress,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pushed image %s\n", push.RemoteImageName)
+
+	// Deploy the image and wait for the game server to be ready.
+	deploy, err := metaplay.DeployGameServer(ctx, env, metaplay.DeployGameServerOptions{
+		ImageTag:     imageTag,
+		ChartPath:    helmutil.GetHelmChartPath("https://charts.metaplay.dev", metaplay.GameServerChartName, chartVersion),
+		ChartVersion: chartVersion,
+		Values: map[string]interface{}{
+			"environment":       envConfig.Name,
+			"environmentFamily": envConfig.GetEnvironmentFamily(),
+			"sdk": map[string]interface{}{
+				"version": project.VersionMetadata.SdkVersion.String(),
+			},
+		},
+		Progress: progress,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deployed release %s (revision %d)\n", deploy.ReleaseName, deploy.Revision)
+	return nil
+}
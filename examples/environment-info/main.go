@@ -0,0 +1,65 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Example of using the metaplay package: print the details of a cloud environment as JSON.
+//
+// Log in with 'metaplay auth login' first, then run:
+//
+//	go run ./examples/environment-info -project path/to/project -env tough-falcons
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/metaplay"
+)
+
+func main() {
+	projectDir := flag.String("project", ".", "Directory of the project's metaplay-project.yaml")
+	environment := flag.String("env", "", "Environment to inspect, eg, 'tough-falcons'")
+	flag.Parse()
+
+	if err := run(context.Background(), *projectDir, *environment); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, projectDir, environment string) error {
+	project, err := metaplay.LoadProject(projectDir)
+	if err != nil {
+		return err
+	}
+	envConfig, err := project.Config.FindEnvironmentConfig(environment)
+	if err != nil {
+		return err
+	}
+
+	// Use the credentials of 'metaplay auth login'.
+	tokenSet, err := auth.LoadAndRefreshTokenSet(auth.NewMetaplayAuthProvider())
+	if err != nil {
+		return err
+	}
+	if tokenSet == nil {
+		return fmt.Errorf("not logged in, run 'metaplay auth login' first")
+	}
+
+	env := metaplay.NewEnvironment(tokenSet, envConfig.StackDomain, envConfig.HumanID)
+	details, err := metaplay.GetEnvironmentDetails(ctx, env)
+	if err != nil {
+		return err
+	}
+
+	detailsJSON, err := json.MarshalIndent(details, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(detailsJSON))
+	return nil
+}
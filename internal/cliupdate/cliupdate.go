@@ -0,0 +1,217 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Package cliupdate installs new versions of the CLI binary in place, keeping the previous
+// binary as a backup that can be restored, and extracts binaries from release archives.
+package cliupdate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/creativeprojects/go-selfupdate"
+	"github.com/creativeprojects/go-selfupdate/update"
+	"github.com/rs/zerolog/log"
+)
+
+// Name of the CLI binary within the release archives.
+const binaryName = "metaplay"
+
+// How long to wait for a binary to run 'version' when verifying it.
+var VerifyTimeout = 30 * time.Second
+
+// Matches the version in the output of 'metaplay version', eg, 'Version:  1.2.3'.
+var versionOutputRegex = regexp.MustCompile(`Version:\s+(\S+)`)
+
+// Get the path of the backup of the previous binary, next to the executable, eg,
+// 'metaplay.previous' for 'metaplay', or 'metaplay.previous.exe' for 'metaplay.exe'.
+func BackupPath(exePath string) string {
+	ext := filepath.Ext(exePath)
+	return strings.TrimSuffix(exePath, ext) + ".previous" + ext
+}
+
+// Get the path where a binary is moved out of the way when it's replaced. On Windows, the
+// running executable can be renamed but not deleted, so it may remain until the next update.
+func discardPath(exePath string) string {
+	return filepath.Join(filepath.Dir(exePath), "."+filepath.Base(exePath)+".discard")
+}
+
+// Verify that the binary runs by invoking 'version' on it. Returns the reported version, or
+// an empty string if it cannot be parsed from the output.
+func Verify(ctx context.Context, binaryPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, VerifyTimeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, binaryPath, "version", "--skip-version-check")
+	cmd.Dir = os.TempDir() // Don't check compatibility with a project in the working directory.
+	cmd.Env = append(os.Environ(), "METAPLAYCLI_COLOR=no")
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("'%s version' did not complete within %s", binaryPath, VerifyTimeout)
+		}
+		return "", fmt.Errorf("'%s version' failed: %w\n%s", binaryPath, err, strings.TrimSpace(output.String()))
+	}
+
+	if match := versionOutputRegex.FindStringSubmatch(output.String()); match != nil {
+		return match[1], nil
+	}
+	return "", nil
+}
+
+// Install the new binary in place of the executable, keeping the current executable as the
+// backup (see BackupPath). The new binary is written next to the executable and renamed over
+// it, so the executable is never partially written. The installed binary is then verified,
+// and the previous binary is restored if it doesn't run. Returns the installed version.
+func Install(ctx context.Context, exePath string, newBinary io.Reader) (string, error) {
+	backupPath := BackupPath(exePath)
+	log.Debug().Msgf("Install new binary to %s, backup to %s", exePath, backupPath)
+
+	// Renames the executable to the backup path and the new binary in its place. Renaming the
+	// running executable is allowed on all platforms, including Windows.
+	err := update.Apply(newBinary, update.Options{
+		TargetPath:  exePath,
+		OldSavePath: backupPath,
+	})
+	if err != nil {
+		if rollbackErr := update.RollbackError(err); rollbackErr != nil {
+			return "", fmt.Errorf("failed to install the new binary (%w), and failed to restore the previous binary (%v): restore it manually by renaming %s to %s", err, rollbackErr, backupPath, exePath)
+		}
+		return "", fmt.Errorf("failed to install the new binary: %w", err)
+	}
+
+	return VerifyInstalled(ctx, exePath)
+}
+
+// Verify that the newly installed executable works, eg, it hasn't been quarantined by an
+// antivirus mid-write. If it doesn't, the backup of the previous binary is restored and the
+// broken binary is removed. Returns the installed version.
+func VerifyInstalled(ctx context.Context, exePath string) (string, error) {
+	backupPath := BackupPath(exePath)
+	installedVersion, err := Verify(ctx, exePath)
+	if err != nil {
+		if restoreErr := swapFiles(exePath, backupPath); restoreErr != nil {
+			return "", fmt.Errorf("the installed binary does not work (%w), and failed to restore the previous binary (%v): restore it manually by renaming %s to %s", err, restoreErr, backupPath, exePath)
+		}
+		_ = os.Remove(backupPath)
+		return "", fmt.Errorf("the installed binary does not work, the previous binary was restored: %w", err)
+	}
+	return installedVersion, nil
+}
+
+// Restore the backup of the previous binary (see BackupPath) as the executable. The backup is
+// verified to run first. The replaced binary becomes the new backup, so the rollback can be
+// undone by rolling back again. Returns the restored version.
+func Rollback(ctx context.Context, exePath string) (string, error) {
+	backupPath := BackupPath(exePath)
+	if _, err := os.Stat(backupPath); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("no previous binary found at %s; a backup is only kept when updating with 'metaplay update cli'", backupPath)
+	} else if err != nil {
+		return "", err
+	}
+
+	restoredVersion, err := Verify(ctx, backupPath)
+	if err != nil {
+		return "", fmt.Errorf("the previous binary does not work, not restoring it: %w", err)
+	}
+
+	if err := swapFiles(exePath, backupPath); err != nil {
+		return "", fmt.Errorf("failed to restore the previous binary: %w", err)
+	}
+	return restoredVersion, nil
+}
+
+// Swap the executable with the backup. If a step fails, the earlier steps are undone.
+func swapFiles(exePath string, backupPath string) error {
+	tmpPath := discardPath(exePath)
+
+	// Remove a leftover from an earlier swap: on Windows, it may have been a running executable.
+	_ = os.Remove(tmpPath)
+
+	if err := os.Rename(exePath, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(backupPath, exePath); err != nil {
+		if undoErr := os.Rename(tmpPath, exePath); undoErr != nil {
+			return fmt.Errorf("%w (and failed to move %s back to %s: %v)", err, tmpPath, exePath, undoErr)
+		}
+		return err
+	}
+	if err := os.Rename(tmpPath, backupPath); err != nil {
+		// The swap itself succeeded, only the backup is lost.
+		log.Warn().Msgf("Failed to keep the replaced binary as %s: %v", backupPath, err)
+	}
+	return nil
+}
+
+// Read the CLI binary for the current platform from a release archive (.tar.gz or .zip).
+func ExtractBinary(archivePath string) ([]byte, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open release archive: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := selfupdate.DecompressCommand(file, archivePath, binaryName, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract the %s binary from %s: %w", binaryName, archivePath, err)
+	}
+	binary, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract the %s binary from %s: %w", binaryName, archivePath, err)
+	}
+	if len(binary) == 0 {
+		return nil, fmt.Errorf("the %s binary in %s is empty", binaryName, archivePath)
+	}
+	return binary, nil
+}
+
+// Find the checksums file published with the release archives (eg,
+// 'MetaplayCLI_1.2.3_checksums.txt') in the archive's directory. Returns an empty string if
+// there is none, and an error if there are several.
+func FindChecksumsFile(archivePath string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(archivePath), "*checksums.txt"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", nil
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("multiple checksums files found next to the archive (%s), specify the one to use", strings.Join(matches, ", "))
+	}
+}
+
+// Verify the SHA256 checksum of the archive against its entry in the checksums file, which
+// has lines of format '<sha256>  <archive file name>'.
+func VerifyChecksum(archivePath string, checksumsPath string) error {
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read release archive: %w", err)
+	}
+	checksums, err := os.ReadFile(checksumsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read checksums file: %w", err)
+	}
+
+	validator := &selfupdate.ChecksumValidator{}
+	if err := validator.Validate(filepath.Base(archivePath), archive, checksums); err != nil {
+		return fmt.Errorf("checksum verification of %s against %s failed: %w", filepath.Base(archivePath), checksumsPath, err)
+	}
+	return nil
+}
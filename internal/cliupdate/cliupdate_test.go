@@ -0,0 +1,192 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package cliupdate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Fake CLI binary that prints the given version, like 'metaplay version'.
+func fakeBinary(version string) string {
+	return fmt.Sprintf("#!/bin/sh\necho '  Version:            %s'\n", version)
+}
+
+// Create a fake CLI executable in a new directory, returning its path.
+func writeFakeExecutable(t *testing.T, content string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake executables are shell scripts")
+	}
+	exePath := filepath.Join(t.TempDir(), "metaplay")
+	if err := os.WriteFile(exePath, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return exePath
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestBackupPath(t *testing.T) {
+	if got := BackupPath(filepath.Join("bin", "metaplay")); got != filepath.Join("bin", "metaplay.previous") {
+		t.Errorf("BackupPath() = %q", got)
+	}
+	if got := BackupPath(filepath.Join("bin", "metaplay.exe")); got != filepath.Join("bin", "metaplay.previous.exe") {
+		t.Errorf("BackupPath() = %q", got)
+	}
+}
+
+func TestInstallAndRollback(t *testing.T) {
+	ctx := context.Background()
+	exePath := writeFakeExecutable(t, fakeBinary("1.0.0"))
+
+	// Install keeps the previous binary as the backup.
+	installedVersion, err := Install(ctx, exePath, strings.NewReader(fakeBinary("1.1.0")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if installedVersion != "1.1.0" {
+		t.Errorf("installed version = %q, expected 1.1.0", installedVersion)
+	}
+	if readFile(t, BackupPath(exePath)) != fakeBinary("1.0.0") {
+		t.Errorf("backup is not the previous binary")
+	}
+
+	// Rollback swaps the binaries.
+	restoredVersion, err := Rollback(ctx, exePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restoredVersion != "1.0.0" {
+		t.Errorf("restored version = %q, expected 1.0.0", restoredVersion)
+	}
+	if readFile(t, exePath) != fakeBinary("1.0.0") || readFile(t, BackupPath(exePath)) != fakeBinary("1.1.0") {
+		t.Errorf("binaries were not swapped")
+	}
+
+	// A broken backup is not restored.
+	if err := os.WriteFile(BackupPath(exePath), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rollback(ctx, exePath); err == nil || !strings.Contains(err.Error(), "does not work") {
+		t.Errorf("expected broken backup error, got: %v", err)
+	}
+	if readFile(t, exePath) != fakeBinary("1.0.0") {
+		t.Errorf("executable was modified")
+	}
+
+	// No backup.
+	os.Remove(BackupPath(exePath))
+	if _, err := Rollback(ctx, exePath); err == nil || !strings.Contains(err.Error(), "no previous binary") {
+		t.Errorf("expected missing backup error, got: %v", err)
+	}
+}
+
+func TestInstallBrokenBinaryRestoresPrevious(t *testing.T) {
+	exePath := writeFakeExecutable(t, fakeBinary("1.0.0"))
+
+	_, err := Install(context.Background(), exePath, strings.NewReader("#!/bin/sh\nexit 1\n"))
+	if err == nil || !strings.Contains(err.Error(), "previous binary was restored") {
+		t.Fatalf("expected restore error, got: %v", err)
+	}
+	if readFile(t, exePath) != fakeBinary("1.0.0") {
+		t.Errorf("previous binary was not restored")
+	}
+	if _, err := os.Stat(BackupPath(exePath)); !os.IsNotExist(err) {
+		t.Errorf("expected the broken binary to be removed, got: %v", err)
+	}
+}
+
+// Write a .tar.gz release archive containing the files, returning its path.
+func writeTarGz(t *testing.T, dir string, name string, files map[string]string) string {
+	archivePath := filepath.Join(dir, name)
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for fileName, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: fileName, Mode: 0755, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestExtractBinaryAndVerifyChecksum(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := writeTarGz(t, dir, "MetaplayCLI_Linux_x86_64.tar.gz", map[string]string{
+		"LICENSE":  "Apache-2.0",
+		"metaplay": "binary-contents",
+	})
+
+	binary, err := ExtractBinary(archivePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(binary) != "binary-contents" {
+		t.Errorf("extracted binary = %q", binary)
+	}
+
+	// No checksums file yet.
+	if found, err := FindChecksumsFile(archivePath); err != nil || found != "" {
+		t.Errorf("FindChecksumsFile() = %q, %v, expected none", found, err)
+	}
+
+	// Checksums file in goreleaser format.
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksums := fmt.Sprintf("%x  MetaplayCLI_Linux_x86_64.tar.gz\n%x  MetaplayCLI_Darwin_arm64.tar.gz\n", sha256.Sum256(archive), sha256.Sum256([]byte("other")))
+	checksumsPath := filepath.Join(dir, "MetaplayCLI_1.2.3_checksums.txt")
+	if err := os.WriteFile(checksumsPath, []byte(checksums), 0644); err != nil {
+		t.Fatal(err)
+	}
+	found, err := FindChecksumsFile(archivePath)
+	if err != nil || found != checksumsPath {
+		t.Errorf("FindChecksumsFile() = %q, %v, expected %q", found, err, checksumsPath)
+	}
+	if err := VerifyChecksum(archivePath, checksumsPath); err != nil {
+		t.Errorf("unexpected checksum error: %v", err)
+	}
+
+	// Tampered archive.
+	if err := os.WriteFile(archivePath, append(archive, 0), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksum(archivePath, checksumsPath); err == nil {
+		t.Errorf("expected a checksum error for a modified archive")
+	}
+
+	// Archive without the binary.
+	emptyPath := writeTarGz(t, dir, "empty.tar.gz", map[string]string{"README.md": "readme"})
+	if _, err := ExtractBinary(emptyPath); err == nil {
+		t.Errorf("expected an error for an archive without the binary")
+	}
+}
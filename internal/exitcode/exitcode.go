@@ -0,0 +1,88 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Package exitcode defines the process exit codes of the CLI. Scripts can rely on
+// these to distinguish between different kinds of failures:
+//
+//	0   ExitSuccess       The command completed successfully.
+//	1   ExitError         Generic failure (anything not covered by the more specific codes).
+//	2   ExitUsage         Invalid command line: bad arguments, flags, or flag values.
+//	3   ExitBuildFailed   Building the project (docker image, server, bot client, dashboard) failed.
+//	4   ExitAuthRequired  Not signed in or the credentials have expired.
+//	5   ExitNotFound      A required resource (project, environment, deployment, ...) was not found.
+//	130 ExitInterrupted   The command was interrupted (eg, with Ctrl-C).
+//
+// Commands return errors carrying a code (created with New or Errorf) and the
+// central command runner maps them to the process exit code with FromError.
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	ExitSuccess      = 0
+	ExitError        = 1
+	ExitUsage        = 2
+	ExitBuildFailed  = 3
+	ExitAuthRequired = 4
+	ExitNotFound     = 5
+	ExitInterrupted  = 130
+)
+
+// Error that carries the process exit code to use if the command fails with it.
+type Error struct {
+	Code   int   // Process exit code.
+	Err    error // Underlying error.
+	Silent bool  // Exit with the code without printing the error, eg, to keep the output parseable.
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap err with the given exit code. Returns nil if err is nil.
+func New(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Create a new error with the given exit code and formatted message.
+func Errorf(code int, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Create an error that only sets the process exit code: the command runner exits with the
+// code without printing anything. Used by commands whose output is meant to be parsed and
+// that report the failure through it, eg, 'metaplay version --check', and for failures that
+// have already been reported, eg, usage errors.
+func Silent(code int) error {
+	return &Error{Code: code, Err: fmt.Errorf("exit code %d", code), Silent: true}
+}
+
+// Check whether the error should be reported only through the exit code, see Silent.
+func IsSilent(err error) bool {
+	var exitErr *Error
+	return errors.As(err, &exitErr) && exitErr.Silent
+}
+
+// Resolve the process exit code for an error: the code of the outermost
+// exitcode.Error in the chain, or ExitError if there is none.
+func FromError(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitError
+}
@@ -15,8 +15,8 @@ import (
 // ChooseOrgAndProject fetches all the organizations and projects from the portal (that the user has
 // access to) and then displays an interactive list for the user to choose the project from.
 func ChooseOrgAndProject(tokenSet *auth.TokenSet) (*portalapi.ProjectInfo, error) {
-	if !isInteractiveMode {
-		return nil, fmt.Errorf("interactive mode required for project selection")
+	if !IsInteractive() {
+		return nil, ErrNotInteractive
 	}
 
 	// Get available organizations from the portal.
@@ -6,29 +6,43 @@ package tui
 import (
 	"fmt"
 
+	"github.com/charmbracelet/bubbles/list"
 	"github.com/metaplay/cli/pkg/metaproj"
 	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 )
 
-func ChooseTargetEnvironmentDialog(environments []metaproj.ProjectEnvironmentConfig) (*metaproj.ProjectEnvironmentConfig, error) {
-	if !isInteractiveMode {
-		return nil, fmt.Errorf("interactive mode required for project selection")
+// Let the user choose one of the project's environments with an interactive picker.
+// The environments can be fuzzy searched by their IDs (press '/' to search).
+// Returns the HumanID of the chosen environment.
+func SelectEnvironment(project *metaproj.MetaplayProject) (string, error) {
+	if !IsInteractive() {
+		return "", ErrNotInteractive
 	}
 
-	// Let the user choose the target pod.
-	selected, err := ChooseFromListDialog(
-		"Select Target Environment",
-		environments,
-		func(env *metaproj.ProjectEnvironmentConfig) (string, string) {
-			return env.Name, fmt.Sprintf("[%s]", env.HumanID)
-		},
-	)
+	environments := project.Config.Environments
+	if len(environments) == 0 {
+		return "", fmt.Errorf("no environments configured in the project; add them with 'metaplay update project-environments'")
+	}
+
+	// Convert the environments to list items. The description (HumanID) is used for filtering.
+	listItems := make([]list.Item, len(environments))
+	for ndx, env := range environments {
+		listItems[ndx] = compactListItem{
+			index:       ndx,
+			name:        env.Name,
+			description: fmt.Sprintf("[%s]", env.HumanID),
+		}
+	}
+
+	// Let the user choose the target environment.
+	chosen, err := chooseFromList("Select Target Environment (press / to search)", listItems, true)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	selected := environments[chosen]
 
 	log.Info().Msgf(" %s %s %s", styles.RenderSuccess("✓"), selected.Name, styles.RenderMuted(fmt.Sprintf("[%s]", selected.HumanID)))
 
-	return selected, nil
+	return selected.HumanID, nil
 }
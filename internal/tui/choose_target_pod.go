@@ -42,8 +42,8 @@ func GetStatefulSetDescription(sts *appsv1.StatefulSet) string {
 }
 
 func ChooseTargetPodDialog(pods []corev1.Pod) (*corev1.Pod, error) {
-	if !isInteractiveMode {
-		return nil, fmt.Errorf("interactive mode required for project selection")
+	if !IsInteractive() {
+		return nil, ErrNotInteractive
 	}
 
 	// Let the user choose the target pod.
@@ -78,10 +78,17 @@ func (m compactListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.model.SetWidth(msg.Width)
 		return m, nil
 	case tea.KeyMsg:
+		// While typing a filter, keys other than ctrl+c and enter go to the filter input.
+		isFiltering := m.model.FilterState() == list.Filtering
 		switch msg.String() {
-		case "ctrl+c", "q":
+		case "ctrl+c":
 			m.quitting = true
 			return m, tea.Quit
+		case "q":
+			if !isFiltering {
+				m.quitting = true
+				return m, tea.Quit
+			}
 		case "enter":
 			if item, ok := m.model.SelectedItem().(compactListItem); ok {
 				m.selected = &item
@@ -114,11 +121,17 @@ func min(x, y int) int {
 	return y
 }
 
-func chooseFromList(title string, items []list.Item) (int, error) {
-	// Initialize list with custom delegate
-	list := list.New(items, compactListDelegate{}, 0, min(2+len(items), 20))
+// Let the user choose an item from the list. If filterable is true, the user can fuzzy
+// search the items (by their description) by pressing '/'.
+func chooseFromList(title string, items []list.Item, filterable bool) (int, error) {
+	// Initialize list with custom delegate. Reserve room for the filter input, if enabled.
+	height := min(2+len(items), 20)
+	if filterable {
+		height += 2
+	}
+	list := list.New(items, compactListDelegate{}, 0, height)
 	list.SetShowTitle(false)
-	list.SetFilteringEnabled(false)
+	list.SetFilteringEnabled(filterable)
 	list.SetShowStatusBar(false)
 	list.SetShowHelp(false)
 
@@ -145,6 +158,7 @@ func chooseFromList(title string, items []list.Item) (int, error) {
 // Show a dialog to user to select an item from the provided list.
 // The toItemFunc() is used to convert the items into a (name, description)
 // tuple for display. The selected item in the list is returned (or error).
+// Returns ErrNotInteractive if not in interactive mode.
 func ChooseFromListDialog[TItem any](title string, items []TItem, toItemFunc func(item *TItem) (string, string)) (*TItem, error) {
 	// \todo Bit of a hack to render title first
 	if len(items) == 0 {
@@ -153,6 +167,9 @@ func ChooseFromListDialog[TItem any](title string, items []TItem, toItemFunc fun
 		log.Info().Msg("")
 		return nil, fmt.Errorf("no items in the list")
 	}
+	if !IsInteractive() {
+		return nil, ErrNotInteractive
+	}
 
 	// Convert items to list items.
 	listItems := make([]list.Item, len(items))
@@ -167,7 +184,7 @@ func ChooseFromListDialog[TItem any](title string, items []TItem, toItemFunc fun
 	}
 
 	// Let the user choose list items.
-	chosen, err := chooseFromList(title, listItems)
+	chosen, err := chooseFromList(title, listItems, false)
 	if err != nil {
 		return nil, err
 	}
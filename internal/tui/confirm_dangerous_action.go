@@ -0,0 +1,126 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package tui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/metaplay/cli/pkg/styles"
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned by ConfirmDangerousAction() when the user doesn't confirm the action.
+var ErrCancelled = errors.New("cancelled by user")
+
+// ConfirmDangerousAction asks the user to confirm a destructive action by typing in the name
+// of the target environment. Returns nil if the action was confirmed (or skipConfirm is set),
+// or ErrCancelled if the user typed something else or pressed Ctrl+C. In non-interactive mode,
+// an error is returned unless skipConfirm is set.
+func ConfirmDangerousAction(ctx context.Context, actionDescription string, environmentName string, skipConfirm bool) error {
+	if skipConfirm {
+		return nil
+	}
+
+	if !IsInteractive() {
+		return ErrNotInteractive
+	}
+
+	fmt.Fprintln(os.Stderr, styles.StyleWarning.Render(actionDescription))
+	fmt.Fprint(os.Stderr, "Type the environment name to confirm: ")
+
+	input, err := readInputLine(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(input) != environmentName {
+		return ErrCancelled
+	}
+	return nil
+}
+
+// Read a line of input, returning ErrCancelled if the context gets cancelled while waiting.
+// If stdin is a terminal, it is put into raw mode so that Ctrl+C can be handled as a
+// cancellation (returns ErrCancelled) instead of killing the process with the terminal in an
+// inconsistent state. The terminal is restored before returning, also when the context gets
+// cancelled while the background read is still blocked.
+func readInputLine(ctx context.Context) (string, error) {
+	fd := int(os.Stdin.Fd())
+	isRaw := term.IsTerminal(fd)
+	if isRaw {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return "", fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer term.Restore(fd, state)
+	}
+
+	// Read the input in the background so that we can react to the context getting cancelled.
+	type readResult struct {
+		input string
+		err   error
+	}
+	resultCh := make(chan readResult, 1)
+	go func() {
+		input, err := readConfirmationInput(isRaw)
+		resultCh <- readResult{input, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if isRaw {
+			fmt.Fprint(os.Stderr, "\r\n")
+		} else {
+			fmt.Fprintln(os.Stderr)
+		}
+		return "", ErrCancelled
+	case result := <-resultCh:
+		return result.input, result.err
+	}
+}
+
+// Read a line of input from stdin. In raw mode, the input is echoed and the line editing
+// (backspace) and Ctrl+C are handled here.
+func readConfirmationInput(isRaw bool) (string, error) {
+	if !isRaw {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", ErrCancelled
+		}
+		return line, nil
+	}
+
+	var input []rune
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			fmt.Fprint(os.Stderr, "\r\n")
+			return "", ErrCancelled
+		}
+
+		switch r {
+		case '\r', '\n': // Enter
+			fmt.Fprint(os.Stderr, "\r\n")
+			return string(input), nil
+		case 3, 4: // Ctrl+C, Ctrl+D
+			fmt.Fprint(os.Stderr, "^C\r\n")
+			return "", ErrCancelled
+		case 127, '\b': // Backspace
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+				fmt.Fprint(os.Stderr, "\b \b")
+			}
+		default:
+			if r >= 32 {
+				input = append(input, r)
+				fmt.Fprint(os.Stderr, string(r))
+			}
+		}
+	}
+}
@@ -13,20 +13,22 @@ import (
 
 // Model for the confirmation dialog
 type confirmDialog struct {
-	ctx      context.Context
-	title    string
-	body     string
-	question string
-	choice   bool
-	quitting bool
+	ctx        context.Context
+	title      string
+	body       string
+	question   string
+	defaultYes bool // Answer when the user presses enter.
+	choice     bool
+	quitting   bool
 }
 
-func newConfirmDialog(ctx context.Context, title string, body string, question string) confirmDialog {
+func newConfirmDialog(ctx context.Context, title string, body string, question string, defaultYes bool) confirmDialog {
 	return confirmDialog{
-		ctx:      ctx,
-		title:    title,
-		body:     body,
-		question: question,
+		ctx:        ctx,
+		title:      title,
+		body:       body,
+		question:   question,
+		defaultYes: defaultYes,
 	}
 }
 
@@ -38,10 +40,14 @@ func (m confirmDialog) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "y", "Y", "enter":
+		case "y", "Y":
 			m.choice = true
 			m.quitting = true
 			return m, tea.Quit
+		case "enter":
+			m.choice = m.defaultYes
+			m.quitting = true
+			return m, tea.Quit
 		case "n", "N", "q", "ctrl+c":
 			m.choice = false
 			m.quitting = true
@@ -63,15 +69,28 @@ func (m confirmDialog) View() string {
 
 	// Show question until answered
 	if !m.quitting {
-		content += m.question + styles.RenderPrompt(" [Y/n]") + "\n"
+		if m.defaultYes {
+			content += m.question + styles.RenderPrompt(" [Y/n]") + "\n"
+		} else {
+			content += m.question + styles.RenderPrompt(" [y/N]") + "\n"
+		}
 	}
 
 	return content
 }
 
-// Show the user a confirm dialog and wait for a yes/no answer.
+// Show the user a confirm dialog and wait for a yes/no answer. Returns ErrNotInteractive
+// if not in interactive mode.
 func DoConfirmDialog(ctx context.Context, title string, body string, question string) (bool, error) {
-	p := tea.NewProgram(newConfirmDialog(ctx, title, body, question))
+	return doConfirmDialog(ctx, title, body, question, true)
+}
+
+func doConfirmDialog(ctx context.Context, title string, body string, question string, defaultYes bool) (bool, error) {
+	if !IsInteractive() {
+		return false, ErrNotInteractive
+	}
+
+	p := tea.NewProgram(newConfirmDialog(ctx, title, body, question, defaultYes))
 	m, err := p.Run()
 	if err != nil {
 		return false, fmt.Errorf("failed to run confirmation dialog: %v", err)
@@ -84,3 +103,9 @@ func DoConfirmDialog(ctx context.Context, title string, body string, question st
 func DoConfirmQuestion(ctx context.Context, question string) (bool, error) {
 	return DoConfirmDialog(ctx, "", "", question)
 }
+
+// Show the user a one-line confirm question where pressing enter answers no, for actions
+// the user may not have intended.
+func DoConfirmQuestionDefaultNo(ctx context.Context, question string) (bool, error) {
+	return doConfirmDialog(ctx, "", "", question, false)
+}
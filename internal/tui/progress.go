@@ -0,0 +1,121 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ProgressMode determines how the progress of long operations (run with a TaskRunner) is rendered.
+type ProgressMode string
+
+const (
+	ProgressModeAuto ProgressMode = "auto" // Live status area in interactive mode, plain log lines otherwise.
+	ProgressModeJSON ProgressMode = "json" // Plain log lines, and the phase events as JSON lines on stderr.
+)
+
+// Valid values for the progress mode.
+var ProgressModes = []ProgressMode{ProgressModeAuto, ProgressModeJSON}
+
+// Current progress mode.
+var progressMode = ProgressModeAuto
+
+// Destination of the phase events in ProgressModeJSON.
+var progressJSONWriter io.Writer = os.Stderr
+
+// PhaseState is the state of a phase (task) of a long operation.
+type PhaseState string
+
+const (
+	PhaseStarted   PhaseState = "started"   // The phase has started.
+	PhaseProgress  PhaseState = "progress"  // The phase's status lines have changed, eg, push progress.
+	PhaseCompleted PhaseState = "completed" // The phase completed successfully.
+	PhaseFailed    PhaseState = "failed"    // The phase failed.
+)
+
+// PhaseEvent is emitted by a TaskRunner when one of its phases changes state.
+type PhaseEvent struct {
+	Phase     string     `json:"phase"`               // Title of the phase.
+	State     PhaseState `json:"state"`               // New state of the phase.
+	Status    []string   `json:"status,omitempty"`    // Current status lines, with PhaseProgress.
+	ElapsedMs int64      `json:"elapsedMs,omitempty"` // Time spent in the phase, with PhaseCompleted and PhaseFailed.
+	Error     string     `json:"error,omitempty"`     // Error message, with PhaseFailed.
+	Time      time.Time  `json:"time"`                // Time of the event.
+}
+
+// PhaseObserver receives the phase events of all TaskRunners.
+type PhaseObserver func(event PhaseEvent)
+
+var (
+	phaseObservers   = map[int]PhaseObserver{}
+	nextObserverID   = 0
+	phaseObserversMu sync.Mutex
+)
+
+// Parse the progress mode, eg, from the --progress flag.
+func ParseProgressMode(str string) (ProgressMode, error) {
+	for _, mode := range ProgressModes {
+		if str == string(mode) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("invalid progress mode '%s', must be one of: %v", str, ProgressModes)
+}
+
+// Set the progress mode. ProgressModeJSON starts writing the phase events to stderr.
+func SetProgressMode(mode ProgressMode) {
+	progressMode = mode
+	if mode == ProgressModeJSON {
+		AddPhaseObserver(writePhaseEventJSON)
+	}
+}
+
+// Check whether the live status area is used for the progress of long operations.
+func useLiveProgress() bool {
+	return isInteractiveMode && progressMode == ProgressModeAuto
+}
+
+// Register an observer for the phase events of all TaskRunners, eg, to collect timings.
+// Returns a function that removes the observer.
+func AddPhaseObserver(observer PhaseObserver) func() {
+	phaseObserversMu.Lock()
+	defer phaseObserversMu.Unlock()
+	id := nextObserverID
+	nextObserverID++
+	phaseObservers[id] = observer
+	return func() {
+		phaseObserversMu.Lock()
+		defer phaseObserversMu.Unlock()
+		delete(phaseObservers, id)
+	}
+}
+
+// Send the phase event to all the registered observers.
+func emitPhaseEvent(event PhaseEvent) {
+	event.Time = time.Now()
+	phaseObserversMu.Lock()
+	observers := make([]PhaseObserver, 0, len(phaseObservers))
+	for _, observer := range phaseObservers {
+		observers = append(observers, observer)
+	}
+	phaseObserversMu.Unlock()
+
+	for _, observer := range observers {
+		observer(event)
+	}
+}
+
+// Write the phase event as a line of JSON, used with ProgressModeJSON.
+func writePhaseEventJSON(event PhaseEvent) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(progressJSONWriter, string(line))
+}
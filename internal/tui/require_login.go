@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/metaplay/cli/internal/exitcode"
 	"github.com/metaplay/cli/pkg/auth"
 )
 
@@ -27,8 +28,8 @@ func RequireLoggedIn(ctx context.Context, authProvider *auth.AuthProviderConfig)
 	// If not yet logged in, ask if we should do it.
 
 	// If not in interactive shell, bail out immediately.
-	if !isInteractiveMode {
-		return nil, fmt.Errorf("login required, use 'metaplay auth machine-login' to login in non-interactive environments")
+	if !IsInteractive() {
+		return nil, exitcode.Errorf(exitcode.ExitAuthRequired, "login required, use 'metaplay auth machine-login' to login in non-interactive environments")
 	}
 
 	// Confirm the login operation with the user.
@@ -0,0 +1,39 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+)
+
+// PrintSummaryTable prints a bordered summary box with the title and the key/value rows,
+// eg, to summarize what a command is about to do before doing it.
+func PrintSummaryTable(title string, rows [][2]string) {
+	log.Info().Msg(renderSummaryTable(title, rows))
+}
+
+// Render the summary box as a string.
+func renderSummaryTable(title string, rows [][2]string) string {
+	// Align the values by padding the keys to the same width.
+	keyWidth := 0
+	for _, row := range rows {
+		keyWidth = max(keyWidth, lipgloss.Width(row[0]))
+	}
+
+	lines := []string{styles.StyleTitle.Render(title), ""}
+	for _, row := range rows {
+		key := row[0] + ":" + strings.Repeat(" ", keyWidth-lipgloss.Width(row[0]))
+		lines = append(lines, styles.StyleMuted.Render(key)+"  "+styles.StyleTechnical.Render(row[1]))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.ColorNeutral).
+		Padding(0, 1)
+	return box.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,304 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package tui
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/metaplay/cli/pkg/styles"
+	"golang.org/x/term"
+)
+
+// Terminal width to assume when it cannot be detected.
+const defaultTableWidth = 80
+
+// Below this width, tables are rendered as vertical records instead of columns.
+const tableRecordLayoutWidth = 60
+
+// Space between the columns of a table.
+const tableColumnSeparator = "  "
+
+// Column of a Table.
+type TableColumn struct {
+	Key      string                    // Identifier used to select the column (--columns), eg, 'name'.
+	Title    string                    // Column header, eg, 'NAME'.
+	Priority int                       // Columns with lower priority are truncated and dropped first to fit the terminal.
+	MinWidth int                       // Width down to which the column can be truncated, 0 for the header width.
+	MaxWidth int                       // Width at which the values are truncated even if there is space, 0 for no limit.
+	Style    func(value string) string // Optional styling of the values, applied after truncation.
+}
+
+// Table that adapts to the terminal width: low priority columns are truncated and dropped to
+// fit, narrow terminals get a vertical record layout, and non-terminal outputs get the full
+// values as tab-separated values for processing with tools like awk or cut.
+type Table struct {
+	Columns []TableColumn
+	Rows    [][]string // Plain-text cells, one for each column.
+}
+
+// Options for rendering a Table.
+type TableOptions struct {
+	Columns    []string // Keys of the columns to include (in order), or empty for all columns.
+	NoTruncate bool     // Show the full values, even if they don't fit the terminal.
+	Width      int      // Width to fit the table into.
+	Plain      bool     // Render tab-separated values, without truncation or styling.
+}
+
+// Create the options for rendering a table to stdout: the width is detected from the terminal,
+// and if stdout is not a terminal, the plain tab-separated format is used.
+func NewTableOptions(columns []string, noTruncate bool) TableOptions {
+	opts := TableOptions{
+		Columns:    columns,
+		NoTruncate: noTruncate,
+		Width:      defaultTableWidth,
+	}
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		opts.Plain = true
+	} else if width, _, err := term.GetSize(fd); err == nil && width > 0 {
+		opts.Width = width
+	}
+	return opts
+}
+
+// Create a table with the given columns.
+func NewTable(columns ...TableColumn) *Table {
+	return &Table{Columns: columns}
+}
+
+// Add a row to the table. The number of cells must match the number of columns.
+func (table *Table) AddRow(cells ...string) {
+	if len(cells) != len(table.Columns) {
+		panic(fmt.Sprintf("table row has %d cells, expecting %d", len(cells), len(table.Columns)))
+	}
+	table.Rows = append(table.Rows, cells)
+}
+
+// Get the keys of the table's columns, eg, for listing the valid values for --columns.
+func (table *Table) ColumnKeys() []string {
+	keys := make([]string, len(table.Columns))
+	for ndx, column := range table.Columns {
+		keys[ndx] = column.Key
+	}
+	return keys
+}
+
+// Render the table into lines of output.
+func (table *Table) Render(opts TableOptions) ([]string, error) {
+	columnNdxs, err := table.resolveColumns(opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Plain {
+		return table.renderTSV(columnNdxs), nil
+	}
+	if !opts.NoTruncate && opts.Width < tableRecordLayoutWidth {
+		return table.renderRecords(columnNdxs, opts.Width), nil
+	}
+
+	// Compute the natural widths of the columns, limited by their maximum widths.
+	widths := make([]int, len(columnNdxs))
+	for ndx, columnNdx := range columnNdxs {
+		column := table.Columns[columnNdx]
+		widths[ndx] = lipgloss.Width(column.Title)
+		for _, row := range table.Rows {
+			widths[ndx] = max(widths[ndx], lipgloss.Width(row[columnNdx]))
+		}
+		if !opts.NoTruncate && column.MaxWidth > 0 {
+			widths[ndx] = min(widths[ndx], max(column.MaxWidth, lipgloss.Width(column.Title)))
+		}
+	}
+
+	// Fit the table into the terminal. Explicitly selected columns are never dropped.
+	if !opts.NoTruncate {
+		columnNdxs, widths = table.fitColumns(columnNdxs, widths, opts.Width, len(opts.Columns) == 0)
+		if columnNdxs == nil {
+			return table.renderRecords(table.mustResolveColumns(opts.Columns), opts.Width), nil
+		}
+	}
+
+	// Render the header and the rows.
+	lines := []string{}
+	header := make([]string, len(columnNdxs))
+	for ndx, columnNdx := range columnNdxs {
+		header[ndx] = padTableCell(table.Columns[columnNdx].Title, widths[ndx])
+	}
+	lines = append(lines, styles.RenderMuted(strings.TrimRight(strings.Join(header, tableColumnSeparator), " ")))
+	for _, row := range table.Rows {
+		cells := make([]string, len(columnNdxs))
+		for ndx, columnNdx := range columnNdxs {
+			value := truncateTableCell(row[columnNdx], widths[ndx])
+			padding := strings.Repeat(" ", widths[ndx]-lipgloss.Width(value))
+			if style := table.Columns[columnNdx].Style; style != nil && value != "" {
+				value = style(value)
+			}
+			cells[ndx] = value + padding
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, tableColumnSeparator), " "))
+	}
+	return lines, nil
+}
+
+// Resolve the indexes of the columns to render, in order. All columns are rendered by default.
+func (table *Table) resolveColumns(keys []string) ([]int, error) {
+	if len(keys) == 0 {
+		ndxs := make([]int, len(table.Columns))
+		for ndx := range table.Columns {
+			ndxs[ndx] = ndx
+		}
+		return ndxs, nil
+	}
+
+	ndxs := []int{}
+	for _, key := range keys {
+		ndx := slices.IndexFunc(table.Columns, func(column TableColumn) bool { return column.Key == strings.TrimSpace(key) })
+		if ndx < 0 {
+			return nil, fmt.Errorf("unknown column %q, valid columns are: %s", key, strings.Join(table.ColumnKeys(), ", "))
+		}
+		ndxs = append(ndxs, ndx)
+	}
+	return ndxs, nil
+}
+
+// Resolve the columns that have already been validated.
+func (table *Table) mustResolveColumns(keys []string) []int {
+	ndxs, err := table.resolveColumns(keys)
+	if err != nil {
+		panic(err)
+	}
+	return ndxs
+}
+
+// Fit the columns into the width, going through the columns from the lowest priority: truncate
+// the column down to its minimum width and, if the table still doesn't fit, drop the column (if
+// allowed). The highest priority column is never dropped. Returns nil if the columns cannot be
+// fit.
+func (table *Table) fitColumns(columnNdxs []int, widths []int, maxWidth int, allowDrop bool) ([]int, []int) {
+	// Order the columns by priority, lowest first. Of equal priority columns, the rightmost
+	// column goes first.
+	byPriority := make([]int, len(columnNdxs))
+	for ndx := range byPriority {
+		byPriority[ndx] = ndx
+	}
+	slices.SortStableFunc(byPriority, func(a, b int) int {
+		if diff := table.Columns[columnNdxs[a]].Priority - table.Columns[columnNdxs[b]].Priority; diff != 0 {
+			return diff
+		}
+		return b - a
+	})
+
+	dropped := make([]bool, len(columnNdxs))
+	totalWidth := func() int {
+		total := -len(tableColumnSeparator)
+		for ndx, width := range widths {
+			if !dropped[ndx] {
+				total += width + len(tableColumnSeparator)
+			}
+		}
+		return total
+	}
+
+	for priorityNdx, ndx := range byPriority {
+		excess := totalWidth() - maxWidth
+		if excess <= 0 {
+			break
+		}
+
+		column := table.Columns[columnNdxs[ndx]]
+		minWidth := column.MinWidth
+		if minWidth == 0 {
+			minWidth = lipgloss.Width(column.Title)
+		}
+		widths[ndx] = max(min(widths[ndx], minWidth), widths[ndx]-excess)
+
+		if totalWidth() > maxWidth && allowDrop && priorityNdx < len(byPriority)-1 {
+			dropped[ndx] = true
+		}
+	}
+	if totalWidth() > maxWidth {
+		return nil, nil
+	}
+
+	keptNdxs, keptWidths := []int{}, []int{}
+	for ndx := range columnNdxs {
+		if !dropped[ndx] {
+			keptNdxs = append(keptNdxs, columnNdxs[ndx])
+			keptWidths = append(keptWidths, widths[ndx])
+		}
+	}
+	return keptNdxs, keptWidths
+}
+
+// Render the rows as vertical records of 'Title: value' lines, separated by empty lines.
+func (table *Table) renderRecords(columnNdxs []int, maxWidth int) []string {
+	titleWidth := 0
+	for _, columnNdx := range columnNdxs {
+		titleWidth = max(titleWidth, lipgloss.Width(table.Columns[columnNdx].Title)+1)
+	}
+
+	lines := []string{}
+	for rowNdx, row := range table.Rows {
+		if rowNdx > 0 {
+			lines = append(lines, "")
+		}
+		for _, columnNdx := range columnNdxs {
+			column := table.Columns[columnNdx]
+			value := truncateTableCell(row[columnNdx], max(maxWidth-titleWidth-1, 1))
+			if column.Style != nil && value != "" {
+				value = column.Style(value)
+			}
+			lines = append(lines, styles.RenderMuted(padTableCell(column.Title+":", titleWidth))+" "+value)
+		}
+	}
+	return lines
+}
+
+// Render the rows as tab-separated values with a header line, without truncation or styling.
+func (table *Table) renderTSV(columnNdxs []int) []string {
+	toLine := func(cells []string) string {
+		values := make([]string, len(columnNdxs))
+		for ndx, columnNdx := range columnNdxs {
+			values[ndx] = strings.ReplaceAll(cells[columnNdx], "\t", " ")
+		}
+		return strings.Join(values, "\t")
+	}
+
+	titles := make([]string, len(table.Columns))
+	for ndx, column := range table.Columns {
+		titles[ndx] = column.Title
+	}
+	lines := []string{toLine(titles)}
+	for _, row := range table.Rows {
+		lines = append(lines, toLine(row))
+	}
+	return lines
+}
+
+// Pad the value with spaces to the width.
+func padTableCell(value string, width int) string {
+	return value + strings.Repeat(" ", max(width-lipgloss.Width(value), 0))
+}
+
+// Truncate the value to the width, marking the truncation with an ellipsis.
+func truncateTableCell(value string, width int) string {
+	if lipgloss.Width(value) <= width {
+		return value
+	}
+	var result strings.Builder
+	resultWidth := 0
+	for _, r := range value {
+		runeWidth := lipgloss.Width(string(r))
+		if resultWidth+runeWidth > width-1 {
+			break
+		}
+		result.WriteRune(r)
+		resultWidth += runeWidth
+	}
+	return result.String() + "…"
+}
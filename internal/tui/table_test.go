@@ -0,0 +1,116 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func newTestTable() *Table {
+	table := NewTable(
+		TableColumn{Key: "name", Title: "NAME", Priority: 3},
+		TableColumn{Key: "id", Title: "ID", Priority: 2},
+		TableColumn{Key: "domain", Title: "DOMAIN", Priority: 1, MinWidth: 8},
+	)
+	table.AddRow("Development", "tough-falcons", "p1.metaplay.io")
+	table.AddRow("Production", "lovely-wombats-prod", "stack.production.example.com")
+	return table
+}
+
+func TestTableRender(t *testing.T) {
+	table := newTestTable()
+
+	// Wide terminal: everything fits.
+	lines, err := table.Render(TableOptions{Width: 120})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 || !strings.Contains(lines[2], "stack.production.example.com") {
+		t.Errorf("unexpected lines:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// Narrower terminal: the lowest priority column is truncated with an ellipsis.
+	lines, err = table.Render(TableOptions{Width: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range lines {
+		if lipgloss.Width(line) > 60 {
+			t.Errorf("line wider than 60: %q", line)
+		}
+	}
+	if !strings.Contains(lines[2], "…") || !strings.Contains(lines[2], "lovely-wombats-prod") {
+		t.Errorf("expected the domain to be truncated:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// With --no-trunc, the values are never truncated.
+	lines, err = table.Render(TableOptions{Width: 60, NoTruncate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(lines[2], "stack.production.example.com") {
+		t.Errorf("expected the full values:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// Narrow terminal: vertical records.
+	lines, err = table.Render(TableOptions{Width: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 7 || !strings.Contains(lines[4], "NAME:") || !strings.Contains(lines[4], "Production") {
+		t.Errorf("expected records:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// Plain output: tab-separated full values of the selected columns.
+	lines, err = table.Render(TableOptions{Width: 40, Plain: true, Columns: []string{"id", "domain"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 || lines[0] != "ID\tDOMAIN" || lines[2] != "lovely-wombats-prod\tstack.production.example.com" {
+		t.Errorf("unexpected TSV:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// Unknown columns are rejected.
+	if _, err := table.Render(TableOptions{Width: 120, Columns: []string{"unknown"}}); err == nil || !strings.Contains(err.Error(), "name, id, domain") {
+		t.Errorf("expected an unknown column error, got: %v", err)
+	}
+}
+
+func TestTableDropColumns(t *testing.T) {
+	table := NewTable(
+		TableColumn{Key: "name", Title: "NAME", Priority: 2, MinWidth: 20},
+		TableColumn{Key: "description", Title: "DESCRIPTION", Priority: 1, MinWidth: 40},
+	)
+	table.AddRow(strings.Repeat("n", 30), strings.Repeat("d", 50))
+
+	// The low priority column cannot be truncated enough, so it is dropped.
+	lines, err := table.Render(TableOptions{Width: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(lines[0], "DESCRIPTION") || !strings.Contains(lines[0], "NAME") {
+		t.Errorf("expected the description to be dropped:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// Explicitly selected columns are not dropped, but truncated further.
+	lines, err = table.Render(TableOptions{Width: 64, Columns: []string{"name", "description"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(lines[0], "DESCRIPTION") || lipgloss.Width(lines[1]) > 64 {
+		t.Errorf("expected both columns truncated:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// If the selected columns cannot be fit, the records layout is used instead.
+	lines, err = table.Render(TableOptions{Width: 60, Columns: []string{"name", "description"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || !strings.Contains(lines[1], "DESCRIPTION:") {
+		t.Errorf("expected records:\n%s", strings.Join(lines, "\n"))
+	}
+}
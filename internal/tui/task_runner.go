@@ -28,40 +28,53 @@ const (
 // Spinner frames for the running state
 var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
-// TaskOutput contains the outputs from a given task and is shown along with the task's status.
+// TaskOutput contains the outputs from a given task. In the live status area, the header and
+// footer lines are shown along with the task's status and the log lines are printed above it.
 type TaskOutput struct {
 	headerLines []string   // Header lines (all are shown, updates are logged)
 	logLines    []string   // Append-only log lines of output (only 5 are shown)
 	footerLines []string   // Footer lines (all are shown, updates are logged)
 	mu          sync.Mutex // Protects the lines slice
+	phase       string     // Title of the task, for the phase events (empty for standalone outputs)
+
+	onLine   func(line string)    // If set, log lines are forwarded here instead of being logged
+	onStatus func(lines []string) // If set, header and footer updates are forwarded here instead of being logged
 }
 
 // TaskRunFunc is the function signature for task execution functions
 type TaskRunFunc func(output *TaskOutput) error
 
+// Create a standalone TaskOutput that is not rendered by a TaskRunner. Instead, the appended
+// lines are forwarded to onLine and the header and footer updates to onStatus (either can be
+// nil to discard the output). Used when running tasks from library code.
+func NewCallbackTaskOutput(onLine func(line string), onStatus func(lines []string)) *TaskOutput {
+	if onLine == nil {
+		onLine = func(string) {}
+	}
+	if onStatus == nil {
+		onStatus = func([]string) {}
+	}
+	return &TaskOutput{onLine: onLine, onStatus: onStatus}
+}
+
 // Append a new line at the end of the output.
 func (to *TaskOutput) AppendLine(line string) {
 	to.mu.Lock()
 	to.logLines = append(to.logLines, line)
 	to.mu.Unlock()
 
-	// If not in interactive mode, log line.
-	if !isInteractiveMode {
+	// Forward to callback (in the live status area, the line is printed above it), or if not
+	// in interactive mode, log line.
+	if to.onLine != nil {
+		to.onLine(line)
+	} else if !useLiveProgress() {
 		log.Info().Msgf("  %s", line)
 	}
 }
 
 // AppendLinef appends a new formatted line at the end of the output using fmt.Sprintf.
 func (to *TaskOutput) AppendLinef(format string, a ...interface{}) {
-	line := fmt.Sprintf(format, a...)
-	to.mu.Lock()
-	to.logLines = append(to.logLines, line)
-	to.mu.Unlock()
-
-	// If not in interactive mode, log line.
-	if !isInteractiveMode {
-		log.Info().Msgf("  %s", line)
-	}
+	to.AppendLine(fmt.Sprintf(format, a...))
 }
 
 // Update the header lines to the provided ones. Also logged in non-interactive mode.
@@ -70,12 +83,7 @@ func (to *TaskOutput) SetHeaderLines(lines []string) {
 	to.headerLines = lines
 	to.mu.Unlock()
 
-	// If not in interactive mode, log the lines.
-	if !isInteractiveMode {
-		for _, line := range lines {
-			log.Info().Msgf("  %s", line)
-		}
-	}
+	to.reportStatusLines()
 }
 
 // Update the footer lines to the provided ones. Also logged in non-interactive mode.
@@ -84,37 +92,33 @@ func (to *TaskOutput) SetFooterLines(lines []string) {
 	to.footerLines = lines
 	to.mu.Unlock()
 
-	// If not in interactive mode, log the lines.
-	if !isInteractiveMode {
+	to.reportStatusLines()
+}
+
+// Forward the updated header and footer lines to the callback, or log them if not in interactive
+// mode. Also emits the phase progress event.
+func (to *TaskOutput) reportStatusLines() {
+	lines := to.getStatusLines()
+	if to.onStatus != nil {
+		to.onStatus(lines)
+	} else if !useLiveProgress() {
 		for _, line := range lines {
 			log.Info().Msgf("  %s", line)
 		}
 	}
+	if to.phase != "" {
+		emitPhaseEvent(PhaseEvent{Phase: to.phase, State: PhaseProgress, Status: lines})
+	}
 }
 
-// getLines returns a copy of the current output lines.
-func (to *TaskOutput) getLines() []string {
+// getStatusLines returns a copy of the current header and footer lines.
+func (to *TaskOutput) getStatusLines() []string {
 	to.mu.Lock()
 	defer to.mu.Unlock()
 
-	// Get the most recent log lines (up to numLinesToShow)
-	numLinesToShow := 10
-	shownLogLines := to.logLines
-	if len(shownLogLines) > numLinesToShow {
-		shownLogLines = shownLogLines[len(shownLogLines)-numLinesToShow:]
-	}
-
-	// Calculate total size needed
-	totalSize := len(to.headerLines) + len(shownLogLines) + len(to.footerLines)
-
-	// Create a result slice with the right capacity
-	result := make([]string, 0, totalSize)
-
-	// Append all sections in the correct order
+	result := make([]string, 0, len(to.headerLines)+len(to.footerLines))
 	result = append(result, to.headerLines...)
-	result = append(result, shownLogLines...)
 	result = append(result, to.footerLines...)
-
 	return result
 }
 
@@ -138,6 +142,8 @@ type TaskRunner struct {
 	frameIndex int           // Current frame index for spinner animation
 	lastTick   time.Time     // Last time the spinner was updated
 	program    *tea.Program  // Reference to the tea program for quitting
+
+	pendingLogLines []string // Task log lines to print above the live status area
 }
 
 // tickMsg is sent when the spinner should advance one frame
@@ -146,6 +152,9 @@ type tickMsg struct{}
 // doneMsg is sent when all tasks have completed or failed
 type doneMsg struct{ err error }
 
+// taskLogMsg is sent when a task appends a log line, to print it above the live status area
+type taskLogMsg struct{ line string }
+
 // NewTaskRunner creates a new TaskRunner
 func NewTaskRunner() *TaskRunner {
 	return &TaskRunner{
@@ -163,6 +172,7 @@ func (m *TaskRunner) AddTask(title string, runFunc TaskRunFunc) {
 		runFunc: runFunc,
 		status:  StatusPending,
 	}
+	task.output.phase = title
 
 	// Add to runner
 	m.tasks = append(m.tasks, task)
@@ -198,9 +208,42 @@ func (m *TaskRunner) getStatusSymbol(status TaskStatus) string {
 	}
 }
 
-// Run starts executing tasks sequentially and displays the progress
+// Mark the task as running and emit the phase event.
+func (task *Task) start() {
+	task.mu.Lock()
+	task.status = StatusRunning
+	task.startTime = time.Now()
+	task.mu.Unlock()
+
+	emitPhaseEvent(PhaseEvent{Phase: task.title, State: PhaseStarted})
+}
+
+// Mark the task as completed (or failed, if err is non-nil) and emit the phase event.
+// Returns the time elapsed while running the task.
+func (task *Task) finish(err error) time.Duration {
+	task.mu.Lock()
+	elapsed := time.Since(task.startTime)
+	task.elapsed = elapsed
+	if err != nil {
+		task.status = StatusFailed
+		task.error = err
+	} else {
+		task.status = StatusCompleted
+	}
+	task.mu.Unlock()
+
+	if err != nil {
+		emitPhaseEvent(PhaseEvent{Phase: task.title, State: PhaseFailed, ElapsedMs: elapsed.Milliseconds(), Error: err.Error()})
+	} else {
+		emitPhaseEvent(PhaseEvent{Phase: task.title, State: PhaseCompleted, ElapsedMs: elapsed.Milliseconds()})
+	}
+	return elapsed
+}
+
+// Run starts executing tasks sequentially and displays the progress: in a live status area
+// when in interactive mode (see ProgressMode), or as plain log lines otherwise.
 func (m *TaskRunner) Run() error {
-	if isInteractiveMode {
+	if useLiveProgress() {
 		return m.runInteractive()
 	}
 	return m.runNonInteractive()
@@ -211,6 +254,13 @@ func (m *TaskRunner) runInteractive() error {
 	// Create and store the program instance
 	m.program = tea.NewProgram(m)
 
+	// Print the tasks' log lines above the live status area.
+	for _, task := range m.tasks {
+		task.output.onLine = func(line string) {
+			m.program.Send(taskLogMsg{line: line})
+		}
+	}
+
 	// Start task execution in background
 	go m.executeTasks()
 
@@ -230,27 +280,12 @@ func (m *TaskRunner) runNonInteractive() error {
 	for _, task := range m.tasks {
 		log.Info().Msgf("%s...", task.title)
 
-		task.mu.Lock()
-		task.status = StatusRunning
-		task.startTime = time.Now()
-		task.mu.Unlock()
-
+		task.start()
 		if err := task.runFunc(&task.output); err != nil {
-			task.mu.Lock()
-			task.elapsed = time.Since(task.startTime)
-			task.status = StatusFailed
-			task.error = err
-			task.mu.Unlock()
-
-			// log.Error().Msgf(styleError.Render("ERROR: %v"), err)
+			task.finish(err)
 			return err
 		}
-
-		task.mu.Lock()
-		task.status = StatusCompleted
-		elapsed := time.Since(task.startTime)
-		task.elapsed = elapsed
-		task.mu.Unlock()
+		elapsed := task.finish(nil)
 
 		log.Info().Msgf(" %s %s %s", styles.RenderSuccess("✓"), "Done", humanizeElapsed(elapsed))
 	}
@@ -284,29 +319,18 @@ func (m *TaskRunner) executeTasks() {
 	var firstError error
 	for _, task := range m.tasks {
 		// Update task status to running and start timing
-		task.mu.Lock()
-		task.status = StatusRunning
-		task.startTime = time.Now()
-		task.mu.Unlock()
+		task.start()
 
 		// Execute the task
 		log.Debug().Msgf("Task start: %s", task.title)
 		if err := task.runFunc(&task.output); err != nil {
-			task.mu.Lock()
-			task.elapsed = time.Since(task.startTime)
-			task.status = StatusFailed
-			task.error = err
-			task.mu.Unlock()
+			task.finish(err)
 			if firstError == nil {
 				firstError = err
 			}
 			break
 		} else {
-			task.mu.Lock()
-			task.status = StatusCompleted
-			elapsed := time.Since(task.startTime)
-			task.elapsed = elapsed
-			task.mu.Unlock()
+			elapsed := task.finish(nil)
 			log.Debug().Msgf("Task completed: %s %s", task.title, humanizeElapsed(elapsed))
 		}
 	}
@@ -359,13 +383,37 @@ func (m TaskRunner) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.lastTick = time.Now()
 			}
 		}
+		// Print the pending log lines above the live status area.
+		if printCmd := m.flushLogLines(); printCmd != nil {
+			return m, tea.Batch(printCmd, m.tick())
+		}
 		return m, m.tick()
+	case taskLogMsg:
+		m.pendingLogLines = append(m.pendingLogLines, msg.line)
 	case doneMsg:
+		// Print the remaining log lines before quitting.
+		if printCmd := m.flushLogLines(); printCmd != nil {
+			return m, tea.Sequence(printCmd, tea.Quit)
+		}
 		return m, tea.Quit
 	}
 	return m, nil
 }
 
+// flushLogLines returns a command to print the pending log lines above the live status area,
+// or nil if there are none.
+func (m *TaskRunner) flushLogLines() tea.Cmd {
+	if len(m.pendingLogLines) == 0 {
+		return nil
+	}
+	lines := make([]string, len(m.pendingLogLines))
+	for ndx, line := range m.pendingLogLines {
+		lines[ndx] = fmt.Sprintf("    %s", styles.RenderMuted(line))
+	}
+	m.pendingLogLines = nil
+	return tea.Println(strings.Join(lines, "\n"))
+}
+
 // humanizeElapsed formats a duration as seconds with one decimal place
 func humanizeElapsed(d time.Duration) string {
 	return styles.RenderMuted(fmt.Sprintf("[%.1fs]", d.Seconds()))
@@ -383,7 +431,7 @@ func (m TaskRunner) View() string {
 		err := task.error
 		title := task.title
 		elapsed := task.elapsed
-		outputLines := task.output.getLines()
+		outputLines := task.output.getStatusLines()
 		task.mu.Unlock()
 
 		statusStyle := taskStatusStyle(status)
@@ -399,7 +447,7 @@ func (m TaskRunner) View() string {
 		}
 		lines = append(lines, taskLine)
 
-		// Add output lines if there are any, indented by 4 spaces
+		// Add status lines if there are any, indented by 4 spaces
 		for _, outputLine := range outputLines {
 			lines = append(lines, fmt.Sprintf("    %s", styles.RenderMuted(outputLine)))
 		}
@@ -0,0 +1,156 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Capture the messages logged during the test.
+func captureLogMessages(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	oldLogger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { log.Logger = oldLogger })
+	return &buf
+}
+
+// Parse the messages of the captured JSON log lines.
+func logMessages(t *testing.T, buf *bytes.Buffer) []string {
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		messages = append(messages, entry.Message)
+	}
+	return messages
+}
+
+// Use the given interactive mode and progress mode for the duration of the test.
+func useProgressMode(t *testing.T, interactive bool, mode ProgressMode) {
+	oldInteractive, oldMode := isInteractiveMode, progressMode
+	isInteractiveMode = interactive
+	progressMode = mode
+	t.Cleanup(func() {
+		isInteractiveMode = oldInteractive
+		progressMode = oldMode
+	})
+}
+
+func newTestTaskRunner(failSecond bool) *TaskRunner {
+	runner := NewTaskRunner()
+	runner.AddTask("Push image", func(output *TaskOutput) error {
+		output.AppendLine("pushing layer 1")
+		output.SetFooterLines([]string{"Progress: 100%"})
+		return nil
+	})
+	runner.AddTask("Upgrade release", func(output *TaskOutput) error {
+		output.AppendLine("upgrading")
+		if failSecond {
+			return errors.New("upgrade failed")
+		}
+		return nil
+	})
+	return runner
+}
+
+func TestTaskRunnerPlainFallback(t *testing.T) {
+	// Without a terminal (or with --plain), the tasks are logged as sequential lines without
+	// any live status area elements, to keep CI logs clean.
+	for _, test := range []struct {
+		name        string
+		interactive bool
+		mode        ProgressMode
+	}{
+		{"non-interactive", false, ProgressModeAuto},
+		{"json", false, ProgressModeJSON},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			useProgressMode(t, test.interactive, test.mode)
+			buf := captureLogMessages(t)
+
+			if err := newTestTaskRunner(false).Run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			messages := logMessages(t, buf)
+			expectedPrefixes := []string{"Push image...", "  pushing layer 1", "  Progress: 100%", " ✓ Done", "Upgrade release...", "  upgrading", " ✓ Done", ""}
+			if len(messages) != len(expectedPrefixes) {
+				t.Fatalf("logged %q, expected %d lines", messages, len(expectedPrefixes))
+			}
+			for ndx, prefix := range expectedPrefixes {
+				if !strings.HasPrefix(messages[ndx], prefix) {
+					t.Errorf("line %d = %q, expected prefix %q", ndx, messages[ndx], prefix)
+				}
+			}
+			for _, message := range messages {
+				if strings.Contains(message, "\x1b[") || strings.ContainsAny(message, strings.Join(spinnerFrames, "")) {
+					t.Errorf("unexpected terminal control characters in %q", message)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskRunnerPhaseEvents(t *testing.T) {
+	useProgressMode(t, false, ProgressModeJSON)
+	captureLogMessages(t)
+
+	var buf bytes.Buffer
+	oldWriter := progressJSONWriter
+	progressJSONWriter = &buf
+	defer func() { progressJSONWriter = oldWriter }()
+	removeObserver := AddPhaseObserver(writePhaseEventJSON)
+	defer removeObserver()
+
+	err := newTestTaskRunner(true).Run()
+	if err == nil {
+		t.Fatalf("expected the second task to fail")
+	}
+
+	var events []PhaseEvent
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event PhaseEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid JSON phase event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+
+	expected := []struct {
+		phase string
+		state PhaseState
+	}{
+		{"Push image", PhaseStarted},
+		{"Push image", PhaseProgress},
+		{"Push image", PhaseCompleted},
+		{"Upgrade release", PhaseStarted},
+		{"Upgrade release", PhaseFailed},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("got %d events (%+v), expected %d", len(events), events, len(expected))
+	}
+	for ndx, exp := range expected {
+		if events[ndx].Phase != exp.phase || events[ndx].State != exp.state {
+			t.Errorf("event %d = %s/%s, expected %s/%s", ndx, events[ndx].Phase, events[ndx].State, exp.phase, exp.state)
+		}
+	}
+	if status := events[1].Status; len(status) != 1 || status[0] != "Progress: 100%" {
+		t.Errorf("progress status = %q, expected [\"Progress: 100%%\"]", status)
+	}
+	if events[4].Error != "upgrade failed" {
+		t.Errorf("failed event error = %q, expected %q", events[4].Error, "upgrade failed")
+	}
+}
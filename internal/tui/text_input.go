@@ -0,0 +1,48 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/metaplay/cli/pkg/styles"
+)
+
+// DoTextInputQuestion asks the user to type in a value, eg, a path. An empty input selects the
+// default value, if one is given. The value is checked with validateFunc (if not nil) and asked
+// again until it is valid. Returns ErrCancelled if the user presses Ctrl+C, or ErrNotInteractive
+// in non-interactive mode.
+func DoTextInputQuestion(ctx context.Context, question string, defaultValue string, validateFunc func(value string) error) (string, error) {
+	if !IsInteractive() {
+		return "", ErrNotInteractive
+	}
+
+	prompt := question + ": "
+	if defaultValue != "" {
+		prompt = fmt.Sprintf("%s %s: ", question, styles.RenderMuted(fmt.Sprintf("[%s]", defaultValue)))
+	}
+
+	for {
+		fmt.Fprint(os.Stderr, prompt)
+		input, err := readInputLine(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		value := strings.TrimSpace(input)
+		if value == "" {
+			value = defaultValue
+		}
+		if validateFunc != nil {
+			if err := validateFunc(value); err != nil {
+				fmt.Fprintln(os.Stderr, styles.StyleWarning.Render(err.Error()))
+				continue
+			}
+		}
+		return value, nil
+	}
+}
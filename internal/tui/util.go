@@ -3,11 +3,25 @@
  */
 package tui
 
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/term"
+)
+
 // Is the UI library in interactive mode?
 var isInteractiveMode = true
 
-func IsInteractiveMode() bool {
-	return isInteractiveMode
+// ErrNotInteractive is returned by the dialogs when they cannot be shown because the UI is not
+// in interactive mode (eg, with --plain or without a terminal).
+var ErrNotInteractive = errors.New("cannot prompt the user in non-interactive mode, confirm the action with the command's flags instead (eg, --yes)")
+
+// IsInteractive returns whether the user can be prompted for input: the UI is in interactive
+// mode and stdin is a terminal. Prompts fail immediately with ErrNotInteractive otherwise, so
+// commands should check this and require explicit flags (eg, --yes) instead of prompting.
+func IsInteractive() bool {
+	return isInteractiveMode && term.IsTerminal(int(os.Stdin.Fd()))
 }
 
 // Set the interactive mode of the UI library.
@@ -19,6 +19,7 @@ const devBuild = "dev"
 var (
 	AppVersion = devBuild         // In release builds this will be overwritten via ldflags
 	GitCommit  = "unknown-commit" // -"-
+	BuildDate  = "unknown-date"   // -"-
 )
 
 func IsDevBuild() bool {
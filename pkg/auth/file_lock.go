@@ -0,0 +1,64 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Interval for polling a lock file held by another process.
+const fileLockPollInterval = 50 * time.Millisecond
+
+// Exclusive advisory lock on a file (flock on Unix, LockFileEx on Windows), used to serialize
+// the read-modify-write of files shared by concurrent CLI processes. The lock is released by
+// the OS if the process dies.
+type fileLock struct {
+	file *os.File
+}
+
+// Acquire an exclusive lock on the file at path, creating it if needed. Waits for the lock
+// until the timeout, after which the lock is considered stale (eg, held by a hung process)
+// and an error is returned instead of waiting forever.
+func acquireFileLock(path string, timeout time.Duration) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			holder, _ := os.ReadFile(path)
+			file.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for the lock on %s held by another Metaplay CLI process (pid %s)", timeout, path, strings.TrimSpace(string(holder)))
+		}
+		time.Sleep(fileLockPollInterval)
+	}
+
+	// Record the holder for diagnostics.
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+	}
+	return &fileLock{file: file}, nil
+}
+
+// Release the lock.
+func (lock *fileLock) release() error {
+	if err := unlockFile(lock.file); err != nil {
+		lock.file.Close()
+		return fmt.Errorf("failed to unlock %s: %w", lock.file.Name(), err)
+	}
+	return lock.file.Close()
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package auth
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Try to acquire an exclusive flock on the file without blocking. Returns false if the file is
+// locked by someone else.
+func tryLockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release the flock on the file.
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
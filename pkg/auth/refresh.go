@@ -17,8 +17,12 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// Returned by refreshTokenSet() when the token endpoint rejects the refresh, eg, because the
+// refresh token has expired or already been used.
+var errRefreshTokenRejected = errors.New("the refresh token was rejected")
+
 // Get the expires-at of the access token of the tokenSet.
-func getAccessTokenExpiresAt(tokenSet *TokenSet) (time.Time, error) {
+func GetAccessTokenExpiresAt(tokenSet *TokenSet) (time.Time, error) {
 	// Parse the token without validation
 	token, _, err := jwt.NewParser().ParseUnverified(tokenSet.AccessToken, jwt.MapClaims{})
 	if err != nil {
@@ -38,6 +42,30 @@ func getAccessTokenExpiresAt(tokenSet *TokenSet) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("failed to parse claims")
 }
 
+// Get a human-readable identity of the user from the tokenSet without contacting
+// the auth provider. Uses the email in the ID token if available, and falls back
+// to the subject of the access token (eg, for machine users).
+func GetTokenSetUserIdentity(tokenSet *TokenSet) string {
+	for _, tokenStr := range []string{tokenSet.IDToken, tokenSet.AccessToken} {
+		if tokenStr == "" {
+			continue
+		}
+		token, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+		if err != nil {
+			continue
+		}
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if email, ok := claims["email"].(string); ok && email != "" {
+				return email
+			}
+			if sub, ok := claims["sub"].(string); ok && sub != "" {
+				return sub
+			}
+		}
+	}
+	return "unknown"
+}
+
 // Load the current token set. If not logged in, just return empty tokens.
 // If logged in and tokens have expired, refresh the tokens. If the refresh
 // fails, return an error.
@@ -56,7 +84,7 @@ func LoadAndRefreshTokenSet(authProvider *AuthProviderConfig) (*TokenSet, error)
 
 	// Resolve when access token expires.
 	tokenSet := sessionState.TokenSet
-	expiresAt, err := getAccessTokenExpiresAt(tokenSet)
+	expiresAt, err := GetAccessTokenExpiresAt(tokenSet)
 
 	// Compare expiration time with the current time
 	isExpired := time.Now().After(expiresAt)
@@ -64,16 +92,10 @@ func LoadAndRefreshTokenSet(authProvider *AuthProviderConfig) (*TokenSet, error)
 	// Refresh the tokenSet (if we have a refresh token -- machine users do not).
 	if isExpired {
 		if tokenSet.RefreshToken != "" {
-			// Refresh the tokenSet.
-			tokenSet, err = refreshTokenSet(tokenSet, authProvider)
-			if err != nil {
-				return nil, fmt.Errorf("failed to refresh tokens: %w", err)
-			}
-
-			// Persist the refreshed tokens.
-			err = SaveSessionState(authProvider.GetSessionID(), sessionState.UserType, tokenSet)
+			// Refresh and persist the tokenSet.
+			tokenSet, err = refreshSessionTokenSet(authProvider)
 			if err != nil {
-				return nil, fmt.Errorf("failed to persist refreshed tokens: %w", err)
+				return nil, err
 			}
 		} else {
 			return nil, fmt.Errorf("access token has expired and there is no refresh token")
@@ -83,6 +105,64 @@ func LoadAndRefreshTokenSet(authProvider *AuthProviderConfig) (*TokenSet, error)
 	return tokenSet, nil
 }
 
+// Refresh the tokens of the current session and persist them, while holding the lock on the
+// persisted config. This serializes concurrent CLI processes refreshing the same session (eg,
+// parallel CI jobs): as a refresh token can only be used once, the processes after the first
+// one use the tokens it refreshed.
+func refreshSessionTokenSet(authProvider *AuthProviderConfig) (*TokenSet, error) {
+	sessionID := authProvider.GetSessionID()
+	var tokenSet *TokenSet
+	err := withPersistedConfigLock(func() error {
+		// Re-load the session, another process may have refreshed the tokens while we waited for the lock.
+		sessionState, err := LoadSessionState(sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to load credentials: %w", err)
+		}
+		if sessionState == nil {
+			return errors.New("logged out while refreshing the tokens. Please log in again")
+		}
+		if expiresAt, err := GetAccessTokenExpiresAt(sessionState.TokenSet); err == nil && time.Now().Before(expiresAt) {
+			log.Debug().Msg("Tokens were already refreshed by another process")
+			tokenSet = sessionState.TokenSet
+			return nil
+		}
+
+		// Refresh the tokenSet.
+		refreshedTokenSet, err := refreshTokenSet(sessionState.TokenSet, authProvider)
+		if errors.Is(err, errRefreshTokenRejected) {
+			// Remove the session state (something has gone badly wrong).
+			log.Debug().Msg("Clearing local credentials...")
+			err = updatePersistedConfigLocked(func(config *PersistedConfig) error {
+				delete(config.Sessions, sessionID)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to delete bad tokens: %w", err)
+			}
+			log.Debug().Msg("Local credentials removed.")
+			return errors.New("failed to refresh tokens, exiting. Please log in again")
+		} else if err != nil {
+			return fmt.Errorf("failed to refresh tokens: %w", err)
+		}
+
+		// Persist the refreshed tokens.
+		persistedState, err := encodeSessionState(sessionState.UserType, refreshedTokenSet)
+		if err == nil {
+			err = updatePersistedConfigLocked(func(config *PersistedConfig) error {
+				config.Sessions[sessionID] = *persistedState
+				return nil
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to persist refreshed tokens: %w", err)
+		}
+
+		tokenSet = refreshedTokenSet
+		return nil
+	})
+	return tokenSet, err
+}
+
 // Refresh the tokenSet. Return a new tokenSet that was returned by the token endpoint.
 func refreshTokenSet(tokenSet *TokenSet, authProvider *AuthProviderConfig) (*TokenSet, error) {
 	// Create URL-encoded form data
@@ -116,16 +196,7 @@ func refreshTokenSet(tokenSet *TokenSet, authProvider *AuthProviderConfig) (*Tok
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		log.Error().Msgf("Failed to refresh tokens. Response: %s", body)
-		log.Debug().Msg("Clearing local credentials...")
-
-		// Remove the session state (something has gone badly wrong).
-		err = DeleteSessionState(authProvider.GetSessionID())
-		if err != nil {
-			return nil, fmt.Errorf("failed to delete bad tokens: %w", err)
-		}
-
-		log.Debug().Msg("Local credentials removed.")
-		return nil, errors.New("failed to refresh tokens, exiting. Please log in again")
+		return nil, errRefreshTokenRejected
 	}
 
 	// Parse the response body
@@ -15,11 +15,17 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
+	"github.com/metaplay/cli/pkg/common"
 	"github.com/rs/zerolog/log"
 	"github.com/zalando/go-keyring"
 )
 
+// Timeout for acquiring the lock on the persisted config. A lock held for longer is considered
+// stale, and the operation fails instead of waiting forever.
+const persistedConfigLockTimeout = 30 * time.Second
+
 // Service name and keyring key
 const (
 	keyringService = "metaplay-cli"
@@ -134,29 +140,10 @@ func decrypt(data []byte, key []byte) ([]byte, error) {
 }
 
 // resolvePersistedConfigFilePath resolves the path to the persisted configuration.
-// It follows platform-specific best practices for Linux, macOS, and Windows.
 func resolvePersistedConfigFilePath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	baseDir, err := common.GetStateDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user's home directory: %w", err)
-	}
-
-	// Use the appropriate directory for storing application data
-	var baseDir string
-	if runtime.GOOS == "windows" {
-		// Windows: Use AppData\Local for application-specific data
-		baseDir = filepath.Join(homeDir, "AppData", "Local", "Metaplay")
-	} else if runtime.GOOS == "darwin" {
-		// macOS: Use ~/Library/Application Support for application data
-		baseDir = filepath.Join(homeDir, "Library", "Application Support", "Metaplay")
-	} else {
-		// Linux and other Unix-like systems: Use ~/.config/metaplay for user-specific configuration data
-		baseDir = filepath.Join(homeDir, ".config", "metaplay")
-	}
-
-	// Ensure the directory exists
-	if err := os.MkdirAll(baseDir, 0700); err != nil {
-		return "", fmt.Errorf("failed to create directory for file path: %w", err)
+		return "", err
 	}
 
 	// Return the resolved file path
@@ -192,7 +179,8 @@ func loadPersistedConfig() (*PersistedConfig, error) {
 	return &persistedConfig, nil
 }
 
-// Save the persisted config back to the file on disk.
+// Save the persisted config back to the file on disk. The file is replaced atomically, so
+// concurrent readers never see a partially written file.
 func savePersistedConfig(config *PersistedConfig) error {
 	// Resolve path to the file.
 	filePath, err := resolvePersistedConfigFilePath()
@@ -206,17 +194,60 @@ func savePersistedConfig(config *PersistedConfig) error {
 		return fmt.Errorf("failed to serialize PersistedConfig: %w", err)
 	}
 
-	// Write sessionState to file.
-	err = os.WriteFile(filePath, configJSON, 0600)
+	// Write sessionState to a temporary file and move it over the file.
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "config-*.json.tmp")
 	if err != nil {
+		return fmt.Errorf("failed to create temporary session state file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(configJSON); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write session sate to file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write session sate to file: %w", err)
+	}
+	if err := os.Chmod(tmpFile.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to set permissions of session state file: %w", err)
+	}
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
 		return fmt.Errorf("failed to write session sate to file: %w", err)
 	}
 
 	return nil
 }
 
-// Load the persisted config from disk, apply the update, and then persist the config back to disk.
+// Run the function while holding the lock on the persisted config, to serialize the
+// read-modify-write of the config between concurrent CLI processes. Not re-entrant.
+func withPersistedConfigLock(fn func() error) error {
+	filePath, err := resolvePersistedConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	lock, err := acquireFileLock(filePath+".lock", persistedConfigLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.release(); err != nil {
+			log.Warn().Msgf("Failed to release the lock on the session state: %v", err)
+		}
+	}()
+
+	return fn()
+}
+
+// Load the persisted config from disk, apply the update, and then persist the config back to
+// disk, while holding the lock on the persisted config.
 func updatePersistedConfig(updateFunc func(*PersistedConfig) error) error {
+	return withPersistedConfigLock(func() error {
+		return updatePersistedConfigLocked(updateFunc)
+	})
+}
+
+// Same as updatePersistedConfig() but the caller must hold the lock on the persisted config.
+func updatePersistedConfigLocked(updateFunc func(*PersistedConfig) error) error {
 	// Load config from disk.
 	configState, err := loadPersistedConfig()
 	if err != nil {
@@ -235,37 +266,43 @@ func updatePersistedConfig(updateFunc func(*PersistedConfig) error) error {
 
 // SaveSessionState saves the current session state (with encrypted tokenSet).
 func SaveSessionState(sessionID string, userType UserType, tokenSet *TokenSet) error {
+	sessionState, err := encodeSessionState(userType, tokenSet)
+	if err != nil {
+		return err
+	}
+
+	// Update session state in persisted config.
+	return updatePersistedConfig(func(config *PersistedConfig) error {
+		config.Sessions[sessionID] = *sessionState
+		return nil
+	})
+}
+
+// Encode the session state for persisting, with the tokenSet encrypted.
+func encodeSessionState(userType UserType, tokenSet *TokenSet) (*PersistedSessionState, error) {
 	// Serialize the tokenSet to JSON
 	tokenSetJSON, err := json.Marshal(tokenSet)
 	if err != nil {
-		return fmt.Errorf("failed to serialize TokenSet: %w", err)
+		return nil, fmt.Errorf("failed to serialize TokenSet: %w", err)
 	}
 
 	// Get an encryption key.
 	key, err := getOrCreateAESKey()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Encrypt the tokenSet
 	encryptedTokenSet, err := encrypt(tokenSetJSON, key)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt TokenSet: %w", err)
+		return nil, fmt.Errorf("failed to encrypt TokenSet: %w", err)
 	}
 
 	// Construct session state.
-	sessionState := PersistedSessionState{
+	return &PersistedSessionState{
 		UserType:        userType,
 		EncodedTokenSet: base64.StdEncoding.EncodeToString(encryptedTokenSet),
-	}
-
-	// Update session state in persisted config.
-	updatePersistedConfig(func(config *PersistedConfig) error {
-		config.Sessions[sessionID] = sessionState
-		return nil
-	})
-
-	return nil
+	}, nil
 }
 
 // LoadSessionState loads a session state and decrypts the tokenSet.
@@ -284,6 +321,11 @@ func LoadSessionState(sessionID string) (*SessionState, error) {
 		return nil, nil
 	}
 
+	return decodeSessionState(sessionState)
+}
+
+// Decode the persisted session state, decrypting the tokenSet.
+func decodeSessionState(sessionState PersistedSessionState) (*SessionState, error) {
 	// Base64 decode to get encrypted tokenSet bytes.
 	tokenSetBytes, err := base64.StdEncoding.DecodeString(sessionState.EncodedTokenSet)
 	if err != nil {
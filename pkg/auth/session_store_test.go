@@ -0,0 +1,194 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zalando/go-keyring"
+)
+
+// Redirect the persisted config into a temporary directory.
+func useTempStateDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on HOME to redirect the state directory")
+	}
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+}
+
+// Create an (unsigned) access token that expires at the given time.
+func newTestAccessToken(t *testing.T, expiresAt time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": expiresAt.Unix(), "sub": "test-user"}).SignedString([]byte("test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// Token endpoint that rotates the refresh token on each refresh, like the real one: each
+// refresh token can only be used once.
+type testTokenEndpoint struct {
+	t            *testing.T
+	mu           sync.Mutex
+	refreshToken string // Currently valid refresh token.
+	numRefreshes int    // Number of successful refreshes.
+}
+
+func (endpoint *testTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Widen the window for concurrent refreshes.
+	time.Sleep(20 * time.Millisecond)
+
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+
+	if err := r.ParseForm(); err != nil || r.Form.Get("refresh_token") != endpoint.refreshToken {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	endpoint.numRefreshes++
+	endpoint.refreshToken = fmt.Sprintf("refresh-%d", endpoint.numRefreshes)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(TokenSet{
+		AccessToken:  newTestAccessToken(endpoint.t, time.Now().Add(time.Hour)),
+		RefreshToken: endpoint.refreshToken,
+	})
+}
+
+func TestConcurrentTokenRefresh(t *testing.T) {
+	useTempStateDir(t)
+
+	endpoint := &testTokenEndpoint{t: t, refreshToken: "refresh-0"}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+	authProvider := &AuthProviderConfig{Name: "Test Auth", ClientID: "test-client", TokenEndpoint: server.URL}
+
+	// Start with an expired access token.
+	expiredTokenSet := &TokenSet{AccessToken: newTestAccessToken(t, time.Now().Add(-time.Minute)), RefreshToken: "refresh-0"}
+	if err := SaveSessionState(authProvider.GetSessionID(), UserTypeHuman, expiredTokenSet); err != nil {
+		t.Fatal(err)
+	}
+
+	// Refresh concurrently. Each refresher opens the lock file separately, like separate processes.
+	const numRefreshers = 8
+	tokenSets := make([]*TokenSet, numRefreshers)
+	errs := make([]error, numRefreshers)
+	var wg sync.WaitGroup
+	for ndx := range numRefreshers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokenSets[ndx], errs[ndx] = LoadAndRefreshTokenSet(authProvider)
+		}()
+	}
+	wg.Wait()
+
+	// Only the first refresher uses the refresh token, the others get the refreshed tokens.
+	for ndx := range numRefreshers {
+		if errs[ndx] != nil {
+			t.Fatalf("refresher %d failed: %v", ndx, errs[ndx])
+		}
+		if tokenSets[ndx].RefreshToken != "refresh-1" || tokenSets[ndx].AccessToken != tokenSets[0].AccessToken {
+			t.Errorf("refresher %d got unexpected tokens: %+v", ndx, tokenSets[ndx])
+		}
+	}
+	if endpoint.numRefreshes != 1 {
+		t.Errorf("token endpoint was called %d times, expected 1", endpoint.numRefreshes)
+	}
+
+	// The store must be valid and contain the refreshed tokens.
+	sessionState, err := LoadSessionState(authProvider.GetSessionID())
+	if err != nil {
+		t.Fatalf("failed to load session state: %v", err)
+	}
+	if sessionState == nil || sessionState.TokenSet.RefreshToken != "refresh-1" || sessionState.UserType != UserTypeHuman {
+		t.Errorf("unexpected persisted session state: %+v", sessionState)
+	}
+}
+
+func TestConcurrentSaveSessionState(t *testing.T) {
+	useTempStateDir(t)
+
+	// Save sessions concurrently: none of the updates may be lost.
+	const numSessions = 16
+	var wg sync.WaitGroup
+	for ndx := range numSessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokenSet := &TokenSet{AccessToken: fmt.Sprintf("access-%d", ndx)}
+			if err := SaveSessionState(fmt.Sprintf("session-%d", ndx), UserTypeMachine, tokenSet); err != nil {
+				t.Errorf("failed to save session %d: %v", ndx, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	config, err := loadPersistedConfig()
+	if err != nil {
+		t.Fatalf("persisted config is not valid: %v", err)
+	}
+	if len(config.Sessions) != numSessions {
+		t.Errorf("found %d sessions, expected %d", len(config.Sessions), numSessions)
+	}
+	for ndx := range numSessions {
+		sessionState, err := LoadSessionState(fmt.Sprintf("session-%d", ndx))
+		if err != nil || sessionState == nil || sessionState.TokenSet.AccessToken != fmt.Sprintf("access-%d", ndx) {
+			t.Errorf("unexpected session %d: %+v, err: %v", ndx, sessionState, err)
+		}
+	}
+
+	// No temporary files are left behind.
+	filePath, err := resolvePersistedConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFiles, _ := filepath.Glob(filepath.Join(filepath.Dir(filePath), "*.tmp"))
+	if len(tmpFiles) > 0 {
+		t.Errorf("temporary files left behind: %v", tmpFiles)
+	}
+}
+
+func TestFileLockTimeout(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+
+	lock, err := acquireFileLock(lockPath, time.Second)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	// A held lock times out instead of waiting forever.
+	startTime := time.Now()
+	_, err = acquireFileLock(lockPath, 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > 2*time.Second {
+		t.Errorf("acquiring the lock took %s, expected it to time out quickly", elapsed)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("error %q does not name the holder", err)
+	}
+
+	// Once released, the lock can be acquired again.
+	if err := lock.release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+	lock, err = acquireFileLock(lockPath, time.Second)
+	if err != nil {
+		t.Fatalf("failed to re-acquire lock: %v", err)
+	}
+	_ = lock.release()
+}
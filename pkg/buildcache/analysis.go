@@ -0,0 +1,193 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Package buildcache analyzes the layer cache usage of docker builds, based on the
+// '--progress=rawjson' output of buildx.
+package buildcache
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Build step (a vertex with a '[stage n/m]' prefix in buildkit) and whether it was served from cache.
+type Step struct {
+	Stage       string        `json:"stage"`       // Name of the Dockerfile stage, eg, 'build'.
+	Index       int           `json:"index"`       // Index of the step within the stage, starting from 1.
+	Instruction string        `json:"instruction"` // Dockerfile instruction, eg, 'COPY Backend/ Backend/'.
+	Cached      bool          `json:"cached"`      // Was the step served from the build cache?
+	Duration    time.Duration `json:"duration"`    // Time taken by the step.
+	Error       string        `json:"error,omitempty"`
+	logs        []byte        // Output of the step, only kept for the current run.
+}
+
+// Cache usage of a single docker build.
+type Analysis struct {
+	ImageName string    `json:"imageName"`
+	Timestamp time.Time `json:"timestamp"`
+	Steps     []*Step   `json:"steps"` // Steps in the order they were started.
+}
+
+// Cache usage summary of a Dockerfile stage.
+type StageSummary struct {
+	Stage     string
+	NumSteps  int
+	NumCached int
+	Duration  time.Duration
+}
+
+// Vertex in the buildkit SolveStatus, as output by 'docker buildx build --progress=rawjson'.
+type rawVertex struct {
+	Digest    string     `json:"digest"`
+	Name      string     `json:"name"`
+	Started   *time.Time `json:"started"`
+	Completed *time.Time `json:"completed"`
+	Cached    bool       `json:"cached"`
+	Error     string     `json:"error"`
+}
+
+// Log output of a vertex in the buildkit SolveStatus.
+type rawVertexLog struct {
+	Vertex string `json:"vertex"`
+	Data   []byte `json:"data"`
+}
+
+// Buildkit SolveStatus update, one per line in the rawjson output.
+type rawSolveStatus struct {
+	Vertexes []rawVertex    `json:"vertexes"`
+	Logs     []rawVertexLog `json:"logs"`
+}
+
+// Matches the step prefix buildkit adds to the vertex names, eg, '[build 3/9] COPY ...'.
+var stepNameRegex = regexp.MustCompile(`^\[([^\s\]]+) (\d+)/(\d+)\] (.*)$`)
+
+// Parse the '--progress=rawjson' output of a buildx build from r until EOF. The onStepDone
+// callback (if non-nil) is called whenever a step completes. Lines that are not valid JSON
+// are ignored, as docker may mix in plain text messages.
+func ParseRawJSONProgress(r io.Reader, imageName string, onStepDone func(step *Step)) (*Analysis, error) {
+	analysis := &Analysis{ImageName: imageName, Timestamp: time.Now().UTC()}
+	stepsByDigest := map[string]*Step{}
+	completed := map[string]bool{}
+	pendingLogs := map[string][]byte{} // Logs received before the vertex itself
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var status rawSolveStatus
+		if err := json.Unmarshal(scanner.Bytes(), &status); err != nil {
+			continue
+		}
+
+		for _, vertex := range status.Vertexes {
+			step, found := stepsByDigest[vertex.Digest]
+			if !found {
+				match := stepNameRegex.FindStringSubmatch(vertex.Name)
+				if match == nil {
+					continue // internal vertex, eg, '[internal] load build context'
+				}
+				index, _ := strconv.Atoi(match[2])
+				step = &Step{Stage: match[1], Index: index, Instruction: match[4], logs: pendingLogs[vertex.Digest]}
+				delete(pendingLogs, vertex.Digest)
+				stepsByDigest[vertex.Digest] = step
+				analysis.Steps = append(analysis.Steps, step)
+			}
+
+			step.Cached = step.Cached || vertex.Cached
+			if vertex.Error != "" {
+				step.Error = vertex.Error
+			}
+			if vertex.Started != nil && vertex.Completed != nil {
+				step.Duration = vertex.Completed.Sub(*vertex.Started)
+				if !completed[vertex.Digest] {
+					completed[vertex.Digest] = true
+					if onStepDone != nil {
+						onStepDone(step)
+					}
+				}
+			}
+		}
+
+		for _, vertexLog := range status.Logs {
+			if step, found := stepsByDigest[vertexLog.Vertex]; found {
+				step.logs = append(step.logs, vertexLog.Data...)
+			} else {
+				pendingLogs[vertexLog.Vertex] = append(pendingLogs[vertexLog.Vertex], vertexLog.Data...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return analysis, fmt.Errorf("failed to read docker build progress: %w", err)
+	}
+	return analysis, nil
+}
+
+// Get the output of the step, eg, to show the logs of a failed step.
+func (step *Step) Logs() string {
+	return string(step.logs)
+}
+
+// Get the name of the step in the same format as buildkit, eg, '[build 3/9] COPY ...'.
+func (step *Step) String() string {
+	return fmt.Sprintf("[%s %d] %s", step.Stage, step.Index, step.Instruction)
+}
+
+// Count the steps served from the cache and the total number of steps.
+func (analysis *Analysis) CacheHits() (numCached int, numSteps int) {
+	for _, step := range analysis.Steps {
+		if step.Cached {
+			numCached++
+		}
+	}
+	return numCached, len(analysis.Steps)
+}
+
+// Summarize the cache usage by stage, in the order the stages were first started.
+func (analysis *Analysis) StageSummaries() []StageSummary {
+	summaries := []StageSummary{}
+	stageIndex := map[string]int{}
+	for _, step := range analysis.Steps {
+		ndx, found := stageIndex[step.Stage]
+		if !found {
+			ndx = len(summaries)
+			stageIndex[step.Stage] = ndx
+			summaries = append(summaries, StageSummary{Stage: step.Stage})
+		}
+		summaries[ndx].NumSteps++
+		if step.Cached {
+			summaries[ndx].NumCached++
+		}
+		summaries[ndx].Duration += step.Duration
+	}
+	return summaries
+}
+
+// Get the steps that failed.
+func (analysis *Analysis) FailedSteps() []*Step {
+	failed := []*Step{}
+	for _, step := range analysis.Steps {
+		if step.Error != "" {
+			failed = append(failed, step)
+		}
+	}
+	return failed
+}
+
+// Get the steps of the stage, in Dockerfile order.
+func (analysis *Analysis) stageSteps(stage string) []*Step {
+	steps := []*Step{}
+	for _, step := range analysis.Steps {
+		if step.Stage == stage {
+			steps = append(steps, step)
+		}
+	}
+	// The steps are in start order, which for cached steps may differ from Dockerfile order.
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Index < steps[j].Index })
+	return steps
+}
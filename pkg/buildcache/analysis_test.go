@@ -0,0 +1,110 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package buildcache
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Render a rawjson progress line for a completed vertex.
+func rawVertexLine(digest, name string, cached bool, duration time.Duration) string {
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	completed := started.Add(duration)
+	return fmt.Sprintf(`{"vertexes":[{"digest":"%s","name":"%s","started":"%s","completed":"%s","cached":%t}]}`,
+		digest, name, started.Format(time.RFC3339Nano), completed.Format(time.RFC3339Nano), cached)
+}
+
+const testRestoreBuildOutput = `{"vertexes":[{"digest":"sha256:0","name":"[internal] load build definition from Dockerfile"}]}
+{"vertexes":[{"digest":"sha256:1","name":"[build 1/5] FROM mcr.microsoft.com/dotnet/sdk:8.0"}]}
+#0 building with "default" instance using docker driver
+`
+
+func TestParseRawJSONProgress(t *testing.T) {
+	lines := []string{
+		testRestoreBuildOutput,
+		rawVertexLine("sha256:1", "[build 1/5] FROM mcr.microsoft.com/dotnet/sdk:8.0", true, 0),
+		rawVertexLine("sha256:2", "[build 2/5] COPY SharedCode/ SharedCode/", false, 200*time.Millisecond),
+		rawVertexLine("sha256:3", "[build 3/5] COPY Backend/ Backend/", false, 300*time.Millisecond),
+		fmt.Sprintf(`{"logs":[{"vertex":"sha256:4","stream":1,"data":"%s"}]}`, base64.StdEncoding.EncodeToString([]byte("Restored packages\n"))),
+		rawVertexLine("sha256:4", "[build 4/5] RUN dotnet restore Server/Server.csproj", false, 40*time.Second),
+		rawVertexLine("sha256:5", "[build 5/5] RUN dotnet publish -c Release", false, 60*time.Second),
+		rawVertexLine("sha256:6", "[runtime 1/2] FROM mcr.microsoft.com/dotnet/aspnet:8.0", true, 0),
+		rawVertexLine("sha256:7", "[runtime 2/2] COPY --from=build /build/out /app", false, time.Second),
+	}
+
+	doneSteps := []string{}
+	analysis, err := ParseRawJSONProgress(strings.NewReader(strings.Join(lines, "\n")), "mygame:test", func(step *Step) {
+		doneSteps = append(doneSteps, step.String())
+	})
+	if err != nil {
+		t.Fatalf("failed to parse progress: %v", err)
+	}
+
+	if len(doneSteps) != 7 {
+		t.Errorf("completed steps = %v, expected 7 steps", doneSteps)
+	}
+	if numCached, numSteps := analysis.CacheHits(); numCached != 2 || numSteps != 7 {
+		t.Errorf("cache hits = %d/%d, expected 2/7", numCached, numSteps)
+	}
+	if logs := analysis.Steps[3].Logs(); logs != "Restored packages\n" {
+		t.Errorf("logs of restore step = %q", logs)
+	}
+
+	summaries := analysis.StageSummaries()
+	if len(summaries) != 2 || summaries[0].Stage != "build" || summaries[0].NumSteps != 5 || summaries[0].NumCached != 1 || summaries[1].Stage != "runtime" {
+		t.Errorf("unexpected stage summaries: %+v", summaries)
+	}
+	if summaries[0].Duration != 100*time.Second+500*time.Millisecond {
+		t.Errorf("build stage duration = %v", summaries[0].Duration)
+	}
+
+	// The SharedCode copy invalidates the restore.
+	suggestions := analysis.Suggestions()
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "'COPY SharedCode/ SharedCode/' (step 2) happens before 'RUN dotnet restore") {
+		t.Errorf("unexpected suggestions: %q", suggestions)
+	}
+}
+
+func TestSuggestionsForBroadCopy(t *testing.T) {
+	lines := []string{
+		rawVertexLine("sha256:1", "[stage-0 1/3] FROM alpine", true, 0),
+		rawVertexLine("sha256:2", "[stage-0 2/3] COPY . /src", false, time.Second),
+		rawVertexLine("sha256:3", "[stage-0 3/3] RUN make", false, 10*time.Second),
+	}
+	analysis, err := ParseRawJSONProgress(strings.NewReader(strings.Join(lines, "\n")), "test:1", nil)
+	if err != nil {
+		t.Fatalf("failed to parse progress: %v", err)
+	}
+	suggestions := analysis.Suggestions()
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "copies the whole build context") {
+		t.Errorf("unexpected suggestions: %q", suggestions)
+	}
+}
+
+func TestSaveAndLoadAnalysis(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "nested", "analysis.json")
+
+	// Missing file is not an error.
+	loaded, err := LoadAnalysis(filePath)
+	if err != nil || loaded != nil {
+		t.Fatalf("LoadAnalysis() of missing file = %v, %v", loaded, err)
+	}
+
+	analysis := &Analysis{ImageName: "mygame:test", Steps: []*Step{{Stage: "build", Index: 1, Instruction: "FROM alpine", Cached: true}}}
+	if err := analysis.Save(filePath); err != nil {
+		t.Fatalf("failed to save analysis: %v", err)
+	}
+	loaded, err = LoadAnalysis(filePath)
+	if err != nil {
+		t.Fatalf("failed to load analysis: %v", err)
+	}
+	if loaded.ImageName != "mygame:test" || len(loaded.Steps) != 1 || !loaded.Steps[0].Cached {
+		t.Errorf("loaded analysis doesn't match: %+v", loaded)
+	}
+}
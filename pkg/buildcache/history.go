@@ -0,0 +1,53 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package buildcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Get the path of the file storing the last analysis of the project's builds, in the user's
+// cache directory.
+func LastAnalysisFilePath(projectID string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "metaplay", "build-cache", projectID+".json"), nil
+}
+
+// Load an earlier analysis from the file. Returns nil if the file doesn't exist.
+func LoadAnalysis(filePath string) (*Analysis, error) {
+	content, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read build cache analysis from %s: %w", filePath, err)
+	}
+
+	var analysis Analysis
+	if err := json.Unmarshal(content, &analysis); err != nil {
+		return nil, fmt.Errorf("failed to parse build cache analysis in %s: %w", filePath, err)
+	}
+	return &analysis, nil
+}
+
+// Save the analysis into the file, to compare the next run against.
+func (analysis *Analysis) Save(filePath string) error {
+	content, err := json.MarshalIndent(analysis, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for build cache analysis: %w", err)
+	}
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write build cache analysis to %s: %w", filePath, err)
+	}
+	return nil
+}
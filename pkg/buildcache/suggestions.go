@@ -0,0 +1,96 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package buildcache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Matches the RUN instructions that restore (download) the dependencies of a project. These are
+// the slow steps that benefit most from the cache.
+var restoreCommandRegex = regexp.MustCompile(`\b(dotnet restore|npm ci|npm install|pnpm install|yarn install)\b`)
+
+// Suggest changes to the Dockerfile to improve the cache hit rate, based on which steps
+// invalidated the cache of the later steps in each stage. Analysis only, so the suggestions
+// are worded for a human to act on.
+func (analysis *Analysis) Suggestions() []string {
+	suggestions := []string{}
+	for _, summary := range analysis.StageSummaries() {
+		steps := analysis.stageSteps(summary.Stage)
+
+		// Find the first rebuilt step: it invalidated the cache for all the later steps.
+		firstNdx := -1
+		for ndx, step := range steps {
+			if !step.Cached {
+				firstNdx = ndx
+				break
+			}
+		}
+		if firstNdx == -1 || firstNdx == len(steps)-1 {
+			continue // fully cached, or only the last step was rebuilt
+		}
+		first := steps[firstNdx]
+		later := steps[firstNdx+1:]
+		var laterDuration time.Duration
+		for _, step := range later {
+			laterDuration += step.Duration
+		}
+		instruction := strings.ToUpper(strings.SplitN(first.Instruction, " ", 2)[0])
+
+		switch {
+		case first.Index == 1:
+			suggestions = append(suggestions, fmt.Sprintf("Stage '%s' was fully rebuilt because its base image changed ('%s'). This is expected when a newer base image is pulled.", summary.Stage, first.Instruction))
+
+		case instruction == "COPY" || instruction == "ADD":
+			if restore := findRestoreStep(later); restore != nil {
+				suggestions = append(suggestions, fmt.Sprintf("Stage '%s': '%s' (step %d) happens before '%s' (step %d), so any change in the copied files re-runs the restore (%s). Copy only the project files (eg, *.csproj or package.json) before the restore and the rest of the sources after it to preserve the restore cache.",
+					summary.Stage, first.Instruction, first.Index, restore.Instruction, restore.Index, restore.Duration.Round(100*time.Millisecond)))
+			} else if isBroadCopy(first.Instruction) {
+				suggestions = append(suggestions, fmt.Sprintf("Stage '%s': '%s' (step %d) copies the whole build context, so any change invalidates the %d later steps (%s). Copy only the needed directories, or exclude files that don't affect the build with .dockerignore.",
+					summary.Stage, first.Instruction, first.Index, len(later), laterDuration.Round(100*time.Millisecond)))
+			} else {
+				suggestions = append(suggestions, fmt.Sprintf("Stage '%s': '%s' (step %d) invalidated the cache of the %d later steps (%s). If the copied files change often, consider moving the copy later in the stage.",
+					summary.Stage, first.Instruction, first.Index, len(later), laterDuration.Round(100*time.Millisecond)))
+			}
+
+		case instruction == "ARG" || instruction == "ENV":
+			suggestions = append(suggestions, fmt.Sprintf("Stage '%s': '%s' (step %d) changed and invalidated the cache of the %d later steps (%s). Declare frequently changing build arguments (eg, commit ID or build number) as late as possible in the stage.",
+				summary.Stage, first.Instruction, first.Index, len(later), laterDuration.Round(100*time.Millisecond)))
+		}
+	}
+	return suggestions
+}
+
+// Find the first step that restores the project dependencies.
+func findRestoreStep(steps []*Step) *Step {
+	for _, step := range steps {
+		if strings.HasPrefix(strings.ToUpper(step.Instruction), "RUN") && restoreCommandRegex.MatchString(step.Instruction) {
+			return step
+		}
+	}
+	return nil
+}
+
+// Check whether the COPY/ADD instruction copies the whole build context, eg, 'COPY . .'.
+func isBroadCopy(instruction string) bool {
+	fields := strings.Fields(instruction)
+	sources := []string{}
+	for _, field := range fields[1:] {
+		if !strings.HasPrefix(field, "--") {
+			sources = append(sources, field)
+		}
+	}
+	if len(sources) < 2 {
+		return false
+	}
+	for _, source := range sources[:len(sources)-1] {
+		if source == "." || source == "./" || source == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package buildhash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Length of the content hash (in hex characters) used for image tags.
+const HashLength = 20
+
+// Version of the hash computation. Bump when changing how the hash is computed, so that
+// images tagged with an older hash are not mistaken for ones built from the same inputs.
+const hashVersion = "v1"
+
+// Inputs to a docker image build that determine its content.
+type Inputs struct {
+	RootDir        string   // Docker build root directory, all other paths are relative to it.
+	Dirs           []string // Directories whose files are included, eg, the backend and shared code.
+	Files          []string // Individual files included, eg, the Dockerfile.
+	SdkVersion     string   // Metaplay SDK version.
+	DotnetVersion  string   // .NET version the project is built for.
+	IgnoreMatcher  *IgnoreMatcher
+	NormalizeCRLFs bool // Convert CRLF line endings to LF before hashing, for stability across OSes.
+}
+
+// Compute a stable hash over the build inputs. The hash only depends on the relative paths
+// (with forward slashes) and the contents of the files, and the versions, so the same sources
+// produce the same hash on all operating systems.
+func Compute(inputs Inputs) (string, error) {
+	// Collect all the files to hash, as slash-separated paths relative to the root directory.
+	fileSet := map[string]bool{}
+	addFile := func(absPath string) error {
+		relPath, err := filepath.Rel(inputs.RootDir, absPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path of %s relative to %s: %w", absPath, inputs.RootDir, err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if inputs.IgnoreMatcher == nil || !inputs.IgnoreMatcher.IsIgnored(relPath) {
+			fileSet[relPath] = true
+		}
+		return nil
+	}
+
+	for _, dir := range inputs.Dirs {
+		err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.Type().IsRegular() {
+				return addFile(filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to list files in %s: %w", dir, err)
+		}
+	}
+	for _, filePath := range inputs.Files {
+		if err := addFile(filePath); err != nil {
+			return "", err
+		}
+	}
+
+	relPaths := make([]string, 0, len(fileSet))
+	for relPath := range fileSet {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+
+	// Hash the versions, and the path and content hash of each file.
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "hash-version\x00%s\n", hashVersion)
+	fmt.Fprintf(hasher, "sdk-version\x00%s\n", inputs.SdkVersion)
+	fmt.Fprintf(hasher, "dotnet-version\x00%s\n", inputs.DotnetVersion)
+	for _, relPath := range relPaths {
+		content, err := os.ReadFile(filepath.Join(inputs.RootDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+		if inputs.NormalizeCRLFs {
+			content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+		}
+		contentHash := sha256.Sum256(content)
+		fmt.Fprintf(hasher, "file\x00%s\x00%s\n", relPath, hex.EncodeToString(contentHash[:]))
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))[:HashLength], nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package buildhash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	matcher, err := ParseIgnorePatterns(`
+# Comment
+**/bin
+**/obj
+*.log
+/Backend/Server/appsettings.local.json
+!keep.log
+`)
+	if err != nil {
+		t.Fatalf("failed to parse patterns: %v", err)
+	}
+
+	tests := []struct {
+		path      string
+		isIgnored bool
+	}{
+		{"Backend/Server/Program.cs", false},
+		{"Backend/Server/bin/Debug/Server.dll", true},
+		{"bin/foo", true},
+		{"Backend/Server/obj", true},
+		{"error.log", true},
+		{"Backend/error.log", false}, // '*.log' only matches in the root
+		{"keep.log", false},
+		{"Backend/Server/appsettings.local.json", true},
+		{"Backend/Server/appsettings.json", false},
+	}
+
+	for _, test := range tests {
+		if got := matcher.IsIgnored(test.path); got != test.isIgnored {
+			t.Errorf("IsIgnored(%q) = %v, expected %v", test.path, got, test.isIgnored)
+		}
+	}
+}
+
+func TestComputeIsStable(t *testing.T) {
+	writeFiles := func(rootDir string, files map[string]string) {
+		for relPath, content := range files {
+			absPath := filepath.Join(rootDir, filepath.FromSlash(relPath))
+			if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	computeHash := func(rootDir string, normalizeCRLFs bool) string {
+		matcher, err := ParseIgnorePatterns("**/bin\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := Compute(Inputs{
+			RootDir:        rootDir,
+			Dirs:           []string{filepath.Join(rootDir, "Backend")},
+			Files:          []string{filepath.Join(rootDir, "Dockerfile")},
+			SdkVersion:     "32.0.0",
+			DotnetVersion:  "9.0",
+			IgnoreMatcher:  matcher,
+			NormalizeCRLFs: normalizeCRLFs,
+		})
+		if err != nil {
+			t.Fatalf("failed to compute hash: %v", err)
+		}
+		return hash
+	}
+
+	// Same content with different line endings and ignored files.
+	dirA := t.TempDir()
+	writeFiles(dirA, map[string]string{
+		"Dockerfile":         "FROM scratch\n",
+		"Backend/Program.cs": "class Program {}\n",
+		"Backend/bin/a.dll":  "binary",
+	})
+	dirB := t.TempDir()
+	writeFiles(dirB, map[string]string{
+		"Dockerfile":         "FROM scratch\r\n",
+		"Backend/Program.cs": "class Program {}\r\n",
+	})
+
+	hashA := computeHash(dirA, true)
+	if len(hashA) != HashLength {
+		t.Errorf("hash length = %d, expected %d", len(hashA), HashLength)
+	}
+	if hashB := computeHash(dirB, true); hashA != hashB {
+		t.Errorf("hashes differ with normalized line endings: %s vs %s", hashA, hashB)
+	}
+	if hashB := computeHash(dirB, false); hashA == hashB {
+		t.Errorf("hashes should differ without normalized line endings")
+	}
+
+	// Changing a file changes the hash.
+	writeFiles(dirB, map[string]string{"Backend/Program.cs": "class Program { }\n"})
+	if hashB := computeHash(dirB, true); hashA == hashB {
+		t.Errorf("hashes should differ after changing a file")
+	}
+}
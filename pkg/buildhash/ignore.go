@@ -0,0 +1,145 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package buildhash
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Single pattern from an ignore file.
+type ignorePattern struct {
+	regex     *regexp.Regexp // Compiled pattern, matches slash-separated relative paths.
+	exclusion bool           // Pattern starts with '!', ie, re-includes matching paths.
+}
+
+// Matcher for .dockerignore-style ignore files: patterns are relative to the root directory,
+// use '*', '?', and '**' wildcards, and '!' negates a pattern. The last matching pattern
+// decides whether a path is ignored. A pattern matching a directory also ignores its contents.
+type IgnoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// Parse the ignore patterns from the content of an ignore file.
+func ParseIgnorePatterns(content string) (*IgnoreMatcher, error) {
+	matcher := &IgnoreMatcher{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		exclusion := false
+		if strings.HasPrefix(line, "!") {
+			exclusion = true
+			line = strings.TrimSpace(line[1:])
+		}
+
+		// Normalize the pattern like docker does: forward slashes, no leading or trailing slashes.
+		pattern := path.Clean(strings.ReplaceAll(line, "\\", "/"))
+		pattern = strings.TrimPrefix(pattern, "/")
+		if pattern == "" || pattern == "." {
+			continue
+		}
+
+		regex, err := compileIgnorePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern '%s': %w", line, err)
+		}
+		matcher.patterns = append(matcher.patterns, ignorePattern{regex: regex, exclusion: exclusion})
+	}
+	return matcher, scanner.Err()
+}
+
+// Read the ignore patterns from the given files and combine them (in order). Missing files
+// are skipped.
+func ReadIgnoreFiles(filePaths ...string) (*IgnoreMatcher, error) {
+	combined := &IgnoreMatcher{}
+	for _, filePath := range filePaths {
+		content, err := os.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read ignore file %s: %w", filePath, err)
+		}
+
+		matcher, err := ParseIgnorePatterns(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ignore file %s: %w", filePath, err)
+		}
+		combined.patterns = append(combined.patterns, matcher.patterns...)
+	}
+	return combined, nil
+}
+
+// Check whether the slash-separated path (relative to the root directory) is ignored.
+func (m *IgnoreMatcher) IsIgnored(relPath string) bool {
+	// Check the path and all its parent directories, as ignoring a directory also ignores
+	// its contents.
+	candidates := []string{}
+	for p := relPath; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		candidates = append(candidates, p)
+	}
+
+	ignored := false
+	for _, pattern := range m.patterns {
+		for _, candidate := range candidates {
+			if pattern.regex.MatchString(candidate) {
+				ignored = !pattern.exclusion
+				break
+			}
+		}
+	}
+	return ignored
+}
+
+// Convert a docker ignore pattern into a regular expression.
+func compileIgnorePattern(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				// '**' matches any number of directories. Swallow a following slash so
+				// that 'a/**/b' also matches 'a/b'.
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			// Character classes are passed through as-is.
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			sb.WriteString(pattern[i : i+end+1])
+			i += end
+		case '\\':
+			// Escaped character.
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
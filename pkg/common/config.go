@@ -3,13 +3,25 @@
  */
 package common
 
-import "os"
+import (
+	"os"
+	"strings"
+)
 
 const DefaultPortalBaseURL = "https://portal.metaplay.dev"
 
 // Base URL of the Metaplay portal.
 var PortalBaseURL = DefaultPortalBaseURL
 
+// Base URL of the StackAPI to use for all environments instead of the one derived from the
+// environment's stack domain, or empty for no override.
+//
+// DEVELOPER-ONLY: This is an escape hatch for developing the StackAPI itself, to point the CLI
+// at a local or staging instance with METAPLAY_STACK_API_URL, eg, 'http://localhost:5000/stackapi'.
+// Using the wrong StackAPI with a production environment can cause damage, so a warning is shown
+// on every command that uses it.
+var StackApiBaseURLOverride string
+
 func init() {
 	// Allow overriding portalBaseURL with an environment variable (for testing purposes)
 	// To test against local portal: set METAPLAYCLI_PORTAL_BASEURL=http://localhost:3000
@@ -17,4 +29,7 @@ func init() {
 	if override != "" {
 		PortalBaseURL = override
 	}
+
+	// Allow overriding the StackAPI base URL (for StackAPI development only).
+	StackApiBaseURLOverride = strings.TrimSuffix(os.Getenv("METAPLAY_STACK_API_URL"), "/")
 }
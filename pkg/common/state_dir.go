@@ -0,0 +1,41 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Resolve the directory where the CLI stores its persistent state (credentials, logs, etc.).
+// It follows platform-specific best practices for Linux, macOS, and Windows.
+// The directory is created if it doesn't exist yet.
+func GetStateDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user's home directory: %w", err)
+	}
+
+	// Use the appropriate directory for storing application data
+	var baseDir string
+	if runtime.GOOS == "windows" {
+		// Windows: Use AppData\Local for application-specific data
+		baseDir = filepath.Join(homeDir, "AppData", "Local", "Metaplay")
+	} else if runtime.GOOS == "darwin" {
+		// macOS: Use ~/Library/Application Support for application data
+		baseDir = filepath.Join(homeDir, "Library", "Application Support", "Metaplay")
+	} else {
+		// Linux and other Unix-like systems: Use ~/.config/metaplay for user-specific configuration data
+		baseDir = filepath.Join(homeDir, ".config", "metaplay")
+	}
+
+	// Ensure the directory exists
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory for file path: %w", err)
+	}
+
+	return baseDir, nil
+}
@@ -0,0 +1,345 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Package containerutil detects the local container runtime (docker or a docker-compatible
+// one like podman) used for building, running, and pushing images, and adapts the
+// invocations to the differences between the runtimes.
+package containerutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Container runtimes supported by the CLI, in the order of auto-detection.
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Names of the supported container runtimes, in the order of auto-detection.
+var RuntimeNames = []string{RuntimeDocker, RuntimePodman}
+
+// Optional feature of a container runtime.
+type Capability string
+
+const (
+	// Docker's 'buildx build': --metadata-file, --progress=rawjson, compressed outputs, and
+	// cache exporters like '--cache-to=type=gha'.
+	CapabilityBuildx Capability = "buildx"
+)
+
+// How long to wait for the runtime to respond when detecting it. The 'docker' invocation can
+// sometimes hang indefinitely, eg, when Docker Desktop is starting up.
+var DetectTimeout = 10 * time.Second
+
+// Container runtime used for building, running, and pushing images.
+type Runtime struct {
+	Name     string // Runtime flavor: RuntimeDocker or RuntimePodman.
+	Binary   string // Executable to invoke, eg, 'docker' or 'podman' (podman may also be installed as 'docker').
+	Version  string // Version of the runtime, eg, '27.5.1'.
+	Rootless bool   // Is the runtime running without root privileges?
+	APIHost  string // Docker-compatible API endpoint (for DOCKER_HOST), eg, 'unix:///run/user/1000/podman/podman.sock', empty for the default.
+
+	apiSocketExists bool // Does the API socket exist (always true for docker)?
+	hasBuildx       bool // Is the buildx plugin available (docker only)?
+}
+
+// Describe the runtime for the user, eg, 'podman 5.2.1 (rootless)'.
+func (rt *Runtime) String() string {
+	desc := strings.TrimSpace(fmt.Sprintf("%s %s", rt.Name, rt.Version))
+	if rt.Binary != rt.Name {
+		desc += fmt.Sprintf(" (as '%s')", rt.Binary)
+	}
+	if rt.Rootless {
+		desc += " (rootless)"
+	}
+	return desc
+}
+
+// Check whether the runtime supports an optional feature.
+func (rt *Runtime) Supports(capability Capability) bool {
+	switch capability {
+	case CapabilityBuildx:
+		return rt.Name == RuntimeDocker && rt.hasBuildx
+	default:
+		return false
+	}
+}
+
+// Return an error describing that the feature isn't available with the runtime, or nil if
+// the runtime supports the capability the feature requires.
+func (rt *Runtime) RequireCapability(capability Capability, feature string) error {
+	if rt.Supports(capability) {
+		return nil
+	}
+	if capability == CapabilityBuildx && rt.Name == RuntimeDocker {
+		return fmt.Errorf("%s requires docker buildx, which is not installed. See https://docs.docker.com/go/buildx/ for installing it", feature)
+	}
+	return fmt.Errorf("%s requires %s, which is not supported by %s", feature, capability, rt.Name)
+}
+
+// Create a command invoking the runtime with the given arguments.
+func (rt *Runtime) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, rt.Binary, args...)
+}
+
+// Get the arguments for logging into a registry, with the password passed via stdin. Podman
+// doesn't accept the URL scheme in the registry name.
+func (rt *Runtime) LoginArgs(username, registryURL string) []string {
+	registry := registryURL
+	if rt.Name == RuntimePodman {
+		registry = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(registryURL, "https://"), "http://"), "/")
+	}
+	return []string{"login", "--username", username, "--password-stdin", registry}
+}
+
+// Check that the runtime's docker-compatible API is reachable, which is required for pushing
+// images and listing them. Returns an actionable error if the API socket isn't available.
+func (rt *Runtime) CheckAPIAvailable() error {
+	if rt.apiSocketExists {
+		return nil
+	}
+
+	switch {
+	case runtime.GOOS != "linux":
+		return fmt.Errorf("the podman API socket is not available, ensure the podman machine is running with 'podman machine start'")
+	case rt.Rootless:
+		return fmt.Errorf("the podman API socket is not available, enable it with 'systemctl --user enable --now podman.socket'")
+	default:
+		return fmt.Errorf("the podman API socket is not available, enable it with 'sudo systemctl enable --now podman.socket'")
+	}
+}
+
+// Point the docker API clients of this process (the docker client library and
+// go-containerregistry's daemon access) at the runtime's API endpoint. An explicitly set
+// DOCKER_HOST is respected.
+func (rt *Runtime) ConfigureAPIClients() {
+	if rt.APIHost == "" || os.Getenv("DOCKER_HOST") != "" {
+		return
+	}
+	log.Debug().Msgf("Use container runtime API at %s", rt.APIHost)
+	os.Setenv("DOCKER_HOST", rt.APIHost)
+}
+
+// Detect the container runtime to use. If preferred is non-empty, only that runtime is
+// considered, otherwise docker is tried first and podman second. The returned errors describe
+// how to resolve the problem, eg, start the docker daemon.
+func Detect(ctx context.Context, preferred string) (*Runtime, error) {
+	if preferred != "" && !slices.Contains(RuntimeNames, preferred) {
+		return nil, fmt.Errorf("invalid container runtime '%s', must be one of: %s", preferred, strings.Join(RuntimeNames, ", "))
+	}
+
+	candidates := RuntimeNames
+	if preferred != "" {
+		candidates = []string{preferred}
+	}
+
+	var errs []error
+	for _, name := range candidates {
+		if _, err := exec.LookPath(name); err != nil {
+			log.Debug().Msgf("Container runtime %s not found: %v", name, err)
+			if preferred != "" {
+				return nil, fmt.Errorf("%s is not installed or not in PATH", name)
+			}
+			continue
+		}
+
+		rt, err := detectRuntime(ctx, name)
+		if err == nil {
+			log.Debug().Msgf("Detected container runtime: %s", rt)
+			return rt, nil
+		}
+		log.Debug().Msgf("Container runtime %s is not usable: %v", name, err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no container runtime found, install Docker (https://docs.docker.com/get-docker/) or Podman (https://podman.io/docs/installation)")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Detect the details of the runtime installed as the given binary.
+func detectRuntime(ctx context.Context, binary string) (*Runtime, error) {
+	// Podman is commonly installed as 'docker' (podman-docker), so check what the binary is.
+	versionOutput, err := runDetectCommand(ctx, binary, "--version")
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(strings.ToLower(versionOutput), "podman") {
+		return detectPodman(ctx, binary)
+	}
+	return detectDocker(ctx, binary)
+}
+
+// Detect docker (or another runtime compatible with the docker CLI).
+func detectDocker(ctx context.Context, binary string) (*Runtime, error) {
+	infoOutput, err := runDetectCommand(ctx, binary, "info", "--format", "{{json .}}")
+	if err != nil {
+		return nil, describeDockerError(err)
+	}
+	rt, err := parseDockerInfo(infoOutput)
+	if err != nil {
+		return nil, err
+	}
+	rt.Binary = binary
+
+	// Resolve the API endpoint from the active docker context, eg, for rootless docker or
+	// Docker Desktop on macOS, where the default socket may not exist.
+	if host, err := runDetectCommand(ctx, binary, "context", "inspect", "--format", "{{.Endpoints.docker.Host}}"); err == nil {
+		rt.APIHost = strings.TrimSpace(host)
+	}
+
+	_, err = runDetectCommand(ctx, binary, "buildx", "version")
+	rt.hasBuildx = err == nil
+	return rt, nil
+}
+
+// Detect podman.
+func detectPodman(ctx context.Context, binary string) (*Runtime, error) {
+	infoOutput, err := runDetectCommand(ctx, binary, "info", "--format", "json")
+	if err != nil {
+		return nil, describePodmanError(err)
+	}
+	rt, err := parsePodmanInfo(infoOutput)
+	if err != nil {
+		return nil, err
+	}
+	rt.Binary = binary
+	return rt, nil
+}
+
+// Parse the output of 'docker info --format {{json .}}'.
+func parseDockerInfo(output string) (*Runtime, error) {
+	var info struct {
+		ServerVersion   string   `json:"ServerVersion"`
+		SecurityOptions []string `json:"SecurityOptions"`
+		ServerErrors    []string `json:"ServerErrors"`
+	}
+	if err := json.Unmarshal([]byte(output), &info); err != nil {
+		return nil, fmt.Errorf("failed to parse docker info: %w", err)
+	}
+	if len(info.ServerErrors) > 0 {
+		return nil, describeDockerError(errors.New(strings.Join(info.ServerErrors, "; ")))
+	}
+
+	rt := &Runtime{
+		Name:            RuntimeDocker,
+		Version:         info.ServerVersion,
+		apiSocketExists: true,
+	}
+	for _, option := range info.SecurityOptions {
+		if option == "name=rootless" {
+			rt.Rootless = true
+		}
+	}
+	return rt, nil
+}
+
+// Parse the output of 'podman info --format json'.
+func parsePodmanInfo(output string) (*Runtime, error) {
+	var info struct {
+		Host struct {
+			RemoteSocket struct {
+				Path   string `json:"path"`
+				Exists bool   `json:"exists"`
+			} `json:"remoteSocket"`
+			Security struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+		Version struct {
+			Version string `json:"Version"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(output), &info); err != nil {
+		return nil, fmt.Errorf("failed to parse podman info: %w", err)
+	}
+
+	rt := &Runtime{
+		Name:            RuntimePodman,
+		Version:         info.Version.Version,
+		Rootless:        info.Host.Security.Rootless,
+		apiSocketExists: info.Host.RemoteSocket.Exists,
+	}
+	if info.Host.RemoteSocket.Exists {
+		rt.APIHost = info.Host.RemoteSocket.Path
+		if !strings.Contains(rt.APIHost, "://") {
+			rt.APIHost = "unix://" + rt.APIHost
+		}
+	}
+	return rt, nil
+}
+
+// Run a command used for detecting the runtime, with a timeout. Returns the stdout output.
+func runDetectCommand(ctx context.Context, binary string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, DetectTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("timeout while running '%s %s', ensure %s is running and responsive", binary, strings.Join(args, " "), binary)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("'%s %s' failed: %s", binary, strings.Join(args, " "), msg)
+		}
+		return "", fmt.Errorf("'%s %s' failed: %w", binary, strings.Join(args, " "), err)
+	}
+	return stdout.String(), nil
+}
+
+// Add instructions for resolving a failure to reach the docker daemon.
+func describeDockerError(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "timeout"):
+		return err
+	case strings.Contains(msg, "permission denied"):
+		return fmt.Errorf("docker is not available: %w. Add your user to the 'docker' group with 'sudo usermod -aG docker $USER' (and log in again), or use rootless docker", err)
+	case runtime.GOOS == "linux":
+		return fmt.Errorf("docker is not available: %w. Ensure the docker daemon is running: 'sudo systemctl start docker', or 'systemctl --user start docker' for rootless docker", err)
+	default:
+		return fmt.Errorf("docker is not available: %w. Ensure Docker Desktop is installed and running", err)
+	}
+}
+
+// Add instructions for resolving a failure to use podman.
+func describePodmanError(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "timeout"):
+		return err
+	case runtime.GOOS != "linux":
+		return fmt.Errorf("podman is not available: %w. Ensure the podman machine is running with 'podman machine start'", err)
+	default:
+		return fmt.Errorf("podman is not available: %w", err)
+	}
+}
+
+// Get the runtime to assume when no runtime has been detected: docker with buildx, with its
+// default API endpoint.
+func DefaultRuntime() *Runtime {
+	return &Runtime{
+		Name:            RuntimeDocker,
+		Binary:          RuntimeDocker,
+		apiSocketExists: true,
+		hasBuildx:       true,
+	}
+}
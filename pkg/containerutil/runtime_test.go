@@ -0,0 +1,216 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package containerutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Put fake container runtime executables (name -> shell script) in an otherwise empty PATH.
+func useFakeRuntimes(t *testing.T, scripts map[string]string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake runtime executables are shell scripts")
+	}
+	binDir := t.TempDir()
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", binDir)
+}
+
+// Fake 'docker' with buildx, responding to the commands used by the detection.
+const fakeDocker = `case "$1" in
+--version) echo "Docker version 27.5.1, build 9f9e405" ;;
+info) echo '{"ServerVersion":"27.5.1","SecurityOptions":["name=seccomp,profile=builtin"]}' ;;
+context) echo "unix:///var/run/docker.sock" ;;
+buildx) echo "github.com/docker/buildx v0.20.0" ;;
+*) exit 1 ;;
+esac`
+
+// Fake 'podman' with the API socket enabled.
+const fakePodman = `case "$1" in
+--version) echo "podman version 5.2.1" ;;
+info) echo '{"host":{"remoteSocket":{"path":"/run/user/1000/podman/podman.sock","exists":true},"security":{"rootless":true}},"version":{"Version":"5.2.1"}}' ;;
+*) exit 1 ;;
+esac`
+
+func TestParseDockerInfo(t *testing.T) {
+	rt, err := parseDockerInfo(`{"ServerVersion":"27.5.1","SecurityOptions":["name=seccomp,profile=builtin","name=rootless","name=cgroupns"]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Name != RuntimeDocker || rt.Version != "27.5.1" || !rt.Rootless {
+		t.Errorf("unexpected runtime: %+v", rt)
+	}
+	if err := rt.CheckAPIAvailable(); err != nil {
+		t.Errorf("expected docker API to be available, got: %v", err)
+	}
+
+	// The docker CLI reports daemon connection problems in ServerErrors.
+	_, err = parseDockerInfo(`{"ServerErrors":["Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"]}`)
+	if err == nil || !strings.Contains(err.Error(), "Cannot connect to the Docker daemon") {
+		t.Errorf("expected daemon connection error, got: %v", err)
+	}
+
+	if _, err := parseDockerInfo("not json"); err == nil {
+		t.Errorf("expected a parse error")
+	}
+}
+
+func TestParsePodmanInfo(t *testing.T) {
+	rt, err := parsePodmanInfo(`{"host":{"remoteSocket":{"path":"/run/user/1000/podman/podman.sock","exists":true},"security":{"rootless":true}},"version":{"Version":"5.2.1"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Name != RuntimePodman || rt.Version != "5.2.1" || !rt.Rootless {
+		t.Errorf("unexpected runtime: %+v", rt)
+	}
+	if rt.APIHost != "unix:///run/user/1000/podman/podman.sock" {
+		t.Errorf("APIHost = %q", rt.APIHost)
+	}
+	if err := rt.CheckAPIAvailable(); err != nil {
+		t.Errorf("expected podman API to be available, got: %v", err)
+	}
+	if rt.Supports(CapabilityBuildx) {
+		t.Errorf("podman must not support buildx")
+	}
+
+	// Socket not enabled.
+	rt, err = parsePodmanInfo(`{"host":{"remoteSocket":{"path":"/run/podman/podman.sock","exists":false}},"version":{"Version":"4.9.3"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.APIHost != "" {
+		t.Errorf("expected no APIHost without the socket, got %q", rt.APIHost)
+	}
+	if err := rt.CheckAPIAvailable(); err == nil || !strings.Contains(err.Error(), "podman") {
+		t.Errorf("expected podman API socket error, got: %v", err)
+	}
+}
+
+func TestRuntimeString(t *testing.T) {
+	tests := []struct {
+		rt       Runtime
+		expected string
+	}{
+		{Runtime{Name: RuntimeDocker, Binary: "docker", Version: "27.5.1"}, "docker 27.5.1"},
+		{Runtime{Name: RuntimePodman, Binary: "podman", Version: "5.2.1", Rootless: true}, "podman 5.2.1 (rootless)"},
+		{Runtime{Name: RuntimePodman, Binary: "docker", Version: "5.2.1"}, "podman 5.2.1 (as 'docker')"},
+	}
+	for _, test := range tests {
+		if got := test.rt.String(); got != test.expected {
+			t.Errorf("String() = %q, expected %q", got, test.expected)
+		}
+	}
+}
+
+func TestLoginArgs(t *testing.T) {
+	docker := DefaultRuntime()
+	podman := &Runtime{Name: RuntimePodman, Binary: "podman"}
+	registryURL := "https://000000000000.dkr.ecr.eu-west-1.amazonaws.com"
+
+	args := docker.LoginArgs("AWS", registryURL)
+	if !slices.Equal(args, []string{"login", "--username", "AWS", "--password-stdin", registryURL}) {
+		t.Errorf("unexpected docker login args: %v", args)
+	}
+
+	// Podman doesn't accept the scheme.
+	args = podman.LoginArgs("AWS", registryURL+"/")
+	if !slices.Equal(args, []string{"login", "--username", "AWS", "--password-stdin", "000000000000.dkr.ecr.eu-west-1.amazonaws.com"}) {
+		t.Errorf("unexpected podman login args: %v", args)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	ctx := context.Background()
+
+	// Docker is preferred when both are available.
+	useFakeRuntimes(t, map[string]string{"docker": fakeDocker, "podman": fakePodman})
+	rt, err := Detect(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Name != RuntimeDocker || rt.APIHost != "unix:///var/run/docker.sock" || !rt.Supports(CapabilityBuildx) {
+		t.Errorf("unexpected runtime: %+v", rt)
+	}
+
+	// Explicitly preferred podman.
+	rt, err = Detect(ctx, RuntimePodman)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Name != RuntimePodman || rt.Binary != "podman" {
+		t.Errorf("unexpected runtime: %+v", rt)
+	}
+
+	// Fall back to podman when the docker daemon is not running.
+	useFakeRuntimes(t, map[string]string{"docker": "echo 'Cannot connect to the Docker daemon' >&2; exit 1", "podman": fakePodman})
+	rt, err = Detect(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Name != RuntimePodman {
+		t.Errorf("expected podman, got %+v", rt)
+	}
+
+	// Podman installed as 'docker' (podman-docker).
+	useFakeRuntimes(t, map[string]string{"docker": fakePodman})
+	rt, err = Detect(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Name != RuntimePodman || rt.Binary != "docker" {
+		t.Errorf("expected podman as docker, got %+v", rt)
+	}
+
+	// Docker without buildx.
+	useFakeRuntimes(t, map[string]string{"docker": strings.Replace(fakeDocker, `buildx) echo "github.com/docker/buildx v0.20.0" ;;`, "", 1)})
+	rt, err = Detect(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Supports(CapabilityBuildx) {
+		t.Errorf("expected no buildx support")
+	}
+	if err := rt.RequireCapability(CapabilityBuildx, "--compress"); err == nil || !strings.Contains(err.Error(), "buildx") {
+		t.Errorf("expected buildx requirement error, got: %v", err)
+	}
+
+	// Preferred runtime not installed.
+	if _, err := Detect(ctx, RuntimePodman); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("expected not installed error, got: %v", err)
+	}
+
+	// Invalid preferred runtime.
+	if _, err := Detect(ctx, "containerd"); err == nil || !strings.Contains(err.Error(), "invalid container runtime") {
+		t.Errorf("expected invalid runtime error, got: %v", err)
+	}
+
+	// Nothing installed.
+	useFakeRuntimes(t, map[string]string{})
+	if _, err := Detect(ctx, ""); err == nil || !strings.Contains(err.Error(), "no container runtime found") {
+		t.Errorf("expected no runtime error, got: %v", err)
+	}
+}
+
+func TestDetectTimeout(t *testing.T) {
+	oldTimeout := DetectTimeout
+	defer func() { DetectTimeout = oldTimeout }()
+	DetectTimeout = 200 * time.Millisecond
+
+	// Docker hangs.
+	useFakeRuntimes(t, map[string]string{"docker": "while :; do :; done"})
+	if _, err := Detect(context.Background(), RuntimeDocker); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected timeout error, got: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Package credfiles manages the credential-bearing files (eg, kubeconfigs with embedded
+// credentials) written by the CLI. The files are stored in a managed directory, each with a
+// metadata sidecar that records the environment and the expiration time of the credentials,
+// so that expired files can be listed and pruned. Only files created by the CLI are ever
+// touched: a file is considered managed only if it has a valid sidecar and starts with the
+// marker header.
+package credfiles
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/pkg/common"
+)
+
+// Name of the managed directory (within the CLI's state directory).
+const credentialsDirName = "credentials"
+
+// Suffix of the metadata sidecar files.
+const sidecarSuffix = ".meta.json"
+
+// Marker stored in the sidecar files.
+const sidecarMarker = "metaplay-cli-credential-file/v1"
+
+// Marker header written as the first line of each credential file. The credential formats
+// used (YAML and INI) support '#' comments.
+const MarkerHeader = "# Credential file managed by the Metaplay CLI, see 'metaplay auth list-credentials'."
+
+// Metadata of a managed credential file, stored in its sidecar.
+type Metadata struct {
+	Marker      string    `json:"marker"`      // Always sidecarMarker.
+	Kind        string    `json:"kind"`        // Kind of the credentials, eg, 'kubeconfig'.
+	Environment string    `json:"environment"` // Human ID of the environment the credentials are for.
+	CreatedAt   time.Time `json:"createdAt"`   // Time when the file was written.
+	ExpiresAt   time.Time `json:"expiresAt"`   // Time when the credentials expire.
+}
+
+// A managed credential file.
+type File struct {
+	Path string // Path to the credential file.
+	Metadata
+}
+
+// Check whether the credentials in the file have expired.
+func (file *File) IsExpired(now time.Time) bool {
+	return !now.Before(file.ExpiresAt)
+}
+
+// Delete the credential file and its sidecar.
+func (file *File) Remove() error {
+	if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove credential file %s: %w", file.Path, err)
+	}
+	if err := os.Remove(file.Path + sidecarSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove credential file metadata %s: %w", file.Path+sidecarSuffix, err)
+	}
+	return nil
+}
+
+// Resolve the managed directory. The directory is created if it doesn't exist yet.
+func GetDir() (string, error) {
+	stateDir, err := common.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(stateDir, credentialsDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create credentials directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// Write a credential file into the managed directory. The file is prefixed with the marker
+// header and accompanied by a metadata sidecar. Both are only readable by the user. Returns
+// the path to the written file.
+func Write(kind string, environment string, extension string, content []byte, expiresAt time.Time) (string, error) {
+	dir, err := GetDir()
+	if err != nil {
+		return "", err
+	}
+
+	// Create the file with a unique name (os.CreateTemp uses mode 0600).
+	file, err := os.CreateTemp(dir, fmt.Sprintf("%s-%s-*%s", environment, kind, extension))
+	if err != nil {
+		return "", fmt.Errorf("failed to create credential file: %w", err)
+	}
+	filePath := file.Name()
+	_, err = file.WriteString(MarkerHeader + "\n")
+	if err == nil {
+		_, err = file.Write(content)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("failed to write credential file %s: %w", filePath, err)
+	}
+
+	// Write the sidecar.
+	metadata, err := json.MarshalIndent(Metadata{
+		Marker:      sidecarMarker,
+		Kind:        kind,
+		Environment: environment,
+		CreatedAt:   time.Now().UTC(),
+		ExpiresAt:   expiresAt.UTC(),
+	}, "", "  ")
+	if err != nil {
+		os.Remove(filePath)
+		return "", err
+	}
+	if err := os.WriteFile(filePath+sidecarSuffix, metadata, 0600); err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("failed to write credential file metadata: %w", err)
+	}
+
+	return filePath, nil
+}
+
+// List the managed credential files, sorted by creation time. Files in the directory that
+// were not created by the CLI are ignored.
+func List() ([]*File, error) {
+	dir, err := GetDir()
+	if err != nil {
+		return nil, err
+	}
+	return listDir(dir, time.Time{})
+}
+
+// Delete the expired credential files. At most the given duration is spent on pruning (zero
+// for no limit), so that the pruning can be done opportunistically when the CLI starts.
+// Returns the deleted files.
+func Prune(now time.Time, budget time.Duration) ([]*File, error) {
+	dir, err := GetDir()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Time{}
+	if budget > 0 {
+		deadline = time.Now().Add(budget)
+	}
+	files, err := listDir(dir, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []*File{}
+	for _, file := range files {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		if !file.IsExpired(now) {
+			continue
+		}
+		if err := file.Remove(); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, file)
+	}
+	return pruned, nil
+}
+
+// List the managed credential files in the directory, stopping early if the deadline (if
+// non-zero) is reached.
+func listDir(dir string, deadline time.Time) ([]*File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials directory %s: %w", dir, err)
+	}
+
+	files := []*File{}
+	for _, entry := range entries {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sidecarSuffix) {
+			continue
+		}
+		filePath := filepath.Join(dir, strings.TrimSuffix(entry.Name(), sidecarSuffix))
+		file, ok := readManagedFile(filePath)
+		if ok {
+			files = append(files, file)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt.Before(files[j].CreatedAt)
+	})
+	return files, nil
+}
+
+// Read the metadata of a credential file. Returns false if the file is not a valid managed
+// file: the sidecar is missing or invalid, or the file doesn't start with the marker header.
+func readManagedFile(filePath string) (*File, bool) {
+	content, err := os.ReadFile(filePath + sidecarSuffix)
+	if err != nil {
+		return nil, false
+	}
+	var metadata Metadata
+	if err := json.Unmarshal(content, &metadata); err != nil || metadata.Marker != sidecarMarker {
+		return nil, false
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+	firstLine, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil || strings.TrimRight(firstLine, "\r\n") != MarkerHeader {
+		return nil, false
+	}
+
+	return &File{Path: filePath, Metadata: metadata}, true
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package credfiles
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestPruneOnlyRemovesExpiredManagedFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on HOME to redirect the state directory")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	now := time.Now()
+	expiredPath, err := Write("kubeconfig", "tough-falcons", ".yaml", []byte("apiVersion: v1\n"), now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("failed to write credential file: %v", err)
+	}
+	validPath, err := Write("kubeconfig", "lovely-wombats", ".yaml", []byte("apiVersion: v1\n"), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to write credential file: %v", err)
+	}
+
+	// Files must only be readable by the user.
+	info, err := os.Stat(validPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("credential file mode = %v, expected 0600", info.Mode().Perm())
+	}
+
+	// Files not created by the CLI: no sidecar, and a sidecar without the marker header.
+	dir, err := GetDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreignPath := filepath.Join(dir, "foreign.yaml")
+	if err := os.WriteFile(foreignPath, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	noHeaderPath := filepath.Join(dir, "no-header.yaml")
+	if err := os.WriteFile(noHeaderPath, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sidecar, err := os.ReadFile(expiredPath + sidecarSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(noHeaderPath+sidecarSuffix, sidecar, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := List()
+	if err != nil {
+		t.Fatalf("failed to list credential files: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("listed %d credential files, expected 2", len(files))
+	}
+
+	pruned, err := Prune(now, 0)
+	if err != nil {
+		t.Fatalf("failed to prune credential files: %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].Path != expiredPath {
+		t.Errorf("pruned %v, expected only %s", pruned, expiredPath)
+	}
+	for _, path := range []string{expiredPath, expiredPath + sidecarSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", path)
+		}
+	}
+	for _, path := range []string{validPath, foreignPath, noHeaderPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package dotnetutil
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
+)
+
+// Name of the .NET runtime (in 'dotnet --list-runtimes') that the game server runs on.
+const netCoreRuntimeName = "Microsoft.NETCore.App"
+
+// Matches the lines of 'dotnet --list-sdks', eg, '8.0.414 [/usr/share/dotnet/sdk]'.
+var listSdksLineRegex = regexp.MustCompile(`^(\S+)\s+\[(.*)\]$`)
+
+// Matches the lines of 'dotnet --list-runtimes', eg, 'Microsoft.NETCore.App 8.0.20 [/usr/share/dotnet/shared/Microsoft.NETCore.App]'.
+var listRuntimesLineRegex = regexp.MustCompile(`^(\S+)\s+(\S+)\s+\[(.*)\]$`)
+
+// Installed .NET SDK (from 'dotnet --list-sdks').
+type SDK struct {
+	Version *version.Version // Version of the SDK, eg, '8.0.414'.
+	Path    string           // Directory where the SDK is installed.
+}
+
+// Run the dotnet binary with the given arguments and return its output.
+func runDotnet(dotnetPath string, args ...string) (string, error) {
+	cmd := exec.Command(dotnetPath, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run 'dotnet %s': %w: %s", strings.Join(args, " "), err, strings.TrimSpace(out.String()))
+	}
+	return out.String(), nil
+}
+
+// List the installed .NET SDKs using 'dotnet --list-sdks' with the given dotnet binary.
+func ListInstalledSDKs(dotnetPath string) ([]SDK, error) {
+	output, err := runDotnet(dotnetPath, "--list-sdks")
+	if err != nil {
+		return nil, err
+	}
+	return parseListSdksOutput(output), nil
+}
+
+// Parse the output of 'dotnet --list-sdks'. Lines with invalid versions are ignored.
+func parseListSdksOutput(output string) []SDK {
+	sdks := []SDK{}
+	for _, line := range strings.Split(output, "\n") {
+		match := listSdksLineRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		sdkVersion, err := version.NewVersion(match[1])
+		if err != nil {
+			log.Debug().Msgf("Ignoring .NET SDK with invalid version '%s': %v", match[1], err)
+			continue
+		}
+		sdks = append(sdks, SDK{Version: sdkVersion, Path: match[2]})
+	}
+	return sdks
+}
+
+// Find the latest SDK that is at least the minimum version and whose major version is at
+// least minMajor (eg, building for .NET 9 requires SDK 9.x). Returns nil if none is found.
+func FindBestSDK(sdks []SDK, minimum *version.Version, minMajor int) *SDK {
+	var best *SDK
+	for ndx, sdk := range sdks {
+		if sdk.Version.LessThan(minimum) || sdk.Version.Segments()[0] < minMajor {
+			continue
+		}
+		if best == nil || sdk.Version.GreaterThan(best.Version) {
+			best = &sdks[ndx]
+		}
+	}
+	return best
+}
+
+// Get the latest installed .NET runtime (Microsoft.NETCore.App) version using
+// 'dotnet --list-runtimes' with the given dotnet binary.
+func GetRuntimeVersion(dotnetPath string) (*version.Version, error) {
+	output, err := runDotnet(dotnetPath, "--list-runtimes")
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeVersion := parseListRuntimesOutput(output)
+	if runtimeVersion == nil {
+		return nil, fmt.Errorf("no %s runtime found in 'dotnet --list-runtimes' output", netCoreRuntimeName)
+	}
+	return runtimeVersion, nil
+}
+
+// Parse the output of 'dotnet --list-runtimes' and return the latest Microsoft.NETCore.App
+// version, or nil if none is found.
+func parseListRuntimesOutput(output string) *version.Version {
+	var latest *version.Version
+	for _, line := range strings.Split(output, "\n") {
+		match := listRuntimesLineRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil || match[1] != netCoreRuntimeName {
+			continue
+		}
+		runtimeVersion, err := version.NewVersion(match[2])
+		if err != nil {
+			log.Debug().Msgf("Ignoring .NET runtime with invalid version '%s': %v", match[2], err)
+			continue
+		}
+		if latest == nil || runtimeVersion.GreaterThan(latest) {
+			latest = runtimeVersion
+		}
+	}
+	return latest
+}
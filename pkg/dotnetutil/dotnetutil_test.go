@@ -0,0 +1,57 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package dotnetutil
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestFindBestSDK(t *testing.T) {
+	sdks := parseListSdksOutput(`8.0.414 [/usr/share/dotnet/sdk]
+9.0.100-rc.1.24452.12 [/usr/share/dotnet/sdk]
+9.0.305 [/usr/share/dotnet/sdk]
+not-a-version [/usr/share/dotnet/sdk]
+`)
+	if len(sdks) != 3 {
+		t.Fatalf("expected 3 SDKs, got %d", len(sdks))
+	}
+
+	tests := []struct {
+		minimum  string
+		minMajor int
+		expected string // Empty if no SDK should be found.
+	}{
+		{"8.0.400", 8, "9.0.305"},
+		{"8.0.400", 9, "9.0.305"},
+		{"9.0.400", 9, ""},
+		{"8.0.100", 10, ""},
+	}
+
+	for _, test := range tests {
+		best := FindBestSDK(sdks, version.Must(version.NewVersion(test.minimum)), test.minMajor)
+		got := ""
+		if best != nil {
+			got = best.Version.String()
+		}
+		if got != test.expected {
+			t.Errorf("FindBestSDK(%s, %d) = %q, expected %q", test.minimum, test.minMajor, got, test.expected)
+		}
+	}
+}
+
+func TestParseListRuntimesOutput(t *testing.T) {
+	runtimeVersion := parseListRuntimesOutput(`Microsoft.AspNetCore.App 9.0.9 [/usr/share/dotnet/shared/Microsoft.AspNetCore.App]
+Microsoft.NETCore.App 8.0.20 [/usr/share/dotnet/shared/Microsoft.NETCore.App]
+Microsoft.NETCore.App 9.0.9 [/usr/share/dotnet/shared/Microsoft.NETCore.App]
+`)
+	if runtimeVersion == nil || runtimeVersion.String() != "9.0.9" {
+		t.Errorf("expected runtime version 9.0.9, got %v", runtimeVersion)
+	}
+
+	if runtimeVersion := parseListRuntimesOutput(""); runtimeVersion != nil {
+		t.Errorf("expected no runtime version, got %v", runtimeVersion)
+	}
+}
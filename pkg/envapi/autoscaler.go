@@ -0,0 +1,186 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Annotation in which FreezeShardSetAutoscaler() saves the original replica bounds of the
+// HorizontalPodAutoscaler, for RestoreShardSetAutoscaler() to restore.
+const autoscalerOriginalSpecAnnotation = "metaplay.io/original-hpa-spec"
+
+// HorizontalPodAutoscaler that scales a shard set's StatefulSet.
+type ShardSetAutoscaler struct {
+	Name                string // Name of the HorizontalPodAutoscaler.
+	MinReplicas         int32  // Current minimum number of replicas.
+	MaxReplicas         int32  // Current maximum number of replicas.
+	IsFrozen            bool   // Is the autoscaler frozen by FreezeShardSetAutoscaler()?
+	OriginalMinReplicas int32  // Minimum number of replicas before freezing, only if frozen.
+	OriginalMaxReplicas int32  // Maximum number of replicas before freezing, only if frozen.
+}
+
+// Replica bounds of an autoscaler, as saved in autoscalerOriginalSpecAnnotation.
+type autoscalerSpec struct {
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	MaxReplicas int32  `json:"maxReplicas"`
+}
+
+// Find the HorizontalPodAutoscaler of the named shard set. Returns nil (without an error) if
+// the shard set is not autoscaled.
+func (gs *TargetGameServer) GetShardSetAutoscaler(ctx context.Context, shardSetName string) (*ShardSetAutoscaler, error) {
+	shardSet, err := gs.getShardSetByName(shardSetName)
+	if err != nil {
+		return nil, err
+	}
+	hpa, err := findShardSetAutoscaler(ctx, shardSet.Cluster.KubeClient.Clientset, gs.Namespace, shardSetName)
+	if err != nil || hpa == nil {
+		return nil, err
+	}
+	return newShardSetAutoscaler(hpa)
+}
+
+// Freeze the HorizontalPodAutoscaler of the named shard set at the given number of replicas,
+// by setting both its minimum and maximum replicas to it. The original bounds are saved in an
+// annotation on the autoscaler, so that RestoreShardSetAutoscaler() can restore them. Freezing
+// an already frozen autoscaler keeps the originally saved bounds.
+func (gs *TargetGameServer) FreezeShardSetAutoscaler(ctx context.Context, shardSetName string, replicas int32) error {
+	shardSet, err := gs.getShardSetByName(shardSetName)
+	if err != nil {
+		return err
+	}
+	return freezeShardSetAutoscaler(ctx, shardSet.Cluster.KubeClient.Clientset, gs.Namespace, shardSetName, replicas)
+}
+
+// Restore the original replica bounds of a HorizontalPodAutoscaler frozen with
+// FreezeShardSetAutoscaler(). Returns the restored autoscaler.
+func (gs *TargetGameServer) RestoreShardSetAutoscaler(ctx context.Context, shardSetName string) (*ShardSetAutoscaler, error) {
+	shardSet, err := gs.getShardSetByName(shardSetName)
+	if err != nil {
+		return nil, err
+	}
+	return restoreShardSetAutoscaler(ctx, shardSet.Cluster.KubeClient.Clientset, gs.Namespace, shardSetName)
+}
+
+// Find the HorizontalPodAutoscaler targeting the shard set's StatefulSet, or nil if none.
+func findShardSetAutoscaler(ctx context.Context, clientset kubernetes.Interface, namespace, shardSetName string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list horizontal pod autoscalers: %w", err)
+	}
+	for ndx := range hpas.Items {
+		targetRef := hpas.Items[ndx].Spec.ScaleTargetRef
+		if targetRef.Kind == "StatefulSet" && targetRef.Name == shardSetName {
+			return &hpas.Items[ndx], nil
+		}
+	}
+	return nil, nil
+}
+
+// Convert the HorizontalPodAutoscaler into a ShardSetAutoscaler.
+func newShardSetAutoscaler(hpa *autoscalingv2.HorizontalPodAutoscaler) (*ShardSetAutoscaler, error) {
+	autoscaler := &ShardSetAutoscaler{
+		Name:        hpa.Name,
+		MinReplicas: 1, // Kubernetes default
+		MaxReplicas: hpa.Spec.MaxReplicas,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		autoscaler.MinReplicas = *hpa.Spec.MinReplicas
+	}
+	originalSpec, err := getOriginalAutoscalerSpec(hpa)
+	if err != nil {
+		return nil, err
+	}
+	if originalSpec != nil {
+		autoscaler.IsFrozen = true
+		autoscaler.OriginalMinReplicas = 1
+		if originalSpec.MinReplicas != nil {
+			autoscaler.OriginalMinReplicas = *originalSpec.MinReplicas
+		}
+		autoscaler.OriginalMaxReplicas = originalSpec.MaxReplicas
+	}
+	return autoscaler, nil
+}
+
+// Get the original replica bounds saved by freezeShardSetAutoscaler(), or nil if not frozen.
+func getOriginalAutoscalerSpec(hpa *autoscalingv2.HorizontalPodAutoscaler) (*autoscalerSpec, error) {
+	originalSpecJSON, found := hpa.Annotations[autoscalerOriginalSpecAnnotation]
+	if !found {
+		return nil, nil
+	}
+	var originalSpec autoscalerSpec
+	if err := json.Unmarshal([]byte(originalSpecJSON), &originalSpec); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s on horizontal pod autoscaler %s: %w", autoscalerOriginalSpecAnnotation, hpa.Name, err)
+	}
+	return &originalSpec, nil
+}
+
+func freezeShardSetAutoscaler(ctx context.Context, clientset kubernetes.Interface, namespace, shardSetName string, replicas int32) error {
+	if replicas < 1 {
+		return fmt.Errorf("cannot freeze the horizontal pod autoscaler at %d replicas, the minimum is 1", replicas)
+	}
+
+	hpa, err := findShardSetAutoscaler(ctx, clientset, namespace, shardSetName)
+	if err != nil {
+		return err
+	}
+	if hpa == nil {
+		return fmt.Errorf("no horizontal pod autoscaler found for shard set '%s'", shardSetName)
+	}
+
+	// Save the original bounds, unless already frozen.
+	if _, found := hpa.Annotations[autoscalerOriginalSpecAnnotation]; !found {
+		originalSpecJSON, err := json.Marshal(autoscalerSpec{MinReplicas: hpa.Spec.MinReplicas, MaxReplicas: hpa.Spec.MaxReplicas})
+		if err != nil {
+			return err
+		}
+		if hpa.Annotations == nil {
+			hpa.Annotations = map[string]string{}
+		}
+		hpa.Annotations[autoscalerOriginalSpecAnnotation] = string(originalSpecJSON)
+	}
+	hpa.Spec.MinReplicas = &replicas
+	hpa.Spec.MaxReplicas = replicas
+
+	log.Debug().Msgf("Freeze horizontal pod autoscaler %s at %d replicas", hpa.Name, replicas)
+	if _, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Update(ctx, hpa, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update horizontal pod autoscaler %s: %w", hpa.Name, err)
+	}
+	return nil
+}
+
+func restoreShardSetAutoscaler(ctx context.Context, clientset kubernetes.Interface, namespace, shardSetName string) (*ShardSetAutoscaler, error) {
+	hpa, err := findShardSetAutoscaler(ctx, clientset, namespace, shardSetName)
+	if err != nil {
+		return nil, err
+	}
+	if hpa == nil {
+		return nil, fmt.Errorf("no horizontal pod autoscaler found for shard set '%s'", shardSetName)
+	}
+	originalSpec, err := getOriginalAutoscalerSpec(hpa)
+	if err != nil {
+		return nil, err
+	}
+	if originalSpec == nil {
+		return nil, fmt.Errorf("horizontal pod autoscaler %s is not frozen", hpa.Name)
+	}
+
+	hpa.Spec.MinReplicas = originalSpec.MinReplicas
+	hpa.Spec.MaxReplicas = originalSpec.MaxReplicas
+	delete(hpa.Annotations, autoscalerOriginalSpecAnnotation)
+
+	log.Debug().Msgf("Restore horizontal pod autoscaler %s", hpa.Name)
+	updated, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Update(ctx, hpa, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update horizontal pod autoscaler %s: %w", hpa.Name, err)
+	}
+	return newShardSetAutoscaler(updated)
+}
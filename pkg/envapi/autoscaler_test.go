@@ -0,0 +1,101 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestAutoscaler(name, targetKind, targetName string, minReplicas *int32, maxReplicas int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tough-falcons"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: targetKind, Name: targetName},
+			MinReplicas:    minReplicas,
+			MaxReplicas:    maxReplicas,
+		},
+	}
+}
+
+func TestFreezeAndRestoreShardSetAutoscaler(t *testing.T) {
+	ctx := context.Background()
+	minReplicas := int32(2)
+	clientset := fake.NewClientset(
+		newTestAutoscaler("other-hpa", "Deployment", "all", nil, 3),
+		newTestAutoscaler("all-hpa", "StatefulSet", "all", &minReplicas, 8),
+	)
+
+	// Only autoscalers of the shard set's StatefulSet are found.
+	hpa, err := findShardSetAutoscaler(ctx, clientset, "tough-falcons", "all")
+	if err != nil || hpa == nil || hpa.Name != "all-hpa" {
+		t.Fatalf("unexpected autoscaler: %v, err: %v", hpa, err)
+	}
+	if hpa, err := findShardSetAutoscaler(ctx, clientset, "tough-falcons", "service"); err != nil || hpa != nil {
+		t.Fatalf("expected no autoscaler for shard set 'service', got: %v, err: %v", hpa, err)
+	}
+
+	// Freeze twice: the original bounds are kept from the first freeze.
+	for _, replicas := range []int32{5, 4} {
+		if err := freezeShardSetAutoscaler(ctx, clientset, "tough-falcons", "all", replicas); err != nil {
+			t.Fatalf("failed to freeze at %d: %v", replicas, err)
+		}
+		hpa, _ := findShardSetAutoscaler(ctx, clientset, "tough-falcons", "all")
+		autoscaler, err := newShardSetAutoscaler(hpa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := ShardSetAutoscaler{Name: "all-hpa", MinReplicas: replicas, MaxReplicas: replicas, IsFrozen: true, OriginalMinReplicas: 2, OriginalMaxReplicas: 8}
+		if *autoscaler != expected {
+			t.Errorf("frozen autoscaler = %+v, expected %+v", *autoscaler, expected)
+		}
+	}
+
+	if err := freezeShardSetAutoscaler(ctx, clientset, "tough-falcons", "all", 0); err == nil {
+		t.Error("expected an error when freezing at 0 replicas")
+	}
+
+	// Restore the original bounds.
+	restored, err := restoreShardSetAutoscaler(ctx, clientset, "tough-falcons", "all")
+	if err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	expected := ShardSetAutoscaler{Name: "all-hpa", MinReplicas: 2, MaxReplicas: 8}
+	if *restored != expected {
+		t.Errorf("restored autoscaler = %+v, expected %+v", *restored, expected)
+	}
+
+	// Restoring again fails, as the autoscaler is not frozen anymore.
+	if _, err := restoreShardSetAutoscaler(ctx, clientset, "tough-falcons", "all"); err == nil {
+		t.Error("expected an error when restoring an autoscaler that is not frozen")
+	}
+}
+
+func TestRestoreShardSetAutoscalerDefaultMinReplicas(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewClientset(newTestAutoscaler("all-hpa", "StatefulSet", "all", nil, 6))
+
+	if err := freezeShardSetAutoscaler(ctx, clientset, "tough-falcons", "all", 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restoreShardSetAutoscaler(ctx, clientset, "tough-falcons", "all"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unset minimum stays unset, rather than being written as the default.
+	hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("tough-falcons").Get(ctx, "all-hpa", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hpa.Spec.MinReplicas != nil || hpa.Spec.MaxReplicas != 6 {
+		t.Errorf("unexpected restored spec: min=%v, max=%d", hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+	}
+	if _, found := hpa.Annotations[autoscalerOriginalSpecAnnotation]; found {
+		t.Error("original spec annotation was not removed")
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+// Label selector for the ConfigMaps belonging to the game server.
+// \todo Only the game server chart adds this label for now.
+const gameServerConfigMapLabelSelector = "app=metaplay-server"
+
+// Field manager used when applying changes with server-side apply.
+const cliFieldManager = "metaplay-cli"
+
+// List the ConfigMaps of the game server in the environment.
+func (target *TargetEnvironment) ListGameServerConfigMaps(ctx context.Context) ([]corev1.ConfigMap, error) {
+	kubeCli, err := target.GetPrimaryKubeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	configMaps, err := kubeCli.Clientset.CoreV1().ConfigMaps(kubeCli.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: gameServerConfigMapLabelSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game server ConfigMaps in environment %s: %w", target.HumanId, err)
+	}
+	log.Debug().Msgf("Found %d game server ConfigMaps in environment %s", len(configMaps.Items), target.HumanId)
+	return configMaps.Items, nil
+}
+
+// Get the named ConfigMap in the environment. Returns nil if the ConfigMap doesn't exist.
+func (target *TargetEnvironment) GetConfigMap(ctx context.Context, name string) (*corev1.ConfigMap, error) {
+	kubeCli, err := target.GetPrimaryKubeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	configMap, err := kubeCli.Clientset.CoreV1().ConfigMaps(kubeCli.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s in environment %s: %w", name, target.HumanId, err)
+	}
+	return configMap, nil
+}
+
+// Apply the contents (data and binary data) of the named ConfigMap in the environment using
+// server-side apply. The ConfigMap is created if it doesn't exist. Only the contents are
+// applied, the metadata of an existing ConfigMap is left as-is. If another field manager owns
+// the changed fields, the apply fails with a conflict unless forceConflicts is set.
+func (target *TargetEnvironment) ApplyConfigMapContents(ctx context.Context, name string, data map[string]string, binaryData map[string][]byte, forceConflicts bool) error {
+	kubeCli, err := target.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+
+	applyConfig := corev1ac.ConfigMap(name, kubeCli.Namespace).
+		WithData(data).
+		WithBinaryData(binaryData)
+	_, err = kubeCli.Clientset.CoreV1().ConfigMaps(kubeCli.Namespace).Apply(ctx, applyConfig, metav1.ApplyOptions{
+		FieldManager: cliFieldManager,
+		Force:        forceConflicts,
+	})
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("conflict applying ConfigMap %s in environment %s (the fields are managed by another tool, eg, Helm): %w", name, target.HumanId, err)
+	} else if err != nil {
+		return fmt.Errorf("failed to apply ConfigMap %s in environment %s: %w", name, target.HumanId, err)
+	}
+	return nil
+}
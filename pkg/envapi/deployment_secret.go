@@ -0,0 +1,41 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate checks that the environment details (as returned by TargetEnvironment.GetDetails())
+// have all the fields required for operating on the environment. All the missing fields are
+// reported in a single error.
+func (details *DeploymentSecret) Validate() error {
+	requiredFields := []struct {
+		name  string
+		value string
+	}{
+		{"deployment.kubernetes_namespace", details.Deployment.KubernetesNamespace},
+		{"deployment.aws_region", details.Deployment.AwsRegion},
+		{"deployment.ecr_repo", details.Deployment.EcrRepo},
+	}
+
+	missingFields := []string{}
+	for _, field := range requiredFields {
+		if field.value == "" {
+			missingFields = append(missingFields, field.name)
+		}
+	}
+	if len(missingFields) > 0 {
+		return fmt.Errorf("invalid environment details, missing required fields: %s", strings.Join(missingFields, ", "))
+	}
+
+	return nil
+}
+
+// HasGameServerDeployment returns true if the environment has been set up for a game server
+// deployment, ie, it has the public and admin hostnames for the game server.
+func (details *DeploymentSecret) HasGameServerDeployment() bool {
+	return details.Deployment.ServerHostname != "" && details.Deployment.AdminHostname != ""
+}
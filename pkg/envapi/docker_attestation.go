@@ -0,0 +1,101 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Media type of the config blob of attestation artifacts pushed with PushDockerImageAttestation().
+const dockerAttestationConfigMediaType types.MediaType = "application/vnd.metaplay.attestation.config.v1+json"
+
+// Get the tag of the attestation artifact of an image, eg, 'sha256-<hex>.att' for the image ID
+// 'sha256:<hex>'. The artifact is tagged by the image ID (digest of the image config), which
+// is the same in the local docker daemon and in all registries that the image is pushed to.
+func DockerAttestationTag(imageID string) (string, error) {
+	hash, err := v1.NewHash(imageID)
+	if err != nil {
+		return "", fmt.Errorf("invalid docker image ID '%s': %w", imageID, err)
+	}
+	return fmt.Sprintf("%s-%s.att", hash.Algorithm, hash.Hex), nil
+}
+
+// Push an attestation of an image into the repository as an OCI artifact next to the image.
+// The content is stored as the artifact's only layer with the given media type. Returns the
+// reference of the pushed artifact.
+func PushDockerImageAttestation(ctx context.Context, creds *DockerCredentials, repository, imageID string, content []byte, mediaType string) (string, error) {
+	tag, err := DockerAttestationTag(imageID)
+	if err != nil {
+		return "", err
+	}
+	ref, err := name.ParseReference(fmt.Sprintf("%s:%s", repository, tag), name.WithDefaultRegistry(creds.RegistryURL))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse attestation reference: %w", err)
+	}
+
+	artifact, err := mutate.AppendLayers(empty.Image, static.NewLayer(content, types.MediaType(mediaType)))
+	if err != nil {
+		return "", fmt.Errorf("failed to create attestation artifact: %w", err)
+	}
+	artifact = mutate.ConfigMediaType(mutate.MediaType(artifact, types.OCIManifestSchema1), dockerAttestationConfigMediaType)
+
+	log.Debug().Msgf("Push attestation of image %s to %s", imageID, ref)
+	if err := remote.Write(ref, artifact, remote.WithAuth(dockerAuthenticator(creds)), remote.WithContext(ctx)); err != nil {
+		return "", fmt.Errorf("failed to push attestation to %s: %w", ref, err)
+	}
+	return ref.String(), nil
+}
+
+// Fetch the attestation of an image pushed with PushDockerImageAttestation() from the repository.
+// Returns nil (without an error) if the image has no attestation in the repository.
+func FetchDockerImageAttestation(ctx context.Context, creds *DockerCredentials, repository, imageID string, mediaType string) ([]byte, error) {
+	tag, err := DockerAttestationTag(imageID)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := name.ParseReference(fmt.Sprintf("%s:%s", repository, tag), name.WithDefaultRegistry(creds.RegistryURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse attestation reference: %w", err)
+	}
+
+	log.Debug().Msgf("Fetch attestation of image %s from %s", imageID, ref)
+	artifact, err := remote.Image(ref, remote.WithAuth(dockerAuthenticator(creds)), remote.WithContext(ctx))
+	if err != nil {
+		if isRegistryNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch attestation %s: %w", ref, err)
+	}
+
+	// Find the layer with the attestation content.
+	layers, err := artifact.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation %s: %w", ref, err)
+	}
+	for _, layer := range layers {
+		layerMediaType, err := layer.MediaType()
+		if err != nil || !strings.EqualFold(string(layerMediaType), mediaType) {
+			continue
+		}
+		reader, err := layer.Uncompressed()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attestation %s: %w", ref, err)
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("attestation %s has no content of type %s", ref, mediaType)
+}
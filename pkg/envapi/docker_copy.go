@@ -0,0 +1,117 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/rs/zerolog/log"
+)
+
+// Number of layers to copy in parallel between registries.
+const dockerCopyParallelism = 4
+
+// Source image for copying between registries, see ResolveRemoteDockerImage().
+type RemoteDockerImage struct {
+	Ref        name.Reference // Reference to the image in the source registry.
+	Digest     v1.Hash        // Digest of the image manifest.
+	Image      v1.Image       // Image accessor (layers are fetched lazily).
+	ConfigFile *v1.ConfigFile // Image config, including the labels.
+}
+
+// Resolve an image in a remote registry for copying it to another registry.
+func ResolveRemoteDockerImage(ctx context.Context, creds *DockerCredentials, imageRef string) (*RemoteDockerImage, error) {
+	ref, err := name.ParseReference(imageRef, name.WithDefaultRegistry(creds.RegistryURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse docker image reference: %w", err)
+	}
+
+	desc, err := remote.Get(ref, remote.WithAuth(dockerAuthenticator(creds)), remote.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote docker image %s: %w", imageRef, err)
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker image from descriptor: %w", err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker image config file: %w", err)
+	}
+
+	return &RemoteDockerImage{
+		Ref:        ref,
+		Digest:     desc.Digest,
+		Image:      img,
+		ConfigFile: cfg,
+	}, nil
+}
+
+// Check whether copying the image to the destination can be done within the registry, by
+// mounting the layers from the source repository (ECR only supports this within a registry,
+// ie, within the same AWS account and region). Otherwise, the layers are streamed through
+// this machine.
+func (src *RemoteDockerImage) CanMountInto(dstImageRef string) bool {
+	dstRef, err := name.ParseReference(dstImageRef)
+	if err != nil {
+		return false
+	}
+	return src.Ref.Context().RegistryStr() == dstRef.Context().RegistryStr()
+}
+
+// Copy the image into the destination registry and verify that the digest of the copied image
+// matches the source. The layers are copied in parallel, and layers already existing in the
+// destination are skipped. Progress is reported with onProgress (bytes complete, bytes total).
+// Returns the digest of the copied image.
+func (src *RemoteDockerImage) CopyTo(ctx context.Context, creds *DockerCredentials, dstImageRef string, onProgress func(complete, total int64)) (v1.Hash, error) {
+	dstRef, err := name.ParseReference(dstImageRef, name.WithDefaultRegistry(creds.RegistryURL))
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to parse docker image reference: %w", err)
+	}
+
+	// Report progress from a separate goroutine (the channel is closed by remote.Write()).
+	progress := make(chan v1.Update, 16)
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		for update := range progress {
+			if update.Error == nil && onProgress != nil {
+				onProgress(update.Complete, update.Total)
+			}
+		}
+	}()
+
+	// Write the image: layers from the same registry are mounted instead of uploaded.
+	dstAuth := remote.WithAuth(dockerAuthenticator(creds))
+	log.Debug().Msgf("Copy docker image %s to %s", src.Ref, dstRef)
+	err = remote.Write(dstRef, src.Image, dstAuth, remote.WithContext(ctx), remote.WithJobs(dockerCopyParallelism), remote.WithProgress(progress))
+	<-progressDone
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to copy docker image to %s: %w", dstImageRef, err)
+	}
+
+	// Verify that the destination has the exact same image.
+	dstDesc, err := remote.Head(dstRef, dstAuth, remote.WithContext(ctx))
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to verify the copied docker image %s: %w", dstImageRef, err)
+	}
+	if dstDesc.Digest != src.Digest {
+		return v1.Hash{}, fmt.Errorf("digest of the copied image %s (%s) does not match the source image (%s)", dstImageRef, dstDesc.Digest, src.Digest)
+	}
+
+	return dstDesc.Digest, nil
+}
+
+// Create a registry authenticator from the docker credentials.
+func dockerAuthenticator(creds *DockerCredentials) authn.Authenticator {
+	return authn.FromConfig(authn.AuthConfig{
+		Username: creds.Username,
+		Password: creds.Password,
+	})
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// Layer of a local docker image, as reported by 'docker history'.
+type DockerImageLayer struct {
+	Size      int64  // Size of the layer in bytes (zero for metadata-only steps).
+	CreatedBy string // Dockerfile step that created the layer, eg, 'RUN dotnet publish ...'.
+}
+
+// Size of a local docker image and its layers, see ReadLocalDockerImageSize().
+type DockerImageSize struct {
+	ImageID string             // ID of the image.
+	Size    int64              // Total size of the image in bytes.
+	Layers  []DockerImageLayer // Layers in build order (base image first), including metadata-only steps.
+
+	PreviousRepoTag string // Name of the previous local image of the same repository, empty if none.
+	PreviousSize    int64  // Total size of the previous image in bytes.
+}
+
+// Matches runs of whitespace in the layer commands.
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+// Read the size and the layer sizes of a local docker image, along with the size of the
+// previous image of the same repository in the local docker, if any. Works with images built
+// by both the classic builder and buildkit (including buildx).
+func ReadLocalDockerImageSize(ctx context.Context, imageRef string) (*DockerImageSize, error) {
+	repository, err := getDockerImageRepositoryFilter(imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect docker image %s: %w", imageRef, err)
+	}
+	history, err := cli.ImageHistory(ctx, imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of docker image %s: %w", imageRef, err)
+	}
+
+	result := &DockerImageSize{
+		ImageID: inspect.ID,
+		Size:    inspect.Size,
+		Layers:  newDockerImageLayers(history),
+	}
+
+	// Find the newest other image of the same repository.
+	images, err := cli.ImageList(ctx, image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", repository)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local docker images: %w", err)
+	}
+	if previous := findPreviousDockerImage(images, inspect.ID); previous != nil {
+		result.PreviousRepoTag = previous.RepoTags[0]
+		result.PreviousSize = previous.Size
+	}
+	return result, nil
+}
+
+// Get the repository of the image reference in the form the docker daemon matches in the
+// 'reference' filter of the image list, eg, 'mygame' for 'docker.io/library/mygame:1' and
+// '<registry>/mygame' for images of other registries.
+func getDockerImageRepositoryFilter(imageRef string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse docker image reference: %w", err)
+	}
+	return reference.FamiliarName(named), nil
+}
+
+// Convert the image history (newest step first) into layers in build order.
+func newDockerImageLayers(history []image.HistoryResponseItem) []DockerImageLayer {
+	layers := make([]DockerImageLayer, len(history))
+	for ndx, item := range history {
+		layers[len(history)-1-ndx] = DockerImageLayer{
+			Size:      item.Size,
+			CreatedBy: formatLayerCreatedBy(item.CreatedBy),
+		}
+	}
+	return layers
+}
+
+// Format the command that created a layer as the Dockerfile step, removing the shell wrapping
+// of the classic builder and the '# buildkit' marker of buildkit, eg,
+// '/bin/sh -c #(nop) WORKDIR /app' becomes 'WORKDIR /app' and '/bin/sh -c dotnet publish'
+// becomes 'RUN dotnet publish'.
+func formatLayerCreatedBy(createdBy string) string {
+	step := strings.TrimSpace(createdBy)
+	step = strings.TrimSpace(strings.TrimSuffix(step, "# buildkit"))
+	if rest, found := strings.CutPrefix(step, "/bin/sh -c #(nop)"); found {
+		step = strings.TrimSpace(rest)
+	} else if rest, found := strings.CutPrefix(step, "/bin/sh -c "); found {
+		step = "RUN " + strings.TrimSpace(rest)
+	} else if rest, found := strings.CutPrefix(step, "RUN /bin/sh -c "); found {
+		step = "RUN " + strings.TrimSpace(rest)
+	}
+	return whitespaceRegex.ReplaceAllString(step, " ")
+}
+
+// Find the newest tagged image that isn't the current image. Returns nil if there is none.
+func findPreviousDockerImage(images []image.Summary, currentImageID string) *image.Summary {
+	var previous *image.Summary
+	for ndx := range images {
+		img := &images[ndx]
+		if img.ID == currentImageID || len(img.RepoTags) == 0 {
+			continue
+		}
+		if previous == nil || img.Created > previous.Created {
+			previous = img
+		}
+	}
+	return previous
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+func TestFormatLayerCreatedBy(t *testing.T) {
+	testCases := []struct {
+		createdBy string
+		expected  string
+	}{
+		// Classic builder.
+		{"/bin/sh -c #(nop)  WORKDIR /app", "WORKDIR /app"},
+		{"/bin/sh -c #(nop) COPY dir:0123abcd in /app ", "COPY dir:0123abcd in /app"},
+		{"/bin/sh -c dotnet publish -c Release   -o /app", "RUN dotnet publish -c Release -o /app"},
+		// Buildkit.
+		{"RUN /bin/sh -c dotnet publish -c Release -o /app # buildkit", "RUN dotnet publish -c Release -o /app"},
+		{"COPY /build/out /app # buildkit", "COPY /build/out /app"},
+		{"ENTRYPOINT [\"/app/Server\"]", "ENTRYPOINT [\"/app/Server\"]"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := formatLayerCreatedBy(tc.createdBy); got != tc.expected {
+			t.Errorf("formatLayerCreatedBy(%q) = %q, expected %q", tc.createdBy, got, tc.expected)
+		}
+	}
+}
+
+func TestNewDockerImageLayers(t *testing.T) {
+	// The history is newest first, the layers are in build order.
+	layers := newDockerImageLayers([]image.HistoryResponseItem{
+		{CreatedBy: "COPY /build/out /app # buildkit", Size: 300},
+		{CreatedBy: "/bin/sh -c #(nop) ADD file:abc in / ", Size: 100},
+	})
+	if len(layers) != 2 || layers[0].Size != 100 || layers[1].CreatedBy != "COPY /build/out /app" {
+		t.Errorf("unexpected layers: %+v", layers)
+	}
+}
+
+func TestFindPreviousDockerImage(t *testing.T) {
+	images := []image.Summary{
+		{ID: "sha256:current", RepoTags: []string{"mygame:3"}, Created: 300},
+		{ID: "sha256:old", RepoTags: []string{"mygame:1"}, Created: 100},
+		{ID: "sha256:previous", RepoTags: []string{"mygame:2"}, Created: 200},
+		{ID: "sha256:dangling", Created: 250},
+	}
+	previous := findPreviousDockerImage(images, "sha256:current")
+	if previous == nil || previous.ID != "sha256:previous" {
+		t.Errorf("expected the previous image, got: %+v", previous)
+	}
+	if previous := findPreviousDockerImage(images[:1], "sha256:current"); previous != nil {
+		t.Errorf("expected no previous image, got: %+v", previous)
+	}
+}
+
+func TestGetDockerImageRepositoryFilter(t *testing.T) {
+	testCases := []struct {
+		imageRef string
+		expected string
+	}{
+		{"mygame:364cff09", "mygame"},
+		{"docker.io/library/mygame:364cff09", "mygame"},
+		{"metaplay/mygame:364cff09", "metaplay/mygame"},
+		{"123456789012.dkr.ecr.eu-west-1.amazonaws.com/tough-falcons:364cff09", "123456789012.dkr.ecr.eu-west-1.amazonaws.com/tough-falcons"},
+		{"localhost:5000/mygame:364cff09", "localhost:5000/mygame"},
+	}
+	for _, tc := range testCases {
+		got, err := getDockerImageRepositoryFilter(tc.imageRef)
+		if err != nil || got != tc.expected {
+			t.Errorf("getDockerImageRepositoryFilter(%q) = %q (err: %v), expected %q", tc.imageRef, got, err, tc.expected)
+		}
+	}
+
+	if _, err := getDockerImageRepositoryFilter("MyGame:latest"); err == nil {
+		t.Errorf("expected an invalid reference to fail")
+	}
+}
@@ -5,7 +5,9 @@ package envapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/docker/docker/api/types/filters"
@@ -16,6 +18,7 @@ import (
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/daemon"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 )
 
 // Metadata about a Metaplay docker image.
@@ -88,6 +91,25 @@ func ReadLocalDockerImageMetadata(imageRef string) (*v1.ConfigFile, error) {
 	return cfg, nil
 }
 
+// ReadLocalDockerImageID returns the ID (sha256 digest of the image config) of a local Docker image.
+func ReadLocalDockerImageID(imageRef string) (string, error) {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse docker image reference: %w", err)
+	}
+
+	img, err := daemon.Image(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get local docker image: %w", err)
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		return "", fmt.Errorf("failed to get docker image ID: %w", err)
+	}
+	return configName.String(), nil
+}
+
 // FetchRemoteDockerImageMetadata retrieves the labels of an image in a remote Docker registry.
 func FetchRemoteDockerImageMetadata(creds *DockerCredentials, imageRef string) (*v1.ConfigFile, error) {
 	// Create a registry authenticator using the provided credentials
@@ -122,6 +144,49 @@ func FetchRemoteDockerImageMetadata(creds *DockerCredentials, imageRef string) (
 	return cfg, nil
 }
 
+// Check whether the given image exists in the remote registry. Only the manifest
+// is fetched. Returns false (without an error) if the registry reports that the
+// image or repository does not exist.
+func RemoteDockerImageExists(creds *DockerCredentials, imageRef string) (bool, error) {
+	authenticator := authn.FromConfig(authn.AuthConfig{
+		Username: creds.Username,
+		Password: creds.Password,
+	})
+
+	ref, err := name.ParseReference(imageRef, name.WithDefaultRegistry(creds.RegistryURL))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse docker image reference: %w", err)
+	}
+
+	_, err = remote.Head(ref, remote.WithAuth(authenticator))
+	if err != nil {
+		if isRegistryNotFoundError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check for docker image %s in the registry: %w", imageRef, err)
+	}
+
+	return true, nil
+}
+
+// Check whether the error from a registry operation means that the image or the repository
+// does not exist.
+func isRegistryNotFoundError(err error) bool {
+	var transportErr *transport.Error
+	if !errors.As(err, &transportErr) {
+		return false
+	}
+	if transportErr.StatusCode == http.StatusNotFound {
+		return true
+	}
+	for _, diagnostic := range transportErr.Errors {
+		if diagnostic.Code == transport.ManifestUnknownErrorCode || diagnostic.Code == transport.NameUnknownErrorCode {
+			return true
+		}
+	}
+	return false
+}
+
 // ReadLocalDockerImagesByProjectID retrieves metadata for all local Docker images
 // that have the 'io.metaplay.project_id' label matching the provided projectID.
 func ReadLocalDockerImagesByProjectID(projectID string) ([]MetaplayImageInfo, error) {
@@ -0,0 +1,69 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// Tagged image in an ECR repository, see ListECRImages().
+type ECRImage struct {
+	Tags      []string  // Tags of the image.
+	Digest    string    // Digest of the image manifest.
+	PushedAt  time.Time // When the image was pushed into the repository.
+	SizeBytes int64     // Compressed size of the image in the repository.
+}
+
+// Tags of artifacts stored next to the images that are not images themselves, eg, the
+// attestations pushed with PushDockerImageAttestation() ('sha256-<hex>.att') and cosign
+// signatures and SBOMs ('sha256-<hex>.sig', 'sha256-<hex>.sbom').
+var ecrArtifactTagRegex = regexp.MustCompile(`^sha256-[0-9a-f]{64}\.[a-z]+$`)
+
+// List the tagged images in the ECR repository, newest first. Untagged images and artifacts
+// that are not images (see ecrArtifactTagRegex) are skipped.
+func ListECRImages(ctx context.Context, client ecr.DescribeImagesAPIClient, repository ECRRepository) ([]ECRImage, error) {
+	paginator := ecr.NewDescribeImagesPaginator(client, &ecr.DescribeImagesInput{
+		RegistryId:     aws.String(repository.RegistryID),
+		RepositoryName: aws.String(repository.Name),
+		Filter:         &types.DescribeImagesFilter{TagStatus: types.TagStatusTagged},
+	})
+
+	var images []ECRImage
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list images in ECR repository %s: %w", repository.Name, err)
+		}
+		for _, detail := range page.ImageDetails {
+			tags := []string{}
+			for _, tag := range detail.ImageTags {
+				if !ecrArtifactTagRegex.MatchString(tag) {
+					tags = append(tags, tag)
+				}
+			}
+			if len(tags) == 0 {
+				continue
+			}
+			images = append(images, ECRImage{
+				Tags:      tags,
+				Digest:    aws.ToString(detail.ImageDigest),
+				PushedAt:  aws.ToTime(detail.ImagePushedAt),
+				SizeBytes: aws.ToInt64(detail.ImageSizeInBytes),
+			})
+		}
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].PushedAt.After(images[j].PushedAt)
+	})
+	return images, nil
+}
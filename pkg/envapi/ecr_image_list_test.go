@@ -0,0 +1,49 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// Fake ECR DescribeImages API, returning the image details in pages of one.
+type fakeECRDescribeImages struct {
+	details []types.ImageDetail
+}
+
+func (f *fakeECRDescribeImages) DescribeImages(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+	ndx := 0
+	if params.NextToken != nil {
+		ndx = len(aws.ToString(params.NextToken))
+	}
+	output := &ecr.DescribeImagesOutput{ImageDetails: f.details[ndx : ndx+1]}
+	if ndx+1 < len(f.details) {
+		output.NextToken = aws.String(strings.Repeat("x", ndx+1))
+	}
+	return output, nil
+}
+
+func TestListECRImages(t *testing.T) {
+	client := &fakeECRDescribeImages{details: []types.ImageDetail{
+		{ImageTags: []string{"364cff09"}, ImageDigest: aws.String("sha256:old"), ImagePushedAt: aws.Time(time.Unix(100, 0))},
+		{ImageDigest: aws.String("sha256:untagged"), ImagePushedAt: aws.Time(time.Unix(300, 0))},
+		{ImageTags: []string{"7d1ebc85", "release-1.2.3"}, ImageDigest: aws.String("sha256:new"), ImagePushedAt: aws.Time(time.Unix(200, 0))},
+		{ImageTags: []string{"sha256-" + strings.Repeat("ab", 32) + ".att"}, ImageDigest: aws.String("sha256:attestation"), ImagePushedAt: aws.Time(time.Unix(400, 0))},
+	}}
+
+	images, err := ListECRImages(context.Background(), client, ECRRepository{RegistryID: "123456789012", Name: "mygame"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(images) != 2 || images[0].Digest != "sha256:new" || images[1].Digest != "sha256:old" {
+		t.Errorf("expected the tagged images newest first without the attestation, got: %+v", images)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Manifest media types to accept from ECR, so that the manifests are returned exactly as
+// stored instead of being converted (which would change their digests).
+var ecrAcceptedManifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// Returned by TagECRImage() when the source image doesn't exist.
+var ErrECRImageNotFound = errors.New("image not found")
+
+// Subset of the ECR API used for tagging images (implemented by *ecr.Client).
+type ECRImageAPI interface {
+	BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error)
+	PutImage(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error)
+}
+
+// ECR repository, split from a repository URI, eg,
+// '<account>.dkr.ecr.<region>.amazonaws.com/<name>'.
+type ECRRepository struct {
+	RegistryID string // AWS account ID that owns the registry.
+	Name       string // Name of the repository within the registry.
+}
+
+// Result of TagECRImage().
+type ECRImageTagResult struct {
+	Digest         string // Digest of the image, now also tagged with the destination tag.
+	PreviousDigest string // Digest of the image that the destination tag was moved from, if any.
+	AlreadyTagged  bool   // Whether the destination tag already referred to the image.
+}
+
+// Parse an ECR repository URI, eg, '123456789012.dkr.ecr.eu-west-1.amazonaws.com/mygame'.
+func ParseECRRepository(repositoryURI string) (ECRRepository, error) {
+	host, name, found := strings.Cut(repositoryURI, "/")
+	registryID, _, isECR := strings.Cut(host, ".dkr.ecr.")
+	if !found || name == "" || !isECR || registryID == "" {
+		return ECRRepository{}, fmt.Errorf("invalid ECR repository '%s', expecting '<account>.dkr.ecr.<region>.amazonaws.com/<name>'", repositoryURI)
+	}
+	return ECRRepository{RegistryID: registryID, Name: name}, nil
+}
+
+// Create an ECR client for the environment's registry, using the environment's AWS credentials.
+func (target *TargetEnvironment) NewECRClient(ctx context.Context, envDetails *DeploymentSecret) (*ecr.Client, error) {
+	awsConfig, err := target.newAWSConfig(ctx, envDetails)
+	if err != nil {
+		return nil, err
+	}
+	return ecr.NewFromConfig(awsConfig), nil
+}
+
+// Add the destination tag to the image with the source tag, without transferring any layer
+// data: the image manifest is fetched and put back into the repository with the new tag. If
+// the destination tag refers to another image, it's only moved if overwrite is set.
+func TagECRImage(ctx context.Context, client ECRImageAPI, repository ECRRepository, sourceTag, destTag string, overwrite bool) (*ECRImageTagResult, error) {
+	source, err := getECRImage(ctx, client, repository, sourceTag)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, fmt.Errorf("%w: no image with tag '%s' in repository %s", ErrECRImageNotFound, sourceTag, repository.Name)
+	}
+	sourceDigest := aws.ToString(source.ImageId.ImageDigest)
+
+	// Check the destination tag.
+	result := &ECRImageTagResult{Digest: sourceDigest}
+	dest, err := getECRImage(ctx, client, repository, destTag)
+	if err != nil {
+		return nil, err
+	}
+	if dest != nil {
+		destDigest := aws.ToString(dest.ImageId.ImageDigest)
+		if destDigest == sourceDigest {
+			result.AlreadyTagged = true
+			return result, nil
+		}
+		if !overwrite {
+			return nil, fmt.Errorf("tag '%s' already exists in repository %s for another image (%s)", destTag, repository.Name, destDigest)
+		}
+		result.PreviousDigest = destDigest
+	}
+
+	// Put the manifest back with the new tag.
+	log.Debug().Msgf("Tag image %s@%s as %s", repository.Name, sourceDigest, destTag)
+	output, err := client.PutImage(ctx, &ecr.PutImageInput{
+		RegistryId:             aws.String(repository.RegistryID),
+		RepositoryName:         aws.String(repository.Name),
+		ImageManifest:          source.ImageManifest,
+		ImageManifestMediaType: source.ImageManifestMediaType,
+		ImageDigest:            aws.String(sourceDigest),
+		ImageTag:               aws.String(destTag),
+	})
+	if err != nil {
+		var immutableErr *types.ImageTagAlreadyExistsException
+		if errors.As(err, &immutableErr) {
+			return nil, fmt.Errorf("tag '%s' cannot be moved: repository %s has immutable tags", destTag, repository.Name)
+		}
+		return nil, fmt.Errorf("failed to tag image in ECR: %w", err)
+	}
+	if output.Image != nil && output.Image.ImageId != nil && output.Image.ImageId.ImageDigest != nil {
+		result.Digest = *output.Image.ImageId.ImageDigest
+	}
+	return result, nil
+}
+
+// Get the image with the tag, including its manifest. Returns nil if the tag doesn't exist.
+func getECRImage(ctx context.Context, client ECRImageAPI, repository ECRRepository, tag string) (*types.Image, error) {
+	output, err := client.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+		RegistryId:         aws.String(repository.RegistryID),
+		RepositoryName:     aws.String(repository.Name),
+		ImageIds:           []types.ImageIdentifier{{ImageTag: aws.String(tag)}},
+		AcceptedMediaTypes: ecrAcceptedManifestMediaTypes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image '%s' from ECR: %w", tag, err)
+	}
+	for _, failure := range output.Failures {
+		if failure.FailureCode == types.ImageFailureCodeImageNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get image '%s' from ECR: %s", tag, aws.ToString(failure.FailureReason))
+	}
+	if len(output.Images) == 0 {
+		return nil, nil
+	}
+	image := output.Images[0]
+	if image.ImageManifest == nil || image.ImageId == nil || image.ImageId.ImageDigest == nil {
+		return nil, fmt.Errorf("ECR returned an incomplete image for tag '%s'", tag)
+	}
+	return &image, nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// Fake ECR repository with images by tag, recording the PutImage calls.
+type fakeECR struct {
+	images map[string]types.Image // Images by tag.
+	puts   []*ecr.PutImageInput
+}
+
+func newFakeImage(digest string) types.Image {
+	return types.Image{
+		ImageId:                &types.ImageIdentifier{ImageDigest: aws.String(digest)},
+		ImageManifest:          aws.String(`{"manifest":"` + digest + `"}`),
+		ImageManifestMediaType: aws.String("application/vnd.oci.image.manifest.v1+json"),
+	}
+}
+
+func (f *fakeECR) BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	tag := aws.ToString(params.ImageIds[0].ImageTag)
+	if image, ok := f.images[tag]; ok {
+		return &ecr.BatchGetImageOutput{Images: []types.Image{image}}, nil
+	}
+	return &ecr.BatchGetImageOutput{Failures: []types.ImageFailure{{FailureCode: types.ImageFailureCodeImageNotFound}}}, nil
+}
+
+func (f *fakeECR) PutImage(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+	f.puts = append(f.puts, params)
+	image := types.Image{ImageId: &types.ImageIdentifier{ImageDigest: params.ImageDigest, ImageTag: params.ImageTag}, ImageManifest: params.ImageManifest}
+	f.images[aws.ToString(params.ImageTag)] = image
+	return &ecr.PutImageOutput{Image: &image}, nil
+}
+
+func TestParseECRRepository(t *testing.T) {
+	repository, err := ParseECRRepository("123456789012.dkr.ecr.eu-west-1.amazonaws.com/metaplay/tough-falcons")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repository.RegistryID != "123456789012" || repository.Name != "metaplay/tough-falcons" {
+		t.Errorf("unexpected repository: %+v", repository)
+	}
+
+	for _, invalid := range []string{"", "mygame", "registry.example.com/mygame", "123456789012.dkr.ecr.eu-west-1.amazonaws.com/"} {
+		if _, err := ParseECRRepository(invalid); err == nil {
+			t.Errorf("expected an error for '%s'", invalid)
+		}
+	}
+}
+
+func TestTagECRImage(t *testing.T) {
+	ctx := context.Background()
+	repository := ECRRepository{RegistryID: "123456789012", Name: "mygame"}
+	ecrClient := &fakeECR{images: map[string]types.Image{
+		"sha-abc123": newFakeImage("sha256:aaa"),
+		"stable":     newFakeImage("sha256:bbb"),
+	}}
+
+	// New tag: the source manifest is put with the new tag.
+	result, err := TagECRImage(ctx, ecrClient, repository, "sha-abc123", "release-1.2.3", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Digest != "sha256:aaa" || result.AlreadyTagged || result.PreviousDigest != "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(ecrClient.puts) != 1 || aws.ToString(ecrClient.puts[0].ImageManifest) != `{"manifest":"sha256:aaa"}` || aws.ToString(ecrClient.puts[0].ImageManifestMediaType) != "application/vnd.oci.image.manifest.v1+json" {
+		t.Errorf("unexpected PutImage calls: %+v", ecrClient.puts)
+	}
+
+	// Already tagged: nothing is put.
+	result, err = TagECRImage(ctx, ecrClient, repository, "sha-abc123", "release-1.2.3", false)
+	if err != nil || !result.AlreadyTagged || len(ecrClient.puts) != 1 {
+		t.Errorf("expected already tagged, got: %+v, %v", result, err)
+	}
+
+	// Existing tag for another image requires overwrite.
+	if _, err := TagECRImage(ctx, ecrClient, repository, "sha-abc123", "stable", false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected existing tag error, got: %v", err)
+	}
+	result, err = TagECRImage(ctx, ecrClient, repository, "sha-abc123", "stable", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.PreviousDigest != "sha256:bbb" || result.Digest != "sha256:aaa" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// Missing source image.
+	if _, err := TagECRImage(ctx, ecrClient, repository, "missing", "other", false); !errors.Is(err, ErrECRImageNotFound) {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
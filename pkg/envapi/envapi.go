@@ -25,6 +25,9 @@ type Deployment struct {
 	GameserverAdminIamRole         string   `json:"gameserver_admin_iam_role"`
 	GameserverIamRole              string   `json:"gameserver_iam_role"`
 	GameserverServiceAccount       string   `json:"gameserver_service_account"`
+	HelmChartName                  string   `json:"helm_chart_name"`
+	HelmChartVersion               string   `json:"helm_chart_version"`
+	HelmReleaseName                string   `json:"helm_release_name"`
 	KubernetesNamespace            string   `json:"kubernetes_namespace"`
 	MetaplayInfraVersion           string   `json:"metaplay_infra_version"`
 	MetaplayRequiredSdkVersion     string   `json:"metaplay_required_sdk_version"`
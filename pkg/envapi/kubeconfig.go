@@ -0,0 +1,220 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+)
+
+// Separator between the documents of a multi-document YAML payload.
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// Parse a kubeconfig payload (YAML or JSON, possibly with multiple YAML documents of which
+// exactly one is a kubeconfig), validate that it can be used to access the environment, and
+// normalize it: the result has a current-context with the namespace set, and exactly one
+// cluster, user and context. Errors name the missing or unsupported field.
+func NormalizeKubeConfig(payload string, namespace string) (*clientcmdapi.Config, error) {
+	config, err := parseKubeConfigDocuments(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the context to use.
+	contextName, err := resolveKubeConfigContext(config, namespace)
+	if err != nil {
+		return nil, err
+	}
+	context := config.Contexts[contextName]
+
+	// Resolve the cluster and user referenced by the context.
+	if context.Cluster == "" {
+		return nil, fmt.Errorf("invalid kubeconfig: context '%s' has no cluster", contextName)
+	}
+	cluster, found := config.Clusters[context.Cluster]
+	if !found {
+		return nil, fmt.Errorf("invalid kubeconfig: cluster '%s' of context '%s' not found in clusters", context.Cluster, contextName)
+	}
+	if cluster.Server == "" {
+		return nil, fmt.Errorf("invalid kubeconfig: cluster '%s' has no server", context.Cluster)
+	}
+	if context.AuthInfo == "" {
+		return nil, fmt.Errorf("invalid kubeconfig: context '%s' has no user", contextName)
+	}
+	authInfo, found := config.AuthInfos[context.AuthInfo]
+	if !found {
+		return nil, fmt.Errorf("invalid kubeconfig: user '%s' of context '%s' not found in users", context.AuthInfo, contextName)
+	}
+	if err := validateKubeConfigUser(context.AuthInfo, authInfo); err != nil {
+		return nil, err
+	}
+
+	// Access the environment's namespace, unless the kubeconfig specifies one.
+	normalizedContext := context.DeepCopy()
+	if normalizedContext.Namespace == "" {
+		normalizedContext.Namespace = namespace
+	} else if normalizedContext.Namespace != namespace {
+		log.Debug().Msgf("Kubeconfig context '%s' uses namespace '%s' instead of '%s'", contextName, normalizedContext.Namespace, namespace)
+	}
+
+	// Only keep the used context, cluster and user.
+	normalized := clientcmdapi.NewConfig()
+	normalized.Clusters[context.Cluster] = cluster.DeepCopy()
+	normalized.AuthInfos[context.AuthInfo] = authInfo.DeepCopy()
+	normalized.Contexts[contextName] = normalizedContext
+	normalized.CurrentContext = contextName
+	return normalized, nil
+}
+
+// Serialize the kubeconfig into YAML.
+func SerializeKubeConfig(config *clientcmdapi.Config) (string, error) {
+	payload, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+	return string(payload), nil
+}
+
+// Merge the current context of the environment's kubeconfig (with its cluster and user) into
+// the config, eg, the user's ~/.kube/config, and make it the current context. The merged
+// cluster, user, and context are all named 'name', and replace any existing entries with the
+// same name, so that merging again updates them in place.
+func MergeKubeConfigContext(config *clientcmdapi.Config, envKubeConfig *clientcmdapi.Config, name string) error {
+	context, found := envKubeConfig.Contexts[envKubeConfig.CurrentContext]
+	if !found {
+		return fmt.Errorf("invalid kubeconfig: current-context '%s' not found in contexts", envKubeConfig.CurrentContext)
+	}
+	cluster, found := envKubeConfig.Clusters[context.Cluster]
+	if !found {
+		return fmt.Errorf("invalid kubeconfig: cluster '%s' of context '%s' not found in clusters", context.Cluster, envKubeConfig.CurrentContext)
+	}
+	authInfo, found := envKubeConfig.AuthInfos[context.AuthInfo]
+	if !found {
+		return fmt.Errorf("invalid kubeconfig: user '%s' of context '%s' not found in users", context.AuthInfo, envKubeConfig.CurrentContext)
+	}
+
+	mergedContext := context.DeepCopy()
+	mergedContext.Cluster = name
+	mergedContext.AuthInfo = name
+
+	config.Clusters[name] = cluster.DeepCopy()
+	config.AuthInfos[name] = authInfo.DeepCopy()
+	config.Contexts[name] = mergedContext
+	config.CurrentContext = name
+	return nil
+}
+
+// Parse the kubeconfig payload, which may contain multiple YAML documents, eg, with a leading
+// '---' or other Kubernetes resources. Exactly one of the documents must be a kubeconfig.
+func parseKubeConfigDocuments(payload string) (*clientcmdapi.Config, error) {
+	var configs []*clientcmdapi.Config
+	for ndx, document := range yamlDocumentSeparator.Split(payload, -1) {
+		if isEmptyYAMLDocument(document) {
+			continue
+		}
+		// Skip other Kubernetes resources.
+		var header struct {
+			Kind string `json:"kind"`
+		}
+		if err := yaml.Unmarshal([]byte(document), &header); err == nil && header.Kind != "" && header.Kind != "Config" {
+			log.Debug().Msgf("Ignoring document %d of kind %s in the kubeconfig payload", ndx+1, header.Kind)
+			continue
+		}
+		config, err := clientcmd.Load([]byte(document))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig (document %d): %w", ndx+1, err)
+		}
+		// Skip documents that are not kubeconfigs, ie, have none of the kubeconfig fields.
+		if len(config.Clusters) == 0 && len(config.AuthInfos) == 0 && len(config.Contexts) == 0 {
+			continue
+		}
+		configs = append(configs, config)
+	}
+
+	switch len(configs) {
+	case 0:
+		return nil, errors.New("invalid kubeconfig: no clusters, users or contexts found")
+	case 1:
+		return configs[0], nil
+	default:
+		return nil, fmt.Errorf("invalid kubeconfig: payload contains %d kubeconfig documents, expected one", len(configs))
+	}
+}
+
+// Check whether the YAML document has no content, ie, only whitespace and comments.
+func isEmptyYAMLDocument(document string) bool {
+	for _, line := range strings.Split(document, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve the name of the context to use: the current-context, or the only context if no
+// current-context is set. If there are no contexts but only one cluster and user, a context
+// is created for them.
+func resolveKubeConfigContext(config *clientcmdapi.Config, namespace string) (string, error) {
+	if config.CurrentContext != "" {
+		if _, found := config.Contexts[config.CurrentContext]; !found {
+			return "", fmt.Errorf("invalid kubeconfig: current-context '%s' not found in contexts", config.CurrentContext)
+		}
+		return config.CurrentContext, nil
+	}
+
+	switch len(config.Contexts) {
+	case 0:
+		if len(config.Clusters) != 1 || len(config.AuthInfos) != 1 {
+			return "", fmt.Errorf("invalid kubeconfig: no current-context or contexts, and %d clusters and %d users (expected exactly one of each)", len(config.Clusters), len(config.AuthInfos))
+		}
+		context := clientcmdapi.NewContext()
+		for name := range config.Clusters {
+			context.Cluster = name
+		}
+		for name := range config.AuthInfos {
+			context.AuthInfo = name
+		}
+		config.Contexts[namespace] = context
+		return namespace, nil
+	case 1:
+		for name := range config.Contexts {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("invalid kubeconfig: no current-context and %d contexts to choose from", len(config.Contexts))
+}
+
+// Check that the user has credentials in a form supported by the CLI.
+func validateKubeConfigUser(name string, authInfo *clientcmdapi.AuthInfo) error {
+	// Auth provider plugins (eg, 'gcp', 'azure', 'oidc') are not compiled into the CLI.
+	if authInfo.AuthProvider != nil {
+		return fmt.Errorf("unsupported kubeconfig: user '%s' uses auth-provider '%s', only token, client certificate and exec credentials are supported", name, authInfo.AuthProvider.Name)
+	}
+
+	if authInfo.Exec != nil {
+		if authInfo.Exec.Command == "" {
+			return fmt.Errorf("invalid kubeconfig: user '%s' has no exec.command", name)
+		}
+		if authInfo.Exec.APIVersion == "" {
+			return fmt.Errorf("invalid kubeconfig: user '%s' has no exec.apiVersion", name)
+		}
+		return nil
+	}
+
+	hasToken := authInfo.Token != "" || authInfo.TokenFile != ""
+	hasClientCert := (len(authInfo.ClientCertificateData) > 0 || authInfo.ClientCertificate != "") && (len(authInfo.ClientKeyData) > 0 || authInfo.ClientKey != "")
+	hasBasicAuth := authInfo.Username != "" && authInfo.Password != ""
+	if !hasToken && !hasClientCert && !hasBasicAuth {
+		return fmt.Errorf("invalid kubeconfig: user '%s' has no credentials (token, client certificate and key, or exec)", name)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/metaplay/cli/pkg/envapi"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const eksExecKubeConfig = `
+apiVersion: v1
+kind: Config
+current-context: tough-falcons
+clusters:
+- name: eks-cluster
+  cluster:
+    server: https://ABCDEF.gr7.eu-west-1.eks.amazonaws.com
+    certificate-authority-data: dGVzdC1jYQ==
+contexts:
+- name: tough-falcons
+  context:
+    cluster: eks-cluster
+    user: eks-user
+users:
+- name: eks-user
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: ["eks", "get-token", "--cluster-name", "metaplay"]
+`
+
+const tokenKubeConfig = `
+apiVersion: v1
+kind: Config
+current-context: ctx
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: ctx
+  context:
+    cluster: cluster
+    user: user
+    namespace: custom-namespace
+users:
+- name: user
+  user:
+    token: secret-token
+`
+
+const clientCertKubeConfig = `
+apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+    insecure-skip-tls-verify: true
+contexts:
+- name: only-context
+  context:
+    cluster: cluster
+    user: user
+users:
+- name: user
+  user:
+    client-certificate-data: Y2VydA==
+    client-key-data: a2V5
+`
+
+const noContextsKubeConfig = `
+apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: user
+  user:
+    token: secret-token
+`
+
+const multiDocumentKubeConfig = `---
+# Credentials for the environment
+apiVersion: v1
+kind: Secret
+metadata:
+  name: unrelated
+data: {}
+---
+apiVersion: v1
+kind: Config
+current-context: ctx
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: ctx
+  context:
+    cluster: cluster
+    user: user
+users:
+- name: user
+  user:
+    token: secret-token
+---
+`
+
+const jsonKubeConfig = `{
+  "apiVersion": "v1",
+  "kind": "Config",
+  "current-context": "ctx",
+  "clusters": [{"name": "cluster", "cluster": {"server": "https://127.0.0.1:6443"}}],
+  "contexts": [{"name": "ctx", "context": {"cluster": "cluster", "user": "user"}}],
+  "users": [{"name": "user", "user": {"token": "secret-token"}}]
+}`
+
+const extraEntriesKubeConfig = `
+apiVersion: v1
+kind: Config
+current-context: ctx
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+- name: other-cluster
+  cluster:
+    server: https://10.0.0.1:6443
+contexts:
+- name: ctx
+  context:
+    cluster: cluster
+    user: user
+- name: other-ctx
+  context:
+    cluster: other-cluster
+    user: other-user
+users:
+- name: user
+  user:
+    token: secret-token
+- name: other-user
+  user:
+    token: other-token
+`
+
+func TestNormalizeKubeConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		context   string
+		server    string
+		namespace string
+	}{
+		{"eks exec", eksExecKubeConfig, "tough-falcons", "https://ABCDEF.gr7.eu-west-1.eks.amazonaws.com", "tough-falcons"},
+		{"token with namespace", tokenKubeConfig, "ctx", "https://127.0.0.1:6443", "custom-namespace"},
+		{"client certificate without current-context", clientCertKubeConfig, "only-context", "https://127.0.0.1:6443", "tough-falcons"},
+		{"no contexts", noContextsKubeConfig, "tough-falcons", "https://127.0.0.1:6443", "tough-falcons"},
+		{"multiple documents", multiDocumentKubeConfig, "ctx", "https://127.0.0.1:6443", "tough-falcons"},
+		{"json", jsonKubeConfig, "ctx", "https://127.0.0.1:6443", "tough-falcons"},
+		{"extra entries", extraEntriesKubeConfig, "ctx", "https://127.0.0.1:6443", "tough-falcons"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := envapi.NormalizeKubeConfig(test.payload, "tough-falcons")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.CurrentContext != test.context {
+				t.Errorf("current-context = %q, expected %q", config.CurrentContext, test.context)
+			}
+			if len(config.Clusters) != 1 || len(config.AuthInfos) != 1 || len(config.Contexts) != 1 {
+				t.Fatalf("expected exactly one cluster, user and context, got %d, %d, %d", len(config.Clusters), len(config.AuthInfos), len(config.Contexts))
+			}
+			context := config.Contexts[config.CurrentContext]
+			if context.Namespace != test.namespace {
+				t.Errorf("namespace = %q, expected %q", context.Namespace, test.namespace)
+			}
+			if server := config.Clusters[context.Cluster].Server; server != test.server {
+				t.Errorf("server = %q, expected %q", server, test.server)
+			}
+
+			// The normalized kubeconfig must survive a round-trip through serialization.
+			serialized, err := envapi.SerializeKubeConfig(config)
+			if err != nil {
+				t.Fatalf("failed to serialize: %v", err)
+			}
+			reloaded, err := clientcmd.Load([]byte(serialized))
+			if err != nil {
+				t.Fatalf("failed to reload serialized kubeconfig: %v", err)
+			}
+			if reloaded.CurrentContext != test.context || reloaded.Contexts[test.context].Namespace != test.namespace {
+				t.Errorf("unexpected reloaded kubeconfig: %s", serialized)
+			}
+		})
+	}
+}
+
+func TestNormalizeKubeConfigErrors(t *testing.T) {
+	replace := func(payload, old, new string) string {
+		return strings.Replace(payload, old, new, 1)
+	}
+
+	tests := []struct {
+		name     string
+		payload  string
+		contains string
+	}{
+		{"empty", "", "no clusters, users or contexts"},
+		{"invalid yaml", "apiVersion: v1\nclusters: [", "failed to parse kubeconfig"},
+		{"two kubeconfigs", tokenKubeConfig + "---\n" + tokenKubeConfig, "2 kubeconfig documents"},
+		{"missing server", replace(tokenKubeConfig, "server: https://127.0.0.1:6443", "insecure-skip-tls-verify: true"), "cluster 'cluster' has no server"},
+		{"missing current-context", replace(tokenKubeConfig, "current-context: ctx", "current-context: missing"), "current-context 'missing' not found"},
+		{"missing user", replace(tokenKubeConfig, "user: user", "user: missing"), "user 'missing' of context 'ctx' not found"},
+		{"missing cluster", replace(tokenKubeConfig, "cluster: cluster", "cluster: missing"), "cluster 'missing' of context 'ctx' not found"},
+		{"no credentials", replace(tokenKubeConfig, "token: secret-token", "username: admin"), "user 'user' has no credentials"},
+		{"auth provider", replace(tokenKubeConfig, "token: secret-token", "auth-provider:\n      name: gcp"), "auth-provider 'gcp'"},
+		{"exec without apiVersion", replace(eksExecKubeConfig, "apiVersion: client.authentication.k8s.io/v1beta1", ""), "no exec.apiVersion"},
+		{"ambiguous context", replace(extraEntriesKubeConfig, "current-context: ctx", ""), "no current-context and 2 contexts"},
+		{"ambiguous cluster and user", replace(replace(extraEntriesKubeConfig, "current-context: ctx", ""), "contexts:", "unused-contexts:"), "2 clusters and 2 users"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := envapi.NormalizeKubeConfig(test.payload, "tough-falcons")
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.contains) {
+				t.Errorf("error %q does not contain %q", err, test.contains)
+			}
+		})
+	}
+}
+
+func TestMergeKubeConfigContext(t *testing.T) {
+	config, err := clientcmd.Load([]byte(tokenKubeConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envKubeConfig, err := clientcmd.Load([]byte(eksExecKubeConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Merging twice updates the entries in place.
+	for range 2 {
+		if err := envapi.MergeKubeConfigContext(config, envKubeConfig, "metaplay-tough-falcons"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if config.CurrentContext != "metaplay-tough-falcons" {
+		t.Errorf("current-context = %q", config.CurrentContext)
+	}
+	if len(config.Contexts) != 2 || len(config.Clusters) != 2 || len(config.AuthInfos) != 2 {
+		t.Errorf("expected the existing and merged entries, got %d contexts, %d clusters, %d users", len(config.Contexts), len(config.Clusters), len(config.AuthInfos))
+	}
+	context := config.Contexts["metaplay-tough-falcons"]
+	if context.Cluster != "metaplay-tough-falcons" || context.AuthInfo != "metaplay-tough-falcons" {
+		t.Errorf("unexpected merged context: %+v", context)
+	}
+	if config.Clusters["metaplay-tough-falcons"].Server != "https://ABCDEF.gr7.eu-west-1.eks.amazonaws.com" {
+		t.Errorf("unexpected merged cluster: %+v", config.Clusters["metaplay-tough-falcons"])
+	}
+	if exec := config.AuthInfos["metaplay-tough-falcons"].Exec; exec == nil || exec.Command != "aws" {
+		t.Errorf("unexpected merged user: %+v", config.AuthInfos["metaplay-tough-falcons"])
+	}
+	if config.Contexts["ctx"].Namespace != "custom-namespace" {
+		t.Errorf("existing context was modified: %+v", config.Contexts["ctx"])
+	}
+
+	// Invalid kubeconfig.
+	envKubeConfig.CurrentContext = "missing"
+	if err := envapi.MergeKubeConfigContext(config, envKubeConfig, "metaplay-tough-falcons"); err == nil {
+		t.Errorf("expected an error for a missing current-context")
+	}
+}
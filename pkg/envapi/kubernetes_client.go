@@ -16,6 +16,6 @@ type KubeClient struct {
 	KubeConfig    string
 	RestConfig    *rest.Config
 	RestClient    *rest.RESTClient
-	Clientset     *kubernetes.Clientset
-	DynamicClient *dynamic.DynamicClient
+	Clientset     kubernetes.Interface
+	DynamicClient dynamic.Interface
 }
@@ -14,6 +14,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// GVR for new operator gameserver CR: gameservers.gameservers.metaplay.io
+var newGameServerGVR = schema.GroupVersionResource{
+	Group:    "gameservers.metaplay.io",
+	Version:  "v0",
+	Resource: "gameservers",
+}
+
 // NewGameServerCR represents the structured CRD for the new operator GameServer.
 type NewGameServerCR struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -82,12 +89,7 @@ type NewGameServerCR struct {
 
 // Get a gameserver CR used by the new operator from the cluster.
 func getGameServerNewCR(ctx context.Context, kubeCli *KubeClient) (*NewGameServerCR, error) {
-	// GVR for new operator gameserver CR: gameservers.gameservers.metaplay.io
-	var gvr = schema.GroupVersionResource{
-		Group:    "gameservers.metaplay.io",
-		Version:  "v0",
-		Resource: "gameservers",
-	}
+	gvr := newGameServerGVR
 
 	// Fetch all GameServers in the namespace
 	gameServers, err := kubeCli.DynamicClient.Resource(gvr).Namespace(kubeCli.Namespace).List(ctx, metav1.ListOptions{})
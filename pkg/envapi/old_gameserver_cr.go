@@ -15,6 +15,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// GVR for the old operator gameserver CR: gameservers.metaplay.io
+var oldGameServerGVR = schema.GroupVersionResource{
+	Group:    "metaplay.io",
+	Version:  "v1",
+	Resource: "gameservers",
+}
+
 // OldGameServerCR represents the structured CRD for the old operator GameServer.
 type OldGameServerCR struct {
 	APIVersion string            `json:"apiVersion"`
@@ -41,12 +48,7 @@ type OldGameServerCR struct {
 
 // Get a gameserver CR used by the old operator from the cluster.
 func getGameServerOldCR(ctx context.Context, kubeCli *KubeClient) (*OldGameServerCR, error) {
-	// GVR for the old operator gameserver CR: gameservers.metaplay.io
-	gvr := schema.GroupVersionResource{
-		Group:    "metaplay.io",
-		Version:  "v1",
-		Resource: "gameservers",
-	}
+	gvr := oldGameServerGVR
 
 	// Fetch all GameServers in the namespace
 	gameServers, err := kubeCli.DynamicClient.Resource(gvr).Namespace(kubeCli.Namespace).List(ctx, metav1.ListOptions{})
@@ -0,0 +1,327 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+)
+
+// Name of the Kubernetes Lease object used as the advisory lock for mutating
+// operations (deploy, remove, etc.) against an environment.
+const operationLockName = "metaplay-cli-operation-lock"
+
+// Annotations stored on the Lease object to identify the lock holder.
+const operationLockUserAnnotation = "io.metaplay.lock-user"
+const operationLockHostAnnotation = "io.metaplay.lock-host"
+const operationLockCliVersionAnnotation = "io.metaplay.lock-cli-version"
+const operationLockOperationAnnotation = "io.metaplay.lock-operation"
+
+// How long a lock is valid without a heartbeat. The heartbeat is sent at a
+// fraction of this so a few missed heartbeats don't cause the lock to expire.
+const operationLockTTL = 60 * time.Second
+const operationLockHeartbeatInterval = operationLockTTL / 3
+
+// How often to poll the lock while waiting for another holder to release it.
+const operationLockPollInterval = 2 * time.Second
+
+// Identity of the holder of an operation lock. The fields are only for displaying
+// the holder: each acquisition gets a unique identity, see newOperationLockIdentity().
+type OperationLockHolder struct {
+	User       string // Email (or other identity) of the user holding the lock.
+	Host       string // Hostname of the machine holding the lock.
+	CliVersion string // Version of the CLI holding the lock.
+	Operation  string // Operation being performed, eg, 'deploy server'.
+}
+
+// Information about the current state of an environment's operation lock.
+type OperationLockInfo struct {
+	Holder        OperationLockHolder
+	AcquireTime   time.Time     // When the lock was acquired.
+	RenewTime     time.Time     // Time of the latest heartbeat.
+	LeaseDuration time.Duration // How long the lock is valid after the latest heartbeat.
+}
+
+// Has the holder of the lock stopped sending heartbeats?
+func (info *OperationLockInfo) IsExpired() bool {
+	return time.Since(info.RenewTime) > info.LeaseDuration
+}
+
+// An acquired operation lock. Keeps the lock alive with periodic heartbeats
+// until Release() is called.
+type OperationLock struct {
+	leases         coordinationv1client.LeaseInterface
+	namespace      string
+	holderIdentity string
+	stopHeartbeat  chan struct{}
+	heartbeatDone  chan struct{}
+	releaseOnce    sync.Once
+}
+
+// Create a unique identity for an acquisition of the lock by holder, stored in the Lease's
+// spec.holderIdentity. The pid and a random suffix make it unique even between processes
+// of the same user on the same host, eg, parallel CI jobs on one runner.
+func newOperationLockIdentity(holder OperationLockHolder) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		panic(fmt.Sprintf("failed to generate operation lock identity: %v", err))
+	}
+	return fmt.Sprintf("%s@%s/%d-%s", holder.User, holder.Host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// Parse the lock information from a Lease object.
+func operationLockInfoFromLease(lease *coordinationv1.Lease) *OperationLockInfo {
+	info := &OperationLockInfo{
+		Holder: OperationLockHolder{
+			User:       lease.Annotations[operationLockUserAnnotation],
+			Host:       lease.Annotations[operationLockHostAnnotation],
+			CliVersion: lease.Annotations[operationLockCliVersionAnnotation],
+			Operation:  lease.Annotations[operationLockOperationAnnotation],
+		},
+		LeaseDuration: operationLockTTL,
+	}
+	if lease.Spec.AcquireTime != nil {
+		info.AcquireTime = lease.Spec.AcquireTime.Time
+	}
+	if lease.Spec.RenewTime != nil {
+		info.RenewTime = lease.Spec.RenewTime.Time
+	}
+	if lease.Spec.LeaseDurationSeconds != nil {
+		info.LeaseDuration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return info
+}
+
+// Fill in the Lease object to represent the lock being held by holder with the identity.
+func fillOperationLockLease(lease *coordinationv1.Lease, holder OperationLockHolder, identity string) {
+	now := metav1.NewMicroTime(time.Now())
+	leaseDurationSeconds := int32(operationLockTTL.Seconds())
+
+	lease.Name = operationLockName
+	lease.Annotations = map[string]string{
+		operationLockUserAnnotation:       holder.User,
+		operationLockHostAnnotation:       holder.Host,
+		operationLockCliVersionAnnotation: holder.CliVersion,
+		operationLockOperationAnnotation:  holder.Operation,
+	}
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &leaseDurationSeconds
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+}
+
+// Get the current state of the environment's operation lock. Returns nil if
+// nobody is holding the lock.
+func (target *TargetEnvironment) GetOperationLock(ctx context.Context) (*OperationLockInfo, error) {
+	kubeCli, err := target.GetPrimaryKubeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	lease, err := kubeCli.Clientset.CoordinationV1().Leases(kubeCli.Namespace).Get(ctx, operationLockName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get operation lock: %w", err)
+	}
+
+	return operationLockInfoFromLease(lease), nil
+}
+
+// Try to acquire the environment's operation lock once. Returns the existing lock
+// info if somebody else is holding a non-expired lock.
+func tryAcquireOperationLock(ctx context.Context, leases coordinationv1client.LeaseInterface, holder OperationLockHolder, identity string) (bool, *OperationLockInfo, error) {
+	// Check for an existing lock.
+	existing, err := leases.Get(ctx, operationLockName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// No lock exists, create one. Conflicts mean somebody else got there first.
+		lease := &coordinationv1.Lease{}
+		fillOperationLockLease(lease, holder, identity)
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil, nil
+		}
+		return err == nil, nil, err
+	} else if err != nil {
+		return false, nil, err
+	}
+
+	// If the existing lock is still alive, we can't take it.
+	existingInfo := operationLockInfoFromLease(existing)
+	if !existingInfo.IsExpired() {
+		return false, existingInfo, nil
+	}
+
+	// The existing holder has stopped sending heartbeats, take over the lock.
+	// The update fails with a conflict if somebody else modified the lease in the meantime.
+	log.Warn().Msgf("Taking over expired operation lock from %s (operation '%s', last heartbeat %s ago)", existingInfo.Holder.User, existingInfo.Holder.Operation, time.Since(existingInfo.RenewTime).Round(time.Second))
+	fillOperationLockLease(existing, holder, identity)
+	_, err = leases.Update(ctx, existing, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		return false, nil, nil
+	}
+	return err == nil, nil, err
+}
+
+// Acquire the environment's operation lock, waiting up to waitTimeout for any
+// existing holder to release it. The returned lock is kept alive with heartbeats
+// until OperationLock.Release() is called.
+func (target *TargetEnvironment) AcquireOperationLock(ctx context.Context, holder OperationLockHolder, waitTimeout time.Duration) (*OperationLock, error) {
+	kubeCli, err := target.GetPrimaryKubeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	leases := kubeCli.Clientset.CoordinationV1().Leases(kubeCli.Namespace)
+	identity := newOperationLockIdentity(holder)
+	deadline := time.Now().Add(waitTimeout)
+	loggedWaiting := false
+	for {
+		acquired, existingInfo, err := tryAcquireOperationLock(ctx, leases, holder, identity)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			log.Debug().Msgf("Acquired operation lock in namespace %s as %s", kubeCli.Namespace, identity)
+			return newOperationLock(leases, kubeCli.Namespace, identity), nil
+		}
+
+		// Somebody else is holding the lock: wait for it to be released.
+		if existingInfo != nil && !loggedWaiting {
+			log.Info().Msgf("Waiting for %s (%s) to finish operation '%s' in the environment...", existingInfo.Holder.User, existingInfo.Holder.Host, existingInfo.Holder.Operation)
+			loggedWaiting = true
+		}
+		if time.Now().After(deadline) {
+			if existingInfo != nil {
+				return nil, fmt.Errorf("timed out waiting for operation lock held by %s (host %s, CLI %s, operation '%s')", existingInfo.Holder.User, existingInfo.Holder.Host, existingInfo.Holder.CliVersion, existingInfo.Holder.Operation)
+			}
+			return nil, fmt.Errorf("timed out waiting for operation lock")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(operationLockPollInterval):
+		}
+	}
+}
+
+// Create the handle of an acquired lock and start its heartbeat.
+func newOperationLock(leases coordinationv1client.LeaseInterface, namespace, identity string) *OperationLock {
+	lock := &OperationLock{
+		leases:         leases,
+		namespace:      namespace,
+		holderIdentity: identity,
+		stopHeartbeat:  make(chan struct{}),
+		heartbeatDone:  make(chan struct{}),
+	}
+	go lock.runHeartbeat()
+	return lock
+}
+
+// Periodically renew the lock until stopped.
+func (lock *OperationLock) runHeartbeat() {
+	defer close(lock.heartbeatDone)
+
+	ticker := time.NewTicker(operationLockHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lock.stopHeartbeat:
+			return
+		case <-ticker.C:
+			if err := lock.renew(context.Background()); err != nil {
+				log.Warn().Msgf("Failed to renew operation lock: %v", err)
+			}
+		}
+	}
+}
+
+// Update the lock's renew time, if we're still holding it.
+func (lock *OperationLock) renew(ctx context.Context) error {
+	lease, err := lock.leases.Get(ctx, operationLockName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != lock.holderIdentity {
+		return fmt.Errorf("operation lock is now held by somebody else")
+	}
+
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.RenewTime = &now
+	_, err = lock.leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// Stop the heartbeat and release the lock. Safe to call multiple times.
+func (lock *OperationLock) Release() error {
+	var err error
+	lock.releaseOnce.Do(func() {
+		close(lock.stopHeartbeat)
+		<-lock.heartbeatDone
+
+		// Only delete the lease if it's still ours (somebody may have force-unlocked it).
+		ctx := context.Background()
+		lease, getErr := lock.leases.Get(ctx, operationLockName, metav1.GetOptions{})
+		if apierrors.IsNotFound(getErr) {
+			return
+		} else if getErr != nil {
+			err = fmt.Errorf("failed to release operation lock: %w", getErr)
+			return
+		}
+		if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != lock.holderIdentity {
+			log.Debug().Msgf("Operation lock no longer held by us, not releasing it")
+			return
+		}
+
+		err = lock.leases.Delete(ctx, operationLockName, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			err = fmt.Errorf("failed to release operation lock: %w", err)
+		} else {
+			err = nil
+			log.Debug().Msgf("Released operation lock in namespace %s", lock.namespace)
+		}
+	})
+	return err
+}
+
+// Forcibly remove a stale operation lock. Refuses to remove a lock whose holder
+// is still sending heartbeats.
+func (target *TargetEnvironment) ForceReleaseOperationLock(ctx context.Context) error {
+	kubeCli, err := target.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
+
+	leases := kubeCli.Clientset.CoordinationV1().Leases(kubeCli.Namespace)
+	lease, err := leases.Get(ctx, operationLockName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("no operation lock is held in the environment")
+	} else if err != nil {
+		return fmt.Errorf("failed to get operation lock: %w", err)
+	}
+
+	info := operationLockInfoFromLease(lease)
+	if !info.IsExpired() {
+		return fmt.Errorf("operation lock held by %s is still active (last heartbeat %s ago)", info.Holder.User, time.Since(info.RenewTime).Round(time.Second))
+	}
+
+	return leases.Delete(ctx, operationLockName, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+	})
+}
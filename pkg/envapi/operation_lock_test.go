@@ -0,0 +1,117 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOperationLockHoldersWithSameUserAndHost(t *testing.T) {
+	ctx := context.Background()
+	leases := fake.NewClientset().CoordinationV1().Leases("tough-falcons")
+
+	// Two parallel CI jobs on the same runner host look the same.
+	holder := OperationLockHolder{User: "ci@example.org", Host: "runner-1", CliVersion: "1.0.0", Operation: "deploy server"}
+	firstIdentity := newOperationLockIdentity(holder)
+	secondIdentity := newOperationLockIdentity(holder)
+	if firstIdentity == secondIdentity {
+		t.Fatalf("expected unique identities, got %q twice", firstIdentity)
+	}
+
+	// The first job gets the lock, the second one has to wait for it.
+	acquired, _, err := tryAcquireOperationLock(ctx, leases, holder, firstIdentity)
+	if err != nil || !acquired {
+		t.Fatalf("expected the first holder to acquire the lock, got: %v, err: %v", acquired, err)
+	}
+	acquired, existingInfo, err := tryAcquireOperationLock(ctx, leases, holder, secondIdentity)
+	if err != nil || acquired {
+		t.Fatalf("expected the second holder not to acquire the lock, got: %v, err: %v", acquired, err)
+	}
+	if existingInfo == nil || existingInfo.Holder.User != holder.User || existingInfo.Holder.Host != holder.Host {
+		t.Errorf("expected the lock info of the first holder, got: %+v", existingInfo)
+	}
+
+	// The first job stops sending heartbeats and the second one takes over the lock.
+	lease, err := leases.Get(ctx, operationLockName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredAt := metav1.NewMicroTime(time.Now().Add(-2 * operationLockTTL))
+	lease.Spec.RenewTime = &expiredAt
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	acquired, _, err = tryAcquireOperationLock(ctx, leases, holder, secondIdentity)
+	if err != nil || !acquired {
+		t.Fatalf("expected the second holder to take over the expired lock, got: %v, err: %v", acquired, err)
+	}
+
+	// The stale first holder can neither renew nor release the second holder's lock.
+	staleLock := newOperationLock(leases, "tough-falcons", firstIdentity)
+	if err := staleLock.renew(ctx); err == nil {
+		t.Error("expected the stale holder to fail renewing the lock")
+	}
+	if err := staleLock.Release(); err != nil {
+		t.Fatalf("failed to release stale lock: %v", err)
+	}
+	lease, err = leases.Get(ctx, operationLockName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the lock to still exist, got: %v", err)
+	}
+	if *lease.Spec.HolderIdentity != secondIdentity {
+		t.Errorf("expected the lock to be held by %q, got %q", secondIdentity, *lease.Spec.HolderIdentity)
+	}
+
+	// The actual holder can release the lock.
+	lock := newOperationLock(leases, "tough-falcons", secondIdentity)
+	if err := lock.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+	if info, err := leases.Get(ctx, operationLockName, metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the lock to be released, got: %+v", info)
+	}
+}
+
+func TestTargetEnvironmentOperationLock(t *testing.T) {
+	ctx := context.Background()
+	target := newTestKubeTarget()
+
+	info, err := target.GetOperationLock(ctx)
+	if err != nil || info != nil {
+		t.Fatalf("expected no operation lock, got: %+v, err: %v", info, err)
+	}
+	if err := target.ForceReleaseOperationLock(ctx); err == nil {
+		t.Error("expected force-releasing a missing lock to fail")
+	}
+
+	holder := OperationLockHolder{User: "ci@example.org", Host: "runner-1", CliVersion: "1.0.0", Operation: "deploy server"}
+	lock, err := target.AcquireOperationLock(ctx, holder, time.Second)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	info, err = target.GetOperationLock(ctx)
+	if err != nil || info == nil || info.Holder.Operation != holder.Operation {
+		t.Fatalf("expected the lock to be held by %+v, got: %+v, err: %v", holder, info, err)
+	}
+
+	// An active lock can't be force-released, nor acquired by somebody else.
+	if err := target.ForceReleaseOperationLock(ctx); err == nil {
+		t.Error("expected force-releasing an active lock to fail")
+	}
+	if _, err := target.AcquireOperationLock(ctx, holder, 0); err == nil {
+		t.Error("expected acquiring a held lock to fail")
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+	if info, err := target.GetOperationLock(ctx); err != nil || info != nil {
+		t.Errorf("expected the lock to be released, got: %+v, err: %v", info, err)
+	}
+}
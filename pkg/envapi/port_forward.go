@@ -0,0 +1,210 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// Port of the game server's admin API (and the LiveOps Dashboard).
+const gameServerAdminPort = 5550
+
+// A well-known service in the environment that can be port-forwarded to.
+type PortForwardTarget struct {
+	Name        string             // Name used to choose the target, eg, 'grafana'.
+	Description string             // Human-readable description of the target.
+	Selector    string             // Label selector of the pods serving the target.
+	TargetPort  intstr.IntOrString // Port (number or container port name) on the pods.
+	LocalPort   int                // Default local port to forward from.
+}
+
+// Well-known observability services, matched by their Kubernetes service name.
+var wellKnownPortForwardServices = []struct {
+	name        string
+	description string
+	localPort   int
+	excludes    []string // Services with these in their name are not matched, eg, exporters.
+}{
+	{"grafana", "Grafana dashboards", 3000, nil},
+	{"prometheus", "Prometheus metrics", 9090, []string{"operator", "alertmanager", "exporter", "kube-state-metrics", "pushgateway"}},
+}
+
+// Find the well-known services that can be port-forwarded to in the environment's namespace:
+// the game server admin API (if a game server is running) and the observability services.
+func DiscoverPortForwardTargets(ctx context.Context, kubeCli *KubeClient) ([]PortForwardTarget, error) {
+	targets := []PortForwardTarget{}
+
+	// Game server admin API, served by the game server pods.
+	pods, err := kubeCli.Clientset.CoreV1().Pods(kubeCli.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metaplayGameServerPodLabelSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game server pods: %w", err)
+	}
+	if len(pods.Items) > 0 {
+		targets = append(targets, PortForwardTarget{
+			Name:        "admin",
+			Description: "Game server admin API and LiveOps Dashboard",
+			Selector:    metaplayGameServerPodLabelSelector,
+			TargetPort:  intstr.FromInt(gameServerAdminPort),
+			LocalPort:   gameServerAdminPort,
+		})
+	}
+
+	// Observability services.
+	services, err := kubeCli.Clientset.CoreV1().Services(kubeCli.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	sort.Slice(services.Items, func(i, j int) bool {
+		return services.Items[i].Name < services.Items[j].Name
+	})
+	for _, known := range wellKnownPortForwardServices {
+		for _, service := range services.Items {
+			if !isWellKnownService(service.Name, known.name, known.excludes) || len(service.Spec.Selector) == 0 || len(service.Spec.Ports) == 0 {
+				continue
+			}
+			targets = append(targets, PortForwardTarget{
+				Name:        known.name,
+				Description: fmt.Sprintf("%s (service %s)", known.description, service.Name),
+				Selector:    labels.SelectorFromSet(service.Spec.Selector).String(),
+				TargetPort:  resolveServiceTargetPort(service.Spec.Ports[0]),
+				LocalPort:   known.localPort,
+			})
+			break
+		}
+	}
+
+	return targets, nil
+}
+
+// Check whether the Kubernetes service name matches the well-known service.
+func isWellKnownService(serviceName string, name string, excludes []string) bool {
+	if !strings.Contains(serviceName, name) {
+		return false
+	}
+	for _, exclude := range excludes {
+		if strings.Contains(serviceName, exclude) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve the port on the pods that a service port routes to.
+func resolveServiceTargetPort(servicePort corev1.ServicePort) intstr.IntOrString {
+	if servicePort.TargetPort.Type == intstr.String || servicePort.TargetPort.IntVal != 0 {
+		return servicePort.TargetPort
+	}
+	return intstr.FromInt32(servicePort.Port)
+}
+
+// Find a running and ready pod serving the target, and resolve the port to forward to on it.
+// Resolved again on each reconnect, as the pods may have been replaced.
+func (target *PortForwardTarget) ResolvePod(ctx context.Context, kubeCli *KubeClient) (string, int, error) {
+	pods, err := kubeCli.Clientset.CoreV1().Pods(kubeCli.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: target.Selector,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to list pods for %s: %w", target.Name, err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning || !isPodReady(&pod) {
+			continue
+		}
+		if target.TargetPort.Type == intstr.Int {
+			return pod.Name, target.TargetPort.IntValue(), nil
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, port := range container.Ports {
+				if port.Name == target.TargetPort.StrVal {
+					return pod.Name, int(port.ContainerPort), nil
+				}
+			}
+		}
+		return "", 0, fmt.Errorf("pod %s has no port named '%s'", pod.Name, target.TargetPort.StrVal)
+	}
+	return "", 0, fmt.Errorf("no ready pods found for %s", target.Name)
+}
+
+// Check whether the pod's Ready condition is true.
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Forward the local port (on localhost) to the port on the pod, equivalent to
+// 'kubectl port-forward'. Calls onReady once the local port is listening. Blocks until the
+// context is cancelled (returns nil) or the connection to the pod is lost (returns an error).
+func (kubeCli *KubeClient) PortForward(ctx context.Context, podName string, localPort int, podPort int, onReady func()) error {
+	transport, upgrader, err := spdy.RoundTripperFor(kubeCli.RestConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create port-forward transport: %w", err)
+	}
+	url := kubeCli.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(kubeCli.Namespace).
+		Name(podName).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stopChan := make(chan struct{})
+	readyChan := make(chan struct{})
+	var errOut bytes.Buffer
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("%d:%d", localPort, podPort)}, stopChan, readyChan, io.Discard, &errOut)
+	if err != nil {
+		return fmt.Errorf("failed to create port-forward to pod %s: %w", podName, err)
+	}
+
+	// Stop forwarding when the context is cancelled.
+	forwardDone := make(chan struct{})
+	defer close(forwardDone)
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(stopChan)
+		case <-forwardDone:
+		}
+	}()
+	go func() {
+		select {
+		case <-readyChan:
+			if onReady != nil {
+				onReady()
+			}
+		case <-forwardDone:
+		}
+	}()
+
+	err = forwarder.ForwardPorts()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err == nil {
+		err = fmt.Errorf("port-forward to pod %s stopped", podName)
+	}
+	if details := strings.TrimSpace(errOut.String()); details != "" {
+		return fmt.Errorf("%w: %s", err, details)
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Create a service routing to the pods with the given app label.
+func newTestService(name string, app string, targetPort intstr.IntOrString) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tough-falcons"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": app},
+			Ports:    []corev1.ServicePort{{Port: 80, TargetPort: targetPort}},
+		},
+	}
+}
+
+// Create a running pod with the given app label and named container port.
+func newTestServicePod(name string, app string, ready bool) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tough-falcons", Labels: map[string]string{"app": app}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  app,
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 3000}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: readyStatus}},
+		},
+	}
+}
+
+func TestDiscoverPortForwardTargets(t *testing.T) {
+	kubeCli := &KubeClient{
+		Namespace: "tough-falcons",
+		Clientset: fake.NewClientset(
+			newTestGameServerPod("all-0", "all-uid"),
+			newTestService("grafana", "grafana", intstr.FromString("http")),
+			newTestService("prometheus-node-exporter", "node-exporter", intstr.FromInt32(9100)),
+			newTestService("prometheus-server", "prometheus", intstr.IntOrString{}),
+		),
+	}
+
+	targets, err := DiscoverPortForwardTargets(context.Background(), kubeCli)
+	if err != nil {
+		t.Fatalf("failed to discover targets: %v", err)
+	}
+	want := []PortForwardTarget{
+		{Name: "admin", Selector: "app=metaplay-server", TargetPort: intstr.FromInt(gameServerAdminPort), LocalPort: gameServerAdminPort},
+		{Name: "grafana", Selector: "app=grafana", TargetPort: intstr.FromString("http"), LocalPort: 3000},
+		{Name: "prometheus", Selector: "app=prometheus", TargetPort: intstr.FromInt32(80), LocalPort: 9090},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("expected %d targets, got: %+v", len(want), targets)
+	}
+	for ndx, target := range targets {
+		if target.Name != want[ndx].Name || target.Selector != want[ndx].Selector || target.TargetPort != want[ndx].TargetPort || target.LocalPort != want[ndx].LocalPort {
+			t.Errorf("target %d = %+v, expected %+v", ndx, target, want[ndx])
+		}
+	}
+}
+
+func TestPortForwardTargetResolvePod(t *testing.T) {
+	ctx := context.Background()
+	kubeCli := &KubeClient{
+		Namespace: "tough-falcons",
+		Clientset: fake.NewClientset(
+			newTestServicePod("grafana-0", "grafana", false),
+			newTestServicePod("grafana-1", "grafana", true),
+		),
+	}
+
+	// Named ports are resolved from the ready pod's containers.
+	target := PortForwardTarget{Name: "grafana", Selector: "app=grafana", TargetPort: intstr.FromString("http")}
+	podName, port, err := target.ResolvePod(ctx, kubeCli)
+	if err != nil || podName != "grafana-1" || port != 3000 {
+		t.Errorf("ResolvePod() = %s:%d, err: %v, expected grafana-1:3000", podName, port, err)
+	}
+
+	target.TargetPort = intstr.FromInt(8080)
+	if podName, port, err = target.ResolvePod(ctx, kubeCli); err != nil || podName != "grafana-1" || port != 8080 {
+		t.Errorf("ResolvePod() = %s:%d, err: %v, expected grafana-1:8080", podName, port, err)
+	}
+
+	target.TargetPort = intstr.FromString("metrics")
+	if _, _, err = target.ResolvePod(ctx, kubeCli); err == nil {
+		t.Errorf("expected a missing named port to fail")
+	}
+
+	target = PortForwardTarget{Name: "prometheus", Selector: "app=prometheus", TargetPort: intstr.FromInt(9090)}
+	if _, _, err = target.ResolvePod(ctx, kubeCli); err == nil {
+		t.Errorf("expected a target without ready pods to fail")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Pod template annotation used by 'kubectl rollout restart' to trigger a rolling restart.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// Trigger a rolling restart of all the game server's shard sets (StatefulSets), the same
+// way 'kubectl rollout restart' does: by updating an annotation in the pod template.
+func (gs *TargetGameServer) RestartShardSets(ctx context.Context) error {
+	restartedAt := time.Now().Format(time.RFC3339)
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"%s":"%s"}}}}}`, restartedAtAnnotation, restartedAt)
+
+	for _, shardSet := range gs.ShardSets {
+		kubeCli := shardSet.Cluster.KubeClient
+		log.Debug().Msgf("Restart StatefulSet %s: %s", shardSet.Name, patch)
+		_, err := kubeCli.Clientset.AppsV1().StatefulSets(gs.Namespace).Patch(ctx, shardSet.Name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to restart shard set '%s': %w", shardSet.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Wait until the rolling update of all the game server's shard sets has completed, ie, all
+// pods have been replaced with the latest revision and are ready.
+func (gs *TargetGameServer) WaitForShardSetRollouts(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, shardSet := range gs.ShardSets {
+		kubeCli := shardSet.Cluster.KubeClient
+		for {
+			statefulSet, err := kubeCli.Clientset.AppsV1().StatefulSets(gs.Namespace).Get(ctx, shardSet.Name, metav1.GetOptions{})
+			if err != nil {
+				if ctx.Err() != nil {
+					return fmt.Errorf("timeout while waiting for shard set '%s' to restart", shardSet.Name)
+				}
+				return fmt.Errorf("failed to get shard set '%s': %w", shardSet.Name, err)
+			}
+
+			if isStatefulSetRolledOut(statefulSet) {
+				log.Debug().Msgf("StatefulSet %s rolled out to revision %s", shardSet.Name, statefulSet.Status.UpdateRevision)
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("timeout while waiting for shard set '%s' to restart (%d/%d pods updated)", shardSet.Name, statefulSet.Status.UpdatedReplicas, getStatefulSetReplicas(statefulSet))
+			case <-time.After(2 * time.Second):
+			}
+		}
+	}
+
+	return nil
+}
+
+// Check whether the StatefulSet's latest revision has been fully rolled out, using the
+// same criteria as 'kubectl rollout status'.
+func isStatefulSetRolledOut(statefulSet *appsv1.StatefulSet) bool {
+	status := statefulSet.Status
+	replicas := getStatefulSetReplicas(statefulSet)
+	if status.ObservedGeneration < statefulSet.Generation {
+		return false
+	}
+	if status.UpdatedReplicas < replicas || status.ReadyReplicas < replicas {
+		return false
+	}
+	return status.UpdateRevision == status.CurrentRevision
+}
+
+func getStatefulSetReplicas(statefulSet *appsv1.StatefulSet) int32 {
+	if statefulSet.Spec.Replicas == nil {
+		return 1
+	}
+	return *statefulSet.Spec.Replicas
+}
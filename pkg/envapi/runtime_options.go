@@ -0,0 +1,94 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/metaplay/cli/pkg/metahttp"
+	"github.com/rs/zerolog/log"
+)
+
+// Types of runtime option values known by the StackAPI. An empty type means that the
+// type is not known and the value is passed through as a string.
+const (
+	RuntimeOptionTypeString = "string"
+	RuntimeOptionTypeBool   = "bool"
+	RuntimeOptionTypeInt    = "int"
+	RuntimeOptionTypeFloat  = "float"
+)
+
+// Runtime option of the game server in an environment.
+type RuntimeOption struct {
+	Key             string `json:"key"`             // Full key of the option, eg, 'Player:MaxNameLength'.
+	Value           any    `json:"value"`           // Current value of the option.
+	Type            string `json:"type,omitempty"`  // Type of the value (see RuntimeOptionType*), or empty if unknown.
+	RequiresRestart bool   `json:"requiresRestart"` // Does changing the option require a game server restart?
+}
+
+// Request body for updating runtime options.
+type updateRuntimeOptionsRequest struct {
+	Options map[string]any `json:"options"`
+}
+
+// Get the runtime options of the game server in the environment from the StackAPI.
+func (target *TargetEnvironment) GetRuntimeOptions() ([]RuntimeOption, error) {
+	path := fmt.Sprintf("/v0/deployments/%s/runtimeOptions", target.HumanId)
+	log.Debug().Msgf("Get runtime options from %s%s", target.StackApiClient.BaseURL, path)
+	options, err := metahttp.Get[[]RuntimeOption](target.StackApiClient, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get runtime options: %w", err)
+	}
+	return options, nil
+}
+
+// Update the given runtime options (by key) of the game server in the environment. Returns
+// all the runtime options after the update.
+func (target *TargetEnvironment) SetRuntimeOptions(values map[string]any) ([]RuntimeOption, error) {
+	path := fmt.Sprintf("/v0/deployments/%s/runtimeOptions", target.HumanId)
+	log.Debug().Msgf("Update runtime options at %s%s: %v", target.StackApiClient.BaseURL, path, values)
+	options, err := metahttp.Put[[]RuntimeOption](target.StackApiClient, path, updateRuntimeOptionsRequest{Options: values})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update runtime options: %w", err)
+	}
+	return options, nil
+}
+
+// Parse a value given as a string into the option's type. Values of options with an
+// unknown type are returned as strings.
+func (option *RuntimeOption) ParseValue(str string) (any, error) {
+	switch option.Type {
+	case RuntimeOptionTypeBool:
+		value, err := strconv.ParseBool(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for runtime option %s: expecting a boolean", str, option.Key)
+		}
+		return value, nil
+	case RuntimeOptionTypeInt:
+		value, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for runtime option %s: expecting an integer", str, option.Key)
+		}
+		return value, nil
+	case RuntimeOptionTypeFloat:
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for runtime option %s: expecting a number", str, option.Key)
+		}
+		return value, nil
+	default:
+		return str, nil
+	}
+}
+
+// Find a runtime option by its key.
+func FindRuntimeOption(options []RuntimeOption, key string) *RuntimeOption {
+	for ndx := range options {
+		if options[ndx].Key == key {
+			return &options[ndx]
+		}
+	}
+	return nil
+}
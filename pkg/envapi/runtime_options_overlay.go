@@ -0,0 +1,140 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Aspect of a cloud environment that a locally running game server cannot emulate, along
+// with the runtime options left out of the local options overlay because of it.
+type RuntimeOptionsEmulationGap struct {
+	Name        string   // Short name of the gap, eg, 'Database'.
+	Description string   // How the local server differs from the environment.
+	Keys        []string // Keys of the runtime options left out because of the gap.
+}
+
+// Runtime option sections bound to the cloud infrastructure. Their values are meaningless
+// or harmful in a locally running game server, so they are never copied into the overlay.
+var runtimeOptionsEmulationGaps = []struct {
+	name        string
+	description string
+	sections    []string
+}{
+	{"Database", "the local server uses a SQLite database instead of the environment's database engine", []string{"Database"}},
+	{"Cluster", "the local server runs all shards in a single node instead of the environment's multi-node topology", []string{"Clustering"}},
+	{"Endpoints", "the local server uses localhost without TLS instead of the environment's domains and CDN", []string{"Deployment", "Environment"}},
+	{"Admin API", "the local admin API has no authentication", []string{"AdminApi"}},
+	{"External services", "cloud services and stores are not reachable with the environment's credentials", []string{"AWS", "PushNotification", "GooglePlayStore", "AppleStore"}},
+}
+
+// Runtime option values that refer to secrets stored in the cloud environment.
+var runtimeOptionSecretPrefixes = []string{"aws-sm://", "kube-secret://"}
+
+// Key names hinting that the value of a runtime option is a secret.
+var runtimeOptionSecretKeyNames = []string{"secret", "password", "token", "privatekey", "apikey"}
+
+// Split the runtime options of an environment into the ones that can be emulated by a locally
+// running game server, eg, feature flags and game-specific options, and the gaps that cannot
+// be emulated. All the known gaps are returned, even if no runtime options are left out
+// because of them. Options with secret values are never emulated.
+func SplitLocallyEmulatedRuntimeOptions(options []RuntimeOption) ([]RuntimeOption, []RuntimeOptionsEmulationGap) {
+	gaps := make([]RuntimeOptionsEmulationGap, len(runtimeOptionsEmulationGaps))
+	for ndx, gap := range runtimeOptionsEmulationGaps {
+		gaps[ndx] = RuntimeOptionsEmulationGap{Name: gap.name, Description: gap.description}
+	}
+	secretsGap := RuntimeOptionsEmulationGap{Name: "Secrets", Description: "secret values are not copied from the environment"}
+
+	emulated := []RuntimeOption{}
+	for _, option := range options {
+		if option.Value == nil {
+			continue
+		}
+
+		section, _, _ := strings.Cut(option.Key, ":")
+		gapNdx := -1
+		for ndx, gap := range runtimeOptionsEmulationGaps {
+			for _, gapSection := range gap.sections {
+				if strings.EqualFold(section, gapSection) {
+					gapNdx = ndx
+				}
+			}
+		}
+
+		if gapNdx >= 0 {
+			gaps[gapNdx].Keys = append(gaps[gapNdx].Keys, option.Key)
+		} else if isSecretRuntimeOption(option) {
+			secretsGap.Keys = append(secretsGap.Keys, option.Key)
+		} else {
+			emulated = append(emulated, option)
+		}
+	}
+
+	if len(secretsGap.Keys) > 0 {
+		gaps = append(gaps, secretsGap)
+	}
+	return emulated, gaps
+}
+
+// Check whether the runtime option is (or refers to) a secret, based on its name or value.
+func isSecretRuntimeOption(option RuntimeOption) bool {
+	if str, ok := option.Value.(string); ok {
+		for _, prefix := range runtimeOptionSecretPrefixes {
+			if strings.HasPrefix(str, prefix) {
+				return true
+			}
+		}
+	}
+
+	name := strings.ToLower(option.Key[strings.LastIndex(option.Key, ":")+1:])
+	for _, secretName := range runtimeOptionSecretKeyNames {
+		if strings.Contains(name, secretName) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render the runtime options as a YAML options file that the game server can load on top
+// of its other options files. The keys, eg, 'Player:MaxNameLength', are split into nested
+// sections.
+func RenderRuntimeOptionsOverlay(options []RuntimeOption) ([]byte, error) {
+	root := map[string]any{}
+	for _, option := range options {
+		parts := strings.Split(option.Key, ":")
+		section := root
+		for _, part := range parts[:len(parts)-1] {
+			child, found := section[part]
+			if !found {
+				child = map[string]any{}
+				section[part] = child
+			}
+			childSection, ok := child.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("runtime option %s conflicts with the value of another runtime option", option.Key)
+			}
+			section = childSection
+		}
+
+		leaf := parts[len(parts)-1]
+		if _, found := section[leaf]; found {
+			return nil, fmt.Errorf("runtime option %s conflicts with another runtime option", option.Key)
+		}
+		section[leaf] = runtimeOptionOverlayValue(option)
+	}
+
+	return yaml.Marshal(root)
+}
+
+// Convert the value of a runtime option for the overlay. Numbers from JSON are float64s, so
+// integer options are converted back to integers.
+func runtimeOptionOverlayValue(option RuntimeOption) any {
+	if f, ok := option.Value.(float64); ok && option.Type == RuntimeOptionTypeInt && f == float64(int64(f)) {
+		return int64(f)
+	}
+	return option.Value
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"testing"
+)
+
+func TestRuntimeOptionParseValue(t *testing.T) {
+	tests := []struct {
+		optionType string
+		input      string
+		want       any
+		wantErr    bool
+	}{
+		{RuntimeOptionTypeBool, "true", true, false},
+		{RuntimeOptionTypeBool, "yes", nil, true},
+		{RuntimeOptionTypeInt, "42", int64(42), false},
+		{RuntimeOptionTypeInt, "4.2", nil, true},
+		{RuntimeOptionTypeFloat, "4.2", 4.2, false},
+		{RuntimeOptionTypeFloat, "many", nil, true},
+		{RuntimeOptionTypeString, "42", "42", false},
+		{"", "anything", "anything", false},
+	}
+
+	for _, test := range tests {
+		option := RuntimeOption{Key: "Test:Option", Type: test.optionType}
+		got, err := option.ParseValue(test.input)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseValue(%q) with type %q = %v (err: %v), expected %v", test.input, test.optionType, got, err, test.want)
+		}
+	}
+}
+
+func TestFindRuntimeOption(t *testing.T) {
+	options := []RuntimeOption{{Key: "Player:MaxNameLength"}, {Key: "System:EnableDevFeatures"}}
+	if option := FindRuntimeOption(options, "System:EnableDevFeatures"); option != &options[1] {
+		t.Errorf("expected to find the option, got: %v", option)
+	}
+	if option := FindRuntimeOption(options, "System:Missing"); option != nil {
+		t.Errorf("expected no option, got: %v", option)
+	}
+}
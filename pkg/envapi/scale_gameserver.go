@@ -0,0 +1,146 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Scaling configuration of a shard set, as declared in the gameserver CR.
+type ShardSetScaling struct {
+	Name         string // Name of the shard set.
+	NodeCount    *int   // Desired number of nodes (pods), nil if not specified.
+	MinNodeCount *int   // Minimum number of nodes enforced by the chart, nil if unbounded.
+	MaxNodeCount *int   // Maximum number of nodes enforced by the chart, nil if unbounded.
+}
+
+// Current replica counts of a shard set's StatefulSet.
+type ShardSetReplicas struct {
+	Desired int32 // Number of replicas in the StatefulSet spec.
+	Ready   int32 // Number of ready replicas.
+}
+
+// Get the scaling configuration of the named shard set from the gameserver CR.
+func (gs *TargetGameServer) GetShardSetScaling(shardSetName string) (*ShardSetScaling, error) {
+	if gs.GameServerNewCR != nil {
+		for _, shard := range gs.GameServerNewCR.Spec.Shards {
+			if shard.Name == shardSetName {
+				return &ShardSetScaling{
+					Name:         shard.Name,
+					NodeCount:    shard.NodeCount,
+					MinNodeCount: shard.MinNodeCount,
+					MaxNodeCount: shard.MaxNodeCount,
+				}, nil
+			}
+		}
+	} else if gs.GameServerOldCR != nil {
+		for _, shard := range gs.GameServerOldCR.Spec.ShardSpec {
+			if shard.Name == shardSetName {
+				nodeCount := shard.NodeCount
+				return &ShardSetScaling{
+					Name:      shard.Name,
+					NodeCount: &nodeCount,
+				}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("shard set with name '%s' not found", shardSetName)
+}
+
+// Scale the named shard set to the given number of nodes by updating the node count in the
+// gameserver CR. The operator then reconciles the shard set's StatefulSet to match. Note that
+// the change is overwritten by the next Helm deployment of the game server.
+func (gs *TargetGameServer) ScaleShardSet(ctx context.Context, shardSetName string, nodeCount int) error {
+	kubeCli := gs.Clusters[0].KubeClient // gameserver CR lives in the primary cluster
+
+	// Resolve the CR to patch and the index of the shard set in it.
+	var patch string
+	var crName string
+	if gs.GameServerNewCR != nil {
+		crName = gs.GameServerNewCR.GetName()
+		for ndx, shard := range gs.GameServerNewCR.Spec.Shards {
+			if shard.Name == shardSetName {
+				patch = fmt.Sprintf(`[{"op":"test","path":"/spec/shards/%d/name","value":"%s"},{"op":"add","path":"/spec/shards/%d/nodeCount","value":%d}]`, ndx, shardSetName, ndx, nodeCount)
+			}
+		}
+	} else if gs.GameServerOldCR != nil {
+		crName = gs.GameServerOldCR.Metadata.Name
+		for ndx, shard := range gs.GameServerOldCR.Spec.ShardSpec {
+			if shard.Name == shardSetName {
+				patch = fmt.Sprintf(`[{"op":"test","path":"/spec/shardSpec/%d/name","value":"%s"},{"op":"replace","path":"/spec/shardSpec/%d/nodeCount","value":%d}]`, ndx, shardSetName, ndx, nodeCount)
+			}
+		}
+	}
+	if patch == "" {
+		return fmt.Errorf("shard set with name '%s' not found", shardSetName)
+	}
+
+	gvr := oldGameServerGVR
+	if gs.GameServerNewCR != nil {
+		gvr = newGameServerGVR
+	}
+
+	// Apply the patch. The 'test' operations ensure the shard list hasn't changed under us.
+	log.Debug().Msgf("Patch gameserver CR %s: %s", crName, patch)
+	_, err := kubeCli.DynamicClient.Resource(gvr).Namespace(gs.Namespace).Patch(ctx, crName, types.JSONPatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to scale shard set '%s': %w", shardSetName, err)
+	}
+
+	return nil
+}
+
+// Get the current replica counts of the named shard set's StatefulSet.
+func (gs *TargetGameServer) GetShardSetReplicas(ctx context.Context, shardSetName string) (*ShardSetReplicas, error) {
+	shardSet, err := gs.getShardSetByName(shardSetName)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeCli := shardSet.Cluster.KubeClient
+	statefulSet, err := kubeCli.Clientset.AppsV1().StatefulSets(gs.Namespace).Get(ctx, shardSet.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard set '%s': %w", shardSet.Name, err)
+	}
+
+	return &ShardSetReplicas{
+		Desired: getStatefulSetReplicas(statefulSet),
+		Ready:   statefulSet.Status.ReadyReplicas,
+	}, nil
+}
+
+// Wait until the named shard set's StatefulSet has the given number of replicas, and all of
+// them are ready.
+func (gs *TargetGameServer) WaitForShardSetReplicas(ctx context.Context, shardSetName string, replicas int32, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		current, err := gs.GetShardSetReplicas(ctx, shardSetName)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("timeout while waiting for shard set '%s' to scale", shardSetName)
+			}
+			return err
+		}
+
+		if current.Desired == replicas && current.Ready == replicas {
+			return nil
+		}
+		log.Debug().Msgf("Shard set %s: desired=%d, ready=%d, waiting for %d", shardSetName, current.Desired, current.Ready, replicas)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout while waiting for shard set '%s' to scale (%d/%d pods ready)", shardSetName, current.Ready, replicas)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
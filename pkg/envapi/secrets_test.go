@@ -0,0 +1,100 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Create a TargetEnvironment whose primary Kubernetes client is a fake clientset with the
+// given objects.
+func newTestKubeTarget(objects ...runtime.Object) *TargetEnvironment {
+	return &TargetEnvironment{
+		HumanId: "tough-falcons",
+		primaryKubeClient: &KubeClient{
+			Namespace: "tough-falcons",
+			Clientset: fake.NewClientset(objects...),
+		},
+	}
+}
+
+func TestUserSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	// A built-in secret without the user secret label.
+	builtinSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "user-builtin", Namespace: "tough-falcons"}}
+	target := newTestKubeTarget(builtinSecret)
+
+	// No user secrets yet.
+	secrets, err := target.ListSecrets(ctx)
+	if err != nil || secrets == nil || len(secrets) != 0 {
+		t.Fatalf("expected an empty list of secrets, got: %v, err: %v", secrets, err)
+	}
+
+	// Secret names must have the user prefix.
+	if err := target.CreateSecret(ctx, "my-secret", nil); err == nil {
+		t.Errorf("expected creating a secret without the '%s' prefix to fail", userSecretNamePrefix)
+	}
+	if err := target.CreateSecret(ctx, "user-api-key", map[string][]byte{"key": []byte("value")}); err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	secrets, err = target.ListSecrets(ctx)
+	if err != nil || len(secrets) != 1 || secrets[0].Name != "user-api-key" {
+		t.Fatalf("expected only the user secret to be listed, got: %v, err: %v", secrets, err)
+	}
+	secret, err := target.GetSecret(ctx, "user-api-key")
+	if err != nil || string(secret.Data["key"]) != "value" {
+		t.Fatalf("failed to get secret: %+v, err: %v", secret, err)
+	}
+
+	// Secrets without the user label can't be accessed.
+	if _, err := target.GetSecret(ctx, "user-builtin"); err == nil {
+		t.Errorf("expected getting a non-user secret to fail")
+	}
+	if err := target.DeleteSecret(ctx, "user-builtin"); err == nil {
+		t.Errorf("expected deleting a non-user secret to fail")
+	}
+	if _, err := target.GetSecret(ctx, "user-missing"); err == nil {
+		t.Errorf("expected getting a missing secret to fail")
+	}
+	if err := target.DeleteSecret(ctx, "user-missing"); err == nil {
+		t.Errorf("expected deleting a missing secret to fail")
+	}
+
+	if err := target.DeleteSecret(ctx, "user-api-key"); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+	if _, err := target.GetSecret(ctx, "user-api-key"); err == nil {
+		t.Errorf("expected the secret to be deleted")
+	}
+}
+
+func TestGameServerConfigMaps(t *testing.T) {
+	ctx := context.Background()
+	target := newTestKubeTarget(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "gameserver-config", Namespace: "tough-falcons", Labels: map[string]string{"app": "metaplay-server"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other-config", Namespace: "tough-falcons"}},
+	)
+
+	configMaps, err := target.ListGameServerConfigMaps(ctx)
+	if err != nil || len(configMaps) != 1 || configMaps[0].Name != "gameserver-config" {
+		t.Fatalf("expected only the game server ConfigMap, got: %v, err: %v", configMaps, err)
+	}
+
+	configMap, err := target.GetConfigMap(ctx, "other-config")
+	if err != nil || configMap == nil {
+		t.Fatalf("failed to get ConfigMap: %v", err)
+	}
+	configMap, err = target.GetConfigMap(ctx, "missing-config")
+	if err != nil || configMap != nil {
+		t.Errorf("expected no ConfigMap and no error for a missing ConfigMap, got: %v, err: %v", configMap, err)
+	}
+}
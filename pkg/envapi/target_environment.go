@@ -9,11 +9,12 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-
-	"gopkg.in/yaml.v3"
+	"time"
 
 	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/common"
 	"github.com/metaplay/cli/pkg/metahttp"
+	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -27,6 +28,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 // Wrapper object for accessing an environment within a target stack.
@@ -38,6 +40,7 @@ type TargetEnvironment struct {
 
 	primaryKubeClient *KubeClient       // Lazily initialized KubeClient.
 	targetGameServer  *TargetGameServer // Lazily initialized TargetGameServer.
+	details           *DeploymentSecret // Lazily fetched environment details, see GetDetails().
 }
 
 // Container for AWS access credentials into the target environment.
@@ -55,10 +58,21 @@ type DockerCredentials struct {
 	Username    string
 	Password    string
 	RegistryURL string
+	ExpiresAt   time.Time // Time when the credentials expire (zero if unknown).
+}
+
+// Get the host name of the registry (without the URL scheme), eg, '<account>.dkr.ecr.<region>.amazonaws.com'.
+func (creds *DockerCredentials) RegistryHost() string {
+	host := strings.TrimPrefix(creds.RegistryURL, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.TrimSuffix(host, "/")
 }
 
+// Only warn about the StackAPI override once per command.
+var stackApiOverrideWarningShown = false
+
 func NewTargetEnvironment(tokenSet *auth.TokenSet, stackDomain, humanId string) *TargetEnvironment {
-	stackApiBaseURL := fmt.Sprintf("https://infra.%s/stackapi", stackDomain)
+	stackApiBaseURL := resolveStackApiBaseURL(stackDomain)
 	log.Debug().Msgf("Create TargetEnvironment with stackApiBaseURL=%s", stackApiBaseURL)
 	return &TargetEnvironment{
 		TokenSet:        tokenSet,
@@ -68,6 +82,24 @@ func NewTargetEnvironment(tokenSet *auth.TokenSet, stackDomain, humanId string)
 	}
 }
 
+// Resolve the base URL of the StackAPI of the stack. The METAPLAY_STACK_API_URL override (for
+// StackAPI development only) replaces the URL for all stacks, with a warning about it.
+func resolveStackApiBaseURL(stackDomain string) string {
+	defaultURL := fmt.Sprintf("https://infra.%s/stackapi", stackDomain)
+	if common.StackApiBaseURLOverride == "" {
+		return defaultURL
+	}
+
+	if !stackApiOverrideWarningShown {
+		stackApiOverrideWarningShown = true
+		metahttp.GetWarningLogger().Warn().Msg(styles.StyleWarning.Render(fmt.Sprintf(
+			"WARNING: METAPLAY_STACK_API_URL is set, using StackAPI %s instead of %s. This is for StackAPI development only!",
+			common.StackApiBaseURLOverride,
+			defaultURL)))
+	}
+	return common.StackApiBaseURLOverride
+}
+
 func (target *TargetEnvironment) GetKubernetesNamespace() string {
 	return target.HumanId
 }
@@ -246,20 +278,46 @@ func (target *TargetEnvironment) GetGameServer(ctx context.Context) (*TargetGame
 	return nil, fmt.Errorf("neither old nor new gameserver CR found in Kubernetes")
 }
 
-// Request details about an environment from the StackAPI.
+// Get details about an environment. The details are requested from the StackAPI on the first
+// call and the same details are returned for the lifetime of the TargetEnvironment, so they
+// stay consistent within a command. The returned details must not be modified. Use
+// RefreshDetails() to request the latest details.
 func (target *TargetEnvironment) GetDetails() (*DeploymentSecret, error) {
+	// If already fetched, just return the earlier details.
+	if target.details != nil {
+		return target.details, nil
+	}
+	return target.RefreshDetails()
+}
+
+// Request the latest details about an environment from the StackAPI, replacing the details
+// returned by GetDetails(). On failure, the earlier details are retained.
+func (target *TargetEnvironment) RefreshDetails() (*DeploymentSecret, error) {
 	path := fmt.Sprintf("/v0/deployments/%s", target.HumanId)
 	log.Debug().Msgf("Get environment details from %s%s", target.StackApiClient.BaseURL, path)
 	details, err := metahttp.Get[DeploymentSecret](target.StackApiClient, path)
-	return &details, err
+	if err != nil {
+		return &details, err
+	}
+	target.details = &details
+	return target.details, nil
 }
 
 // Get a short-lived kubeconfig with the access credentials embedded in the kubeconfig file.
+// The kubeconfig is validated and normalized, see NormalizeKubeConfig().
 func (target *TargetEnvironment) GetKubeConfigWithEmbeddedCredentials() (string, error) {
 	log.Debug().Msg("Fetching kubeconfig with embedded secret")
 	path := fmt.Sprintf("/v0/credentials/%s/k8s", target.HumanId)
-	config, err := metahttp.Post[string](target.StackApiClient, path, nil)
-	return config, err
+	payload, err := metahttp.Post[string](target.StackApiClient, path, nil)
+	if err != nil {
+		return "", err
+	}
+
+	config, err := NormalizeKubeConfig(payload, target.GetKubernetesNamespace())
+	if err != nil {
+		return "", fmt.Errorf("received an unusable kubeconfig for environment %s: %w", target.HumanId, err)
+	}
+	return SerializeKubeConfig(config)
 }
 
 // Get the Kubernetes credentials in the execcredential format
@@ -284,55 +342,36 @@ func (target *TargetEnvironment) GetKubeConfigWithExecCredential(userID string)
 		return "", err
 	}
 
-	if string(credentials.Spec.Cluster.CertificateAuthorityData) == "" && credentials.Spec.Cluster.Server == "" {
-		return "", fmt.Errorf("Received kubeExecCredential with missing spec.cluster")
+	cluster := credentials.Spec.Cluster
+	if cluster == nil || cluster.Server == "" {
+		return "", fmt.Errorf("received kubeExecCredential with missing spec.cluster.server")
 	}
 
-	kubeConfig, err := yaml.Marshal(KubeConfig{
-		ApiVersion: "v1",
-		Clusters: []KubeConfigCluster{
-			{
-				Cluster: KubeConfigClusterData{
-					CertificateAuthorityData: base64.StdEncoding.EncodeToString(credentials.Spec.Cluster.CertificateAuthorityData[:]),
-					Server:                   credentials.Spec.Cluster.Server,
-				},
-				Name: credentials.Spec.Cluster.Server,
-			},
-		},
-		Contexts: []KubeConfigContext{
-			{
-				Context: KubeConfigContextData{
-					Cluster:   credentials.Spec.Cluster.Server,
-					Namespace: target.HumanId,
-					User:      userID,
-				},
-				Name: target.HumanId,
-			},
-		},
-		CurrentContext: target.HumanId,
-		Kind:           "Config",
-		Preferences:    make(map[string]interface{}),
-		Users: []KubeConfigUser{
-			{
-				Name: userID,
-				User: KubeConfigUserData{
-					Exec: KubeConfigUserDataExec{
-						Command: "metaplay",
-						Args: []string{
-							"get",
-							"kubernetes-execcredential",
-							target.HumanId,
-							target.StackApiBaseURL,
-						},
-						ApiVersion:      "client.authentication.k8s.io/v1beta1",
-						InteractiveMode: "Never",
-					},
-				},
+	kubeConfig := clientcmdapi.NewConfig()
+	kubeConfig.Clusters[cluster.Server] = &clientcmdapi.Cluster{
+		Server:                   cluster.Server,
+		CertificateAuthorityData: cluster.CertificateAuthorityData,
+	}
+	kubeConfig.Contexts[target.HumanId] = &clientcmdapi.Context{
+		Cluster:   cluster.Server,
+		Namespace: target.HumanId,
+		AuthInfo:  userID,
+	}
+	kubeConfig.CurrentContext = target.HumanId
+	kubeConfig.AuthInfos[userID] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			Command: "metaplay",
+			Args: []string{
+				"get",
+				"kubernetes-execcredential",
+				target.HumanId,
+				target.StackApiBaseURL,
 			},
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
 		},
-	})
-	dump := string(kubeConfig[:])
-	return dump, nil
+	}
+	return SerializeKubeConfig(kubeConfig)
 }
 
 // Get AWS credentials against the target environment.
@@ -355,18 +394,18 @@ func (target *TargetEnvironment) GetAWSCredentials() (*AWSCredentials, error) {
 	return &awsCredentials, err
 }
 
-// Get Docker credentials for the environment's docker registry.
-func (target *TargetEnvironment) GetDockerCredentials(envDetails *DeploymentSecret) (*DockerCredentials, error) {
+// Create an AWS config for the environment's region, using the environment's AWS credentials.
+func (target *TargetEnvironment) newAWSConfig(ctx context.Context, envDetails *DeploymentSecret) (aws.Config, error) {
 	// Fetch AWS credentials from Metaplay cloud
 	log.Debug().Msg("Get AWS credentials")
 	awsCredentials, err := target.GetAWSCredentials()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get AWS credentials: %v", err)
+		return aws.Config{}, fmt.Errorf("failed to get AWS credentials: %v", err)
 	}
 
 	// Create AWS config with provided region and credentials
 	log.Debug().Msg("Create AWS config")
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
+	return config.LoadDefaultConfig(ctx,
 		config.WithRegion(envDetails.Deployment.AwsRegion),
 		config.WithCredentialsProvider(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
 			return aws.Credentials{
@@ -376,13 +415,16 @@ func (target *TargetEnvironment) GetDockerCredentials(envDetails *DeploymentSecr
 			}, nil
 		})),
 	)
-	if err != nil {
-		return nil, err
-	}
+}
 
+// Get Docker credentials for the environment's docker registry.
+func (target *TargetEnvironment) GetDockerCredentials(envDetails *DeploymentSecret) (*DockerCredentials, error) {
 	// Create an ECR client
 	log.Debug().Msg("Create ECR client")
-	client := ecr.NewFromConfig(cfg)
+	client, err := target.NewECRClient(context.TODO(), envDetails)
+	if err != nil {
+		return nil, err
+	}
 
 	// Fetch the ECR docker authentication token
 	log.Debug().Msg("Fetch ECR login credentials from AWS")
@@ -414,11 +456,17 @@ func (target *TargetEnvironment) GetDockerCredentials(envDetails *DeploymentSecr
 	username := parts[0]
 	password := parts[1]
 
-	log.Debug().Msgf("ECR: username=%s, proxyEndpoint=%s", username, registryURL)
+	var expiresAt time.Time
+	if response.AuthorizationData[0].ExpiresAt != nil {
+		expiresAt = *response.AuthorizationData[0].ExpiresAt
+	}
+
+	log.Debug().Msgf("ECR: username=%s, proxyEndpoint=%s, expiresAt=%s", username, registryURL, expiresAt)
 
 	return &DockerCredentials{
 		Username:    username,
 		Password:    password,
 		RegistryURL: registryURL,
+		ExpiresAt:   expiresAt,
 	}, nil
 }
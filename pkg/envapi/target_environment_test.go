@@ -0,0 +1,389 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/common"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/envapi/testutil"
+	"k8s.io/client-go/pkg/apis/clientauthentication"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const testEnvironment = "tough-falcons"
+
+// Kubeconfig with embedded credentials, as returned by the StackAPI.
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test-cluster
+  cluster:
+    server: https://127.0.0.1:6443
+    insecure-skip-tls-verify: true
+contexts:
+- name: tough-falcons
+  context:
+    cluster: test-cluster
+    namespace: tough-falcons
+    user: test-user
+current-context: tough-falcons
+users:
+- name: test-user
+  user:
+    token: test-token
+`
+
+func newTestDeploymentSecret() envapi.DeploymentSecret {
+	return envapi.DeploymentSecret{
+		Deployment: envapi.Deployment{
+			AdminHostname:       "tough-falcons-admin.p1.metaplay.io",
+			ServerHostname:      "tough-falcons.p1.metaplay.io",
+			AwsRegion:           "eu-west-1",
+			EcrRepo:             "000000000000.dkr.ecr.eu-west-1.amazonaws.com/tough-falcons",
+			KubernetesNamespace: testEnvironment,
+			HelmChartName:       "metaplay-gameserver",
+			HelmChartVersion:    "0.8.0",
+			HelmReleaseName:     "tough-falcons-gameserver",
+		},
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	server, mock := testutil.NewMockStackAPI(t)
+	mock.Deployments[testEnvironment] = newTestDeploymentSecret()
+	targetEnv := testutil.NewTargetEnvironment(server, testEnvironment)
+
+	details, err := targetEnv.GetDetails()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if details.Deployment.AdminHostname != "tough-falcons-admin.p1.metaplay.io" {
+		t.Errorf("admin hostname = %q", details.Deployment.AdminHostname)
+	}
+	if details.Deployment.HelmChartName != "metaplay-gameserver" || details.Deployment.HelmChartVersion != "0.8.0" || details.Deployment.HelmReleaseName != "tough-falcons-gameserver" {
+		t.Errorf("unexpected Helm details: %+v", details.Deployment)
+	}
+	if err := details.Validate(); err != nil {
+		t.Errorf("details should be valid: %v", err)
+	}
+	if !details.HasGameServerDeployment() {
+		t.Errorf("details should have a game server deployment")
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 1 || calls[0].Method != http.MethodGet || calls[0].Path != "/v0/deployments/tough-falcons" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	if calls[0].Authorization != "Bearer "+testutil.MockAccessToken {
+		t.Errorf("authorization = %q, expected the access token", calls[0].Authorization)
+	}
+
+	// Unknown environment.
+	otherEnv := testutil.NewTargetEnvironment(server, "other-env")
+	if _, err := otherEnv.GetDetails(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error, got: %v", err)
+	}
+
+	// The details are cached: no new request is made.
+	cached, err := targetEnv.GetDetails()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cached != details || mock.CallCount(http.MethodGet, "/v0/deployments/tough-falcons") != 1 {
+		t.Errorf("expected the cached details without a new request")
+	}
+
+	// Refreshing requests the latest details.
+	updated := newTestDeploymentSecret()
+	updated.Deployment.ServerHostname = "new.p1.metaplay.io"
+	mock.Deployments[testEnvironment] = updated
+	refreshed, err := targetEnv.RefreshDetails()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refreshed.Deployment.ServerHostname != "new.p1.metaplay.io" {
+		t.Errorf("refreshed server hostname = %q", refreshed.Deployment.ServerHostname)
+	}
+	if latest, _ := targetEnv.GetDetails(); latest != refreshed {
+		t.Errorf("expected GetDetails() to return the refreshed details")
+	}
+
+	// Server error, with the message from the error body. The earlier details are retained.
+	mock.SetError(http.MethodGet, "/v0/deployments/tough-falcons", http.StatusInternalServerError, "database unavailable")
+	if _, err := targetEnv.RefreshDetails(); err == nil || !strings.Contains(err.Error(), "database unavailable") {
+		t.Errorf("expected the error message from the response, got: %v", err)
+	}
+	if latest, err := targetEnv.GetDetails(); err != nil || latest != refreshed {
+		t.Errorf("expected the earlier details to be retained, got: %v", err)
+	}
+}
+
+func TestStackApiBaseURLOverride(t *testing.T) {
+	server, mock := testutil.NewMockStackAPI(t)
+	mock.Deployments[testEnvironment] = newTestDeploymentSecret()
+
+	// Without the override, the URL is derived from the stack domain.
+	targetEnv := envapi.NewTargetEnvironment(&auth.TokenSet{AccessToken: testutil.MockAccessToken}, "p1.metaplay.io", testEnvironment)
+	if targetEnv.StackApiBaseURL != "https://infra.p1.metaplay.io/stackapi" {
+		t.Errorf("StackApiBaseURL = %q", targetEnv.StackApiBaseURL)
+	}
+
+	// With the override, the StackAPI calls go to the override URL.
+	common.StackApiBaseURLOverride = server.URL
+	t.Cleanup(func() { common.StackApiBaseURLOverride = "" })
+	targetEnv = envapi.NewTargetEnvironment(&auth.TokenSet{AccessToken: testutil.MockAccessToken}, "p1.metaplay.io", testEnvironment)
+	if targetEnv.StackApiBaseURL != server.URL {
+		t.Errorf("StackApiBaseURL = %q, expected %q", targetEnv.StackApiBaseURL, server.URL)
+	}
+	if _, err := targetEnv.GetDetails(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetKubeConfigWithEmbeddedCredentials(t *testing.T) {
+	server, mock := testutil.NewMockStackAPI(t)
+	mock.KubeConfigs[testEnvironment] = testKubeConfig
+	targetEnv := testutil.NewTargetEnvironment(server, testEnvironment)
+
+	kubeConfig, err := targetEnv.GetKubeConfigWithEmbeddedCredentials()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := clientcmd.Load([]byte(kubeConfig))
+	if err != nil {
+		t.Fatalf("invalid kubeconfig: %v", err)
+	}
+	if parsed.CurrentContext != testEnvironment || parsed.AuthInfos["test-user"] == nil || parsed.AuthInfos["test-user"].Token != "test-token" {
+		t.Errorf("kubeconfig = %q, expected the fixture", kubeConfig)
+	}
+	if count := mock.CallCount(http.MethodPost, "/v0/credentials/tough-falcons/k8s"); count != 1 {
+		t.Errorf("got %d calls to the k8s credentials endpoint, expected 1", count)
+	}
+
+	// The Kubernetes client is created from the kubeconfig (without connecting) and cached.
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kubeCli.Namespace != testEnvironment || kubeCli.RestConfig.Host != "https://127.0.0.1:6443" || kubeCli.RestConfig.BearerToken != "test-token" {
+		t.Errorf("unexpected kube client: namespace=%q, host=%q", kubeCli.Namespace, kubeCli.RestConfig.Host)
+	}
+	if kubeCli.Clientset == nil || kubeCli.DynamicClient == nil || kubeCli.RestClient == nil {
+		t.Errorf("kube client is missing clients")
+	}
+	cachedCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil || cachedCli != kubeCli {
+		t.Errorf("expected the cached kube client, got %p (err %v)", cachedCli, err)
+	}
+	if count := mock.CallCount(http.MethodPost, "/v0/credentials/tough-falcons/k8s"); count != 2 {
+		t.Errorf("got %d calls to the k8s credentials endpoint, expected 2", count)
+	}
+
+	// Invalid kubeconfig.
+	mock.KubeConfigs["broken-env"] = "not: [a kubeconfig"
+	brokenEnv := testutil.NewTargetEnvironment(server, "broken-env")
+	if _, err := brokenEnv.GetPrimaryKubeClient(); err == nil {
+		t.Errorf("expected an error for an invalid kubeconfig")
+	}
+
+	// Missing credentials.
+	mock.SetError(http.MethodPost, "/v0/credentials/tough-falcons/k8s", http.StatusForbidden, "access denied")
+	if _, err := targetEnv.GetKubeConfigWithEmbeddedCredentials(); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected an access denied error, got: %v", err)
+	}
+}
+
+func TestGetKubeConfigWithExecCredential(t *testing.T) {
+	server, mock := testutil.NewMockStackAPI(t)
+	mock.KubeExecCredentials[testEnvironment] = envapi.KubeExecCredential{
+		ApiVersion: "client.authentication.k8s.io/v1beta1",
+		Kind:       "ExecCredential",
+		Spec: clientauthentication.ExecCredentialSpec{
+			Cluster: &clientauthentication.Cluster{
+				Server:                   "https://127.0.0.1:6443",
+				CertificateAuthorityData: []byte("test-ca"),
+			},
+		},
+	}
+	targetEnv := testutil.NewTargetEnvironment(server, testEnvironment)
+
+	kubeConfigStr, err := targetEnv.GetKubeConfigWithExecCredential("user@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeConfig, err := clientcmd.Load([]byte(kubeConfigStr))
+	if err != nil {
+		t.Fatalf("invalid kubeconfig: %v", err)
+	}
+	if kubeConfig.CurrentContext != testEnvironment || len(kubeConfig.AuthInfos) != 1 || kubeConfig.AuthInfos["user@example.com"] == nil {
+		t.Fatalf("unexpected kubeconfig: %+v", kubeConfig)
+	}
+	if cluster := kubeConfig.Clusters["https://127.0.0.1:6443"]; cluster == nil || string(cluster.CertificateAuthorityData) != "test-ca" {
+		t.Errorf("unexpected clusters: %+v", kubeConfig.Clusters)
+	}
+	exec := kubeConfig.AuthInfos["user@example.com"].Exec
+	if exec == nil {
+		t.Fatalf("user has no exec config")
+	}
+	if exec.Command != "metaplay" || strings.Join(exec.Args, " ") != "get kubernetes-execcredential tough-falcons "+server.URL {
+		t.Errorf("unexpected exec command: %s %v", exec.Command, exec.Args)
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 1 || calls[0].Query != "type=execcredential" {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+
+	// The raw exec credential.
+	credential, err := targetEnv.GetKubeExecCredential()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var parsed envapi.KubeExecCredential
+	if err := json.Unmarshal([]byte(*credential), &parsed); err != nil || parsed.Spec.Cluster.Server != "https://127.0.0.1:6443" {
+		t.Errorf("unexpected exec credential %q (err %v)", *credential, err)
+	}
+
+	// Credential without the cluster info.
+	mock.KubeExecCredentials["no-cluster"] = envapi.KubeExecCredential{Spec: clientauthentication.ExecCredentialSpec{Cluster: &clientauthentication.Cluster{}}}
+	if _, err := testutil.NewTargetEnvironment(server, "no-cluster").GetKubeConfigWithExecCredential("user"); err == nil {
+		t.Errorf("expected an error for a credential without cluster info")
+	}
+}
+
+func TestGetAWSCredentials(t *testing.T) {
+	server, mock := testutil.NewMockStackAPI(t)
+	mock.AWSCredentials[testEnvironment] = envapi.AWSCredentials{
+		AccessKeyID:     "AKIAMOCK",
+		SecretAccessKey: "mock-secret",
+		SessionToken:    "mock-session",
+	}
+	mock.AWSCredentials["no-key-id"] = envapi.AWSCredentials{SecretAccessKey: "mock-secret"}
+	mock.AWSCredentials["no-secret"] = envapi.AWSCredentials{AccessKeyID: "AKIAMOCK"}
+	targetEnv := testutil.NewTargetEnvironment(server, testEnvironment)
+
+	creds, err := targetEnv.GetAWSCredentials()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessKeyID != "AKIAMOCK" || creds.SecretAccessKey != "mock-secret" || creds.SessionToken != "mock-session" || creds.Version != 1 {
+		t.Errorf("unexpected credentials: %+v", creds)
+	}
+
+	for _, humanID := range []string{"no-key-id", "no-secret", "missing-env"} {
+		if _, err := testutil.NewTargetEnvironment(server, humanID).GetAWSCredentials(); err == nil {
+			t.Errorf("%s: expected an error", humanID)
+		}
+	}
+}
+
+func TestGetDockerCredentials(t *testing.T) {
+	server, mock := testutil.NewMockStackAPI(t)
+	testutil.UseMockECR(t, server)
+	mock.AWSCredentials[testEnvironment] = envapi.AWSCredentials{
+		AccessKeyID:     "AKIAMOCK",
+		SecretAccessKey: "mock-secret",
+	}
+	targetEnv := testutil.NewTargetEnvironment(server, testEnvironment)
+	details := newTestDeploymentSecret()
+
+	creds, err := targetEnv.GetDockerCredentials(&details)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.Username != "AWS" || creds.Password != "mock-ecr-password" {
+		t.Errorf("unexpected username/password: %s/%s", creds.Username, creds.Password)
+	}
+	if creds.RegistryHost() != "000000000000.dkr.ecr.eu-west-1.amazonaws.com" {
+		t.Errorf("registry host = %q", creds.RegistryHost())
+	}
+	if !creds.ExpiresAt.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expires at = %v", creds.ExpiresAt)
+	}
+
+	// Fails if the AWS credentials can't be fetched.
+	mock.SetError(http.MethodPost, "/v0/credentials/tough-falcons/aws", http.StatusForbidden, "access denied")
+	if _, err := targetEnv.GetDockerCredentials(&details); err == nil || !strings.Contains(err.Error(), "failed to get AWS credentials") {
+		t.Errorf("expected an AWS credentials error, got: %v", err)
+	}
+}
+
+func TestRuntimeOptions(t *testing.T) {
+	server, mock := testutil.NewMockStackAPI(t)
+	mock.RuntimeOptions[testEnvironment] = []envapi.RuntimeOption{
+		{Key: "Player:MaxNameLength", Value: float64(20), Type: envapi.RuntimeOptionTypeInt},
+		{Key: "System:MaintenanceMode", Value: false, Type: envapi.RuntimeOptionTypeBool, RequiresRestart: true},
+	}
+	targetEnv := testutil.NewTargetEnvironment(server, testEnvironment)
+
+	options, err := targetEnv.GetRuntimeOptions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(options) != 2 || options[0].Key != "Player:MaxNameLength" {
+		t.Fatalf("unexpected options: %+v", options)
+	}
+
+	options, err = targetEnv.SetRuntimeOptions(map[string]any{"Player:MaxNameLength": 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if options[0].Value != float64(30) {
+		t.Errorf("updated value = %v, expected 30", options[0].Value)
+	}
+
+	calls := mock.Calls()
+	lastCall := calls[len(calls)-1]
+	if lastCall.Method != http.MethodPut || !strings.Contains(string(lastCall.Body), `"Player:MaxNameLength":30`) {
+		t.Errorf("unexpected update request: %s %s", lastCall.Method, lastCall.Body)
+	}
+
+	if _, err := targetEnv.SetRuntimeOptions(map[string]any{"Unknown:Option": 1}); err == nil || !strings.Contains(err.Error(), "unknown runtime option") {
+		t.Errorf("expected an unknown option error, got: %v", err)
+	}
+}
+
+func TestLocallyEmulatedRuntimeOptions(t *testing.T) {
+	options := []envapi.RuntimeOption{
+		{Key: "Player:MaxNameLength", Value: float64(20), Type: envapi.RuntimeOptionTypeInt},
+		{Key: "FeatureFlags:EnableGuilds", Value: true, Type: envapi.RuntimeOptionTypeBool},
+		{Key: "Database:Backend", Value: "MySql"},
+		{Key: "Clustering:Mode", Value: "Kubernetes"},
+		{Key: "Game:Store:ApiKey", Value: "abc"},
+		{Key: "Game:Store:Credentials", Value: "aws-sm://store-credentials"},
+		{Key: "Game:Store:Currency", Value: "Gems"},
+	}
+
+	emulated, gaps := envapi.SplitLocallyEmulatedRuntimeOptions(options)
+	if len(emulated) != 3 {
+		t.Fatalf("expected 3 emulated options, got: %+v", emulated)
+	}
+	leftOut := map[string][]string{}
+	for _, gap := range gaps {
+		leftOut[gap.Name] = gap.Keys
+	}
+	if len(leftOut["Database"]) != 1 || len(leftOut["Cluster"]) != 1 || len(leftOut["Secrets"]) != 2 {
+		t.Errorf("unexpected gaps: %+v", gaps)
+	}
+
+	overlay, err := envapi.RenderRuntimeOptionsOverlay(emulated)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "FeatureFlags:\n    EnableGuilds: true\nGame:\n    Store:\n        Currency: Gems\nPlayer:\n    MaxNameLength: 20\n"
+	if string(overlay) != expected {
+		t.Errorf("unexpected overlay:\n%s", overlay)
+	}
+
+	if _, err := envapi.RenderRuntimeOptionsOverlay(append(emulated, envapi.RuntimeOption{Key: "Game:Store", Value: "x"})); err == nil {
+		t.Errorf("expected an error for conflicting options")
+	}
+}
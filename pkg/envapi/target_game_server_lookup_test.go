@@ -0,0 +1,189 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// Create a gameserver CR of the new operator with the given shard sets.
+func newTestGameServerNewCR(name string, shardNames ...string) *unstructured.Unstructured {
+	shards := []interface{}{}
+	for _, shardName := range shardNames {
+		shards = append(shards, map[string]interface{}{"name": shardName})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gameservers.metaplay.io/v0",
+		"kind":       "GameServer",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "tough-falcons"},
+		"spec": map[string]interface{}{
+			"image":  map[string]interface{}{"repository": "tough-falcons", "tag": "364cff09"},
+			"shards": shards,
+		},
+	}}
+}
+
+// Create a gameserver CR of the old operator with the given shard sets.
+func newTestGameServerOldCR(name string, shardNames ...string) *unstructured.Unstructured {
+	shards := []interface{}{}
+	for _, shardName := range shardNames {
+		shards = append(shards, map[string]interface{}{"name": shardName, "nodeCount": int64(1)})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metaplay.io/v1",
+		"kind":       "GameServer",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "tough-falcons"},
+		"spec": map[string]interface{}{
+			"shardSpec": shards,
+			"statefulSetSpec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{"name": "shard-server", "image": "tough-falcons:364cff09"}},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{"shardConfigMap": name + "-config"},
+	}}
+}
+
+// Create a TargetEnvironment whose primary Kubernetes client is a fake dynamic client with
+// the given objects.
+func newTestGameServerTarget(objects ...runtime.Object) (*TargetEnvironment, *dynamicfake.FakeDynamicClient) {
+	listKinds := map[schema.GroupVersionResource]string{
+		newGameServerGVR: "GameServerList",
+		oldGameServerGVR: "GameServerList",
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+	target := &TargetEnvironment{
+		HumanId: "tough-falcons",
+		primaryKubeClient: &KubeClient{
+			Namespace:     "tough-falcons",
+			DynamicClient: dynamicClient,
+		},
+	}
+	return target, dynamicClient
+}
+
+// Get the names of the shard sets of the game server.
+func getShardSetNames(gameServer *TargetGameServer) []string {
+	names := []string{}
+	for _, shardSet := range gameServer.ShardSets {
+		names = append(names, shardSet.Name)
+	}
+	return names
+}
+
+func TestGetGameServerNewCR(t *testing.T) {
+	ctx := context.Background()
+	target, _ := newTestGameServerTarget(newTestGameServerNewCR("gameserver", "all", "logic"))
+
+	gameServer, err := target.GetGameServer(ctx)
+	if err != nil {
+		t.Fatalf("failed to get game server: %v", err)
+	}
+	if gameServer.GameServerNewCR == nil || gameServer.GameServerOldCR != nil {
+		t.Fatalf("expected a new operator game server, got: %+v", gameServer)
+	}
+	if gameServer.GameServerNewCR.Name != "gameserver" || gameServer.GameServerNewCR.Spec.Image.Tag != "364cff09" {
+		t.Errorf("unexpected CR: name=%q, tag=%q", gameServer.GameServerNewCR.Name, gameServer.GameServerNewCR.Spec.Image.Tag)
+	}
+	if gameServer.Namespace != "tough-falcons" || len(gameServer.Clusters) != 1 {
+		t.Errorf("expected namespace tough-falcons with the primary cluster, got: %q with %d clusters", gameServer.Namespace, len(gameServer.Clusters))
+	}
+	if names := strings.Join(getShardSetNames(gameServer), ","); names != "all,logic" {
+		t.Errorf("shard sets = %q, expected %q", names, "all,logic")
+	}
+
+	// The game server is resolved only once.
+	again, err := target.GetGameServer(ctx)
+	if err != nil || again != gameServer {
+		t.Errorf("expected the same game server on the second call, got: %p vs %p, err: %v", again, gameServer, err)
+	}
+}
+
+func TestGetGameServerOldCR(t *testing.T) {
+	target, _ := newTestGameServerTarget(newTestGameServerOldCR("gameserver", "all"))
+
+	gameServer, err := target.GetGameServer(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get game server: %v", err)
+	}
+	if gameServer.GameServerOldCR == nil || gameServer.GameServerNewCR != nil {
+		t.Fatalf("expected an old operator game server, got: %+v", gameServer)
+	}
+	if gameServer.GameServerOldCR.Metadata.Name != "gameserver" || gameServer.GameServerOldCR.Status.ShardConfigMap != "gameserver-config" {
+		t.Errorf("unexpected CR: name=%q, shardConfigMap=%q", gameServer.GameServerOldCR.Metadata.Name, gameServer.GameServerOldCR.Status.ShardConfigMap)
+	}
+	if names := strings.Join(getShardSetNames(gameServer), ","); names != "all" {
+		t.Errorf("shard sets = %q, expected %q", names, "all")
+	}
+	if gameServer.ShardSets[0].Cluster != &gameServer.Clusters[0] {
+		t.Errorf("expected the shard set to be on the primary cluster")
+	}
+}
+
+func TestGetGameServerNotFound(t *testing.T) {
+	target, _ := newTestGameServerTarget()
+
+	_, err := target.GetGameServer(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "neither old nor new gameserver CR found") {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
+
+func TestGetGameServerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		failList *schema.GroupVersionResource // List requests of this resource fail.
+		contains string
+	}{
+		{"new CR list fails", nil, &newGameServerGVR, "failed to fetch CR group=gameservers.metaplay.io"},
+		{"old CR list fails", nil, &oldGameServerGVR, "failed to fetch CR group=metaplay.io"},
+		{"multiple new CRs", []runtime.Object{newTestGameServerNewCR("first"), newTestGameServerNewCR("second")}, nil, "multiple Kubernetes GameServer CRs found"},
+		{"multiple old CRs", []runtime.Object{newTestGameServerOldCR("first"), newTestGameServerOldCR("second")}, nil, "multiple Kubernetes GameServer CRs found"},
+	}
+
+	for _, test := range tests {
+		target, dynamicClient := newTestGameServerTarget(test.objects...)
+		if test.failList != nil {
+			failList := *test.failList
+			dynamicClient.PrependReactor("list", "gameservers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetResource() == failList {
+					return true, nil, errors.New("connection refused")
+				}
+				return false, nil, nil
+			})
+		}
+
+		gameServer, err := target.GetGameServer(context.Background())
+		if err == nil || !strings.Contains(err.Error(), test.contains) {
+			t.Errorf("%s: error = %v, expected it to contain %q", test.name, err, test.contains)
+		}
+		if gameServer != nil || target.targetGameServer != nil {
+			t.Errorf("%s: expected no game server on failure", test.name)
+		}
+	}
+}
+
+// The CRs are looked up in the environment's namespace only.
+func TestGetGameServerOtherNamespace(t *testing.T) {
+	other := newTestGameServerNewCR("gameserver", "all")
+	other.SetNamespace("other-environment")
+	target, _ := newTestGameServerTarget(other)
+
+	if _, err := target.GetGameServer(context.Background()); err == nil {
+		t.Errorf("expected the game server in another namespace not to be found")
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Create a StatefulSet for the shard set with the given desired and ready replica counts.
+func newTestStatefulSet(name string, uid types.UID, desired int32, ready int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tough-falcons", UID: uid},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &desired},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas:   ready,
+			UpdatedReplicas: ready,
+			CurrentRevision: name + "-1",
+			UpdateRevision:  name + "-1",
+		},
+	}
+}
+
+// Create a game server pod owned by the given StatefulSet.
+func newTestGameServerPod(name string, ownerUID types.UID) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "tough-falcons",
+		Labels:          map[string]string{"app": "metaplay-server"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: name, UID: ownerUID}},
+	}}
+}
+
+// Resolve a game server (with the new CR and the given shard sets) whose primary cluster
+// has the given Kubernetes objects.
+func newTestTargetGameServer(t *testing.T, shardNames []string, objects ...runtime.Object) *TargetGameServer {
+	target, _ := newTestGameServerTarget(newTestGameServerNewCR("gameserver", shardNames...))
+	target.primaryKubeClient.Clientset = fake.NewClientset(objects...)
+	gameServer, err := target.GetGameServer(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get game server: %v", err)
+	}
+	return gameServer
+}
+
+func TestGameServerShardSetPods(t *testing.T) {
+	gameServer := newTestTargetGameServer(t, []string{"all", "logic"},
+		newTestStatefulSet("all", "all-uid", 2, 2),
+		newTestStatefulSet("logic", "logic-uid", 1, 1),
+		newTestGameServerPod("all-0", "all-uid"),
+		newTestGameServerPod("all-1", "all-uid"),
+		newTestGameServerPod("logic-0", "logic-uid"),
+		newTestGameServerPod("orphan-0", "orphan-uid"),
+	)
+
+	shardSetWithPods, err := gameServer.GetShardSetWithPods("all")
+	if err != nil {
+		t.Fatalf("failed to get shard set pods: %v", err)
+	}
+	if len(shardSetWithPods.Pods) != 2 || shardSetWithPods.ShardSet.Name != "all" {
+		t.Errorf("expected the two pods of shard set 'all', got: %+v", shardSetWithPods)
+	}
+	if _, err := gameServer.GetShardSetWithPods("missing"); err == nil {
+		t.Errorf("expected a missing shard set to fail")
+	}
+
+	allShardSets, err := gameServer.GetAllShardSetsWithPods()
+	if err != nil {
+		t.Fatalf("failed to get all shard set pods: %v", err)
+	}
+	if len(allShardSets) != 2 || len(allShardSets[0].Pods) != 2 || len(allShardSets[1].Pods) != 1 {
+		t.Errorf("unexpected shard sets with pods: %+v", allShardSets)
+	}
+
+	kubeCli, pod, err := gameServer.GetPod("logic-0")
+	if err != nil || kubeCli == nil || pod.Name != "logic-0" {
+		t.Errorf("failed to get pod logic-0: %v, err: %v", pod, err)
+	}
+	for _, podName := range []string{"logic", "missing-0", "logic-5"} {
+		if _, _, err := gameServer.GetPod(podName); err == nil {
+			t.Errorf("expected getting pod %q to fail", podName)
+		}
+	}
+}
+
+func TestGameServerShardSetScaling(t *testing.T) {
+	ctx := context.Background()
+	gameServer := newTestTargetGameServer(t, []string{"all"}, newTestStatefulSet("all", "all-uid", 3, 2))
+
+	scaling, err := gameServer.GetShardSetScaling("all")
+	if err != nil || scaling.Name != "all" || scaling.NodeCount != nil {
+		t.Errorf("unexpected scaling for shard set 'all': %+v, err: %v", scaling, err)
+	}
+	if _, err := gameServer.GetShardSetScaling("missing"); err == nil {
+		t.Errorf("expected a missing shard set to fail")
+	}
+
+	replicas, err := gameServer.GetShardSetReplicas(ctx, "all")
+	if err != nil || replicas.Desired != 3 || replicas.Ready != 2 {
+		t.Errorf("unexpected replicas for shard set 'all': %+v, err: %v", replicas, err)
+	}
+
+	// Scaling patches the node count in the gameserver CR.
+	if err := gameServer.ScaleShardSet(ctx, "all", 5); err != nil {
+		t.Fatalf("failed to scale shard set: %v", err)
+	}
+	if err := gameServer.ScaleShardSet(ctx, "missing", 5); err == nil {
+		t.Errorf("expected scaling a missing shard set to fail")
+	}
+	kubeCli := gameServer.Clusters[0].KubeClient
+	cr, err := kubeCli.DynamicClient.Resource(newGameServerGVR).Namespace("tough-falcons").Get(ctx, "gameserver", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := cr.Object["spec"].(map[string]interface{})["shards"].([]interface{})
+	if nodeCount := shards[0].(map[string]interface{})["nodeCount"]; nodeCount != int64(5) {
+		t.Errorf("expected nodeCount 5 in the gameserver CR, got: %v", nodeCount)
+	}
+
+	// The StatefulSet never becomes fully ready.
+	if err := gameServer.WaitForShardSetReplicas(ctx, "all", 3, 10*time.Millisecond); err == nil {
+		t.Errorf("expected waiting for the replicas to time out")
+	}
+	if err := gameServer.WaitForShardSetReplicas(ctx, "all", 2, 10*time.Millisecond); err == nil {
+		t.Errorf("expected waiting for the wrong number of replicas to time out")
+	}
+}
+
+func TestGameServerRestartShardSets(t *testing.T) {
+	ctx := context.Background()
+	gameServer := newTestTargetGameServer(t, []string{"all"}, newTestStatefulSet("all", "all-uid", 1, 1))
+
+	if err := gameServer.RestartShardSets(ctx); err != nil {
+		t.Fatalf("failed to restart shard sets: %v", err)
+	}
+	kubeCli := gameServer.Clusters[0].KubeClient
+	statefulSet, err := kubeCli.Clientset.AppsV1().StatefulSets("tough-falcons").Get(ctx, "all", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := statefulSet.Spec.Template.Annotations[restartedAtAnnotation]; !ok {
+		t.Errorf("expected the %s annotation on the pod template", restartedAtAnnotation)
+	}
+
+	// The fake StatefulSet status is already up-to-date.
+	if err := gameServer.WaitForShardSetRollouts(ctx, time.Second); err != nil {
+		t.Errorf("failed to wait for rollouts: %v", err)
+	}
+}
+
+func TestIsStatefulSetRolledOut(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(statefulSet *appsv1.StatefulSet)
+		want   bool
+	}{
+		{"rolled out", func(statefulSet *appsv1.StatefulSet) {}, true},
+		{"generation not observed", func(statefulSet *appsv1.StatefulSet) { statefulSet.Generation = 2 }, false},
+		{"pods not updated", func(statefulSet *appsv1.StatefulSet) { statefulSet.Status.UpdatedReplicas = 1 }, false},
+		{"pods not ready", func(statefulSet *appsv1.StatefulSet) { statefulSet.Status.ReadyReplicas = 1 }, false},
+		{"revision not current", func(statefulSet *appsv1.StatefulSet) { statefulSet.Status.UpdateRevision = "all-2" }, false},
+		{"default replicas", func(statefulSet *appsv1.StatefulSet) {
+			statefulSet.Spec.Replicas = nil
+			statefulSet.Status.ReadyReplicas = 1
+			statefulSet.Status.UpdatedReplicas = 1
+		}, true},
+	}
+
+	for _, test := range tests {
+		statefulSet := newTestStatefulSet("all", "all-uid", 2, 2)
+		test.modify(statefulSet)
+		if got := isStatefulSetRolledOut(statefulSet); got != test.want {
+			t.Errorf("%s: isStatefulSetRolledOut() = %v, expected %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Package testutil provides test helpers for code that uses a TargetEnvironment, most notably
+// a mock StackAPI server with configurable responses.
+package testutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/metahttp"
+)
+
+// Access token that the mock StackAPI expects the requests to be authorized with.
+const MockAccessToken = "mock-access-token"
+
+// Value of the X-Amz-Target header of ECR GetAuthorizationToken requests.
+const ecrGetAuthorizationTokenTarget = "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken"
+
+// Request received by the MockStackAPI.
+type RecordedCall struct {
+	Method        string // HTTP method, eg, 'GET'.
+	Path          string // URL path, eg, '/v0/deployments/tough-falcons'.
+	Query         string // Raw URL query, eg, 'type=execcredential'.
+	Authorization string // Value of the Authorization header.
+	Body          []byte // Request body.
+}
+
+// Error response to return for a route instead of the fixture.
+type mockError struct {
+	statusCode int
+	message    string
+}
+
+// Mock implementation of the StackAPI endpoints used by TargetEnvironment. The fixtures are
+// keyed by the environment human ID and can be modified directly by the tests, before any
+// requests are made. Requests for environments without a fixture get a 404 response.
+//
+// The mock also serves the ECR GetAuthorizationToken API used by GetDockerCredentials(),
+// see UseMockECR().
+type MockStackAPI struct {
+	Deployments         map[string]envapi.DeploymentSecret   // Responses for GET /v0/deployments/<env>.
+	KubeConfigs         map[string]string                    // Responses for POST /v0/credentials/<env>/k8s.
+	KubeExecCredentials map[string]envapi.KubeExecCredential // Responses for POST /v0/credentials/<env>/k8s?type=execcredential.
+	AWSCredentials      map[string]envapi.AWSCredentials     // Responses for POST /v0/credentials/<env>/aws.
+	RuntimeOptions      map[string][]envapi.RuntimeOption    // Responses for GET and PUT /v0/deployments/<env>/runtimeOptions.
+
+	ECRUsername      string    // Username in the ECR authorization token.
+	ECRPassword      string    // Password in the ECR authorization token.
+	ECRProxyEndpoint string    // Registry URL returned by ECR.
+	ECRExpiresAt     time.Time // Expiration time of the ECR authorization token.
+
+	mu     sync.Mutex
+	calls  []RecordedCall
+	errors map[string]mockError
+}
+
+// Start a mock StackAPI server, closed automatically at the end of the test.
+func NewMockStackAPI(t *testing.T) (*httptest.Server, *MockStackAPI) {
+	mock := &MockStackAPI{
+		Deployments:         map[string]envapi.DeploymentSecret{},
+		KubeConfigs:         map[string]string{},
+		KubeExecCredentials: map[string]envapi.KubeExecCredential{},
+		AWSCredentials:      map[string]envapi.AWSCredentials{},
+		RuntimeOptions:      map[string][]envapi.RuntimeOption{},
+		ECRUsername:         "AWS",
+		ECRPassword:         "mock-ecr-password",
+		ECRProxyEndpoint:    "https://000000000000.dkr.ecr.eu-west-1.amazonaws.com",
+		ECRExpiresAt:        time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		errors:              map[string]mockError{},
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return server, mock
+}
+
+// Create a TargetEnvironment for the environment that talks to the mock StackAPI server.
+func NewTargetEnvironment(server *httptest.Server, humanID string) *envapi.TargetEnvironment {
+	tokenSet := &auth.TokenSet{AccessToken: MockAccessToken}
+	return &envapi.TargetEnvironment{
+		TokenSet:        tokenSet,
+		StackApiBaseURL: server.URL,
+		HumanId:         humanID,
+		StackApiClient:  metahttp.NewClient(tokenSet, server.URL),
+	}
+}
+
+// Route AWS ECR requests to the mock server for the duration of the test, and isolate the
+// AWS SDK from the user's AWS configuration.
+func UseMockECR(t *testing.T, server *httptest.Server) {
+	noFile := filepath.Join(t.TempDir(), "none")
+	t.Setenv("AWS_ENDPOINT_URL_ECR", server.URL)
+	t.Setenv("AWS_CONFIG_FILE", noFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", noFile)
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_PROFILE", "")
+	os.Unsetenv("AWS_PROFILE")
+}
+
+// Respond to requests with the method and path (without the query) with an error, instead
+// of the fixture.
+func (m *MockStackAPI) SetError(method string, path string, statusCode int, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[method+" "+path] = mockError{statusCode: statusCode, message: message}
+}
+
+// Get all the requests received so far, in order.
+func (m *MockStackAPI) Calls() []RecordedCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]RecordedCall{}, m.calls...)
+}
+
+// Count the requests received with the method and path (without the query).
+func (m *MockStackAPI) CallCount(method string, path string) int {
+	count := 0
+	for _, call := range m.Calls() {
+		if call.Method == method && call.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *MockStackAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	m.mu.Lock()
+	m.calls = append(m.calls, RecordedCall{
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Query:         r.URL.RawQuery,
+		Authorization: r.Header.Get("Authorization"),
+		Body:          body,
+	})
+	mockErr, hasErr := m.errors[r.Method+" "+r.URL.Path]
+	m.mu.Unlock()
+
+	if hasErr {
+		writeJSON(w, mockErr.statusCode, metahttp.APIError{Code: http.StatusText(mockErr.statusCode), Message: mockErr.message})
+		return
+	}
+
+	// ECR requests are identified by their target header, as they are all POSTs to '/'.
+	if r.Header.Get("X-Amz-Target") == ecrGetAuthorizationTokenTarget {
+		m.serveECRAuthorizationToken(w)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+MockAccessToken {
+		writeJSON(w, http.StatusUnauthorized, metahttp.APIError{Code: "Unauthorized", Message: "invalid access token"})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(segments) == 3 && segments[0] == "v0" && segments[1] == "deployments":
+		serveFixture(w, m.Deployments, segments[2])
+
+	case (r.Method == http.MethodGet || r.Method == http.MethodPut) && len(segments) == 4 && segments[0] == "v0" && segments[1] == "deployments" && segments[3] == "runtimeOptions":
+		if r.Method == http.MethodPut && !m.updateRuntimeOptions(w, segments[2], body) {
+			return
+		}
+		serveFixture(w, m.RuntimeOptions, segments[2])
+
+	case r.Method == http.MethodPost && len(segments) == 4 && segments[0] == "v0" && segments[1] == "credentials" && segments[3] == "k8s":
+		if r.URL.Query().Get("type") == "execcredential" {
+			serveFixture(w, m.KubeExecCredentials, segments[2])
+		} else if kubeConfig, found := m.KubeConfigs[segments[2]]; found {
+			w.Header().Set("Content-Type", "application/yaml")
+			_, _ = io.WriteString(w, kubeConfig)
+		} else {
+			writeNotFound(w, segments[2])
+		}
+
+	case r.Method == http.MethodPost && len(segments) == 4 && segments[0] == "v0" && segments[1] == "credentials" && segments[3] == "aws":
+		serveFixture(w, m.AWSCredentials, segments[2])
+
+	default:
+		writeJSON(w, http.StatusNotFound, metahttp.APIError{Code: "NotFound", Message: fmt.Sprintf("no mock route for %s %s", r.Method, r.URL.Path)})
+	}
+}
+
+// Apply a runtime options update request to the fixture. Returns false if an error response
+// was written.
+func (m *MockStackAPI) updateRuntimeOptions(w http.ResponseWriter, humanID string, body []byte) bool {
+	options, found := m.RuntimeOptions[humanID]
+	if !found {
+		writeNotFound(w, humanID)
+		return false
+	}
+	var request struct {
+		Options map[string]any `json:"options"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeJSON(w, http.StatusBadRequest, metahttp.APIError{Code: "BadRequest", Message: err.Error()})
+		return false
+	}
+	for key, value := range request.Options {
+		updated := false
+		for ndx := range options {
+			if options[ndx].Key == key {
+				options[ndx].Value = value
+				updated = true
+			}
+		}
+		if !updated {
+			writeJSON(w, http.StatusBadRequest, metahttp.APIError{Code: "BadRequest", Message: fmt.Sprintf("unknown runtime option '%s'", key)})
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MockStackAPI) serveECRAuthorizationToken(w http.ResponseWriter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token := base64.StdEncoding.EncodeToString([]byte(m.ECRUsername + ":" + m.ECRPassword))
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"authorizationData": []map[string]any{
+			{
+				"authorizationToken": token,
+				"proxyEndpoint":      m.ECRProxyEndpoint,
+				"expiresAt":          m.ECRExpiresAt.Unix(),
+			},
+		},
+	})
+}
+
+// Respond with the fixture of the environment, or 404 if there is none.
+func serveFixture[T any](w http.ResponseWriter, fixtures map[string]T, humanID string) {
+	fixture, found := fixtures[humanID]
+	if !found {
+		writeNotFound(w, humanID)
+		return
+	}
+	writeJSON(w, http.StatusOK, fixture)
+}
+
+func writeNotFound(w http.ResponseWriter, humanID string) {
+	writeJSON(w, http.StatusNotFound, metahttp.APIError{Code: "NotFound", Message: fmt.Sprintf("environment '%s' not found", humanID)})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(value)
+}
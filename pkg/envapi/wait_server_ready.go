@@ -11,10 +11,13 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/metaplay/cli/internal/tui"
+	"github.com/metaplay/cli/pkg/styles"
 	"github.com/rs/zerolog/log"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -292,18 +295,29 @@ func isGameServerReady(ctx context.Context, kubeCli *KubeClient, gameServer *Tar
 	}
 
 	// Check that all pods belonging to all shards are ready.
+	// Iterate the shard sets in a stable order to avoid the output jumping around.
 	allPodsReady := true
 	statusLines := []string{}
-	for shardSetName, shardSetPods := range podsByShard {
+	shardSetNames := make([]string, 0, len(podsByShard))
+	for shardSetName := range podsByShard {
+		shardSetNames = append(shardSetNames, shardSetName)
+	}
+	sort.Strings(shardSetNames)
+	for _, shardSetName := range shardSetNames {
+		shardSetPods := podsByShard[shardSetName]
+
 		// Check that all expected pods are found.
-		statusLines = append(statusLines, fmt.Sprintf("  ShardSet '%s' pods (%d):", shardSetName, len(shardSetPods)))
+		podLines := []string{}
+		numReady := 0
 		for podNdx, pod := range shardSetPods {
 			// Check that the pod is healthy & ready.
 			podName := fmt.Sprintf("%s-%d", shardSetName, podNdx)
 			if pod != nil {
 				status := resolvePodStatus(*pod)
-				statusLines = append(statusLines, fmt.Sprintf("    %s: %s [%s]", podName, status.Phase, status.Message))
-				if status.Phase != PhaseReady {
+				podLines = append(podLines, fmt.Sprintf("    %s: %s %s", podName, renderPodPhase(status.Phase), styles.RenderMuted(status.Message)))
+				if status.Phase == PhaseReady {
+					numReady++
+				} else {
 					allPodsReady = false
 				}
 
@@ -327,10 +341,13 @@ func isGameServerReady(ctx context.Context, kubeCli *KubeClient, gameServer *Tar
 					return false, nil, fmt.Errorf("pod %s failed to deploy (see above for logs and details)", podName)
 				}
 			} else {
-				statusLines = append(statusLines, fmt.Sprintf("    %s: not found", podName))
+				podLines = append(podLines, fmt.Sprintf("    %s: %s", podName, styles.RenderMuted("not found")))
 				allPodsReady = false
 			}
 		}
+
+		statusLines = append(statusLines, fmt.Sprintf("  ShardSet '%s' pods (%d/%d ready):", shardSetName, numReady, len(shardSetPods)))
+		statusLines = append(statusLines, podLines...)
 	}
 
 	// For the new game server, also check the CR status.
@@ -348,6 +365,7 @@ func isGameServerReady(ctx context.Context, kubeCli *KubeClient, gameServer *Tar
 }
 
 // waitForGameServerReady waits until the gameserver in a namespace is ready or a timeout occurs.
+// The game server pods are watched for changes so the pod states shown are updated live.
 func (targetEnv *TargetEnvironment) waitForGameServerReady(ctx context.Context, output *tui.TaskOutput, timeout time.Duration) error {
 	// Get target gameServer.
 	gameServer, err := targetEnv.GetGameServer(ctx)
@@ -355,20 +373,46 @@ func (targetEnv *TargetEnvironment) waitForGameServerReady(ctx context.Context,
 		return err
 	}
 
-	// Keep checking the gameservers until they are ready, or timeout is hit.
-	startTime := time.Now()
-	for time.Since(startTime) < timeout {
-		// Get kube client for primary cluster.
-		kubeCli, err := targetEnv.GetPrimaryKubeClient()
-		if err != nil {
-			return err
-		}
+	// Must have either old or new CR.
+	if gameServer.GameServerNewCR == nil && gameServer.GameServerOldCR == nil {
+		return fmt.Errorf("only new or old CR must be defined, not both")
+	}
 
-		// Must have either old or new CR.
-		if gameServer.GameServerNewCR == nil && gameServer.GameServerOldCR == nil {
-			return fmt.Errorf("only new or old CR must be defined, not both")
-		}
+	// Get kube client for primary cluster.
+	kubeCli, err := targetEnv.GetPrimaryKubeClient()
+	if err != nil {
+		return err
+	}
 
+	// Watch the pods for changes. If watching fails, fall back to polling only.
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+	podChanges, err := watchGameServerPods(watchCtx, kubeCli)
+	if err != nil {
+		log.Debug().Msgf("Unable to watch game server pods, falling back to polling: %v", err)
+	}
+
+	// Also poll periodically, as not all changes (eg, to the StatefulSets) are visible as pod
+	// events. Poll slower in non-interactive mode to avoid spamming the log.
+	pollInterval := 2 * time.Second
+	if !tui.IsInteractive() {
+		pollInterval = 5 * time.Second
+	}
+	pollTicker := time.NewTicker(pollInterval)
+	defer pollTicker.Stop()
+
+	timeoutTimer := time.NewTimer(timeout)
+	defer timeoutTimer.Stop()
+
+	// Resolve status lines to show.
+	crVersion := "new"
+	if gameServer.GameServerOldCR != nil {
+		crVersion = "old"
+	}
+
+	// Keep checking the gameservers until they are ready, or timeout is hit.
+	var prevStatusLines []string
+	for {
 		// Get status of the deployment.
 		// \todo handle edge clusters (for new CR only)
 		isReady, statusLines, err := isGameServerReady(ctx, kubeCli, gameServer)
@@ -376,32 +420,35 @@ func (targetEnv *TargetEnvironment) waitForGameServerReady(ctx context.Context,
 			return err
 		}
 
-		// Resolve status lines to show.
-		crVersion := "new"
-		if gameServer.GameServerOldCR != nil {
-			crVersion = "old"
+		// Show the game server shard/pod states, if changed.
+		if !slices.Equal(statusLines, prevStatusLines) {
+			headerLines := append(
+				[]string{fmt.Sprintf("Game server pod states (%s CR):", crVersion)},
+				statusLines...,
+			)
+			output.SetHeaderLines(headerLines)
+			prevStatusLines = statusLines
 		}
-		headerLines := append(
-			[]string{fmt.Sprintf("Game server pod states (%s CR):", crVersion)},
-			statusLines...,
-		)
-
-		// Show the game server shard/pod states.
-		output.SetHeaderLines(headerLines)
 
 		// If gamserver is ready, we're done.
 		if isReady {
 			return nil
 		}
 
-		// Wait a bit to check again (slower updates in non-interactive mode to avoid spamming the log).
-		if tui.IsInteractiveMode() {
-			time.Sleep(200 * time.Millisecond)
-		} else {
-			time.Sleep(2 * time.Second)
+		// Wait for a pod to change, or the next poll.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeoutTimer.C:
+			return errors.New("timeout waiting for pods to be ready")
+		case _, ok := <-podChanges:
+			if !ok {
+				log.Debug().Msg("Game server pod watch ended, falling back to polling")
+				podChanges = nil
+			}
+		case <-pollTicker.C:
 		}
 	}
-	return errors.New("timeout waiting for pods to be ready")
 }
 
 // fetchPodLogs fetches logs for a specific pod and container.
@@ -596,50 +643,70 @@ func waitForHTTPServerToRespond(ctx context.Context, output *tui.TaskOutput, url
 	}
 }
 
-func (targetEnv *TargetEnvironment) WaitForServerToBeReady(ctx context.Context, taskRunner *tui.TaskRunner) error {
+// A single step of checking that a deployed game server is ready, see GetServerReadinessChecks().
+type ServerReadinessCheck struct {
+	Title string                             // Human-readable title of the check.
+	Run   func(output *tui.TaskOutput) error // Run the check, waits until the check passes or times out.
+}
+
+// Get the checks for a freshly deployed game server to be ready: the game server pods are
+// healthy, the domain names have propagated, and both the game server and the LiveOps
+// Dashboard serve traffic. The checks are intended to be run in order.
+func (targetEnv *TargetEnvironment) GetServerReadinessChecks(ctx context.Context) ([]ServerReadinessCheck, error) {
 	// Fetch environment details.
 	envDetails, err := targetEnv.GetDetails()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Wait for the gameserver Kubernetes resources to be ready.
-	// Only wait for a few minutes as pods generally become healthy fairly
-	// soon as we want to display the logs from errors early.
-	// This can take a long time when larger changes are being applied (eg,
-	// enabling the new operator).
-	taskRunner.AddTask("Wait for game server pods to be ready", func(output *tui.TaskOutput) error {
-		return targetEnv.waitForGameServerReady(ctx, output, 10*time.Minute)
-	})
-
-	// CHECK CLIENT-FACING NETWORKING
-
 	serverPrimaryAddress := envDetails.Deployment.ServerHostname
 	serverPrimaryPort := 9339 // \todo should use envDetails.Deployment.ServerPorts but its occasionally empty
 	log.Debug().Msgf("envDetails.Deployment.ServerPorts: %+v", envDetails.Deployment.ServerPorts)
 
-	// Wait for the primary domain name to resolve to an IP address.
-	taskRunner.AddTask("Wait for game server domain name to propagate", func(output *tui.TaskOutput) error {
-		return waitForDomainResolution(output, serverPrimaryAddress, 15*time.Minute)
-	})
-
-	// Wait for server to respond to client traffic.
-	taskRunner.AddTask("Wait for game server to serve clients", func(output *tui.TaskOutput) error {
-		return waitForGameServerClientEndpointToBeReady(ctx, output, serverPrimaryAddress, serverPrimaryPort, 5*time.Minute)
-	})
-
-	// CHECK ADMIN INTERFACE
-
-	// Wait for the admin domain name to resolve to an IP address.
-	taskRunner.AddTask("Wait for LiveOps Dashboard domain name to propagate", func(output *tui.TaskOutput) error {
-		return waitForDomainResolution(output, envDetails.Deployment.AdminHostname, 15*time.Minute)
-	})
-
-	// Wait for admin API to successfully respond to an HTTP request.
-	taskRunner.AddTask("Wait for LiveOps Dashboard to serve traffic", func(output *tui.TaskOutput) error {
-		return waitForHTTPServerToRespond(ctx, output, "https://"+envDetails.Deployment.AdminHostname, 5*time.Minute)
-	})
+	return []ServerReadinessCheck{
+		// Wait for the gameserver Kubernetes resources to be ready.
+		// Only wait for a few minutes as pods generally become healthy fairly
+		// soon as we want to display the logs from errors early.
+		// This can take a long time when larger changes are being applied (eg,
+		// enabling the new operator).
+		{"Wait for game server pods to be ready", func(output *tui.TaskOutput) error {
+			return targetEnv.waitForGameServerReady(ctx, output, 10*time.Minute)
+		}},
+
+		// CHECK CLIENT-FACING NETWORKING
+
+		// Wait for the primary domain name to resolve to an IP address.
+		{"Wait for game server domain name to propagate", func(output *tui.TaskOutput) error {
+			return waitForDomainResolution(output, serverPrimaryAddress, 15*time.Minute)
+		}},
+
+		// Wait for server to respond to client traffic.
+		{"Wait for game server to serve clients", func(output *tui.TaskOutput) error {
+			return waitForGameServerClientEndpointToBeReady(ctx, output, serverPrimaryAddress, serverPrimaryPort, 5*time.Minute)
+		}},
+
+		// CHECK ADMIN INTERFACE
+
+		// Wait for the admin domain name to resolve to an IP address.
+		{"Wait for LiveOps Dashboard domain name to propagate", func(output *tui.TaskOutput) error {
+			return waitForDomainResolution(output, envDetails.Deployment.AdminHostname, 15*time.Minute)
+		}},
+
+		// Wait for admin API to successfully respond to an HTTP request.
+		{"Wait for LiveOps Dashboard to serve traffic", func(output *tui.TaskOutput) error {
+			return waitForHTTPServerToRespond(ctx, output, "https://"+envDetails.Deployment.AdminHostname, 5*time.Minute)
+		}},
+	}, nil
+}
 
-	// Success
+// Add the game server readiness checks (see GetServerReadinessChecks()) as tasks to the task runner.
+func (targetEnv *TargetEnvironment) WaitForServerToBeReady(ctx context.Context, taskRunner *tui.TaskRunner) error {
+	checks, err := targetEnv.GetServerReadinessChecks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, check := range checks {
+		taskRunner.AddTask(check.Title, check.Run)
+	}
 	return nil
 }
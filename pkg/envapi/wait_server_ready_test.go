@@ -0,0 +1,137 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Create a game server StatefulSet running the given image.
+func newTestShardSetStatefulSet(name string, replicas int32, image string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tough-falcons", Labels: map[string]string{"app": "metaplay-server"}},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "shard-server", Image: image}},
+			}},
+		},
+		Status: appsv1.StatefulSetStatus{UpdateRevision: name + "-1"},
+	}
+}
+
+// Create a game server pod of the StatefulSet with the given shard server container status.
+func newTestShardServerPod(name string, statefulSetName string, image string, status corev1.ContainerStatus) *corev1.Pod {
+	status.Name = "shard-server"
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "tough-falcons",
+			Labels:          map[string]string{"app": "metaplay-server", "controller-revision-hash": statefulSetName + "-1"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: statefulSetName}},
+		},
+		Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "shard-server", Image: image}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{status}},
+	}
+}
+
+var (
+	testContainerReady   = corev1.ContainerStatus{Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	testContainerRunning = corev1.ContainerStatus{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	testContainerWaiting = corev1.ContainerStatus{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}
+)
+
+func TestResolvePodStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []corev1.ContainerStatus
+		want     GameServerPodPhase
+	}{
+		{"no container statuses", nil, PhaseUnknown},
+		{"no shard server container", []corev1.ContainerStatus{{Name: "sidecar", Ready: true}}, PhaseUnknown},
+		{"ready", []corev1.ContainerStatus{{Name: "shard-server", Ready: true, State: testContainerReady.State}}, PhaseReady},
+		{"running", []corev1.ContainerStatus{{Name: "shard-server", State: testContainerRunning.State}}, PhaseRunning},
+		{"waiting", []corev1.ContainerStatus{{Name: "shard-server", State: testContainerWaiting.State}}, PhasePending},
+		{"crash loop", []corev1.ContainerStatus{{Name: "shard-server", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}}, PhaseFailed},
+		{"terminated", []corev1.ContainerStatus{{Name: "shard-server", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error"}}}}, PhaseFailed},
+		{"no state", []corev1.ContainerStatus{{Name: "shard-server"}}, PhaseUnknown},
+	}
+
+	for _, test := range tests {
+		pod := corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: test.statuses}}
+		if got := resolvePodStatus(pod); got.Phase != test.want {
+			t.Errorf("%s: resolvePodStatus() phase = %s (%s), expected %s", test.name, got.Phase, got.Message, test.want)
+		}
+	}
+}
+
+func TestIsGameServerReady(t *testing.T) {
+	const image = "tough-falcons:364cff09"
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		ready   bool
+	}{
+		{"no StatefulSets", nil, false},
+		{"all pods ready", []runtime.Object{
+			newTestShardSetStatefulSet("all", 2, image),
+			newTestShardServerPod("all-0", "all", image, testContainerReady),
+			newTestShardServerPod("all-1", "all", image, testContainerReady),
+		}, true},
+		{"pod not ready", []runtime.Object{
+			newTestShardSetStatefulSet("all", 2, image),
+			newTestShardServerPod("all-0", "all", image, testContainerReady),
+			newTestShardServerPod("all-1", "all", image, testContainerWaiting),
+		}, false},
+		{"pod missing", []runtime.Object{
+			newTestShardSetStatefulSet("all", 2, image),
+			newTestShardServerPod("all-0", "all", image, testContainerReady),
+		}, false},
+		{"pod with old image", []runtime.Object{
+			newTestShardSetStatefulSet("all", 1, image),
+			newTestShardServerPod("all-0", "all", "tough-falcons:0ld1mage", testContainerReady),
+		}, false},
+		{"pod of another StatefulSet", []runtime.Object{
+			newTestShardSetStatefulSet("all", 1, image),
+			newTestShardServerPod("all-0", "other", image, testContainerReady),
+		}, false},
+	}
+
+	for _, test := range tests {
+		kubeCli := &KubeClient{Namespace: "tough-falcons", Clientset: fake.NewClientset(test.objects...)}
+		gameServer := &TargetGameServer{Namespace: "tough-falcons", GameServerNewCR: &NewGameServerCR{}}
+		ready, statusLines, err := isGameServerReady(context.Background(), kubeCli, gameServer)
+		if err != nil {
+			t.Errorf("%s: failed to check readiness: %v", test.name, err)
+		} else if ready != test.ready || len(statusLines) == 0 {
+			t.Errorf("%s: isGameServerReady() = %v, expected %v (status: %v)", test.name, ready, test.ready, statusLines)
+		}
+	}
+}
+
+func TestIsGameServerReadyOldCR(t *testing.T) {
+	const image = "tough-falcons:364cff09"
+	owned := newTestShardSetStatefulSet("all", 1, image)
+	owned.OwnerReferences = []metav1.OwnerReference{{Kind: "GameServer", Name: "gameserver", UID: "gameserver-uid"}}
+	notOwned := newTestShardSetStatefulSet("other", 1, image)
+	kubeCli := &KubeClient{
+		Namespace: "tough-falcons",
+		Clientset: fake.NewClientset(owned, notOwned, newTestShardServerPod("all-0", "all", image, testContainerReady)),
+	}
+
+	// Only the StatefulSets owned by the old gameserver CR are checked.
+	oldCR := &OldGameServerCR{}
+	oldCR.Metadata.UID = "gameserver-uid"
+	ready, _, err := isGameServerReady(context.Background(), kubeCli, &TargetGameServer{Namespace: "tough-falcons", GameServerOldCR: oldCR})
+	if err != nil || !ready {
+		t.Errorf("expected the game server to be ready, got: %v, err: %v", ready, err)
+	}
+}
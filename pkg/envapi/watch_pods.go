@@ -0,0 +1,93 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package envapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Watch the game server pods in the namespace and signal any changes to them on the returned
+// channel. Bursts of events are coalesced into a single signal. The watch is re-established
+// when the API server closes it. The channel is closed when the context is cancelled or the
+// watch cannot be re-established, after which the caller should fall back to polling.
+func watchGameServerPods(ctx context.Context, kubeCli *KubeClient) (<-chan struct{}, error) {
+	startWatch := func() (<-chan struct{}, func(), error) {
+		watcher, err := kubeCli.Clientset.CoreV1().Pods(kubeCli.Namespace).Watch(ctx, metav1.ListOptions{
+			LabelSelector: "app=metaplay-server",
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to watch game server pods: %w", err)
+		}
+
+		// Convert the typed events into plain change signals.
+		events := make(chan struct{})
+		go func() {
+			defer close(events)
+			for event := range watcher.ResultChan() {
+				log.Debug().Msgf("Game server pod event: %s", event.Type)
+				select {
+				case events <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return events, watcher.Stop, nil
+	}
+
+	events, stopWatch, err := startWatch()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		for {
+			select {
+			case <-ctx.Done():
+				stopWatch()
+				return
+			case _, ok := <-events:
+				if !ok {
+					// The API server closes watches periodically, start a new one.
+					log.Debug().Msg("Game server pod watch closed, restarting it")
+					events, stopWatch, err = startWatch()
+					if err != nil {
+						log.Debug().Msgf("Failed to restart game server pod watch: %v", err)
+						return
+					}
+					continue
+				}
+
+				// Signal the change, unless there is already one pending.
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// Render the game server pod phase with a color matching its state.
+func renderPodPhase(phase GameServerPodPhase) string {
+	switch phase {
+	case PhaseReady:
+		return styles.RenderSuccess("✓ " + string(phase))
+	case PhaseRunning, PhasePending:
+		return styles.RenderWarning("○ " + string(phase))
+	case PhaseFailed:
+		return styles.RenderError("✗ " + string(phase))
+	default:
+		return styles.RenderMuted("? " + string(phase))
+	}
+}
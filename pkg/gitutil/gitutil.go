@@ -0,0 +1,53 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package gitutil
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Run git with the given arguments in the directory and return its trimmed output.
+func runGit(dir string, args ...string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git not found: %w", err)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("'git %s' failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Get the SHA of the commit checked out in the git repository containing the directory.
+func GetCurrentCommitSHA(dir string) (string, error) {
+	return runGit(dir, "rev-parse", "HEAD")
+}
+
+// Get the name of the branch checked out in the git repository containing the directory.
+// Returns 'HEAD' if not on a branch (detached HEAD).
+func GetCurrentBranch(dir string) (string, error) {
+	return runGit(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// Check whether the git working tree containing the directory has uncommitted changes.
+func GetIsDirty(dir string) (bool, error) {
+	output, err := runGit(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// Get the root directory of the git repository containing the directory.
+func GetRepositoryRoot(dir string) (string, error) {
+	return runGit(dir, "rev-parse", "--show-toplevel")
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/tui"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Helm value (object) holding the canary configuration of a release.
+const CanaryValuesKey = "canary"
+
+// Fields of the canary configuration that a chart must declare in its values schema to
+// support canary deployments.
+var canaryRequiredSchemaFields = []string{"enabled", "weight", "stableRelease"}
+
+// Canary configuration of a release, parsed from its Helm values.
+type CanaryConfig struct {
+	Weight        int    // Percentage of the traffic routed to the canary release.
+	StableRelease string // Name of the stable release that the canary runs alongside.
+}
+
+// Locate (download, if needed) and load the Helm chart.
+func LoadChart(chartURL string, chartVersion string) (*chart.Chart, error) {
+	pathOptions := action.ChartPathOptions{Version: chartVersion}
+	chartPath, err := pathOptions.LocateChart(chartURL, cli.New())
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate Helm chart: %w", err)
+	}
+	loadedChart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Helm chart: %w", err)
+	}
+	return loadedChart, nil
+}
+
+// Check whether the chart supports canary deployments, ie, its values schema declares the
+// canary configuration fields. Charts without a values schema are assumed not to support it.
+func ChartSupportsCanary(loadedChart *chart.Chart) bool {
+	if len(loadedChart.Schema) == 0 {
+		return false
+	}
+
+	type schemaNode struct {
+		Properties map[string]*schemaNode `json:"properties"`
+	}
+	var schema schemaNode
+	if err := json.Unmarshal(loadedChart.Schema, &schema); err != nil {
+		return false
+	}
+	canarySchema := schema.Properties[CanaryValuesKey]
+	if canarySchema == nil {
+		return false
+	}
+	for _, field := range canaryRequiredSchemaFields {
+		if _, found := canarySchema.Properties[field]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Create the Helm values that turn a release into a canary of the stable release, receiving
+// the given percentage of the traffic.
+func NewCanaryValues(stableRelease string, weight int) map[string]interface{} {
+	return map[string]interface{}{
+		"enabled":       true,
+		"weight":        weight,
+		"stableRelease": stableRelease,
+	}
+}
+
+// Get the canary configuration of the release. Returns nil if the release is not a canary.
+func GetCanaryConfig(rel *release.Release) *CanaryConfig {
+	canaryValues, ok := rel.Config[CanaryValuesKey].(map[string]interface{})
+	if !ok || canaryValues["enabled"] != true {
+		return nil
+	}
+	config := &CanaryConfig{}
+	switch weight := canaryValues["weight"].(type) {
+	case int:
+		config.Weight = weight
+	case int64:
+		config.Weight = int(weight)
+	case float64:
+		config.Weight = int(weight)
+	}
+	config.StableRelease, _ = canaryValues["stableRelease"].(string)
+	return config
+}
+
+// Find the canary release and the stable release it runs alongside from the releases.
+// Returns nils if there is no canary release.
+func FindCanaryReleases(releases []*release.Release) (stable *release.Release, canary *release.Release, err error) {
+	for _, rel := range releases {
+		config := GetCanaryConfig(rel)
+		if config == nil {
+			continue
+		}
+		if canary != nil {
+			return nil, nil, fmt.Errorf("multiple canary releases found: %s, %s", canary.Name, rel.Name)
+		}
+		canary = rel
+		stable = FindReleaseByName(releases, config.StableRelease)
+		if stable == nil {
+			return nil, nil, fmt.Errorf("the stable release '%s' of canary release '%s' was not found", config.StableRelease, rel.Name)
+		}
+	}
+	return stable, canary, nil
+}
+
+// Get the image tag of a game server release from its Helm values.
+func GetReleaseImageTag(rel *release.Release) string {
+	imageValues, ok := rel.Config["image"].(map[string]interface{})
+	if !ok {
+		return "unknown"
+	}
+	if tag, ok := imageValues["tag"].(string); ok && tag != "" {
+		return tag
+	}
+	return "unknown"
+}
+
+// Upgrade an existing release with an already loaded chart and the given values, the
+// equivalent of `helm upgrade` (with `--wait` if wait is set). The description (if non-empty)
+// is recorded in the release history, and the labels are stamped on the release.
+func UpgradeReleaseWithChart(output *tui.TaskOutput, actionConfig *action.Configuration, namespace string, releaseName string, loadedChart *chart.Chart, values map[string]interface{}, wait bool, timeout time.Duration, description string, labels map[string]string) (*release.Release, error) {
+	output.SetHeaderLines([]string{fmt.Sprintf("Upgrading release %s with chart version %s", releaseName, loadedChart.Metadata.Version)})
+	actionConfig.Log = func(format string, args ...interface{}) {
+		output.AppendLine(strings.TrimRight(fmt.Sprintf(format, args...), "\n"))
+	}
+
+	upgradeCmd := action.NewUpgrade(actionConfig)
+	upgradeCmd.Namespace = namespace
+	upgradeCmd.Wait = wait
+	upgradeCmd.Timeout = timeout
+	upgradeCmd.MaxHistory = 10      // Keep 10 releases max
+	upgradeCmd.Atomic = false       // Don't rollback on failures to not hide errors
+	upgradeCmd.CleanupOnFail = true // Clean resources on failure
+	upgradeCmd.Description = description
+	upgradeCmd.Labels = labels
+	rel, err := upgradeCmd.Run(releaseName, loadedChart, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upgrade Helm release %s: %w", releaseName, err)
+	}
+	return rel, nil
+}
@@ -10,11 +10,11 @@ import (
 	"helm.sh/helm/v3/pkg/release"
 )
 
-// Find an existing Helm relase with the given chart name.
+// Find an existing Helm relase with the given chart name in the namespace.
 // If multiple releases are found, it is considered an error.
-func GetExistingRelease(actionConfig *action.Configuration, chartName string) (*release.Release, error) {
+func GetExistingRelease(actionConfig *action.Configuration, namespace, chartName string) (*release.Release, error) {
 	// Find all releases of the chart deployed in the environment.
-	releases, err := HelmListReleases(actionConfig, chartName)
+	releases, err := HelmListReleases(actionConfig, namespace, chartName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve existing Helm releases: %v", err)
 	}
@@ -14,7 +14,7 @@ import (
 
 // HelmListReleases lists all Helm releases in the specified namespace
 // that match the specified chartName.
-func HelmListReleases(actionConfig *action.Configuration, chartName string) ([]*release.Release, error) {
+func HelmListReleases(actionConfig *action.Configuration, namespace, chartName string) ([]*release.Release, error) {
 	// Create Helm List action
 	list := action.NewList(actionConfig)
 	list.AllNamespaces = false // restrict to the given namespace
@@ -28,9 +28,14 @@ func HelmListReleases(actionConfig *action.Configuration, chartName string) ([]*
 	}
 	log.Debug().Msgf("Found %d Helm releases: %s", len(releases), strings.Join(GetReleaseNames(releases), ", "))
 
-	// Filter releases by chart name
+	// Filter releases by namespace and chart name. The action config is namespace-scoped
+	// already but check explicitly to never operate on releases in other namespaces.
 	var filteredReleases []*release.Release
 	for _, rel := range releases {
+		if rel.Namespace != namespace {
+			log.Debug().Msgf("Ignoring Helm release %s in namespace %s", rel.Name, rel.Namespace)
+			continue
+		}
 		if rel.Chart != nil && rel.Chart.Metadata != nil {
 			if rel.Chart.Metadata.Name == chartName {
 				filteredReleases = append(filteredReleases, rel)
@@ -50,3 +55,13 @@ func GetReleaseNames(releases []*release.Release) []string {
 	}
 	return names
 }
+
+// Find the release with the given name. Returns nil if not found.
+func FindReleaseByName(releases []*release.Release, releaseName string) *release.Release {
+	for _, release := range releases {
+		if release.Name == releaseName {
+			return release
+		}
+	}
+	return nil
+}
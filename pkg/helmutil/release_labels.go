@@ -0,0 +1,88 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Labels stamped on the Helm releases installed or upgraded by the CLI, to tell them apart from
+// releases managed by other tools. Helm stores the release labels as Kubernetes labels on the
+// release secrets, so the values are sanitized to valid label values.
+const (
+	ManagedByLabel    = "managed-by"           // Tool that manages the release.
+	ManagedByCliValue = "metaplay-cli"         // Value of ManagedByLabel for releases managed by the CLI.
+	CliVersionLabel   = "metaplay-cli-version" // Version of the CLI that last deployed the release.
+	DeployedByLabel   = "deployed-by"          // Identity (email) of the user who last deployed the release.
+)
+
+// Maximum length of a Kubernetes label value.
+const maxLabelValueLength = 63
+
+// Characters not allowed in Kubernetes label values.
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Create the labels to stamp on a release deployed by the CLI. Empty values are omitted.
+func NewReleaseLabels(cliVersion string, deployedBy string) map[string]string {
+	labels := map[string]string{
+		ManagedByLabel: ManagedByCliValue,
+	}
+	if value := sanitizeLabelValue(cliVersion); value != "" {
+		labels[CliVersionLabel] = value
+	}
+	if value := sanitizeLabelValue(deployedBy); value != "" {
+		labels[DeployedByLabel] = value
+	}
+	return labels
+}
+
+// Create the description to record in the release history (see 'helm history') for a deploy
+// by the CLI, eg, 'Deployed by jane.doe@example.com with Metaplay CLI 1.5.0'. Unlike in the
+// labels, the identity is recorded as-is. The details (eg, a policy override) are appended
+// if non-empty.
+func NewReleaseDescription(cliVersion string, deployedBy string, details string) string {
+	if deployedBy == "" {
+		deployedBy = "unknown"
+	}
+	description := fmt.Sprintf("Deployed by %s", deployedBy)
+	if cliVersion != "" {
+		description += fmt.Sprintf(" with Metaplay CLI %s", cliVersion)
+	}
+	if details != "" {
+		description += ": " + details
+	}
+	return description
+}
+
+// Check whether the release was installed or last upgraded by the CLI.
+func IsManagedByCli(rel *release.Release) bool {
+	return rel.Labels[ManagedByLabel] == ManagedByCliValue
+}
+
+// Get who last deployed the release with the CLI (in the sanitized form, eg,
+// 'jane.doe_at_example.com'), or an empty string if unknown.
+func GetReleaseDeployedBy(rel *release.Release) string {
+	return rel.Labels[DeployedByLabel]
+}
+
+// Get the version of the CLI that last deployed the release, or an empty string if unknown.
+func GetReleaseCliVersion(rel *release.Release) string {
+	return rel.Labels[CliVersionLabel]
+}
+
+// Convert the string into a valid Kubernetes label value: '@' is replaced with '_at_' to keep
+// emails readable, other invalid characters with '_', and the result is truncated to 63
+// characters. Label values must also start and end with an alphanumeric character.
+func sanitizeLabelValue(str string) string {
+	value := strings.ReplaceAll(str, "@", "_at_")
+	value = invalidLabelValueChars.ReplaceAllString(value, "_")
+	if len(value) > maxLabelValueLength {
+		value = value[:maxLabelValueLength]
+	}
+	return strings.Trim(value, "._-")
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metaplay/cli/internal/tui"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestNewReleaseLabels(t *testing.T) {
+	tests := []struct {
+		cliVersion string
+		deployedBy string
+		expected   map[string]string
+	}{
+		{"1.5.0", "jane.doe@example.com", map[string]string{ManagedByLabel: ManagedByCliValue, CliVersionLabel: "1.5.0", DeployedByLabel: "jane.doe_at_example.com"}},
+		{"1.5.0+dev", "", map[string]string{ManagedByLabel: ManagedByCliValue, CliVersionLabel: "1.5.0_dev"}},
+		{"", "machine|client:1234", map[string]string{ManagedByLabel: ManagedByCliValue, DeployedByLabel: "machine_client_1234"}},
+		{"", "@@@", map[string]string{ManagedByLabel: ManagedByCliValue, DeployedByLabel: "at__at__at"}},
+		{"", "-", map[string]string{ManagedByLabel: ManagedByCliValue}},
+		{"", strings.Repeat("a", 60) + "@example.com", map[string]string{ManagedByLabel: ManagedByCliValue, DeployedByLabel: strings.Repeat("a", 60) + "_at"}},
+	}
+
+	for _, test := range tests {
+		labels := NewReleaseLabels(test.cliVersion, test.deployedBy)
+		if len(labels) != len(test.expected) {
+			t.Errorf("NewReleaseLabels(%q, %q) = %v, expected %v", test.cliVersion, test.deployedBy, labels, test.expected)
+			continue
+		}
+		for key, value := range test.expected {
+			if labels[key] != value {
+				t.Errorf("NewReleaseLabels(%q, %q)[%s] = %q, expected %q", test.cliVersion, test.deployedBy, key, labels[key], value)
+			}
+		}
+		for key, value := range labels {
+			if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+				t.Errorf("label %s=%q is not a valid Kubernetes label value: %v", key, value, errs)
+			}
+		}
+	}
+}
+
+func TestNewReleaseDescription(t *testing.T) {
+	tests := []struct {
+		cliVersion string
+		deployedBy string
+		details    string
+		expected   string
+	}{
+		{"1.5.0", "jane.doe@example.com", "", "Deployed by jane.doe@example.com with Metaplay CLI 1.5.0"},
+		{"1.5.0", "jane.doe@example.com", "Policy override (no-friday-deploys) by jane.doe@example.com", "Deployed by jane.doe@example.com with Metaplay CLI 1.5.0: Policy override (no-friday-deploys) by jane.doe@example.com"},
+		{"", "", "", "Deployed by unknown"},
+	}
+
+	for _, test := range tests {
+		description := NewReleaseDescription(test.cliVersion, test.deployedBy, test.details)
+		if description != test.expected {
+			t.Errorf("NewReleaseDescription(%q, %q, %q) = %q, expected %q", test.cliVersion, test.deployedBy, test.details, description, test.expected)
+		}
+	}
+}
+
+func TestUpgradeReleaseWithChartLabels(t *testing.T) {
+	existing := newTestRelease(1, release.StatusDeployed, map[string]interface{}{}, "")
+	existing.Labels = map[string]string{"team": "backend"}
+	actionConfig := newTestActionConfig(t, existing)
+
+	labels := NewReleaseLabels("1.5.0", "jane.doe@example.com")
+	_, err := UpgradeReleaseWithChart(tui.NewCallbackTaskOutput(nil, nil), actionConfig, existing.Namespace, existing.Name, existing.Chart, map[string]interface{}{}, true, time.Minute, NewReleaseDescription("1.5.0", "jane.doe@example.com", ""), labels)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The CLI labels are added on top of the existing labels.
+	upgraded, err := actionConfig.Releases.Last(existing.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded.Version != 2 || !IsManagedByCli(upgraded) || upgraded.Labels["team"] != "backend" {
+		t.Errorf("unexpected upgraded release: version %d, labels %v", upgraded.Version, upgraded.Labels)
+	}
+	if GetReleaseDeployedBy(upgraded) != "jane.doe_at_example.com" || GetReleaseCliVersion(upgraded) != "1.5.0" {
+		t.Errorf("unexpected deployer labels: %v", upgraded.Labels)
+	}
+	if upgraded.Info.Description != "Deployed by jane.doe@example.com with Metaplay CLI 1.5.0" {
+		t.Errorf("unexpected release description: %q", upgraded.Info.Description)
+	}
+	if IsManagedByCli(existing) {
+		t.Errorf("release without labels should not be managed by the CLI")
+	}
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// GetReleaseStatus fetches the current state of the named Helm release.
+func GetReleaseStatus(actionConfig *action.Configuration, releaseName string) (*release.Release, error) {
+	status := action.NewStatus(actionConfig)
+	rel, err := status.Run(releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of Helm release %s: %w", releaseName, err)
+	}
+	return rel, nil
+}
+
+// IsTimeoutError checks whether the error from a Helm operation is due to the
+// operation's timeout expiring while waiting for the resources.
+func IsTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || wait.Interrupted(err) {
+		return true
+	}
+	// Helm doesn't always wrap the underlying errors, so also check the message.
+	message := err.Error()
+	return strings.Contains(message, "timed out waiting") || strings.Contains(message, "context deadline exceeded")
+}
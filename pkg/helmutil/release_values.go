@@ -0,0 +1,68 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Get the latest revision of the named Helm release with the 'deployed' status. Later
+// revisions that failed or are still pending are skipped, as they're not what is running.
+func getDeployedReleaseRevision(actionConfig *action.Configuration, releaseName string) (*release.Release, error) {
+	rel, err := actionConfig.Releases.Deployed(releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the deployed revision of Helm release %s: %w", releaseName, err)
+	}
+	return rel, nil
+}
+
+// GetReleaseValues fetches the effective values of the currently deployed revision of the
+// named Helm release: the chart's default values merged with the user-supplied values.
+func GetReleaseValues(actionConfig *action.Configuration, releaseName string) (map[string]interface{}, error) {
+	rel, err := getDeployedReleaseRevision(actionConfig, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	getValues := action.NewGetValues(actionConfig)
+	getValues.Version = rel.Version
+	getValues.AllValues = true
+	values, err := getValues.Run(releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get values of Helm release %s: %w", releaseName, err)
+	}
+	return values, nil
+}
+
+// GetReleaseUserValues fetches the user-supplied values of the currently deployed revision of
+// the named Helm release, ie, the values given with the values files and --set, without the
+// chart's default values.
+func GetReleaseUserValues(actionConfig *action.Configuration, releaseName string) (map[string]interface{}, error) {
+	rel, err := getDeployedReleaseRevision(actionConfig, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	getValues := action.NewGetValues(actionConfig)
+	getValues.Version = rel.Version
+	values, err := getValues.Run(releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get values of Helm release %s: %w", releaseName, err)
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return values, nil
+}
+
+// GetReleaseManifest fetches the rendered Kubernetes manifest of the currently deployed
+// revision of the named Helm release.
+func GetReleaseManifest(actionConfig *action.Configuration, releaseName string) (string, error) {
+	rel, err := getDeployedReleaseRevision(actionConfig, releaseName)
+	if err != nil {
+		return "", err
+	}
+	return rel.Manifest, nil
+}
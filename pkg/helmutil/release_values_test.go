@@ -0,0 +1,150 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"io"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// Create a Helm action config backed by in-memory release storage, with the given releases.
+func newTestActionConfig(t *testing.T, releases ...*release.Release) *action.Configuration {
+	actionConfig := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(format string, v ...interface{}) {},
+	}
+	for _, rel := range releases {
+		if err := actionConfig.Releases.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return actionConfig
+}
+
+func newTestRelease(version int, status release.Status, config map[string]interface{}, manifest string) *release.Release {
+	return &release.Release{
+		Name:      "gameserver",
+		Namespace: "tough-falcons",
+		Version:   version,
+		Info:      &release.Info{Status: status},
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{Name: "metaplay-gameserver", Version: "0.8.0", APIVersion: chart.APIVersionV2},
+			Values: map[string]interface{}{
+				"image":    map[string]interface{}{"tag": "latest", "pullPolicy": "IfNotPresent"},
+				"replicas": 1,
+			},
+		},
+		Config:   config,
+		Manifest: manifest,
+	}
+}
+
+func TestGetReleaseValues(t *testing.T) {
+	actionConfig := newTestActionConfig(t,
+		newTestRelease(1, release.StatusSuperseded, map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}}, "kind: ConfigMap # v1"),
+		newTestRelease(2, release.StatusDeployed, map[string]interface{}{"image": map[string]interface{}{"tag": "v2"}}, "kind: ConfigMap # v2"),
+	)
+
+	// Values are from the deployed revision, merged over the chart defaults.
+	values, err := GetReleaseValues(actionConfig, "gameserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	image, ok := values["image"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("values have no image: %v", values)
+	}
+	if image["tag"] != "v2" || image["pullPolicy"] != "IfNotPresent" {
+		t.Errorf("image values = %v, expected tag v2 with the default pullPolicy", image)
+	}
+	if values["replicas"] != 1 {
+		t.Errorf("replicas = %v, expected the chart default 1", values["replicas"])
+	}
+
+	manifest, err := GetReleaseManifest(actionConfig, "gameserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest != "kind: ConfigMap # v2" {
+		t.Errorf("manifest = %q, expected the deployed revision's manifest", manifest)
+	}
+
+	// Missing release.
+	if _, err := GetReleaseValues(actionConfig, "missing"); err == nil {
+		t.Errorf("expected an error for a missing release")
+	}
+	if _, err := GetReleaseManifest(actionConfig, "missing"); err == nil {
+		t.Errorf("expected an error for a missing release")
+	}
+}
+
+func TestGetReleaseUserValues(t *testing.T) {
+	actionConfig := newTestActionConfig(t,
+		newTestRelease(1, release.StatusDeployed, map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}}, ""),
+	)
+
+	// Only the user-supplied values, without the chart defaults.
+	values, err := GetReleaseUserValues(actionConfig, "gameserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	image, ok := values["image"].(map[string]interface{})
+	if !ok || image["tag"] != "v1" {
+		t.Errorf("image values = %v, expected tag v1", values["image"])
+	}
+	if _, found := image["pullPolicy"]; found {
+		t.Errorf("expected no chart default pullPolicy, got %v", image)
+	}
+	if _, found := values["replicas"]; found {
+		t.Errorf("expected no chart default replicas, got %v", values)
+	}
+
+	if _, err := GetReleaseUserValues(actionConfig, "missing"); err == nil {
+		t.Errorf("expected an error for a missing release")
+	}
+}
+
+func TestGetReleaseValuesSkipsFailedRevision(t *testing.T) {
+	actionConfig := newTestActionConfig(t,
+		newTestRelease(1, release.StatusSuperseded, map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}}, "kind: ConfigMap # v1"),
+		newTestRelease(2, release.StatusDeployed, map[string]interface{}{"image": map[string]interface{}{"tag": "v2"}}, "kind: ConfigMap # v2"),
+		newTestRelease(3, release.StatusFailed, map[string]interface{}{"image": map[string]interface{}{"tag": "v3"}}, "kind: ConfigMap # v3"),
+	)
+
+	// The failed upgrade to v3 isn't running, the values and manifest are from v2.
+	values, err := GetReleaseValues(actionConfig, "gameserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag := values["image"].(map[string]interface{})["tag"]; tag != "v2" {
+		t.Errorf("image tag = %v, expected the deployed revision's v2", tag)
+	}
+	userValues, err := GetReleaseUserValues(actionConfig, "gameserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag := userValues["image"].(map[string]interface{})["tag"]; tag != "v2" {
+		t.Errorf("user image tag = %v, expected the deployed revision's v2", tag)
+	}
+	manifest, err := GetReleaseManifest(actionConfig, "gameserver")
+	if err != nil || manifest != "kind: ConfigMap # v2" {
+		t.Errorf("manifest = %q (err: %v), expected the deployed revision's manifest", manifest, err)
+	}
+
+	// A release whose only revision failed has no deployed values.
+	actionConfig = newTestActionConfig(t, newTestRelease(1, release.StatusFailed, nil, ""))
+	if _, err := GetReleaseValues(actionConfig, "gameserver"); err == nil {
+		t.Errorf("expected an error for a release without a deployed revision")
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// Known resource kinds and their accepted API versions. Manifests rendered from the
+// Metaplay charts should only contain these.
+var knownResourceKinds = map[string][]string{
+	"ConfigMap":               {"v1"},
+	"Secret":                  {"v1"},
+	"Service":                 {"v1"},
+	"ServiceAccount":          {"v1"},
+	"Pod":                     {"v1"},
+	"PersistentVolumeClaim":   {"v1"},
+	"Deployment":              {"apps/v1"},
+	"StatefulSet":             {"apps/v1"},
+	"DaemonSet":               {"apps/v1"},
+	"Job":                     {"batch/v1"},
+	"CronJob":                 {"batch/v1"},
+	"Ingress":                 {"networking.k8s.io/v1"},
+	"NetworkPolicy":           {"networking.k8s.io/v1"},
+	"PodDisruptionBudget":     {"policy/v1"},
+	"HorizontalPodAutoscaler": {"autoscaling/v2"},
+	"Role":                    {"rbac.authorization.k8s.io/v1"},
+	"RoleBinding":             {"rbac.authorization.k8s.io/v1"},
+	"ClusterRole":             {"rbac.authorization.k8s.io/v1"},
+	"ClusterRoleBinding":      {"rbac.authorization.k8s.io/v1"},
+	"Certificate":             {"cert-manager.io/v1"},
+	"ServiceMonitor":          {"monitoring.coreos.com/v1"},
+	"PodMonitor":              {"monitoring.coreos.com/v1"},
+	"GameServer":              {"metaplay.io/v1", "gameservers.metaplay.io/v0"},
+}
+
+// Problem found in a rendered manifest by ValidateManifests().
+type ValidationError struct {
+	Source  string // Template that the document was rendered from (from the '# Source:' comment), if known.
+	Index   int    // Index of the document in the manifests.
+	Message string // Description of the problem.
+}
+
+func (e ValidationError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s: %s", e.Source, e.Message)
+	}
+	return fmt.Sprintf("document #%d: %s", e.Index, e.Message)
+}
+
+// RenderManifests renders the chart's manifests locally, the equivalent of `helm template`.
+// No access to a Kubernetes cluster is needed. Returns all the manifests (including hooks)
+// as a single multi-document YAML string.
+func RenderManifests(chartPath string, releaseName string, namespace string, values map[string]interface{}) (string, error) {
+	// Configure a client-only install in dry-run mode: Helm uses mock Kubernetes client and
+	// capabilities instead of contacting a cluster.
+	actionConfig := new(action.Configuration)
+	actionConfig.Log = func(format string, args ...interface{}) {
+		log.Debug().Msgf(format, args...)
+	}
+	installCmd := action.NewInstall(actionConfig)
+	installCmd.ClientOnly = true
+	installCmd.DryRun = true
+	installCmd.Replace = true
+	installCmd.ReleaseName = releaseName
+	installCmd.Namespace = namespace
+	installCmd.Devel = true // If version is development, accept it
+
+	// Load (download) Helm chart
+	chartFilePath, err := installCmd.ChartPathOptions.LocateChart(chartPath, cli.New())
+	if err != nil {
+		return "", fmt.Errorf("failed to locate Helm chart: %w", err)
+	}
+	loadedChart, err := loader.Load(chartFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load Helm chart: %w", err)
+	}
+
+	// Render the templates.
+	release, err := installCmd.Run(loadedChart, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render Helm chart: %w", err)
+	}
+
+	// Combine the manifests and the hooks (like 'helm template' does).
+	var manifests strings.Builder
+	manifests.WriteString(release.Manifest)
+	for _, hook := range release.Hooks {
+		fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest)
+	}
+	return manifests.String(), nil
+}
+
+// ValidateManifests parses the multi-document YAML manifests (eg, from RenderManifests())
+// and checks that each resource has a known kind and API version, and a name. Returns the
+// found problems, or an empty slice if all the manifests are valid.
+func ValidateManifests(manifests string) []ValidationError {
+	validationErrors := []ValidationError{}
+
+	decoder := yaml.NewDecoder(strings.NewReader(manifests))
+	for index := 0; ; index++ {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			validationErrors = append(validationErrors, ValidationError{Index: index, Message: fmt.Sprintf("invalid YAML: %v", err)})
+			break // decoder cannot recover from syntax errors
+		}
+
+		// Skip empty documents (eg, templates that rendered to nothing).
+		if len(doc) == 0 {
+			continue
+		}
+
+		validationErrors = append(validationErrors, validateManifestDocument(index, findManifestSource(manifests, index), doc)...)
+	}
+
+	return validationErrors
+}
+
+// Validate a single parsed manifest document.
+func validateManifestDocument(index int, source string, doc map[string]interface{}) []ValidationError {
+	newError := func(format string, args ...any) ValidationError {
+		return ValidationError{Source: source, Index: index, Message: fmt.Sprintf(format, args...)}
+	}
+
+	kind, _ := doc["kind"].(string)
+	apiVersion, _ := doc["apiVersion"].(string)
+	if kind == "" {
+		return []ValidationError{newError("missing 'kind'")}
+	}
+	if apiVersion == "" {
+		return []ValidationError{newError("missing 'apiVersion' for %s", kind)}
+	}
+
+	var validationErrors []ValidationError
+	acceptedVersions, found := knownResourceKinds[kind]
+	if !found {
+		validationErrors = append(validationErrors, newError("unknown resource kind '%s'", kind))
+	} else if !containsString(acceptedVersions, apiVersion) {
+		validationErrors = append(validationErrors, newError("unsupported apiVersion '%s' for %s, expecting one of: %s", apiVersion, kind, strings.Join(acceptedVersions, ", ")))
+	}
+
+	metadata, _ := doc["metadata"].(map[string]interface{})
+	if name, _ := metadata["name"].(string); name == "" {
+		validationErrors = append(validationErrors, newError("missing 'metadata.name' for %s", kind))
+	}
+
+	return validationErrors
+}
+
+// Find the template source ('# Source: <path>' comment emitted by Helm) of the index'th
+// document in the manifests. Returns an empty string if not found.
+func findManifestSource(manifests string, index int) string {
+	docs := strings.Split("\n"+manifests, "\n---")
+	// The manifests may or may not start with a separator: skip the leading empty part.
+	if len(docs) > 0 && strings.TrimSpace(docs[0]) == "" {
+		docs = docs[1:]
+	}
+	if index >= len(docs) {
+		return ""
+	}
+	for _, line := range strings.Split(docs[index], "\n") {
+		if source, ok := strings.CutPrefix(strings.TrimSpace(line), "# Source: "); ok {
+			return source
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
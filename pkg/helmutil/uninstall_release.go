@@ -5,15 +5,18 @@ package helmutil
 
 import (
 	"fmt"
+	"time"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/release"
 )
 
-// UninstallRelease uninstalls the given Helm release.
-func UninstallRelease(actionConfig *action.Configuration, release *release.Release) error {
+// UninstallRelease uninstalls the given Helm release. The timeout applies to the
+// release's uninstall hooks: the deletion of the release's resources is not waited for.
+func UninstallRelease(actionConfig *action.Configuration, release *release.Release, timeout time.Duration) error {
 	// Create Helm Uninstall action
 	uninstall := action.NewUninstall(actionConfig)
+	uninstall.Timeout = timeout
 
 	// Execute the Uninstall action
 	_, err := uninstall.Run(release.Name)
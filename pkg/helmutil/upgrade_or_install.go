@@ -12,13 +12,20 @@ import (
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/strvals"
 )
 
-// HelmUpgradeOrInstall performs the equivalent of `helm upgrade --install --wait --values <path> ...`
+// HelmUpgradeOrInstall performs the equivalent of `helm upgrade --install --wait --values <path> --set <value> ...`
+// The description (if non-empty) is recorded in the release history, see `helm history`.
+// The labels (see NewReleaseLabels()) are stamped on the release, on top of its existing labels.
+// With atomic (like `helm --atomic`), a failed install is uninstalled and a failed upgrade is
+// rolled back to the previous revision. Otherwise, failed releases are left in place to not
+// hide the errors.
 func HelmUpgradeOrInstall(
 	output *tui.TaskOutput,
 	actionConfig *action.Configuration,
@@ -27,7 +34,11 @@ func HelmUpgradeOrInstall(
 	chartVersion string,
 	valuesFiles []string,
 	extraValues map[string]interface{},
+	setValues []string,
 	timeout time.Duration,
+	atomic bool,
+	description string,
+	labels map[string]string,
 ) (*release.Release, error) {
 	// Show header at top
 	headerLine := fmt.Sprintf("Deploying chart %s as release %s", chartURL, releaseName)
@@ -36,7 +47,7 @@ func HelmUpgradeOrInstall(
 	// Pipe Helm output to task output
 	actionConfig.Log = func(format string, args ...interface{}) {
 		// Render line and trim any trailing line endings
-		line := fmt.Sprintf(format, args)
+		line := fmt.Sprintf(format, args...)
 		line = strings.TrimRight(line, "\n")
 		output.AppendLine(line)
 	}
@@ -57,6 +68,9 @@ func HelmUpgradeOrInstall(
 		installCmd.Wait = true
 		installCmd.Timeout = timeout
 		installCmd.Devel = true // If version is development, accept it
+		installCmd.Atomic = atomic
+		installCmd.Description = description
+		installCmd.Labels = labels
 		chartPathOptions = &installCmd.ChartPathOptions
 	} else {
 		output.AppendLinef("Existing release found (version %s), upgrade existing release", existingRelease.Chart.Metadata.Version)
@@ -67,8 +81,10 @@ func HelmUpgradeOrInstall(
 		upgradeCmd.Timeout = timeout
 		upgradeCmd.MaxHistory = 10      // Keep 10 releases max
 		upgradeCmd.Devel = true         // If version is development, accept it
-		upgradeCmd.Atomic = false       // Don't rollback on failures to not hide errors
+		upgradeCmd.Atomic = atomic      // Rollback on failures only if requested, to not hide errors
 		upgradeCmd.CleanupOnFail = true // Clean resources on failure
+		upgradeCmd.Description = description
+		upgradeCmd.Labels = labels
 		chartPathOptions = &upgradeCmd.ChartPathOptions
 	}
 
@@ -89,27 +105,17 @@ func HelmUpgradeOrInstall(
 
 	output.AppendLinef("Chart loaded: %s (version %s)", loadedChart.Name(), loadedChart.Metadata.Version)
 
-	// Construct base values
-	baseValues := map[string]interface{}{}
-	if extraValues != nil {
-		baseValues = extraValues
-	}
-
-	// Load values from files if any
-	filesValueMap := map[string]interface{}{}
+	// Resolve final values map from the extra values and values files.
 	for _, valuesFile := range valuesFiles {
 		output.AppendLinef("Loading values from: %s", valuesFile)
-		values, err := chartutil.ReadValuesFile(valuesFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read values file: %w", err)
-		}
-
-		// Merge with previous values, files processed later override earlier ones
-		filesValueMap = mergeValuesMaps(filesValueMap, values.AsMap())
 	}
-
-	// Resolve final values map: use extraValues as base to allow files to override any defaults.
-	finalValueMap := mergeValuesMaps(baseValues, filesValueMap)
+	for _, setValue := range setValues {
+		output.AppendLinef("Setting value: %s", setValue)
+	}
+	finalValueMap, err := ResolveValues(valuesFiles, extraValues, setValues)
+	if err != nil {
+		return nil, err
+	}
 
 	// Log values as YAML.
 	finalValuesYAML, err := yaml.Marshal(finalValueMap)
@@ -138,6 +144,51 @@ func HelmUpgradeOrInstall(
 	}
 }
 
+// Resolve the final Helm values from the values files and the extra values. The values
+// files are applied on top of extraValues so that they can override any defaults. The
+// setValues (in Helm's '--set' format, eg, 'shards=4') are applied last.
+func ResolveValues(valuesFiles []string, extraValues map[string]interface{}, setValues []string) (map[string]interface{}, error) {
+	// Construct base values
+	baseValues := map[string]interface{}{}
+	if extraValues != nil {
+		baseValues = extraValues
+	}
+
+	// Load values from files if any
+	filesValueMap := map[string]interface{}{}
+	for _, valuesFile := range valuesFiles {
+		values, err := chartutil.ReadValuesFile(valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %w", err)
+		}
+
+		// Merge with previous values, files processed later override earlier ones
+		filesValueMap = mergeValuesMaps(filesValueMap, values.AsMap())
+	}
+
+	// Resolve final values map: use extraValues as base to allow files to override any defaults.
+	finalValues := mergeValuesMaps(baseValues, filesValueMap)
+
+	// Apply the individual values on top of everything, like Helm does with --set.
+	setValueMap := map[string]interface{}{}
+	for _, setValue := range setValues {
+		if err := strvals.ParseInto(setValue, setValueMap); err != nil {
+			return nil, fmt.Errorf("invalid --set value '%s': %w", setValue, err)
+		}
+	}
+	return mergeValuesMaps(finalValues, setValueMap), nil
+}
+
+// Compute the effective values of a release: the chart's default values with the user values
+// applied on top, as Helm does when rendering the chart.
+func ComputeReleaseValues(loadedChart *chart.Chart, userValues map[string]interface{}) (map[string]interface{}, error) {
+	values, err := chartutil.CoalesceValues(loadedChart, userValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute Helm values: %w", err)
+	}
+	return values.AsMap(), nil
+}
+
 // Combine two Helm values maps into one. On conflicts, the fields in 'override' win
 // over 'base'. Maps are recursively merged. Sequences are replaced.
 func mergeValuesMaps(base, override map[string]interface{}) map[string]interface{} {
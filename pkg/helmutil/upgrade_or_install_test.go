@@ -0,0 +1,49 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveValues(t *testing.T) {
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(valuesFile, []byte("shards: 2\nconfig:\n  logLevel: debug\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	extraValues := map[string]interface{}{
+		"shards": 1,
+		"image":  map[string]interface{}{"tag": "364cff09"},
+	}
+
+	// The files override the extra values, and the set values override the files.
+	values, err := ResolveValues([]string{valuesFile}, extraValues, []string{"shards=4", "config.logLevel=info"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"shards": int64(4),
+		"image":  map[string]interface{}{"tag": "364cff09"},
+		"config": map[string]interface{}{"logLevel": "info"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("ResolveValues() = %v, expected %v", values, expected)
+	}
+
+	// Without set values, the files win.
+	values, err = ResolveValues([]string{valuesFile}, extraValues, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["shards"] != float64(2) {
+		t.Errorf("shards = %#v, expected the value from the file", values["shards"])
+	}
+
+	if _, err := ResolveValues(nil, nil, []string{"shards"}); err == nil {
+		t.Errorf("expected an error for an invalid set value")
+	}
+}
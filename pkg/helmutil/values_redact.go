@@ -0,0 +1,138 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Comment attached to redacted values in the marshaled YAML.
+const RedactedValueComment = "REDACTED: fill in manually before deploying"
+
+// Parts of key names that suggest the value is a credential.
+var sensitiveKeyParts = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "privatekey", "private_key", "credential", "connectionstring"}
+
+// Suffixes of key names that refer to a credential stored elsewhere (eg, 'secretName' or
+// 'passwordFile'), rather than containing the credential itself.
+var credentialReferenceSuffixes = []string{"name", "ref", "file", "path", "enabled"}
+
+// Check whether the values key looks like it holds a credential, eg, 'dbPassword' or
+// 'apiKey', and must not be written out as-is.
+func IsSensitiveValueKey(key string) bool {
+	lowerKey := strings.ToLower(key)
+	if strings.HasPrefix(lowerKey, "existing") {
+		return false // eg, 'existingSecret'
+	}
+	for _, suffix := range credentialReferenceSuffixes {
+		if strings.HasSuffix(lowerKey, suffix) {
+			return false
+		}
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(lowerKey, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshal the Helm values into YAML with the sensitive-looking values (see IsSensitiveValueKey)
+// replaced with empty values and a comment saying they must be filled in manually. Returns
+// the YAML and the dot-separated paths of the redacted values, eg, 'database.password'.
+func MarshalValuesRedacted(values map[string]interface{}) ([]byte, []string, error) {
+	var root yaml.Node
+	if err := root.Encode(values); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode Helm values: %w", err)
+	}
+
+	redacted := redactSensitiveNodes(&root)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal Helm values: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal Helm values: %w", err)
+	}
+	return buf.Bytes(), redacted, nil
+}
+
+// Redact the sensitive values in the YAML node tree. Scalars are replaced with empty strings,
+// and whole mappings and sequences (eg, a list of tokens) with empty ones, so that no part of
+// the subtree is written out. Returns the paths of the redacted values.
+func redactSensitiveNodes(root *yaml.Node) []string {
+	var redacted []string
+	walkValueNodes(root, "", func(keyNode *yaml.Node, valueNode *yaml.Node, path string) bool {
+		if !IsSensitiveValueKey(keyNode.Value) {
+			return true
+		}
+		switch {
+		case valueNode.Kind == yaml.ScalarNode && valueNode.Tag != "!!null":
+			valueNode.Tag = "!!str"
+			valueNode.Value = ""
+			valueNode.Style = yaml.DoubleQuotedStyle
+		case (valueNode.Kind == yaml.MappingNode || valueNode.Kind == yaml.SequenceNode) && len(valueNode.Content) > 0:
+			valueNode.Content = nil
+			valueNode.Style = yaml.FlowStyle
+		default:
+			return true
+		}
+		valueNode.LineComment = RedactedValueComment
+		redacted = append(redacted, path)
+		return false
+	})
+	return redacted
+}
+
+// Find the values that are still marked as redacted (see MarshalValuesRedacted) in the YAML
+// values file contents, ie, that haven't been filled in. Returns their dot-separated paths.
+func FindRedactedValues(valuesYAML []byte) ([]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(valuesYAML, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse Helm values: %w", err)
+	}
+
+	var redacted []string
+	walkValueNodes(&root, "", func(keyNode *yaml.Node, valueNode *yaml.Node, path string) bool {
+		if strings.Contains(valueNode.LineComment, RedactedValueComment) || strings.Contains(keyNode.LineComment, RedactedValueComment) {
+			redacted = append(redacted, path)
+		}
+		return true
+	})
+	return redacted, nil
+}
+
+// Call visit for each key-value pair in the YAML node tree, with the dot-separated path of the
+// value, recursing into mappings and sequences. If visit returns false, the value is not
+// recursed into.
+func walkValueNodes(node *yaml.Node, path string, visit func(keyNode *yaml.Node, valueNode *yaml.Node, path string) bool) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for ndx := 0; ndx+1 < len(node.Content); ndx += 2 {
+			keyNode := node.Content[ndx]
+			valueNode := node.Content[ndx+1]
+			childPath := keyNode.Value
+			if path != "" {
+				childPath = path + "." + keyNode.Value
+			}
+			if visit(keyNode, valueNode, childPath) {
+				walkValueNodes(valueNode, childPath, visit)
+			}
+		}
+	case yaml.SequenceNode:
+		for ndx, item := range node.Content {
+			walkValueNodes(item, fmt.Sprintf("%s[%d]", path, ndx), visit)
+		}
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkValueNodes(child, path, visit)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package helmutil
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestIsSensitiveValueKey(t *testing.T) {
+	sensitive := []string{"password", "dbPassword", "apiKey", "api_key", "clientSecret", "authToken", "googleCredentials", "privateKey", "connectionString"}
+	for _, key := range sensitive {
+		if !IsSensitiveValueKey(key) {
+			t.Errorf("expected %q to be sensitive", key)
+		}
+	}
+
+	notSensitive := []string{"tag", "replicas", "secretName", "tokenEnabled", "passwordFile", "existingSecret", "secretKeyRef", "environment"}
+	for _, key := range notSensitive {
+		if IsSensitiveValueKey(key) {
+			t.Errorf("expected %q not to be sensitive", key)
+		}
+	}
+}
+
+func TestMarshalValuesRedacted(t *testing.T) {
+	values := map[string]interface{}{
+		"environment": "Develop",
+		"database": map[string]interface{}{
+			"host":       "db.local",
+			"password":   "hunter2",
+			"secretName": "db-credentials",
+		},
+		"sidecars": []interface{}{
+			map[string]interface{}{"name": "proxy", "apiKey": "abc123"},
+		},
+		"emptyToken": nil,
+	}
+
+	output, redacted, err := MarshalValuesRedacted(values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(redacted, []string{"database.password", "sidecars[0].apiKey"}) {
+		t.Errorf("redacted = %v", redacted)
+	}
+
+	text := string(output)
+	if strings.Contains(text, "hunter2") || strings.Contains(text, "abc123") {
+		t.Errorf("sensitive values were not redacted:\n%s", text)
+	}
+	if strings.Count(text, RedactedValueComment) != 2 {
+		t.Errorf("expected 2 redaction comments:\n%s", text)
+	}
+
+	// The output is still valid values with the other fields intact.
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(output, &parsed); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	database := parsed["database"].(map[string]interface{})
+	if database["password"] != "" || database["host"] != "db.local" || database["secretName"] != "db-credentials" {
+		t.Errorf("unexpected database values: %v", database)
+	}
+}
+
+func TestFindRedactedValues(t *testing.T) {
+	values := map[string]interface{}{
+		"database": map[string]interface{}{"host": "db.local", "password": "hunter2"},
+		"sidecars": []interface{}{map[string]interface{}{"apiKey": "abc123"}},
+	}
+	output, _, err := MarshalValuesRedacted(values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Freshly exported values have all the redacted values marked.
+	redacted, err := FindRedactedValues(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(redacted, []string{"database.password", "sidecars[0].apiKey"}) {
+		t.Errorf("redacted = %v", redacted)
+	}
+
+	// Filled-in values no longer have the comment.
+	filled := strings.Replace(string(output), `password: "" # `+RedactedValueComment, "password: hunter2", 1)
+	redacted, err = FindRedactedValues([]byte(filled))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(redacted, []string{"sidecars[0].apiKey"}) {
+		t.Errorf("redacted = %v, expected only the unfilled value\n%s", redacted, filled)
+	}
+
+	if _, err := FindRedactedValues([]byte("a: [")); err == nil {
+		t.Errorf("expected a parse error")
+	}
+}
+
+func TestMarshalValuesRedactedSubtrees(t *testing.T) {
+	values := map[string]interface{}{
+		"credentials": map[string]interface{}{
+			"user": "admin",
+			"key":  "hunter2",
+			"nested": map[string]interface{}{
+				"value": "nested-secret",
+			},
+		},
+		"apiTokens":    []interface{}{"token-1", map[string]interface{}{"value": "token-2"}},
+		"emptySecrets": map[string]interface{}{},
+	}
+
+	output, redacted, err := MarshalValuesRedacted(values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(redacted, []string{"apiTokens", "credentials"}) {
+		t.Errorf("redacted = %v", redacted)
+	}
+
+	text := string(output)
+	for _, secret := range []string{"admin", "hunter2", "nested-secret", "token-1", "token-2"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("sensitive value %q was not redacted:\n%s", secret, text)
+		}
+	}
+
+	// The redacted subtrees are empty and marked for filling in.
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(output, &parsed); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if credentials, ok := parsed["credentials"].(map[string]interface{}); !ok || len(credentials) != 0 {
+		t.Errorf("expected an empty credentials mapping, got: %v", parsed["credentials"])
+	}
+	if tokens, ok := parsed["apiTokens"].([]interface{}); !ok || len(tokens) != 0 {
+		t.Errorf("expected an empty apiTokens sequence, got: %v", parsed["apiTokens"])
+	}
+	found, err := FindRedactedValues(output)
+	if err != nil || !slices.Equal(found, redacted) {
+		t.Errorf("FindRedactedValues() = %v (err: %v), expected %v\n%s", found, err, redacted, text)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metahttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Progress callback for Download(). Called with the number of bytes downloaded so far and
+// the total size of the file, or -1 if the size is not known.
+type DownloadProgressFunc func(downloaded, total int64)
+
+// Options for Download(). The zero value downloads the file without checksum verification
+// and with the default number of retries.
+type DownloadOptions struct {
+	ExpectedSHA256 string               // Expected SHA256 checksum of the file (in hex), if known.
+	SHA256URL      string               // URL of a '.sha256' file with the expected checksum, used if ExpectedSHA256 is not set.
+	MaxRetries     int                  // Number of retries on transient failures, 0 for the default.
+	OnProgress     DownloadProgressFunc // Called as the download progresses, if set.
+}
+
+// Default number of retries on transient download failures.
+const defaultDownloadMaxRetries = 3
+
+// Delay before the first retry of a failed download, doubled for each further retry.
+var downloadRetryBaseDelay = 1 * time.Second
+
+// Expected format of a SHA256 checksum.
+var sha256HexRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Error returned by Download() when the server responds with a non-success status code.
+type DownloadStatusError struct {
+	URL        string // URL of the failed request.
+	StatusCode int    // HTTP status code of the response.
+	RequestID  string // Request ID of the failed request.
+}
+
+func (e *DownloadStatusError) Error() string {
+	return fmt.Sprintf("download from %s failed with status code %d (request ID %s)", e.URL, e.StatusCode, e.RequestID)
+}
+
+// Server errors and throttling are worth retrying, other error responses are not.
+func (e *DownloadStatusError) isTransient() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+}
+
+// Download a file from the specified URL to the specified file path. The file is first
+// downloaded into a temporary file in the same directory and only renamed into place once
+// the download has completed and its checksum (if known) has been verified, so a failed
+// download never leaves a partial file behind. Transient failures are retried with an
+// exponential backoff, resuming the download with a Range request if the server supports it.
+func Download(c *Client, url string, filePath string, opts DownloadOptions) error {
+	// Resolve the expected checksum, if any.
+	expectedSHA256 := strings.ToLower(strings.TrimSpace(opts.ExpectedSHA256))
+	if expectedSHA256 == "" && opts.SHA256URL != "" {
+		var err error
+		expectedSHA256, err = fetchExpectedSHA256(c, opts.SHA256URL)
+		if err != nil {
+			return err
+		}
+	}
+	if expectedSHA256 != "" && !sha256HexRegex.MatchString(expectedSHA256) {
+		return fmt.Errorf("invalid expected SHA256 checksum '%s'", expectedSHA256)
+	}
+
+	// Download into a temporary file in the target directory, so that the final rename is atomic.
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), fmt.Sprintf(".%s.*.partial", filepath.Base(filePath)))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for download: %w", err)
+	}
+	tmpFilePath := tmpFile.Name()
+	completed := false
+	defer func() {
+		if !completed {
+			tmpFile.Close()
+			os.Remove(tmpFilePath)
+		}
+	}()
+
+	// Download the file, retrying on transient failures.
+	maxRetries := opts.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultDownloadMaxRetries
+	}
+	var downloaded int64
+	for attempt := 0; ; attempt++ {
+		downloaded, err = downloadAttempt(c, url, tmpFile, downloaded, opts.OnProgress)
+		if err == nil {
+			break
+		}
+		if !isTransientDownloadError(err) || attempt >= maxRetries {
+			return fmt.Errorf("failed to download file from %s%s (request ID %s): %w", c.BaseURL, url, c.RequestID, err)
+		}
+		delay := downloadRetryBaseDelay << attempt
+		log.Debug().Msgf("Download from %s%s failed after %d bytes, retrying in %v (attempt %d/%d): %v", c.BaseURL, url, downloaded, delay, attempt+1, maxRetries, err)
+		time.Sleep(delay)
+	}
+
+	// Verify the checksum of the complete file.
+	if expectedSHA256 != "" {
+		if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read downloaded file: %w", err)
+		}
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, tmpFile); err != nil {
+			return fmt.Errorf("failed to read downloaded file: %w", err)
+		}
+		actualSHA256 := hex.EncodeToString(hasher.Sum(nil))
+		if actualSHA256 != expectedSHA256 {
+			return fmt.Errorf("checksum mismatch for file downloaded from %s%s: expected SHA256 %s, got %s", c.BaseURL, url, expectedSHA256, actualSHA256)
+		}
+		log.Debug().Msgf("Verified SHA256 checksum of downloaded file: %s", actualSHA256)
+	}
+
+	// Move the file into place.
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write downloaded file: %w", err)
+	}
+	if err := os.Rename(tmpFilePath, filePath); err != nil {
+		return fmt.Errorf("failed to move downloaded file to %s: %w", filePath, err)
+	}
+	completed = true
+	return nil
+}
+
+// Make a single attempt at downloading the file into the target file, starting from the given
+// offset. If the server doesn't honor the Range request, the download restarts from the
+// beginning. Returns the number of bytes in the target file after the attempt.
+func downloadAttempt(c *Client, url string, file *os.File, offset int64, onProgress DownloadProgressFunc) (int64, error) {
+	request := c.Resty.R().SetDoNotParseResponse(true)
+	if offset > 0 {
+		request.SetHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	response, err := request.Get(url)
+	if err != nil {
+		return offset, err
+	}
+	body := response.RawBody()
+	defer body.Close()
+
+	// Resume from the offset (if the server returned the requested range) or restart.
+	switch response.StatusCode() {
+	case http.StatusPartialContent:
+		if offset == 0 || !strings.HasPrefix(response.Header().Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return truncateDownload(file, fmt.Errorf("unexpected Content-Range '%s' in response", response.Header().Get("Content-Range")))
+		}
+		log.Debug().Msgf("Resuming download from %s%s at %d bytes", c.BaseURL, url, offset)
+	case http.StatusOK:
+		if offset > 0 {
+			log.Debug().Msgf("Server doesn't support resuming the download from %s%s, restarting", c.BaseURL, url)
+			if _, err := truncateDownload(file, nil); err != nil {
+				return 0, err
+			}
+			offset = 0
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return truncateDownload(file, fmt.Errorf("server rejected the range request"))
+	default:
+		return offset, &DownloadStatusError{URL: c.BaseURL + url, StatusCode: response.StatusCode(), RequestID: c.RequestID}
+	}
+
+	// Resolve total size of the file, if known.
+	total := int64(-1)
+	if contentLength := response.RawResponse.ContentLength; contentLength >= 0 {
+		total = offset + contentLength
+	}
+
+	// Stream the body into the file.
+	writer := &progressWriter{file: file, written: offset, total: total, onProgress: onProgress}
+	_, err = io.Copy(writer, body)
+	if err != nil {
+		return writer.written, err
+	}
+	if total >= 0 && writer.written != total {
+		return writer.written, io.ErrUnexpectedEOF
+	}
+	return writer.written, nil
+}
+
+// Discard the partially downloaded data so that the next attempt starts from the beginning.
+func truncateDownload(file *os.File, err error) (int64, error) {
+	if truncErr := file.Truncate(0); truncErr != nil {
+		return 0, truncErr
+	}
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return 0, seekErr
+	}
+	return 0, err
+}
+
+// Network errors and server-side failures are retried. Local file errors and error responses
+// (eg, 404 Not Found) are not.
+func isTransientDownloadError(err error) bool {
+	var statusErr *DownloadStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.isTransient()
+	}
+	var pathErr *os.PathError
+	return !errors.As(err, &pathErr)
+}
+
+// Fetch the expected checksum from a '.sha256' file. The file contains the checksum in hex,
+// optionally followed by the file name (as output by 'sha256sum').
+func fetchExpectedSHA256(c *Client, url string) (string, error) {
+	response, err := c.Resty.R().Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum from %s (request ID %s): %w", url, c.RequestID, err)
+	}
+	if response.IsError() {
+		return "", fmt.Errorf("failed to fetch checksum from %s with status code %d (request ID %s)", url, response.StatusCode(), c.RequestID)
+	}
+	fields := strings.Fields(response.String())
+	if len(fields) == 0 || !sha256HexRegex.MatchString(strings.ToLower(fields[0])) {
+		return "", fmt.Errorf("invalid checksum file %s: expecting a SHA256 checksum in hex", url)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// Writer that writes to the download file and reports the progress.
+type progressWriter struct {
+	file       *os.File
+	written    int64
+	total      int64
+	onProgress DownloadProgressFunc
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.written += int64(n)
+	if w.onProgress != nil {
+		w.onProgress(w.written, w.total)
+	}
+	return n, err
+}
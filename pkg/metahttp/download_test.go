@@ -0,0 +1,187 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metahttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/metaplay/cli/pkg/auth"
+)
+
+// Content served by the test servers.
+var testDownloadContent = bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+func testDownloadSHA256() string {
+	sum := sha256.Sum256(testDownloadContent)
+	return hex.EncodeToString(sum[:])
+}
+
+// Create a client for the test server and make retries immediate.
+func newTestDownloadClient(t *testing.T, server *httptest.Server) *Client {
+	oldDelay := downloadRetryBaseDelay
+	downloadRetryBaseDelay = time.Millisecond
+	t.Cleanup(func() { downloadRetryBaseDelay = oldDelay })
+	return NewClient(&auth.TokenSet{AccessToken: "test-token"}, server.URL)
+}
+
+// Check that the target directory contains only the expected files, ie, no partial downloads.
+func checkDirFiles(t *testing.T, dir string, expected ...string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Errorf("files in target directory = %v, expected %v", names, expected)
+	}
+}
+
+func TestDownloadResumesAfterDroppedConnection(t *testing.T) {
+	var requestCount atomic.Int32
+	var resumedFrom atomic.Value
+	resumedFrom.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestCount.Add(1) == 1 {
+			// Drop the connection half-way through the first transfer.
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(testDownloadContent)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(testDownloadContent[:len(testDownloadContent)/2])
+			w.(http.Flusher).Flush()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		resumedFrom.Store(r.Header.Get("Range"))
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(testDownloadContent))
+	}))
+	defer server.Close()
+
+	client := newTestDownloadClient(t, server)
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.bin")
+	var lastDownloaded, lastTotal int64
+	err := Download(client, "/file.bin", filePath, DownloadOptions{
+		ExpectedSHA256: testDownloadSHA256(),
+		OnProgress: func(downloaded, total int64) {
+			lastDownloaded, lastTotal = downloaded, total
+		},
+	})
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %v", err)
+	}
+	if !bytes.Equal(content, testDownloadContent) {
+		t.Errorf("downloaded content doesn't match (got %d bytes, expected %d)", len(content), len(testDownloadContent))
+	}
+	if requestCount.Load() != 2 {
+		t.Errorf("request count = %d, expected 2", requestCount.Load())
+	}
+	if rangeHeader := resumedFrom.Load().(string); rangeHeader == "" || rangeHeader == "bytes=0-" {
+		t.Errorf("expected the second request to resume the download, got Range header %q", rangeHeader)
+	}
+	if lastDownloaded != int64(len(testDownloadContent)) || lastTotal != int64(len(testDownloadContent)) {
+		t.Errorf("last progress = %d/%d, expected %d/%d", lastDownloaded, lastTotal, len(testDownloadContent), len(testDownloadContent))
+	}
+	checkDirFiles(t, dir, "file.bin")
+}
+
+func TestDownloadRetriesServerErrors(t *testing.T) {
+	var requestCount atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestCount.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(testDownloadContent)
+	}))
+	defer server.Close()
+
+	client := newTestDownloadClient(t, server)
+	filePath := filepath.Join(t.TempDir(), "file.bin")
+	if err := Download(client, "/file.bin", filePath, DownloadOptions{}); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if requestCount.Load() != 3 {
+		t.Errorf("request count = %d, expected 3", requestCount.Load())
+	}
+}
+
+func TestDownloadDoesNotRetryNotFound(t *testing.T) {
+	var requestCount atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := newTestDownloadClient(t, server)
+	dir := t.TempDir()
+	err := Download(client, "/file.bin", filepath.Join(dir, "file.bin"), DownloadOptions{})
+	var statusErr *DownloadStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a DownloadStatusError with status 404, got: %v", err)
+	}
+	if requestCount.Load() != 1 {
+		t.Errorf("request count = %d, expected 1", requestCount.Load())
+	}
+	checkDirFiles(t, dir)
+}
+
+func TestDownloadVerifiesChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file.bin":
+			w.Write(testDownloadContent)
+		case "/file.bin.sha256":
+			fmt.Fprintf(w, "%s  file.bin\n", testDownloadSHA256())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestDownloadClient(t, server)
+	dir := t.TempDir()
+
+	// Mismatching checksum fails and leaves nothing behind.
+	err := Download(client, "/file.bin", filepath.Join(dir, "bad.bin"), DownloadOptions{ExpectedSHA256: sha256Hex("something else")})
+	if err == nil {
+		t.Fatalf("expected checksum mismatch error")
+	}
+	checkDirFiles(t, dir)
+
+	// Checksum from the accompanying .sha256 file.
+	err = Download(client, "/file.bin", filepath.Join(dir, "good.bin"), DownloadOptions{SHA256URL: "/file.bin.sha256"})
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	checkDirFiles(t, dir, "good.bin")
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -7,44 +7,149 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	goversion "github.com/hashicorp/go-version"
 	"github.com/metaplay/cli/internal/version"
 	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/styles"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
-// Wrapper object for accessing an environment within a target stack.
-type Client struct {
-	TokenSet *auth.TokenSet // Tokens to use to access the environment.
-	BaseURL  string         // Base URL of the target API (e.g. 'https://api.metaplay.io')
-	Resty    *resty.Client  // Resty client with authorization header configured.
+// Response header with which servers can indicate the minimum CLI version they support.
+const minCliVersionHeader = "X-Metaplay-Min-CLI-Version"
+
+// Logger used for out-of-band warnings (eg, outdated CLI). Defaults to the global logger.
+var warningLogger *zerolog.Logger
+
+// Only warn about an outdated CLI once per command.
+var outdatedWarningShown = false
+
+// Request ID shared by all HTTP requests made during a single CLI command, sent
+// in the X-Request-ID header so requests can be correlated with server logs.
+var commandRequestID = uuid.NewString()
+
+// Has any request been made with the current command request ID?
+var hasSentRequests = false
+
+// Get the request ID used for all HTTP requests made by the current command.
+func GetCommandRequestID() string {
+	return commandRequestID
 }
 
-// NewClient creates a new HTTP client with the given auth token set and base URL.
-func NewClient(tokenSet *auth.TokenSet, baseURL string) *Client {
-	restyClient := resty.New().
-		SetAuthToken(tokenSet.AccessToken).
-		SetBaseURL(baseURL).
-		SetHeader("X-Application-Name", fmt.Sprintf("MetaplayCLI/%s", version.AppVersion))
-	return &Client{
-		TokenSet: tokenSet,
-		BaseURL:  baseURL,
-		Resty:    restyClient,
+// Start a new per-command request ID. Called at the start of each command.
+func ResetCommandRequestID() string {
+	commandRequestID = uuid.NewString()
+	hasSentRequests = false
+	return commandRequestID
+}
+
+// Have any HTTP requests been made during the current command? Used to decide
+// whether the request ID is worth showing to the user.
+func HasSentRequests() bool {
+	return hasSentRequests
+}
+
+// Set the logger to use for out-of-band warnings, so that they don't interfere
+// with the command's primary output (eg, JSON).
+func SetWarningLogger(logger *zerolog.Logger) {
+	warningLogger = logger
+}
+
+// Get the logger to use for out-of-band warnings.
+func GetWarningLogger() *zerolog.Logger {
+	if warningLogger == nil {
+		return &log.Logger
 	}
+	return warningLogger
 }
 
-// Download a file from the specified URL to the specified file path.
-// Note: The file gets created even if the request fails.
-func Download(c *Client, url string, filePath string) (*resty.Response, error) {
-	// Perform the request: download directly to a file.
-	response, err := c.Resty.R().SetOutput(filePath).Get(url)
+// Check whether the server indicated that this CLI is too old, and warn the user about it
+// (only once per command). Never fails the request.
+func checkMinCliVersion(response *resty.Response) {
+	minVersionStr := response.Header().Get(minCliVersionHeader)
+	if minVersionStr == "" || outdatedWarningShown || version.IsDevBuild() {
+		return
+	}
 
+	minVersion, err := goversion.NewVersion(minVersionStr)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to download file from %s%s: %w", c.BaseURL, filePath, err)
+		log.Debug().Msgf("Ignoring invalid %s header '%s': %v", minCliVersionHeader, minVersionStr, err)
+		return
+	}
+	appVersion, err := goversion.NewVersion(version.AppVersion)
+	if err != nil {
+		log.Debug().Msgf("Unable to parse CLI version '%s': %v", version.AppVersion, err)
+		return
+	}
+
+	if appVersion.LessThan(minVersion) {
+		outdatedWarningShown = true
+		GetWarningLogger().Warn().Msgf("Warning: This version of the Metaplay CLI (%s) is older than the minimum version (%s) supported by the server. Update with: %s", version.AppVersion, minVersion, styles.RenderPrompt("metaplay update cli"))
 	}
+}
+
+// Maximum number of bytes of a non-JSON error response body to include in errors.
+const maxRawErrorBodyLength = 500
 
-	return response, nil
+// Error body returned by the APIs in non-2xx responses.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Describe the body of an error response for inclusion in an error message. Uses the
+// message from the APIError if the body is one, otherwise the raw body (truncated).
+// Returns an empty string if the body is empty.
+func describeErrorBody(body []byte) string {
+	var apiError APIError
+	if err := json.Unmarshal(body, &apiError); err == nil && apiError.Message != "" {
+		if apiError.Code != "" {
+			return fmt.Sprintf("%s (code %s)", apiError.Message, apiError.Code)
+		}
+		return apiError.Message
+	}
+
+	rawBody := strings.TrimSpace(string(body))
+	if len(rawBody) > maxRawErrorBodyLength {
+		rawBody = rawBody[:maxRawErrorBodyLength] + "..."
+	}
+	return rawBody
+}
+
+// Wrapper object for accessing an environment within a target stack.
+type Client struct {
+	TokenSet  *auth.TokenSet // Tokens to use to access the environment.
+	BaseURL   string         // Base URL of the target API (e.g. 'https://api.metaplay.io')
+	Resty     *resty.Client  // Resty client with authorization header configured.
+	RequestID string         // Value of the X-Request-ID header sent with each request.
+}
+
+// NewClient creates a new HTTP client with the given auth token set and base URL.
+func NewClient(tokenSet *auth.TokenSet, baseURL string) *Client {
+	client := &Client{
+		TokenSet:  tokenSet,
+		BaseURL:   baseURL,
+		RequestID: commandRequestID,
+	}
+	client.Resty = resty.New().
+		SetAuthToken(tokenSet.AccessToken).
+		SetBaseURL(baseURL).
+		SetHeader("X-Application-Name", fmt.Sprintf("MetaplayCLI/%s", version.AppVersion)).
+		OnBeforeRequest(func(rc *resty.Client, req *resty.Request) error {
+			req.SetHeader("X-Request-ID", client.RequestID)
+			hasSentRequests = true
+			log.Debug().Msgf("%s %s%s [request ID: %s]", req.Method, baseURL, req.URL, client.RequestID)
+			return nil
+		}).
+		OnAfterResponse(func(rc *resty.Client, response *resty.Response) error {
+			checkMinCliVersion(response)
+			return nil
+		})
+	return client
 }
 
 // Make a HTTP request to the target URL with the specified method and body, and unmarshal the response into the specified type.
@@ -73,7 +178,7 @@ func Request[TResponse any](c *Client, method string, url string, body interface
 
 	// Handle request errors
 	if err != nil {
-		return result, fmt.Errorf("%s request to %s%s failed: %w", method, c.BaseURL, url, err)
+		return result, fmt.Errorf("%s request to %s%s failed (request ID %s): %w", method, c.BaseURL, url, c.RequestID, err)
 	}
 
 	// Debug log the raw response.
@@ -81,7 +186,10 @@ func Request[TResponse any](c *Client, method string, url string, body interface
 
 	// Check response status code
 	if response.StatusCode() < http.StatusOK || response.StatusCode() >= http.StatusMultipleChoices {
-		return result, fmt.Errorf("%s request to %s%s failed with status code %d", method, c.BaseURL, url, response.StatusCode())
+		if bodyDesc := describeErrorBody(response.Body()); bodyDesc != "" {
+			return result, fmt.Errorf("%s request to %s%s failed with status code %d (request ID %s): %s", method, c.BaseURL, url, response.StatusCode(), c.RequestID, bodyDesc)
+		}
+		return result, fmt.Errorf("%s request to %s%s failed with status code %d (request ID %s)", method, c.BaseURL, url, response.StatusCode(), c.RequestID)
 	}
 
 	// If type TResult is just string, get the body of the HTTP response as plaintext
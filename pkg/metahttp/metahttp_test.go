@@ -0,0 +1,196 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metahttp_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/metahttp"
+)
+
+// Access token used by the test clients.
+const testAccessToken = "test-access-token"
+
+// Create a Client that sends its requests to an httptest.Server serving the handler. The
+// server is closed at the end of the test.
+func NewTestClient(t *testing.T, handler http.Handler) *metahttp.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return metahttp.NewClient(&auth.TokenSet{AccessToken: testAccessToken}, server.URL)
+}
+
+type testItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Handler that echoes the request back as a testItem: the method and body as the name, and the
+// body length as the count. Also checks the request headers.
+func echoHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer "+testAccessToken {
+			t.Errorf("Authorization = %q, expected the bearer token", auth)
+		}
+		if r.Header.Get("X-Request-ID") == "" {
+			t.Errorf("missing X-Request-ID header")
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(testItem{Name: r.Method + " " + r.URL.Path + " " + strings.TrimSpace(string(body)), Count: len(body)})
+	}
+}
+
+func TestRequestSuccess(t *testing.T) {
+	client := NewTestClient(t, echoHandler(t))
+	body := map[string]string{"key": "value"}
+
+	tests := []struct {
+		method   string
+		request  func() (testItem, error)
+		expected string
+	}{
+		{http.MethodGet, func() (testItem, error) { return metahttp.Get[testItem](client, "/v0/items") }, "GET /v0/items "},
+		{http.MethodPost, func() (testItem, error) { return metahttp.Post[testItem](client, "/v0/items", body) }, `POST /v0/items {"key":"value"}`},
+		{http.MethodPut, func() (testItem, error) { return metahttp.Put[testItem](client, "/v0/items/1", body) }, `PUT /v0/items/1 {"key":"value"}`},
+		{http.MethodDelete, func() (testItem, error) { return metahttp.Delete[testItem](client, "/v0/items/1", nil) }, "DELETE /v0/items/1 "},
+		{http.MethodDelete, func() (testItem, error) { return metahttp.Delete[testItem](client, "/v0/items/1", body) }, `DELETE /v0/items/1 {"key":"value"}`},
+	}
+
+	for _, test := range tests {
+		item, err := test.request()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.method, err)
+			continue
+		}
+		if item.Name != test.expected {
+			t.Errorf("%s: name = %q, expected %q", test.method, item.Name, test.expected)
+		}
+	}
+}
+
+func TestRequestStringResponse(t *testing.T) {
+	client := NewTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, "apiVersion: v1\nkind: Config\n")
+	}))
+
+	// String responses are returned as-is, without JSON decoding (trailing whitespace is trimmed).
+	result, err := metahttp.Post[string](client, "/v0/credentials/tough-falcons/k8s", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "apiVersion: v1\nkind: Config" {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestRequestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		body        string
+		contains    []string
+		notContains []string
+	}{
+		{"api error", http.StatusNotFound, `{"code":"NotFound","message":"environment not found"}`, []string{"status code 404", "environment not found (code NotFound)"}, nil},
+		{"api error without code", http.StatusForbidden, `{"message":"access denied"}`, []string{"status code 403", "access denied"}, []string{"(code"}},
+		{"raw body", http.StatusBadGateway, "<html>Bad Gateway</html>", []string{"status code 502", "<html>Bad Gateway</html>"}, nil},
+		{"long raw body", http.StatusInternalServerError, strings.Repeat("x", 1000), []string{"status code 500", strings.Repeat("x", 500) + "..."}, []string{strings.Repeat("x", 501)}},
+		{"empty body", http.StatusServiceUnavailable, "", []string{"status code 503"}, []string{"): "}},
+		{"redirect", http.StatusNotModified, "", []string{"status code 304"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.statusCode)
+				_, _ = io.WriteString(w, test.body)
+			}))
+
+			_, err := metahttp.Get[testItem](client, "/v0/items")
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			expected := append([]string{
+				fmt.Sprintf("GET request to %s/v0/items", client.BaseURL),
+				fmt.Sprintf("request ID %s", client.RequestID),
+			}, test.contains...)
+			for _, str := range expected {
+				if !strings.Contains(err.Error(), str) {
+					t.Errorf("error %q does not contain %q", err, str)
+				}
+			}
+			for _, str := range test.notContains {
+				if strings.Contains(err.Error(), str) {
+					t.Errorf("error %q should not contain %q", err, str)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestMalformedJSON(t *testing.T) {
+	client := NewTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"name": "truncated`)
+	}))
+
+	if _, err := metahttp.Get[testItem](client, "/v0/items"); err == nil {
+		t.Errorf("expected an error for malformed JSON")
+	}
+
+	// A JSON value of the wrong type is also an error.
+	client = NewTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"name": 123}`)
+	}))
+	if _, err := metahttp.Get[testItem](client, "/v0/items"); err == nil {
+		t.Errorf("expected an error for a mismatching JSON type")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	client := NewTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer close(release)
+	client.Resty.SetTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := metahttp.Get[testItem](client, "/v0/slow")
+	if err == nil {
+		t.Fatalf("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s, expected it to time out quickly", elapsed)
+	}
+	for _, str := range []string{fmt.Sprintf("GET request to %s/v0/slow failed", client.BaseURL), "Timeout"} {
+		if !strings.Contains(err.Error(), str) {
+			t.Errorf("error %q does not contain %q", err, str)
+		}
+	}
+}
+
+func TestRequestConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	client := metahttp.NewClient(&auth.TokenSet{AccessToken: testAccessToken}, server.URL)
+	server.Close()
+
+	_, err := metahttp.Get[testItem](client, "/v0/items")
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("GET request to %s/v0/items failed", server.URL)) {
+		t.Errorf("expected a request error with the URL, got: %v", err)
+	}
+}
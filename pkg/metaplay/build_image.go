@@ -0,0 +1,415 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/metaplay/cli/pkg/containerutil"
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/rs/zerolog/log"
+)
+
+// Docker image label marking images built from a git working tree with uncommitted changes.
+const DockerImageDirtyLabel = "io.metaplay.dirty"
+
+// Name of the 'docker buildx bake' file in the build root directory, see BuildImageOptions.BakeTarget.
+const BakeFileName = "docker-bake.hcl"
+
+// Returned (wrapped) by BuildImage() when the docker build itself fails, as opposed to
+// failing to start the build.
+var ErrBuildFailed = errors.New("docker build failed")
+
+// Build engines supported by BuildImage(). The 'buildx' and 'buildkit' engines require docker,
+// and the 'podman' engine requires podman.
+var BuildEngines = []string{"buildx", "buildkit", "podman"}
+
+// Target architectures supported by BuildImage().
+var BuildArchitectures = []string{"amd64", "arm64"}
+
+// Build args that BuildImage() passes to Dockerfile.server. Custom build args with the same
+// key (see BuildImageOptions.BuildArgs) override these.
+var BuiltinBuildArgs = []string{"SDK_ROOT", "PROJECT_ROOT", "BACKEND_DIR", "SHARED_CODE_DIR", "METAPLAY_DOTNET_SDK_VERSION", "PROJECT_ID", "BUILD_NUMBER", "COMMIT_ID"}
+
+// Options for BuildImage().
+type BuildImageOptions struct {
+	Project      *metaproj.MetaplayProject // Project to build the image for, required.
+	ImageName    string                    // Name of the image to build, in format 'name:tag', required.
+	CommitID     string                    // Commit ID to embed into the image, or 'none'.
+	BuildNumber  string                    // Build number to embed into the image, or 'none'.
+	IsDirty      bool                      // Whether the image is built from uncommitted changes, stored in DockerImageDirtyLabel.
+	Architecture string                    // Target architecture, one of BuildArchitectures, empty for 'amd64'.
+	Runtime      *containerutil.Runtime    // Container runtime to build with, nil for docker.
+	Engine       string                    // Build engine, one of BuildEngines, empty for the runtime's default (see DefaultBuildEngine()).
+	Squash       bool                      // Squash the image layers, only supported by 'podman' (buildkit ignores it).
+	Compress     string                    // Layer compression (eg, 'zstd'), only supported by 'buildx'.
+	BakeTarget   string                    // Target to build with 'docker buildx bake' from the build root's BakeFileName, empty for 'docker buildx build'. Only supported by 'buildx'.
+	BuildArgs    []string                  // Custom build args in format 'KEY=VALUE', see ParseBuildArg().
+	ExtraArgs    []string                  // Extra arguments to pass to 'docker build' (or 'docker buildx bake').
+	Stdout       io.Writer                 // Receives the output of docker, nil to discard.
+	Stderr       io.Writer                 // Receives the error output of docker, nil to discard.
+	Progress     ProgressCallbacks         // Progress reporting, the docker invocation is reported as a log line.
+}
+
+// Result of BuildImage().
+type BuildResult struct {
+	ImageName string // Name of the built image, 'name:tag'.
+	ImageID   string // ID of the built image (sha256 digest of the image config).
+}
+
+// Check that the project has the inputs for building a server image: the Metaplay SDK with
+// its Dockerfile.server, the project backend, and the shared code. The returned errors wrap
+// fs.ErrNotExist.
+func CheckBuildInputs(project *metaproj.MetaplayProject) error {
+	sdkRootPath := project.GetSdkRootDir()
+	if _, err := os.Stat(sdkRootPath); os.IsNotExist(err) {
+		return fmt.Errorf("the Metaplay SDK directory '%s' does not exist: %w", sdkRootPath, fs.ErrNotExist)
+	}
+
+	dockerFilePath := filepath.Join(sdkRootPath, "Dockerfile.server")
+	if _, err := os.Stat(dockerFilePath); os.IsNotExist(err) {
+		return fmt.Errorf("cannot locate Dockerfile.server at %s: %w", dockerFilePath, fs.ErrNotExist)
+	}
+
+	projectBackendDir := project.GetBackendDir()
+	if _, err := os.Stat(projectBackendDir); os.IsNotExist(err) {
+		return fmt.Errorf("unable to find project backend in '%s': %w", projectBackendDir, fs.ErrNotExist)
+	}
+
+	sharedCodeDir := project.GetSharedCodeDir()
+	if _, err := os.Stat(sharedCodeDir); os.IsNotExist(err) {
+		return fmt.Errorf("the shared code directory (%s) does not exist: %w", sharedCodeDir, fs.ErrNotExist)
+	}
+
+	return nil
+}
+
+// Check whether the project's build root directory has a BakeFileName for 'docker buildx bake'.
+func HasBakeFile(project *metaproj.MetaplayProject) bool {
+	_, err := os.Stat(filepath.Join(project.GetBuildRootDir(), BakeFileName))
+	return err == nil
+}
+
+// Build the game server docker image of the project with the local docker. The docker build
+// is cancelled if the context is cancelled.
+func BuildImage(ctx context.Context, opts BuildImageOptions) (*BuildResult, error) {
+	project := opts.Project
+	if project == nil {
+		return nil, errors.New("the project to build must be specified")
+	}
+	if opts.ImageName == "" {
+		return nil, errors.New("the name of the image to build must be specified")
+	}
+	for _, buildArg := range opts.BuildArgs {
+		if _, _, err := ParseBuildArg(buildArg); err != nil {
+			return nil, err
+		}
+	}
+	if err := CheckBuildInputs(project); err != nil {
+		return nil, err
+	}
+
+	// Resolve target platform.
+	architecture := opts.Architecture
+	if architecture == "" {
+		architecture = "amd64"
+	}
+	if !slices.Contains(BuildArchitectures, architecture) {
+		return nil, fmt.Errorf("invalid architecture '%s', must be one of %v", architecture, BuildArchitectures)
+	}
+	platform := fmt.Sprintf("linux/%s", architecture)
+
+	// Resolve the container runtime and build engine.
+	rt := opts.Runtime
+	if rt == nil {
+		rt = containerutil.DefaultRuntime()
+	}
+	buildEngine := opts.Engine
+	if buildEngine == "" {
+		buildEngine = DefaultBuildEngine(rt)
+	}
+	if err := CheckBuildEngine(rt, buildEngine); err != nil {
+		return nil, err
+	}
+	if opts.BakeTarget != "" {
+		if buildEngine != "buildx" {
+			return nil, fmt.Errorf("building with %s is only supported by the buildx build engine, got %s", BakeFileName, buildEngine)
+		}
+		if !HasBakeFile(project) {
+			return nil, fmt.Errorf("cannot locate %s in the build root directory %s: %w", BakeFileName, project.GetBuildRootDir(), fs.ErrNotExist)
+		}
+	}
+
+	// Resolve docker build root directory. All other paths need to be made relative to it.
+	buildRootDir := project.GetBuildRootDir()
+	sdkRootPath := project.GetSdkRootDir()
+	dockerFilePath := filepath.Join(sdkRootPath, "Dockerfile.server")
+
+	// Rebase paths to be relative to docker build root.
+	rebasedSdkRoot, err := rebasePath(sdkRootPath, buildRootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relative path to MetaplaySDK/ from build root: %w", err)
+	}
+	rebasedDockerFilePath, err := rebasePath(dockerFilePath, buildRootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relative path to Dockerfile.server from build root: %w", err)
+	}
+	rebasedProjectRoot, err := rebasePath(project.RelativeDir, buildRootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relative path to project root from build root: %w", err)
+	}
+
+	// Rebase paths relative to project root dir (where metaplay-project.yaml is located).
+	rebasedBackendDir, err := rebasePath(project.GetBackendDir(), project.RelativeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relative path to project backend directory from project root: %w", err)
+	}
+	rebasedSharedCodeDir, err := rebasePath(project.GetSharedCodeDir(), project.RelativeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relative path to project shared code directory from project root: %w", err)
+	}
+
+	// Silence docker's recomendation messages at end-of-build.
+	var dockerEnv []string = os.Environ()
+	dockerEnv = append(dockerEnv, "DOCKER_CLI_HINTS=false")
+
+	// Handle build engine differences.
+	var buildEngineArgs []string
+	switch buildEngine {
+	case "buildkit":
+		dockerEnv = append(dockerEnv, "DOCKER_BUILDKIT=1")
+		buildEngineArgs = []string{"build"}
+	case "podman":
+		// Podman builds OCI images by default, use the docker format for ECR compatibility.
+		buildEngineArgs = []string{"build", "--format", "docker"}
+	default:
+		buildEngineArgs = []string{"buildx", "build", "--load"}
+	}
+
+	// Handle layer minimization options.
+	if opts.Squash {
+		if buildEngine != "podman" {
+			return nil, fmt.Errorf("squashing the image is not supported by the %s build engine", buildEngine)
+		}
+		buildEngineArgs = append(buildEngineArgs, "--squash")
+	}
+	if opts.Compress != "" {
+		if buildEngine != "buildx" {
+			return nil, fmt.Errorf("compressing the image is not supported by the %s build engine", buildEngine)
+		}
+		// Output into the local docker like --load does, but with the requested compression.
+		buildEngineArgs = []string{"buildx", "build", "--output", fmt.Sprintf("type=docker,compression=%s,force-compression=true", opts.Compress)}
+	}
+
+	// Resolve .NET runtime version to build project for, expects '<major>.<minor>'.
+	projectDotnetVersionSegments := project.Config.DotnetRuntimeVersion.Segments()
+	projectDotnetVersion := fmt.Sprintf("%d.%d", projectDotnetVersionSegments[0], projectDotnetVersionSegments[1])
+
+	// Resolve the build args. The custom build args are appended after the built-in ones:
+	// docker uses the last value given for a key, so custom build args override the built-in ones.
+	buildArgs := []string{
+		"SDK_ROOT=" + filepath.ToSlash(rebasedSdkRoot),
+		"PROJECT_ROOT=" + filepath.ToSlash(rebasedProjectRoot),
+		"BACKEND_DIR=" + filepath.ToSlash(rebasedBackendDir),
+		"SHARED_CODE_DIR=" + filepath.ToSlash(rebasedSharedCodeDir),
+		"METAPLAY_DOTNET_SDK_VERSION=" + projectDotnetVersion,
+		fmt.Sprintf("PROJECT_ID=%s", project.Config.ProjectHumanID),
+		fmt.Sprintf("BUILD_NUMBER=%s", opts.BuildNumber),
+		fmt.Sprintf("COMMIT_ID=%s", opts.CommitID),
+	}
+	buildArgs = append(buildArgs, opts.BuildArgs...)
+	dirtyLabel := fmt.Sprintf("%s=%t", DockerImageDirtyLabel, opts.IsDirty)
+
+	// With buildx, capture the build metadata (including the image ID) into a temp file.
+	metadataFilePath := ""
+	if buildEngine == "buildx" {
+		metadataFile, err := os.CreateTemp("", "metaplay-build-metadata-*.json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary build metadata file: %w", err)
+		}
+		metadataFile.Close()
+		metadataFilePath = metadataFile.Name()
+		defer os.Remove(metadataFilePath)
+	}
+
+	// Resolve final docker build invocation
+	var dockerArgs []string
+	if opts.BakeTarget != "" {
+		// The bake file defines the Dockerfile and the build context of the target, the CLI
+		// fills in the image name, platform, build args, and labels.
+		dockerArgs = newBakeArgs(opts.BakeTarget, opts.ImageName, platform, opts.Compress, buildArgs, dirtyLabel)
+		dockerArgs = append(dockerArgs, "--metadata-file", metadataFilePath)
+		dockerArgs = append(dockerArgs, opts.ExtraArgs...)
+		dockerArgs = append(dockerArgs, opts.BakeTarget)
+	} else {
+		dockerArgs = append(
+			buildEngineArgs,
+			[]string{
+				"--pull",
+				"-t", opts.ImageName,
+				"-f", filepath.ToSlash(rebasedDockerFilePath),
+				"--platform", platform,
+			}...,
+		)
+		for _, buildArg := range buildArgs {
+			dockerArgs = append(dockerArgs, "--build-arg", buildArg)
+		}
+		dockerArgs = append(dockerArgs, "--label", dirtyLabel)
+		if metadataFilePath != "" {
+			dockerArgs = append(dockerArgs, "--metadata-file", metadataFilePath)
+		}
+		dockerArgs = append(dockerArgs, opts.ExtraArgs...)
+		dockerArgs = append(dockerArgs, ".")
+	}
+
+	opts.Progress.log(fmt.Sprintf("%s %s", rt.Binary, strings.Join(dockerArgs, " ")))
+
+	// Execute the docker build.
+	cmd := rt.Command(ctx, dockerArgs...)
+	cmd.Env = dockerEnv
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	cmd.Dir = buildRootDir
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBuildFailed, err)
+	}
+
+	// Resolve the ID of the built image.
+	imageID, err := resolveBuiltImageID(ctx, rt, metadataFilePath, opts.BakeTarget, opts.ImageName)
+	if err != nil {
+		return nil, err
+	}
+	return &BuildResult{ImageName: opts.ImageName, ImageID: imageID}, nil
+}
+
+// Get the default build engine for the container runtime: 'podman' for podman, and for docker
+// 'buildx' if it's installed and 'buildkit' otherwise.
+func DefaultBuildEngine(rt *containerutil.Runtime) string {
+	switch {
+	case rt.Name == containerutil.RuntimePodman:
+		return "podman"
+	case rt.Supports(containerutil.CapabilityBuildx):
+		return "buildx"
+	default:
+		return "buildkit"
+	}
+}
+
+// Check that the build engine is valid and can be used with the container runtime.
+func CheckBuildEngine(rt *containerutil.Runtime, buildEngine string) error {
+	if !slices.Contains(BuildEngines, buildEngine) {
+		return fmt.Errorf("invalid build engine '%s', must be one of: %v", buildEngine, BuildEngines)
+	}
+	if (buildEngine == "podman") != (rt.Name == containerutil.RuntimePodman) {
+		return fmt.Errorf("the %s build engine cannot be used with %s, use the '%s' engine instead", buildEngine, rt.Name, DefaultBuildEngine(rt))
+	}
+	if buildEngine == "buildx" {
+		return rt.RequireCapability(containerutil.CapabilityBuildx, "the buildx build engine")
+	}
+	return nil
+}
+
+// Parse a custom build arg in format 'KEY=VALUE' (the value may be empty).
+func ParseBuildArg(buildArg string) (string, string, error) {
+	key, value, found := strings.Cut(buildArg, "=")
+	if !found {
+		return "", "", fmt.Errorf("invalid build arg '%s', expecting format 'KEY=VALUE'", buildArg)
+	}
+	if key == "" || strings.ContainsAny(key, " \t\n") {
+		return "", "", fmt.Errorf("invalid build arg '%s', the key must be non-empty and contain no whitespace", buildArg)
+	}
+	return key, value, nil
+}
+
+// Resolve the arguments for building the target of the build root's BakeFileName with
+// 'docker buildx bake', overriding the target's settings with the given ones.
+func newBakeArgs(target, imageName, platform, compress string, buildArgs []string, label string) []string {
+	args := []string{"buildx", "bake", "-f", BakeFileName, "--pull"}
+	if compress != "" {
+		args = append(args, "--set", fmt.Sprintf("%s.output=type=docker,compression=%s,force-compression=true", target, compress))
+	} else {
+		args = append(args, "--load")
+	}
+	args = append(args,
+		"--set", fmt.Sprintf("%s.tags=%s", target, imageName),
+		"--set", fmt.Sprintf("%s.platform=%s", target, platform),
+	)
+	for _, buildArg := range buildArgs {
+		args = append(args, "--set", fmt.Sprintf("%s.args.%s", target, buildArg))
+	}
+	args = append(args, "--set", fmt.Sprintf("%s.labels.%s", target, label))
+	return args
+}
+
+// Parse the image ID from the buildx metadata file contents. With bake, the metadata is keyed
+// by the target name. Returns an empty string if the image ID is not found.
+func parseBuildMetadataImageID(content []byte, bakeTarget string) string {
+	var metadata map[string]any
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return ""
+	}
+	if bakeTarget != "" {
+		if metadata, _ = metadata[bakeTarget].(map[string]any); metadata == nil {
+			return ""
+		}
+	}
+	imageID, _ := metadata["containerimage.config.digest"].(string)
+	return imageID
+}
+
+// Resolve the ID (sha256 digest of the image config) of the built image: from the buildx
+// metadata file, if available, or otherwise by inspecting the image.
+func resolveBuiltImageID(ctx context.Context, rt *containerutil.Runtime, metadataFilePath string, bakeTarget string, imageName string) (string, error) {
+	if metadataFilePath != "" {
+		content, err := os.ReadFile(metadataFilePath)
+		if err == nil {
+			if imageID := parseBuildMetadataImageID(content, bakeTarget); imageID != "" {
+				return imageID, nil
+			}
+		}
+		log.Debug().Msgf("Image ID not found in build metadata file %s, inspecting the image instead", metadataFilePath)
+	}
+
+	output, err := rt.Command(ctx, "image", "inspect", "--format", "{{.Id}}", imageName).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the ID of the built image %s: %w", imageName, err)
+	}
+
+	// Podman outputs the ID without the 'sha256:' prefix.
+	imageID := strings.TrimSpace(string(output))
+	if !strings.Contains(imageID, ":") {
+		imageID = "sha256:" + imageID
+	}
+	return imageID, nil
+}
+
+// rebasePath calculates a new path for `targetPath` such that it is relative
+// to `newBaseDir` instead of current working directory.
+func rebasePath(targetPath, newBaseDir string) (string, error) {
+	// Resolve absolute directories of new base path & target path.
+	absNewBaseDir, err := filepath.Abs(newBaseDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve absolute base path: %w", err)
+	}
+	absTargetPath, err := filepath.Abs(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve absolute target path: %w", err)
+	}
+
+	// Compute the relative path to the new base.
+	relativePath, err := filepath.Rel(absNewBaseDir, absTargetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve relative path: %w", err)
+	}
+	return relativePath, nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRebasePath(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		targetPath string
+		newBaseDir string
+		expected   string
+	}{
+		{"Backend/Server", "Backend", "Server"},
+		{"Backend", "Backend", "."},
+		{"Backend/Server/Dockerfile", "Backend/Server", "Dockerfile"},
+		{"Backend/Dockerfile.server", "Project/Backend", "../../Backend/Dockerfile.server"},
+		{".", "Backend", ".."},
+		{filepath.Join(cwd, "Backend", "Server"), "Backend", "Server"},
+		{"Backend/Server", filepath.Join(cwd, "Backend"), "Server"},
+		{"Backend/./Server/../Server", "Backend", "Server"},
+	}
+
+	for _, test := range tests {
+		result, err := rebasePath(filepath.FromSlash(test.targetPath), filepath.FromSlash(test.newBaseDir))
+		if err != nil {
+			t.Errorf("rebasePath(%q, %q): unexpected error: %v", test.targetPath, test.newBaseDir, err)
+			continue
+		}
+		if result != filepath.FromSlash(test.expected) {
+			t.Errorf("rebasePath(%q, %q) = %q, expected %q", test.targetPath, test.newBaseDir, result, filepath.FromSlash(test.expected))
+		}
+	}
+}
+
+func TestParseBuildArg(t *testing.T) {
+	tests := []struct {
+		buildArg      string
+		expectedKey   string
+		expectedValue string
+		expectError   bool
+	}{
+		{"FOO=bar", "FOO", "bar", false},
+		{"FOO=", "FOO", "", false},
+		{"FOO=a=b,c", "FOO", "a=b,c", false},
+		{"FOO", "", "", true},
+		{"=bar", "", "", true},
+		{"MY FOO=bar", "", "", true},
+	}
+
+	for _, test := range tests {
+		key, value, err := ParseBuildArg(test.buildArg)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("ParseBuildArg(%q): expected an error", test.buildArg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseBuildArg(%q): unexpected error: %v", test.buildArg, err)
+			continue
+		}
+		if key != test.expectedKey || value != test.expectedValue {
+			t.Errorf("ParseBuildArg(%q) = (%q, %q), expected (%q, %q)", test.buildArg, key, value, test.expectedKey, test.expectedValue)
+		}
+	}
+}
+
+func TestNewBakeArgs(t *testing.T) {
+	args := newBakeArgs("server", "mygame:364cff09", "linux/amd64", "", []string{"PROJECT_ID=mygame", "FOO=a=b"}, "io.metaplay.dirty=false")
+	expected := []string{
+		"buildx", "bake", "-f", "docker-bake.hcl", "--pull", "--load",
+		"--set", "server.tags=mygame:364cff09",
+		"--set", "server.platform=linux/amd64",
+		"--set", "server.args.PROJECT_ID=mygame",
+		"--set", "server.args.FOO=a=b",
+		"--set", "server.labels.io.metaplay.dirty=false",
+	}
+	if !slices.Equal(args, expected) {
+		t.Errorf("newBakeArgs() = %v, expected %v", args, expected)
+	}
+
+	// Compression replaces --load with an output of the same type.
+	args = newBakeArgs("game", "mygame:364cff09", "linux/arm64", "zstd", nil, "io.metaplay.dirty=true")
+	if slices.Contains(args, "--load") || !slices.Contains(args, "game.output=type=docker,compression=zstd,force-compression=true") {
+		t.Errorf("expected a compressed docker output, got %v", args)
+	}
+}
+
+func TestParseBuildMetadataImageID(t *testing.T) {
+	buildMetadata := []byte(`{"containerimage.config.digest": "sha256:1234", "image.name": "mygame:1"}`)
+	bakeMetadata := []byte(`{"server": {"containerimage.config.digest": "sha256:5678"}}`)
+
+	tests := []struct {
+		content    []byte
+		bakeTarget string
+		expected   string
+	}{
+		{buildMetadata, "", "sha256:1234"},
+		{bakeMetadata, "server", "sha256:5678"},
+		{bakeMetadata, "other", ""},
+		{bakeMetadata, "", ""},
+		{[]byte("not json"), "", ""},
+	}
+	for _, test := range tests {
+		if got := parseBuildMetadataImageID(test.content, test.bakeTarget); got != test.expected {
+			t.Errorf("parseBuildMetadataImageID(%s, %q) = %q, expected %q", test.content, test.bakeTarget, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/internal/version"
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/metaplay/cli/pkg/helmutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Options for DeployGameServer().
+type DeployGameServerOptions struct {
+	ImageTag        string                 // Tag of the image (in the environment's repository) to deploy, required.
+	ImageRepository string                 // Image repository to deploy from, empty for the chart's default (the environment's repository).
+	ChartPath       string                 // Helm chart to deploy: a chart URL (see helmutil.GetHelmChartPath()) or a local chart directory, required.
+	ChartVersion    string                 // Version of the Helm chart, for information only.
+	ReleaseName     string                 // Helm release name, empty for the only existing release or the default name for a new one, see ResolveDefaultGameServerReleaseName().
+	ValuesFiles     []string               // Helm values files, applied on top of Values.
+	Values          map[string]interface{} // Base Helm values, eg, the environment and shard config.
+	SetValues       []string               // Individual values in Helm's '--set' format (eg, 'a.b=c'), applied on top of ValuesFiles.
+	Description     string                 // Details to record in the Helm release history after the deployer's identity, optional.
+	Timeout         time.Duration          // Timeout for the Helm operation, 0 for DefaultHelmTimeout.
+	Atomic          bool                   // Roll back the release automatically if the Helm operation fails (like 'helm --atomic').
+	SkipReadyCheck  bool                   // Don't wait for the game server to be ready after the Helm operation.
+	Progress        ProgressCallbacks      // Progress reporting, the Helm and readiness check output is reported as log lines.
+}
+
+// Result of DeployGameServer().
+type DeployGameServerResult struct {
+	ReleaseName string           // Name of the installed or upgraded Helm release.
+	Revision    int              // Revision of the Helm release.
+	Release     *release.Release // The installed or upgraded Helm release.
+}
+
+// Deploy a game server into the environment by installing or upgrading its Helm release, and
+// wait for the game server to be ready to serve clients. The image must already have been
+// pushed into the environment's repository, see PushImage(). The project's environment
+// policies and the environment's operation lock are the caller's responsibility, see the
+// package docs.
+func DeployGameServer(ctx context.Context, env *Environment, opts DeployGameServerOptions) (*DeployGameServerResult, error) {
+	if opts.ImageTag == "" {
+		return nil, errors.New("the image tag to deploy must be specified")
+	}
+	if opts.ChartPath == "" {
+		return nil, errors.New("the Helm chart to deploy must be specified")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	actionConfig, err := newHelmActionConfig(env)
+	if err != nil {
+		return nil, err
+	}
+	details, err := env.target.GetDetails()
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the release to install or upgrade.
+	existingReleases, err := helmutil.HelmListReleases(actionConfig, env.target.GetKubernetesNamespace(), ResolveGameServerChartName(details))
+	if err != nil {
+		return nil, err
+	}
+	releaseName := opts.ReleaseName
+	if releaseName == "" {
+		if len(existingReleases) > 1 {
+			return nil, fmt.Errorf("multiple game server releases found in the environment (%s), specify the release to deploy", strings.Join(helmutil.GetReleaseNames(existingReleases), ", "))
+		} else if len(existingReleases) == 1 {
+			releaseName = existingReleases[0].Name
+		} else {
+			releaseName = ResolveDefaultGameServerReleaseName(env.HumanID(), details)
+		}
+	}
+	existingRelease := helmutil.FindReleaseByName(existingReleases, releaseName)
+
+	// Set the image on top of the base values, without modifying the caller's values.
+	values := maps.Clone(opts.Values)
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	imageValues := map[string]interface{}{}
+	if baseImageValues, ok := values["image"].(map[string]interface{}); ok {
+		imageValues = maps.Clone(baseImageValues)
+	}
+	imageValues["tag"] = opts.ImageTag
+	if opts.ImageRepository != "" {
+		imageValues["repository"] = opts.ImageRepository
+	}
+	values["image"] = imageValues
+
+	// Install or upgrade the Helm chart, recording who deployed it.
+	deployedBy := auth.GetTokenSetUserIdentity(env.target.TokenSet)
+	release, err := helmutil.HelmUpgradeOrInstall(
+		opts.Progress.taskOutput(),
+		actionConfig,
+		existingRelease,
+		env.target.GetKubernetesNamespace(),
+		releaseName,
+		opts.ChartPath,
+		opts.ChartVersion,
+		opts.ValuesFiles,
+		values,
+		opts.SetValues,
+		helmTimeout(opts.Timeout),
+		opts.Atomic,
+		helmutil.NewReleaseDescription(version.AppVersion, deployedBy, opts.Description),
+		helmutil.NewReleaseLabels(version.AppVersion, deployedBy))
+	if err != nil {
+		return nil, err
+	}
+	result := &DeployGameServerResult{ReleaseName: release.Name, Revision: release.Version, Release: release}
+
+	// Wait for the game server to be ready.
+	if !opts.SkipReadyCheck {
+		if err := WaitForGameServerReady(ctx, env, opts.Progress); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// Resolve the name of the environment's game server Helm chart: the chart name assigned by the
+// StackAPI, or GameServerChartName.
+func ResolveGameServerChartName(details *envapi.DeploymentSecret) string {
+	if details.Deployment.HelmChartName != "" {
+		return details.Deployment.HelmChartName
+	}
+	return GameServerChartName
+}
+
+// Resolve the name of a new game server Helm release: the release name assigned by the
+// StackAPI, or the default '<environment>-gameserver'.
+func ResolveDefaultGameServerReleaseName(humanID string, details *envapi.DeploymentSecret) string {
+	if details.Deployment.HelmReleaseName != "" {
+		return details.Deployment.HelmReleaseName
+	}
+	return fmt.Sprintf("%s-gameserver", humanID)
+}
+
+// Wait for a deployed game server to be ready: its pods are healthy, the domain names have
+// propagated, and both the game server and the LiveOps Dashboard serve traffic.
+func WaitForGameServerReady(ctx context.Context, env *Environment, progress ProgressCallbacks) error {
+	checks, err := env.target.GetServerReadinessChecks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, check := range checks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress.log(check.Title)
+		if err := check.Run(progress.taskOutput()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
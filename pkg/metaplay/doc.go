@@ -0,0 +1,30 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+
+// Package metaplay is the public Go API for the core operations of the Metaplay CLI: building
+// game server images, pushing them into a cloud environment, deploying and removing game
+// servers, and querying environment details. The 'metaplay' commands perform these operations
+// with this package, so tools that embed the operations get the same behavior as the CLI.
+//
+// The commands add the interactive parts on top, which embedding tools are responsible for
+// themselves: the project's environment policies and their overrides (see
+// metaproj.PolicyRule), confirmations, and holding the environment's operation lock while
+// operating on it (see envapi.TargetEnvironment.AcquireOperationLock()).
+//
+// The operations never prompt the user and never exit the process. All confirmations are the
+// caller's responsibility, errors are returned as values, progress is reported via the
+// optional callbacks in each operation's options, and cancellation is via the context.
+//
+// # Compatibility
+//
+// The package follows semantic versioning, with the version in APIVersion. Within a major
+// version, the exported identifiers of this package are not removed or changed in
+// incompatible ways: new operations, new option fields, and new result fields may be added,
+// so construct the option structs with field names. While the major version is 0, minor
+// versions may still contain breaking changes, which are called out in the changelog.
+// The other packages under pkg/ carry no compatibility promise.
+package metaplay
+
+// Semantic version of the API of this package, see the package docs for the compatibility promise.
+const APIVersion = "0.1.0"
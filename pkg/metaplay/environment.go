@@ -0,0 +1,47 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"context"
+
+	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/envapi"
+)
+
+// Environment is a cloud environment the operations target.
+type Environment struct {
+	target *envapi.TargetEnvironment
+}
+
+// Create an Environment for accessing the environment humanID (eg, 'tough-falcons') in the
+// infrastructure stack at stackDomain, authenticated with the given tokens.
+func NewEnvironment(tokenSet *auth.TokenSet, stackDomain, humanID string) *Environment {
+	return &Environment{target: envapi.NewTargetEnvironment(tokenSet, stackDomain, humanID)}
+}
+
+// Create an Environment from an existing envapi.TargetEnvironment.
+func NewEnvironmentFromTarget(target *envapi.TargetEnvironment) *Environment {
+	return &Environment{target: target}
+}
+
+// Human ID of the environment, eg, 'tough-falcons'.
+func (env *Environment) HumanID() string {
+	return env.target.HumanId
+}
+
+// The underlying envapi.TargetEnvironment for operations not covered by this package.
+func (env *Environment) Target() *envapi.TargetEnvironment {
+	return env.target
+}
+
+// Get the details of the environment: its deployment (hostnames, image repository, Kubernetes
+// namespace, etc.), observability endpoints, and OAuth2 client. The details are returned
+// as-is, use DeploymentSecret.Validate() to check that they are complete.
+func GetEnvironmentDetails(ctx context.Context, env *Environment) (*envapi.DeploymentSecret, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return env.target.GetDetails()
+}
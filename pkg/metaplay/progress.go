@@ -0,0 +1,27 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import "github.com/metaplay/cli/internal/tui"
+
+// Callbacks for reporting the progress of an operation. Both callbacks are optional.
+type ProgressCallbacks struct {
+	// Called with each line of log output from the operation, eg, the Helm output.
+	OnLog func(line string)
+	// Called when the current status of the operation changes, eg, the per-layer progress of
+	// an image push. Each call replaces the status lines of the previous call.
+	OnStatus func(lines []string)
+}
+
+// Log a line via the OnLog callback, if set.
+func (p ProgressCallbacks) log(line string) {
+	if p.OnLog != nil {
+		p.OnLog(line)
+	}
+}
+
+// Create a task output that forwards the output of the internal tasks to the callbacks.
+func (p ProgressCallbacks) taskOutput() *tui.TaskOutput {
+	return tui.NewCallbackTaskOutput(p.OnLog, p.OnStatus)
+}
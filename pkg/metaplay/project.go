@@ -0,0 +1,45 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"path/filepath"
+
+	"github.com/metaplay/cli/pkg/metaproj"
+	"github.com/rs/zerolog/log"
+)
+
+// Load the project in projectDir: its metaplay-project.yaml and the version metadata of the
+// Metaplay SDK it uses.
+func LoadProject(projectDir string) (*metaproj.MetaplayProject, error) {
+	return LoadProjectFromConfigFile(filepath.Join(projectDir, metaproj.ConfigFileName))
+}
+
+// Load the project from the given project config file, which can have any name. The project
+// directory is the directory containing the file, and all the paths in the project are relative
+// to it.
+func LoadProjectFromConfigFile(configFilePath string) (*metaproj.MetaplayProject, error) {
+	projectDir := filepath.Dir(configFilePath)
+
+	// Load the project config file.
+	projectConfig, err := metaproj.LoadProjectConfigFileFromPath(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("Project config loaded from %s: %#v", configFilePath, projectConfig)
+
+	// Load version metadata from MetaplaySDK/version.yaml.
+	versionMetadata, err := metaproj.LoadSdkVersionMetadata(filepath.Join(projectDir, projectConfig.SdkRootDir))
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("Version metadata loaded: %+v", versionMetadata)
+
+	project, err := metaproj.NewMetaplayProject(projectDir, projectConfig, versionMetadata)
+	if err != nil {
+		return nil, err
+	}
+	project.ConfigFilePath = configFilePath
+	return project, nil
+}
@@ -0,0 +1,373 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/pkg/common"
+	"github.com/metaplay/cli/pkg/envapi"
+)
+
+// Types of the provenance attestations created by NewImageProvenance(): an in-toto statement
+// with a SLSA v1 provenance predicate, wrapped into a signed DSSE envelope.
+const (
+	ProvenanceStatementType = "https://in-toto.io/Statement/v1"
+	ProvenancePredicateType = "https://slsa.dev/provenance/v1"
+	ProvenancePayloadType   = "application/vnd.in-toto+json"
+	ProvenanceBuildType     = "https://metaplay.io/cli/build-image/v1"
+)
+
+// Media type of the DSSE envelope when stored in an image registry.
+const provenanceEnvelopeMediaType = "application/vnd.dsse.envelope.v1+json"
+
+// Provenance of an image: an in-toto statement about the image (the subject, identified by
+// its image ID), with the SLSA provenance of the build as the predicate.
+type ProvenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []ProvenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     SLSAProvenance      `json:"predicate"`
+}
+
+// Subject of a provenance statement, ie, the built image.
+type ProvenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"` // Digest of the image config (ie, the image ID) by algorithm.
+}
+
+// SLSA v1 provenance predicate, see https://slsa.dev/spec/v1.0/provenance.
+type SLSAProvenance struct {
+	BuildDefinition SLSABuildDefinition `json:"buildDefinition"`
+	RunDetails      SLSARunDetails      `json:"runDetails"`
+}
+
+// Inputs of the build: the parameters given by the user and the resolved source code.
+type SLSABuildDefinition struct {
+	BuildType            string                   `json:"buildType"`
+	ExternalParameters   ImageBuildParameters     `json:"externalParameters"`
+	ResolvedDependencies []SLSAResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// External parameters of an image build (the buildType ProvenanceBuildType).
+type ImageBuildParameters struct {
+	ImageName   string   `json:"imageName"`           // Name of the built image, 'name:tag'.
+	Platform    string   `json:"platform"`            // Target platform, eg, 'linux/amd64'.
+	CommitID    string   `json:"commitId"`            // Commit ID of the source, with a '-dirty' suffix for uncommitted changes.
+	BuildNumber string   `json:"buildNumber"`         // Build number, or 'none'.
+	BuildArgs   []string `json:"buildArgs,omitempty"` // Custom build args in format 'KEY=VALUE'.
+}
+
+// Reference to an artifact used in the build, eg, the source commit.
+type SLSAResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// Details of the build run: who built the image, and when.
+type SLSARunDetails struct {
+	Builder  SLSABuilder       `json:"builder"`
+	Metadata SLSABuildMetadata `json:"metadata"`
+}
+
+// Identity of the builder, eg, the URL of the CI run.
+type SLSABuilder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// Timing and invocation ID of the build run.
+type SLSABuildMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// Builder of an image for NewImageProvenance().
+type ProvenanceBuilder struct {
+	ID           string    // URI identifying the builder, eg, the URL of the CI run.
+	InvocationID string    // ID of the build invocation, eg, the CI build number, optional.
+	CliVersion   string    // Version of the Metaplay CLI that built the image.
+	StartedOn    time.Time // Start time of the build.
+	FinishedOn   time.Time // End time of the build.
+}
+
+// DSSE envelope with a signed payload, see https://github.com/secure-systems-lab/dsse.
+// The payload and the signatures are base64-encoded in JSON.
+type ProvenanceEnvelope struct {
+	PayloadType string                `json:"payloadType"`
+	Payload     []byte                `json:"payload"`
+	Signatures  []ProvenanceSignature `json:"signatures"`
+}
+
+// Signature of a DSSE envelope. For keyless signatures, the signature is an OIDC identity
+// token whose audience binds it to the payload, see NewKeylessProvenanceSigner().
+type ProvenanceSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   []byte `json:"sig"`
+}
+
+// Create the provenance statement of an image built with BuildImage().
+func NewImageProvenance(opts BuildImageOptions, result *BuildResult, builder ProvenanceBuilder) (*ProvenanceStatement, error) {
+	algorithm, digest, found := strings.Cut(result.ImageID, ":")
+	if !found || algorithm == "" || digest == "" {
+		return nil, fmt.Errorf("invalid image ID '%s'", result.ImageID)
+	}
+
+	architecture := opts.Architecture
+	if architecture == "" {
+		architecture = "amd64"
+	}
+
+	// Resolve the source commit, the Dockerfile, and the SDK version used in the build.
+	dependencies := []SLSAResourceDescriptor{}
+	if commitID, isDirty := strings.CutSuffix(opts.CommitID, "-dirty"); commitID != "" && commitID != "none" {
+		source := SLSAResourceDescriptor{Name: "source"}
+		if !isDirty {
+			source.Digest = map[string]string{"gitCommit": commitID}
+		}
+		dependencies = append(dependencies, source)
+	}
+	if opts.Project != nil {
+		dockerfilePath := filepath.Join(opts.Project.GetSdkRootDir(), "Dockerfile.server")
+		dockerfile, err := os.ReadFile(dockerfilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", dockerfilePath, err)
+		}
+		dockerfileDigest := sha256.Sum256(dockerfile)
+		dependencies = append(dependencies, SLSAResourceDescriptor{
+			Name:   "Dockerfile.server",
+			Digest: map[string]string{"sha256": hex.EncodeToString(dockerfileDigest[:])},
+		})
+		if opts.Project.VersionMetadata.SdkVersion != nil {
+			dependencies = append(dependencies, SLSAResourceDescriptor{
+				Name: "metaplay-sdk",
+				URI:  fmt.Sprintf("pkg:generic/metaplay-sdk@%s", opts.Project.VersionMetadata.SdkVersion),
+			})
+		}
+	}
+
+	statement := &ProvenanceStatement{
+		Type:          ProvenanceStatementType,
+		Subject:       []ProvenanceSubject{{Name: result.ImageName, Digest: map[string]string{algorithm: digest}}},
+		PredicateType: ProvenancePredicateType,
+		Predicate: SLSAProvenance{
+			BuildDefinition: SLSABuildDefinition{
+				BuildType: ProvenanceBuildType,
+				ExternalParameters: ImageBuildParameters{
+					ImageName:   result.ImageName,
+					Platform:    fmt.Sprintf("linux/%s", architecture),
+					CommitID:    opts.CommitID,
+					BuildNumber: opts.BuildNumber,
+					BuildArgs:   opts.BuildArgs,
+				},
+				ResolvedDependencies: dependencies,
+			},
+			RunDetails: SLSARunDetails{
+				Builder: SLSABuilder{ID: builder.ID},
+				Metadata: SLSABuildMetadata{
+					InvocationID: builder.InvocationID,
+				},
+			},
+		},
+	}
+	if builder.CliVersion != "" {
+		statement.Predicate.RunDetails.Builder.Version = map[string]string{"metaplay-cli": builder.CliVersion}
+	}
+	if !builder.StartedOn.IsZero() {
+		startedOn := builder.StartedOn.UTC()
+		statement.Predicate.RunDetails.Metadata.StartedOn = &startedOn
+	}
+	if !builder.FinishedOn.IsZero() {
+		finishedOn := builder.FinishedOn.UTC()
+		statement.Predicate.RunDetails.Metadata.FinishedOn = &finishedOn
+	}
+	return statement, nil
+}
+
+// Get the image ID of the statement's subject, eg, 'sha256:<hex>'.
+func (statement *ProvenanceStatement) ImageID() string {
+	if len(statement.Subject) == 0 {
+		return ""
+	}
+	if digest, found := statement.Subject[0].Digest["sha256"]; found {
+		return "sha256:" + digest
+	}
+	return ""
+}
+
+// Get the commit ID that the image was built from, including the '-dirty' suffix for images
+// built from uncommitted changes.
+func (statement *ProvenanceStatement) CommitID() string {
+	return statement.Predicate.BuildDefinition.ExternalParameters.CommitID
+}
+
+// Check whether the commit recorded in the provenance matches the expected commit. A prefix
+// of the commit (of at least 7 characters) is accepted, like with git. Images built from
+// uncommitted changes never match.
+func (statement *ProvenanceStatement) MatchesCommit(expectedCommitID string) bool {
+	recorded := statement.CommitID()
+	if recorded == "" || strings.HasSuffix(recorded, "-dirty") {
+		return false
+	}
+	if recorded == expectedCommitID {
+		return true
+	}
+	return len(expectedCommitID) >= 7 && strings.HasPrefix(strings.ToLower(recorded), strings.ToLower(expectedCommitID))
+}
+
+// Encode the DSSE pre-authentication encoding (PAE) of a payload, which is what gets signed.
+func provenancePAE(payloadType string, payload []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+// Sign the provenance statement into a DSSE envelope.
+func SignProvenance(ctx context.Context, statement *ProvenanceStatement, signer ProvenanceSigner) (*ProvenanceEnvelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal provenance statement: %w", err)
+	}
+
+	sig, err := signer.Sign(ctx, provenancePAE(ProvenancePayloadType, payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign provenance: %w", err)
+	}
+
+	return &ProvenanceEnvelope{
+		PayloadType: ProvenancePayloadType,
+		Payload:     payload,
+		Signatures:  []ProvenanceSignature{{KeyID: signer.KeyID(), Sig: sig}},
+	}, nil
+}
+
+// Verify the signature of a DSSE envelope and that the provenance statement in it is about
+// the image with the given ID. Returns the verified statement.
+func VerifyProvenance(ctx context.Context, envelope *ProvenanceEnvelope, verifier ProvenanceVerifier, imageID string) (*ProvenanceStatement, error) {
+	if envelope.PayloadType != ProvenancePayloadType {
+		return nil, fmt.Errorf("unexpected provenance payload type '%s', expecting '%s'", envelope.PayloadType, ProvenancePayloadType)
+	}
+	if len(envelope.Signatures) == 0 {
+		return nil, errors.New("provenance is not signed")
+	}
+
+	// At least one of the signatures must be valid.
+	pae := provenancePAE(envelope.PayloadType, envelope.Payload)
+	var verifyErrs []error
+	for _, signature := range envelope.Signatures {
+		err := verifier.Verify(ctx, signature.KeyID, pae, signature.Sig)
+		if err == nil {
+			verifyErrs = nil
+			break
+		}
+		verifyErrs = append(verifyErrs, err)
+	}
+	if len(verifyErrs) > 0 {
+		return nil, fmt.Errorf("provenance signature verification failed: %w", errors.Join(verifyErrs...))
+	}
+
+	// Only trust the payload after the signature has been verified.
+	var statement ProvenanceStatement
+	if err := json.Unmarshal(envelope.Payload, &statement); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance statement: %w", err)
+	}
+	if statement.Type != ProvenanceStatementType || statement.PredicateType != ProvenancePredicateType {
+		return nil, fmt.Errorf("unsupported provenance statement type '%s' with predicate type '%s'", statement.Type, statement.PredicateType)
+	}
+	if statement.ImageID() != imageID {
+		return nil, fmt.Errorf("provenance is for image %s, not for image %s", statement.ImageID(), imageID)
+	}
+	return &statement, nil
+}
+
+// Get the path of the locally stored provenance of an image.
+func getLocalProvenancePath(imageID string) (string, error) {
+	_, digest, found := strings.Cut(imageID, ":")
+	if !found || digest == "" || strings.ContainsAny(digest, `/\.`) {
+		return "", fmt.Errorf("invalid image ID '%s'", imageID)
+	}
+	stateDir, err := common.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "provenance", digest+".json"), nil
+}
+
+// Store the provenance of a locally built image, so that it can be pushed along with the image
+// with PushImage().
+func SaveLocalImageProvenance(imageID string, envelope *ProvenanceEnvelope) error {
+	filePath, err := getLocalProvenancePath(imageID)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal provenance: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return fmt.Errorf("failed to create provenance directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+	return nil
+}
+
+// Load the locally stored provenance of an image. Returns nil (without an error) if the image
+// has no stored provenance.
+func LoadLocalImageProvenance(imageID string) (*ProvenanceEnvelope, error) {
+	filePath, err := getLocalProvenancePath(imageID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read provenance: %w", err)
+	}
+	var envelope ProvenanceEnvelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance %s: %w", filePath, err)
+	}
+	return &envelope, nil
+}
+
+// Push the provenance of an image into the repository as an OCI artifact tagged
+// 'sha256-<hex>.att' (by the image ID). Returns the reference of the pushed artifact.
+func PushImageProvenance(ctx context.Context, dockerCredentials *envapi.DockerCredentials, repository, imageID string, envelope *ProvenanceEnvelope) (string, error) {
+	content, err := json.Marshal(envelope)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal provenance: %w", err)
+	}
+	return envapi.PushDockerImageAttestation(ctx, dockerCredentials, repository, imageID, content, provenanceEnvelopeMediaType)
+}
+
+// Fetch the provenance of an image pushed with PushImageProvenance() from the repository. The
+// provenance is not verified, use VerifyProvenance(). Returns nil (without an error) if the
+// image has no provenance in the repository.
+func FetchImageProvenance(ctx context.Context, dockerCredentials *envapi.DockerCredentials, repository, imageID string) (*ProvenanceEnvelope, error) {
+	content, err := envapi.FetchDockerImageAttestation(ctx, dockerCredentials, repository, imageID, provenanceEnvelopeMediaType)
+	if err != nil || content == nil {
+		return nil, err
+	}
+	var envelope ProvenanceEnvelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance of image %s: %w", imageID, err)
+	}
+	return &envelope, nil
+}
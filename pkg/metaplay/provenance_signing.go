@@ -0,0 +1,328 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer of the OIDC identity tokens of GitHub Actions workflows, used for keyless signing.
+const GitHubActionsOIDCIssuer = "https://token.actions.githubusercontent.com"
+
+// Prefix of the audience of the OIDC tokens used as keyless signatures. The audience is
+// followed by the SHA-256 digest of the signed message, which binds the token to the payload.
+const keylessProvenanceAudiencePrefix = "metaplay-provenance:sha256:"
+
+// Timeout for the HTTP requests of keyless signing and verification.
+const keylessProvenanceHTTPTimeout = 30 * time.Second
+
+// Signs provenance payloads, see SignProvenance().
+type ProvenanceSigner interface {
+	KeyID() string                                            // ID of the signing key, stored with the signature.
+	Sign(ctx context.Context, message []byte) ([]byte, error) // Sign the message (the DSSE PAE of the payload).
+}
+
+// Verifies the signatures of provenance payloads, see VerifyProvenance().
+type ProvenanceVerifier interface {
+	Verify(ctx context.Context, keyID string, message, sig []byte) error // Verify the signature of the message.
+}
+
+// Get the key ID of an ed25519 public key: the SHA-256 digest of the key in PKIX format.
+func ed25519KeyID(publicKey ed25519.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	digest := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(digest[:]), nil
+}
+
+// Decode the single PEM block of a key file.
+func readPEMFile(filePath string, keyKind string) (*pem.Block, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s key file: %w", keyKind, err)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s key file %s is not in PEM format", keyKind, filePath)
+	}
+	return block, nil
+}
+
+// Signs with an ed25519 private key.
+type keyFileProvenanceSigner struct {
+	keyID      string
+	privateKey ed25519.PrivateKey
+}
+
+// Load an ed25519 private key from a PEM file in PKCS #8 format for signing provenance, eg,
+// generated with 'openssl genpkey -algorithm ed25519 -out provenance.key'.
+func LoadProvenanceSigningKey(filePath string) (ProvenanceSigner, error) {
+	block, err := readPEMFile(filePath, "private")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key file %s: %w", filePath, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key file %s contains a %T, only ed25519 keys are supported", filePath, key)
+	}
+	keyID, err := ed25519KeyID(privateKey.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	return &keyFileProvenanceSigner{keyID: keyID, privateKey: privateKey}, nil
+}
+
+func (signer *keyFileProvenanceSigner) KeyID() string {
+	return signer.keyID
+}
+
+func (signer *keyFileProvenanceSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	return ed25519.Sign(signer.privateKey, message), nil
+}
+
+// Verifies signatures with an ed25519 public key.
+type keyFileProvenanceVerifier struct {
+	keyID     string
+	publicKey ed25519.PublicKey
+}
+
+// Load an ed25519 public key from a PEM file in PKIX format for verifying provenance, eg,
+// extracted from the private key with 'openssl pkey -in provenance.key -pubout'.
+func LoadProvenanceVerificationKey(filePath string) (ProvenanceVerifier, error) {
+	block, err := readPEMFile(filePath, "public")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key file %s: %w", filePath, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key file %s contains a %T, only ed25519 keys are supported", filePath, key)
+	}
+	keyID, err := ed25519KeyID(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &keyFileProvenanceVerifier{keyID: keyID, publicKey: publicKey}, nil
+}
+
+func (verifier *keyFileProvenanceVerifier) Verify(ctx context.Context, keyID string, message, sig []byte) error {
+	if keyID != "" && keyID != verifier.keyID {
+		return fmt.Errorf("signed with key %s, not with the given public key %s", keyID, verifier.keyID)
+	}
+	if !ed25519.Verify(verifier.publicKey, message, sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// Get the audience of the OIDC token that signs the message.
+func keylessProvenanceAudience(message []byte) string {
+	digest := sha256.Sum256(message)
+	return keylessProvenanceAudiencePrefix + hex.EncodeToString(digest[:])
+}
+
+// Signs with the OIDC identity token of a GitHub Actions workflow run. The token is requested
+// with an audience containing the digest of the message, so the token signed by GitHub
+// attests that the workflow run produced the message.
+type keylessProvenanceSigner struct {
+	requestURL   string
+	requestToken string
+	httpClient   *http.Client
+}
+
+// Create a keyless provenance signer that uses the workflow's OIDC identity token as the
+// signature. Only supported in GitHub Actions, with the 'id-token: write' permission.
+func NewKeylessProvenanceSigner() (ProvenanceSigner, error) {
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return nil, errors.New("keyless signing is only supported in GitHub Actions with the 'id-token: write' permission; use a signing key file instead")
+	}
+	return &keylessProvenanceSigner{
+		requestURL:   requestURL,
+		requestToken: requestToken,
+		httpClient:   &http.Client{Timeout: keylessProvenanceHTTPTimeout},
+	}, nil
+}
+
+func (signer *keylessProvenanceSigner) KeyID() string {
+	return GitHubActionsOIDCIssuer
+}
+
+func (signer *keylessProvenanceSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	requestURL, err := url.Parse(signer.requestURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC token request URL: %w", err)
+	}
+	query := requestURL.Query()
+	query.Set("audience", keylessProvenanceAudience(message))
+	requestURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+signer.requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	var response struct {
+		Value string `json:"value"`
+	}
+	if err := getJSON(signer.httpClient, req, &response); err != nil {
+		return nil, fmt.Errorf("failed to request OIDC token: %w", err)
+	}
+	if response.Value == "" {
+		return nil, errors.New("no OIDC token in the response")
+	}
+	return []byte(response.Value), nil
+}
+
+// Verifies keyless signatures: the OIDC token must be signed by the issuer, have the audience
+// bound to the message, and have the expected subject.
+type keylessProvenanceVerifier struct {
+	issuer     string
+	identity   string
+	httpClient *http.Client
+}
+
+// Create a verifier for keyless provenance signatures made by NewKeylessProvenanceSigner().
+// The subject ('sub' claim) of the token must match the identity, eg,
+// 'repo:myorg/mygame:ref:refs/heads/main'. A trailing '*' matches any suffix. The identity
+// is required, as anyone can get a token from the issuer (eg, from any GitHub Actions
+// workflow in any repository), so the signature alone doesn't prove anything.
+//
+// The token's expiry is not checked, as the signature is verified long after signing. The
+// issuer's current signing keys are used, so signatures made with keys that the issuer has
+// since rotated out can no longer be verified: use a signing key file for long-lived images.
+func NewKeylessProvenanceVerifier(issuer, identity string) (ProvenanceVerifier, error) {
+	if identity == "" || identity == "*" {
+		return nil, errors.New("keyless provenance verification requires the signer's identity")
+	}
+	return &keylessProvenanceVerifier{
+		issuer:     issuer,
+		identity:   identity,
+		httpClient: &http.Client{Timeout: keylessProvenanceHTTPTimeout},
+	}, nil
+}
+
+func (verifier *keylessProvenanceVerifier) Verify(ctx context.Context, keyID string, message, sig []byte) error {
+	if keyID != verifier.issuer {
+		return fmt.Errorf("not a keyless signature by %s (key ID: %s)", verifier.issuer, keyID)
+	}
+
+	// Verify the token's signature with the issuer's keys. The claims are checked below.
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(string(sig), &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return verifier.fetchIssuerKey(ctx, kid)
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithoutClaimsValidation())
+	if err != nil {
+		return fmt.Errorf("invalid OIDC token: %w", err)
+	}
+
+	if claims.Issuer != verifier.issuer {
+		return fmt.Errorf("OIDC token issued by %s, expecting %s", claims.Issuer, verifier.issuer)
+	}
+	if !slices.Contains(claims.Audience, keylessProvenanceAudience(message)) {
+		return errors.New("OIDC token was not issued for this provenance")
+	}
+	prefix, isWildcard := strings.CutSuffix(verifier.identity, "*")
+	if (isWildcard && !strings.HasPrefix(claims.Subject, prefix)) || (!isWildcard && claims.Subject != verifier.identity) {
+		return fmt.Errorf("signed by %s, expecting %s", claims.Subject, verifier.identity)
+	}
+	return nil
+}
+
+// Fetch the public key with the given key ID from the issuer's JWKS, found via the issuer's
+// OpenID configuration.
+func (verifier *keylessProvenanceVerifier) fetchIssuerKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	configURL := strings.TrimSuffix(verifier.issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, configURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		JwksURI string `json:"jwks_uri"`
+	}
+	if err := getJSON(verifier.httpClient, req, &config); err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenID configuration of %s: %w", verifier.issuer, err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, config.JwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(verifier.httpClient, req, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys of %s: %w", verifier.issuer, err)
+	}
+
+	for _, key := range jwks.Keys {
+		if key.Kid != kid || key.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus in signing key %s: %w", kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent in signing key %s: %w", kid, err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+	return nil, fmt.Errorf("signing key %s not found in the keys of %s (the key may have been rotated)", kid, verifier.issuer)
+}
+
+// Execute the request and decode the JSON response into result.
+func getJSON(httpClient *http.Client, req *http.Request, result any) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %s", req.URL.Redacted(), resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", req.URL.Redacted(), err)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testImageID = "sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"
+
+func newTestProvenance(t *testing.T) *ProvenanceStatement {
+	statement, err := NewImageProvenance(
+		BuildImageOptions{ImageName: "mygame:364cff09", CommitID: "364cff0912345678", BuildNumber: "715", BuildArgs: []string{"FOO=BAR"}},
+		&BuildResult{ImageName: "mygame:364cff09", ImageID: testImageID},
+		ProvenanceBuilder{ID: "https://github.com/myorg/mygame/actions/runs/1", CliVersion: "1.2.3", StartedOn: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statement
+}
+
+// Generate an ed25519 key pair into PEM files, returns the private and public key file paths.
+func writeTestKeyFiles(t *testing.T) (string, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "provenance.key")
+	publicPath := filepath.Join(dir, "provenance.pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+func TestNewImageProvenance(t *testing.T) {
+	statement := newTestProvenance(t)
+
+	if statement.ImageID() != testImageID {
+		t.Errorf("subject image ID = %s, expected %s", statement.ImageID(), testImageID)
+	}
+	if statement.CommitID() != "364cff0912345678" {
+		t.Errorf("commit ID = %s", statement.CommitID())
+	}
+	params := statement.Predicate.BuildDefinition.ExternalParameters
+	if params.Platform != "linux/amd64" || params.BuildNumber != "715" || len(params.BuildArgs) != 1 {
+		t.Errorf("unexpected build parameters: %+v", params)
+	}
+	deps := statement.Predicate.BuildDefinition.ResolvedDependencies
+	if len(deps) != 1 || deps[0].Digest["gitCommit"] != "364cff0912345678" {
+		t.Errorf("unexpected resolved dependencies: %+v", deps)
+	}
+
+	// Dirty commits are not recorded as git commit digests.
+	dirty, err := NewImageProvenance(BuildImageOptions{CommitID: "364cff09-dirty"}, &BuildResult{ImageID: testImageID}, ProvenanceBuilder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps := dirty.Predicate.BuildDefinition.ResolvedDependencies; len(deps) != 1 || deps[0].Digest != nil {
+		t.Errorf("unexpected resolved dependencies for a dirty build: %+v", deps)
+	}
+
+	if _, err := NewImageProvenance(BuildImageOptions{}, &BuildResult{ImageID: "invalid"}, ProvenanceBuilder{}); err == nil {
+		t.Error("expected an error with an invalid image ID")
+	}
+}
+
+func TestProvenanceMatchesCommit(t *testing.T) {
+	tests := []struct {
+		recorded string
+		expected string
+		matches  bool
+	}{
+		{"364cff0912345678", "364cff0912345678", true},
+		{"364cff0912345678", "364cff09", true},
+		{"364cff0912345678", "364CFF09", true},
+		{"364cff0912345678", "364cf", false}, // Too short prefix.
+		{"364cff0912345678", "1a27c25753", false},
+		{"364cff0912345678-dirty", "364cff0912345678", false},
+		{"364cff0912345678-dirty", "364cff0912345678-dirty", false},
+		{"", "364cff09", false},
+	}
+	for _, test := range tests {
+		statement := &ProvenanceStatement{}
+		statement.Predicate.BuildDefinition.ExternalParameters.CommitID = test.recorded
+		if matches := statement.MatchesCommit(test.expected); matches != test.matches {
+			t.Errorf("MatchesCommit(%q) with recorded %q = %v, expected %v", test.expected, test.recorded, matches, test.matches)
+		}
+	}
+}
+
+func TestSignAndVerifyProvenanceWithKeyFile(t *testing.T) {
+	ctx := context.Background()
+	privatePath, publicPath := writeTestKeyFiles(t)
+	signer, err := LoadProvenanceSigningKey(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := LoadProvenanceVerificationKey(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := SignProvenance(ctx, newTestProvenance(t), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Round-trip the envelope through JSON, like when stored in the registry.
+	envelopeJSON, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ProvenanceEnvelope
+	if err := json.Unmarshal(envelopeJSON, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	statement, err := VerifyProvenance(ctx, &decoded, verifier, testImageID)
+	if err != nil {
+		t.Fatalf("failed to verify provenance: %v", err)
+	}
+	if statement.CommitID() != "364cff0912345678" {
+		t.Errorf("verified commit ID = %s", statement.CommitID())
+	}
+
+	// Provenance of another image is rejected.
+	if _, err := VerifyProvenance(ctx, envelope, verifier, "sha256:0000"); err == nil || !strings.Contains(err.Error(), "not for image") {
+		t.Errorf("expected an image mismatch error, got: %v", err)
+	}
+
+	// Tampered payloads are rejected.
+	tampered := *envelope
+	tampered.Payload = []byte(strings.Replace(string(envelope.Payload), "364cff0912345678", "1a27c25753000000", -1))
+	if _, err := VerifyProvenance(ctx, &tampered, verifier, testImageID); err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+		t.Errorf("expected a signature error for a tampered payload, got: %v", err)
+	}
+
+	// Signatures with another key are rejected.
+	_, otherPublicPath := writeTestKeyFiles(t)
+	otherVerifier, err := LoadProvenanceVerificationKey(otherPublicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyProvenance(ctx, envelope, otherVerifier, testImageID); err == nil {
+		t.Error("expected an error when verifying with another key")
+	}
+}
+
+// OIDC issuer serving its OpenID configuration and JWKS, and issuing tokens like GitHub Actions.
+type testOIDCIssuer struct {
+	server     *httptest.Server
+	privateKey *rsa.PrivateKey
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testOIDCIssuer{privateKey: privateKey}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer.server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "test-key",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+		}}})
+	})
+	// Token request endpoint, like ACTIONS_ID_TOKEN_REQUEST_URL. Tokens expire immediately
+	// to check that the expiry is not validated.
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+			Issuer:    issuer.server.URL,
+			Subject:   "repo:myorg/mygame:ref:refs/heads/main",
+			Audience:  jwt.ClaimStrings{r.URL.Query().Get("audience")},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		})
+		token.Header["kid"] = "test-key"
+		signed, err := token.SignedString(privateKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"value": signed})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func TestSignAndVerifyKeylessProvenance(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestOIDCIssuer(t)
+
+	// Keyless signing is only available in GitHub Actions.
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	if _, err := NewKeylessProvenanceSigner(); err == nil {
+		t.Fatal("expected an error outside GitHub Actions")
+	}
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", issuer.server.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	signer, err := NewKeylessProvenanceSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sign as the test issuer (the key ID is always the GitHub Actions issuer).
+	envelope, err := SignProvenance(ctx, newTestProvenance(t), signer)
+	if err != nil {
+		t.Fatalf("failed to sign provenance: %v", err)
+	}
+	envelope.Signatures[0].KeyID = issuer.server.URL
+
+	tests := []struct {
+		identity string
+		contains string // Expected error, empty for success.
+	}{
+		{"repo:myorg/mygame:ref:refs/heads/main", ""},
+		{"repo:myorg/mygame:*", ""},
+		{"repo:myorg/othergame:*", "expecting repo:myorg/othergame:*"},
+		{"repo:myorg/mygame", "expecting repo:myorg/mygame"},
+	}
+	for _, test := range tests {
+		verifier, err := NewKeylessProvenanceVerifier(issuer.server.URL, test.identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = VerifyProvenance(ctx, envelope, verifier, testImageID)
+		if test.contains == "" && err != nil {
+			t.Errorf("identity %q: failed to verify: %v", test.identity, err)
+		} else if test.contains != "" && (err == nil || !strings.Contains(err.Error(), test.contains)) {
+			t.Errorf("identity %q: error = %v, expected it to contain %q", test.identity, err, test.contains)
+		}
+	}
+
+	// The token is bound to the payload: it cannot be reused for another statement.
+	tampered := *envelope
+	tampered.Payload = []byte(strings.Replace(string(envelope.Payload), "364cff0912345678", "1a27c25753000000", -1))
+	verifier, err := NewKeylessProvenanceVerifier(issuer.server.URL, "repo:myorg/mygame:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyProvenance(ctx, &tampered, verifier, testImageID); err == nil || !strings.Contains(err.Error(), "not issued for this provenance") {
+		t.Errorf("expected an audience error for a tampered payload, got: %v", err)
+	}
+
+	// Tokens from other issuers are rejected.
+	verifier, err = NewKeylessProvenanceVerifier(GitHubActionsOIDCIssuer, "repo:myorg/mygame:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyProvenance(ctx, envelope, verifier, testImageID); err == nil {
+		t.Error("expected an error when verifying against another issuer")
+	}
+
+	// Verifiers without an identity would accept tokens from any workflow, so they are refused.
+	for _, identity := range []string{"", "*"} {
+		if _, err := NewKeylessProvenanceVerifier(issuer.server.URL, identity); err == nil {
+			t.Errorf("identity %q: expected an error for an unconstrained keyless verifier", identity)
+		}
+	}
+}
+
+func TestLocalImageProvenance(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on HOME to redirect the state directory")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	// Images without a stored provenance have none.
+	envelope, err := LoadLocalImageProvenance(testImageID)
+	if err != nil || envelope != nil {
+		t.Fatalf("expected no provenance, got: %v, err: %v", envelope, err)
+	}
+
+	privatePath, _ := writeTestKeyFiles(t)
+	signer, err := LoadProvenanceSigningKey(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := SignProvenance(context.Background(), newTestProvenance(t), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveLocalImageProvenance(testImageID, saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLocalImageProvenance(testImageID)
+	if err != nil || loaded == nil || string(loaded.Payload) != string(saved.Payload) {
+		t.Errorf("loaded provenance does not match the saved one (err: %v)", err)
+	}
+
+	// Image IDs cannot escape the provenance directory.
+	if err := SaveLocalImageProvenance("sha256:../config", saved); err == nil {
+		t.Error("expected an error with an invalid image ID")
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/metaplay/cli/pkg/envapi"
+	"github.com/rs/zerolog/log"
+)
+
+// Options for PushImage().
+type PushImageOptions struct {
+	ImageName string            // Name of the local docker image to push, in format 'name:tag'.
+	Progress  ProgressCallbacks // Progress reporting, the per-layer progress is reported as status.
+}
+
+// Result of PushImage().
+type PushImageResult struct {
+	RemoteImageName string // Name of the pushed image in the environment's image repository, 'repository:tag'.
+}
+
+// Push a locally built docker image into the environment's image repository. The image keeps
+// its tag, so it can then be deployed with DeployGameServer() using the same tag.
+func PushImage(ctx context.Context, env *Environment, opts PushImageOptions) (*PushImageResult, error) {
+	// Get environment details.
+	envDetails, err := env.target.GetDetails()
+	if err != nil {
+		return nil, err
+	}
+	if err := envDetails.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Get docker credentials.
+	dockerCredentials, err := env.target.GetDockerCredentials(envDetails)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("Got docker credentials: username=%s", dockerCredentials.Username)
+
+	remoteImageName, err := PushImageToRepository(ctx, opts.ImageName, envDetails.Deployment.EcrRepo, dockerCredentials, opts.Progress)
+	if err != nil {
+		return nil, err
+	}
+	return &PushImageResult{RemoteImageName: remoteImageName}, nil
+}
+
+// Push a docker image from the local repository into the remote repository dstRepoName using
+// already resolved credentials. The image is tagged into the remote repository with its
+// existing tag. If the image was built with a provenance (see SaveLocalImageProvenance()),
+// the provenance is pushed next to it. Returns the name of the image in the remote repository.
+func PushImageToRepository(ctx context.Context, imageName, dstRepoName string, dockerCredentials *envapi.DockerCredentials, progress ProgressCallbacks) (string, error) {
+	// Create a Docker client. DOCKER_HOST is respected, so that the client can talk to the
+	// API of the detected container runtime, eg, podman or rootless docker (see
+	// containerutil.Runtime.ConfigureAPIClients()).
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return "", fmt.Errorf("failed to create Docker client: %w", err)
+	}
+
+	// Extract tag from source image.
+	imageTag, err := extractImageTag(imageName)
+	if err != nil {
+		return "", err
+	}
+
+	// Resolve source and destination image names.
+	srcImageName := imageName
+	dstImageName := fmt.Sprintf("%s:%s", dstRepoName, imageTag)
+
+	// If names don't match, tag the source image as the destination.
+	if srcImageName != dstImageName {
+		progress.log(fmt.Sprintf("Tagging image %s as %s", srcImageName, dstImageName))
+		if err := cli.ImageTag(ctx, srcImageName, dstImageName); err != nil {
+			return "", fmt.Errorf("failed to tag image: %w", err)
+		}
+	}
+
+	// Push the image
+	progress.log(fmt.Sprintf("Pushing image %s", dstImageName))
+	authConfig := registry.AuthConfig{
+		Username:      dockerCredentials.Username,
+		Password:      dockerCredentials.Password,
+		ServerAddress: dockerCredentials.RegistryURL,
+	}
+	authConfigBytes, err := json.Marshal(authConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal auth config: %w", err)
+	}
+
+	// Encode with base64
+	authStr := string(base64.StdEncoding.EncodeToString(authConfigBytes))
+
+	pushResponseReader, err := cli.ImagePush(ctx, dstImageName, image.PushOptions{
+		RegistryAuth: authStr,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to push docker image: %w", err)
+	}
+	defer pushResponseReader.Close()
+
+	// Follow push progress
+	decoder := json.NewDecoder(pushResponseReader)
+	progressIDs := []string{}                          // Track order of progress IDs
+	progresses := map[string]jsonmessage.JSONMessage{} // Track progress by ID
+
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", fmt.Errorf("failed to decode push response: %w", err)
+		}
+
+		// Track progress by ID to show the latest status for each layer
+		if message.ID != "" {
+			// Add ID to the order tracking slice if it's not already there
+			if _, exists := progresses[message.ID]; !exists {
+				progressIDs = append(progressIDs, message.ID)
+			}
+			progresses[message.ID] = message
+		}
+
+		// If progress has an error, return it
+		if message.Error != nil {
+			return "", fmt.Errorf("error pushing image: %s", message.Error.Message)
+		}
+
+		// Report the current progress of all layers.
+		if progress.OnStatus != nil {
+			progress.OnStatus(renderPushProgress(progressIDs, progresses))
+		}
+	}
+
+	// Push the image's provenance along with it, if it was built with one.
+	imageInfo, _, err := cli.ImageInspectWithRaw(ctx, srcImageName)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect docker image: %w", err)
+	}
+	envelope, err := LoadLocalImageProvenance(imageInfo.ID)
+	if err != nil {
+		return "", err
+	}
+	if envelope != nil {
+		progress.log(fmt.Sprintf("Pushing provenance of image %s", dstImageName))
+		if _, err := PushImageProvenance(ctx, dockerCredentials, dstRepoName, imageInfo.ID, envelope); err != nil {
+			return "", err
+		}
+	}
+
+	return dstImageName, nil
+}
+
+// Render the push progress of each layer as status lines.
+func renderPushProgress(progressIDs []string, progresses map[string]jsonmessage.JSONMessage) []string {
+	lines := []string{}
+	for _, id := range progressIDs {
+		// Skip empty progress entries
+		if progresses[id].Progress == nil && progresses[id].Status == "" {
+			continue
+		}
+
+		// Format the progress line
+		progressLine := fmt.Sprintf("Layer %s: %s", id[:min(len(id), 12)], progresses[id].Status)
+		if progresses[id].Progress != nil {
+			progressLine += fmt.Sprintf(" %s", progresses[id].Progress.String())
+		}
+		lines = append(lines, progressLine)
+	}
+	return lines
+}
+
+// Extract the tag from a full 'name:tag' docker image name.
+func extractImageTag(imageName string) (string, error) {
+	if imageName == "" {
+		return "", errors.New("must specify a valid docker image name")
+	}
+	imageParts := strings.Split(imageName, ":")
+	if len(imageParts) != 2 || len(imageParts[0]) == 0 || len(imageParts[1]) == 0 {
+		return "", fmt.Errorf("invalid docker image name '%s', expecting the name in format 'name:tag'", imageName)
+	}
+	return imageParts[1], nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/metaplay/cli/pkg/helmutil"
+	"github.com/rs/zerolog/log"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Name of the Helm chart used for game server deployments.
+const GameServerChartName = "metaplay-gameserver"
+
+// Default timeout for the Helm operations.
+const DefaultHelmTimeout = 10 * time.Minute
+
+// Returned when the environment has no game server deployment to operate on.
+var ErrNoGameServerDeployment = errors.New("no game server deployment found")
+
+// Options for RemoveGameServer().
+type RemoveGameServerOptions struct {
+	ReleaseName string        // Name of the Helm release to remove, can be empty if the environment has only one.
+	Timeout     time.Duration // Timeout for the Helm uninstall hooks, 0 for DefaultHelmTimeout. The resources are deleted in the background.
+}
+
+// Remove a game server deployment (Helm release) from the environment. Returns
+// ErrNoGameServerDeployment if there is nothing to remove.
+func RemoveGameServer(ctx context.Context, env *Environment, opts RemoveGameServerOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	actionConfig, err := newHelmActionConfig(env)
+	if err != nil {
+		return err
+	}
+
+	details, err := env.target.GetDetails()
+	if err != nil {
+		return err
+	}
+
+	// Resolve all deployed game server Helm releases.
+	helmReleases, err := helmutil.HelmListReleases(actionConfig, env.target.GetKubernetesNamespace(), ResolveGameServerChartName(details))
+	if err != nil {
+		return err
+	}
+	if len(helmReleases) == 0 {
+		return ErrNoGameServerDeployment
+	}
+
+	// Resolve the release to remove.
+	release, err := findGameServerRelease(helmReleases, opts.ReleaseName)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Remove release %s", release.Name)
+	return helmutil.UninstallRelease(actionConfig, release, helmTimeout(opts.Timeout))
+}
+
+// Resolve the timeout for a Helm operation, defaulting to DefaultHelmTimeout.
+func helmTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultHelmTimeout
+	}
+	return timeout
+}
+
+// Create a Helm action config for accessing the environment's Kubernetes namespace.
+func newHelmActionConfig(env *Environment) (*action.Configuration, error) {
+	kubeconfigPayload, err := env.target.GetKubeConfigWithEmbeddedCredentials()
+	if err != nil {
+		return nil, err
+	}
+	actionConfig, err := helmutil.NewActionConfig(kubeconfigPayload, env.target.GetKubernetesNamespace())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Helm config: %w", err)
+	}
+	return actionConfig, nil
+}
+
+// Find the game server release to operate on: the named release if specified, or the
+// only release if there is just one.
+func findGameServerRelease(releases []*release.Release, releaseName string) (*release.Release, error) {
+	if releaseName != "" {
+		found := helmutil.FindReleaseByName(releases, releaseName)
+		if found == nil {
+			return nil, fmt.Errorf("game server release '%s' not found; existing releases: %s", releaseName, strings.Join(helmutil.GetReleaseNames(releases), ", "))
+		}
+		return found, nil
+	}
+	if len(releases) != 1 {
+		return nil, fmt.Errorf("multiple game server releases found (%s), specify the release to use", strings.Join(helmutil.GetReleaseNames(releases), ", "))
+	}
+	return releases[0], nil
+}
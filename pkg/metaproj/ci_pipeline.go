@@ -0,0 +1,505 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/metaplay/cli/pkg/portalapi"
+)
+
+// CI system to generate a pipeline for.
+type CIProvider string
+
+const (
+	CIProviderGitHub CIProvider = "github" // GitHub Actions
+	CIProviderGitLab CIProvider = "gitlab" // GitLab CI/CD
+	CIProviderAzure  CIProvider = "azure"  // Azure Pipelines
+)
+
+// All the supported CI providers.
+var CIProviders = []CIProvider{CIProviderGitHub, CIProviderGitLab, CIProviderAzure}
+
+// Default path (relative to the git repository root) of the pipeline file for each CI provider.
+var ciPipelineDefaultPaths = map[CIProvider]string{
+	CIProviderGitHub: ".github/workflows/metaplay-build-deploy.yaml",
+	CIProviderGitLab: ".gitlab-ci.yml",
+	CIProviderAzure:  "azure-pipelines.yml",
+}
+
+// Markers in the generated pipeline files. The regions between the begin and end markers,
+// and the CLI version, are re-rendered by UpdateCIPipeline(). The settings marker records
+// the options that the regions are rendered with.
+const (
+	ciMarkerSettings   = "# metaplay-ci:settings"
+	ciMarkerBegin      = "# metaplay-ci:begin "
+	ciMarkerEnd        = "# metaplay-ci:end "
+	ciMarkerCliVersion = "# metaplay-ci:cli-version"
+)
+
+// Matches the line with the pinned CLI version, eg, 'METAPLAY_CLI_VERSION: "1.2.3" # metaplay-ci:cli-version'.
+var ciCliVersionLineRegex = regexp.MustCompile(`(?m)^([ \t]*METAPLAY_CLI_VERSION:[ \t]*)"[^"]*"([ \t]*` + regexp.QuoteMeta(ciMarkerCliVersion) + `)[ \t]*$`)
+
+// Options for generating a CI pipeline.
+type CIPipelineOptions struct {
+	Provider               CIProvider                  // CI system to generate the pipeline for.
+	CliVersion             string                      // Version of the Metaplay CLI to pin the pipeline to, eg, '1.2.3'.
+	ProjectDir             string                      // Directory of metaplay-project.yaml relative to the git repository root, with forward slashes.
+	ConfigFile             string                      // Path of a custom project config file relative to the git repository root, with forward slashes (overrides ProjectDir).
+	Branch                 string                      // Branch whose pushes trigger the pipeline, eg, 'main'.
+	DeployEnvironmentTypes []portalapi.EnvironmentType // Types of environments to deploy into, no deploy job if empty.
+}
+
+// Environment in the generated pipeline.
+type ciPipelineEnvironment struct {
+	HumanID string // Environment human ID, eg, 'tough-falcons'.
+	JobName string // Environment human ID usable as a job name in Azure Pipelines, eg, 'tough_falcons'.
+}
+
+// Get the default path of the pipeline file of the CI provider, relative to the git repository root.
+func GetDefaultCIPipelinePath(provider CIProvider) string {
+	return ciPipelineDefaultPaths[provider]
+}
+
+// Generate a CI pipeline file that builds the project's server image, pushes it into all the
+// project's environments, and (optionally) deploys it into the environments of the given types.
+func GenerateCIPipeline(config *ProjectConfig, opts CIPipelineOptions) (string, error) {
+	if !slices.Contains(CIProviders, opts.Provider) {
+		return "", fmt.Errorf("invalid CI provider '%s', must be one of: %v", opts.Provider, CIProviders)
+	}
+	for _, envType := range opts.DeployEnvironmentTypes {
+		if !isValidEnvironmentType(envType) {
+			return "", fmt.Errorf("invalid environment type '%s' to deploy into", envType)
+		}
+	}
+	if len(config.Environments) == 0 {
+		return "", fmt.Errorf("no environments in %s, run 'metaplay update project-environments' to fetch them from the portal", ConfigFileName)
+	}
+
+	// Resolve the environments to push to and deploy into.
+	environments := []ciPipelineEnvironment{}
+	deployEnvironments := []ciPipelineEnvironment{}
+	for _, envConfig := range config.Environments {
+		env := ciPipelineEnvironment{
+			HumanID: envConfig.HumanID,
+			JobName: strings.ReplaceAll(envConfig.HumanID, "-", "_"),
+		}
+		environments = append(environments, env)
+		if slices.Contains(opts.DeployEnvironmentTypes, envConfig.Type) {
+			deployEnvironments = append(deployEnvironments, env)
+		}
+	}
+	if len(opts.DeployEnvironmentTypes) > 0 && len(deployEnvironments) == 0 {
+		return "", fmt.Errorf("no environments of type %s in %s to deploy into", joinEnvironmentTypes(opts.DeployEnvironmentTypes), ConfigFileName)
+	}
+
+	// Pass the project config file or directory to the CLI if the project is not in the repository root.
+	projectFlag := ""
+	if opts.ConfigFile != "" {
+		projectFlag = fmt.Sprintf(" --config-file %s", opts.ConfigFile)
+	} else if opts.ProjectDir != "" && opts.ProjectDir != "." {
+		projectFlag = fmt.Sprintf(" -p %s", opts.ProjectDir)
+	}
+
+	data := struct {
+		ProjectID          string
+		CliVersion         string
+		ProjectFlag        string
+		Branch             string
+		Settings           string
+		Environments       []ciPipelineEnvironment
+		DeployEnvironments []ciPipelineEnvironment
+	}{
+		ProjectID:          config.ProjectHumanID,
+		CliVersion:         opts.CliVersion,
+		ProjectFlag:        projectFlag,
+		Branch:             opts.Branch,
+		Settings:           fmt.Sprintf("%s provider=%s deploy-types=%s", ciMarkerSettings, opts.Provider, joinEnvironmentTypes(opts.DeployEnvironmentTypes)),
+		Environments:       environments,
+		DeployEnvironments: deployEnvironments,
+	}
+
+	var result strings.Builder
+	if err := ciPipelineTemplates[opts.Provider].Execute(&result, data); err != nil {
+		return "", fmt.Errorf("failed to render the CI pipeline template: %w", err)
+	}
+	return result.String(), nil
+}
+
+// Update a pipeline previously generated with GenerateCIPipeline(): only the pinned CLI version
+// and the lists of environments are updated, other changes to the file are preserved.
+func UpdateCIPipeline(existing string, config *ProjectConfig, provider CIProvider, cliVersion string) (string, error) {
+	// Parse the settings that the pipeline was generated with.
+	opts, err := parseCIPipelineSettings(existing)
+	if err != nil {
+		return "", err
+	}
+	if opts.Provider != provider {
+		return "", fmt.Errorf("the pipeline was generated for provider '%s', not '%s'", opts.Provider, provider)
+	}
+	opts.CliVersion = cliVersion
+
+	// Render the pipeline from scratch to get the up-to-date regions.
+	generated, err := GenerateCIPipeline(config, opts)
+	if err != nil {
+		return "", err
+	}
+	generatedRegions := parseCIPipelineRegions(generated)
+
+	// Replace the regions of the existing file.
+	existingLines := strings.Split(existing, "\n")
+	updatedLines := []string{}
+	numRegions := 0
+	for ndx := 0; ndx < len(existingLines); ndx++ {
+		line := existingLines[ndx]
+		updatedLines = append(updatedLines, line)
+		name, isBegin := strings.CutPrefix(strings.TrimSpace(line), ciMarkerBegin)
+		if !isBegin {
+			continue
+		}
+
+		// Skip the existing region until the end marker.
+		endNdx := slices.IndexFunc(existingLines[ndx+1:], func(l string) bool { return strings.TrimSpace(l) == ciMarkerEnd+name })
+		if endNdx == -1 {
+			return "", fmt.Errorf("missing '%s%s' after line %d", ciMarkerEnd, name, ndx+1)
+		}
+		ndx += endNdx + 1
+
+		regionLines, found := generatedRegions[name]
+		if !found {
+			return "", fmt.Errorf("no environments for the region '%s' at line %d, regenerate the pipeline with --force", name, ndx+1)
+		}
+		updatedLines = append(updatedLines, regionLines...)
+		updatedLines = append(updatedLines, existingLines[ndx])
+		numRegions++
+	}
+	if numRegions == 0 {
+		return "", fmt.Errorf("no '%s' markers found, regenerate the pipeline with --force", strings.TrimSpace(ciMarkerBegin))
+	}
+	updated := strings.Join(updatedLines, "\n")
+
+	// Update the pinned CLI version.
+	if !ciCliVersionLineRegex.MatchString(updated) {
+		return "", fmt.Errorf("no line with '%s' found, regenerate the pipeline with --force", ciMarkerCliVersion)
+	}
+	updated = ciCliVersionLineRegex.ReplaceAllString(updated, fmt.Sprintf(`${1}"%s"${2}`, cliVersion))
+
+	return updated, nil
+}
+
+// Parse the settings marker line of a generated pipeline, eg,
+// '# metaplay-ci:settings provider=github deploy-types=development,staging'.
+func parseCIPipelineSettings(content string) (CIPipelineOptions, error) {
+	for _, line := range strings.Split(content, "\n") {
+		settings, found := strings.CutPrefix(strings.TrimSpace(line), ciMarkerSettings)
+		if !found {
+			continue
+		}
+
+		opts := CIPipelineOptions{}
+		for _, field := range strings.Fields(settings) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "provider":
+				opts.Provider = CIProvider(value)
+			case "deploy-types":
+				for _, envType := range strings.Split(value, ",") {
+					if envType != "" {
+						opts.DeployEnvironmentTypes = append(opts.DeployEnvironmentTypes, portalapi.EnvironmentType(envType))
+					}
+				}
+			default:
+				return CIPipelineOptions{}, fmt.Errorf("unknown setting '%s' in '%s'", key, strings.TrimSpace(line))
+			}
+		}
+		return opts, nil
+	}
+	return CIPipelineOptions{}, fmt.Errorf("no '%s' marker found, the file was not generated by 'metaplay project generate-ci'", ciMarkerSettings)
+}
+
+// Get the lines inside each named region of a generated pipeline.
+func parseCIPipelineRegions(content string) map[string][]string {
+	regions := map[string][]string{}
+	currentName := ""
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if name, isBegin := strings.CutPrefix(trimmed, ciMarkerBegin); isBegin {
+			currentName = name
+			regions[name] = []string{}
+		} else if trimmed == ciMarkerEnd+currentName {
+			currentName = ""
+		} else if currentName != "" {
+			regions[currentName] = append(regions[currentName], line)
+		}
+	}
+	return regions
+}
+
+func joinEnvironmentTypes(envTypes []portalapi.EnvironmentType) string {
+	strs := []string{}
+	for _, envType := range envTypes {
+		strs = append(strs, string(envType))
+	}
+	return strings.Join(strs, ",")
+}
+
+// Templates use '[[ ]]' as delimiters as GitHub Actions uses '${{ }}' for its own expressions.
+func newCIPipelineTemplate(name, text string) *template.Template {
+	return template.Must(template.New(name).Delims("[[", "]]").Parse(text))
+}
+
+var ciPipelineTemplates = map[CIProvider]*template.Template{
+	CIProviderGitHub: newCIPipelineTemplate("github", ciPipelineHeader+`#
+# Required repository secrets:
+# - METAPLAY_CREDENTIALS: Credentials of a Metaplay machine user with access to the environments.
+
+name: Metaplay build and deploy
+
+on:
+  push:
+    branches:
+      - [[.Branch]]
+  workflow_dispatch:
+
+env:
+  METAPLAY_CLI_VERSION: "[[.CliVersion]]" `+ciMarkerCliVersion+`
+  IMAGE_NAME: [[.ProjectID]]:${{ github.sha }}
+
+jobs:
+  build:
+    name: Build and push image
+    runs-on: ubuntu-latest
+    steps:
+      - name: Check out
+        uses: actions/checkout@v4
+
+      - name: Install Metaplay CLI
+        run: |
+          curl -sSfL https://raw.githubusercontent.com/metaplay/cli/main/install.sh | bash -s -- --version "$METAPLAY_CLI_VERSION"
+          echo "$HOME/.local/bin" >> "$GITHUB_PATH"
+
+      - name: Set up Docker Buildx
+        uses: docker/setup-buildx-action@v3
+
+      - name: Log in as machine user
+        run: metaplay auth machine-login
+        env:
+          METAPLAY_CREDENTIALS: ${{ secrets.METAPLAY_CREDENTIALS }}
+
+      - name: Build image
+        run: metaplay[[.ProjectFlag]] build image "$IMAGE_NAME" --commit-id="$GITHUB_SHA" --build-number="$GITHUB_RUN_NUMBER" -- --cache-from=type=gha --cache-to=type=gha,mode=max
+
+      - name: Push image
+        run: |
+          for env in $ENVIRONMENTS; do
+            metaplay[[.ProjectFlag]] image push "$env" "$IMAGE_NAME"
+          done
+        env:
+          `+ciMarkerBegin+`environments
+          ENVIRONMENTS: [[range $ndx, $env := .Environments]][[if $ndx]] [[end]][[$env.HumanID]][[end]]
+          `+ciMarkerEnd+`environments
+[[- if .DeployEnvironments]]
+
+  deploy:
+    name: Deploy to ${{ matrix.environment }}
+    needs: build
+    runs-on: ubuntu-latest
+    environment: ${{ matrix.environment }}
+    strategy:
+      fail-fast: false
+      matrix:
+        environment:
+          `+ciMarkerBegin+`deploy-environments
+[[- range .DeployEnvironments]]
+          - [[.HumanID]]
+[[- end]]
+          `+ciMarkerEnd+`deploy-environments
+    steps:
+      - name: Check out
+        uses: actions/checkout@v4
+
+      - name: Install Metaplay CLI
+        run: |
+          curl -sSfL https://raw.githubusercontent.com/metaplay/cli/main/install.sh | bash -s -- --version "$METAPLAY_CLI_VERSION"
+          echo "$HOME/.local/bin" >> "$GITHUB_PATH"
+
+      - name: Log in as machine user
+        run: metaplay auth machine-login
+        env:
+          METAPLAY_CREDENTIALS: ${{ secrets.METAPLAY_CREDENTIALS }}
+
+      - name: Deploy game server
+        run: metaplay[[.ProjectFlag]] deploy server "${{ matrix.environment }}" "$IMAGE_NAME"
+[[- end]]
+`),
+
+	CIProviderGitLab: newCIPipelineTemplate("gitlab", ciPipelineHeader+`#
+# Required CI/CD variables:
+# - METAPLAY_CREDENTIALS: Credentials of a Metaplay machine user with access to the environments (masked).
+
+variables:
+  METAPLAY_CLI_VERSION: "[[.CliVersion]]" `+ciMarkerCliVersion+`
+  IMAGE_NAME: [[.ProjectID]]:$CI_COMMIT_SHA
+
+stages:
+  - build
+[[- if .DeployEnvironments]]
+  - deploy
+[[- end]]
+
+.metaplay:
+  image: docker:27
+  services:
+    - docker:27-dind
+  variables:
+    DOCKER_TLS_CERTDIR: "/certs"
+  before_script:
+    - apk add --no-cache bash curl git
+    - curl -sSfL https://raw.githubusercontent.com/metaplay/cli/main/install.sh | bash -s -- --version "$METAPLAY_CLI_VERSION"
+    - export PATH="$HOME/.local/bin:$PATH"
+    - metaplay auth machine-login
+  rules:
+    - if: $CI_COMMIT_BRANCH == "[[.Branch]]"
+    - if: $CI_PIPELINE_SOURCE == "web"
+
+build:
+  extends: .metaplay
+  stage: build
+  variables:
+    `+ciMarkerBegin+`environments
+    ENVIRONMENTS: [[range $ndx, $env := .Environments]][[if $ndx]] [[end]][[$env.HumanID]][[end]]
+    `+ciMarkerEnd+`environments
+  cache:
+    key: metaplay-buildx
+    paths:
+      - .buildx-cache/
+  script:
+    - docker buildx create --use --driver docker-container
+    - metaplay[[.ProjectFlag]] build image "$IMAGE_NAME" --commit-id="$CI_COMMIT_SHA" --build-number="$CI_PIPELINE_IID" -- --cache-from=type=local,src=.buildx-cache --cache-to=type=local,dest=.buildx-cache,mode=max
+    - for env in $ENVIRONMENTS; do metaplay[[.ProjectFlag]] image push "$env" "$IMAGE_NAME"; done
+[[- if .DeployEnvironments]]
+
+deploy:
+  extends: .metaplay
+  stage: deploy
+  needs:
+    - build
+  parallel:
+    matrix:
+      - ENVIRONMENT:
+          `+ciMarkerBegin+`deploy-environments
+[[- range .DeployEnvironments]]
+          - [[.HumanID]]
+[[- end]]
+          `+ciMarkerEnd+`deploy-environments
+  environment:
+    name: $ENVIRONMENT
+  script:
+    - metaplay[[.ProjectFlag]] deploy server "$ENVIRONMENT" "$IMAGE_NAME"
+[[- end]]
+`),
+
+	CIProviderAzure: newCIPipelineTemplate("azure", ciPipelineHeader+`#
+# Required pipeline variables:
+# - METAPLAY_CREDENTIALS: Credentials of a Metaplay machine user with access to the environments (secret).
+
+trigger:
+  branches:
+    include:
+      - [[.Branch]]
+
+pool:
+  vmImage: ubuntu-latest
+
+variables:
+  METAPLAY_CLI_VERSION: "[[.CliVersion]]" `+ciMarkerCliVersion+`
+  IMAGE_NAME: [[.ProjectID]]:$(Build.SourceVersion)
+  BUILDX_CACHE_DIR: $(Pipeline.Workspace)/.buildx-cache
+
+stages:
+  - stage: Build
+    jobs:
+      - job: Build
+        displayName: Build and push image
+        variables:
+          `+ciMarkerBegin+`environments
+          ENVIRONMENTS: [[range $ndx, $env := .Environments]][[if $ndx]] [[end]][[$env.HumanID]][[end]]
+          `+ciMarkerEnd+`environments
+        steps:
+          - checkout: self
+
+          - script: |
+              curl -sSfL https://raw.githubusercontent.com/metaplay/cli/main/install.sh | bash -s -- --version "$METAPLAY_CLI_VERSION"
+              echo "##vso[task.prependpath]$HOME/.local/bin"
+            displayName: Install Metaplay CLI
+
+          - script: metaplay auth machine-login
+            displayName: Log in as machine user
+            env:
+              METAPLAY_CREDENTIALS: $(METAPLAY_CREDENTIALS)
+
+          - task: Cache@2
+            displayName: Restore build cache
+            inputs:
+              key: 'metaplay-buildx | "$(Agent.OS)" | "$(Build.SourceVersion)"'
+              restoreKeys: 'metaplay-buildx | "$(Agent.OS)"'
+              path: $(BUILDX_CACHE_DIR)
+
+          - script: |
+              docker buildx create --use --driver docker-container
+              metaplay[[.ProjectFlag]] build image "$IMAGE_NAME" --commit-id="$(Build.SourceVersion)" --build-number="$(Build.BuildId)" -- --cache-from=type=local,src="$BUILDX_CACHE_DIR" --cache-to=type=local,dest="$BUILDX_CACHE_DIR",mode=max
+            displayName: Build image
+
+          - script: |
+              for env in $ENVIRONMENTS; do
+                metaplay[[.ProjectFlag]] image push "$env" "$IMAGE_NAME"
+              done
+            displayName: Push image
+[[- if .DeployEnvironments]]
+
+  - stage: Deploy
+    dependsOn: Build
+    jobs:
+      - job: Deploy
+        displayName: Deploy to $(ENVIRONMENT)
+        strategy:
+          matrix:
+            `+ciMarkerBegin+`deploy-environments
+[[- range .DeployEnvironments]]
+            [[.JobName]]:
+              ENVIRONMENT: [[.HumanID]]
+[[- end]]
+            `+ciMarkerEnd+`deploy-environments
+        steps:
+          - checkout: self
+
+          - script: |
+              curl -sSfL https://raw.githubusercontent.com/metaplay/cli/main/install.sh | bash -s -- --version "$METAPLAY_CLI_VERSION"
+              echo "##vso[task.prependpath]$HOME/.local/bin"
+            displayName: Install Metaplay CLI
+
+          - script: metaplay auth machine-login
+            displayName: Log in as machine user
+            env:
+              METAPLAY_CREDENTIALS: $(METAPLAY_CREDENTIALS)
+
+          - script: metaplay[[.ProjectFlag]] deploy server "$ENVIRONMENT" "$IMAGE_NAME"
+            displayName: Deploy game server
+[[- end]]
+`),
+}
+
+// Header shared by all the pipeline templates.
+const ciPipelineHeader = `# Build the game server image of the Metaplay project '[[.ProjectID]]', push it into the
+# project's environments, and deploy it.
+#
+# Generated by 'metaplay project generate-ci'. Run 'metaplay project generate-ci --update' to
+# update the pinned CLI version and the environments from metaplay-project.yaml. Other changes
+# to this file are preserved by the update.
+[[.Settings]]
+`
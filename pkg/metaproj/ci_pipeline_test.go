@@ -0,0 +1,169 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/metaplay/cli/pkg/portalapi"
+)
+
+func newCIPipelineTestConfig() *ProjectConfig {
+	return &ProjectConfig{
+		ProjectHumanID: "mygame",
+		Environments: []ProjectEnvironmentConfig{
+			{Name: "Develop", HumanID: "tough-falcons", Type: portalapi.EnvironmentTypeDevelopment},
+			{Name: "Staging", HumanID: "lovely-wombats", Type: portalapi.EnvironmentTypeStaging},
+			{Name: "Production", HumanID: "mighty-eagles", Type: portalapi.EnvironmentTypeProduction},
+		},
+	}
+}
+
+func TestGenerateCIPipeline(t *testing.T) {
+	config := newCIPipelineTestConfig()
+
+	for _, provider := range CIProviders {
+		for _, deployTypes := range [][]portalapi.EnvironmentType{nil, {portalapi.EnvironmentTypeDevelopment, portalapi.EnvironmentTypeStaging}} {
+			content, err := GenerateCIPipeline(config, CIPipelineOptions{
+				Provider:               provider,
+				CliVersion:             "1.2.3",
+				ProjectDir:             "Game/Backend",
+				Branch:                 "main",
+				DeployEnvironmentTypes: deployTypes,
+			})
+			if err != nil {
+				t.Fatalf("%s: failed to generate: %v", provider, err)
+			}
+
+			// The pipeline must be valid YAML.
+			var parsed map[string]any
+			if err := yaml.Unmarshal([]byte(content), &parsed); err != nil {
+				t.Fatalf("%s: generated invalid YAML: %v\n%s", provider, err, content)
+			}
+
+			for _, expected := range []string{`METAPLAY_CLI_VERSION: "1.2.3"`, "mygame:", " -p Game/Backend build image", "ENVIRONMENTS: tough-falcons lovely-wombats mighty-eagles"} {
+				if !strings.Contains(content, expected) {
+					t.Errorf("%s: pipeline does not contain %q:\n%s", provider, expected, content)
+				}
+			}
+
+			// Only environments of the deploy types are deployed into.
+			hasDeploy := strings.Contains(content, "deploy server")
+			if hasDeploy != (len(deployTypes) > 0) {
+				t.Errorf("%s: has deploy job = %v with deploy types %v", provider, hasDeploy, deployTypes)
+			}
+			regions := parseCIPipelineRegions(content)
+			if len(deployTypes) > 0 {
+				deployRegion := strings.Join(regions["deploy-environments"], "\n")
+				if !strings.Contains(deployRegion, "lovely-wombats") || strings.Contains(deployRegion, "mighty-eagles") {
+					t.Errorf("%s: unexpected deploy environments: %s", provider, deployRegion)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateCIPipelineErrors(t *testing.T) {
+	config := newCIPipelineTestConfig()
+	tests := []struct {
+		name     string
+		config   *ProjectConfig
+		opts     CIPipelineOptions
+		contains string
+	}{
+		{"invalid provider", config, CIPipelineOptions{Provider: "jenkins"}, "invalid CI provider"},
+		{"invalid deploy type", config, CIPipelineOptions{Provider: CIProviderGitHub, DeployEnvironmentTypes: []portalapi.EnvironmentType{"prod"}}, "invalid environment type"},
+		{"no environments", &ProjectConfig{ProjectHumanID: "mygame"}, CIPipelineOptions{Provider: CIProviderGitHub}, "no environments"},
+		{"no matching environments", &ProjectConfig{ProjectHumanID: "mygame", Environments: config.Environments[:1]}, CIPipelineOptions{Provider: CIProviderGitLab, DeployEnvironmentTypes: []portalapi.EnvironmentType{portalapi.EnvironmentTypeProduction}}, "no environments of type production"},
+	}
+
+	for _, test := range tests {
+		_, err := GenerateCIPipeline(test.config, test.opts)
+		if err == nil || !strings.Contains(err.Error(), test.contains) {
+			t.Errorf("%s: error = %v, expected it to contain %q", test.name, err, test.contains)
+		}
+	}
+}
+
+func TestUpdateCIPipeline(t *testing.T) {
+	config := newCIPipelineTestConfig()
+
+	for _, provider := range CIProviders {
+		original, err := GenerateCIPipeline(config, CIPipelineOptions{
+			Provider:               provider,
+			CliVersion:             "1.2.3",
+			Branch:                 "main",
+			DeployEnvironmentTypes: []portalapi.EnvironmentType{portalapi.EnvironmentTypeDevelopment},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to generate: %v", provider, err)
+		}
+
+		// Simulate manual edits to the pipeline.
+		edited := strings.Replace(original, "main", "release", 1) + "# Custom trailer\n"
+
+		// Add a development environment and remove the staging one.
+		newConfig := newCIPipelineTestConfig()
+		newConfig.Environments[1] = ProjectEnvironmentConfig{HumanID: "quick-otters", Type: portalapi.EnvironmentTypeDevelopment}
+
+		updated, err := UpdateCIPipeline(edited, newConfig, provider, "1.3.0")
+		if err != nil {
+			t.Fatalf("%s: failed to update: %v", provider, err)
+		}
+
+		for _, expected := range []string{`METAPLAY_CLI_VERSION: "1.3.0"`, "release", "# Custom trailer", "ENVIRONMENTS: tough-falcons quick-otters mighty-eagles"} {
+			if !strings.Contains(updated, expected) {
+				t.Errorf("%s: updated pipeline does not contain %q:\n%s", provider, expected, updated)
+			}
+		}
+		if strings.Contains(updated, "lovely-wombats") || strings.Contains(updated, `"1.2.3"`) {
+			t.Errorf("%s: updated pipeline contains stale values:\n%s", provider, updated)
+		}
+		deployRegion := strings.Join(parseCIPipelineRegions(updated)["deploy-environments"], "\n")
+		if !strings.Contains(deployRegion, "quick-otters") {
+			t.Errorf("%s: new development environment not deployed into: %s", provider, deployRegion)
+		}
+
+		// Updating again is a no-op.
+		again, err := UpdateCIPipeline(updated, newConfig, provider, "1.3.0")
+		if err != nil || again != updated {
+			t.Errorf("%s: second update changed the pipeline (err: %v)", provider, err)
+		}
+
+		// The provider must match.
+		otherProvider := CIProviderGitHub
+		if provider == CIProviderGitHub {
+			otherProvider = CIProviderGitLab
+		}
+		if _, err := UpdateCIPipeline(edited, newConfig, otherProvider, "1.3.0"); err == nil {
+			t.Errorf("%s: expected an error when updating as %s", provider, otherProvider)
+		}
+	}
+}
+
+func TestUpdateCIPipelineErrors(t *testing.T) {
+	config := newCIPipelineTestConfig()
+	original, err := GenerateCIPipeline(config, CIPipelineOptions{Provider: CIProviderGitHub, CliVersion: "1.2.3", Branch: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		contains string
+	}{
+		{"not generated", "name: My workflow\n", "was not generated"},
+		{"missing end marker", strings.Replace(original, ciMarkerEnd+"environments", "", 1), "missing '# metaplay-ci:end environments'"},
+		{"missing version marker", strings.Replace(original, ciMarkerCliVersion, "", 1), "no line with '# metaplay-ci:cli-version'"},
+	}
+	for _, test := range tests {
+		_, err := UpdateCIPipeline(test.existing, config, CIProviderGitHub, "1.3.0")
+		if err == nil || !strings.Contains(err.Error(), test.contains) {
+			t.Errorf("%s: error = %v, expected it to contain %q", test.name, err, test.contains)
+		}
+	}
+}
@@ -11,10 +11,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/hashicorp/go-version"
 	"github.com/metaplay/cli/pkg/auth"
+	"github.com/metaplay/cli/pkg/containerutil"
 	"github.com/metaplay/cli/pkg/portalapi"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
@@ -25,9 +27,19 @@ import (
 type MetaplayProject struct {
 	Config          ProjectConfig
 	RelativeDir     string
+	ConfigFilePath  string // Relative path to the project config file, defaults to metaplay-project.yaml in RelativeDir.
 	VersionMetadata MetaplayVersionMetadata
 }
 
+// Get the path to the project config file, ie, metaplay-project.yaml unless another file was
+// specified with --config-file.
+func (project *MetaplayProject) GetConfigFilePath() string {
+	if project.ConfigFilePath != "" {
+		return project.ConfigFilePath
+	}
+	return filepath.Join(project.RelativeDir, ConfigFileName)
+}
+
 func (project *MetaplayProject) UsesCustomDashboard() bool {
 	return project.Config.Features.Dashboard.UseCustom
 }
@@ -79,6 +91,34 @@ func (project *MetaplayProject) GetServerValuesFiles(envConfig *ProjectEnvironme
 	}
 }
 
+// Default pattern for the per-environment game server Helm values files, used if the project
+// doesn't specify 'serverValuesFilePattern'.
+const DefaultServerValuesFilePattern = "deployments/<environment>.yaml"
+
+// Placeholder for the environment ID in 'serverValuesFilePattern'.
+const serverValuesFilePatternPlaceholder = "<environment>"
+
+// Find the per-environment game server Helm values file of the environment using the project's
+// 'serverValuesFilePattern'. Returns an empty string if the file doesn't exist.
+func (project *MetaplayProject) FindEnvironmentServerValuesFile(envConfig *ProjectEnvironmentConfig) (string, error) {
+	pattern := project.Config.ServerValuesFilePattern
+	if pattern == "" {
+		pattern = DefaultServerValuesFilePattern
+	}
+	valuesFilePath := filepath.Join(project.RelativeDir, strings.ReplaceAll(pattern, serverValuesFilePatternPlaceholder, envConfig.HumanID))
+
+	info, err := os.Stat(valuesFilePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to check environment Helm values file %s: %w", valuesFilePath, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("environment Helm values file %s is a directory", valuesFilePath)
+	}
+	return valuesFilePath, nil
+}
+
 func (project *MetaplayProject) GetBotClientValuesFiles(envConfig *ProjectEnvironmentConfig) []string {
 	if envConfig.BotClientValuesFile != "" {
 		return []string{
@@ -101,25 +141,35 @@ func LoadProjectConfigFile(projectDir string) (*ProjectConfig, error) {
 	}
 
 	// Build the full path to the config file in the directory.
-	configFilePath := filepath.Join(projectDir, ConfigFileName)
+	return LoadProjectConfigFileFromPath(filepath.Join(projectDir, ConfigFileName))
+}
 
+// Load the Metaplay project config file from the given path. The file can have any name, and
+// the paths within it are relative to the directory containing the file.
+func LoadProjectConfigFileFromPath(configFilePath string) (*ProjectConfig, error) {
 	// Read the file content.
 	content, err := os.ReadFile(configFilePath)
 	if err != nil {
 		return nil, err
 	}
 
+	return ParseProjectConfig(content, configFilePath)
+}
+
+// Parse and validate the content of the Metaplay project config file at the given path, eg,
+// to check edits to the file before writing them.
+func ParseProjectConfig(content []byte, configFilePath string) (*ProjectConfig, error) {
 	// Unmarshal the YAML content into the ProjectConfig struct.
 	var projectConfig ProjectConfig
-	err = yaml.Unmarshal(content, &projectConfig)
+	err := yaml.Unmarshal(content, &projectConfig)
 	if err != nil {
 		return nil, err
 	}
 
 	// Validate the project config.
-	err = ValidateProjectConfig(projectDir, &projectConfig)
+	err = ValidateProjectConfig(filepath.Dir(configFilePath), &projectConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate metaplay-project.yaml: %v", err)
+		return nil, fmt.Errorf("failed to validate %s: %v", filepath.Base(configFilePath), err)
 	}
 
 	return &projectConfig, nil
@@ -197,6 +247,11 @@ func validateHelmChartVersion(fieldName string, chartVersion string) error {
 
 // Check that the provided project config is a valid one.
 func ValidateProjectConfig(projectDir string, config *ProjectConfig) error {
+	// Schema version.
+	if err := validateProjectConfigVersion(config); err != nil {
+		return err
+	}
+
 	// Project identity and directories.
 	if config.ProjectHumanID == "" {
 		return fmt.Errorf("missing required field 'projectID'")
@@ -240,6 +295,14 @@ func ValidateProjectConfig(projectDir string, config *ProjectConfig) error {
 	if err := validateHelmChartVersion("botClientChartVersion", config.BotClientChartVersion); err != nil {
 		return err
 	}
+	if config.ServerValuesFilePattern != "" {
+		if filepath.IsAbs(config.ServerValuesFilePattern) {
+			return fmt.Errorf("field 'serverValuesFilePattern' ('%s') specifies an absolute path: all paths must be relative", config.ServerValuesFilePattern)
+		}
+		if !strings.Contains(config.ServerValuesFilePattern, serverValuesFilePatternPlaceholder) {
+			return fmt.Errorf("field 'serverValuesFilePattern' ('%s') must contain the placeholder '%s'", config.ServerValuesFilePattern, serverValuesFilePatternPlaceholder)
+		}
+	}
 
 	// Validate auth providers (if specified).
 	if config.AuthProviders == nil {
@@ -302,6 +365,16 @@ func ValidateProjectConfig(projectDir string, config *ProjectConfig) error {
 		}
 	}
 
+	// Validate container runtime.
+	if config.ContainerRuntime != "" && !slices.Contains(containerutil.RuntimeNames, config.ContainerRuntime) {
+		return fmt.Errorf("invalid 'containerRuntime' '%s', must be one of: %s", config.ContainerRuntime, strings.Join(containerutil.RuntimeNames, ", "))
+	}
+
+	// Validate image size budget.
+	if config.ImageSizeBudgetMB < 0 {
+		return fmt.Errorf("invalid 'imageSizeBudgetMB' %d, must be a positive number of megabytes", config.ImageSizeBudgetMB)
+	}
+
 	// Validate project features.
 	dashboardConfig := config.Features.Dashboard
 	if dashboardConfig.UseCustom {
@@ -335,6 +408,9 @@ func ValidateProjectConfig(projectDir string, config *ProjectConfig) error {
 		if envConfig.Type == "" {
 			return fmt.Errorf("environment '%s' did not specify required field 'type'", envName)
 		}
+		if !isValidEnvironmentType(envConfig.Type) {
+			return fmt.Errorf("environment '%s' specified invalid 'type' '%s': must be one of 'development', 'staging', or 'production'", envName, envConfig.Type)
+		}
 		if err := ValidateEnvironmentID(envConfig.HumanID); err != nil {
 			return fmt.Errorf("environment '%s' specified invalid 'humanId': %w", envName, err)
 		}
@@ -359,6 +435,11 @@ func ValidateProjectConfig(projectDir string, config *ProjectConfig) error {
 		}
 	}
 
+	// Validate policies.
+	if err := validatePolicies(config.Policies); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -445,8 +526,7 @@ func LoadSdkVersionMetadata(sdkRootDir string) (*MetaplayVersionMetadata, error)
 		}
 
 		// Check that the SDK version is the minimum supported by the CLI.
-		minSupportedVersion, _ := version.NewVersion("32.0.0-aaaaa") // allow prerelease SDK versions
-		if sdkVersion.LessThan(minSupportedVersion) {
+		if compatible, _ := CheckSdkCompatibility(sdkVersion); !compatible {
 			return nil, fmt.Errorf("minimum Metaplay SDK version supported by this CLI is Release 32, your project is using %s", sdkVersion)
 		}
 
@@ -578,6 +658,9 @@ var projectFileTemplate = template.Must(template.New("Metaplay project config").
 # yaml-language-server: $schema={{.SdkRootDir}}/projectConfigSchema.json
 $schema: "{{.SdkRootDir}}/projectConfigSchema.json"
 
+# Version of the metaplay-project.yaml schema.
+configVersion: {{.ConfigVersion}}
+
 # Configure project.
 projectID: {{.ProjectID}}
 buildRootDir: .
@@ -622,6 +705,7 @@ func GenerateProjectConfigFile(
 	// Data for the template
 	data := struct {
 		SchemaPath            string
+		ConfigVersion         int
 		ProjectID             string
 		BuildRootDir          string
 		SdkRootDir            string
@@ -635,6 +719,7 @@ func GenerateProjectConfigFile(
 		CustomDashboardPath   string
 		Environments          []portalapi.EnvironmentInfo
 	}{
+		ConfigVersion:         CurrentProjectConfigVersion,
 		ProjectID:             project.HumanID,
 		BuildRootDir:          ".",
 		SdkRootDir:            filepath.ToSlash(pathToMetaplaySdk),
@@ -0,0 +1,143 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindEnvironmentServerValuesFile(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "deployments"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "deployments", "tough-falcons.yaml"), []byte("replicas: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project := &MetaplayProject{RelativeDir: projectDir}
+
+	// The default pattern finds the environment's file.
+	path, err := project.FindEnvironmentServerValuesFile(&ProjectEnvironmentConfig{HumanID: "tough-falcons"})
+	if err != nil || path != filepath.Join(projectDir, "deployments", "tough-falcons.yaml") {
+		t.Errorf("expected to find the values file of tough-falcons, got %q (err: %v)", path, err)
+	}
+
+	// A missing file is not an error.
+	path, err = project.FindEnvironmentServerValuesFile(&ProjectEnvironmentConfig{HumanID: "lovely-wombats"})
+	if err != nil || path != "" {
+		t.Errorf("expected no values file for lovely-wombats, got %q (err: %v)", path, err)
+	}
+
+	// A custom pattern is used if specified.
+	project.Config.ServerValuesFilePattern = "Backend/Deployments/<environment>-server.yaml"
+	path, err = project.FindEnvironmentServerValuesFile(&ProjectEnvironmentConfig{HumanID: "tough-falcons"})
+	if err != nil || path != "" {
+		t.Errorf("expected no values file with the custom pattern, got %q (err: %v)", path, err)
+	}
+}
+
+func TestLoadProjectConfigFileFromPath(t *testing.T) {
+	// Project config in a non-standard location, with the paths relative to it.
+	rootDir := t.TempDir()
+	for _, dir := range []string{"ci", "MetaplaySDK", "Backend", "Assets/SharedCode", "Unity"} {
+		if err := os.MkdirAll(filepath.Join(rootDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFilePath := filepath.Join(rootDir, "ci", "project.ci.yaml")
+	configYAML := `projectID: mygame
+buildRootDir: ..
+sdkRootDir: ../MetaplaySDK
+backendDir: ../Backend
+sharedCodeDir: ../Assets/SharedCode
+unityProjectDir: ../Unity
+dotnetRuntimeVersion: "9.0"
+serverChartVersion: 0.8.0
+botClientChartVersion: 0.8.0
+`
+	if err := os.WriteFile(configFilePath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadProjectConfigFileFromPath(configFilePath)
+	if err != nil {
+		t.Fatalf("failed to load the config file: %v", err)
+	}
+	if config.ProjectHumanID != "mygame" || config.SdkRootDir != "../MetaplaySDK" {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	// The same file in the root directory is invalid, as the paths are relative to the file.
+	rootConfigFilePath := filepath.Join(rootDir, ConfigFileName)
+	if err := os.WriteFile(rootConfigFilePath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProjectConfigFileFromPath(rootConfigFilePath); err == nil {
+		t.Errorf("expected loading %s to fail", rootConfigFilePath)
+	}
+
+	// The project's config file path defaults to metaplay-project.yaml in the project directory.
+	project := &MetaplayProject{RelativeDir: "Game"}
+	if path := project.GetConfigFilePath(); path != filepath.Join("Game", ConfigFileName) {
+		t.Errorf("default config file path = %q", path)
+	}
+	project.ConfigFilePath = filepath.Join("ci", "project.ci.yaml")
+	if path := project.GetConfigFilePath(); path != filepath.Join("ci", "project.ci.yaml") {
+		t.Errorf("custom config file path = %q", path)
+	}
+}
+
+func TestProjectConfigVersion(t *testing.T) {
+	// Configs without 'configVersion' are version 1, and thus outdated.
+	config := &ProjectConfig{}
+	if config.GetConfigVersion() != 1 || !config.IsConfigVersionOutdated() {
+		t.Errorf("expected a missing configVersion to be an outdated version 1")
+	}
+	if changes := GetProjectConfigChangesSince(1); len(changes) == 0 {
+		t.Errorf("expected changes since version 1")
+	}
+	config.ConfigVersion = CurrentProjectConfigVersion
+	if config.IsConfigVersionOutdated() || len(GetProjectConfigChangesSince(CurrentProjectConfigVersion)) != 0 {
+		t.Errorf("expected the current configVersion to be up-to-date")
+	}
+
+	// Configs newer than the CLI are rejected.
+	config.ConfigVersion = CurrentProjectConfigVersion + 1
+	if err := validateProjectConfigVersion(config); err == nil {
+		t.Errorf("expected an error for a configVersion newer than the CLI")
+	}
+
+	// Migration adds the version after the '$schema', or updates an existing version, and
+	// fills in the defaults of the fields added since the version.
+	migrated := MigrateProjectConfigFile("$schema: \"MetaplaySDK/projectConfigSchema.json\"\n\nprojectID: mygame\n", 1)
+	expected := fmt.Sprintf("$schema: \"MetaplaySDK/projectConfigSchema.json\"\n\n# Version of the metaplay-project.yaml schema.\nconfigVersion: %d\n\nprojectID: mygame\n\n# %s\nserverValuesFilePattern: \"deployments/<environment>.yaml\"\n", CurrentProjectConfigVersion, projectConfigVersionDefaults[2][0].Comment)
+	if migrated != expected {
+		t.Errorf("unexpected migrated config:\n%s", migrated)
+	}
+	migrated = MigrateProjectConfigFile("configVersion: 1\nprojectID: mygame\nenvironments:\n  - humanId: tough-falcons\n", 1)
+	expected = fmt.Sprintf("configVersion: %d\nprojectID: mygame\n# %s\nserverValuesFilePattern: \"deployments/<environment>.yaml\"\n\nenvironments:\n  - humanId: tough-falcons\n", CurrentProjectConfigVersion, projectConfigVersionDefaults[2][0].Comment)
+	if migrated != expected {
+		t.Errorf("unexpected migrated config:\n%s", migrated)
+	}
+
+	// Fields already in the config are kept as-is.
+	content := "configVersion: 1\nprojectID: mygame\nserverValuesFilePattern: values/<environment>.yaml\n"
+	if migrated := MigrateProjectConfigFile(content, 1); migrated != fmt.Sprintf("configVersion: %d\nprojectID: mygame\nserverValuesFilePattern: values/<environment>.yaml\n", CurrentProjectConfigVersion) {
+		t.Errorf("unexpected migrated config:\n%s", migrated)
+	}
+
+	// The migrated config is valid and uses the default values file pattern.
+	migratedConfig := &ProjectConfig{}
+	if err := yaml.Unmarshal([]byte(MigrateProjectConfigFile("projectID: mygame\n", 1)), migratedConfig); err != nil {
+		t.Fatalf("failed to parse migrated config: %v", err)
+	}
+	if migratedConfig.ConfigVersion != CurrentProjectConfigVersion || migratedConfig.ServerValuesFilePattern != DefaultServerValuesFilePattern {
+		t.Errorf("unexpected migrated config: %+v", migratedConfig)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/metaplay/cli/pkg/portalapi"
+)
+
+// Rule that restricts the operations allowed on an environment.
+type PolicyRule string
+
+const (
+	PolicyRuleRequireCI         PolicyRule = "require-ci"           // Deployments are only allowed from a CI system.
+	PolicyRuleNoBotClientDeploy PolicyRule = "no-botclient-deploys" // Bot clients may not be deployed.
+)
+
+// All supported policy rules with their descriptions.
+var policyRuleDescriptions = map[PolicyRule]string{
+	PolicyRuleRequireCI:         "deployments are only allowed from CI",
+	PolicyRuleNoBotClientDeploy: "bot clients may not be deployed",
+}
+
+// Policy from 'metaplay-project.yaml' ($.policies[]). The rule applies to all environments
+// of the listed types, eg:
+//
+//	policies:
+//	  - rule: require-ci
+//	    environmentTypes: [ production ]
+type EnvironmentPolicy struct {
+	Rule             PolicyRule                  `yaml:"rule"`             // Rule to enforce.
+	EnvironmentTypes []portalapi.EnvironmentType `yaml:"environmentTypes"` // Types of environments that the rule applies to.
+}
+
+// Get the human-readable description of the rule.
+func (rule PolicyRule) Description() string {
+	return policyRuleDescriptions[rule]
+}
+
+// Check whether the policy applies to the given environment.
+func (policy *EnvironmentPolicy) AppliesTo(envConfig *ProjectEnvironmentConfig) bool {
+	return slices.Contains(policy.EnvironmentTypes, envConfig.Type)
+}
+
+// Get the policies that apply to the given environment.
+func (projectConfig *ProjectConfig) GetEnvironmentPolicies(envConfig *ProjectEnvironmentConfig) []EnvironmentPolicy {
+	var policies []EnvironmentPolicy
+	for _, policy := range projectConfig.Policies {
+		if policy.AppliesTo(envConfig) {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+func validatePolicies(policies []EnvironmentPolicy) error {
+	for ndx, policy := range policies {
+		if policy.Rule == "" {
+			return fmt.Errorf("policy at index %d did not specify required field 'rule'", ndx)
+		}
+		if _, found := policyRuleDescriptions[policy.Rule]; !found {
+			return fmt.Errorf("policy at index %d specified unknown rule '%s': must be one of '%s' or '%s'", ndx, policy.Rule, PolicyRuleRequireCI, PolicyRuleNoBotClientDeploy)
+		}
+		if len(policy.EnvironmentTypes) == 0 {
+			return fmt.Errorf("policy '%s' did not specify any 'environmentTypes'", policy.Rule)
+		}
+		for _, envType := range policy.EnvironmentTypes {
+			if !isValidEnvironmentType(envType) {
+				return fmt.Errorf("policy '%s' specified invalid environment type '%s': must be one of 'development', 'staging', or 'production'", policy.Rule, envType)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"testing"
+
+	"github.com/metaplay/cli/pkg/portalapi"
+)
+
+func TestValidatePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  EnvironmentPolicy
+		isValid bool
+	}{
+		{"valid", EnvironmentPolicy{PolicyRuleRequireCI, []portalapi.EnvironmentType{portalapi.EnvironmentTypeProduction}}, true},
+		{"missing rule", EnvironmentPolicy{"", []portalapi.EnvironmentType{portalapi.EnvironmentTypeProduction}}, false},
+		{"unknown rule", EnvironmentPolicy{"no-fridays", []portalapi.EnvironmentType{portalapi.EnvironmentTypeProduction}}, false},
+		{"no types", EnvironmentPolicy{PolicyRuleNoBotClientDeploy, nil}, false},
+		{"invalid type", EnvironmentPolicy{PolicyRuleNoBotClientDeploy, []portalapi.EnvironmentType{"prod"}}, false},
+	}
+
+	for _, test := range tests {
+		err := validatePolicies([]EnvironmentPolicy{test.policy})
+		if (err == nil) != test.isValid {
+			t.Errorf("%s: validatePolicies() error = %v, expected valid = %v", test.name, err, test.isValid)
+		}
+	}
+}
+
+func TestGetEnvironmentPolicies(t *testing.T) {
+	config := ProjectConfig{
+		Policies: []EnvironmentPolicy{
+			{PolicyRuleRequireCI, []portalapi.EnvironmentType{portalapi.EnvironmentTypeStaging, portalapi.EnvironmentTypeProduction}},
+			{PolicyRuleNoBotClientDeploy, []portalapi.EnvironmentType{portalapi.EnvironmentTypeProduction}},
+		},
+	}
+
+	devPolicies := config.GetEnvironmentPolicies(&ProjectEnvironmentConfig{Type: portalapi.EnvironmentTypeDevelopment})
+	if len(devPolicies) != 0 {
+		t.Errorf("development: got %d policies, expected 0", len(devPolicies))
+	}
+	prodPolicies := config.GetEnvironmentPolicies(&ProjectEnvironmentConfig{Type: portalapi.EnvironmentTypeProduction})
+	if len(prodPolicies) != 2 {
+		t.Errorf("production: got %d policies, expected 2", len(prodPolicies))
+	}
+}
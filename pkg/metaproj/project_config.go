@@ -25,6 +25,8 @@ type ProjectFeaturesConfig struct {
 // Metaplay project config file, named `metaplay-project.yaml`.
 // Note: When adding new fields, remember to update ValidateProjectConfig().
 type ProjectConfig struct {
+	ConfigVersion int `yaml:"configVersion,omitempty"` // Version of the schema of this file (see CurrentProjectConfigVersion), missing means version 1
+
 	ProjectHumanID  string `yaml:"projectID"`       // The project's human ID (as in the portal)
 	BuildRootDir    string `yaml:"buildRootDir"`    // Relative path to the docker build root directory
 	SdkRootDir      string `yaml:"sdkRootDir"`      // Relative path to the MetaplaySDK directory
@@ -34,13 +36,21 @@ type ProjectConfig struct {
 
 	DotnetRuntimeVersion *version.Version `yaml:"dotnetRuntimeVersion"` // .NET runtime version that the project is using (major.minor), eg, '8.0' or '9.0'
 
+	ContainerRuntime string `yaml:"containerRuntime,omitempty"` // Container runtime for building and pushing images: 'docker' or 'podman' (auto-detected if not specified)
+
+	ImageSizeBudgetMB int `yaml:"imageSizeBudgetMB,omitempty"` // Size budget of the game server image in megabytes, exceeding it is warned about by 'metaplay build image' (no budget if not specified)
+
 	HelmChartRepository   string `yaml:"helmChartRepository"`   // Helm chart repository to use (defaults to 'https://charts.metaplay.dev')
 	ServerChartVersion    string `yaml:"serverChartVersion"`    // Version of the game server Helm chart to use (or 'latest-prerelease' for absolute latest)
 	BotClientChartVersion string `yaml:"botClientChartVersion"` // Version of the bot client Helm chart to use (or 'latest-prerelease' for absolute latest)
 
+	ServerValuesFilePattern string `yaml:"serverValuesFilePattern,omitempty"` // Path pattern (relative to metaplay-project.yaml) of the per-environment game server Helm values files, '<environment>' is replaced with the environment ID (defaults to 'deployments/<environment>.yaml')
+
 	AuthProviders map[string]*auth.AuthProviderConfig `yaml:"authProviders,omitempty"`
 
 	Features ProjectFeaturesConfig `yaml:"features"`
 
 	Environments []ProjectEnvironmentConfig `yaml:"environments"`
+
+	Policies []EnvironmentPolicy `yaml:"policies,omitempty"` // Policies restricting the operations on environments, by environment type.
 }
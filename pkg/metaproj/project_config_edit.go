@@ -0,0 +1,256 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Valid label in a stack domain, eg, 'p1' in 'p1.metaplay.io'.
+var stackDomainLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Validate the stack domain of an environment, eg, 'p1.metaplay.io'. The domain must be a
+// plain host name: the URLs to the stack's services are derived from it.
+func ValidateStackDomain(domain string) error {
+	if domain == "" {
+		return fmt.Errorf("stack domain is empty")
+	}
+	if strings.Contains(domain, "://") {
+		if parsed, err := url.Parse(domain); err == nil && parsed.Hostname() != "" {
+			return fmt.Errorf("stack domain must be a domain name, not a URL: use '%s' instead of '%s'", parsed.Hostname(), domain)
+		}
+		return fmt.Errorf("stack domain must be a domain name, not a URL: '%s'", domain)
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("stack domain '%s' must have at least two dot-separated labels, eg, 'p1.metaplay.io'", domain)
+	}
+	for _, label := range labels {
+		if !stackDomainLabelPattern.MatchString(label) {
+			return fmt.Errorf("stack domain '%s' contains an invalid label '%s' - only lower-case ASCII alphanumeric characters (a-z, 0-9) and inner dashes are allowed", domain, label)
+		}
+	}
+	return nil
+}
+
+// Add an environment to the end of the 'environments' in the content of a project config file.
+// The file is parsed with yaml.v3 to locate the environments, and the new entry is spliced into
+// the text, so the rest of the file, including its comments, order, and whitespace, is retained.
+func AddEnvironmentToConfigFile(content string, envConfig ProjectEnvironmentConfig) (string, error) {
+	if err := ValidateEnvironmentID(envConfig.HumanID); err != nil {
+		return "", err
+	}
+	if err := ValidateStackDomain(envConfig.StackDomain); err != nil {
+		return "", err
+	}
+	if !isValidEnvironmentType(envConfig.Type) {
+		return "", fmt.Errorf("invalid environment type '%s': must be one of 'development', 'staging', or 'production'", envConfig.Type)
+	}
+
+	lines, newline := splitConfigFileLines(content)
+	envsKey, envsNode, err := findConfigEnvironmentsNode(content)
+	if err != nil {
+		return "", err
+	}
+
+	// Render the environment as a sequence item.
+	envYAML, err := yaml.Marshal(envConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal environment to YAML: %w", err)
+	}
+	renderItem := func(indent string) []string {
+		itemLines := strings.Split(strings.TrimSuffix(string(envYAML), "\n"), "\n")
+		for ndx, line := range itemLines {
+			if ndx == 0 {
+				itemLines[ndx] = indent + "- " + line
+			} else {
+				itemLines[ndx] = indent + "  " + line
+			}
+		}
+		return itemLines
+	}
+
+	// No 'environments' at all: append it to the end of the file.
+	if envsKey == nil {
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, "", "# Project environments.", "environments:")
+		lines = append(lines, renderItem("  ")...)
+		return joinConfigFileLines(lines, newline), nil
+	}
+
+	// Empty 'environments' (null or '[]'): replace the value with a block sequence.
+	if envsNode.Kind == yaml.ScalarNode || (envsNode.Kind == yaml.SequenceNode && len(envsNode.Content) == 0) {
+		if envsNode.Kind == yaml.SequenceNode && envsNode.Line != envsKey.Line {
+			return "", fmt.Errorf("unable to edit the empty 'environments' spanning multiple lines")
+		}
+		keyLineNdx := envsKey.Line - 1
+		keyIndent := strings.Repeat(" ", envsKey.Column-1)
+		lines[keyLineNdx] = keyIndent + "environments:"
+		lines = insertConfigFileLines(lines, keyLineNdx+1, renderItem(keyIndent+"  "))
+		return joinConfigFileLines(lines, newline), nil
+	}
+
+	if envsNode.Style&yaml.FlowStyle != 0 {
+		return "", fmt.Errorf("unable to edit 'environments' in the flow style, convert it to a block sequence ('- name: ...') first")
+	}
+
+	// Check that the environment doesn't exist yet.
+	for _, itemNode := range envsNode.Content {
+		if getMappingValue(itemNode, "humanId") == envConfig.HumanID {
+			return "", fmt.Errorf("environment '%s' already exists in the project config", envConfig.HumanID)
+		}
+	}
+
+	// Insert after the last environment, with the same indentation.
+	lastItem := envsNode.Content[len(envsNode.Content)-1]
+	itemIndent := strings.Repeat(" ", max(lastItem.Column-3, 0))
+	lines = insertConfigFileLines(lines, lastNodeLine(lastItem), renderItem(itemIndent))
+	return joinConfigFileLines(lines, newline), nil
+}
+
+// Remove the environment with the given human ID from the 'environments' in the content of a
+// project config file. The comments directly above the environment's entry are removed with
+// it, and the rest of the file is retained as is.
+func RemoveEnvironmentFromConfigFile(content string, humanID string) (string, error) {
+	lines, newline := splitConfigFileLines(content)
+	envsKey, envsNode, err := findConfigEnvironmentsNode(content)
+	if err != nil {
+		return "", err
+	}
+	if envsKey == nil || envsNode.Kind != yaml.SequenceNode {
+		return "", fmt.Errorf("environment '%s' not found in the project config", humanID)
+	}
+	if envsNode.Style&yaml.FlowStyle != 0 {
+		return "", fmt.Errorf("unable to edit 'environments' in the flow style, convert it to a block sequence ('- name: ...') first")
+	}
+
+	itemNdx := -1
+	for ndx, itemNode := range envsNode.Content {
+		if getMappingValue(itemNode, "humanId") == humanID {
+			itemNdx = ndx
+			break
+		}
+	}
+	if itemNdx == -1 {
+		return "", fmt.Errorf("environment '%s' not found in the project config", humanID)
+	}
+
+	// Resolve the lines of the entry: from its comments to the start of the next entry (or
+	// the last line of the entry if it's the last one).
+	startLineNdx := itemStartLineNdx(lines, envsNode.Content[itemNdx])
+	var endLineNdx int
+	if itemNdx+1 < len(envsNode.Content) {
+		endLineNdx = itemStartLineNdx(lines, envsNode.Content[itemNdx+1])
+	} else {
+		endLineNdx = lastNodeLine(envsNode.Content[itemNdx])
+	}
+	lines = append(lines[:startLineNdx], lines[endLineNdx:]...)
+
+	// Keep the 'environments' valid when removing the last environment.
+	if len(envsNode.Content) == 1 {
+		keyLineNdx := envsKey.Line - 1
+		lines[keyLineNdx] = strings.Repeat(" ", envsKey.Column-1) + "environments: []"
+	}
+
+	return joinConfigFileLines(lines, newline), nil
+}
+
+// Find the key and value nodes of the top-level 'environments' in a project config file.
+// Returns nil nodes if there are no environments in the file.
+func findConfigEnvironmentsNode(content string) (*yaml.Node, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse project config: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("project config must be a YAML mapping")
+	}
+
+	root := doc.Content[0]
+	for ndx := 0; ndx+1 < len(root.Content); ndx += 2 {
+		keyNode, valueNode := root.Content[ndx], root.Content[ndx+1]
+		if keyNode.Value != "environments" {
+			continue
+		}
+		if valueNode.Kind == yaml.ScalarNode && valueNode.Tag != "!!null" {
+			return nil, nil, fmt.Errorf("'environments' must be a list, got '%s'", valueNode.Value)
+		}
+		if valueNode.Kind != yaml.ScalarNode && valueNode.Kind != yaml.SequenceNode {
+			return nil, nil, fmt.Errorf("'environments' must be a list")
+		}
+		return keyNode, valueNode, nil
+	}
+	return nil, nil, nil
+}
+
+// Get the value of a key in a mapping node, or an empty string if not found.
+func getMappingValue(node *yaml.Node, key string) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for ndx := 0; ndx+1 < len(node.Content); ndx += 2 {
+		if node.Content[ndx].Value == key {
+			return node.Content[ndx+1].Value
+		}
+	}
+	return ""
+}
+
+// Get the (1-based) last line occupied by the node or any of its children.
+func lastNodeLine(node *yaml.Node) int {
+	last := node.Line
+	if node.Kind == yaml.ScalarNode && (node.Style&(yaml.LiteralStyle|yaml.FoldedStyle)) != 0 {
+		last += strings.Count(strings.TrimSuffix(node.Value, "\n"), "\n") + 1
+	}
+	for _, child := range node.Content {
+		last = max(last, lastNodeLine(child))
+	}
+	return last
+}
+
+// Get the (0-based) index of the first line of a sequence item, including the comment lines
+// directly above it at the same indentation.
+func itemStartLineNdx(lines []string, itemNode *yaml.Node) int {
+	lineNdx := itemNode.Line - 1
+	dashIndent := max(itemNode.Column-3, 0)
+	for lineNdx > 0 {
+		prevLine := lines[lineNdx-1]
+		trimmed := strings.TrimLeft(prevLine, " ")
+		if !strings.HasPrefix(trimmed, "#") || len(prevLine)-len(trimmed) != dashIndent {
+			break
+		}
+		lineNdx--
+	}
+	return lineNdx
+}
+
+// Split the content of a config file into lines, and detect its line ending.
+func splitConfigFileLines(content string) ([]string, string) {
+	newline := "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+	}
+	return strings.Split(strings.TrimSuffix(content, newline), newline), newline
+}
+
+// Join the lines of a config file, with a final line ending.
+func joinConfigFileLines(lines []string, newline string) string {
+	return strings.Join(lines, newline) + newline
+}
+
+// Insert lines at the given (0-based) index.
+func insertConfigFileLines(lines []string, lineNdx int, newLines []string) []string {
+	result := make([]string, 0, len(lines)+len(newLines))
+	result = append(result, lines[:lineNdx]...)
+	result = append(result, newLines...)
+	return append(result, lines[lineNdx:]...)
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/metaplay/cli/pkg/portalapi"
+)
+
+const testEditConfigFile = `# Configure project.
+projectID: idler
+
+# Project environments.
+environments:
+  - name: Develop # The main dev environment.
+    humanId: idler-develop
+    type: development
+    stackDomain: p1.metaplay.io
+  # Staging for QA.
+  - name: Staging
+    humanId: idler-staging
+    type: staging
+    stackDomain: p1.metaplay.io
+
+# Policies.
+policies: []
+`
+
+func TestValidateStackDomain(t *testing.T) {
+	tests := []struct {
+		domain  string
+		isValid bool
+	}{
+		{"p1.metaplay.io", true},
+		{"my-stack.example.com", true},
+		{"", false},
+		{"localhost", false},
+		{"https://p1.metaplay.io", false},
+		{"p1.metaplay.io/stackapi", false},
+		{"P1.Metaplay.io", false},
+		{"-p1.metaplay.io", false},
+	}
+
+	for _, test := range tests {
+		if err := ValidateStackDomain(test.domain); (err == nil) != test.isValid {
+			t.Errorf("ValidateStackDomain(%q) error = %v, expected valid = %v", test.domain, err, test.isValid)
+		}
+	}
+}
+
+func TestAddEnvironmentToConfigFile(t *testing.T) {
+	newEnv := ProjectEnvironmentConfig{
+		Name:        "Load Test",
+		HumanID:     "idler-loadtest",
+		Type:        portalapi.EnvironmentTypeDevelopment,
+		StackDomain: "p2.metaplay.io",
+	}
+
+	result, err := AddEnvironmentToConfigFile(testEditConfigFile, newEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := strings.Replace(testEditConfigFile, "\n# Policies.", `  - name: Load Test
+    humanId: idler-loadtest
+    type: development
+    stackDomain: p2.metaplay.io
+
+# Policies.`, 1)
+	if result != expected {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+
+	// Adding a duplicate fails.
+	if _, err := AddEnvironmentToConfigFile(result, newEnv); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected a duplicate error, got: %v", err)
+	}
+
+	// Invalid values are rejected.
+	invalidEnv := newEnv
+	invalidEnv.StackDomain = "https://p2.metaplay.io"
+	if _, err := AddEnvironmentToConfigFile(testEditConfigFile, invalidEnv); err == nil {
+		t.Errorf("expected an error for a stack domain URL")
+	}
+	invalidEnv = newEnv
+	invalidEnv.HumanID = "Loadtest"
+	if _, err := AddEnvironmentToConfigFile(testEditConfigFile, invalidEnv); err == nil {
+		t.Errorf("expected an error for an invalid humanId")
+	}
+
+	// Add to a config without environments.
+	result, err = AddEnvironmentToConfigFile("projectID: idler\nenvironments: []\n", newEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(result, "projectID: idler\nenvironments:\n  - name: Load Test\n    humanId: idler-loadtest\n") {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+}
+
+func TestRemoveEnvironmentFromConfigFile(t *testing.T) {
+	// Remove the first environment.
+	result, err := RemoveEnvironmentFromConfigFile(testEditConfigFile, "idler-develop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `# Configure project.
+projectID: idler
+
+# Project environments.
+environments:
+  # Staging for QA.
+  - name: Staging
+    humanId: idler-staging
+    type: staging
+    stackDomain: p1.metaplay.io
+
+# Policies.
+policies: []
+`
+	if result != expected {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+
+	// Remove the last environment, with its comment.
+	result, err = RemoveEnvironmentFromConfigFile(result, "idler-staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = `# Configure project.
+projectID: idler
+
+# Project environments.
+environments: []
+
+# Policies.
+policies: []
+`
+	if result != expected {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+
+	if _, err := RemoveEnvironmentFromConfigFile(testEditConfigFile, "idler-missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Current version of the metaplay-project.yaml schema. New project configs are written with
+// this version, and older configs can be migrated to it with MigrateProjectConfigFile().
+const CurrentProjectConfigVersion = 2
+
+// Changes introduced by each version of the schema, shown to the user when their config
+// is older than the current version.
+var projectConfigVersionChanges = map[int][]string{
+	2: {
+		"The schema version is declared with 'configVersion' (files without it are version 1).",
+		"Optional 'serverValuesFilePattern' configures where the per-environment Helm values files are located.",
+		"Optional 'policies' restrict the operations allowed on environments by environment type.",
+		"Optional 'containerRuntime' selects the container runtime ('docker' or 'podman') for building and pushing images.",
+		"Optional 'imageSizeBudgetMB' sets the size budget of the game server image, checked by 'metaplay build image'.",
+	},
+}
+
+// Matches the top-level 'configVersion' field in a project config file.
+var configVersionLinePattern = regexp.MustCompile(`(?m)^configVersion:.*$`)
+
+// Matches the top-level '$schema' field in a project config file.
+var schemaLinePattern = regexp.MustCompile(`(?m)^\$schema:.*$`)
+
+// Get the schema version of the project config. Configs without 'configVersion' are version 1.
+func (projectConfig *ProjectConfig) GetConfigVersion() int {
+	if projectConfig.ConfigVersion == 0 {
+		return 1
+	}
+	return projectConfig.ConfigVersion
+}
+
+// Check whether the project config uses an older schema than the current one.
+func (projectConfig *ProjectConfig) IsConfigVersionOutdated() bool {
+	return projectConfig.GetConfigVersion() < CurrentProjectConfigVersion
+}
+
+// Get the changes in the schema since the given version, oldest first.
+func GetProjectConfigChangesSince(configVersion int) []string {
+	changes := []string{}
+	for version := configVersion + 1; version <= CurrentProjectConfigVersion; version++ {
+		for _, change := range projectConfigVersionChanges[version] {
+			changes = append(changes, fmt.Sprintf("v%d: %s", version, change))
+		}
+	}
+	return changes
+}
+
+// Validate the schema version of the project config.
+func validateProjectConfigVersion(config *ProjectConfig) error {
+	if config.ConfigVersion < 0 {
+		return fmt.Errorf("invalid configVersion %d", config.ConfigVersion)
+	}
+	if config.ConfigVersion > CurrentProjectConfigVersion {
+		return fmt.Errorf("configVersion %d is newer than the latest version %d supported by this CLI, update the CLI with 'metaplay update cli'", config.ConfigVersion, CurrentProjectConfigVersion)
+	}
+	return nil
+}
+
+// Default for a field introduced in a schema version, filled in by MigrateProjectConfigFile()
+// when the field is missing from the file.
+type projectConfigFieldDefault struct {
+	Key     string // Top-level key of the field.
+	Value   string // Default value, as YAML.
+	Comment string // Comment written above the field.
+}
+
+// Defaults of the fields introduced by each version of the schema. Only fields whose default
+// is known are listed: the others (eg, 'containerRuntime') are auto-detected or unset.
+var projectConfigVersionDefaults = map[int][]projectConfigFieldDefault{
+	2: {
+		{
+			Key:     "serverValuesFilePattern",
+			Value:   fmt.Sprintf("%q", DefaultServerValuesFilePattern),
+			Comment: "Path pattern of the per-environment game server Helm values files, '<environment>' is replaced with the environment ID.",
+		},
+	},
+}
+
+// Matches the top-level 'environments' field in a project config file.
+var environmentsLinePattern = regexp.MustCompile(`(?m)^environments:`)
+
+// Migrate the content of a project config file from the given schema version to the current
+// one. The file is edited minimally to retain its formatting and comments: the fields
+// introduced since the version are added with their defaults (before 'environments' to keep
+// it last), and the 'configVersion' is updated.
+func MigrateProjectConfigFile(content string, fromVersion int) string {
+	for version := fromVersion + 1; version <= CurrentProjectConfigVersion; version++ {
+		for _, field := range projectConfigVersionDefaults[version] {
+			content = addMissingProjectConfigField(content, field)
+		}
+	}
+
+	versionLine := fmt.Sprintf("configVersion: %d", CurrentProjectConfigVersion)
+
+	// Update an existing 'configVersion'.
+	if configVersionLinePattern.MatchString(content) {
+		return configVersionLinePattern.ReplaceAllString(content, versionLine)
+	}
+
+	// Otherwise, add it after the '$schema' or at the start of the file.
+	versionBlock := fmt.Sprintf("# Version of the metaplay-project.yaml schema.\n%s\n", versionLine)
+	if loc := schemaLinePattern.FindStringIndex(content); loc != nil {
+		return content[:loc[1]] + "\n\n" + strings.TrimSuffix(versionBlock, "\n") + content[loc[1]:]
+	}
+	return versionBlock + "\n" + content
+}
+
+// Add the top-level field with its default value to the project config file content, unless
+// the field is already present.
+func addMissingProjectConfigField(content string, field projectConfigFieldDefault) string {
+	if regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(field.Key) + `:`).MatchString(content) {
+		return content
+	}
+
+	fieldBlock := fmt.Sprintf("# %s\n%s: %s\n\n", field.Comment, field.Key, field.Value)
+	if loc := environmentsLinePattern.FindStringIndex(content); loc != nil {
+		return content[:loc[0]] + fieldBlock + content[loc[0]:]
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + "\n" + strings.TrimSuffix(fieldBlock, "\n")
+}
@@ -4,9 +4,19 @@
 package metaproj
 
 import (
+	"fmt"
+
 	"github.com/hashicorp/go-version"
 )
 
+// Minimum Metaplay SDK version (Release 32) supported by this CLI. Prerelease versions of
+// the minimum release are also supported. There is no upper bound.
+var MinSupportedSdkVersion = version.Must(version.NewVersion("32.0.0"))
+
+// Versions of the metaplay-project.yaml schema supported by this CLI. The file declares its
+// schema version in 'configVersion', files without it are considered version 1.
+var SupportedProjectConfigSchemaVersions = []int{1, CurrentProjectConfigVersion}
+
 // Represents MetaplaySDK/version.yaml.
 type MetaplayVersionMetadata struct {
 	SdkVersion                   *version.Version `yaml:"sdkVersion"`
@@ -20,3 +30,13 @@ type MetaplayVersionMetadata struct {
 	RecommendedNodeVersion       *version.Version `yaml:"nodeVersion"`
 	RecommendedPnpmVersion       *version.Version `yaml:"pnpmVersion"`
 }
+
+// Check whether the given Metaplay SDK version is supported by this CLI. Returns the
+// verdict and the reasons for it.
+func CheckSdkCompatibility(sdkVersion *version.Version) (bool, []string) {
+	// Compare the core versions so that prerelease versions of a supported release are accepted.
+	if sdkVersion.Core().LessThan(MinSupportedSdkVersion) {
+		return false, []string{fmt.Sprintf("Metaplay SDK %s is older than the minimum supported version %s", sdkVersion, MinSupportedSdkVersion)}
+	}
+	return true, []string{fmt.Sprintf("Metaplay SDK %s is within the supported range (%s or later)", sdkVersion, MinSupportedSdkVersion)}
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package metaproj
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestCheckSdkCompatibility(t *testing.T) {
+	tests := []struct {
+		sdkVersion   string
+		isCompatible bool
+	}{
+		{"32.0.0", true},
+		{"32.0.0-aaaaa", true}, // prerelease of the minimum release
+		{"32.1", true},
+		{"34.0.0", true},
+		{"31.3.0", false},
+		{"26.0", false},
+	}
+
+	for _, test := range tests {
+		sdkVersion := version.Must(version.NewVersion(test.sdkVersion))
+		compatible, reasons := CheckSdkCompatibility(sdkVersion)
+		if compatible != test.isCompatible {
+			t.Errorf("CheckSdkCompatibility(%q) = %v, expected %v", test.sdkVersion, compatible, test.isCompatible)
+		}
+		if len(reasons) == 0 {
+			t.Errorf("CheckSdkCompatibility(%q) returned no reasons", test.sdkVersion)
+		}
+	}
+}
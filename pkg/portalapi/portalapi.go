@@ -4,8 +4,10 @@
 package portalapi
 
 import (
+	"errors"
 	"fmt"
 	"math/rand/v2"
+	"net/http"
 	"path/filepath"
 
 	"github.com/metaplay/cli/pkg/auth"
@@ -90,7 +92,8 @@ func (c *Client) AgreeToContract(contractID string) error {
 }
 
 // DownloadSdkByVersionId downloads the SDK with the specified version ID to the target directory.
-func (c *Client) DownloadSdkByVersionId(targetDir, versionId string) (string, error) {
+// The optional onProgress is called as the download progresses.
+func (c *Client) DownloadSdkByVersionId(targetDir, versionId string, onProgress metahttp.DownloadProgressFunc) (string, error) {
 	if versionId == "" {
 		return "", fmt.Errorf("version ID is required")
 	}
@@ -99,17 +102,14 @@ func (c *Client) DownloadSdkByVersionId(targetDir, versionId string) (string, er
 	path := fmt.Sprintf("/api/v1/sdk/%s/download", versionId)
 	tmpFilename := fmt.Sprintf("metaplay-sdk-%08x.zip", rand.Uint32())
 	tmpSdkZipPath := filepath.Join(targetDir, tmpFilename)
-	resp, err := metahttp.Download(c.httpClient, path, tmpSdkZipPath)
+	err := metahttp.Download(c.httpClient, path, tmpSdkZipPath, metahttp.DownloadOptions{OnProgress: onProgress})
 	if err != nil {
-		return "", fmt.Errorf("failed to download SDK: %w", err)
-	}
-
-	// Handle server errors.
-	if resp.IsError() {
-		if resp.StatusCode() == 403 {
+		// Handle server errors.
+		var statusErr *metahttp.DownloadStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden {
 			return "", fmt.Errorf("you must agree to the SDK terms and conditions in https://portal.metaplay.dev first")
 		}
-		return "", fmt.Errorf("failed to download the Metaplay SDK from the portal with status code %d", resp.StatusCode())
+		return "", fmt.Errorf("failed to download the Metaplay SDK from the portal: %w", err)
 	}
 
 	log.Debug().Msgf("Downloaded SDK to %s", tmpSdkZipPath)
@@ -250,7 +250,7 @@ func (c *Client) FindSdkVersionByVersionOrName(versionOrName string) (*SdkVersio
 
 // DownloadLatestSdk downloads the latest SDK to the specified target directory.
 // This is a convenience function that combines GetLatestSdkVersionInfo and DownloadSdkByVersionId.
-func (c *Client) DownloadLatestSdk(targetDir string) (string, error) {
+func (c *Client) DownloadLatestSdk(targetDir string, onProgress metahttp.DownloadProgressFunc) (string, error) {
 	// Get the latest SDK version info
 	latestSdk, err := c.GetLatestSdkVersionInfo()
 	if err != nil {
@@ -263,5 +263,5 @@ func (c *Client) DownloadLatestSdk(targetDir string) (string, error) {
 	}
 
 	// Download the SDK
-	return c.DownloadSdkByVersionId(targetDir, latestSdk.ID)
+	return c.DownloadSdkByVersionId(targetDir, latestSdk.ID, onProgress)
 }
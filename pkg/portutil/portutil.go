@@ -0,0 +1,59 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package portutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// FindFreePort returns the preferred port if it is free, or otherwise a free port assigned
+// by the operating system. Use a preferred port of 0 to always get an OS-assigned port.
+func FindFreePort(preferred int) (int, error) {
+	if preferred > 0 && !IsPortInUse(preferred) {
+		return preferred, nil
+	}
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// IsPortInUse checks whether the local TCP port is already in use, by trying to listen on it.
+func IsPortInUse(port int) bool {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return true
+	}
+	listener.Close()
+	return false
+}
+
+// WaitForPort polls the host's TCP port with the given interval until it accepts connections,
+// or the context is cancelled (use context.WithTimeout() to limit the wait).
+func WaitForPort(ctx context.Context, host string, port int, interval time.Duration) error {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := net.Dialer{Timeout: interval}
+
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout while waiting for %s to accept connections: %w", address, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
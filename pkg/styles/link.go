@@ -0,0 +1,57 @@
+/*
+ * Copyright Metaplay. Licensed under the Apache-2.0 license.
+ */
+package styles
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// RenderLink renders text as a clickable hyperlink to url (using the OSC 8 escape sequence)
+// if colors are enabled and the terminal is known to support hyperlinks. Otherwise, falls back
+// to 'text (url)', or just the url if it is the same as the text.
+func RenderLink(text string, url string) string {
+	if lipgloss.ColorProfile() != termenv.Ascii && terminalSupportsHyperlinks() {
+		return fmt.Sprintf("\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\", url, StyleTechnical.Render(text))
+	}
+	if text == url {
+		return StyleTechnical.Render(url)
+	}
+	return fmt.Sprintf("%s (%s)", StyleTechnical.Render(text), url)
+}
+
+// Detect whether the terminal supports OSC 8 hyperlinks, based on the environment variables
+// set by the terminal emulators. FORCE_HYPERLINK=1 (or 0) overrides the detection.
+func terminalSupportsHyperlinks() bool {
+	if force, found := os.LookupEnv("FORCE_HYPERLINK"); found {
+		return force != "0" && force != ""
+	}
+
+	// CI logs and multiplexers don't render the escape sequences.
+	if os.Getenv("CI") != "" || os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return false
+	}
+
+	// Windows Terminal, kitty, and Konsole.
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "Hyper", "ghostty":
+		return true
+	}
+
+	// GNOME Terminal and other VTE-based terminals since VTE 0.50.
+	if vteVersion, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vteVersion >= 5000 {
+		return true
+	}
+
+	return false
+}